@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -12,9 +13,17 @@ import (
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
 	"lfg/shared/auth"
 	"lfg/shared/config"
+	"lfg/shared/metrics"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
 	"lfg/api-gateway/middleware"
+	"lfg/api-gateway/trading"
+	tradingv1 "lfg/order-service/proto/trading/v1"
 )
 
 func main() {
@@ -24,13 +33,68 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceAPIGateway, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.JWTAccessTTL, cfg.JWTRefreshTTL)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
-	rateLimiter := middleware.NewRateLimiter(cfg.RateLimitRequests, cfg.RateLimitWindow)
+	trustedProxies, err := middleware.NewTrustedProxies(cfg.RateLimitTrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	// Rate limits are shared between gateway instances through Redis when
+	// configured, otherwise each instance keeps its own
+	var rateLimitStore middleware.Store = middleware.NewMemoryStore()
+	if cfg.RateLimitRedisURL != "" {
+		redisStore, err := middleware.NewRedisStore(cfg.RateLimitRedisURL)
+		if err != nil {
+			log.Fatalf("Failed to configure rate limit store: %v", err)
+		}
+		defer redisStore.Close()
+
+		pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := redisStore.Ping(pingCtx); err != nil {
+			log.Printf("Rate limit store unreachable, requests are allowed until it is: %v", err)
+		}
+		cancel()
+		rateLimitStore = redisStore
+	}
+
+	rateLimiter := middleware.NewRateLimiter(
+		rateLimitStore,
+		middleware.NewQuota(cfg.RateLimitRequests, cfg.RateLimitWindow),
+		middleware.NewQuota(cfg.RateLimitUserRequests, cfg.RateLimitWindow),
+		trustedProxies,
+	)
+
+	// Routes that write or are open to abuse cost more than reads
+	rateLimiter.SetRouteCost("/register", 10)
+	rateLimiter.SetRouteCost("/login", 5)
+	rateLimiter.SetRouteCost("/orders/place", 10)
+	rateLimiter.SetRouteCost("/orders/cancel", 2)
+	rateLimiter.SetRouteCost("/exchange/buy", 10)
+	rateLimiter.SetRouteCost("/exchange/sell", 10)
+	rateLimiter.SetRouteCost("/markets/sets/mint", 5)
+	rateLimiter.SetRouteCost("/markets/sets/redeem", 5)
+	rateLimiter.SetRouteCost("/markets/resolution/dispute", 5)
+	rateLimiter.SetRouteCost("/webhooks/create", 5)
+	rateLimiter.SetRouteCost("/api-keys/create", 5)
+	rateLimiter.SetRouteCost("POST /v1/orders", 10)
+	rateLimiter.SetRouteCost("DELETE /v1/orders/", 2)
+	rateLimiter.SetRouteCost("PATCH /v1/orders/", 10)
+	rateLimiter.SetRouteCost(tradingv1.TradingService_PlaceOrder_FullMethodName, 10)
+	rateLimiter.SetRouteCost(tradingv1.TradingService_CancelOrder_FullMethodName, 2)
+	rateLimiter.SetRouteCost(tradingv1.TradingService_AmendOrder_FullMethodName, 10)
 	corsMiddleware := middleware.NewCORSMiddleware(cfg.CORSAllowedOrigins)
+	adminOnly := middleware.NewRoleMiddleware(string(models.UserRoleAdmin))
 
 	// Parse service URLs
 	userServiceURL, err := url.Parse(cfg.UserServiceURL)
@@ -63,13 +127,65 @@ func main() {
 		log.Fatalf("Invalid notification service URL: %v", err)
 	}
 
-	// Create reverse proxies
-	userProxy := httputil.NewSingleHostReverseProxy(userServiceURL)
-	walletProxy := httputil.NewSingleHostReverseProxy(walletServiceURL)
-	orderProxy := httputil.NewSingleHostReverseProxy(orderServiceURL)
-	marketProxy := httputil.NewSingleHostReverseProxy(marketServiceURL)
-	creditExchangeProxy := httputil.NewSingleHostReverseProxy(creditExchangeURL)
-	notificationProxy := httputil.NewSingleHostReverseProxy(notificationServiceURL)
+	// Create reverse proxies; each signs its requests with a service token
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceAPIGateway, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	userProxy := newServiceProxy(userServiceURL, auth.ServiceUser, serviceTokens)
+	walletProxy := newServiceProxy(walletServiceURL, auth.ServiceWallet, serviceTokens)
+	orderProxy := newServiceProxy(orderServiceURL, auth.ServiceOrder, serviceTokens)
+	marketProxy := newServiceProxy(marketServiceURL, auth.ServiceMarket, serviceTokens)
+	creditExchangeProxy := newServiceProxy(creditExchangeURL, auth.ServiceCreditExchange, serviceTokens)
+	notificationProxy := newServiceProxy(notificationServiceURL, auth.ServiceNotification, serviceTokens)
+
+	// Bots sign requests with API keys looked up in user-service. Keys reach
+	// reads with the read scope, and only the writes given a scope here.
+	// Managing keys and admin endpoints need a session token.
+	apiKeys := middleware.NewAPIKeyMiddleware(
+		middleware.NewUserServiceKeyStore(userServiceURL, serviceTokens, cfg.APIKeyCacheTTL, cfg.APIKeyCacheSize),
+		trustedProxies,
+		cfg.APIKeySignatureWindow,
+	)
+	apiKeys.SetRouteScope("/orders/place", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("/orders/cancel", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("/markets/sets/mint", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("/markets/sets/redeem", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("/exchange/buy", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("/exchange/sell", models.APIKeyScopeWithdraw)
+	apiKeys.SetRouteScope("POST /v1/orders", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("DELETE /v1/orders/", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope("PATCH /v1/orders/", models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope(tradingv1.TradingService_PlaceOrder_FullMethodName, models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope(tradingv1.TradingService_CancelOrder_FullMethodName, models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope(tradingv1.TradingService_AmendOrder_FullMethodName, models.APIKeyScopeTrade)
+	apiKeys.SetRouteScope(tradingv1.TradingService_GetOrder_FullMethodName, models.APIKeyScopeRead)
+	apiKeys.SetRouteScope(tradingv1.TradingService_ListOrders_FullMethodName, models.APIKeyScopeRead)
+	apiKeys.SetRouteScope(tradingv1.TradingService_StreamFills_FullMethodName, models.APIKeyScopeRead)
+	apiKeys.SetRouteScope(tradingv1.TradingService_StreamBook_FullMethodName, models.APIKeyScopeRead)
+	apiKeys.RequireSession("/api-keys")
+	apiKeys.RequireSession("/api-keys/")
+	apiKeys.RequireSession("/admin/")
+	apiKeys.SetLookupLimiter(rateLimiter)
+	authMiddleware.SetAPIKeys(apiKeys)
+
+	// The public trading API is served over gRPC and REST, both calling
+	// order-service over gRPC for the authenticated user
+	orderConn, err := grpc.NewClient(cfg.OrderServiceGRPC,
+		grpc.WithTransportCredentials(serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(serviceTokens.Credentials(auth.ServiceOrder)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create order service client: %v", err)
+	}
+	defer orderConn.Close()
+	tradingClient := tradingv1.NewTradingServiceClient(orderConn)
+
+	tradingREST, err := trading.NewRESTHandler(context.Background(), tradingClient)
+	if err != nil {
+		log.Fatalf("Failed to register trading API routes: %v", err)
+	}
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -81,26 +197,66 @@ func main() {
 		w.Write([]byte(`{"status":"healthy"}`))
 	})
 
+	// Metrics of the gateway itself (bearer token when METRICS_TOKEN is set)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
+
 	// Public endpoints (no auth required, but rate limited)
 	mux.Handle("/register", applyMiddleware(userProxy, rateLimiter))
 	mux.Handle("/login", applyMiddleware(userProxy, rateLimiter))
 
-	// Protected endpoints (auth + rate limiting)
-	mux.Handle("/profile", applyMiddleware(userProxy, rateLimiter, authMiddleware))
-	mux.Handle("/balance", applyMiddleware(walletProxy, rateLimiter, authMiddleware))
-	mux.Handle("/transactions", applyMiddleware(walletProxy, rateLimiter, authMiddleware))
-	mux.Handle("/orders/", applyMiddleware(orderProxy, rateLimiter, authMiddleware))
-	mux.Handle("/exchange/", applyMiddleware(creditExchangeProxy, rateLimiter, authMiddleware))
+	// Protected endpoints (auth + per-user rate limiting)
+	mux.Handle("/profile", applyMiddleware(userProxy, authMiddleware, rateLimiter))
+	mux.Handle("/api-keys", applyMiddleware(userProxy, authMiddleware, rateLimiter))
+	mux.Handle("/api-keys/", applyMiddleware(userProxy, authMiddleware, rateLimiter))
+	mux.Handle("/balance", applyMiddleware(walletProxy, authMiddleware, rateLimiter))
+	mux.Handle("/transactions", applyMiddleware(walletProxy, authMiddleware, rateLimiter))
+	mux.Handle("/orders/", applyMiddleware(orderProxy, authMiddleware, rateLimiter))
+	mux.Handle("/exchange/", applyMiddleware(creditExchangeProxy, authMiddleware, rateLimiter))
+
+	// Versioned trading API (auth + per-user rate limiting)
+	mux.Handle("/v1/", applyMiddleware(tradingREST, authMiddleware, rateLimiter))
 
 	// Public market endpoints (rate limited, no auth)
 	mux.Handle("/markets", applyMiddleware(marketProxy, rateLimiter))
 	mux.Handle("/markets/", applyMiddleware(marketProxy, rateLimiter))
+	mux.Handle("/markets/positions", applyMiddleware(marketProxy, authMiddleware, rateLimiter))
+	mux.Handle("/markets/sets/", applyMiddleware(marketProxy, authMiddleware, rateLimiter))
+	mux.Handle("/markets/resolution/dispute", applyMiddleware(marketProxy, authMiddleware, rateLimiter))
+	mux.Handle("/events", applyMiddleware(marketProxy, rateLimiter))
+	mux.Handle("/events/", applyMiddleware(marketProxy, rateLimiter))
+
+	// Admin endpoints (auth + per-user rate limiting + admin role)
+	mux.Handle("/admin/markets", applyMiddleware(marketProxy, authMiddleware, rateLimiter, adminOnly))
+	mux.Handle("/admin/markets/", applyMiddleware(marketProxy, authMiddleware, rateLimiter, adminOnly))
+	mux.Handle("/admin/events/", applyMiddleware(marketProxy, authMiddleware, rateLimiter, adminOnly))
+	mux.Handle("/admin/templates", applyMiddleware(marketProxy, authMiddleware, rateLimiter, adminOnly))
+	mux.Handle("/admin/templates/", applyMiddleware(marketProxy, authMiddleware, rateLimiter, adminOnly))
+
+	// Watchlist and alert endpoints (auth + per-user rate limiting)
+	mux.Handle("/watchlist", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+	mux.Handle("/watchlist/", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+	mux.Handle("/alerts", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+	mux.Handle("/alerts/", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+
+	// Notification inbox endpoints (auth + per-user rate limiting)
+	mux.Handle("/notifications", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+	mux.Handle("/notifications/", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+
+	// Webhook endpoints (auth + per-user rate limiting)
+	mux.Handle("/webhooks", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+	mux.Handle("/webhooks/", applyMiddleware(notificationProxy, authMiddleware, rateLimiter))
+
+	// Server-Sent Events alternative to the WebSocket (auth + per-user rate limiting)
+	mux.Handle("/events/stream", middleware.Streaming(applyMiddleware(notificationProxy, authMiddleware, rateLimiter)))
 
-	// WebSocket endpoint (auth required)
-	mux.Handle("/ws", applyMiddleware(notificationProxy, authMiddleware))
+	// WebSocket endpoint; notification-service authenticates the token sent
+	// in the Authorization header, the bearer subprotocol or the first message
+	mux.Handle("/ws", notificationProxy)
 
-	// Apply CORS to all routes
-	handler := corsMiddleware.Handle(mux)
+	// Apply CORS to all routes, record metrics per route and trace every
+	// request under a new request ID
+	handler := corsMiddleware.Handle(middleware.StripIdentityHeaders(mux))
+	handler = middleware.RequestID(tracing.Handler(auth.ServiceAPIGateway, metrics.Handler(mux, handler)))
 
 	// Create server
 	server := &http.Server{
@@ -119,6 +275,37 @@ func main() {
 		}
 	}()
 
+	// Public gRPC trading API; every call is authenticated and rate limited
+	// as the REST routes are
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			middleware.RequestIDUnaryInterceptor(),
+			authMiddleware.UnaryServerInterceptor(),
+			rateLimiter.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			metrics.StreamServerInterceptor(),
+			middleware.RequestIDStreamInterceptor(),
+			authMiddleware.StreamServerInterceptor(),
+			rateLimiter.StreamServerInterceptor(),
+		),
+	)
+	tradingv1.RegisterTradingServiceServer(grpcServer, trading.NewServer(tradingClient))
+
+	listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port: %v", err)
+	}
+
+	go func() {
+		log.Printf("API Gateway gRPC listening on port %s...\n", cfg.GRPCPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+
 	// Wait for interrupt
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -133,9 +320,38 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Streams stay open until their callers leave, so give them the same
+	// time to finish as HTTP requests
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
 	fmt.Println("API Gateway exited")
 }
 
+// newServiceProxy creates a reverse proxy to a service that signs each
+// request with a service token, carrying the identity AuthMiddleware set, and
+// propagates its trace
+func newServiceProxy(target *url.URL, service string, serviceTokens *auth.ServiceTokens) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = tracing.Transport(http.DefaultTransport)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		if err := serviceTokens.Sign(r, service); err != nil {
+			log.Printf("Failed to sign request to %s: %v", service, err)
+		}
+	}
+	return proxy
+}
+
 // applyMiddleware chains middleware and the final handler
 func applyMiddleware(handler http.Handler, middlewares ...interface{}) http.Handler {
 	result := handler
@@ -147,6 +363,8 @@ func applyMiddleware(handler http.Handler, middlewares ...interface{}) http.Hand
 			result = mw.Authenticate(result)
 		case *middleware.RateLimiter:
 			result = mw.Limit(result)
+		case *middleware.RoleMiddleware:
+			result = mw.Require(result)
 		}
 	}
 
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+)
+
+// maxSignedBodyBytes bounds the bodies of requests signed with API keys,
+// which are read in full to verify their signature
+const maxSignedBodyBytes = 1 << 20
+
+// ErrAPIKeyNotFound is returned by an APIKeyStore for unknown keys and keys
+// of inactive accounts
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKeyStore looks up the keys signing requests
+type APIKeyStore interface {
+	Credentials(ctx context.Context, keyID string) (*models.APIKeyCredentials, error)
+}
+
+// APIKeyMiddleware authenticates requests signed with an API key, for use
+// by automated clients in place of a session token. A signed request must be
+// sent within the signature window of its timestamp, from an address the
+// key allows, to a route one of the key's scopes covers, and is only
+// accepted once.
+type APIKeyMiddleware struct {
+	store   APIKeyStore
+	proxies *TrustedProxies
+	window  time.Duration
+	routes  routes[models.APIKeyScope]
+	replays *replayCache
+	limiter *RateLimiter
+}
+
+// NewAPIKeyMiddleware creates a new API key middleware accepting requests
+// signed up to window before or after they are received
+func NewAPIKeyMiddleware(store APIKeyStore, proxies *TrustedProxies, window time.Duration) *APIKeyMiddleware {
+	return &APIKeyMiddleware{
+		store:   store,
+		proxies: proxies,
+		window:  window,
+		routes:  make(routes[models.APIKeyScope]),
+		replays: newReplayCache(2 * window),
+	}
+}
+
+// SetRouteScope sets the scope a key needs to make a request matching
+// pattern. As with http.ServeMux patterns, a pattern may start with a
+// method, and a path ending in a slash covers every path below it; gRPC
+// calls are POSTs to their full method name. Routes without a scope take
+// read for GET and HEAD requests and cannot be called with a key otherwise.
+func (m *APIKeyMiddleware) SetRouteScope(pattern string, scope models.APIKeyScope) {
+	m.routes[pattern] = scope
+}
+
+// RequireSession stops keys from making requests matching pattern, which
+// then need a session token whatever the method
+func (m *APIKeyMiddleware) RequireSession(pattern string) {
+	m.routes[pattern] = ""
+}
+
+// SetLookupLimiter charges every key lookup to the client IP's bucket of
+// limiter before the key is looked up, so that clients cannot flood the key
+// store with keys that do not exist
+func (m *APIKeyMiddleware) SetLookupLimiter(limiter *RateLimiter) {
+	m.limiter = limiter
+}
+
+// Authenticate verifies the API key signature of requests and injects the
+// identity of the key's owner into headers for downstream services
+func (m *APIKeyMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+		if err != nil {
+			respondError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		creds, authErr := m.verify(r.Context(), signedRequest{
+			keyID:      r.Header.Get(auth.APIKeyHeader),
+			timestamp:  r.Header.Get(auth.APIKeyTimestampHeader),
+			signature:  r.Header.Get(auth.APIKeySignatureHeader),
+			method:     r.Method,
+			path:       r.URL.Path,
+			requestURI: r.URL.RequestURI(),
+			body:       body,
+			clientIP:   m.proxies.ClientIP(r),
+		})
+		if authErr != nil {
+			respondError(w, authErr.message, authErr.status)
+			return
+		}
+
+		// Inject the key owner's identity for downstream services
+		r.Header.Set("X-User-ID", creds.Key.UserID.String())
+		r.Header.Set("X-User-Email", creds.Email)
+		if creds.Role != "" {
+			r.Header.Set("X-User-Role", string(creds.Role))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signedRequest is what a request signed with an API key carries
+type signedRequest struct {
+	keyID      string
+	timestamp  string
+	signature  string
+	method     string
+	path       string
+	requestURI string
+	body       []byte
+	clientIP   string
+}
+
+// authError is a request refused by authentication, with the HTTP status it
+// is answered with
+type authError struct {
+	message string
+	status  int
+}
+
+// verify checks the signature of a request and that its key may make it,
+// returning the key's credentials
+func (m *APIKeyMiddleware) verify(ctx context.Context, req signedRequest) (*models.APIKeyCredentials, *authError) {
+	timestamp, err := strconv.ParseInt(req.timestamp, 10, 64)
+	if req.keyID == "" || req.signature == "" || err != nil {
+		return nil, &authError{"Signed requests need X-API-Key, X-API-Timestamp and X-API-Signature headers", http.StatusUnauthorized}
+	}
+
+	now := time.Now()
+	signedAt := time.Unix(timestamp, 0)
+	if signedAt.Before(now.Add(-m.window)) || signedAt.After(now.Add(m.window)) {
+		return nil, &authError{"Request timestamp is outside the allowed window", http.StatusUnauthorized}
+	}
+
+	scope, ok := m.scope(req.method, req.path)
+	if !ok {
+		return nil, &authError{"API keys cannot be used for this endpoint", http.StatusForbidden}
+	}
+
+	if m.limiter != nil {
+		decision, err := m.limiter.takeIP(ctx, req.clientIP, 1)
+		if err != nil {
+			// Fail open, so an unavailable store does not take the API down
+			log.Printf("Rate limit store failed: %v", err)
+		} else if !decision.Allowed {
+			return nil, &authError{"Rate limit exceeded", http.StatusTooManyRequests}
+		}
+	}
+
+	creds, err := m.store.Credentials(ctx, req.keyID)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, &authError{"Invalid API key", http.StatusUnauthorized}
+		}
+		log.Printf("Failed to look up API key %s: %v", req.keyID, err)
+		return nil, &authError{"Failed to verify API key", http.StatusServiceUnavailable}
+	}
+	key := creds.Key
+
+	if !auth.VerifyAPIRequest(key.Secret, timestamp, req.method, req.requestURI, req.body, req.signature) {
+		return nil, &authError{"Invalid request signature", http.StatusUnauthorized}
+	}
+
+	if !key.Usable(now) {
+		return nil, &authError{"API key has expired or been revoked", http.StatusUnauthorized}
+	}
+
+	if !key.AllowsIP(req.clientIP) {
+		return nil, &authError{"Requests from this address are not allowed for this API key", http.StatusForbidden}
+	}
+
+	if !key.HasScope(scope) {
+		return nil, &authError{"API key lacks the " + string(scope) + " scope", http.StatusForbidden}
+	}
+
+	if !m.replays.add(req.keyID+":"+req.signature, now) {
+		return nil, &authError{"Request was already received", http.StatusUnauthorized}
+	}
+
+	return creds, nil
+}
+
+// scope returns the scope a key needs to make a request, and false if keys
+// cannot make it. The most specific route covering the request applies.
+func (m *APIKeyMiddleware) scope(method, path string) (models.APIKeyScope, bool) {
+	scope, found := m.routes.match(method, path)
+	if !found {
+		if method == http.MethodGet || method == http.MethodHead {
+			return models.APIKeyScopeRead, true
+		}
+		return "", false
+	}
+	return scope, scope != ""
+}
+
+// replayCache remembers the signatures of accepted requests for as long as
+// their timestamps are accepted, so each signed request is only accepted
+// once by this gateway instance
+type replayCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newReplayCache(ttl time.Duration) *replayCache {
+	return &replayCache{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// add records a signature, returning false if it was already recorded
+func (c *replayCache) add(signature string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > c.ttl {
+		for s, expiresAt := range c.seen {
+			if now.After(expiresAt) {
+				delete(c.seen, s)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if expiresAt, ok := c.seen[signature]; ok && now.Before(expiresAt) {
+		return false
+	}
+	c.seen[signature] = now.Add(c.ttl)
+	return true
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+)
+
+// UserServiceKeyStore looks up API keys in user-service and caches them, and
+// the keys it did not find, for ttl. A revoked key can sign requests to this
+// instance until its cached copy expires. At most size keys are cached, the
+// least recently used making way for new ones, so lookups of random keys
+// cannot grow the cache without bound.
+type UserServiceKeyStore struct {
+	credentialsURL string
+	serviceTokens  *auth.ServiceTokens
+	client         *http.Client
+	ttl            time.Duration
+
+	mu    sync.Mutex
+	cache *lruCache[*models.APIKeyCredentials]
+}
+
+// NewUserServiceKeyStore creates an API key store backed by user-service
+func NewUserServiceKeyStore(userServiceURL *url.URL, serviceTokens *auth.ServiceTokens, ttl time.Duration, size int) *UserServiceKeyStore {
+	return &UserServiceKeyStore{
+		credentialsURL: userServiceURL.JoinPath("/internal/api-keys/credentials").String(),
+		serviceTokens:  serviceTokens,
+		client: &http.Client{
+			Transport: tracing.Transport(http.DefaultTransport),
+			Timeout:   5 * time.Second,
+		},
+		ttl:   ttl,
+		cache: newLRUCache[*models.APIKeyCredentials](size),
+	}
+}
+
+// Credentials implements APIKeyStore
+func (s *UserServiceKeyStore) Credentials(ctx context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	creds, ok := s.cache.get(keyID, now)
+	s.mu.Unlock()
+	if ok {
+		// Keys that were not found are cached as nil
+		if creds == nil {
+			return nil, ErrAPIKeyNotFound
+		}
+		return creds, nil
+	}
+
+	creds, err := s.fetch(ctx, keyID)
+	if err != nil && err != ErrAPIKeyNotFound {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache.put(keyID, creds, now.Add(s.ttl))
+	s.mu.Unlock()
+
+	return creds, err
+}
+
+// fetch looks up a key in user-service
+func (s *UserServiceKeyStore) fetch(ctx context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.credentialsURL+"?key_id="+url.QueryEscape(keyID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.serviceTokens.Sign(req, auth.ServiceUser); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrAPIKeyNotFound
+	default:
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var creds models.APIKeyCredentials
+	if err := json.NewDecoder(resp.Body).Decode(&creds); err != nil {
+		return nil, fmt.Errorf("failed to decode API key: %w", err)
+	}
+	if creds.Key == nil {
+		return nil, fmt.Errorf("user service returned no API key")
+	}
+
+	return &creds, nil
+}
+
+// lruCache holds up to size expiring entries, evicting the least recently
+// used when full. It is not safe for concurrent use.
+type lruCache[V any] struct {
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is an element of an lruCache's order
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+func newLRUCache[V any](size int) *lruCache[V] {
+	return &lruCache[V]{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the value cached under key, if it has not expired by now
+func (c *lruCache[V]) get(key string, now time.Time) (V, bool) {
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[V])
+	if !now.Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// put caches value under key until expiresAt
+func (c *lruCache[V]) put(key string, value V, expiresAt time.Time) {
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[V])
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	for c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+// len returns the number of cached entries, including expired ones not yet
+// evicted
+func (c *lruCache[V]) len() int {
+	return c.order.Len()
+}
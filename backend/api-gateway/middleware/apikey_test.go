@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+)
+
+// fakeKeyStore serves keys from a map and counts lookups
+type fakeKeyStore struct {
+	keys    map[string]*models.APIKeyCredentials
+	lookups int
+}
+
+func (s *fakeKeyStore) Credentials(_ context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	s.lookups++
+	creds, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return creds, nil
+}
+
+const testSecret = "test-secret"
+
+func newTestAPIKeys(t *testing.T, keys ...*models.APIKey) (*APIKeyMiddleware, *fakeKeyStore) {
+	t.Helper()
+
+	store := &fakeKeyStore{keys: make(map[string]*models.APIKeyCredentials)}
+	for _, key := range keys {
+		store.keys[key.KeyID] = &models.APIKeyCredentials{Key: key, Email: "bot@example.com", Role: models.UserRoleUser}
+	}
+
+	proxies, err := NewTrustedProxies(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewAPIKeyMiddleware(store, proxies, 30*time.Second)
+	m.SetRouteScope("/orders/place", models.APIKeyScopeTrade)
+	m.RequireSession("/api-keys/")
+	return m, store
+}
+
+func newTestKey(keyID string, scopes ...models.APIKeyScope) *models.APIKey {
+	return &models.APIKey{
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+		KeyID:  keyID,
+		Secret: testSecret,
+		Scopes: scopes,
+	}
+}
+
+// signedRequest builds a request signed with secret at timestamp
+func newSignedRequest(keyID, secret string, timestamp time.Time, method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	ts := timestamp.Unix()
+	r.Header.Set(auth.APIKeyHeader, keyID)
+	r.Header.Set(auth.APIKeyTimestampHeader, strconv.FormatInt(ts, 10))
+	r.Header.Set(auth.APIKeySignatureHeader, auth.SignAPIRequest(secret, ts, method, r.URL.RequestURI(), []byte(body)))
+	return r
+}
+
+func serve(m *APIKeyMiddleware, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("X-User-ID"))
+	})).ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	trader := newTestKey("trader", models.APIKeyScopeRead, models.APIKeyScopeTrade)
+	reader := newTestKey("reader", models.APIKeyScopeRead)
+	revoked := newTestKey("revoked", models.APIKeyScopeRead)
+	revoked.RevokedAt = &revokedAt
+	pinned := newTestKey("pinned", models.APIKeyScopeRead)
+	pinned.AllowedIPs = []string{"198.51.100.7"}
+
+	tamper := func(r *http.Request) *http.Request {
+		r.Body = http.NoBody
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+	}{
+		{"valid read", newSignedRequest("reader", testSecret, now, http.MethodGet, "/markets?limit=5", ""), http.StatusOK},
+		{"valid trade", newSignedRequest("trader", testSecret, now, http.MethodPost, "/orders/place", `{"quantity":1}`), http.StatusOK},
+		{"signature over another query", func() *http.Request {
+			r := newSignedRequest("reader", testSecret, now, http.MethodGet, "/markets?limit=5", "")
+			r.URL.RawQuery = "limit=500"
+			r.RequestURI = r.URL.RequestURI()
+			return r
+		}(), http.StatusUnauthorized},
+		{"tampered body", tamper(newSignedRequest("trader", testSecret, now, http.MethodPost, "/orders/place", `{"quantity":1}`)), http.StatusUnauthorized},
+		{"wrong secret", newSignedRequest("reader", "other-secret", now, http.MethodGet, "/markets", ""), http.StatusUnauthorized},
+		{"unknown key", newSignedRequest("nobody", testSecret, now, http.MethodGet, "/markets", ""), http.StatusUnauthorized},
+		{"stale timestamp", newSignedRequest("reader", testSecret, now.Add(-time.Minute), http.MethodGet, "/markets", ""), http.StatusUnauthorized},
+		{"future timestamp", newSignedRequest("reader", testSecret, now.Add(time.Minute), http.MethodGet, "/markets", ""), http.StatusUnauthorized},
+		{"missing signature", func() *http.Request {
+			r := newSignedRequest("reader", testSecret, now, http.MethodGet, "/markets", "")
+			r.Header.Del(auth.APIKeySignatureHeader)
+			return r
+		}(), http.StatusUnauthorized},
+		{"revoked key", newSignedRequest("revoked", testSecret, now, http.MethodGet, "/markets", ""), http.StatusUnauthorized},
+		{"address not allowed", newSignedRequest("pinned", testSecret, now, http.MethodGet, "/markets", ""), http.StatusForbidden},
+		{"missing scope", newSignedRequest("reader", testSecret, now, http.MethodPost, "/orders/place", `{}`), http.StatusForbidden},
+		{"write without a scope", newSignedRequest("trader", testSecret, now, http.MethodPost, "/profile", `{}`), http.StatusForbidden},
+		{"session only route", newSignedRequest("trader", testSecret, now, http.MethodGet, "/api-keys/list", ""), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestAPIKeys(t, trader, reader, revoked, pinned)
+
+			w := serve(m, tt.request)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code == http.StatusOK && w.Body.String() == "" {
+				t.Error("identity of the key's owner was not injected")
+			}
+		})
+	}
+}
+
+func TestAPIKeyRejectsReplays(t *testing.T) {
+	key := newTestKey("trader", models.APIKeyScopeTrade)
+	m, _ := newTestAPIKeys(t, key)
+	now := time.Now()
+
+	first := newSignedRequest("trader", testSecret, now, http.MethodPost, "/orders/place", `{"quantity":1}`)
+	replay := newSignedRequest("trader", testSecret, now, http.MethodPost, "/orders/place", `{"quantity":1}`)
+	other := newSignedRequest("trader", testSecret, now, http.MethodPost, "/orders/place", `{"quantity":2}`)
+
+	if w := serve(m, first); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(m, replay); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed request: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := serve(m, other); w.Code != http.StatusOK {
+		t.Errorf("different request in the same second: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPIKeyLookupsAreRateLimitedPerIP(t *testing.T) {
+	m, store := newTestAPIKeys(t)
+	proxies, _ := NewTrustedProxies(nil)
+	m.SetLookupLimiter(NewRateLimiter(NewMemoryStore(), Quota{Capacity: 3, Rate: 0.001}, Quota{Capacity: 100, Rate: 1}, proxies))
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		r := newSignedRequest(fmt.Sprintf("random-%d", i), testSecret, now, http.MethodGet, "/markets", "")
+		w := serve(m, r)
+
+		want := http.StatusUnauthorized
+		if i >= 3 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+
+	if store.lookups != 3 {
+		t.Errorf("store was asked for %d keys, want 3", store.lookups)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	cache := newLRUCache[int](2)
+
+	cache.put("a", 1, later)
+	cache.put("b", 2, later)
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatal("a was not cached")
+	}
+
+	// b is now the least recently used entry
+	cache.put("c", 3, later)
+	if _, ok := cache.get("b", now); ok {
+		t.Error("b was not evicted")
+	}
+	if v, ok := cache.get("a", now); !ok || v != 1 {
+		t.Errorf("a = %v, %v; want 1, true", v, ok)
+	}
+	if cache.len() != 2 {
+		t.Errorf("cache holds %d entries, want 2", cache.len())
+	}
+
+	if _, ok := cache.get("c", later); ok {
+		t.Error("c was returned after expiring")
+	}
+	if cache.len() != 1 {
+		t.Errorf("cache holds %d entries after expiry, want 1", cache.len())
+	}
+
+	for i := 0; i < 1000; i++ {
+		cache.put(strconv.Itoa(i), i, later)
+	}
+	if cache.len() != 2 {
+		t.Errorf("cache grew to %d entries, want 2", cache.len())
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"lfg/shared/auth"
 )
 
-// AuthMiddleware handles JWT authentication
+// AuthMiddleware handles JWT authentication, and API key authentication
+// when API keys are set
 type AuthMiddleware struct {
 	jwtManager *auth.JWTManager
+	apiKeys    *APIKeyMiddleware
 }
 
 // NewAuthMiddleware creates a new auth middleware
@@ -17,9 +19,25 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager) *AuthMiddleware {
 	return &AuthMiddleware{jwtManager: jwtManager}
 }
 
+// SetAPIKeys lets requests carrying an X-API-Key header authenticate with
+// their API key signature instead of a token
+func (m *AuthMiddleware) SetAPIKeys(apiKeys *APIKeyMiddleware) {
+	m.apiKeys = apiKeys
+}
+
 // Authenticate validates JWT token and injects user ID into headers
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	var signed http.Handler
+	if m.apiKeys != nil {
+		signed = m.apiKeys.Authenticate(next)
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if signed != nil && r.Header.Get(auth.APIKeyHeader) != "" {
+			signed.ServeHTTP(w, r)
+			return
+		}
+
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -59,6 +77,17 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// StripIdentityHeaders removes client-supplied identity headers so that only
+// AuthMiddleware can set them for downstream services
+func StripIdentityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("X-User-ID")
+		r.Header.Del("X-User-Email")
+		r.Header.Del("X-User-Role")
+		next.ServeHTTP(w, r)
+	})
+}
+
 func respondError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
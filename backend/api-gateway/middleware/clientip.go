@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// TrustedProxies are the load balancers and proxies in front of the gateway
+// whose forwarding headers are believed. Anyone else can put any address in
+// X-Forwarded-For, so only the addresses appended by trusted proxies count.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies parses the trusted proxies, given as IP addresses or
+// CIDR ranges
+func NewTrustedProxies(proxies []string) (*TrustedProxies, error) {
+	p := &TrustedProxies{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+func (p *TrustedProxies) trusted(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent a request. When the
+// request came through trusted proxies, it is the last address in
+// X-Forwarded-For (or X-Real-IP) that is not itself a trusted proxy.
+func (p *TrustedProxies) ClientIP(r *http.Request) string {
+	return p.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// CallerIP returns the address of the client that made a gRPC call, as
+// ClientIP does for HTTP requests, from the x-forwarded-for and x-real-ip
+// metadata of calls that came through trusted proxies
+func (p *TrustedProxies) CallerIP(ctx context.Context) string {
+	var remoteAddr string
+	if caller, ok := peer.FromContext(ctx); ok {
+		remoteAddr = caller.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var realIP string
+	if values := md.Get("x-real-ip"); len(values) > 0 {
+		realIP = values[0]
+	}
+	return p.clientIP(remoteAddr, md.Get("x-forwarded-for"), realIP)
+}
+
+// clientIP returns the address of a client given the address a request came
+// from and its forwarding headers
+func (p *TrustedProxies) clientIP(remoteAddr string, forwardedFor []string, realIP string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	remote := net.ParseIP(host)
+	if remote == nil || !p.trusted(remote) {
+		return host
+	}
+
+	// Walk back through the proxies that forwarded the request
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !p.trusted(ip) {
+			return ip.String()
+		}
+		remote = ip
+	}
+
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(realIP)); ip != nil {
+			return ip.String()
+		}
+	}
+
+	// Every hop was trusted; the furthest one is the best guess
+	return remote.String()
+}
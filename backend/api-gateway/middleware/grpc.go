@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"lfg/shared/auth"
+	"lfg/shared/tracing"
+)
+
+// UnaryServerInterceptor authenticates gRPC calls as Authenticate does HTTP
+// requests: with a bearer token in the authorization metadata, or with the
+// signature of an API key in the x-api-key, x-api-timestamp and
+// x-api-signature metadata. A call is signed as a POST to its full method
+// name whose body is the request message in the protobuf wire format. The
+// caller's identity is available from auth.IdentityFromContext.
+func (m *AuthMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var err error
+		if m.apiKeys != nil && firstValue(ctx, auth.APIKeyHeader) != "" {
+			ctx, err = m.apiKeys.authenticateCall(ctx, info.FullMethod, req)
+		} else {
+			ctx, err = m.authenticateCall(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming calls as
+// UnaryServerInterceptor does unary ones. Calls signed with an API key are
+// verified when their request message arrives, so only calls sending a
+// single message can be signed.
+func (m *AuthMiddleware) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if m.apiKeys != nil && firstValue(ss.Context(), auth.APIKeyHeader) != "" {
+			return handler(srv, &signedStream{ServerStream: ss, apiKeys: m.apiKeys, method: info.FullMethod})
+		}
+
+		ctx, err := m.authenticateCall(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticateCall validates the bearer token of a call
+func (m *AuthMiddleware) authenticateCall(ctx context.Context) (context.Context, error) {
+	authorization := firstValue(ctx, "authorization")
+	if authorization == "" {
+		return nil, status.Error(codes.Unauthenticated, "Missing authorization metadata")
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization metadata format")
+	}
+
+	claims, err := m.jwtManager.ValidateToken(token)
+	if err != nil {
+		if err == auth.ErrExpiredToken {
+			return nil, status.Error(codes.Unauthenticated, "Token has expired")
+		}
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	return auth.WithIdentity(ctx, auth.Identity{
+		UserID: claims.UserID.String(),
+		Email:  claims.Email,
+		Role:   claims.Role,
+	}), nil
+}
+
+// authenticateCall verifies the API key signature of a call with its
+// request message
+func (m *APIKeyMiddleware) authenticateCall(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, status.Error(codes.Internal, "Request cannot be verified")
+	}
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Request cannot be verified")
+	}
+
+	creds, authErr := m.verify(ctx, signedRequest{
+		keyID:      firstValue(ctx, auth.APIKeyHeader),
+		timestamp:  firstValue(ctx, auth.APIKeyTimestampHeader),
+		signature:  firstValue(ctx, auth.APIKeySignatureHeader),
+		method:     http.MethodPost,
+		path:       method,
+		requestURI: method,
+		body:       body,
+		clientIP:   m.proxies.CallerIP(ctx),
+	})
+	if authErr != nil {
+		return nil, status.Error(authErr.code(), authErr.message)
+	}
+
+	return auth.WithIdentity(ctx, auth.Identity{
+		UserID: creds.Key.UserID.String(),
+		Email:  creds.Email,
+		Role:   string(creds.Role),
+	}), nil
+}
+
+// code returns the gRPC status code of a refused call
+func (e *authError) code() codes.Code {
+	switch e.status {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+// UnaryServerInterceptor rate limits gRPC calls as Limit does HTTP requests,
+// taking tokens from the bucket of the user the call is authenticated for.
+// Quotas are reported in the x-ratelimit-* response metadata.
+func (rl *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, err := rl.limitCall(ctx, info.FullMethod)
+		if md != nil {
+			grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rate limits streaming calls. A stream is charged
+// when its first request message arrives, once a call signed with an API key
+// has been authenticated.
+func (rl *RateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &limitedStream{ServerStream: ss, limiter: rl, method: info.FullMethod})
+	}
+}
+
+// limitCall takes the tokens a call costs, returning the metadata reporting
+// the caller's quota and an error if the call is denied
+func (rl *RateLimiter) limitCall(ctx context.Context, method string) (metadata.MD, error) {
+	var userID string
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		userID = identity.UserID
+	}
+
+	quota, decision, err := rl.take(ctx, userID, rl.proxies.CallerIP(ctx), http.MethodPost, method)
+	if err != nil {
+		// Fail open, so an unavailable store does not take the API down
+		log.Printf("Rate limit store failed: %v", err)
+		return nil, nil
+	}
+
+	md := metadata.MD{}
+	for header, value := range limitHeaders(quota, decision) {
+		md.Set(header, value)
+	}
+
+	if !decision.Allowed {
+		return md, status.Error(codes.ResourceExhausted, "Rate limit exceeded")
+	}
+	return md, nil
+}
+
+// RequestIDUnaryInterceptor gives every gRPC call a new request ID, as
+// RequestID does HTTP requests
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(tracing.WithRequestID(ctx, tracing.NewRequestID()), req)
+	}
+}
+
+// RequestIDStreamInterceptor gives every streaming call a new request ID
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := tracing.WithRequestID(ss.Context(), tracing.NewRequestID())
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// firstValue returns the first value of a call's metadata key, if any
+func firstValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream is a server stream with a different context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the stream's context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// signedStream verifies the API key signature of a streaming call with its
+// request message, and carries the key owner's identity from then on
+type signedStream struct {
+	grpc.ServerStream
+	apiKeys *APIKeyMiddleware
+	method  string
+	ctx     context.Context
+}
+
+// Context returns the stream's context
+func (s *signedStream) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return s.ServerStream.Context()
+}
+
+// RecvMsg receives the request message and verifies the call's signature
+func (s *signedStream) RecvMsg(m interface{}) error {
+	if s.ctx != nil {
+		return status.Error(codes.PermissionDenied, "API keys can only sign calls sending a single message")
+	}
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	ctx, err := s.apiKeys.authenticateCall(s.ServerStream.Context(), s.method, m)
+	if err != nil {
+		return err
+	}
+	s.ctx = ctx
+	return nil
+}
+
+// limitedStream charges a streaming call when its first request message
+// arrives
+type limitedStream struct {
+	grpc.ServerStream
+	limiter *RateLimiter
+	method  string
+	charged bool
+}
+
+// RecvMsg receives a request message, charging the call for the first one
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil || s.charged {
+		return err
+	}
+	s.charged = true
+
+	md, err := s.limiter.limitCall(s.Context(), s.method)
+	if md != nil {
+		s.SetHeader(md)
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+// transportStream records the header a call sets
+type transportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// signedCall returns the metadata of a call to method signed with secret
+func signedCall(keyID, secret, method string, req proto.Message) metadata.MD {
+	body, _ := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	ts := time.Now().Unix()
+	return metadata.Pairs(
+		auth.APIKeyHeader, keyID,
+		auth.APIKeyTimestampHeader, strconv.FormatInt(ts, 10),
+		auth.APIKeySignatureHeader, auth.SignAPIRequest(secret, ts, http.MethodPost, method, body),
+	)
+}
+
+func TestAuthUnaryServerInterceptor(t *testing.T) {
+	jwtManager := auth.NewJWTManager("jwt-secret", time.Hour, 24*time.Hour)
+	user := uuid.New()
+	token, _, err := jwtManager.GenerateToken(user, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := auth.NewJWTManager("jwt-secret", -time.Minute, time.Hour).GenerateToken(user, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trader := newTestKey("trader", models.APIKeyScopeRead, models.APIKeyScopeTrade)
+	reader := newTestKey("reader", models.APIKeyScopeRead)
+	place := tradingv1.TradingService_PlaceOrder_FullMethodName
+	order := &tradingv1.PlaceOrderRequest{ContractId: uuid.NewString(), Quantity: 1}
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+		wantUser uuid.UUID
+	}{
+		{
+			name:     "bearer token",
+			md:       metadata.Pairs("authorization", "Bearer "+token),
+			wantUser: user,
+		},
+		{
+			name:     "expired token",
+			md:       metadata.Pairs("authorization", "Bearer "+expired),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "token without the bearer scheme",
+			md:       metadata.Pairs("authorization", token),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "no credentials",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "API key with the method's scope",
+			md:       signedCall("trader", testSecret, place, order),
+			wantUser: trader.UserID,
+		},
+		{
+			name:     "API key without the method's scope",
+			md:       signedCall("reader", testSecret, place, order),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "API key signature over another request",
+			md:       signedCall("trader", testSecret, place, &tradingv1.PlaceOrderRequest{Quantity: 100}),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "API key signature with another secret",
+			md:       signedCall("trader", "other-secret", place, order),
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKeys, _ := newTestAPIKeys(t, trader, reader)
+			apiKeys.SetRouteScope(place, models.APIKeyScopeTrade)
+			m := NewAuthMiddleware(jwtManager)
+			m.SetAPIKeys(apiKeys)
+
+			var identity auth.Identity
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				identity, _ = auth.IdentityFromContext(ctx)
+				return nil, nil
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, err := m.UnaryServerInterceptor()(ctx, order, &grpc.UnaryServerInfo{FullMethod: place}, handler)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("code = %v, want %v: %v", status.Code(err), tt.wantCode, err)
+			}
+			if err == nil && identity.UserID != tt.wantUser.String() {
+				t.Errorf("call made for %q, want %s", identity.UserID, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestRateLimiterUnaryServerInterceptor(t *testing.T) {
+	proxies, err := NewTrustedProxies(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		// allowed is how many calls in a row are allowed
+		allowed int
+	}{
+		{"placing orders", tradingv1.TradingService_PlaceOrder_FullMethodName, 2},
+		{"listing orders", tradingv1.TradingService_ListOrders_FullMethodName, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := NewRateLimiter(&MemoryStore{buckets: make(map[string]*bucket)}, Quota{Capacity: 4, Rate: 0.001}, Quota{Capacity: 10, Rate: 0.001}, proxies)
+			rl.SetRouteCost(tradingv1.TradingService_PlaceOrder_FullMethodName, 5)
+			interceptor := rl.UnaryServerInterceptor()
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+			ctx := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-1"})
+
+			for i := 0; i <= tt.allowed; i++ {
+				stream := &transportStream{}
+				_, err := interceptor(grpc.NewContextWithServerTransportStream(ctx, stream), nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+				if len(stream.header.Get("x-ratelimit-remaining")) == 0 {
+					t.Errorf("call %d: quota not reported", i)
+				}
+				if i < tt.allowed {
+					if err != nil {
+						t.Fatalf("call %d: err = %v, want it allowed", i, err)
+					}
+					continue
+				}
+				if status.Code(err) != codes.ResourceExhausted {
+					t.Fatalf("call %d: code = %v, want %v", i, status.Code(err), codes.ResourceExhausted)
+				}
+				if len(stream.header.Get("retry-after")) == 0 {
+					t.Error("denied call has no retry-after metadata")
+				}
+			}
+		})
+	}
+}
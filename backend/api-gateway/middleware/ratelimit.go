@@ -1,49 +1,123 @@
 package middleware
 
 import (
+	"context"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
 
-// RateLimiter implements a simple token bucket rate limiter
-type RateLimiter struct {
-	requests int
-	window   time.Duration
-	clients  map[string]*clientBucket
-	mu       sync.RWMutex
-	cleanup  *time.Ticker
-}
-
-type clientBucket struct {
-	tokens     int
-	lastRefill time.Time
-	mu         sync.Mutex
-}
-
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: requests,
-		window:   window,
-		clients:  make(map[string]*clientBucket),
-		cleanup:  time.NewTicker(time.Minute),
+// Quota is a token bucket: it holds up to Capacity tokens and refills
+// continuously at Rate tokens per second
+type Quota struct {
+	Capacity float64
+	Rate     float64
+}
+
+// NewQuota creates a quota allowing requests per window, in bursts of up to
+// requests
+func NewQuota(requests int, window time.Duration) Quota {
+	return Quota{
+		Capacity: float64(requests),
+		Rate:     float64(requests) / window.Seconds(),
 	}
+}
 
-	// Start cleanup goroutine
-	go rl.cleanupExpired()
+// Decision is the outcome of taking tokens from a bucket
+type Decision struct {
+	Allowed bool
+	// Remaining is the number of tokens left in the bucket
+	Remaining float64
+	// RetryAfter is how long until the bucket holds enough tokens for a
+	// denied request
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// decide builds the decision for a bucket holding tokens after a request
+// costing cost was allowed or denied
+func decide(quota Quota, tokens, cost float64, allowed bool) Decision {
+	decision := Decision{
+		Allowed:    allowed,
+		Remaining:  tokens,
+		ResetAfter: seconds((quota.Capacity - tokens) / quota.Rate),
+	}
+	if !allowed {
+		decision.RetryAfter = seconds((cost - tokens) / quota.Rate)
+	}
+	return decision
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store holds token buckets
+type Store interface {
+	// Take removes cost tokens from the bucket under key if it holds that
+	// many, refilling it for the time since it was last used
+	Take(ctx context.Context, key string, quota Quota, cost float64) (Decision, error)
+}
+
+// RateLimiter limits requests with token buckets: per user once a request is
+// authenticated, otherwise per client IP. Each route costs a number of
+// tokens, so expensive routes such as order placement use up a quota faster
+// than cheap ones such as market listings.
+type RateLimiter struct {
+	store     Store
+	ipQuota   Quota
+	userQuota Quota
+	proxies   *TrustedProxies
+	costs     routes[int]
+}
+
+// NewRateLimiter creates a new rate limiter. Requests are limited to ipQuota
+// per client IP, taken from the forwarding headers set by proxies, and to
+// userQuota per user when the rate limiter runs after AuthMiddleware.
+func NewRateLimiter(store Store, ipQuota, userQuota Quota, proxies *TrustedProxies) *RateLimiter {
+	return &RateLimiter{
+		store:     store,
+		ipQuota:   ipQuota,
+		userQuota: userQuota,
+		proxies:   proxies,
+		costs:     make(routes[int]),
+	}
+}
 
-	return rl
+// SetRouteCost sets the number of tokens a request matching pattern costs;
+// other requests cost one. As with http.ServeMux patterns, a pattern may
+// start with a method, and a path ending in a slash covers every path below
+// it; gRPC calls are POSTs to their full method name. It is not safe to call
+// once the limiter is serving.
+func (rl *RateLimiter) SetRouteCost(pattern string, cost int) {
+	rl.costs[pattern] = cost
 }
 
 // Limit applies rate limiting to requests
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get client identifier (IP address)
-		clientIP := getClientIP(r)
+		// X-User-ID can only have been set by AuthMiddleware, since
+		// StripIdentityHeaders removes it from client requests
+		quota, decision, err := rl.take(r.Context(), r.Header.Get("X-User-ID"), rl.proxies.ClientIP(r), r.Method, r.URL.Path)
+		if err != nil {
+			// Fail open, so an unavailable store does not take the API down
+			log.Printf("Rate limit store failed: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for header, value := range limitHeaders(quota, decision) {
+			w.Header().Set(header, value)
+		}
 
-		// Check rate limit
-		if !rl.allow(clientIP) {
+		if !decision.Allowed {
 			respondError(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
@@ -53,66 +127,107 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	})
 }
 
-func (rl *RateLimiter) allow(clientIP string) bool {
-	rl.mu.Lock()
-	bucket, exists := rl.clients[clientIP]
-	if !exists {
-		bucket = &clientBucket{
-			tokens:     rl.requests,
-			lastRefill: time.Now(),
-		}
-		rl.clients[clientIP] = bucket
+// take takes the tokens a request costs from the bucket of its user, or of
+// its client IP when it is not authenticated
+func (rl *RateLimiter) take(ctx context.Context, userID, clientIP, method, path string) (Quota, Decision, error) {
+	cost := 1
+	if c, ok := rl.costs.match(method, path); ok {
+		cost = c
 	}
-	rl.mu.Unlock()
-
-	bucket.mu.Lock()
-	defer bucket.mu.Unlock()
 
-	// Refill tokens based on time elapsed
-	now := time.Now()
-	elapsed := now.Sub(bucket.lastRefill)
-	if elapsed >= rl.window {
-		bucket.tokens = rl.requests
-		bucket.lastRefill = now
+	if userID == "" {
+		decision, err := rl.takeIP(ctx, clientIP, float64(cost))
+		return rl.ipQuota, decision, err
 	}
 
-	// Check if request is allowed
-	if bucket.tokens > 0 {
-		bucket.tokens--
-		return true
+	decision, err := rl.store.Take(ctx, "ratelimit:user:"+userID, rl.userQuota, float64(cost))
+	return rl.userQuota, decision, err
+}
+
+// takeIP takes cost tokens from the bucket of a client IP
+func (rl *RateLimiter) takeIP(ctx context.Context, clientIP string, cost float64) (Decision, error) {
+	return rl.store.Take(ctx, "ratelimit:ip:"+clientIP, rl.ipQuota, cost)
+}
+
+// limitHeaders returns the headers telling a client its quota and what is
+// left of it, and when a denied request can be retried
+func limitHeaders(quota Quota, decision Decision) map[string]string {
+	headers := map[string]string{
+		"X-RateLimit-Limit":     strconv.Itoa(int(quota.Capacity)),
+		"X-RateLimit-Remaining": strconv.Itoa(int(math.Floor(decision.Remaining))),
+		"X-RateLimit-Reset":     strconv.Itoa(int(math.Ceil(decision.ResetAfter.Seconds()))),
+	}
+	if !decision.Allowed {
+		headers["Retry-After"] = strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds())))
 	}
+	return headers
+}
 
-	return false
+// MemoryStore keeps token buckets in memory, so limits apply per gateway
+// instance
+type MemoryStore struct {
+	buckets map[string]*bucket
+	mu      sync.Mutex
+	cleanup *time.Ticker
 }
 
-func (rl *RateLimiter) cleanupExpired() {
-	for range rl.cleanup.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for ip, bucket := range rl.clients {
-			bucket.mu.Lock()
-			if now.Sub(bucket.lastRefill) > rl.window*2 {
-				delete(rl.clients, ip)
-			}
-			bucket.mu.Unlock()
-		}
-		rl.mu.Unlock()
+type bucket struct {
+	tokens   float64
+	lastUsed time.Time
+	// fullAt is when the bucket will have refilled completely
+	fullAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory token bucket store
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*bucket),
+		cleanup: time.NewTicker(time.Minute),
 	}
+
+	// Start cleanup goroutine
+	go s.cleanupFull()
+
+	return s
 }
 
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (proxy/load balancer)
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		return xff
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, quota Quota, cost float64) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: quota.Capacity, lastUsed: now}
+		s.buckets[key] = b
 	}
 
-	// Check X-Real-IP header
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return xri
+	// Refill tokens based on time elapsed
+	b.tokens = math.Min(quota.Capacity, b.tokens+now.Sub(b.lastUsed).Seconds()*quota.Rate)
+	b.lastUsed = now
+
+	allowed := b.tokens >= cost
+	if allowed {
+		b.tokens -= cost
 	}
 
-	// Fallback to RemoteAddr
-	return r.RemoteAddr
+	decision := decide(quota, b.tokens, cost, allowed)
+	b.fullAt = now.Add(decision.ResetAfter)
+	return decision, nil
+}
+
+// cleanupFull drops buckets that have refilled completely, which are the
+// same as new ones
+func (s *MemoryStore) cleanupFull() {
+	for range s.cleanup.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, b := range s.buckets {
+			if now.After(b.fullAt) {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
 }
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a token bucket stored as a hash of its
+// tokens and the time it was last used. It reads the time from Redis so the
+// gateway instances sharing it need not agree on the time.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps token buckets in Redis, so limits hold across every
+// gateway instance sharing it
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis server at url, e.g.
+// redis://localhost:6379/0
+func NewRedisStore(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+// Ping checks that the Redis server is reachable
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the connections to Redis
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, quota Quota, cost float64) (Decision, error) {
+	result, err := takeScript.Run(ctx, s.client, []string{key},
+		quota.Capacity, quota.Rate, cost).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to take tokens: %w", err)
+	}
+	if len(result) != 2 {
+		return Decision{}, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	raw, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Decision{}, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+
+	return decide(quota, tokens, cost, allowed == 1), nil
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	quota := Quota{Capacity: 10, Rate: 2}
+
+	tests := []struct {
+		name           string
+		tokens, cost   float64
+		allowed        bool
+		wantRetryAfter time.Duration
+		wantResetAfter time.Duration
+	}{
+		{"allowed with a full bucket", 10, 1, true, 0, 0},
+		{"allowed", 4, 1, true, 0, 3 * time.Second},
+		{"denied one token short", 0, 1, false, 500 * time.Millisecond, 5 * time.Second},
+		{"denied an expensive request", 1, 5, false, 2 * time.Second, 4500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := decide(quota, tt.tokens, tt.cost, tt.allowed)
+			if d.Allowed != tt.allowed || d.Remaining != tt.tokens {
+				t.Errorf("decision = %+v, want allowed %v with %v tokens", d, tt.allowed, tt.tokens)
+			}
+			if d.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter = %v, want %v", d.RetryAfter, tt.wantRetryAfter)
+			}
+			if d.ResetAfter != tt.wantResetAfter {
+				t.Errorf("ResetAfter = %v, want %v", d.ResetAfter, tt.wantResetAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	quota := Quota{Capacity: 5, Rate: 1}
+
+	tests := []struct {
+		name string
+		// elapsed is how long the drained bucket was left to refill
+		elapsed time.Duration
+		cost    float64
+		allowed bool
+		// remaining is the number of tokens left after the request
+		remaining float64
+	}{
+		{"empty bucket", 0, 1, false, 0},
+		{"partly refilled", 2 * time.Second, 1, true, 1},
+		{"not refilled enough for the cost", 2 * time.Second, 3, false, 2},
+		{"refilled exactly the cost", 3 * time.Second, 3, true, 0},
+		{"capped at capacity", time.Hour, 1, true, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &MemoryStore{buckets: make(map[string]*bucket)}
+			ctx := context.Background()
+
+			if d, _ := s.Take(ctx, "key", quota, quota.Capacity); !d.Allowed {
+				t.Fatal("a new bucket did not start full")
+			}
+			s.buckets["key"].lastUsed = s.buckets["key"].lastUsed.Add(-tt.elapsed)
+
+			d, err := s.Take(ctx, "key", quota, tt.cost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v", d.Allowed, tt.allowed)
+			}
+			// Allow for the time passing between the two requests
+			if d.Remaining < tt.remaining || d.Remaining > tt.remaining+0.01 {
+				t.Errorf("Remaining = %v, want %v", d.Remaining, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestLimitHeaders(t *testing.T) {
+	quota := Quota{Capacity: 60, Rate: 1}
+
+	tests := []struct {
+		name       string
+		decision   Decision
+		wantHeader map[string]string
+	}{
+		{
+			name:     "allowed",
+			decision: Decision{Allowed: true, Remaining: 41.7, ResetAfter: 18300 * time.Millisecond},
+			wantHeader: map[string]string{
+				"X-RateLimit-Limit":     "60",
+				"X-RateLimit-Remaining": "41",
+				"X-RateLimit-Reset":     "19",
+			},
+		},
+		{
+			name:     "denied rounds Retry-After up",
+			decision: Decision{Remaining: 0.4, RetryAfter: 600 * time.Millisecond, ResetAfter: 59600 * time.Millisecond},
+			wantHeader: map[string]string{
+				"X-RateLimit-Limit":     "60",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "60",
+				"Retry-After":           "1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := limitHeaders(quota, tt.decision)
+			if len(headers) != len(tt.wantHeader) {
+				t.Errorf("headers = %v, want %v", headers, tt.wantHeader)
+			}
+			for header, want := range tt.wantHeader {
+				if headers[header] != want {
+					t.Errorf("%s = %q, want %q", header, headers[header], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterLimit(t *testing.T) {
+	proxies, err := NewTrustedProxies(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		// allowed is how many requests in a row are allowed
+		allowed int
+	}{
+		{"anonymous listing", http.MethodGet, "/markets", "", 4},
+		{"user listing", http.MethodGet, "/markets", "user-1", 10},
+		{"user placing orders", http.MethodPost, "/orders/place", "user-1", 2},
+		{"cost applies to the method only", http.MethodGet, "/orders/place", "user-1", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := NewRateLimiter(&MemoryStore{buckets: make(map[string]*bucket)}, Quota{Capacity: 4, Rate: 0.001}, Quota{Capacity: 10, Rate: 0.001}, proxies)
+			rl.SetRouteCost("POST /orders/place", 5)
+			handler := rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i := 0; i <= tt.allowed; i++ {
+				r := httptest.NewRequest(tt.method, tt.path, nil)
+				if tt.userID != "" {
+					r.Header.Set("X-User-ID", tt.userID)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if i < tt.allowed {
+					if w.Code != http.StatusOK {
+						t.Fatalf("request %d: status = %d, want %d", i, w.Code, http.StatusOK)
+					}
+					continue
+				}
+				if w.Code != http.StatusTooManyRequests {
+					t.Fatalf("request %d: status = %d, want %d", i, w.Code, http.StatusTooManyRequests)
+				}
+				if w.Header().Get("Retry-After") == "" {
+					t.Error("denied request has no Retry-After header")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"lfg/shared/tracing"
+)
+
+// RequestID gives every request a new request ID, replacing any the client
+// sent, so the ID that follows a request through the services is always the
+// gateway's
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set(tracing.RequestIDHeader, tracing.NewRequestID())
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"lfg/shared/tracing"
+)
+
+// serverStream is a streaming call with a context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRequestID(t *testing.T) {
+	var seen []string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get(tracing.RequestIDHeader))
+	}))
+
+	for _, requestID := range []string{"client-chosen", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/markets", nil)
+		if requestID != "" {
+			r.Header.Set(tracing.RequestIDHeader, requestID)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if seen[0] == "" || seen[0] == "client-chosen" {
+		t.Errorf("request ID = %q, want the client's replaced", seen[0])
+	}
+	if seen[1] == "" || seen[1] == seen[0] {
+		t.Errorf("request ID = %q, want a new one for every request", seen[1])
+	}
+}
+
+func TestRequestIDInterceptors(t *testing.T) {
+	ctx := tracing.WithRequestID(context.Background(), "client-chosen")
+
+	var unary string
+	_, err := RequestIDUnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		unary = tracing.RequestID(ctx)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stream string
+	err = RequestIDStreamInterceptor()(nil, &serverStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, ss grpc.ServerStream) error {
+		stream = tracing.RequestID(ss.Context())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, got := range map[string]string{"unary": unary, "streaming": stream} {
+		if got == "" || got == "client-chosen" {
+			t.Errorf("%s call request ID = %q, want a new one", name, got)
+		}
+	}
+	if unary == stream {
+		t.Errorf("unary and streaming calls share request ID %q", unary)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// RoleMiddleware restricts routes to users holding one of the allowed roles.
+// It must run after AuthMiddleware, which sets X-User-Role from the JWT.
+type RoleMiddleware struct {
+	allowedRoles []string
+}
+
+// NewRoleMiddleware creates a new role middleware
+func NewRoleMiddleware(allowedRoles ...string) *RoleMiddleware {
+	return &RoleMiddleware{allowedRoles: allowedRoles}
+}
+
+// Require rejects requests whose role is not allowed
+func (m *RoleMiddleware) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := r.Header.Get("X-User-Role")
+		for _, allowed := range m.allowedRoles {
+			if role == allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		respondError(w, "Insufficient permissions", http.StatusForbidden)
+	})
+}
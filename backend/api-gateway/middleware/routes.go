@@ -0,0 +1,39 @@
+package middleware
+
+import "strings"
+
+// routes maps route patterns to settings. As with http.ServeMux patterns, a
+// pattern may start with a method and a space, and a pattern whose path ends
+// in a slash covers every path below it. gRPC calls are matched as POSTs to
+// their full method name.
+type routes[T any] map[string]T
+
+// match returns the setting of the most specific pattern covering a
+// request: the one with the longest path, and of those the one naming the
+// request's method
+func (rs routes[T]) match(method, path string) (T, bool) {
+	var (
+		setting    T
+		found      bool
+		matched    string
+		withMethod bool
+	)
+	for pattern, s := range rs {
+		patternMethod, patternPath, hasMethod := strings.Cut(pattern, " ")
+		if !hasMethod {
+			patternPath = pattern
+		} else if patternMethod != method {
+			continue
+		}
+
+		covers := patternPath == path || (strings.HasSuffix(patternPath, "/") && strings.HasPrefix(path, patternPath))
+		if !covers {
+			continue
+		}
+
+		if !found || len(patternPath) > len(matched) || (len(patternPath) == len(matched) && hasMethod && !withMethod) {
+			setting, found, matched, withMethod = s, true, patternPath, hasMethod
+		}
+	}
+	return setting, found
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Streaming lifts the server's write timeout for long-lived responses such as
+// Server-Sent Events streams
+func Streaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
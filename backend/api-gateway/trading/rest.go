@@ -0,0 +1,70 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"lfg/shared/auth"
+	"lfg/shared/idempotency"
+	"lfg/api-gateway/middleware"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+// NewRESTHandler serves the trading API's HTTP mappings under /v1, calling
+// order-service over gRPC. Requests must have passed AuthMiddleware, whose
+// identity headers give the user each call is made for. Placements carrying
+// an Idempotency-Key are made idempotent by order-service.
+func NewRESTHandler(ctx context.Context, client tradingv1.TradingServiceClient) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		// Nothing from the request is forwarded but the caller's identity
+		// and the Idempotency-Key of a placement
+		runtime.WithIncomingHeaderMatcher(func(header string) (string, bool) {
+			if header == idempotency.Header {
+				return idempotency.MetadataKey, true
+			}
+			return "", false
+		}),
+		runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+			if key == idempotency.ReplayedMetadataKey {
+				return idempotency.ReplayedHeader, true
+			}
+			return "", false
+		}),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(respondError),
+	)
+	if err := tradingv1.RegisterTradingServiceHandlerClient(ctx, mux, client); err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{
+			UserID: r.Header.Get("X-User-ID"),
+			Email:  r.Header.Get("X-User-Email"),
+			Role:   r.Header.Get("X-User-Role"),
+		}))
+		if strings.HasSuffix(r.URL.Path, ":stream") {
+			middleware.Streaming(mux).ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// respondError writes a call's error as the gateway's other errors are
+// written, with the HTTP status matching its gRPC code
+func respondError(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	st := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	json.NewEncoder(w).Encode(map[string]string{"error": st.Message()})
+}
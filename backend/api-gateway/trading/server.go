@@ -0,0 +1,96 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"lfg/shared/idempotency"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+// Server serves the public trading API by forwarding each call to
+// order-service, which trades for the user the call was authenticated for
+type Server struct {
+	client tradingv1.TradingServiceClient
+	tradingv1.UnimplementedTradingServiceServer
+}
+
+// NewServer creates a server forwarding calls to order-service
+func NewServer(client tradingv1.TradingServiceClient) *Server {
+	return &Server{client: client}
+}
+
+// PlaceOrder forwards an order placement, with its idempotency-key
+// metadata if any
+func (s *Server) PlaceOrder(ctx context.Context, req *tradingv1.PlaceOrderRequest) (*tradingv1.PlaceOrderResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(idempotency.MetadataKey); len(keys) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, idempotency.MetadataKey, keys[0])
+	}
+
+	var header metadata.MD
+	resp, err := s.client.PlaceOrder(ctx, req, grpc.Header(&header))
+	if replayed := header.Get(idempotency.ReplayedMetadataKey); len(replayed) > 0 {
+		grpc.SetHeader(ctx, metadata.Pairs(idempotency.ReplayedMetadataKey, replayed[0]))
+	}
+	return resp, err
+}
+
+// CancelOrder forwards an order cancellation
+func (s *Server) CancelOrder(ctx context.Context, req *tradingv1.CancelOrderRequest) (*tradingv1.CancelOrderResponse, error) {
+	return s.client.CancelOrder(ctx, req)
+}
+
+// AmendOrder forwards an order amendment
+func (s *Server) AmendOrder(ctx context.Context, req *tradingv1.AmendOrderRequest) (*tradingv1.AmendOrderResponse, error) {
+	return s.client.AmendOrder(ctx, req)
+}
+
+// GetOrder forwards an order lookup
+func (s *Server) GetOrder(ctx context.Context, req *tradingv1.GetOrderRequest) (*tradingv1.GetOrderResponse, error) {
+	return s.client.GetOrder(ctx, req)
+}
+
+// ListOrders forwards an order listing
+func (s *Server) ListOrders(ctx context.Context, req *tradingv1.ListOrdersRequest) (*tradingv1.ListOrdersResponse, error) {
+	return s.client.ListOrders(ctx, req)
+}
+
+// StreamFills relays the user's fills from order-service
+func (s *Server) StreamFills(req *tradingv1.StreamFillsRequest, stream grpc.ServerStreamingServer[tradingv1.Fill]) error {
+	upstream, err := s.client.StreamFills(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	return relay(upstream, stream)
+}
+
+// StreamBook relays a contract's order book updates from order-service
+func (s *Server) StreamBook(req *tradingv1.StreamBookRequest, stream grpc.ServerStreamingServer[tradingv1.BookUpdate]) error {
+	upstream, err := s.client.StreamBook(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	return relay(upstream, stream)
+}
+
+// relay sends the messages of an upstream stream on to the caller until
+// either ends. Cancelling the caller's context ends the upstream stream.
+func relay[T any](upstream grpc.ServerStreamingClient[T], stream grpc.ServerStreamingServer[T]) error {
+	for {
+		msg, err := upstream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package trading
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/auth"
+	"lfg/shared/idempotency"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+// fakeClient records the placements forwarded to order-service
+type fakeClient struct {
+	tradingv1.TradingServiceClient
+	err      error
+	replayed bool
+
+	identity auth.Identity
+	outgoing metadata.MD
+}
+
+func (c *fakeClient) PlaceOrder(ctx context.Context, req *tradingv1.PlaceOrderRequest, opts ...grpc.CallOption) (*tradingv1.PlaceOrderResponse, error) {
+	c.identity, _ = auth.IdentityFromContext(ctx)
+	c.outgoing, _ = metadata.FromOutgoingContext(ctx)
+	for _, opt := range opts {
+		if header, ok := opt.(grpc.HeaderCallOption); ok && c.replayed {
+			*header.HeaderAddr = metadata.Pairs(idempotency.ReplayedMetadataKey, "true")
+		}
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &tradingv1.PlaceOrderResponse{}, nil
+}
+
+// transportStream records the header a call sets
+type transportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestServerPlaceOrder(t *testing.T) {
+	identity := auth.Identity{UserID: "user-1", Email: "user@example.com", Role: "user"}
+
+	tests := []struct {
+		name         string
+		key          string
+		replayed     bool
+		wantKey      string
+		wantReplayed bool
+	}{
+		{
+			name: "placement",
+		},
+		{
+			name:    "placement with an idempotency key",
+			key:     "k1",
+			wantKey: "k1",
+		},
+		{
+			name:         "replayed placement",
+			key:          "k1",
+			replayed:     true,
+			wantKey:      "k1",
+			wantReplayed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{replayed: tt.replayed}
+			md := metadata.MD{}
+			if tt.key != "" {
+				md.Set(idempotency.MetadataKey, tt.key)
+			}
+			ctx := auth.WithIdentity(metadata.NewIncomingContext(context.Background(), md), identity)
+			stream := &transportStream{}
+			ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+			if _, err := NewServer(client).PlaceOrder(ctx, &tradingv1.PlaceOrderRequest{}); err != nil {
+				t.Fatalf("PlaceOrder() err = %v", err)
+			}
+
+			if client.identity != identity {
+				t.Errorf("placed for %+v, want %+v", client.identity, identity)
+			}
+			if got := strings.Join(client.outgoing.Get(idempotency.MetadataKey), ","); got != tt.wantKey {
+				t.Errorf("forwarded idempotency key %q, want %q", got, tt.wantKey)
+			}
+			if replayed := len(stream.header.Get(idempotency.ReplayedMetadataKey)) > 0; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+}
+
+func TestRESTHandlerPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          string
+		err          error
+		replayed     bool
+		wantStatus   int
+		wantKey      string
+		wantReplayed bool
+	}{
+		{
+			name:       "placement",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "replayed placement",
+			key:          "k1",
+			replayed:     true,
+			wantStatus:   http.StatusOK,
+			wantKey:      "k1",
+			wantReplayed: true,
+		},
+		{
+			name:       "rejected placement",
+			key:        "k1",
+			err:        status.Error(codes.FailedPrecondition, "Market is not open for trading"),
+			wantStatus: http.StatusBadRequest,
+			wantKey:    "k1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{err: tt.err, replayed: tt.replayed}
+			handler, err := NewRESTHandler(context.Background(), client)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"side":"SIDE_BUY"}`))
+			r.Header.Set("X-User-ID", "user-1")
+			r.Header.Set("X-User-Email", "user@example.com")
+			r.Header.Set("X-User-Role", "user")
+			if tt.key != "" {
+				r.Header.Set(idempotency.Header, tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if want := (auth.Identity{UserID: "user-1", Email: "user@example.com", Role: "user"}); client.identity != want {
+				t.Errorf("placed for %+v, want %+v", client.identity, want)
+			}
+			if got := strings.Join(client.outgoing.Get(idempotency.MetadataKey), ","); got != tt.wantKey {
+				t.Errorf("forwarded idempotency key %q, want %q", got, tt.wantKey)
+			}
+			if len(client.outgoing.Get("x-user-id")) > 0 {
+				t.Error("forwarded the identity headers as metadata")
+			}
+			if replayed := w.Header().Get(idempotency.ReplayedHeader) != ""; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+		})
+	}
+}
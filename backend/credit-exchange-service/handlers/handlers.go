@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,7 +12,10 @@ import (
 
 	"github.com/google/uuid"
 
+	"lfg/shared/auth"
+	"lfg/shared/idempotency"
 	"lfg/shared/models"
+	"lfg/shared/tracing"
 	"lfg/credit-exchange-service/repository"
 )
 
@@ -19,13 +23,15 @@ import (
 type ExchangeHandler struct {
 	txRepo           *repository.CreditTransactionRepository
 	walletServiceURL string
+	serviceTokens    *auth.ServiceTokens
 }
 
 // NewExchangeHandler creates a new exchange handler
-func NewExchangeHandler(txRepo *repository.CreditTransactionRepository, walletServiceURL string) *ExchangeHandler {
+func NewExchangeHandler(txRepo *repository.CreditTransactionRepository, walletServiceURL string, serviceTokens *auth.ServiceTokens) *ExchangeHandler {
 	return &ExchangeHandler{
 		txRepo:           txRepo,
 		walletServiceURL: walletServiceURL,
+		serviceTokens:    serviceTokens,
 	}
 }
 
@@ -83,6 +89,7 @@ func (h *ExchangeHandler) BuyCredits(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.txRepo.Create(r.Context(), tx); err != nil {
+		idempotency.Retryable(r.Context())
 		respondError(w, "Failed to create transaction", http.StatusInternalServerError)
 		return
 	}
@@ -91,8 +98,9 @@ func (h *ExchangeHandler) BuyCredits(w http.ResponseWriter, r *http.Request) {
 	// For demo purposes, immediately mark as completed
 	h.txRepo.UpdateStatus(r.Context(), tx.ID, models.CreditTransactionStatusCompleted)
 
-	// Credit user's wallet via internal API call
-	if err := h.creditWallet(userIDStr, creditAmount, tx.ID.String(), "PURCHASE"); err != nil {
+	// Credit user's wallet via internal API call, even if the client goes
+	// away, since the transaction is already recorded
+	if err := h.creditWallet(context.WithoutCancel(r.Context()), userIDStr, creditAmount, tx.ID.String(), "PURCHASE"); err != nil {
 		log.Printf("Failed to credit wallet: %v", err)
 		respondError(w, "Transaction recorded but wallet credit failed", http.StatusInternalServerError)
 		return
@@ -150,8 +158,9 @@ func (h *ExchangeHandler) SellCredits(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check user has sufficient credits in wallet
-	balance, err := h.getWalletBalance(userIDStr)
+	balance, err := h.getWalletBalance(r.Context(), userIDStr)
 	if err != nil {
+		idempotency.Retryable(r.Context())
 		respondError(w, "Failed to check wallet balance", http.StatusInternalServerError)
 		return
 	}
@@ -177,12 +186,13 @@ func (h *ExchangeHandler) SellCredits(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.txRepo.Create(r.Context(), tx); err != nil {
+		idempotency.Retryable(r.Context())
 		respondError(w, "Failed to create transaction", http.StatusInternalServerError)
 		return
 	}
 
 	// Debit user's wallet
-	if err := h.debitWallet(userIDStr, req.CreditAmount, tx.ID.String(), "SALE"); err != nil {
+	if err := h.debitWallet(context.WithoutCancel(r.Context()), userIDStr, req.CreditAmount, tx.ID.String(), "SALE"); err != nil {
 		log.Printf("Failed to debit wallet: %v", err)
 		respondError(w, "Transaction recorded but wallet debit failed", http.StatusInternalServerError)
 		return
@@ -277,13 +287,16 @@ func respondError(w http.ResponseWriter, message string, statusCode int) {
 }
 
 // getWalletBalance fetches the user's wallet balance
-func (h *ExchangeHandler) getWalletBalance(userID string) (float64, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequest("GET", h.walletServiceURL+"/balance", nil)
+func (h *ExchangeHandler) getWalletBalance(ctx context.Context, userID string) (float64, error) {
+	client := &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(http.DefaultTransport)}
+	req, err := http.NewRequestWithContext(ctx, "GET", h.walletServiceURL+"/balance", nil)
 	if err != nil {
 		return 0, err
 	}
 	req.Header.Set("X-User-ID", userID)
+	if err := h.serviceTokens.Sign(req, auth.ServiceWallet); err != nil {
+		return 0, err
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -310,8 +323,8 @@ func (h *ExchangeHandler) getWalletBalance(userID string) (float64, error) {
 }
 
 // creditWallet credits the user's wallet
-func (h *ExchangeHandler) creditWallet(userID string, amount float64, transactionID string, transactionType string) error {
-	client := &http.Client{Timeout: 5 * time.Second}
+func (h *ExchangeHandler) creditWallet(ctx context.Context, userID string, amount float64, transactionID string, transactionType string) error {
+	client := &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(http.DefaultTransport)}
 
 	payload := map[string]interface{}{
 		"amount":           amount,
@@ -324,12 +337,15 @@ func (h *ExchangeHandler) creditWallet(userID string, amount float64, transactio
 		return err
 	}
 
-	req, err := http.NewRequest("POST", h.walletServiceURL+"/credit", bytes.NewBuffer(payloadJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", h.walletServiceURL+"/credit", bytes.NewBuffer(payloadJSON))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-User-ID", userID)
+	if err := h.serviceTokens.Sign(req, auth.ServiceWallet); err != nil {
+		return err
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -346,8 +362,8 @@ func (h *ExchangeHandler) creditWallet(userID string, amount float64, transactio
 }
 
 // debitWallet debits the user's wallet
-func (h *ExchangeHandler) debitWallet(userID string, amount float64, transactionID string, transactionType string) error {
-	client := &http.Client{Timeout: 5 * time.Second}
+func (h *ExchangeHandler) debitWallet(ctx context.Context, userID string, amount float64, transactionID string, transactionType string) error {
+	client := &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(http.DefaultTransport)}
 
 	payload := map[string]interface{}{
 		"amount":           amount,
@@ -360,12 +376,15 @@ func (h *ExchangeHandler) debitWallet(userID string, amount float64, transaction
 		return err
 	}
 
-	req, err := http.NewRequest("POST", h.walletServiceURL+"/debit", bytes.NewBuffer(payloadJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", h.walletServiceURL+"/debit", bytes.NewBuffer(payloadJSON))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-User-ID", userID)
+	if err := h.serviceTokens.Sign(req, auth.ServiceWallet); err != nil {
+		return err
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -10,8 +10,12 @@ import (
 	"syscall"
 	"time"
 
+	"lfg/shared/auth"
 	"lfg/shared/config"
 	"lfg/shared/db"
+	"lfg/shared/idempotency"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 	"lfg/credit-exchange-service/handlers"
 	"lfg/credit-exchange-service/repository"
 )
@@ -23,6 +27,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceCreditExchange, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create context
 	ctx := context.Background()
 
@@ -45,26 +56,40 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close(pool)
+	metrics.RegisterPool(pool)
 
 	log.Println("Connected to database successfully")
 
 	// Initialize repository
 	txRepo := repository.NewCreditTransactionRepository(pool)
 
+	// Initialize service authentication
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceCreditExchange, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway, "/exchange/")
+
 	// Initialize handlers
-	exchangeHandler := handlers.NewExchangeHandler(txRepo, cfg.WalletServiceURL)
+	exchangeHandler := handlers.NewExchangeHandler(txRepo, cfg.WalletServiceURL, serviceTokens)
+
+	// Retried exchanges carrying an Idempotency-Key get the first response
+	idempotent := idempotency.NewMiddleware(idempotency.NewStore(pool), cfg.IdempotencyKeyTTL)
+	go idempotent.Run(ctx)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
-	mux.HandleFunc("/exchange/buy", exchangeHandler.BuyCredits)
-	mux.HandleFunc("/exchange/sell", exchangeHandler.SellCredits)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
+	mux.Handle("/exchange/buy", idempotent.Handler(http.HandlerFunc(exchangeHandler.BuyCredits)))
+	mux.Handle("/exchange/sell", idempotent.Handler(http.HandlerFunc(exchangeHandler.SellCredits)))
 	mux.HandleFunc("/exchange/history", exchangeHandler.ExchangeHistory)
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceCreditExchange, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -0,0 +1,285 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// soh separates the fields of a message
+const soh = '\x01'
+
+// maxBodyLength bounds the messages a counterparty can send
+const maxBodyLength = 64 << 10
+
+// TimestampFormat is the layout of UTCTimestamp fields
+const TimestampFormat = "20060102-15:04:05.000"
+
+var (
+	// ErrGarbled is returned for a message whose framing was intact but whose
+	// checksum or fields were not. It is ignored without consuming a
+	// sequence number.
+	ErrGarbled = errors.New("garbled message")
+
+	// ErrFraming is returned when the stream cannot be split into messages
+	// any more; the connection has to be dropped
+	ErrFraming = errors.New("invalid message framing")
+)
+
+// Field is a tag and its value
+type Field struct {
+	Tag   Tag
+	Value string
+}
+
+// Message is a FIX message without its BeginString, BodyLength and
+// CheckSum fields, which are added when it is written out. MsgType comes
+// first; outgoing messages are created with New and only carry their body,
+// the session adds the rest of the header.
+type Message struct {
+	Fields []Field
+}
+
+// New creates a message of msgType
+func New(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: TagMsgType, Value: msgType}}}
+}
+
+// Add appends a field to the message
+func (m *Message) Add(tag Tag, value string) *Message {
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// Set replaces the value of the first field with tag, appending the field
+// if the message has none
+func (m *Message) Set(tag Tag, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	return m.Add(tag, value)
+}
+
+// AddInt appends an integer field to the message
+func (m *Message) AddInt(tag Tag, value int) *Message {
+	return m.Add(tag, strconv.Itoa(value))
+}
+
+// AddFloat appends a decimal field to the message
+func (m *Message) AddFloat(tag Tag, value float64) *Message {
+	return m.Add(tag, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// AddTime appends a UTCTimestamp field to the message
+func (m *Message) AddTime(tag Tag, value time.Time) *Message {
+	return m.Add(tag, value.UTC().Format(TimestampFormat))
+}
+
+// Type returns the message's MsgType
+func (m *Message) Type() string {
+	return m.Get(TagMsgType)
+}
+
+// Has reports whether the message carries tag
+func (m *Message) Has(tag Tag) bool {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of the first field with tag, or "" if there is none
+func (m *Message) Get(tag Tag) string {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Int returns the value of tag as an integer
+func (m *Message) Int(tag Tag) (int, error) {
+	value := m.Get(tag)
+	if value == "" {
+		return 0, fmt.Errorf("tag %d is missing", tag)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("tag %d is not an integer", tag)
+	}
+	return n, nil
+}
+
+// Float returns the value of tag as a decimal
+func (m *Message) Float(tag Tag) (float64, error) {
+	value := m.Get(tag)
+	if value == "" {
+		return 0, fmt.Errorf("tag %d is missing", tag)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tag %d is not a decimal", tag)
+	}
+	return f, nil
+}
+
+// Bool returns whether tag is set to Y
+func (m *Message) Bool(tag Tag) bool {
+	return m.Get(tag) == "Y"
+}
+
+// Body encodes the fields after MsgType, as kept for resends
+func (m *Message) Body() []byte {
+	var buf bytes.Buffer
+	for _, f := range m.Fields {
+		if f.Tag == TagMsgType {
+			continue
+		}
+		writeField(&buf, f.Tag, f.Value)
+	}
+	return buf.Bytes()
+}
+
+// Bytes encodes the message with its BeginString, BodyLength and CheckSum
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, f := range m.Fields {
+		writeField(&body, f.Tag, f.Value)
+	}
+
+	var buf bytes.Buffer
+	writeField(&buf, TagBeginString, BeginString)
+	writeField(&buf, TagBodyLength, strconv.Itoa(body.Len()))
+	buf.Write(body.Bytes())
+	writeField(&buf, TagCheckSum, fmt.Sprintf("%03d", checksum(buf.Bytes())))
+	return buf.Bytes()
+}
+
+// String renders the message with | separating its fields, for logs
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{soh}, []byte{'|'}))
+}
+
+// ParseFields decodes fields encoded by Body
+func ParseFields(body []byte) ([]Field, error) {
+	var fields []Field
+	for len(body) > 0 {
+		end := bytes.IndexByte(body, soh)
+		if end < 0 {
+			return nil, ErrGarbled
+		}
+		field, err := parseField(body[:end])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		body = body[end+1:]
+	}
+	return fields, nil
+}
+
+// Read reads the next message from r. Messages with a bad checksum or
+// malformed fields return ErrGarbled and can be skipped; any other error
+// leaves the stream unusable.
+func Read(r *bufio.Reader) (*Message, error) {
+	begin, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	if begin.Tag != TagBeginString || begin.Value != BeginString {
+		return nil, ErrFraming
+	}
+
+	length, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	bodyLength, err := strconv.Atoi(length.Value)
+	if length.Tag != TagBodyLength || err != nil || bodyLength <= 0 || bodyLength > maxBodyLength {
+		return nil, ErrFraming
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	// The trailer is always "10=NNN" and SOH
+	trailer := make([]byte, 7)
+	if _, err := io.ReadFull(r, trailer); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(trailer, []byte("10=")) || trailer[6] != soh {
+		return nil, ErrFraming
+	}
+
+	var head bytes.Buffer
+	writeField(&head, TagBeginString, begin.Value)
+	writeField(&head, TagBodyLength, length.Value)
+	sum := (checksum(head.Bytes()) + checksum(body)) % 256
+	if want, err := strconv.Atoi(string(trailer[3:6])); err != nil || want != sum {
+		return nil, ErrGarbled
+	}
+
+	fields, err := ParseFields(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 || fields[0].Tag != TagMsgType {
+		return nil, ErrGarbled
+	}
+	return &Message{Fields: fields}, nil
+}
+
+// readField reads a single field from r, up to its SOH
+func readField(r *bufio.Reader) (Field, error) {
+	line, err := r.ReadSlice(soh)
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return Field{}, ErrFraming
+		}
+		return Field{}, err
+	}
+	field, err := parseField(line[:len(line)-1])
+	if err != nil {
+		return Field{}, ErrFraming
+	}
+	return field, nil
+}
+
+func parseField(raw []byte) (Field, error) {
+	eq := bytes.IndexByte(raw, '=')
+	if eq <= 0 {
+		return Field{}, ErrGarbled
+	}
+	tag, err := strconv.Atoi(string(raw[:eq]))
+	if err != nil || tag <= 0 {
+		return Field{}, ErrGarbled
+	}
+	return Field{Tag: Tag(tag), Value: string(raw[eq+1:])}, nil
+}
+
+func writeField(buf *bytes.Buffer, tag Tag, value string) {
+	buf.WriteString(strconv.Itoa(int(tag)))
+	buf.WriteByte('=')
+	buf.WriteString(value)
+	buf.WriteByte(soh)
+}
+
+func checksum(b []byte) int {
+	sum := 0
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
@@ -0,0 +1,226 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// frame encodes a message written with | between its fields, computing its
+// BodyLength and CheckSum unless they are given
+func frame(body string, bodyLength, sum string) string {
+	body = strings.ReplaceAll(body, "|", "\x01")
+	if bodyLength == "" {
+		bodyLength = fmt.Sprint(len(body))
+	}
+	head := "8=" + BeginString + "\x019=" + bodyLength + "\x01"
+	if sum == "" {
+		sum = fmt.Sprintf("%03d", checksum([]byte(head+body)))
+	}
+	return head + body + "10=" + sum + "\x01"
+}
+
+func TestRead(t *testing.T) {
+	heartbeat := "35=0|49=CLIENT|56=LFG|34=2|52=20240101-12:00:00.000|"
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []Field
+		wantErr error
+	}{
+		{
+			name: "valid message",
+			raw:  frame(heartbeat, "", ""),
+			want: []Field{
+				{TagMsgType, MsgTypeHeartbeat},
+				{TagSenderCompID, "CLIENT"},
+				{TagTargetCompID, "LFG"},
+				{TagMsgSeqNum, "2"},
+				{TagSendingTime, "20240101-12:00:00.000"},
+			},
+		},
+		{
+			name: "empty value",
+			raw:  frame("35=0|58=|", "", ""),
+			want: []Field{{TagMsgType, MsgTypeHeartbeat}, {TagText, ""}},
+		},
+		{
+			name:    "bad checksum",
+			raw:     frame(heartbeat, "", "000"),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "checksum not a number",
+			raw:     frame(heartbeat, "", "abc"),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "MsgType not first",
+			raw:     frame("49=CLIENT|35=0|", "", ""),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "tag not a number",
+			raw:     frame("35=0|x=1|", "", ""),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "field without a tag",
+			raw:     frame("35=0|=1|", "", ""),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "body not ending in SOH",
+			raw:     frame("35=0|58=x", "", ""),
+			wantErr: ErrGarbled,
+		},
+		{
+			name:    "other BeginString",
+			raw:     strings.Replace(frame(heartbeat, "", ""), BeginString, "FIX.4.2", 1),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "BeginString not first",
+			raw:     "9=5\x01" + frame(heartbeat, "", ""),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "BodyLength not a number",
+			raw:     frame(heartbeat, "x", ""),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "BodyLength zero",
+			raw:     frame(heartbeat, "0", ""),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "BodyLength too large",
+			raw:     frame(heartbeat, fmt.Sprint(maxBodyLength+1), ""),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "BodyLength too short",
+			raw:     frame(heartbeat, "10", ""),
+			wantErr: ErrFraming,
+		},
+		{
+			name:    "truncated body",
+			raw:     frame(heartbeat, "", "")[:20],
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "truncated trailer",
+			raw:     strings.TrimSuffix(frame(heartbeat, "", ""), "\x01"),
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "end of stream",
+			raw:     "",
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Read(bufio.NewReader(strings.NewReader(tt.raw)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(msg.Fields) != len(tt.want) {
+				t.Fatalf("fields = %v, want %v", msg.Fields, tt.want)
+			}
+			for i, f := range tt.want {
+				if msg.Fields[i] != f {
+					t.Errorf("field %d = %v, want %v", i, msg.Fields[i], f)
+				}
+			}
+		})
+	}
+}
+
+func TestReadSkipsGarbledMessages(t *testing.T) {
+	stream := frame("35=0|34=2|", "", "000") + frame("35=1|34=3|112=TEST|", "", "")
+	r := bufio.NewReader(strings.NewReader(stream))
+
+	if _, err := Read(r); !errors.Is(err, ErrGarbled) {
+		t.Fatalf("first message: err = %v, want %v", err, ErrGarbled)
+	}
+
+	msg, err := Read(r)
+	if err != nil {
+		t.Fatalf("second message: %v", err)
+	}
+	if msg.Type() != MsgTypeTestRequest || msg.Get(TagTestReqID) != "TEST" {
+		t.Errorf("second message = %s, want the test request", msg)
+	}
+
+	if _, err := Read(r); err != io.EOF {
+		t.Errorf("end of stream: err = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *Message
+		want string
+	}{
+		{
+			name: "heartbeat",
+			msg:  New(MsgTypeHeartbeat).Add(TagMsgSeqNum, "1"),
+			want: frame("35=0|34=1|", "", ""),
+		},
+		{
+			name: "typed fields",
+			msg:  New(MsgTypeExecutionReport).AddInt(TagCumQty, 10).AddFloat(TagAvgPx, 0.55),
+			want: frame("35=8|14=10|6=0.55|", "", ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := string(tt.msg.Bytes())
+			if raw != tt.want {
+				t.Errorf("Bytes() = %q, want %q", raw, tt.want)
+			}
+
+			// The checksum is always three digits
+			if trailer := raw[strings.LastIndex(raw, "10="):]; len(trailer) != 7 {
+				t.Errorf("trailer = %q, want 10=NNN and SOH", trailer)
+			}
+
+			msg, err := Read(bufio.NewReader(strings.NewReader(raw)))
+			if err != nil {
+				t.Fatalf("reading back: %v", err)
+			}
+			if msg.String() != tt.msg.String() {
+				t.Errorf("read back %s, want %s", msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	msg := New(MsgTypeNewOrderSingle).Add(TagClOrdID, "order-1").AddInt(TagOrderQty, 5)
+
+	fields, err := ParseFields(msg.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0] != (Field{TagClOrdID, "order-1"}) || fields[1] != (Field{TagOrderQty, "5"}) {
+		t.Errorf("fields = %v, want ClOrdID and OrderQty", fields)
+	}
+
+	if _, err := ParseFields([]byte("11=order-1")); !errors.Is(err, ErrGarbled) {
+		t.Errorf("unterminated field: err = %v, want %v", err, ErrGarbled)
+	}
+}
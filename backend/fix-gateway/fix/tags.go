@@ -0,0 +1,151 @@
+package fix
+
+// BeginString is the protocol version the gateway speaks
+const BeginString = "FIX.4.4"
+
+// Tag numbers a FIX field
+type Tag int
+
+// Tags of the fields the gateway reads or writes
+const (
+	TagAvgPx                Tag = 6
+	TagBeginSeqNo           Tag = 7
+	TagBeginString          Tag = 8
+	TagBodyLength           Tag = 9
+	TagCheckSum             Tag = 10
+	TagClOrdID              Tag = 11
+	TagCumQty               Tag = 14
+	TagEndSeqNo             Tag = 16
+	TagExecID               Tag = 17
+	TagLastPx               Tag = 31
+	TagLastQty              Tag = 32
+	TagMsgSeqNum            Tag = 34
+	TagMsgType              Tag = 35
+	TagNewSeqNo             Tag = 36
+	TagOrderID              Tag = 37
+	TagOrderQty             Tag = 38
+	TagOrdStatus            Tag = 39
+	TagOrdType              Tag = 40
+	TagOrigClOrdID          Tag = 41
+	TagPossDupFlag          Tag = 43
+	TagPrice                Tag = 44
+	TagRefSeqNum            Tag = 45
+	TagSenderCompID         Tag = 49
+	TagSendingTime          Tag = 52
+	TagSide                 Tag = 54
+	TagSymbol               Tag = 55
+	TagTargetCompID         Tag = 56
+	TagText                 Tag = 58
+	TagTransactTime         Tag = 60
+	TagEncryptMethod        Tag = 98
+	TagCxlRejReason         Tag = 102
+	TagOrdRejReason         Tag = 103
+	TagHeartBtInt           Tag = 108
+	TagTestReqID            Tag = 112
+	TagOrigSendingTime      Tag = 122
+	TagGapFillFlag          Tag = 123
+	TagResetSeqNumFlag      Tag = 141
+	TagExecType             Tag = 150
+	TagLeavesQty            Tag = 151
+	TagRefTagID             Tag = 371
+	TagRefMsgType           Tag = 372
+	TagSessionRejectReason  Tag = 373
+	TagBusinessRejectReason Tag = 380
+	TagCxlRejResponseTo     Tag = 434
+	TagUsername             Tag = 553
+	TagPassword             Tag = 554
+)
+
+// Message types
+const (
+	MsgTypeHeartbeat                 = "0"
+	MsgTypeTestRequest               = "1"
+	MsgTypeResendRequest             = "2"
+	MsgTypeReject                    = "3"
+	MsgTypeSequenceReset             = "4"
+	MsgTypeLogout                    = "5"
+	MsgTypeExecutionReport           = "8"
+	MsgTypeOrderCancelReject         = "9"
+	MsgTypeLogon                     = "A"
+	MsgTypeNewOrderSingle            = "D"
+	MsgTypeOrderCancelRequest        = "F"
+	MsgTypeOrderCancelReplaceRequest = "G"
+	MsgTypeBusinessMessageReject     = "j"
+)
+
+// IsAdmin reports whether msgType is a session-level message type
+func IsAdmin(msgType string) bool {
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeTestRequest, MsgTypeResendRequest, MsgTypeReject,
+		MsgTypeSequenceReset, MsgTypeLogout, MsgTypeLogon:
+		return true
+	}
+	return false
+}
+
+// Side values
+const (
+	SideBuy  = "1"
+	SideSell = "2"
+)
+
+// OrdType values
+const (
+	OrdTypeMarket = "1"
+	OrdTypeLimit  = "2"
+)
+
+// ExecType values
+const (
+	ExecTypeNew      = "0"
+	ExecTypeCanceled = "4"
+	ExecTypeReplaced = "5"
+	ExecTypeRejected = "8"
+	ExecTypeTrade    = "F"
+)
+
+// OrdStatus values
+const (
+	OrdStatusNew             = "0"
+	OrdStatusPartiallyFilled = "1"
+	OrdStatusFilled          = "2"
+	OrdStatusCanceled        = "4"
+	OrdStatusRejected        = "8"
+)
+
+// OrdRejReason values
+const (
+	OrdRejReasonUnknownSymbol  = "1"
+	OrdRejReasonExchangeClosed = "2"
+	OrdRejReasonExceedsLimit   = "3"
+	OrdRejReasonDuplicateOrder = "6"
+	OrdRejReasonOther          = "99"
+)
+
+// CxlRejReason values
+const (
+	CxlRejReasonTooLate      = "0"
+	CxlRejReasonUnknownOrder = "1"
+	CxlRejReasonOther        = "99"
+)
+
+// CxlRejResponseTo values
+const (
+	CxlRejResponseToCancel        = "1"
+	CxlRejResponseToCancelReplace = "2"
+)
+
+// SessionRejectReason values
+const (
+	SessionRejectReasonInvalidTag          = "0"
+	SessionRejectReasonRequiredTagMissing  = "1"
+	SessionRejectReasonIncorrectValue      = "5"
+	SessionRejectReasonCompIDProblem       = "9"
+	SessionRejectReasonSendingTimeAccuracy = "10"
+	SessionRejectReasonInvalidMsgType      = "11"
+)
+
+// BusinessRejectReason values
+const (
+	BusinessRejectReasonUnsupportedMsgType = "3"
+)
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"lfg/shared/auth"
+	"lfg/shared/config"
+	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
+	"lfg/fix-gateway/repository"
+	"lfg/fix-gateway/session"
+	"lfg/fix-gateway/trading"
+	"lfg/matching-engine/engine"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+// workQueue is the NATS queue group sharing out the trades to report
+// across all instances
+const workQueue = "fix-gateway"
+
+var tracer = tracing.Tracer("lfg/fix-gateway")
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceFIXGateway, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Create context
+	ctx := context.Background()
+
+	// Initialize database connection
+	dbCfg := db.Config{
+		Host:            cfg.DBHost,
+		Port:            cfg.DBPort,
+		User:            cfg.DBUser,
+		Password:        cfg.DBPassword,
+		Database:        cfg.DBName,
+		SSLMode:         cfg.DBSSLMode,
+		MaxConns:        cfg.DBMaxConns,
+		MinConns:        cfg.DBMinConns,
+		MaxConnLifetime: 1 * time.Hour,
+		MaxConnIdleTime: 30 * time.Minute,
+	}
+
+	pool, err := db.NewPool(ctx, dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close(pool)
+	metrics.RegisterPool(pool)
+
+	log.Println("Connected to database successfully")
+
+	// Initialize repositories
+	sessionRepo := repository.NewSessionRepository(pool)
+	fixOrderRepo := repository.NewOrderRepository(pool)
+
+	// Initialize service authentication
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceFIXGateway, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+
+	// Orders are placed through the order-service trading API, for the user
+	// of each session; the connection is kept open as order flow is
+	// continuous
+	conn, err := grpc.NewClient(cfg.OrderServiceGRPC,
+		grpc.WithTransportCredentials(serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(serviceTokens.Credentials(auth.ServiceOrder)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to order service: %v", err)
+	}
+	defer conn.Close()
+
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+
+	// Initialize the FIX acceptor and the order entry application
+	keys := session.NewUserServiceKeyStore(cfg.UserServiceURL, serviceTokens)
+	acceptor := session.NewAcceptor(cfg.FIXCompID, keys, sessionRepo, cfg.FIXLogonTimeout, cfg.FIXMessageRetention)
+	app := trading.NewApplication(acceptor, sessionRepo, fixOrderRepo, tradingv1.NewTradingServiceClient(conn))
+	acceptor.SetApplication(app)
+	go acceptor.Run(backgroundCtx)
+
+	// Report the fills of resting FIX orders
+	natsConn, err := nats.Connect(cfg.NATSURL)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to NATS: %v", err)
+		log.Println("Continuing without NATS (fills of resting orders will not be reported)")
+	} else {
+		log.Printf("Connected to NATS at %s", cfg.NATSURL)
+		defer natsConn.Close()
+
+		_, err := natsConn.QueueSubscribe(engine.TradeSubjectPrefix+"*", workQueue, func(msg *nats.Msg) {
+			ctx, span := tracing.StartMessage(backgroundCtx, tracer, msg.Subject, msg.Header)
+			defer span.End()
+
+			app.HandleTrade(ctx, msg.Data)
+		})
+		if err != nil {
+			log.Printf("Failed to subscribe to trades: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", ":"+cfg.FIXPort)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	// Logons carry the secret of an API key, so sessions are served over TLS
+	switch {
+	case cfg.FIXTLSCertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.FIXTLSCertFile, cfg.FIXTLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load FIX TLS certificate: %v", err)
+		}
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	case cfg.FIXAllowPlaintext:
+		log.Println("Warning: Serving FIX sessions without TLS (API key secrets are sent in the clear)")
+	default:
+		log.Fatal("FIX_TLS_CERT_FILE and FIX_TLS_KEY_FILE are required unless FIX_ALLOW_PLAINTEXT is set")
+	}
+
+	go func() {
+		log.Printf("FIX gateway accepting sessions for %s on port %s...\n", cfg.FIXCompID, cfg.FIXPort)
+		if err := acceptor.Serve(backgroundCtx, listener); err != nil {
+			log.Fatalf("FIX acceptor failed: %v", err)
+		}
+	}()
+
+	// Serve health checks and metrics over HTTP
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", health)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
+
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      metrics.Handler(mux, mux),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	go func() {
+		log.Printf("FIX gateway HTTP listening on port %s...\n", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// Wait for interrupt
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down FIX gateway...")
+	stopBackground()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	acceptor.Shutdown(shutdownCtx)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	fmt.Println("FIX gateway exited")
+}
+
+// health reports that the gateway is up
+func health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrOrderNotFound    = errors.New("FIX order not found")
+	ErrDuplicateClOrdID = errors.New("duplicate ClOrdID")
+)
+
+const orderColumns = `order_id, session_id, cl_ord_id, orig_cl_ord_id, order_qty, cum_qty, cum_value, created_at`
+
+// Order is an order entered over FIX, corresponding to the "fix_orders"
+// table. OrderQty, CumQty and CumValue cover the order and the orders it
+// replaced, as FIX reports them.
+type Order struct {
+	OrderID     uuid.UUID
+	SessionID   uuid.UUID
+	ClOrdID     string
+	OrigClOrdID *string
+	OrderQty    int
+	CumQty      int
+	CumValue    float64
+	CreatedAt   time.Time
+}
+
+// AvgPx returns the average price the order was filled at
+func (o *Order) AvgPx() float64 {
+	if o.CumQty == 0 {
+		return 0
+	}
+	return o.CumValue / float64(o.CumQty)
+}
+
+// OrderRepository handles FIX order database operations
+type OrderRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewOrderRepository creates a new FIX order repository
+func NewOrderRepository(pool *pgxpool.Pool) *OrderRepository {
+	return &OrderRepository{pool: pool}
+}
+
+func scanOrder(row pgx.Row, order *Order) error {
+	return row.Scan(
+		&order.OrderID,
+		&order.SessionID,
+		&order.ClOrdID,
+		&order.OrigClOrdID,
+		&order.OrderQty,
+		&order.CumQty,
+		&order.CumValue,
+		&order.CreatedAt,
+	)
+}
+
+// Create records the ClOrdID a session gave an order
+func (r *OrderRepository) Create(ctx context.Context, order *Order) error {
+	err := scanOrder(r.pool.QueryRow(ctx, `
+		INSERT INTO fix_orders (order_id, session_id, cl_ord_id, orig_cl_ord_id, order_qty, cum_qty, cum_value, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING `+orderColumns,
+		order.OrderID, order.SessionID, order.ClOrdID, order.OrigClOrdID, order.OrderQty, order.CumQty, order.CumValue,
+	), order)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateClOrdID
+		}
+		return fmt.Errorf("failed to create FIX order: %w", err)
+	}
+	return nil
+}
+
+// GetByClOrdID retrieves the order a session gave clOrdID
+func (r *OrderRepository) GetByClOrdID(ctx context.Context, sessionID uuid.UUID, clOrdID string) (*Order, error) {
+	var order Order
+	err := scanOrder(r.pool.QueryRow(ctx, `
+		SELECT `+orderColumns+` FROM fix_orders WHERE session_id = $1 AND cl_ord_id = $2
+	`, sessionID, clOrdID), &order)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get FIX order: %w", err)
+	}
+	return &order, nil
+}
+
+// GetByOrderID retrieves the FIX order of a platform order
+func (r *OrderRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	var order Order
+	err := scanOrder(r.pool.QueryRow(ctx, `
+		SELECT `+orderColumns+` FROM fix_orders WHERE order_id = $1
+	`, orderID), &order)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get FIX order: %w", err)
+	}
+	return &order, nil
+}
+
+// AddFill adds an execution to the filled quantity and value of an order,
+// returning the updated order
+func (r *OrderRepository) AddFill(ctx context.Context, orderID uuid.UUID, quantity int, price float64) (*Order, error) {
+	var order Order
+	err := scanOrder(r.pool.QueryRow(ctx, `
+		UPDATE fix_orders
+		SET cum_qty = cum_qty + $2, cum_value = cum_value + $2 * $3::DECIMAL
+		WHERE order_id = $1
+		RETURNING `+orderColumns,
+		orderID, quantity, price,
+	), &order)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to record FIX order fill: %w", err)
+	}
+	return &order, nil
+}
+
+// CarryFills adds to a replacement order what the order it replaced filled
+// since the replacement was recorded with carriedQty and carriedValue,
+// returning the updated replacement
+func (r *OrderRepository) CarryFills(ctx context.Context, replacedID, replacementID uuid.UUID, carriedQty int, carriedValue float64) (*Order, error) {
+	var order Order
+	err := scanOrder(r.pool.QueryRow(ctx, `
+		UPDATE fix_orders o
+		SET cum_qty = o.cum_qty + r.cum_qty - $3, cum_value = o.cum_value + r.cum_value - $4::DECIMAL
+		FROM fix_orders r
+		WHERE o.order_id = $2 AND r.order_id = $1
+		RETURNING o.order_id, o.session_id, o.cl_ord_id, o.orig_cl_ord_id, o.order_qty, o.cum_qty, o.cum_value, o.created_at`,
+		replacedID, replacementID, carriedQty, carriedValue,
+	), &order)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to carry FIX order fills: %w", err)
+	}
+	return &order, nil
+}
+
+// Delete removes an order that was never placed, freeing its ClOrdID
+func (r *OrderRepository) Delete(ctx context.Context, orderID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM fix_orders WHERE order_id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete FIX order: %w", err)
+	}
+	return nil
+}
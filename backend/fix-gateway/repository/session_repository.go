@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSessionNotFound = errors.New("FIX session not found")
+	ErrSessionOwned    = errors.New("FIX session belongs to another user")
+)
+
+const sessionColumns = `id, sender_comp_id, target_comp_id, user_id, next_sender_seq, next_target_seq`
+
+// Session is the persisted state of a FIX session, corresponding to the
+// "fix_sessions" table. SenderCompID is the counterparty's comp ID and
+// TargetCompID the gateway's, as they appear on incoming messages.
+type Session struct {
+	ID            uuid.UUID
+	SenderCompID  string
+	TargetCompID  string
+	UserID        uuid.UUID
+	NextSenderSeq int
+	NextTargetSeq int
+}
+
+// StoredMessage is a message sent to a session, kept for resends
+type StoredMessage struct {
+	SeqNum  int
+	MsgType string
+	Body    []byte
+	SentAt  time.Time
+}
+
+// SessionRepository handles FIX session database operations
+type SessionRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewSessionRepository creates a new FIX session repository
+func NewSessionRepository(pool *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{pool: pool}
+}
+
+func scanSession(row pgx.Row, session *Session) error {
+	return row.Scan(
+		&session.ID,
+		&session.SenderCompID,
+		&session.TargetCompID,
+		&session.UserID,
+		&session.NextSenderSeq,
+		&session.NextTargetSeq,
+	)
+}
+
+// GetOrCreate returns the session between the comp IDs, creating it for
+// userID if it does not exist. A session stays with the user who created it.
+func (r *SessionRepository) GetOrCreate(ctx context.Context, senderCompID, targetCompID string, userID uuid.UUID) (*Session, error) {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO fix_sessions (sender_comp_id, target_comp_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (sender_comp_id, target_comp_id) DO NOTHING
+	`, senderCompID, targetCompID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FIX session: %w", err)
+	}
+
+	var session Session
+	err = scanSession(r.pool.QueryRow(ctx, `
+		SELECT `+sessionColumns+` FROM fix_sessions
+		WHERE sender_comp_id = $1 AND target_comp_id = $2
+	`, senderCompID, targetCompID), &session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FIX session: %w", err)
+	}
+
+	if session.UserID != userID {
+		return nil, ErrSessionOwned
+	}
+	return &session, nil
+}
+
+// GetByID retrieves a session by ID
+func (r *SessionRepository) GetByID(ctx context.Context, sessionID uuid.UUID) (*Session, error) {
+	var session Session
+	err := scanSession(r.pool.QueryRow(ctx, `
+		SELECT `+sessionColumns+` FROM fix_sessions WHERE id = $1
+	`, sessionID), &session)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get FIX session: %w", err)
+	}
+	return &session, nil
+}
+
+// ResetSequences starts both sequences of a session over at 1 and forgets
+// the messages sent to it
+func (r *SessionRepository) ResetSequences(ctx context.Context, sessionID uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM fix_messages WHERE session_id = $1`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete FIX messages: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE fix_sessions
+		SET next_sender_seq = 1, next_target_seq = 1, updated_at = NOW()
+		WHERE id = $1
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to reset FIX session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SetNextTargetSeq records the sequence number expected on the next message
+// from the counterparty
+func (r *SessionRepository) SetNextTargetSeq(ctx context.Context, sessionID uuid.UUID, seqNum int) error {
+	result, err := r.pool.Exec(ctx, `
+		UPDATE fix_sessions SET next_target_seq = $2, updated_at = NOW() WHERE id = $1
+	`, sessionID, seqNum)
+	if err != nil {
+		return fmt.Errorf("failed to update FIX session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// AppendMessage assigns the next outgoing sequence number of a session to a
+// message and stores it, returning the sequence number
+func (r *SessionRepository) AppendMessage(ctx context.Context, sessionID uuid.UUID, msgType string, body []byte, sentAt time.Time) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var seqNum int
+	err = tx.QueryRow(ctx, `
+		UPDATE fix_sessions
+		SET next_sender_seq = next_sender_seq + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING next_sender_seq - 1
+	`, sessionID).Scan(&seqNum)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrSessionNotFound
+		}
+		return 0, fmt.Errorf("failed to assign FIX sequence number: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO fix_messages (session_id, seq_num, msg_type, body, sent_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, sessionID, seqNum, msgType, body, sentAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store FIX message: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return seqNum, nil
+}
+
+// GetMessages returns the stored messages of a session from beginSeq to
+// endSeq, or to the last one if endSeq is 0, in sequence order
+func (r *SessionRepository) GetMessages(ctx context.Context, sessionID uuid.UUID, beginSeq, endSeq int) ([]StoredMessage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT seq_num, msg_type, body, sent_at
+		FROM fix_messages
+		WHERE session_id = $1 AND seq_num >= $2 AND ($3 = 0 OR seq_num <= $3)
+		ORDER BY seq_num
+	`, sessionID, beginSeq, endSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FIX messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []StoredMessage{}
+	for rows.Next() {
+		var msg StoredMessage
+		if err := rows.Scan(&msg.SeqNum, &msg.MsgType, &msg.Body, &msg.SentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan FIX message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get FIX messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// PurgeMessages deletes messages sent before cutoff, returning how many it
+// deleted. Resend requests for them are answered with gap fills.
+func (r *SessionRepository) PurgeMessages(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM fix_messages WHERE sent_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge FIX messages: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
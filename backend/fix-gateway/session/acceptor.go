@@ -0,0 +1,345 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/fix-gateway/fix"
+	"lfg/fix-gateway/repository"
+)
+
+const (
+	// maxHeartbeat bounds the heartbeat interval a counterparty can ask for
+	maxHeartbeat = 300
+
+	// purgeInterval is how often messages past their retention are deleted
+	purgeInterval = time.Hour
+)
+
+// SessionStore keeps the state of sessions and the messages sent to them
+type SessionStore interface {
+	GetOrCreate(ctx context.Context, senderCompID, targetCompID string, userID uuid.UUID) (*repository.Session, error)
+	GetByID(ctx context.Context, sessionID uuid.UUID) (*repository.Session, error)
+	ResetSequences(ctx context.Context, sessionID uuid.UUID) error
+	SetNextTargetSeq(ctx context.Context, sessionID uuid.UUID, seqNum int) error
+	AppendMessage(ctx context.Context, sessionID uuid.UUID, msgType string, body []byte, sentAt time.Time) (int, error)
+	GetMessages(ctx context.Context, sessionID uuid.UUID, beginSeq, endSeq int) ([]repository.StoredMessage, error)
+	PurgeMessages(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Application handles the application messages of logged on sessions
+type Application interface {
+	FromApp(ctx context.Context, s *Session, msg *fix.Message)
+}
+
+// Acceptor accepts FIX 4.4 connections, which are to be served over TLS.
+// Counterparties log on with an API key, Username carrying the key ID and
+// Password its secret; the key needs the trade scope and must allow the
+// address connecting. Each pair of comp IDs is one session, belonging to
+// the user whose key first logged it on, and can only be logged on once at
+// a time.
+type Acceptor struct {
+	compID       string
+	keys         KeyStore
+	sessions     SessionStore
+	logonTimeout time.Duration
+	retention    time.Duration
+	app          Application
+
+	mu        sync.Mutex
+	connected map[uuid.UUID]*Session
+}
+
+// NewAcceptor creates a new acceptor for sessions addressed to compID
+func NewAcceptor(compID string, keys KeyStore, sessions SessionStore, logonTimeout, retention time.Duration) *Acceptor {
+	return &Acceptor{
+		compID:       compID,
+		keys:         keys,
+		sessions:     sessions,
+		logonTimeout: logonTimeout,
+		retention:    retention,
+		connected:    make(map[uuid.UUID]*Session),
+	}
+}
+
+// SetApplication sets the handler of application messages
+func (a *Acceptor) SetApplication(app Application) {
+	a.app = app
+}
+
+// Serve accepts connections on listener until ctx is cancelled
+func (a *Acceptor) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		go a.handle(ctx, conn)
+	}
+}
+
+// Shutdown logs out every logged on session and closes its connection
+func (a *Acceptor) Shutdown(ctx context.Context) {
+	a.mu.Lock()
+	sessions := make([]*Session, 0, len(a.connected))
+	for _, s := range a.connected {
+		sessions = append(sessions, s)
+	}
+	a.mu.Unlock()
+
+	for _, s := range sessions {
+		s.logout(ctx, "Gateway shutting down")
+		s.close()
+	}
+}
+
+// Run deletes stored messages past their retention periodically until ctx
+// is cancelled
+func (a *Acceptor) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.sessions.PurgeMessages(ctx, time.Now().Add(-a.retention)); err != nil {
+				log.Printf("Failed to purge FIX messages: %v", err)
+			}
+		}
+	}
+}
+
+// Send sends a message to a session. If the session is not logged on the
+// message is numbered and stored, and the counterparty gets it by asking
+// for a resend once it logs on again.
+func (a *Acceptor) Send(ctx context.Context, sessionID uuid.UUID, msg *fix.Message) error {
+	a.mu.Lock()
+	s := a.connected[sessionID]
+	a.mu.Unlock()
+
+	if s != nil {
+		return s.Send(ctx, msg)
+	}
+
+	_, err := a.sessions.AppendMessage(ctx, sessionID, msg.Type(), msg.Body(), time.Now())
+	return err
+}
+
+// handle logs a connection on and runs its session
+func (a *Acceptor) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	s, err := a.logon(ctx, conn)
+	if err != nil {
+		log.Printf("FIX logon from %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	log.Printf("FIX session %s logged on for user %s", s, s.UserID())
+	s.run(ctx)
+	log.Printf("FIX session %s logged off", s)
+}
+
+// logon reads and answers the Logon that has to open a connection
+func (a *Acceptor) logon(ctx context.Context, conn net.Conn) (*Session, error) {
+	reader := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(a.logonTimeout))
+	msg, err := fix.Read(reader)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if msg.Type() != fix.MsgTypeLogon {
+		return nil, fmt.Errorf("first message was %q, not a logon", msg.Type())
+	}
+
+	senderCompID := msg.Get(fix.TagSenderCompID)
+	targetCompID := msg.Get(fix.TagTargetCompID)
+	refuse := func(reason string) (*Session, error) {
+		a.refuse(conn, msg, reason)
+		return nil, errors.New(reason)
+	}
+
+	seqNum, err := msg.Int(fix.TagMsgSeqNum)
+	if err != nil || seqNum < 1 {
+		return refuse("MsgSeqNum missing or invalid")
+	}
+	if senderCompID == "" || len(senderCompID) > 64 || targetCompID != a.compID {
+		return refuse("Unknown SenderCompID or TargetCompID")
+	}
+	if encryptMethod := msg.Get(fix.TagEncryptMethod); encryptMethod != "0" {
+		return refuse("EncryptMethod must be 0")
+	}
+	heartbeat, err := msg.Int(fix.TagHeartBtInt)
+	if err != nil || heartbeat < 1 || heartbeat > maxHeartbeat {
+		return refuse(fmt.Sprintf("HeartBtInt must be between 1 and %d", maxHeartbeat))
+	}
+
+	userID, err := a.authenticate(ctx, conn, msg.Get(fix.TagUsername), msg.Get(fix.TagPassword))
+	if err != nil {
+		return refuse(err.Error())
+	}
+
+	state, err := a.sessions.GetOrCreate(ctx, senderCompID, targetCompID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionOwned) {
+			return refuse("Session belongs to another user")
+		}
+		log.Printf("Failed to get FIX session %s->%s: %v", senderCompID, targetCompID, err)
+		return refuse("Session unavailable")
+	}
+
+	s := &Session{
+		acceptor:  a,
+		conn:      conn,
+		reader:    reader,
+		state:     state,
+		heartbeat: time.Duration(heartbeat) * time.Second,
+		done:      make(chan struct{}),
+	}
+	if !a.register(s) {
+		return refuse("Session is already logged on")
+	}
+	refuse = func(reason string) (*Session, error) {
+		a.refuse(conn, msg, reason)
+		a.unregister(s)
+		return nil, errors.New(reason)
+	}
+
+	reset := msg.Bool(fix.TagResetSeqNumFlag)
+	if reset {
+		if seqNum != 1 {
+			return refuse("MsgSeqNum must be 1 when resetting sequence numbers")
+		}
+		if err := a.sessions.ResetSequences(ctx, state.ID); err != nil {
+			log.Printf("Failed to reset FIX session %s->%s: %v", senderCompID, targetCompID, err)
+			return refuse("Session unavailable")
+		}
+		state.NextSenderSeq, state.NextTargetSeq = 1, 1
+	} else if seqNum < state.NextTargetSeq {
+		return refuse(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", state.NextTargetSeq, seqNum))
+	}
+
+	s.nextTargetSeq = state.NextTargetSeq
+	now := time.Now().UnixNano()
+	s.lastSent.Store(now)
+	s.lastReceived.Store(now)
+
+	response := fix.New(fix.MsgTypeLogon).
+		Add(fix.TagEncryptMethod, "0").
+		AddInt(fix.TagHeartBtInt, heartbeat)
+	if reset {
+		response.Add(fix.TagResetSeqNumFlag, "Y")
+	}
+	if err := s.Send(ctx, response); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	// The logon is numbered like any message: if the counterparty is ahead,
+	// ask for what is missing
+	if seqNum == s.nextTargetSeq {
+		s.advance(ctx, seqNum+1)
+	} else {
+		s.gap(ctx, msg, seqNum)
+	}
+
+	return s, nil
+}
+
+// authenticate checks the API key a counterparty logs on with, returning
+// the user it belongs to
+func (a *Acceptor) authenticate(ctx context.Context, conn net.Conn, keyID, secret string) (uuid.UUID, error) {
+	if keyID == "" || secret == "" {
+		return uuid.Nil, errors.New("Username and Password are required")
+	}
+
+	creds, err := a.keys.Credentials(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return uuid.Nil, errors.New("Invalid API key")
+		}
+		log.Printf("Failed to look up API key %s: %v", keyID, err)
+		return uuid.Nil, errors.New("Failed to verify API key")
+	}
+	key := creds.Key
+
+	if subtle.ConstantTimeCompare([]byte(key.Secret), []byte(secret)) != 1 {
+		return uuid.Nil, errors.New("Invalid API key")
+	}
+	if !key.Usable(time.Now()) {
+		return uuid.Nil, errors.New("API key has expired or been revoked")
+	}
+
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil || !key.AllowsIP(host) {
+		return uuid.Nil, errors.New("Connections from this address are not allowed for this API key")
+	}
+	if !key.HasScope(models.APIKeyScopeTrade) {
+		return uuid.Nil, errors.New("API key lacks the trade scope")
+	}
+
+	return key.UserID, nil
+}
+
+// refuse answers a logon that is not accepted with a Logout. No session is
+// established, so it is neither numbered in nor stored with one.
+func (a *Acceptor) refuse(conn net.Conn, logon *fix.Message, reason string) {
+	logout := fix.New(fix.MsgTypeLogout).
+		Add(fix.TagSenderCompID, a.compID).
+		Add(fix.TagTargetCompID, logon.Get(fix.TagSenderCompID)).
+		AddInt(fix.TagMsgSeqNum, 1).
+		AddTime(fix.TagSendingTime, time.Now()).
+		Add(fix.TagText, reason)
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	conn.Write(logout.Bytes())
+}
+
+// register records a session as logged on, returning false if it already is
+func (a *Acceptor) register(s *Session) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.connected[s.ID()]; ok {
+		return false
+	}
+	a.connected[s.ID()] = s
+	return true
+}
+
+func (a *Acceptor) unregister(s *Session) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.connected[s.ID()] == s {
+		delete(a.connected, s.ID())
+	}
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+)
+
+// ErrKeyNotFound is returned by a KeyStore for unknown keys and keys of
+// inactive accounts
+var ErrKeyNotFound = errors.New("API key not found")
+
+// KeyStore looks up the API keys sessions log on with
+type KeyStore interface {
+	Credentials(ctx context.Context, keyID string) (*models.APIKeyCredentials, error)
+}
+
+// UserServiceKeyStore looks up API keys in user-service. Logons are rare
+// enough that keys are not cached, so a revoked key cannot log on again.
+type UserServiceKeyStore struct {
+	credentialsURL string
+	serviceTokens  *auth.ServiceTokens
+	client         *http.Client
+}
+
+// NewUserServiceKeyStore creates an API key store backed by user-service
+func NewUserServiceKeyStore(userServiceURL string, serviceTokens *auth.ServiceTokens) *UserServiceKeyStore {
+	return &UserServiceKeyStore{
+		credentialsURL: userServiceURL + "/internal/api-keys/credentials",
+		serviceTokens:  serviceTokens,
+		client: &http.Client{
+			Transport: tracing.Transport(http.DefaultTransport),
+			Timeout:   5 * time.Second,
+		},
+	}
+}
+
+// Credentials implements KeyStore
+func (s *UserServiceKeyStore) Credentials(ctx context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.credentialsURL+"?key_id="+url.QueryEscape(keyID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.serviceTokens.Sign(req, auth.ServiceUser); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach user service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var creds models.APIKeyCredentials
+	if err := json.NewDecoder(resp.Body).Decode(&creds); err != nil {
+		return nil, fmt.Errorf("failed to decode API key: %w", err)
+	}
+	if creds.Key == nil {
+		return nil, fmt.Errorf("user service returned no API key")
+	}
+
+	return &creds, nil
+}
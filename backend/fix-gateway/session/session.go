@@ -0,0 +1,438 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/fix-gateway/fix"
+	"lfg/fix-gateway/repository"
+)
+
+// writeTimeout bounds how long a write to a counterparty can block
+const writeTimeout = 10 * time.Second
+
+// Session is a logged on FIX session over one connection. Incoming messages
+// are handled one at a time by the goroutine reading the connection;
+// outgoing ones can be sent from any goroutine and are numbered and stored
+// before they are written, so they can be resent.
+type Session struct {
+	acceptor  *Acceptor
+	conn      net.Conn
+	reader    *bufio.Reader
+	state     *repository.Session
+	heartbeat time.Duration
+
+	// nextTargetSeq and resendRequested are only used by the reading goroutine
+	nextTargetSeq   int
+	resendRequested bool
+
+	writeMu      sync.Mutex
+	lastSent     atomic.Int64
+	lastReceived atomic.Int64
+	testReqID    atomic.Value
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// ID returns the ID of the persisted session
+func (s *Session) ID() uuid.UUID {
+	return s.state.ID
+}
+
+// UserID returns the user the session trades for
+func (s *Session) UserID() uuid.UUID {
+	return s.state.UserID
+}
+
+// String identifies the session in logs
+func (s *Session) String() string {
+	return s.state.SenderCompID + "->" + s.state.TargetCompID
+}
+
+// Send numbers, stores and writes a message created with fix.New. The
+// message is kept for resends even if it cannot be written.
+func (s *Session) Send(ctx context.Context, msg *fix.Message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	now := time.Now()
+	seqNum, err := s.acceptor.sessions.AppendMessage(ctx, s.state.ID, msg.Type(), msg.Body(), now)
+	if err != nil {
+		return err
+	}
+	return s.write(s.header(msg.Type(), seqNum, now), msg)
+}
+
+// Reject sends a session-level Reject of msg
+func (s *Session) Reject(ctx context.Context, msg *fix.Message, reason string, refTag fix.Tag, text string) {
+	reject := fix.New(fix.MsgTypeReject).
+		Add(fix.TagRefSeqNum, msg.Get(fix.TagMsgSeqNum)).
+		Add(fix.TagRefMsgType, msg.Type()).
+		Add(fix.TagSessionRejectReason, reason)
+	if refTag != 0 {
+		reject.AddInt(fix.TagRefTagID, int(refTag))
+	}
+	reject.Add(fix.TagText, text)
+
+	if err := s.Send(ctx, reject); err != nil {
+		log.Printf("Failed to send FIX reject to %s: %v", s, err)
+	}
+}
+
+// header starts an outgoing message with the standard header
+func (s *Session) header(msgType string, seqNum int, sendingTime time.Time) *fix.Message {
+	return fix.New(msgType).
+		Add(fix.TagSenderCompID, s.state.TargetCompID).
+		Add(fix.TagTargetCompID, s.state.SenderCompID).
+		AddInt(fix.TagMsgSeqNum, seqNum).
+		AddTime(fix.TagSendingTime, sendingTime)
+}
+
+// write writes header and then the body of msg to the connection; callers
+// hold writeMu
+func (s *Session) write(header, msg *fix.Message) error {
+	if msg != nil {
+		header.Fields = append(header.Fields, msg.Fields[1:]...)
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.conn.Write(header.Bytes()); err != nil {
+		s.close()
+		return fmt.Errorf("failed to write FIX message: %w", err)
+	}
+	s.lastSent.Store(time.Now().UnixNano())
+	return nil
+}
+
+// run reads and handles messages until the connection closes or the
+// session ends
+func (s *Session) run(ctx context.Context) {
+	go s.monitor(ctx)
+
+	for {
+		msg, err := fix.Read(s.reader)
+		if errors.Is(err, fix.ErrGarbled) {
+			log.Printf("Ignoring garbled FIX message from %s", s)
+			continue
+		}
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				log.Printf("FIX session %s disconnected: %v", s, err)
+			}
+			s.close()
+			return
+		}
+
+		// Any message shows the counterparty is alive, answering a pending
+		// test request
+		s.lastReceived.Store(time.Now().UnixNano())
+		s.testReqID.Store("")
+
+		if !s.handle(ctx, msg) {
+			s.close()
+			return
+		}
+	}
+}
+
+// monitor sends heartbeats when the session is idle, and test requests when
+// the counterparty is. A counterparty that sends nothing within a heartbeat
+// interval of a test request is disconnected.
+func (s *Session) monitor(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Allow for transmission delays before testing the counterparty
+	grace := s.heartbeat + s.heartbeat/5
+	var testSentAt time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			lastReceived := time.Unix(0, s.lastReceived.Load())
+			pending, _ := s.testReqID.Load().(string)
+
+			switch {
+			case pending != "" && now.Sub(testSentAt) > grace:
+				log.Printf("FIX session %s did not answer test request; disconnecting", s)
+				s.close()
+				return
+			case pending == "" && now.Sub(lastReceived) > grace:
+				testReqID := strconv.FormatInt(now.UnixNano(), 10)
+				s.testReqID.Store(testReqID)
+				testSentAt = now
+				if err := s.Send(ctx, fix.New(fix.MsgTypeTestRequest).Add(fix.TagTestReqID, testReqID)); err != nil {
+					log.Printf("Failed to send FIX test request to %s: %v", s, err)
+				}
+			case now.Sub(time.Unix(0, s.lastSent.Load())) >= s.heartbeat:
+				if err := s.Send(ctx, fix.New(fix.MsgTypeHeartbeat)); err != nil {
+					log.Printf("Failed to send FIX heartbeat to %s: %v", s, err)
+				}
+			}
+		}
+	}
+}
+
+// handle handles a message received after logon, returning false when the
+// session has to end
+func (s *Session) handle(ctx context.Context, msg *fix.Message) bool {
+	if msg.Get(fix.TagSenderCompID) != s.state.SenderCompID || msg.Get(fix.TagTargetCompID) != s.state.TargetCompID {
+		s.Reject(ctx, msg, fix.SessionRejectReasonCompIDProblem, fix.TagSenderCompID, "CompID problem")
+		s.logout(ctx, "CompID problem")
+		return false
+	}
+
+	seqNum, err := msg.Int(fix.TagMsgSeqNum)
+	if err != nil {
+		s.logout(ctx, "MsgSeqNum missing or invalid")
+		return false
+	}
+
+	// A SequenceReset in reset mode moves the sequence whatever its number
+	if msg.Type() == fix.MsgTypeSequenceReset && !msg.Bool(fix.TagGapFillFlag) {
+		return s.sequenceReset(ctx, msg, seqNum)
+	}
+
+	switch {
+	case seqNum > s.nextTargetSeq:
+		return s.gap(ctx, msg, seqNum)
+	case seqNum < s.nextTargetSeq:
+		if msg.Bool(fix.TagPossDupFlag) {
+			// Already handled
+			return true
+		}
+		s.logout(ctx, fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", s.nextTargetSeq, seqNum))
+		return false
+	}
+
+	if !msg.Bool(fix.TagPossDupFlag) {
+		s.resendRequested = false
+	}
+
+	keep := true
+	switch msg.Type() {
+	case fix.MsgTypeHeartbeat:
+	case fix.MsgTypeTestRequest:
+		heartbeat := fix.New(fix.MsgTypeHeartbeat).Add(fix.TagTestReqID, msg.Get(fix.TagTestReqID))
+		if err := s.Send(ctx, heartbeat); err != nil {
+			log.Printf("Failed to send FIX heartbeat to %s: %v", s, err)
+		}
+	case fix.MsgTypeResendRequest:
+		s.resend(ctx, msg)
+	case fix.MsgTypeReject:
+		log.Printf("FIX session %s rejected message %s: %s", s, msg.Get(fix.TagRefSeqNum), msg.Get(fix.TagText))
+	case fix.MsgTypeSequenceReset:
+		// Gap fill: the counterparty skips administrative messages
+		return s.gapFill(ctx, msg, seqNum)
+	case fix.MsgTypeLogout:
+		s.logout(ctx, "")
+		keep = false
+	case fix.MsgTypeLogon:
+		s.logout(ctx, "Session is already logged on")
+		keep = false
+	default:
+		s.acceptor.app.FromApp(ctx, s, msg)
+	}
+
+	s.advance(ctx, seqNum+1)
+	return keep
+}
+
+// gap handles a message numbered beyond the next expected one by asking the
+// counterparty to resend what is missing. The message itself is resent with
+// the rest, so it is only handled now if it cannot wait.
+func (s *Session) gap(ctx context.Context, msg *fix.Message, seqNum int) bool {
+	switch msg.Type() {
+	case fix.MsgTypeResendRequest:
+		s.resend(ctx, msg)
+	case fix.MsgTypeLogout:
+		s.logout(ctx, "")
+		return false
+	}
+
+	if !s.resendRequested {
+		resendRequest := fix.New(fix.MsgTypeResendRequest).
+			AddInt(fix.TagBeginSeqNo, s.nextTargetSeq).
+			AddInt(fix.TagEndSeqNo, 0)
+		if err := s.Send(ctx, resendRequest); err != nil {
+			log.Printf("Failed to send FIX resend request to %s: %v", s, err)
+			return false
+		}
+		s.resendRequested = true
+	}
+	return true
+}
+
+// gapFill handles a SequenceReset in gap fill mode
+func (s *Session) gapFill(ctx context.Context, msg *fix.Message, seqNum int) bool {
+	newSeqNo, err := msg.Int(fix.TagNewSeqNo)
+	if err != nil {
+		s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, fix.TagNewSeqNo, "NewSeqNo missing or invalid")
+		s.advance(ctx, seqNum+1)
+		return true
+	}
+	if newSeqNo <= seqNum {
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagNewSeqNo, "Attempt to lower sequence number")
+		s.advance(ctx, seqNum+1)
+		return true
+	}
+
+	s.advance(ctx, newSeqNo)
+	return true
+}
+
+// sequenceReset handles a SequenceReset in reset mode
+func (s *Session) sequenceReset(ctx context.Context, msg *fix.Message, seqNum int) bool {
+	newSeqNo, err := msg.Int(fix.TagNewSeqNo)
+	if err != nil {
+		s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, fix.TagNewSeqNo, "NewSeqNo missing or invalid")
+		return true
+	}
+	if newSeqNo < s.nextTargetSeq {
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagNewSeqNo, "Attempt to lower sequence number")
+		return true
+	}
+
+	s.resendRequested = false
+	s.advance(ctx, newSeqNo)
+	return true
+}
+
+// advance sets the sequence number expected on the next incoming message
+func (s *Session) advance(ctx context.Context, nextSeq int) {
+	s.nextTargetSeq = nextSeq
+	if err := s.acceptor.sessions.SetNextTargetSeq(ctx, s.state.ID, nextSeq); err != nil {
+		log.Printf("Failed to store FIX sequence number of %s: %v", s, err)
+	}
+}
+
+// resend answers a ResendRequest. Application messages are sent again as
+// possible duplicates; administrative messages, and messages no longer kept,
+// are skipped with gap fills.
+func (s *Session) resend(ctx context.Context, msg *fix.Message) {
+	beginSeq, err := msg.Int(fix.TagBeginSeqNo)
+	if err != nil || beginSeq < 1 {
+		s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, fix.TagBeginSeqNo, "BeginSeqNo missing or invalid")
+		return
+	}
+	endSeq, err := msg.Int(fix.TagEndSeqNo)
+	if err != nil || endSeq < 0 || (endSeq != 0 && endSeq < beginSeq) {
+		s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, fix.TagEndSeqNo, "EndSeqNo missing or invalid")
+		return
+	}
+
+	// Hold back new messages until the resend is done, so they follow it
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	state, err := s.acceptor.sessions.GetByID(ctx, s.state.ID)
+	if err != nil {
+		log.Printf("Failed to get FIX session %s: %v", s, err)
+		return
+	}
+	lastSeq := state.NextSenderSeq - 1
+	if endSeq == 0 || endSeq > lastSeq {
+		endSeq = lastSeq
+	}
+	if beginSeq > endSeq {
+		return
+	}
+
+	stored, err := s.acceptor.sessions.GetMessages(ctx, s.state.ID, beginSeq, endSeq)
+	if err != nil {
+		log.Printf("Failed to get FIX messages of %s: %v", s, err)
+		return
+	}
+
+	now := time.Now()
+	gapStart := 0
+	fillGap := func(nextSeq int) error {
+		if gapStart == 0 {
+			return nil
+		}
+		header := s.header(fix.MsgTypeSequenceReset, gapStart, now).
+			Add(fix.TagPossDupFlag, "Y").
+			AddTime(fix.TagOrigSendingTime, now).
+			Add(fix.TagGapFillFlag, "Y").
+			AddInt(fix.TagNewSeqNo, nextSeq)
+		gapStart = 0
+		return s.write(header, nil)
+	}
+
+	next := 0
+	for seq := beginSeq; seq <= endSeq; seq++ {
+		for next < len(stored) && stored[next].SeqNum < seq {
+			next++
+		}
+
+		if next >= len(stored) || stored[next].SeqNum != seq || fix.IsAdmin(stored[next].MsgType) {
+			if gapStart == 0 {
+				gapStart = seq
+			}
+			continue
+		}
+
+		if err := fillGap(seq); err != nil {
+			log.Printf("Failed to resend FIX messages to %s: %v", s, err)
+			return
+		}
+
+		fields, err := fix.ParseFields(stored[next].Body)
+		if err != nil {
+			log.Printf("Failed to decode stored FIX message %d of %s: %v", seq, s, err)
+			gapStart = seq
+			continue
+		}
+
+		header := s.header(stored[next].MsgType, seq, now).
+			Add(fix.TagPossDupFlag, "Y").
+			AddTime(fix.TagOrigSendingTime, stored[next].SentAt)
+		header.Fields = append(header.Fields, fields...)
+		if err := s.write(header, nil); err != nil {
+			log.Printf("Failed to resend FIX messages to %s: %v", s, err)
+			return
+		}
+	}
+
+	if err := fillGap(endSeq + 1); err != nil {
+		log.Printf("Failed to resend FIX messages to %s: %v", s, err)
+	}
+}
+
+// logout sends a Logout, with text explaining why if the gateway ends the
+// session
+func (s *Session) logout(ctx context.Context, text string) {
+	logout := fix.New(fix.MsgTypeLogout)
+	if text != "" {
+		logout.Add(fix.TagText, text)
+	}
+	if err := s.Send(ctx, logout); err != nil {
+		log.Printf("Failed to send FIX logout to %s: %v", s, err)
+	}
+}
+
+// close closes the connection and unregisters the session
+func (s *Session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+		s.acceptor.unregister(s)
+	})
+}
@@ -0,0 +1,417 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/fix-gateway/fix"
+	"lfg/fix-gateway/repository"
+)
+
+// memoryStore keeps sessions and their messages in memory
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*repository.Session
+	messages map[uuid.UUID][]repository.StoredMessage
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		sessions: make(map[uuid.UUID]*repository.Session),
+		messages: make(map[uuid.UUID][]repository.StoredMessage),
+	}
+}
+
+func (s *memoryStore) GetOrCreate(_ context.Context, senderCompID, targetCompID string, userID uuid.UUID) (*repository.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if session.SenderCompID == senderCompID && session.TargetCompID == targetCompID {
+			if session.UserID != userID {
+				return nil, repository.ErrSessionOwned
+			}
+			state := *session
+			return &state, nil
+		}
+	}
+
+	session := &repository.Session{
+		ID:            uuid.New(),
+		SenderCompID:  senderCompID,
+		TargetCompID:  targetCompID,
+		UserID:        userID,
+		NextSenderSeq: 1,
+		NextTargetSeq: 1,
+	}
+	s.sessions[session.ID] = session
+	state := *session
+	return &state, nil
+}
+
+func (s *memoryStore) GetByID(_ context.Context, sessionID uuid.UUID) (*repository.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, repository.ErrSessionNotFound
+	}
+	state := *session
+	return &state, nil
+}
+
+func (s *memoryStore) ResetSequences(_ context.Context, sessionID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sessionID].NextSenderSeq = 1
+	s.sessions[sessionID].NextTargetSeq = 1
+	delete(s.messages, sessionID)
+	return nil
+}
+
+func (s *memoryStore) SetNextTargetSeq(_ context.Context, sessionID uuid.UUID, seqNum int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sessionID].NextTargetSeq = seqNum
+	return nil
+}
+
+func (s *memoryStore) AppendMessage(_ context.Context, sessionID uuid.UUID, msgType string, body []byte, sentAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.sessions[sessionID]
+	seqNum := session.NextSenderSeq
+	session.NextSenderSeq++
+	s.messages[sessionID] = append(s.messages[sessionID], repository.StoredMessage{SeqNum: seqNum, MsgType: msgType, Body: body, SentAt: sentAt})
+	return seqNum, nil
+}
+
+func (s *memoryStore) GetMessages(_ context.Context, sessionID uuid.UUID, beginSeq, endSeq int) ([]repository.StoredMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []repository.StoredMessage
+	for _, msg := range s.messages[sessionID] {
+		if msg.SeqNum >= beginSeq && (endSeq == 0 || msg.SeqNum <= endSeq) {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (s *memoryStore) PurgeMessages(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// staticKeys knows one API key with the trade scope
+type staticKeys struct {
+	key *models.APIKey
+}
+
+func (k staticKeys) Credentials(_ context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	if keyID != k.key.KeyID {
+		return nil, ErrKeyNotFound
+	}
+	return &models.APIKeyCredentials{Key: k.key}, nil
+}
+
+// recordingApp records the sequence numbers of the application messages
+// handled
+type recordingApp struct {
+	mu      sync.Mutex
+	handled []int
+}
+
+func (a *recordingApp) FromApp(_ context.Context, _ *Session, msg *fix.Message) {
+	seqNum, _ := msg.Int(fix.TagMsgSeqNum)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handled = append(a.handled, seqNum)
+}
+
+func (a *recordingApp) seqNums() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]int(nil), a.handled...)
+}
+
+// counterparty is the client end of a FIX connection
+type counterparty struct {
+	userID   uuid.UUID
+	conn     net.Conn
+	received chan *fix.Message
+}
+
+// connect logs on to an acceptor with a Logon numbered logonSeq, returning
+// once the acceptor answered it
+func connect(t *testing.T, store SessionStore, app Application, logonSeq int) *counterparty {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	key := &models.APIKey{
+		UserID: uuid.New(),
+		KeyID:  "key-1",
+		Secret: "secret",
+		Scopes: []models.APIKeyScope{models.APIKeyScopeTrade},
+	}
+	acceptor := NewAcceptor("LFG", staticKeys{key}, store, time.Second, time.Hour)
+	acceptor.SetApplication(app)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go acceptor.Serve(ctx, listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &counterparty{userID: key.UserID, conn: conn, received: make(chan *fix.Message, 64)}
+	go func() {
+		defer close(c.received)
+		reader := bufio.NewReader(conn)
+		for {
+			msg, err := fix.Read(reader)
+			if err != nil {
+				return
+			}
+			c.received <- msg
+		}
+	}()
+
+	c.send(t, message(fix.MsgTypeLogon, logonSeq,
+		fix.Field{Tag: fix.TagEncryptMethod, Value: "0"},
+		fix.Field{Tag: fix.TagHeartBtInt, Value: "30"},
+		fix.Field{Tag: fix.TagUsername, Value: key.KeyID},
+		fix.Field{Tag: fix.TagPassword, Value: key.Secret},
+	))
+	select {
+	case msg := <-c.received:
+		if msg == nil || msg.Type() != fix.MsgTypeLogon {
+			t.Fatalf("logon answered with %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("logon was not answered")
+	}
+	return c
+}
+
+// message creates a message from the counterparty
+func message(msgType string, seqNum int, fields ...fix.Field) *fix.Message {
+	msg := fix.New(msgType).
+		Add(fix.TagSenderCompID, "CLIENT").
+		Add(fix.TagTargetCompID, "LFG").
+		AddInt(fix.TagMsgSeqNum, seqNum).
+		AddTime(fix.TagSendingTime, time.Now())
+	msg.Fields = append(msg.Fields, fields...)
+	return msg
+}
+
+func (c *counterparty) send(t *testing.T, msg *fix.Message) {
+	t.Helper()
+	if _, err := c.conn.Write(msg.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// drain returns what the acceptor sends until it has been quiet for a while
+func (c *counterparty) drain() []*fix.Message {
+	var messages []*fix.Message
+	for {
+		select {
+		case msg, ok := <-c.received:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, msg)
+		case <-time.After(200 * time.Millisecond):
+			return messages
+		}
+	}
+}
+
+// reply is a message expected from the acceptor, with some of its fields
+type reply struct {
+	msgType string
+	fields  map[fix.Tag]string
+}
+
+func TestSessionSequence(t *testing.T) {
+	order := func(seqNum int, fields ...fix.Field) *fix.Message {
+		return message(fix.MsgTypeNewOrderSingle, seqNum, fields...)
+	}
+	testRequest := func(seqNum int) *fix.Message {
+		return message(fix.MsgTypeTestRequest, seqNum, fix.Field{Tag: fix.TagTestReqID, Value: "T" + strconv.Itoa(seqNum)})
+	}
+	possDup := fix.Field{Tag: fix.TagPossDupFlag, Value: "Y"}
+	gapFill := func(seqNum, newSeqNo int) *fix.Message {
+		return message(fix.MsgTypeSequenceReset, seqNum, possDup,
+			fix.Field{Tag: fix.TagGapFillFlag, Value: "Y"},
+			fix.Field{Tag: fix.TagNewSeqNo, Value: strconv.Itoa(newSeqNo)})
+	}
+	heartbeat := func(testReqID string) reply {
+		return reply{fix.MsgTypeHeartbeat, map[fix.Tag]string{fix.TagTestReqID: testReqID}}
+	}
+	resendRequest := func(beginSeqNo int) reply {
+		return reply{fix.MsgTypeResendRequest, map[fix.Tag]string{fix.TagBeginSeqNo: strconv.Itoa(beginSeqNo), fix.TagEndSeqNo: "0"}}
+	}
+
+	tests := []struct {
+		name        string
+		logonSeq    int
+		send        []*fix.Message
+		want        []reply
+		wantHandled []int // Application messages handled, by MsgSeqNum
+	}{
+		{
+			name:        "in sequence",
+			logonSeq:    1,
+			send:        []*fix.Message{order(2), testRequest(3)},
+			want:        []reply{heartbeat("T3")},
+			wantHandled: []int{2},
+		},
+		{
+			name:     "gap asks for a resend once",
+			logonSeq: 1,
+			send:     []*fix.Message{order(4), order(5), testRequest(6)},
+			want:     []reply{resendRequest(2)},
+		},
+		{
+			name:        "resent messages close the gap",
+			logonSeq:    1,
+			send:        []*fix.Message{order(3), order(2, possDup), order(3, possDup), testRequest(4)},
+			want:        []reply{resendRequest(2), heartbeat("T4")},
+			wantHandled: []int{2, 3},
+		},
+		{
+			name:        "gap fill skips the missing messages",
+			logonSeq:    1,
+			send:        []*fix.Message{order(4), gapFill(2, 4), order(4, possDup)},
+			want:        []reply{resendRequest(2)},
+			wantHandled: []int{4},
+		},
+		{
+			name:        "gap fill cannot lower the sequence",
+			logonSeq:    1,
+			send:        []*fix.Message{gapFill(2, 1), order(3)},
+			want:        []reply{{fix.MsgTypeReject, map[fix.Tag]string{fix.TagRefSeqNum: "2", fix.TagSessionRejectReason: fix.SessionRejectReasonIncorrectValue}}},
+			wantHandled: []int{3},
+		},
+		{
+			name:     "sequence reset moves the sequence",
+			logonSeq: 1,
+			send: []*fix.Message{
+				message(fix.MsgTypeSequenceReset, 7, fix.Field{Tag: fix.TagNewSeqNo, Value: "10"}),
+				order(10),
+			},
+			wantHandled: []int{10},
+		},
+		{
+			name:        "possible duplicates below the sequence are ignored",
+			logonSeq:    1,
+			send:        []*fix.Message{order(2), order(2, possDup), testRequest(3)},
+			want:        []reply{heartbeat("T3")},
+			wantHandled: []int{2},
+		},
+		{
+			name:        "sequence too low ends the session",
+			logonSeq:    1,
+			send:        []*fix.Message{order(2), order(2)},
+			want:        []reply{{fix.MsgTypeLogout, map[fix.Tag]string{fix.TagText: "MsgSeqNum too low, expecting 3 but received 2"}}},
+			wantHandled: []int{2},
+		},
+		{
+			name:     "logon ahead of the sequence asks for a resend",
+			logonSeq: 3,
+			want:     []reply{resendRequest(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &recordingApp{}
+			c := connect(t, newMemoryStore(), app, tt.logonSeq)
+
+			for _, msg := range tt.send {
+				c.send(t, msg)
+			}
+			checkReplies(t, c.drain(), tt.want)
+
+			handled := app.seqNums()
+			sort.Ints(handled)
+			if len(handled) != len(tt.wantHandled) {
+				t.Fatalf("handled %v, want %v", handled, tt.wantHandled)
+			}
+			for i := range handled {
+				if handled[i] != tt.wantHandled[i] {
+					t.Errorf("handled %v, want %v", handled, tt.wantHandled)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSessionResendsApplicationMessages(t *testing.T) {
+	store := newMemoryStore()
+	c := connect(t, store, &recordingApp{}, 1)
+
+	// The gateway sent a report and a heartbeat after the logon
+	state, err := store.GetOrCreate(context.Background(), "CLIENT", "LFG", c.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := state.ID
+	store.AppendMessage(context.Background(), sessionID, fix.MsgTypeExecutionReport, fix.New(fix.MsgTypeExecutionReport).Add(fix.TagClOrdID, "order-1").Body(), time.Now())
+	store.AppendMessage(context.Background(), sessionID, fix.MsgTypeHeartbeat, nil, time.Now())
+
+	c.send(t, message(fix.MsgTypeResendRequest, 2,
+		fix.Field{Tag: fix.TagBeginSeqNo, Value: "1"},
+		fix.Field{Tag: fix.TagEndSeqNo, Value: "0"}))
+
+	// The logon and heartbeat are skipped; the report is sent again
+	checkReplies(t, c.drain(), []reply{
+		{fix.MsgTypeSequenceReset, map[fix.Tag]string{fix.TagMsgSeqNum: "1", fix.TagGapFillFlag: "Y", fix.TagNewSeqNo: "2"}},
+		{fix.MsgTypeExecutionReport, map[fix.Tag]string{fix.TagMsgSeqNum: "2", fix.TagPossDupFlag: "Y", fix.TagClOrdID: "order-1"}},
+		{fix.MsgTypeSequenceReset, map[fix.Tag]string{fix.TagMsgSeqNum: "3", fix.TagGapFillFlag: "Y", fix.TagNewSeqNo: "4"}},
+	})
+}
+
+// checkReplies checks the messages the acceptor sent against those expected
+func checkReplies(t *testing.T, got []*fix.Message, want []reply) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("acceptor sent %v, want %d messages", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Type() != w.msgType {
+			t.Errorf("message %d is %s, want MsgType %s", i, got[i], w.msgType)
+			continue
+		}
+		for tag, value := range w.fields {
+			if got[i].Get(tag) != value {
+				t.Errorf("message %d is %s, want %d=%s", i, got[i], tag, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,580 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+	"lfg/fix-gateway/fix"
+	"lfg/fix-gateway/repository"
+	"lfg/fix-gateway/session"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+const (
+	// maxClOrdIDLength bounds the ClOrdIDs sessions can choose
+	maxClOrdIDLength = 64
+
+	// requestTimeout bounds the handling of one application message
+	requestTimeout = 10 * time.Second
+
+	// errorDomain is the domain of the ErrorInfo the trading API details its
+	// rejections with
+	errorDomain = "lfg.trading"
+
+	// reasonNotCancellable is the rejection of a cancel of an order no
+	// longer open
+	reasonNotCancellable = "NOT_CANCELLABLE"
+)
+
+// ordRejReasons are the OrdRejReasons of the trading API's rejections;
+// others are OrdRejReasonOther
+var ordRejReasons = map[string]string{
+	"CONTRACT_NOT_FOUND": fix.OrdRejReasonUnknownSymbol,
+	"MARKET_CLOSED":      fix.OrdRejReasonExchangeClosed,
+	"INSUFFICIENT_FUNDS": fix.OrdRejReasonExceedsLimit,
+	"DUPLICATE_ORDER":    fix.OrdRejReasonDuplicateOrder,
+}
+
+var orderStatuses = map[tradingv1.OrderStatus]models.OrderStatus{
+	tradingv1.OrderStatus_ORDER_STATUS_PENDING:          models.OrderStatusPending,
+	tradingv1.OrderStatus_ORDER_STATUS_ACTIVE:           models.OrderStatusActive,
+	tradingv1.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED: models.OrderStatusPartiallyFilled,
+	tradingv1.OrderStatus_ORDER_STATUS_FILLED:           models.OrderStatusFilled,
+	tradingv1.OrderStatus_ORDER_STATUS_CANCELLED:        models.OrderStatusCancelled,
+	tradingv1.OrderStatus_ORDER_STATUS_REJECTED:         models.OrderStatusRejected,
+}
+
+var tracer = tracing.Tracer("lfg/fix-gateway/trading")
+
+// Application maps the order entry messages of FIX sessions onto the
+// order-service trading API, which checks, places and settles them as it
+// does the orders of its other APIs. Every change to an order is reported
+// to its session in an ExecutionReport: the acknowledgement, each fill, and
+// cancels and replacements. Orders changed outside of FIX, by the REST API
+// or a market halt, are not reported.
+type Application struct {
+	acceptor  *session.Acceptor
+	sessions  *repository.SessionRepository
+	fixOrders *repository.OrderRepository
+	trading   tradingv1.TradingServiceClient
+}
+
+// NewApplication creates a new FIX order entry application
+func NewApplication(acceptor *session.Acceptor, sessions *repository.SessionRepository, fixOrders *repository.OrderRepository, trading tradingv1.TradingServiceClient) *Application {
+	return &Application{
+		acceptor:  acceptor,
+		sessions:  sessions,
+		fixOrders: fixOrders,
+		trading:   trading,
+	}
+}
+
+// FromApp implements session.Application
+func (a *Application) FromApp(ctx context.Context, s *session.Session, msg *fix.Message) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "fix "+msg.Type(), trace.WithAttributes(
+		attribute.String("fix.session", s.String()),
+		attribute.String("fix.cl_ord_id", msg.Get(fix.TagClOrdID)),
+	))
+	defer span.End()
+
+	switch msg.Type() {
+	case fix.MsgTypeNewOrderSingle:
+		a.newOrder(ctx, s, msg)
+	case fix.MsgTypeOrderCancelRequest:
+		a.cancelOrder(ctx, s, msg)
+	case fix.MsgTypeOrderCancelReplaceRequest:
+		a.replaceOrder(ctx, s, msg)
+	default:
+		reject := fix.New(fix.MsgTypeBusinessMessageReject).
+			Add(fix.TagRefSeqNum, msg.Get(fix.TagMsgSeqNum)).
+			Add(fix.TagRefMsgType, msg.Type()).
+			Add(fix.TagBusinessRejectReason, fix.BusinessRejectReasonUnsupportedMsgType).
+			Add(fix.TagText, "Unsupported message type")
+		a.send(ctx, s, reject)
+	}
+}
+
+// orderRequest is the order described by a NewOrderSingle or an
+// OrderCancelReplaceRequest
+type orderRequest struct {
+	clOrdID    string
+	contractID uuid.UUID
+	side       models.OrderSide
+	orderType  models.OrderType
+	quantity   int
+	limitPrice *float64
+}
+
+// parseOrder reads the order of msg, sending a session-level Reject and
+// returning false if it is malformed
+func (a *Application) parseOrder(ctx context.Context, s *session.Session, msg *fix.Message) (*orderRequest, bool) {
+	for _, tag := range []fix.Tag{fix.TagClOrdID, fix.TagSymbol, fix.TagSide, fix.TagOrderQty, fix.TagOrdType} {
+		if msg.Get(tag) == "" {
+			s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, tag, "Required tag missing")
+			return nil, false
+		}
+	}
+
+	req := &orderRequest{clOrdID: msg.Get(fix.TagClOrdID)}
+	if len(req.clOrdID) > maxClOrdIDLength {
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagClOrdID, "ClOrdID must be at most 64 characters")
+		return nil, false
+	}
+
+	contractID, err := uuid.Parse(msg.Get(fix.TagSymbol))
+	if err != nil {
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagSymbol, "Symbol must be a contract ID")
+		return nil, false
+	}
+	req.contractID = contractID
+
+	switch msg.Get(fix.TagSide) {
+	case fix.SideBuy:
+		req.side = models.OrderSideBuy
+	case fix.SideSell:
+		req.side = models.OrderSideSell
+	default:
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagSide, "Side must be 1 (buy) or 2 (sell)")
+		return nil, false
+	}
+
+	// Quantities are whole contracts, however they are written
+	quantity, err := msg.Float(fix.TagOrderQty)
+	if err != nil || quantity <= 0 || quantity != math.Trunc(quantity) || quantity > math.MaxInt32 {
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagOrderQty, "OrderQty must be a positive whole number")
+		return nil, false
+	}
+	req.quantity = int(quantity)
+
+	switch msg.Get(fix.TagOrdType) {
+	case fix.OrdTypeMarket:
+		req.orderType = models.OrderTypeMarket
+	case fix.OrdTypeLimit:
+		req.orderType = models.OrderTypeLimit
+		price, err := msg.Float(fix.TagPrice)
+		if err != nil || price <= 0 || price > 1 {
+			s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagPrice, "Limit orders need a Price above 0 and at most 1")
+			return nil, false
+		}
+		req.limitPrice = &price
+	default:
+		s.Reject(ctx, msg, fix.SessionRejectReasonIncorrectValue, fix.TagOrdType, "OrdType must be 1 (market) or 2 (limit)")
+		return nil, false
+	}
+
+	return req, true
+}
+
+// newOrder handles a NewOrderSingle. The FIX order is recorded before the
+// order is placed, so fills of the order are reported however soon they
+// come.
+func (a *Application) newOrder(ctx context.Context, s *session.Session, msg *fix.Message) {
+	req, ok := a.parseOrder(ctx, s, msg)
+	if !ok {
+		return
+	}
+
+	if _, err := a.fixOrders.GetByClOrdID(ctx, s.ID(), req.clOrdID); err == nil {
+		a.send(ctx, s, rejectReport(msg, fix.OrdRejReasonDuplicateOrder, "Duplicate ClOrdID"))
+		return
+	}
+
+	fixOrder := &repository.Order{
+		OrderID:   uuid.New(),
+		SessionID: s.ID(),
+		ClOrdID:   req.clOrdID,
+		OrderQty:  req.quantity,
+	}
+	if err := a.fixOrders.Create(ctx, fixOrder); err != nil {
+		if errors.Is(err, repository.ErrDuplicateClOrdID) {
+			a.send(ctx, s, rejectReport(msg, fix.OrdRejReasonDuplicateOrder, "Duplicate ClOrdID"))
+			return
+		}
+		log.Printf("Failed to record FIX order %s: %v", req.clOrdID, err)
+		a.send(ctx, s, rejectReport(msg, fix.OrdRejReasonOther, "Failed to create order"))
+		return
+	}
+
+	resp, err := a.trading.PlaceOrder(asUser(ctx, s.UserID()), &tradingv1.PlaceOrderRequest{
+		OrderId:    fixOrder.OrderID.String(),
+		ContractId: req.contractID.String(),
+		Type:       orderType(req.orderType),
+		Side:       side(req.side),
+		Quantity:   int32(req.quantity),
+		LimitPrice: req.limitPrice,
+	})
+	if err != nil {
+		if reason, text, ok := rejection(err); ok {
+			if err := a.fixOrders.Delete(ctx, fixOrder.OrderID); err != nil {
+				log.Printf("Failed to delete rejected FIX order %s: %v", fixOrder.OrderID, err)
+			}
+			a.send(ctx, s, rejectReport(msg, ordRejReason(reason), text))
+			return
+		}
+
+		// The order may have been placed, so the FIX order is kept to
+		// report its fills
+		log.Printf("Failed to place FIX order %s: %v", fixOrder.OrderID, err)
+		a.send(ctx, s, rejectReport(msg, fix.OrdRejReasonOther, "Failed to place order"))
+		return
+	}
+
+	a.report(ctx, s, fromOrder(resp.Order), fixOrder, resp.Fills, fix.ExecTypeNew)
+}
+
+// report reports a placed order: its acknowledgement, with ExecType
+// execType, and the fills it took
+func (a *Application) report(ctx context.Context, s *session.Session, order *models.Order, fixOrder *repository.Order, fills []*tradingv1.Fill, execType string) {
+	if order.Status == models.OrderStatusRejected {
+		report := executionReport(order, fixOrder, fix.ExecTypeRejected, fix.OrdStatusRejected, 0).
+			Add(fix.TagOrdRejReason, fix.OrdRejReasonOther).
+			Add(fix.TagText, "Order rejected by the matching engine")
+		a.send(ctx, s, report)
+		return
+	}
+
+	// Market orders do not rest on the book: what they did not fill is
+	// cancelled
+	if order.Type == models.OrderTypeMarket && order.QuantityFilled < order.Quantity {
+		order.Status = models.OrderStatusCancelled
+	}
+
+	// Acknowledge the order before reporting its fills
+	ack := fix.OrdStatusNew
+	if fixOrder.CumQty > 0 {
+		ack = fix.OrdStatusPartiallyFilled
+	}
+	a.send(ctx, s, executionReport(order, fixOrder, execType, ack, fixOrder.OrderQty-fixOrder.CumQty))
+
+	for _, f := range fills {
+		updated, err := a.fixOrders.AddFill(ctx, order.ID, int(f.Quantity), f.Price)
+		if err != nil {
+			log.Printf("Failed to record fill of FIX order %s: %v", order.ID, err)
+			continue
+		}
+		fixOrder = updated
+		a.send(ctx, s, tradeReport(order, fixOrder, fill{
+			execID:   f.TradeId,
+			quantity: int(f.Quantity),
+			price:    f.Price,
+		}, fixOrder.OrderQty-fixOrder.CumQty))
+	}
+
+	if order.Type == models.OrderTypeMarket && order.Status == models.OrderStatusCancelled {
+		report := executionReport(order, fixOrder, fix.ExecTypeCanceled, fix.OrdStatusCanceled, 0).
+			Add(fix.TagText, "Market order could not be filled in full")
+		a.send(ctx, s, report)
+	}
+}
+
+// openOrder looks up the order an OrderCancelRequest or
+// OrderCancelReplaceRequest refers to by its OrigClOrdID, answering with an
+// OrderCancelReject and returning false if it cannot be changed
+func (a *Application) openOrder(ctx context.Context, s *session.Session, msg *fix.Message) (*models.Order, *repository.Order, bool) {
+	for _, tag := range []fix.Tag{fix.TagOrigClOrdID, fix.TagClOrdID} {
+		if msg.Get(tag) == "" {
+			s.Reject(ctx, msg, fix.SessionRejectReasonRequiredTagMissing, tag, "Required tag missing")
+			return nil, nil, false
+		}
+	}
+
+	fixOrder, err := a.fixOrders.GetByClOrdID(ctx, s.ID(), msg.Get(fix.TagOrigClOrdID))
+	if err != nil {
+		if !errors.Is(err, repository.ErrOrderNotFound) {
+			log.Printf("Failed to get FIX order %s: %v", msg.Get(fix.TagOrigClOrdID), err)
+		}
+		a.send(ctx, s, cancelReject(msg, "NONE", fix.OrdStatusRejected, fix.CxlRejReasonUnknownOrder, "Unknown order"))
+		return nil, nil, false
+	}
+
+	resp, err := a.trading.GetOrder(asUser(ctx, s.UserID()), &tradingv1.GetOrderRequest{OrderId: fixOrder.OrderID.String()})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			a.send(ctx, s, cancelReject(msg, "NONE", fix.OrdStatusRejected, fix.CxlRejReasonUnknownOrder, "Unknown order"))
+			return nil, nil, false
+		}
+		log.Printf("Failed to get order %s: %v", fixOrder.OrderID, err)
+		a.send(ctx, s, cancelReject(msg, fixOrder.OrderID.String(), fix.OrdStatusNew, fix.CxlRejReasonOther, "Failed to get order"))
+		return nil, nil, false
+	}
+	order := fromOrder(resp.Order)
+
+	if order.Status != models.OrderStatusActive && order.Status != models.OrderStatusPartiallyFilled {
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), ordStatus(order, fixOrder), fix.CxlRejReasonTooLate, "Order is no longer open"))
+		return nil, nil, false
+	}
+
+	return order, fixOrder, true
+}
+
+// cancelOrder handles an OrderCancelRequest
+func (a *Application) cancelOrder(ctx context.Context, s *session.Session, msg *fix.Message) {
+	order, fixOrder, ok := a.openOrder(ctx, s, msg)
+	if !ok {
+		return
+	}
+
+	resp, err := a.trading.CancelOrder(asUser(ctx, s.UserID()), &tradingv1.CancelOrderRequest{OrderId: order.ID.String()})
+	if err != nil {
+		reason, text := fix.CxlRejReasonOther, "Failed to cancel order"
+		if rejected, message, ok := rejection(err); ok {
+			text = message
+			if rejected == reasonNotCancellable {
+				reason = fix.CxlRejReasonTooLate
+			}
+		} else {
+			log.Printf("Failed to cancel FIX order %s: %v", order.ID, err)
+		}
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), ordStatus(order, fixOrder), reason, text))
+		return
+	}
+
+	report := executionReport(fromOrder(resp.Order), fixOrder, fix.ExecTypeCanceled, fix.OrdStatusCanceled, 0).
+		Set(fix.TagClOrdID, msg.Get(fix.TagClOrdID)).
+		Add(fix.TagOrigClOrdID, fixOrder.ClOrdID)
+	a.send(ctx, s, report)
+}
+
+// replaceOrder handles an OrderCancelReplaceRequest by amending the order,
+// which order-service does by cancelling it and placing a new one for what
+// the replacement leaves to fill. The new order is recorded first, carrying
+// on the fills of the old one as FIX reports them.
+func (a *Application) replaceOrder(ctx context.Context, s *session.Session, msg *fix.Message) {
+	req, ok := a.parseOrder(ctx, s, msg)
+	if !ok {
+		return
+	}
+	order, fixOrder, ok := a.openOrder(ctx, s, msg)
+	if !ok {
+		return
+	}
+	orderStatus := ordStatus(order, fixOrder)
+
+	if req.contractID != order.ContractID || req.side != order.Side || req.orderType != order.Type {
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), orderStatus, fix.CxlRejReasonOther, "Symbol, Side and OrdType cannot be changed"))
+		return
+	}
+	if req.quantity <= fixOrder.CumQty {
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), orderStatus, fix.CxlRejReasonOther, "OrderQty must exceed the quantity already filled"))
+		return
+	}
+	if _, err := a.fixOrders.GetByClOrdID(ctx, s.ID(), req.clOrdID); err == nil {
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), orderStatus, fix.CxlRejReasonOther, "Duplicate ClOrdID"))
+		return
+	}
+
+	origClOrdID := fixOrder.ClOrdID
+	replacement := &repository.Order{
+		OrderID:     uuid.New(),
+		SessionID:   s.ID(),
+		ClOrdID:     req.clOrdID,
+		OrigClOrdID: &origClOrdID,
+		OrderQty:    req.quantity,
+		CumQty:      fixOrder.CumQty,
+		CumValue:    fixOrder.CumValue,
+	}
+	if err := a.fixOrders.Create(ctx, replacement); err != nil {
+		text := "Duplicate ClOrdID"
+		if !errors.Is(err, repository.ErrDuplicateClOrdID) {
+			log.Printf("Failed to record FIX order %s: %v", req.clOrdID, err)
+			text = "Failed to replace order"
+		}
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), orderStatus, fix.CxlRejReasonOther, text))
+		return
+	}
+	carriedQty, carriedValue := replacement.CumQty, replacement.CumValue
+
+	// The order's quantity leaves out what the orders it replaced filled
+	quantity := int32(req.quantity - (fixOrder.OrderQty - order.Quantity))
+	resp, err := a.trading.AmendOrder(asUser(ctx, s.UserID()), &tradingv1.AmendOrderRequest{
+		OrderId:       order.ID.String(),
+		Quantity:      &quantity,
+		LimitPrice:    req.limitPrice,
+		ReplacementId: replacement.OrderID.String(),
+	})
+	if err != nil {
+		if _, text, ok := rejection(err); ok {
+			if err := a.fixOrders.Delete(ctx, replacement.OrderID); err != nil {
+				log.Printf("Failed to delete rejected FIX order %s: %v", replacement.OrderID, err)
+			}
+			a.send(ctx, s, cancelReject(msg, order.ID.String(), orderStatus, fix.CxlRejReasonOther, text))
+			return
+		}
+
+		log.Printf("Failed to replace FIX order %s: %v", order.ID, err)
+		a.replaceFailed(ctx, s, msg, order, fixOrder)
+		return
+	}
+
+	// What the old order filled until it was cancelled carries over
+	if updated, err := a.fixOrders.CarryFills(ctx, fixOrder.OrderID, replacement.OrderID, carriedQty, carriedValue); err != nil {
+		log.Printf("Failed to carry fills of FIX order %s: %v", fixOrder.OrderID, err)
+	} else {
+		replacement = updated
+	}
+
+	a.report(ctx, s, fromOrder(resp.Order), replacement, resp.Fills, fix.ExecTypeReplaced)
+}
+
+// replaceFailed answers a replacement that failed, which order-service may
+// have done after cancelling the order
+func (a *Application) replaceFailed(ctx context.Context, s *session.Session, msg *fix.Message, order *models.Order, fixOrder *repository.Order) {
+	resp, err := a.trading.GetOrder(asUser(ctx, s.UserID()), &tradingv1.GetOrderRequest{OrderId: order.ID.String()})
+	if err != nil || orderStatuses[resp.Order.Status] != models.OrderStatusCancelled {
+		a.send(ctx, s, cancelReject(msg, order.ID.String(), ordStatus(order, fixOrder), fix.CxlRejReasonOther, "Failed to replace order"))
+		return
+	}
+
+	report := executionReport(fromOrder(resp.Order), fixOrder, fix.ExecTypeCanceled, fix.OrdStatusCanceled, 0).
+		Set(fix.TagClOrdID, msg.Get(fix.TagClOrdID)).
+		Add(fix.TagOrigClOrdID, fixOrder.ClOrdID).
+		Add(fix.TagText, "Order was cancelled but its replacement could not be placed")
+	a.send(ctx, s, report)
+}
+
+// tradeEvent is a trade published by the matching engine
+type tradeEvent struct {
+	TradeID      string  `json:"trade_id"`
+	MakerOrderID string  `json:"maker_order_id"`
+	Quantity     int     `json:"quantity"`
+	Price        float64 `json:"price"`
+}
+
+// HandleTrade reports the fill of a resting FIX order by a trade published
+// by the matching engine. Takers are reported when their order is placed.
+func (a *Application) HandleTrade(ctx context.Context, data []byte) {
+	var trade tradeEvent
+	if err := json.Unmarshal(data, &trade); err != nil {
+		log.Printf("Failed to unmarshal trade event: %v", err)
+		return
+	}
+
+	makerOrderID, err := uuid.Parse(trade.MakerOrderID)
+	if err != nil {
+		return
+	}
+
+	if _, err := a.fixOrders.GetByOrderID(ctx, makerOrderID); err != nil {
+		if !errors.Is(err, repository.ErrOrderNotFound) {
+			log.Printf("Failed to get FIX order %s: %v", makerOrderID, err)
+		}
+		return
+	}
+
+	fixOrder, err := a.fixOrders.AddFill(ctx, makerOrderID, trade.Quantity, trade.Price)
+	if err != nil {
+		log.Printf("Failed to record fill of FIX order %s: %v", makerOrderID, err)
+		return
+	}
+
+	sess, err := a.sessions.GetByID(ctx, fixOrder.SessionID)
+	if err != nil {
+		log.Printf("Failed to get FIX session %s: %v", fixOrder.SessionID, err)
+		return
+	}
+
+	resp, err := a.trading.GetOrder(asUser(ctx, sess.UserID), &tradingv1.GetOrderRequest{OrderId: makerOrderID.String()})
+	if err != nil {
+		log.Printf("Failed to get order %s: %v", makerOrderID, err)
+		return
+	}
+	order := fromOrder(resp.Order)
+
+	report := tradeReport(order, fixOrder, fill{
+		execID:   trade.TradeID,
+		quantity: trade.Quantity,
+		price:    trade.Price,
+	}, fixOrder.OrderQty-fixOrder.CumQty)
+	if err := a.acceptor.Send(ctx, fixOrder.SessionID, report); err != nil {
+		log.Printf("Failed to report fill of FIX order %s: %v", makerOrderID, err)
+	}
+}
+
+// send sends a message to a session, which stores it for resends even if
+// it cannot be written
+func (a *Application) send(ctx context.Context, s *session.Session, msg *fix.Message) {
+	if err := s.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send FIX message to %s: %v", s, err)
+	}
+}
+
+// asUser returns a context whose calls to the trading API are made for a
+// user
+func asUser(ctx context.Context, userID uuid.UUID) context.Context {
+	return auth.WithIdentity(ctx, auth.Identity{UserID: userID.String()})
+}
+
+// rejection returns the reason and text of an order or cancellation the
+// trading API rejected, rather than failed to handle
+func rejection(err error) (string, string, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return "", "", false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			return info.Reason, st.Message(), true
+		}
+	}
+	return "", "", false
+}
+
+// ordRejReason returns the OrdRejReason of a rejection reason
+func ordRejReason(reason string) string {
+	if ordRejReason, ok := ordRejReasons[reason]; ok {
+		return ordRejReason
+	}
+	return fix.OrdRejReasonOther
+}
+
+func orderType(t models.OrderType) tradingv1.OrderType {
+	if t == models.OrderTypeLimit {
+		return tradingv1.OrderType_ORDER_TYPE_LIMIT
+	}
+	return tradingv1.OrderType_ORDER_TYPE_MARKET
+}
+
+func side(side models.OrderSide) tradingv1.Side {
+	if side == models.OrderSideSell {
+		return tradingv1.Side_SIDE_SELL
+	}
+	return tradingv1.Side_SIDE_BUY
+}
+
+// fromOrder converts an order of the trading API
+func fromOrder(order *tradingv1.Order) *models.Order {
+	result := &models.Order{
+		Type:              models.OrderTypeMarket,
+		Side:              models.OrderSideBuy,
+		Status:            orderStatuses[order.Status],
+		Quantity:          int(order.Quantity),
+		QuantityFilled:    int(order.QuantityFilled),
+		LimitPriceCredits: order.LimitPrice,
+	}
+	result.ID, _ = uuid.Parse(order.Id)
+	result.ContractID, _ = uuid.Parse(order.ContractId)
+	if order.Type == tradingv1.OrderType_ORDER_TYPE_LIMIT {
+		result.Type = models.OrderTypeLimit
+	}
+	if order.Side == tradingv1.Side_SIDE_SELL {
+		result.Side = models.OrderSideSell
+	}
+	return result
+}
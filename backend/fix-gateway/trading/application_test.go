@@ -0,0 +1,97 @@
+package trading
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/fix-gateway/fix"
+)
+
+// rejected builds an error as the trading API rejects an order
+func rejected(code codes.Code, reason, domain, message string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: domain})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func TestRejection(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		wantOK           bool
+		wantText         string
+		wantOrdRejReason string
+	}{
+		{
+			name:             "unknown contract",
+			err:              rejected(codes.NotFound, "CONTRACT_NOT_FOUND", errorDomain, "Contract not found"),
+			wantOK:           true,
+			wantText:         "Contract not found",
+			wantOrdRejReason: fix.OrdRejReasonUnknownSymbol,
+		},
+		{
+			name:             "closed market",
+			err:              rejected(codes.FailedPrecondition, "MARKET_CLOSED", errorDomain, "Market is not open for trading"),
+			wantOK:           true,
+			wantText:         "Market is not open for trading",
+			wantOrdRejReason: fix.OrdRejReasonExchangeClosed,
+		},
+		{
+			name:             "insufficient funds",
+			err:              rejected(codes.FailedPrecondition, "INSUFFICIENT_FUNDS", errorDomain, "Insufficient balance"),
+			wantOK:           true,
+			wantText:         "Insufficient balance",
+			wantOrdRejReason: fix.OrdRejReasonExceedsLimit,
+		},
+		{
+			name:             "duplicate order ID",
+			err:              rejected(codes.AlreadyExists, "DUPLICATE_ORDER", errorDomain, "Order ID already in use"),
+			wantOK:           true,
+			wantText:         "Order ID already in use",
+			wantOrdRejReason: fix.OrdRejReasonDuplicateOrder,
+		},
+		{
+			name:             "other reason",
+			err:              rejected(codes.InvalidArgument, "INVALID_ORDER", errorDomain, "Quantity must be positive"),
+			wantOK:           true,
+			wantText:         "Quantity must be positive",
+			wantOrdRejReason: fix.OrdRejReasonOther,
+		},
+		{
+			name: "other domain",
+			err:  rejected(codes.FailedPrecondition, "MARKET_CLOSED", "example.com", "Market is not open for trading"),
+		},
+		{
+			name: "failure without details",
+			err:  status.Error(codes.Internal, "Failed to place order"),
+		},
+		{
+			name: "not a status",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, text, ok := rejection(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("rejection() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if got := ordRejReason(reason); got != tt.wantOrdRejReason {
+				t.Errorf("ordRejReason(%q) = %q, want %q", reason, got, tt.wantOrdRejReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package trading
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/fix-gateway/fix"
+	"lfg/fix-gateway/repository"
+)
+
+// fill is an execution reported in an ExecutionReport
+type fill struct {
+	execID   string
+	quantity int
+	price    float64
+}
+
+// executionReport describes an order entered over FIX. leaves is the
+// quantity still working on the book.
+func executionReport(order *models.Order, fixOrder *repository.Order, execType, ordStatus string, leaves int) *fix.Message {
+	report := fix.New(fix.MsgTypeExecutionReport).
+		Add(fix.TagOrderID, order.ID.String()).
+		Add(fix.TagClOrdID, fixOrder.ClOrdID)
+	if fixOrder.OrigClOrdID != nil && execType == fix.ExecTypeReplaced {
+		report.Add(fix.TagOrigClOrdID, *fixOrder.OrigClOrdID)
+	}
+
+	report.
+		Add(fix.TagExecID, uuid.New().String()).
+		Add(fix.TagExecType, execType).
+		Add(fix.TagOrdStatus, ordStatus)
+	addInstrument(report, order)
+	report.
+		AddInt(fix.TagOrderQty, fixOrder.OrderQty).
+		AddInt(fix.TagLeavesQty, leaves).
+		AddInt(fix.TagCumQty, fixOrder.CumQty).
+		AddFloat(fix.TagAvgPx, fixOrder.AvgPx()).
+		AddTime(fix.TagTransactTime, time.Now())
+	return report
+}
+
+// tradeReport reports an execution of an order
+func tradeReport(order *models.Order, fixOrder *repository.Order, f fill, leaves int) *fix.Message {
+	ordStatus := fix.OrdStatusPartiallyFilled
+	if fixOrder.CumQty >= fixOrder.OrderQty {
+		ordStatus = fix.OrdStatusFilled
+	}
+
+	report := executionReport(order, fixOrder, fix.ExecTypeTrade, ordStatus, leaves)
+	report.Set(fix.TagExecID, f.execID)
+	return report.
+		AddInt(fix.TagLastQty, f.quantity).
+		AddFloat(fix.TagLastPx, f.price)
+}
+
+// rejectReport rejects a NewOrderSingle that did not become an order
+func rejectReport(msg *fix.Message, reason, text string) *fix.Message {
+	report := fix.New(fix.MsgTypeExecutionReport).
+		Add(fix.TagOrderID, "NONE").
+		Add(fix.TagClOrdID, msg.Get(fix.TagClOrdID)).
+		Add(fix.TagExecID, uuid.New().String()).
+		Add(fix.TagExecType, fix.ExecTypeRejected).
+		Add(fix.TagOrdStatus, fix.OrdStatusRejected).
+		Add(fix.TagSymbol, msg.Get(fix.TagSymbol)).
+		Add(fix.TagSide, msg.Get(fix.TagSide))
+	if qty := msg.Get(fix.TagOrderQty); qty != "" {
+		report.Add(fix.TagOrderQty, qty)
+	}
+	return report.
+		Add(fix.TagOrdType, msg.Get(fix.TagOrdType)).
+		AddInt(fix.TagLeavesQty, 0).
+		AddInt(fix.TagCumQty, 0).
+		AddInt(fix.TagAvgPx, 0).
+		Add(fix.TagOrdRejReason, reason).
+		Add(fix.TagText, text).
+		AddTime(fix.TagTransactTime, time.Now())
+}
+
+// cancelReject rejects an OrderCancelRequest or OrderCancelReplaceRequest.
+// orderID is "NONE" for orders the session does not know.
+func cancelReject(msg *fix.Message, orderID, ordStatus, reason, text string) *fix.Message {
+	responseTo := fix.CxlRejResponseToCancel
+	if msg.Type() == fix.MsgTypeOrderCancelReplaceRequest {
+		responseTo = fix.CxlRejResponseToCancelReplace
+	}
+
+	return fix.New(fix.MsgTypeOrderCancelReject).
+		Add(fix.TagOrderID, orderID).
+		Add(fix.TagClOrdID, msg.Get(fix.TagClOrdID)).
+		Add(fix.TagOrigClOrdID, msg.Get(fix.TagOrigClOrdID)).
+		Add(fix.TagOrdStatus, ordStatus).
+		Add(fix.TagCxlRejResponseTo, responseTo).
+		Add(fix.TagCxlRejReason, reason).
+		Add(fix.TagText, text)
+}
+
+// ordStatus returns the OrdStatus of an order
+func ordStatus(order *models.Order, fixOrder *repository.Order) string {
+	switch {
+	case order.Status == models.OrderStatusCancelled:
+		return fix.OrdStatusCanceled
+	case order.Status == models.OrderStatusRejected:
+		return fix.OrdStatusRejected
+	case fixOrder.CumQty >= fixOrder.OrderQty:
+		return fix.OrdStatusFilled
+	case fixOrder.CumQty > 0:
+		return fix.OrdStatusPartiallyFilled
+	}
+	return fix.OrdStatusNew
+}
+
+func addInstrument(report *fix.Message, order *models.Order) {
+	side := fix.SideBuy
+	if order.Side == models.OrderSideSell {
+		side = fix.SideSell
+	}
+	ordType := fix.OrdTypeMarket
+	if order.Type == models.OrderTypeLimit {
+		ordType = fix.OrdTypeLimit
+	}
+
+	report.
+		Add(fix.TagSymbol, order.ContractID.String()).
+		Add(fix.TagSide, side).
+		Add(fix.TagOrdType, ordType)
+	if order.LimitPriceCredits != nil {
+		report.AddFloat(fix.TagPrice, *order.LimitPriceCredits)
+	}
+}
@@ -0,0 +1,623 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+	"lfg/market-service/scheduler"
+)
+
+var (
+	tickerPattern   = regexp.MustCompile(`^[A-Z0-9_]+$`)
+	categoryPattern = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+)
+
+// AdminStore creates markets and moves them through their lifecycle
+type AdminStore interface {
+	List(ctx context.Context, filter *models.MarketListFilter) ([]*models.Market, int, error)
+	Facets(ctx context.Context, filter *models.MarketListFilter) (*models.MarketFacets, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Market, error)
+	CreateWithOutcomes(ctx context.Context, market *models.Market, outcomeNames []string) ([]*models.Outcome, []*models.Contract, error)
+	UpdateDraft(ctx context.Context, req *models.MarketUpdateRequest) (*models.Market, error)
+	Publish(ctx context.Context, marketID uuid.UUID) (*models.Market, error)
+	Close(ctx context.Context, marketID uuid.UUID) (*models.Market, error)
+	Cancel(ctx context.Context, marketID uuid.UUID) (*models.MarketSettlement, error)
+	CheckResolution(ctx context.Context, market *models.Market, resolution *models.MarketResolveRequest) error
+	Resolve(ctx context.Context, marketID, outcomeID uuid.UUID) (*models.MarketSettlement, error)
+	ResolveScalar(ctx context.Context, marketID uuid.UUID, value float64) (*models.MarketSettlement, error)
+}
+
+// AuditStore records admin actions and lists them
+type AuditStore interface {
+	Record(ctx context.Context, actorUserID uuid.UUID, action models.AuditAction, marketID *uuid.UUID, details interface{}) error
+	List(ctx context.Context, marketID *uuid.UUID, limit, offset int) ([]*models.AuditLogEntry, error)
+}
+
+// AdminHandler handles HTTP requests for admin market management
+type AdminHandler struct {
+	repo         AdminStore
+	eventRepo    *repository.EventRepository
+	templateRepo *repository.TemplateRepository
+	auditRepo    AuditStore
+	lifecycle    *scheduler.LifecycleScheduler
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(repo AdminStore, eventRepo *repository.EventRepository, templateRepo *repository.TemplateRepository, auditRepo AuditStore, lifecycle *scheduler.LifecycleScheduler) *AdminHandler {
+	return &AdminHandler{
+		repo:         repo,
+		eventRepo:    eventRepo,
+		templateRepo: templateRepo,
+		auditRepo:    auditRepo,
+		lifecycle:    lifecycle,
+	}
+}
+
+// ListMarkets handles listing all markets, including drafts
+func (h *AdminHandler) ListMarkets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	filter := parseMarketFilter(r)
+	filter.IncludeDrafts = true
+
+	listMarkets(w, r, h.repo, filter)
+}
+
+// CreateMarket handles creating a draft market together with its outcomes and contracts
+func (h *AdminHandler) CreateMarket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = models.MarketTypeBinary
+	}
+
+	outcomeNames, err := validateMarketCreate(&req)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	market := &models.Market{
+		ID:               uuid.New(),
+		Ticker:           req.Ticker,
+		Question:         req.Question,
+		Rules:            req.Rules,
+		ResolutionSource: req.ResolutionSource,
+		Category:         req.Category,
+		Tags:             req.Tags,
+		EventID:          req.EventID,
+		Status:           models.MarketStatusDraft,
+		Type:             req.Type,
+		LowerBound:       req.LowerBound,
+		UpperBound:       req.UpperBound,
+		OpensAt:          req.OpensAt,
+		ExpiresAt:        req.ExpiresAt,
+	}
+
+	outcomes, contracts, err := h.repo.CreateWithOutcomes(r.Context(), market, outcomeNames)
+	if err != nil {
+		if err == repository.ErrTickerTaken {
+			respondError(w, "Ticker already exists", http.StatusConflict)
+			return
+		}
+		if err == repository.ErrUnknownCategory || err == repository.ErrEventNotFound {
+			respondMarketActionError(w, err)
+			return
+		}
+		respondError(w, "Failed to create market", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionMarketCreate, &market.ID, req)
+
+	response := map[string]interface{}{
+		"market":    market,
+		"outcomes":  outcomes,
+		"contracts": contracts,
+	}
+
+	respondJSON(w, response, http.StatusCreated)
+}
+
+// UpdateMarket handles editing a draft market
+func (h *AdminHandler) UpdateMarket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Question != nil && (len(*req.Question) < 10 || len(*req.Question) > 500) {
+		respondError(w, "Question must be between 10 and 500 characters", http.StatusBadRequest)
+		return
+	}
+
+	if req.Rules != nil && strings.TrimSpace(*req.Rules) == "" {
+		respondError(w, "Rules cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if req.ResolutionSource != nil && (strings.TrimSpace(*req.ResolutionSource) == "" || len(*req.ResolutionSource) > 255) {
+		respondError(w, "Resolution source must be between 1 and 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	if req.Category != nil && *req.Category != "" && !categoryPattern.MatchString(*req.Category) {
+		respondError(w, "Category must be a lowercase slug of at most 50 characters", http.StatusBadRequest)
+		return
+	}
+
+	if req.Tags != nil {
+		tags, err := normalizeTags(req.Tags)
+		if err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Tags = tags
+	}
+
+	market, err := h.repo.UpdateDraft(r.Context(), &req)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionMarketUpdate, &market.ID, req)
+
+	respondJSON(w, market, http.StatusOK)
+}
+
+// PublishMarket handles making a draft market visible and tradable
+func (h *AdminHandler) PublishMarket(w http.ResponseWriter, r *http.Request) {
+	adminID, req, ok := h.decodeAction(w, r)
+	if !ok {
+		return
+	}
+
+	market, err := h.repo.Publish(r.Context(), req.MarketID)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	if market.Status == models.MarketStatusOpen {
+		h.lifecycle.Publish(r.Context(), models.MarketLifecycleOpened, market)
+	}
+
+	h.audit(r, adminID, models.AuditActionMarketPublish, &market.ID, req)
+
+	respondJSON(w, market, http.StatusOK)
+}
+
+// CloseMarket handles stopping trading on a market ahead of resolution
+func (h *AdminHandler) CloseMarket(w http.ResponseWriter, r *http.Request) {
+	adminID, req, ok := h.decodeAction(w, r)
+	if !ok {
+		return
+	}
+
+	market, err := h.repo.Close(r.Context(), req.MarketID)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	// The scheduler halts the market again if this fails
+	if err := h.lifecycle.HaltTrading(r.Context(), market); err != nil {
+		log.Printf("Failed to halt trading on market %s: %v", market.Ticker, err)
+	}
+	h.lifecycle.Publish(r.Context(), models.MarketLifecycleClosed, market)
+
+	h.audit(r, adminID, models.AuditActionMarketClose, &market.ID, req)
+
+	respondJSON(w, market, http.StatusOK)
+}
+
+// CancelMarket handles cancelling a market and refunding its positions
+func (h *AdminHandler) CancelMarket(w http.ResponseWriter, r *http.Request) {
+	adminID, req, ok := h.decodeAction(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.stopTrading(w, r, adminID, req.MarketID) {
+		return
+	}
+
+	settlement, err := h.repo.Cancel(r.Context(), req.MarketID)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	h.publishStatus(r, req.MarketID, models.MarketLifecycleCancelled)
+
+	h.audit(r, adminID, models.AuditActionMarketCancel, &req.MarketID, map[string]interface{}{
+		"request":    req,
+		"settlement": settlement,
+	})
+
+	respondJSON(w, settlement, http.StatusOK)
+}
+
+// ResolveMarket handles resolving a market and settling its positions
+func (h *AdminHandler) ResolveMarket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return
+	}
+
+	market, err := h.repo.GetByID(r.Context(), req.MarketID)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	if market.Type == models.MarketTypeScalar && req.Value == nil {
+		respondError(w, "Value is required to resolve a scalar market", http.StatusBadRequest)
+		return
+	}
+
+	if market.Type != models.MarketTypeScalar && req.OutcomeID == uuid.Nil {
+		respondError(w, "Outcome ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Stopping trading cannot be undone, so the resolution is checked first
+	if err := h.repo.CheckResolution(r.Context(), market, &req); err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	if !h.stopTrading(w, r, adminID, req.MarketID) {
+		return
+	}
+
+	var settlement *models.MarketSettlement
+	if market.Type == models.MarketTypeScalar {
+		settlement, err = h.repo.ResolveScalar(r.Context(), req.MarketID, *req.Value)
+	} else {
+		settlement, err = h.repo.Resolve(r.Context(), req.MarketID, req.OutcomeID)
+	}
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	h.publishStatus(r, req.MarketID, models.MarketLifecycleResolved)
+
+	h.audit(r, adminID, models.AuditActionMarketResolve, &req.MarketID, map[string]interface{}{
+		"request":    req,
+		"settlement": settlement,
+	})
+
+	respondJSON(w, settlement, http.StatusOK)
+}
+
+// AuditLog handles listing admin audit log entries
+func (h *AdminHandler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	var marketID *uuid.UUID
+	if marketIDStr := r.URL.Query().Get("market_id"); marketIDStr != "" {
+		id, err := uuid.Parse(marketIDStr)
+		if err != nil {
+			respondError(w, "Invalid market ID", http.StatusBadRequest)
+			return
+		}
+		marketID = &id
+	}
+
+	limit := parseInt(r.URL.Query().Get("limit"), 50)
+	if limit > 200 {
+		limit = 200
+	}
+	offset := parseInt(r.URL.Query().Get("offset"), 0)
+
+	entries, err := h.auditRepo.List(r.Context(), marketID, limit, offset)
+	if err != nil {
+		respondError(w, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"entries": entries}, http.StatusOK)
+}
+
+// decodeAction checks method and role and decodes a market action request
+func (h *AdminHandler) decodeAction(w http.ResponseWriter, r *http.Request) (uuid.UUID, *models.MarketActionRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return uuid.Nil, nil, false
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return uuid.Nil, nil, false
+	}
+
+	var req models.MarketActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return uuid.Nil, nil, false
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return uuid.Nil, nil, false
+	}
+
+	return adminID, &req, true
+}
+
+// stopTrading closes a still-trading market before it is resolved or
+// cancelled and halts it on the matching engine so that no fills land after
+// settlement. A market closed earlier is halted again, as halting it may have
+// failed then.
+func (h *AdminHandler) stopTrading(w http.ResponseWriter, r *http.Request, adminID, marketID uuid.UUID) bool {
+	market, err := h.repo.Close(r.Context(), marketID)
+	closed := err == nil
+	if err == repository.ErrInvalidTransition {
+		market, err = h.repo.GetByID(r.Context(), marketID)
+		if err == nil && market.Status != models.MarketStatusClosed {
+			return true
+		}
+	}
+	if err != nil {
+		respondMarketActionError(w, err)
+		return false
+	}
+
+	if closed {
+		h.lifecycle.Publish(r.Context(), models.MarketLifecycleClosed, market)
+		h.audit(r, adminID, models.AuditActionMarketClose, &market.ID, map[string]string{"reason": "closed before settlement"})
+	}
+
+	if err := h.lifecycle.HaltTrading(r.Context(), market); err != nil {
+		log.Printf("Failed to halt trading on market %s: %v", market.Ticker, err)
+		respondError(w, "Failed to halt trading on the market", http.StatusServiceUnavailable)
+		return false
+	}
+
+	return true
+}
+
+// publishStatus emits a lifecycle event with the market's current state
+func (h *AdminHandler) publishStatus(r *http.Request, marketID uuid.UUID, eventType models.MarketLifecycleEventType) {
+	market, err := h.repo.GetByID(r.Context(), marketID)
+	if err != nil {
+		log.Printf("Failed to load market %s for lifecycle event: %v", marketID, err)
+		return
+	}
+
+	h.lifecycle.Publish(r.Context(), eventType, market)
+}
+
+// audit records an admin action; failures are logged but do not fail the request
+func (h *AdminHandler) audit(r *http.Request, adminID uuid.UUID, action models.AuditAction, marketID *uuid.UUID, details interface{}) {
+	if err := h.auditRepo.Record(r.Context(), adminID, action, marketID, details); err != nil {
+		log.Printf("Failed to record audit entry %s by %s: %v", action, adminID, err)
+	}
+}
+
+// requireAdmin extracts the admin user ID, rejecting non-admin callers
+func requireAdmin(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	if r.Header.Get("X-User-Role") != string(models.UserRoleAdmin) {
+		respondError(w, "Insufficient permissions", http.StatusForbidden)
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// validateMarketCreate validates a create request and returns the outcome
+// names the market will be created with
+func validateMarketCreate(req *models.MarketCreateRequest) ([]string, error) {
+	if len(req.Ticker) == 0 || len(req.Ticker) > 50 || !tickerPattern.MatchString(req.Ticker) {
+		return nil, errors.New("Ticker must be 1-50 uppercase letters, digits or underscores")
+	}
+
+	if len(req.Question) < 10 || len(req.Question) > 500 {
+		return nil, errors.New("Question must be between 10 and 500 characters")
+	}
+
+	if strings.TrimSpace(req.Rules) == "" {
+		return nil, errors.New("Rules are required")
+	}
+
+	if strings.TrimSpace(req.ResolutionSource) == "" || len(req.ResolutionSource) > 255 {
+		return nil, errors.New("Resolution source must be between 1 and 255 characters")
+	}
+
+	if req.Category != nil && !categoryPattern.MatchString(*req.Category) {
+		return nil, errors.New("Category must be a lowercase slug of at most 50 characters")
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	req.Tags = tags
+
+	if !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("Expiry must be in the future")
+	}
+
+	if req.OpensAt != nil && !req.OpensAt.Before(req.ExpiresAt) {
+		return nil, errors.New("Open time must be before expiry")
+	}
+
+	switch req.Type {
+	case models.MarketTypeBinary:
+		if len(req.Outcomes) > 0 || req.LowerBound != nil || req.UpperBound != nil {
+			return nil, errors.New("Binary markets do not take outcomes or bounds")
+		}
+		return []string{models.OutcomeNameYes, models.OutcomeNameNo}, nil
+
+	case models.MarketTypeCategorical:
+		if req.LowerBound != nil || req.UpperBound != nil {
+			return nil, errors.New("Categorical markets do not take bounds")
+		}
+		if len(req.Outcomes) < 2 || len(req.Outcomes) > 20 {
+			return nil, errors.New("Categorical markets need between 2 and 20 outcomes")
+		}
+		seen := make(map[string]bool, len(req.Outcomes))
+		names := make([]string, len(req.Outcomes))
+		for i, name := range req.Outcomes {
+			name = strings.TrimSpace(name)
+			if name == "" || len(name) > 100 {
+				return nil, errors.New("Outcome names must be between 1 and 100 characters")
+			}
+			if seen[strings.ToLower(name)] {
+				return nil, fmt.Errorf("Duplicate outcome: %s", name)
+			}
+			seen[strings.ToLower(name)] = true
+			names[i] = name
+		}
+		return names, nil
+
+	case models.MarketTypeScalar:
+		if len(req.Outcomes) > 0 {
+			return nil, errors.New("Scalar markets do not take outcomes")
+		}
+		if req.LowerBound == nil || req.UpperBound == nil || *req.LowerBound >= *req.UpperBound {
+			return nil, errors.New("Scalar markets need a lower bound below the upper bound")
+		}
+		return []string{models.OutcomeNameLong, models.OutcomeNameShort}, nil
+	}
+
+	return nil, errors.New("Type must be BINARY, CATEGORICAL or SCALAR")
+}
+
+// normalizeTags lowercases, trims and de-duplicates market tags
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > 10 {
+		return nil, errors.New("Markets can have at most 10 tags")
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > 50 {
+			return nil, errors.New("Tags must be between 1 and 50 characters")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
+}
+
+// respondMarketActionError maps repository errors of admin actions to responses
+func respondMarketActionError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrMarketNotFound:
+		respondError(w, "Market not found", http.StatusNotFound)
+	case repository.ErrEventNotFound:
+		respondError(w, "Event not found", http.StatusNotFound)
+	case repository.ErrMarketNotInEvent:
+		respondError(w, "Market does not belong to this event", http.StatusBadRequest)
+	case repository.ErrResolutionMissing:
+		respondError(w, "Every unsettled market of the event needs a resolution", http.StatusBadRequest)
+	case repository.ErrTickerTaken:
+		respondError(w, "Ticker already exists", http.StatusConflict)
+	case repository.ErrOutcomeNotFound:
+		respondError(w, "Outcome does not belong to this market", http.StatusBadRequest)
+	case repository.ErrInvalidSchedule:
+		respondError(w, "Open time must be before expiry and expiry in the future", http.StatusBadRequest)
+	case repository.ErrInvalidBounds:
+		respondError(w, "Lower bound must be below upper bound", http.StatusBadRequest)
+	case repository.ErrUnknownCategory:
+		respondError(w, "Unknown category", http.StatusBadRequest)
+	case repository.ErrMarketNotDraft:
+		respondError(w, "Only draft markets can be changed", http.StatusConflict)
+	case repository.ErrInvalidTransition, repository.ErrMarketNotResolvable:
+		respondError(w, "Market status does not allow this action", http.StatusConflict)
+	case repository.ErrTradingNotHalted:
+		respondError(w, "Trading on the market has not been halted yet", http.StatusConflict)
+	case repository.ErrWrongMarketType:
+		respondError(w, "Resolution does not match market type", http.StatusBadRequest)
+	case repository.ErrInvalidScalarValue:
+		respondError(w, "Value must be a finite number", http.StatusBadRequest)
+	default:
+		respondError(w, "Failed to update market", http.StatusInternalServerError)
+	}
+}
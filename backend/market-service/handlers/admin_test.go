@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+	"lfg/market-service/scheduler"
+	pb "lfg/matching-engine/proto"
+)
+
+// fakeStore keeps a single market in memory and records the calls made on
+// it, in order
+type fakeStore struct {
+	AdminStore
+	scheduler.MarketStore
+
+	market    *models.Market
+	contracts []*models.Contract
+	checkErr  error
+	calls     []string
+}
+
+func (s *fakeStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Market, error) {
+	s.calls = append(s.calls, "GetByID")
+	market := *s.market
+	return &market, nil
+}
+
+func (s *fakeStore) CheckResolution(ctx context.Context, market *models.Market, resolution *models.MarketResolveRequest) error {
+	s.calls = append(s.calls, "CheckResolution")
+	return s.checkErr
+}
+
+func (s *fakeStore) Close(ctx context.Context, marketID uuid.UUID) (*models.Market, error) {
+	s.calls = append(s.calls, "Close")
+	if s.market.Status != models.MarketStatusOpen {
+		return nil, repository.ErrInvalidTransition
+	}
+	s.market.Status = models.MarketStatusClosed
+	market := *s.market
+	return &market, nil
+}
+
+func (s *fakeStore) Resolve(ctx context.Context, marketID, outcomeID uuid.UUID) (*models.MarketSettlement, error) {
+	s.calls = append(s.calls, "Resolve")
+	s.market.Status = models.MarketStatusResolved
+	return &models.MarketSettlement{MarketID: marketID}, nil
+}
+
+func (s *fakeStore) Cancel(ctx context.Context, marketID uuid.UUID) (*models.MarketSettlement, error) {
+	s.calls = append(s.calls, "Cancel")
+	s.market.Status = models.MarketStatusCancelled
+	return &models.MarketSettlement{MarketID: marketID}, nil
+}
+
+func (s *fakeStore) GetContractsByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Contract, error) {
+	return s.contracts, nil
+}
+
+func (s *fakeStore) CancelRestingOrders(ctx context.Context, marketID uuid.UUID) (int64, error) {
+	s.calls = append(s.calls, "CancelRestingOrders")
+	return 0, nil
+}
+
+// fakeAudit records the actions audited, failing to if told to
+type fakeAudit struct {
+	AuditStore
+	fail    bool
+	actions []models.AuditAction
+}
+
+func (a *fakeAudit) Record(ctx context.Context, actorUserID uuid.UUID, action models.AuditAction, marketID *uuid.UUID, details interface{}) error {
+	if a.fail {
+		return errors.New("audit log unavailable")
+	}
+	a.actions = append(a.actions, action)
+	return nil
+}
+
+// fakeEngine halts contracts unless told to fail
+type fakeEngine struct {
+	pb.MatchingEngineClient
+	fail bool
+}
+
+func (e *fakeEngine) HaltContract(ctx context.Context, req *pb.HaltContractRequest, opts ...grpc.CallOption) (*pb.HaltContractResponse, error) {
+	if e.fail {
+		return nil, status.Error(codes.Unavailable, "matching engine unavailable")
+	}
+	return &pb.HaltContractResponse{Success: true}, nil
+}
+
+// adminRequest builds a request to the admin API made by a caller of role
+func adminRequest(method, body string, role models.UserRole) *http.Request {
+	r := httptest.NewRequest(method, "/admin/markets", strings.NewReader(body))
+	r.Header.Set("X-User-ID", uuid.NewString())
+	r.Header.Set("X-User-Role", string(role))
+	return r
+}
+
+func TestRequireAdmin(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		userID     string
+		role       string
+		wantStatus int
+	}{
+		{"admin", userID.String(), "admin", http.StatusOK},
+		{"user", userID.String(), "user", http.StatusForbidden},
+		{"no role", userID.String(), "", http.StatusForbidden},
+		{"role in another case", userID.String(), "ADMIN", http.StatusForbidden},
+		{"no user", "", "admin", http.StatusUnauthorized},
+		{"malformed user", "admin", "admin", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/markets", nil)
+			r.Header.Set("X-User-ID", tt.userID)
+			r.Header.Set("X-User-Role", tt.role)
+			w := httptest.NewRecorder()
+
+			got, ok := requireAdmin(w, r)
+			if ok != (tt.wantStatus == http.StatusOK) || w.Code != tt.wantStatus {
+				t.Fatalf("requireAdmin() ok = %v with status %d, want status %d", ok, w.Code, tt.wantStatus)
+			}
+			if ok && got != userID {
+				t.Errorf("requireAdmin() = %s, want %s", got, userID)
+			}
+		})
+	}
+}
+
+func TestAdminEndpointsForbidUsers(t *testing.T) {
+	// The fakes panic on any call, so a user must be turned away before the
+	// handler touches a market or the audit log
+	h := NewAdminHandler(&fakeStore{}, nil, nil, &fakeAudit{}, nil)
+	body := `{"market_id":"` + uuid.NewString() + `"}`
+
+	endpoints := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{"list markets", http.MethodGet, h.ListMarkets},
+		{"create market", http.MethodPost, h.CreateMarket},
+		{"update market", http.MethodPut, h.UpdateMarket},
+		{"publish market", http.MethodPost, h.PublishMarket},
+		{"close market", http.MethodPost, h.CloseMarket},
+		{"cancel market", http.MethodPost, h.CancelMarket},
+		{"resolve market", http.MethodPost, h.ResolveMarket},
+		{"audit log", http.MethodGet, h.AuditLog},
+	}
+
+	for _, tt := range endpoints {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, adminRequest(tt.method, body, models.UserRoleUser))
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestResolveMarket(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      models.MarketStatus
+		checkErr    error
+		haltFails   bool
+		auditFails  bool
+		wantStatus  int
+		wantCalls   string
+		wantMarket  models.MarketStatus
+		wantAudited string
+	}{
+		{
+			name:        "open market",
+			status:      models.MarketStatusOpen,
+			wantStatus:  http.StatusOK,
+			wantCalls:   "GetByID CheckResolution Close CancelRestingOrders Resolve GetByID",
+			wantMarket:  models.MarketStatusResolved,
+			wantAudited: "MARKET_CLOSE MARKET_RESOLVE",
+		},
+		{
+			name:        "market closed earlier",
+			status:      models.MarketStatusClosed,
+			wantStatus:  http.StatusOK,
+			wantCalls:   "GetByID CheckResolution Close GetByID CancelRestingOrders Resolve GetByID",
+			wantMarket:  models.MarketStatusResolved,
+			wantAudited: "MARKET_RESOLVE",
+		},
+		{
+			name:       "failed resolution check",
+			status:     models.MarketStatusOpen,
+			checkErr:   repository.ErrOutcomeNotFound,
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  "GetByID CheckResolution",
+			wantMarket: models.MarketStatusOpen,
+		},
+		{
+			name:        "failed halt",
+			status:      models.MarketStatusOpen,
+			haltFails:   true,
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCalls:   "GetByID CheckResolution Close",
+			wantMarket:  models.MarketStatusClosed,
+			wantAudited: "MARKET_CLOSE",
+		},
+		{
+			name:       "failed audit",
+			status:     models.MarketStatusOpen,
+			auditFails: true,
+			wantStatus: http.StatusOK,
+			wantCalls:  "GetByID CheckResolution Close CancelRestingOrders Resolve GetByID",
+			wantMarket: models.MarketStatusResolved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market := &models.Market{ID: uuid.New(), Ticker: "MARKET", Type: models.MarketTypeBinary, Status: tt.status}
+			store := &fakeStore{
+				market:    market,
+				contracts: []*models.Contract{{ID: uuid.New(), MarketID: market.ID, Ticker: "MARKET-YES"}},
+				checkErr:  tt.checkErr,
+			}
+			audit := &fakeAudit{fail: tt.auditFails}
+			lifecycle := scheduler.NewLifecycleScheduler(store, &fakeEngine{fail: tt.haltFails}, nil, time.Minute)
+			h := NewAdminHandler(store, nil, nil, audit, lifecycle)
+
+			body := `{"market_id":"` + market.ID.String() + `","outcome_id":"` + uuid.NewString() + `"}`
+			w := httptest.NewRecorder()
+			h.ResolveMarket(w, adminRequest(http.MethodPost, body, models.UserRoleAdmin))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := strings.Join(store.calls, " "); got != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", got, tt.wantCalls)
+			}
+			if market.Status != tt.wantMarket {
+				t.Errorf("market left %s, want %s", market.Status, tt.wantMarket)
+			}
+			if got := fmtActions(audit.actions); got != tt.wantAudited {
+				t.Errorf("audited %q, want %q", got, tt.wantAudited)
+			}
+		})
+	}
+}
+
+func TestCancelMarket(t *testing.T) {
+	tests := []struct {
+		name        string
+		haltFails   bool
+		wantStatus  int
+		wantCalls   string
+		wantMarket  models.MarketStatus
+		wantAudited string
+	}{
+		{
+			name:        "open market",
+			wantStatus:  http.StatusOK,
+			wantCalls:   "Close CancelRestingOrders Cancel GetByID",
+			wantMarket:  models.MarketStatusCancelled,
+			wantAudited: "MARKET_CLOSE MARKET_CANCEL",
+		},
+		{
+			name:        "failed halt",
+			haltFails:   true,
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCalls:   "Close",
+			wantMarket:  models.MarketStatusClosed,
+			wantAudited: "MARKET_CLOSE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market := &models.Market{ID: uuid.New(), Ticker: "MARKET", Type: models.MarketTypeBinary, Status: models.MarketStatusOpen}
+			store := &fakeStore{
+				market:    market,
+				contracts: []*models.Contract{{ID: uuid.New(), MarketID: market.ID, Ticker: "MARKET-YES"}},
+			}
+			audit := &fakeAudit{}
+			lifecycle := scheduler.NewLifecycleScheduler(store, &fakeEngine{fail: tt.haltFails}, nil, time.Minute)
+			h := NewAdminHandler(store, nil, nil, audit, lifecycle)
+
+			w := httptest.NewRecorder()
+			h.CancelMarket(w, adminRequest(http.MethodPost, `{"market_id":"`+market.ID.String()+`"}`, models.UserRoleAdmin))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := strings.Join(store.calls, " "); got != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", got, tt.wantCalls)
+			}
+			if market.Status != tt.wantMarket {
+				t.Errorf("market left %s, want %s", market.Status, tt.wantMarket)
+			}
+			if got := fmtActions(audit.actions); got != tt.wantAudited {
+				t.Errorf("audited %q, want %q", got, tt.wantAudited)
+			}
+		})
+	}
+}
+
+// fmtActions joins audited actions with spaces
+func fmtActions(actions []models.AuditAction) string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = string(action)
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+)
+
+// EventHandler handles HTTP requests for events grouping related markets
+type EventHandler struct {
+	repo *repository.EventRepository
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(repo *repository.EventRepository) *EventHandler {
+	return &EventHandler{repo: repo}
+}
+
+// ListEvents handles listing events with public markets
+func (h *EventHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	category := r.URL.Query().Get("category")
+	page := parseInt(r.URL.Query().Get("page"), 1)
+	pageSize := parseInt(r.URL.Query().Get("page_size"), 20)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	events, totalCount, err := h.repo.List(r.Context(), category, page, pageSize)
+	if err != nil {
+		respondError(w, "Failed to list events", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.EventListResponse{
+		Events:     events,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
+// EventDetail handles retrieving an event page with its public markets
+func (h *EventHandler) EventDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventIDStr := r.URL.Query().Get("id")
+	if eventIDStr == "" {
+		respondError(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	eventID, err := uuid.Parse(eventIDStr)
+	if err != nil {
+		respondError(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.repo.GetSummary(r.Context(), eventID)
+	if err != nil {
+		if err == repository.ErrEventNotFound {
+			respondError(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, "Failed to get event", http.StatusInternalServerError)
+		return
+	}
+
+	markets, err := h.repo.GetMarkets(r.Context(), eventID, false)
+	if err != nil {
+		respondError(w, "Failed to get event markets", http.StatusInternalServerError)
+		return
+	}
+
+	// Events without public markets are not public either
+	if len(markets) == 0 {
+		respondError(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, models.EventDetail{EventSummary: summary, Markets: markets}, http.StatusOK)
+}
+
+// CreateEvent handles creating an event that markets can then be attached to
+func (h *AdminHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.EventCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Ticker) == 0 || len(req.Ticker) > 50 || !tickerPattern.MatchString(req.Ticker) {
+		respondError(w, "Ticker must be 1-50 uppercase letters, digits or underscores", http.StatusBadRequest)
+		return
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || len(req.Title) > 255 {
+		respondError(w, "Title must be between 1 and 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	if req.Category != nil && !categoryPattern.MatchString(*req.Category) {
+		respondError(w, "Category must be a lowercase slug of at most 50 characters", http.StatusBadRequest)
+		return
+	}
+
+	if !req.ClosesAt.After(time.Now()) {
+		respondError(w, "Closing time must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	event := &models.Event{
+		ID:          uuid.New(),
+		Ticker:      req.Ticker,
+		Title:       req.Title,
+		Description: req.Description,
+		Category:    req.Category,
+		ClosesAt:    req.ClosesAt,
+	}
+
+	if err := h.eventRepo.Create(r.Context(), event); err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionEventCreate, nil, event)
+
+	respondJSON(w, event, http.StatusCreated)
+}
+
+// SetEventMarkets handles attaching markets to an event or detaching them
+func (h *AdminHandler) SetEventMarkets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.EventMarketsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.MarketIDs) == 0 || len(req.MarketIDs) > 100 {
+		respondError(w, "Between 1 and 100 market IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	markets, err := h.eventRepo.SetMarkets(r.Context(), req.EventID, req.MarketIDs)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	for _, market := range markets {
+		h.audit(r, adminID, models.AuditActionEventMarkets, &market.ID, req)
+	}
+
+	respondJSON(w, map[string]interface{}{"markets": markets}, http.StatusOK)
+}
+
+// ResolveEvent handles resolving every unsettled market of an event together
+func (h *AdminHandler) ResolveEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.EventResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.EventID == uuid.Nil {
+		respondError(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Resolutions) == 0 {
+		respondError(w, "Resolutions are required", http.StatusBadRequest)
+		return
+	}
+
+	// Stopping trading cannot be undone, so the resolutions are checked first
+	if err := h.eventRepo.CheckResolutions(r.Context(), req.EventID, req.Resolutions); err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	if !h.stopEventTrading(w, r, adminID, req.EventID) {
+		return
+	}
+
+	settlements, err := h.eventRepo.ResolveAll(r.Context(), req.EventID, req.Resolutions)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	for _, settlement := range settlements {
+		h.publishStatus(r, settlement.MarketID, models.MarketLifecycleResolved)
+		h.audit(r, adminID, models.AuditActionEventResolve, &settlement.MarketID, map[string]interface{}{
+			"event_id":   req.EventID,
+			"settlement": settlement,
+		})
+	}
+
+	respondJSON(w, map[string]interface{}{"settlements": settlements}, http.StatusOK)
+}
+
+// CancelEvent handles cancelling every unsettled market of an event and
+// refunding their positions
+func (h *AdminHandler) CancelEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.EventActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.EventID == uuid.Nil {
+		respondError(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.stopEventTrading(w, r, adminID, req.EventID) {
+		return
+	}
+
+	settlements, err := h.eventRepo.CancelAll(r.Context(), req.EventID)
+	if err != nil {
+		respondMarketActionError(w, err)
+		return
+	}
+
+	for _, settlement := range settlements {
+		h.publishStatus(r, settlement.MarketID, models.MarketLifecycleCancelled)
+		h.audit(r, adminID, models.AuditActionEventCancel, &settlement.MarketID, map[string]interface{}{
+			"request":    req,
+			"settlement": settlement,
+		})
+	}
+
+	respondJSON(w, map[string]interface{}{"settlements": settlements}, http.StatusOK)
+}
+
+// stopEventTrading closes every still-trading market of an event
+func (h *AdminHandler) stopEventTrading(w http.ResponseWriter, r *http.Request, adminID, eventID uuid.UUID) bool {
+	markets, err := h.eventRepo.GetMarkets(r.Context(), eventID, false)
+	if err != nil {
+		respondError(w, "Failed to get event markets", http.StatusInternalServerError)
+		return false
+	}
+
+	for _, market := range markets {
+		if market.Status != models.MarketStatusOpen && market.Status != models.MarketStatusUpcoming {
+			continue
+		}
+		if !h.stopTrading(w, r, adminID, market.ID) {
+			return false
+		}
+	}
+
+	return true
+}
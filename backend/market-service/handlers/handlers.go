@@ -5,11 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	pb "lfg/matching-engine/proto"
 	"lfg/shared/models"
@@ -18,15 +17,15 @@ import (
 
 // MarketHandler handles HTTP requests for market operations
 type MarketHandler struct {
-	repo               *repository.MarketRepository
-	matchingEngineAddr string
+	repo   *repository.MarketRepository
+	engine pb.MatchingEngineClient
 }
 
 // NewMarketHandler creates a new market handler
-func NewMarketHandler(repo *repository.MarketRepository, matchingEngineAddr string) *MarketHandler {
+func NewMarketHandler(repo *repository.MarketRepository, engine pb.MatchingEngineClient) *MarketHandler {
 	return &MarketHandler{
-		repo:               repo,
-		matchingEngineAddr: matchingEngineAddr,
+		repo:   repo,
+		engine: engine,
 	}
 }
 
@@ -37,33 +36,50 @@ func (h *MarketHandler) ListMarkets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters
-	status := r.URL.Query().Get("status")
-	search := r.URL.Query().Get("search")
-	page := parseInt(r.URL.Query().Get("page"), 1)
-	pageSize := parseInt(r.URL.Query().Get("page_size"), 20)
+	listMarkets(w, r, h.repo, parseMarketFilter(r))
+}
+
+// Autocomplete handles suggesting markets by ticker or question prefix
+func (h *MarketHandler) Autocomplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := strings.TrimSpace(r.URL.Query().Get("q"))
+	if prefix == "" || len(prefix) > 100 {
+		respondError(w, "Query must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
 
-	// Validate page size
-	if pageSize > 100 {
-		pageSize = 100
+	limit := parseInt(r.URL.Query().Get("limit"), 10)
+	if limit <= 0 || limit > 25 {
+		limit = 10
 	}
 
-	// Get markets
-	markets, totalCount, err := h.repo.List(r.Context(), status, search, page, pageSize)
+	suggestions, err := h.repo.Autocomplete(r.Context(), prefix, limit)
 	if err != nil {
-		respondError(w, "Failed to list markets", http.StatusInternalServerError)
+		respondError(w, "Failed to get suggestions", http.StatusInternalServerError)
 		return
 	}
 
-	// Return response
-	response := models.MarketListResponse{
-		Markets:    markets,
-		TotalCount: totalCount,
-		Page:       page,
-		PageSize:   pageSize,
+	respondJSON(w, map[string]interface{}{"suggestions": suggestions}, http.StatusOK)
+}
+
+// Categories handles listing market categories
+func (h *MarketHandler) Categories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	respondJSON(w, response, http.StatusOK)
+	categories, err := h.repo.ListCategories(r.Context())
+	if err != nil {
+		respondError(w, "Failed to list categories", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"categories": categories}, http.StatusOK)
 }
 
 // MarketDetail handles retrieving detailed information for a single market
@@ -86,8 +102,11 @@ func (h *MarketHandler) MarketDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get market
+	// Get market (drafts are not public)
 	market, err := h.repo.GetByID(r.Context(), marketID)
+	if err == nil && market.Status == models.MarketStatusDraft {
+		err = repository.ErrMarketNotFound
+	}
 	if err != nil {
 		if err == repository.ErrMarketNotFound {
 			respondError(w, "Market not found", http.StatusNotFound)
@@ -97,6 +116,13 @@ func (h *MarketHandler) MarketDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get outcomes
+	outcomes, err := h.repo.GetOutcomesByMarketID(r.Context(), marketID)
+	if err != nil {
+		respondError(w, "Failed to get outcomes", http.StatusInternalServerError)
+		return
+	}
+
 	// Get contracts
 	contracts, err := h.repo.GetContractsByMarketID(r.Context(), marketID)
 	if err != nil {
@@ -107,6 +133,7 @@ func (h *MarketHandler) MarketDetail(w http.ResponseWriter, r *http.Request) {
 	// Return response
 	response := map[string]interface{}{
 		"market":    market,
+		"outcomes":  outcomes,
 		"contracts": contracts,
 	}
 
@@ -134,16 +161,7 @@ func (h *MarketHandler) OrderBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch order book from matching engine via gRPC
-	conn, err := grpc.NewClient(h.matchingEngineAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		respondError(w, "Failed to connect to matching engine", http.StatusInternalServerError)
-		return
-	}
-	defer conn.Close()
-
-	client := pb.NewMatchingEngineClient(conn)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
 	grpcReq := &pb.GetOrderBookRequest{
@@ -151,7 +169,7 @@ func (h *MarketHandler) OrderBook(w http.ResponseWriter, r *http.Request) {
 		Depth:      20,
 	}
 
-	resp, err := client.GetOrderBook(ctx, grpcReq)
+	resp, err := h.engine.GetOrderBook(ctx, grpcReq)
 	if err != nil {
 		// Return empty order book if matching engine call fails
 		respondJSON(w, map[string]interface{}{
@@ -188,6 +206,189 @@ func (h *MarketHandler) OrderBook(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+// Positions handles listing the authenticated user's contract positions
+func (h *MarketHandler) Positions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract user ID from header (set by API gateway)
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Optional market filter
+	var marketID *uuid.UUID
+	if marketIDStr := r.URL.Query().Get("market_id"); marketIDStr != "" {
+		id, err := uuid.Parse(marketIDStr)
+		if err != nil {
+			respondError(w, "Invalid market ID", http.StatusBadRequest)
+			return
+		}
+		marketID = &id
+	}
+
+	positions, err := h.repo.GetPositionsByUserID(r.Context(), userID, marketID)
+	if err != nil {
+		respondError(w, "Failed to get positions", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"positions": positions}, http.StatusOK)
+}
+
+// MintCompleteSet handles buying complete sets: one contract of every outcome
+// of a market for one credit per set
+func (h *MarketHandler) MintCompleteSet(w http.ResponseWriter, r *http.Request) {
+	h.completeSet(w, r, h.repo.MintCompleteSet, -models.CompleteSetValueCredits)
+}
+
+// RedeemCompleteSet handles selling complete sets back for one credit per set
+func (h *MarketHandler) RedeemCompleteSet(w http.ResponseWriter, r *http.Request) {
+	h.completeSet(w, r, h.repo.RedeemCompleteSet, models.CompleteSetValueCredits)
+}
+
+type completeSetFunc func(ctx context.Context, userID, marketID uuid.UUID, quantity int) ([]*models.Position, error)
+
+func (h *MarketHandler) completeSet(w http.ResponseWriter, r *http.Request, apply completeSetFunc, creditsPerSet float64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract user ID from header (set by API gateway)
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request
+	var req models.CompleteSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Quantity <= 0 || req.Quantity > 10000 {
+		respondError(w, "Quantity must be between 1 and 10000", http.StatusBadRequest)
+		return
+	}
+
+	positions, err := apply(r.Context(), userID, req.MarketID, req.Quantity)
+	if err != nil {
+		switch err {
+		case repository.ErrMarketNotFound:
+			respondError(w, "Market not found", http.StatusNotFound)
+		case repository.ErrMarketNotOpen:
+			respondError(w, "Market is not open", http.StatusConflict)
+		case repository.ErrWalletNotFound:
+			respondError(w, "Wallet not found", http.StatusNotFound)
+		case repository.ErrInsufficientBalance:
+			respondError(w, "Insufficient balance", http.StatusBadRequest)
+		case repository.ErrInsufficientPosition:
+			respondError(w, "Insufficient position to redeem complete sets", http.StatusBadRequest)
+		default:
+			respondError(w, "Failed to process complete sets", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.CompleteSetResponse{
+		MarketID:  req.MarketID,
+		Quantity:  req.Quantity,
+		Credits:   float64(req.Quantity) * creditsPerSet,
+		Positions: positions,
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
+// parseMarketFilter reads the listing filters of a market list request. Tags
+// may be repeated or comma separated; markets must carry all of them.
+func parseMarketFilter(r *http.Request) *models.MarketListFilter {
+	query := r.URL.Query()
+
+	filter := &models.MarketListFilter{
+		Status:   query.Get("status"),
+		Search:   strings.TrimSpace(query.Get("search")),
+		Category: query.Get("category"),
+		Page:     parseInt(query.Get("page"), 1),
+		PageSize: parseInt(query.Get("page_size"), 20),
+	}
+
+	if eventID, err := uuid.Parse(query.Get("event_id")); err == nil {
+		filter.EventID = &eventID
+	}
+
+	if templateID, err := uuid.Parse(query.Get("template_id")); err == nil {
+		filter.TemplateID = &templateID
+	}
+
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	// Validate pagination
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = 20
+	}
+	if filter.PageSize > 100 {
+		filter.PageSize = 100
+	}
+
+	return filter
+}
+
+// marketLister lists markets and counts their facets
+type marketLister interface {
+	List(ctx context.Context, filter *models.MarketListFilter) ([]*models.Market, int, error)
+	Facets(ctx context.Context, filter *models.MarketListFilter) (*models.MarketFacets, error)
+}
+
+// listMarkets responds with a page of markets matching filter, including
+// facet counts when the request asks for them
+func listMarkets(w http.ResponseWriter, r *http.Request, repo marketLister, filter *models.MarketListFilter) {
+	markets, totalCount, err := repo.List(r.Context(), filter)
+	if err != nil {
+		respondError(w, "Failed to list markets", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.MarketListResponse{
+		Markets:    markets,
+		TotalCount: totalCount,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+	}
+
+	if r.URL.Query().Get("facets") == "true" {
+		facets, err := repo.Facets(r.Context(), filter)
+		if err != nil {
+			respondError(w, "Failed to count facets", http.StatusInternalServerError)
+			return
+		}
+		response.Facets = facets
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
 // Health check handler
 func Health(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, map[string]string{"status": "healthy"}, http.StatusOK)
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+	"lfg/market-service/resolution"
+)
+
+// ResolutionHandler handles HTTP requests for market resolution proposals and disputes
+type ResolutionHandler struct {
+	repo        *repository.MarketRepository
+	auditRepo   *repository.AuditRepository
+	oracle      *resolution.Oracle
+	disputeBond float64
+}
+
+// NewResolutionHandler creates a new resolution handler
+func NewResolutionHandler(repo *repository.MarketRepository, auditRepo *repository.AuditRepository, oracle *resolution.Oracle, disputeBond float64) *ResolutionHandler {
+	return &ResolutionHandler{
+		repo:        repo,
+		auditRepo:   auditRepo,
+		oracle:      oracle,
+		disputeBond: disputeBond,
+	}
+}
+
+// History handles retrieving the resolver, proposals and disputes of a market
+func (h *ResolutionHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	marketID, err := uuid.Parse(r.URL.Query().Get("market_id"))
+	if err != nil {
+		respondError(w, "Invalid market ID", http.StatusBadRequest)
+		return
+	}
+
+	// Drafts are not public
+	market, err := h.repo.GetByID(r.Context(), marketID)
+	if err == nil && market.Status == models.MarketStatusDraft {
+		err = repository.ErrMarketNotFound
+	}
+	if err != nil {
+		if err == repository.ErrMarketNotFound {
+			respondError(w, "Market not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, "Failed to get market", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.repo.GetResolutionHistory(r.Context(), marketID)
+	if err != nil {
+		respondError(w, "Failed to get resolution history", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, history, http.StatusOK)
+}
+
+// Dispute handles a user challenging a pending proposal, posting the dispute bond
+func (h *ResolutionHandler) Dispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract user ID from header (set by API gateway)
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ResolutionDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProposalID == uuid.Nil {
+		respondError(w, "Proposal ID is required", http.StatusBadRequest)
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > 2000 {
+		respondError(w, "Reason must be between 1 and 2000 characters", http.StatusBadRequest)
+		return
+	}
+
+	dispute := &models.ResolutionDispute{
+		ID:          uuid.New(),
+		ProposalID:  req.ProposalID,
+		UserID:      userID,
+		BondCredits: h.disputeBond,
+		Reason:      req.Reason,
+	}
+
+	proposal, err := h.repo.Dispute(r.Context(), dispute)
+	if err != nil {
+		respondResolutionError(w, err)
+		return
+	}
+
+	h.oracle.PublishStatus(r.Context(), proposal.MarketID, models.MarketLifecycleResolutionDisputed)
+
+	respondJSON(w, map[string]interface{}{
+		"dispute":  dispute,
+		"proposal": proposal,
+	}, http.StatusCreated)
+}
+
+// ConfigureResolver handles setting the automated resolver of a market
+func (h *ResolutionHandler) ConfigureResolver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketResolverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Building the resolver validates its type and configuration
+	if _, err := resolution.NewResolver(req.Type, req.Config, time.Second); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.GetByID(r.Context(), req.MarketID); err != nil {
+		respondResolutionError(w, err)
+		return
+	}
+
+	resolver := &models.MarketResolver{
+		MarketID: req.MarketID,
+		Type:     req.Type,
+		Config:   req.Config,
+	}
+
+	if err := h.repo.SetResolver(r.Context(), resolver); err != nil {
+		respondError(w, "Failed to set resolver", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionResolverConfigure, &req.MarketID, req)
+
+	resolver, err := h.repo.GetResolver(r.Context(), req.MarketID)
+	if err != nil {
+		respondError(w, "Failed to get resolver", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, resolver, http.StatusOK)
+}
+
+// Propose handles an admin proposing the result of a closed market, opening
+// its challenge window
+func (h *ResolutionHandler) Propose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ResolutionProposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.MarketID == uuid.Nil {
+		respondError(w, "Market ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if (req.OutcomeID == uuid.Nil) == (req.Value == nil) {
+		respondError(w, "Exactly one of outcome ID or value is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Evidence) > 2000 {
+		respondError(w, "Evidence must be at most 2000 characters", http.StatusBadRequest)
+		return
+	}
+
+	proposal := &models.ResolutionProposal{
+		ID:              uuid.New(),
+		MarketID:        req.MarketID,
+		ProposerUserID:  &adminID,
+		Source:          models.ProposalSourceAdmin,
+		Value:           req.Value,
+		ChallengeEndsAt: time.Now().Add(h.oracle.ChallengeWindow()),
+	}
+	if req.OutcomeID != uuid.Nil {
+		proposal.OutcomeID = &req.OutcomeID
+	}
+	if req.Evidence != "" {
+		proposal.Evidence = &req.Evidence
+	}
+
+	if err := h.repo.CreateProposal(r.Context(), proposal); err != nil {
+		respondResolutionError(w, err)
+		return
+	}
+
+	h.oracle.PublishStatus(r.Context(), proposal.MarketID, models.MarketLifecycleResolutionProposed)
+
+	h.audit(r, adminID, models.AuditActionResolutionPropose, &req.MarketID, proposal)
+
+	respondJSON(w, proposal, http.StatusCreated)
+}
+
+// Adjudicate handles an admin ruling on a disputed proposal
+func (h *ResolutionHandler) Adjudicate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ResolutionAdjudicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProposalID == uuid.Nil {
+		respondError(w, "Proposal ID is required", http.StatusBadRequest)
+		return
+	}
+
+	proposal, settlement, err := h.repo.Adjudicate(r.Context(), req.ProposalID, req.Uphold)
+	if err != nil {
+		respondResolutionError(w, err)
+		return
+	}
+
+	if settlement != nil {
+		h.oracle.PublishStatus(r.Context(), proposal.MarketID, models.MarketLifecycleResolved)
+	}
+
+	h.audit(r, adminID, models.AuditActionResolutionAdjudicate, &proposal.MarketID, map[string]interface{}{
+		"request":    req,
+		"settlement": settlement,
+	})
+
+	respondJSON(w, map[string]interface{}{
+		"proposal":   proposal,
+		"settlement": settlement,
+	}, http.StatusOK)
+}
+
+// audit records an admin action; failures are logged but do not fail the request
+func (h *ResolutionHandler) audit(r *http.Request, adminID uuid.UUID, action models.AuditAction, marketID *uuid.UUID, details interface{}) {
+	if err := h.auditRepo.Record(r.Context(), adminID, action, marketID, details); err != nil {
+		log.Printf("Failed to record audit entry %s by %s: %v", action, adminID, err)
+	}
+}
+
+// respondResolutionError maps repository errors of resolution actions to responses
+func respondResolutionError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrProposalNotFound:
+		respondError(w, "Proposal not found", http.StatusNotFound)
+	case repository.ErrMarketNotClosed:
+		respondError(w, "Market must be closed before resolution is proposed", http.StatusConflict)
+	case repository.ErrProposalPending:
+		respondError(w, "Market already has a pending resolution proposal", http.StatusConflict)
+	case repository.ErrProposalNotDisputable:
+		respondError(w, "Proposal can no longer be disputed", http.StatusConflict)
+	case repository.ErrProposalNotDisputed:
+		respondError(w, "Proposal is not disputed", http.StatusConflict)
+	case repository.ErrChallengeWindowClosed:
+		respondError(w, "Challenge window has closed", http.StatusConflict)
+	case repository.ErrAlreadyDisputed:
+		respondError(w, "You have already disputed this proposal", http.StatusConflict)
+	case repository.ErrInsufficientBalance:
+		respondError(w, "Insufficient balance for dispute bond", http.StatusBadRequest)
+	case repository.ErrWalletNotFound:
+		respondError(w, "Wallet not found", http.StatusNotFound)
+	default:
+		respondMarketActionError(w, err)
+	}
+}
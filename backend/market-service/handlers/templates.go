@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+	"lfg/market-service/scheduler"
+)
+
+// ListTemplates handles listing recurring market templates
+func (h *AdminHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	templates, err := h.templateRepo.List(r.Context())
+	if err != nil {
+		respondError(w, "Failed to list templates", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"templates": templates}, http.StatusOK)
+}
+
+// CreateTemplate handles creating a recurring market template. The first run
+// is rendered and validated like a created market so that broken patterns
+// are rejected up front.
+func (h *AdminHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketTemplateCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		respondError(w, "Name must be between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	if req.IntervalSeconds < 60 {
+		respondError(w, "Interval must be at least 60 seconds", http.StatusBadRequest)
+		return
+	}
+
+	if req.DurationSeconds < 60 {
+		respondError(w, "Duration must be at least 60 seconds", http.StatusBadRequest)
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = models.MarketTypeBinary
+	}
+
+	firstRunAt := time.Now()
+	if req.FirstRunAt != nil {
+		firstRunAt = *req.FirstRunAt
+	}
+
+	autoPublish := true
+	if req.AutoPublish != nil {
+		autoPublish = *req.AutoPublish
+	}
+
+	tmpl := &models.MarketTemplate{
+		ID:               uuid.New(),
+		Name:             req.Name,
+		TickerPattern:    req.TickerPattern,
+		QuestionPattern:  req.QuestionPattern,
+		RulesPattern:     req.RulesPattern,
+		ResolutionSource: req.ResolutionSource,
+		Type:             req.Type,
+		Outcomes:         req.Outcomes,
+		LowerBound:       req.LowerBound,
+		UpperBound:       req.UpperBound,
+		Category:         req.Category,
+		Tags:             req.Tags,
+		EventID:          req.EventID,
+		Params:           req.Params,
+		IntervalSeconds:  req.IntervalSeconds,
+		DurationSeconds:  req.DurationSeconds,
+		AutoPublish:      autoPublish,
+		Active:           true,
+		NextRunAt:        firstRunAt,
+	}
+
+	// Validate the first market the template would create
+	sample, err := renderTemplateSample(tmpl)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tmpl.Outcomes = sample.Outcomes
+	tmpl.Tags = sample.Tags
+
+	if err := h.templateRepo.Create(r.Context(), tmpl); err != nil {
+		switch err {
+		case repository.ErrTemplateNameTaken:
+			respondError(w, "Template name already exists", http.StatusConflict)
+		default:
+			respondMarketActionError(w, err)
+		}
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionTemplateCreate, nil, tmpl)
+
+	respondJSON(w, map[string]interface{}{
+		"template": tmpl,
+		"sample":   sample,
+	}, http.StatusCreated)
+}
+
+// UpdateTemplate handles changing a template's parameters or schedule, or
+// pausing and resuming it
+func (h *AdminHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminID, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarketTemplateUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TemplateID == uuid.Nil {
+		respondError(w, "Template ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Params != nil {
+		tmpl, err := h.templateRepo.GetByID(r.Context(), req.TemplateID)
+		if err != nil {
+			respondTemplateError(w, err)
+			return
+		}
+
+		tmpl.Params = req.Params
+		if _, err := renderTemplateSample(tmpl); err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tmpl, err := h.templateRepo.Update(r.Context(), &req)
+	if err != nil {
+		respondTemplateError(w, err)
+		return
+	}
+
+	h.audit(r, adminID, models.AuditActionTemplateUpdate, nil, req)
+
+	respondJSON(w, tmpl, http.StatusOK)
+}
+
+// renderTemplateSample renders a template's next run and validates it as a
+// market create request, normalizing the template's outcomes and tags
+func renderTemplateSample(tmpl *models.MarketTemplate) (*models.MarketCreateRequest, error) {
+	runAt := tmpl.NextRunAt
+	if runAt.Before(time.Now()) {
+		runAt = time.Now()
+	}
+
+	market, err := scheduler.RenderTemplate(tmpl, runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &models.MarketCreateRequest{
+		Ticker:           market.Ticker,
+		Question:         market.Question,
+		Rules:            market.Rules,
+		ResolutionSource: market.ResolutionSource,
+		Category:         market.Category,
+		Tags:             market.Tags,
+		EventID:          market.EventID,
+		Type:             market.Type,
+		LowerBound:       market.LowerBound,
+		UpperBound:       market.UpperBound,
+		ExpiresAt:        market.ExpiresAt,
+	}
+	if market.Type == models.MarketTypeCategorical {
+		sample.Outcomes = tmpl.Outcomes
+	}
+
+	outcomeNames, err := validateMarketCreate(sample)
+	if err != nil {
+		return nil, err
+	}
+	if market.Type == models.MarketTypeCategorical {
+		sample.Outcomes = outcomeNames
+	}
+
+	return sample, nil
+}
+
+// respondTemplateError maps repository errors of template actions to responses
+func respondTemplateError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrTemplateNotFound:
+		respondError(w, "Template not found", http.StatusNotFound)
+	default:
+		respondError(w, "Failed to update template", http.StatusInternalServerError)
+	}
+}
@@ -10,10 +10,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"lfg/shared/auth"
 	"lfg/shared/config"
 	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 	"lfg/market-service/handlers"
 	"lfg/market-service/repository"
+	"lfg/market-service/resolution"
+	"lfg/market-service/scheduler"
+	pb "lfg/matching-engine/proto"
 )
 
 func main() {
@@ -23,6 +33,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceMarket, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create context
 	ctx := context.Background()
 
@@ -45,26 +62,110 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close(pool)
+	metrics.RegisterPool(pool)
 
 	log.Println("Connected to database successfully")
 
-	// Initialize repository
+	// Initialize repositories
 	marketRepo := repository.NewMarketRepository(pool)
+	eventRepo := repository.NewEventRepository(pool)
+	templateRepo := repository.NewTemplateRepository(pool)
+	auditRepo := repository.NewAuditRepository(pool)
+
+	// Connect to NATS for market lifecycle events
+	natsConn, err := nats.Connect(cfg.NATSURL)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to NATS: %v", err)
+		log.Println("Continuing without NATS (lifecycle events will not be published)")
+	} else {
+		log.Printf("Connected to NATS at %s", cfg.NATSURL)
+		defer natsConn.Close()
+	}
+
+	// Start market lifecycle scheduler
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	defer stopScheduler()
+
+	// Initialize service authentication
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceMarket, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway, "/markets", "/markets/", "/events", "/events/", "/admin/")
+
+	// Markets are halted and order books read over one matching engine
+	// connection rather than one dialled per call
+	conn, err := grpc.NewClient(cfg.MatchingEngineGRPC,
+		grpc.WithTransportCredentials(serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(serviceTokens.Credentials(auth.ServiceMatchingEngine)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to matching engine: %v", err)
+	}
+	defer conn.Close()
+	engineClient := pb.NewMatchingEngineClient(conn)
+
+	lifecycle := scheduler.NewLifecycleScheduler(marketRepo, engineClient, natsConn, cfg.MarketSchedulerInterval)
+	go lifecycle.Run(schedulerCtx)
+	log.Printf("Market lifecycle scheduler running every %s", cfg.MarketSchedulerInterval)
+
+	// Start recurring market template scheduler
+	templates := scheduler.NewTemplateScheduler(marketRepo, templateRepo, lifecycle, cfg.MarketSchedulerInterval)
+	go templates.Run(schedulerCtx)
+
+	// Start resolution oracle
+	oracle := resolution.NewOracle(marketRepo, lifecycle, cfg.ResolutionChallengeWindow, cfg.ResolverTimeout, cfg.MarketSchedulerInterval)
+	go oracle.Run(schedulerCtx)
 
 	// Initialize handlers
-	marketHandler := handlers.NewMarketHandler(marketRepo, cfg.MatchingEngineGRPC)
+	marketHandler := handlers.NewMarketHandler(marketRepo, engineClient)
+	adminHandler := handlers.NewAdminHandler(marketRepo, eventRepo, templateRepo, auditRepo, lifecycle)
+	eventHandler := handlers.NewEventHandler(eventRepo)
+	resolutionHandler := handlers.NewResolutionHandler(marketRepo, auditRepo, oracle, cfg.ResolutionDisputeBond)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
 	mux.HandleFunc("/markets", marketHandler.ListMarkets)
 	mux.HandleFunc("/markets/detail", marketHandler.MarketDetail)
+	mux.HandleFunc("/markets/autocomplete", marketHandler.Autocomplete)
+	mux.HandleFunc("/markets/categories", marketHandler.Categories)
 	mux.HandleFunc("/markets/orderbook", marketHandler.OrderBook)
+	mux.HandleFunc("/markets/positions", marketHandler.Positions)
+	mux.HandleFunc("/markets/sets/mint", marketHandler.MintCompleteSet)
+	mux.HandleFunc("/markets/sets/redeem", marketHandler.RedeemCompleteSet)
+	mux.HandleFunc("/markets/resolution", resolutionHandler.History)
+	mux.HandleFunc("/markets/resolution/dispute", resolutionHandler.Dispute)
+	mux.HandleFunc("/events", eventHandler.ListEvents)
+	mux.HandleFunc("/events/detail", eventHandler.EventDetail)
+
+	// Admin routes (admin role enforced by the API gateway and handlers)
+	mux.HandleFunc("/admin/markets", adminHandler.ListMarkets)
+	mux.HandleFunc("/admin/markets/create", adminHandler.CreateMarket)
+	mux.HandleFunc("/admin/markets/update", adminHandler.UpdateMarket)
+	mux.HandleFunc("/admin/markets/publish", adminHandler.PublishMarket)
+	mux.HandleFunc("/admin/markets/close", adminHandler.CloseMarket)
+	mux.HandleFunc("/admin/markets/cancel", adminHandler.CancelMarket)
+	mux.HandleFunc("/admin/markets/resolve", adminHandler.ResolveMarket)
+	mux.HandleFunc("/admin/markets/audit-log", adminHandler.AuditLog)
+	mux.HandleFunc("/admin/markets/resolver", resolutionHandler.ConfigureResolver)
+	mux.HandleFunc("/admin/markets/resolution/propose", resolutionHandler.Propose)
+	mux.HandleFunc("/admin/markets/resolution/adjudicate", resolutionHandler.Adjudicate)
+	mux.HandleFunc("/admin/events/create", adminHandler.CreateEvent)
+	mux.HandleFunc("/admin/events/markets", adminHandler.SetEventMarkets)
+	mux.HandleFunc("/admin/events/resolve", adminHandler.ResolveEvent)
+	mux.HandleFunc("/admin/events/cancel", adminHandler.CancelEvent)
+	mux.HandleFunc("/admin/templates", adminHandler.ListTemplates)
+	mux.HandleFunc("/admin/templates/create", adminHandler.CreateTemplate)
+	mux.HandleFunc("/admin/templates/update", adminHandler.UpdateTemplate)
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceMarket, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -84,6 +185,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopScheduler()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
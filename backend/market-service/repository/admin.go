@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrMarketNotDraft    = errors.New("market is not a draft")
+	ErrInvalidTransition = errors.New("market status does not allow this action")
+	ErrInvalidSchedule   = errors.New("market must open before it expires and expire in the future")
+	ErrInvalidBounds     = errors.New("scalar lower bound must be below upper bound")
+)
+
+// UpdateDraft applies the set fields of req to a draft market
+func (r *MarketRepository) UpdateDraft(ctx context.Context, req *models.MarketUpdateRequest) (*models.Market, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	market, err := lockMarket(ctx, tx, req.MarketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if market.Status != models.MarketStatusDraft {
+		return nil, ErrMarketNotDraft
+	}
+
+	if req.Question != nil {
+		market.Question = *req.Question
+	}
+	if req.Rules != nil {
+		market.Rules = *req.Rules
+	}
+	if req.ResolutionSource != nil {
+		market.ResolutionSource = *req.ResolutionSource
+	}
+	if req.Category != nil {
+		market.Category = req.Category
+		if *req.Category == "" {
+			market.Category = nil
+		}
+	}
+	if req.Tags != nil {
+		market.Tags = req.Tags
+	}
+	if req.OpensAt != nil {
+		market.OpensAt = req.OpensAt
+	}
+	if req.ExpiresAt != nil {
+		market.ExpiresAt = *req.ExpiresAt
+	}
+	if market.Type == models.MarketTypeScalar {
+		if req.LowerBound != nil {
+			market.LowerBound = req.LowerBound
+		}
+		if req.UpperBound != nil {
+			market.UpperBound = req.UpperBound
+		}
+	}
+
+	if !market.ExpiresAt.After(time.Now()) || (market.OpensAt != nil && !market.OpensAt.Before(market.ExpiresAt)) {
+		return nil, ErrInvalidSchedule
+	}
+	if market.Type == models.MarketTypeScalar && !(*market.LowerBound < *market.UpperBound) {
+		return nil, ErrInvalidBounds
+	}
+
+	err = scanMarket(tx.QueryRow(ctx, `
+		UPDATE markets
+		SET question = $2, rules = $3, resolution_source = $4, scalar_lower_bound = $5,
+		    scalar_upper_bound = $6, opens_at = $7, expires_at = $8, category = $9, tags = $10,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+marketColumns,
+		market.ID,
+		market.Question,
+		market.Rules,
+		market.ResolutionSource,
+		market.LowerBound,
+		market.UpperBound,
+		market.OpensAt,
+		market.ExpiresAt,
+		market.Category,
+		market.Tags,
+	), market)
+	if err != nil {
+		if err := foreignKeyError(err); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update market: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return market, nil
+}
+
+// Publish moves a draft market to UPCOMING, or straight to OPEN when it has no
+// open time or its open time has already passed
+func (r *MarketRepository) Publish(ctx context.Context, marketID uuid.UUID) (*models.Market, error) {
+	return r.transition(ctx, marketID, func(market *models.Market) (models.MarketStatus, error) {
+		if market.Status != models.MarketStatusDraft {
+			return "", ErrMarketNotDraft
+		}
+		if !market.ExpiresAt.After(time.Now()) {
+			return "", ErrInvalidTransition
+		}
+		if market.OpensAt != nil && market.OpensAt.After(time.Now()) {
+			return models.MarketStatusUpcoming, nil
+		}
+		return models.MarketStatusOpen, nil
+	})
+}
+
+// Close stops trading on an UPCOMING or OPEN market ahead of resolution
+func (r *MarketRepository) Close(ctx context.Context, marketID uuid.UUID) (*models.Market, error) {
+	return r.transition(ctx, marketID, func(market *models.Market) (models.MarketStatus, error) {
+		if market.Status != models.MarketStatusUpcoming && market.Status != models.MarketStatusOpen {
+			return "", ErrInvalidTransition
+		}
+		return models.MarketStatusClosed, nil
+	})
+}
+
+// transition locks a market, asks next for its new status and stores it
+func (r *MarketRepository) transition(ctx context.Context, marketID uuid.UUID, next func(*models.Market) (models.MarketStatus, error)) (*models.Market, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	market, err := lockMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := next(market)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanMarket(tx.QueryRow(ctx, `
+		UPDATE markets SET status = $2, updated_at = NOW() WHERE id = $1
+		RETURNING `+marketColumns,
+		marketID, status,
+	), market)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update market status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return market, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// AuditRepository handles admin audit log database operations
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+// Record writes an admin action to the audit log
+func (r *AuditRepository) Record(ctx context.Context, actorUserID uuid.UUID, action models.AuditAction, marketID *uuid.UUID, details interface{}) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	query := `
+		INSERT INTO admin_audit_log (id, actor_user_id, action, market_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+
+	_, err = r.pool.Exec(ctx, query, uuid.New(), actorUserID, action, marketID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves audit log entries, newest first, optionally limited to a market
+func (r *AuditRepository) List(ctx context.Context, marketID *uuid.UUID, limit, offset int) ([]*models.AuditLogEntry, error) {
+	query := `
+		SELECT id, actor_user_id, action, market_id, details, created_at
+		FROM admin_audit_log
+		WHERE $1::uuid IS NULL OR market_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, marketID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditLogEntry{}
+	for rows.Next() {
+		var entry models.AuditLogEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorUserID,
+			&entry.Action,
+			&entry.MarketID,
+			&entry.Details,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit rows: %w", err)
+	}
+
+	return entries, nil
+}
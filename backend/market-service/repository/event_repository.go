@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrEventNotFound     = errors.New("event not found")
+	ErrMarketNotInEvent  = errors.New("market does not belong to this event")
+	ErrResolutionMissing = errors.New("every unsettled market of the event needs a resolution")
+)
+
+// eventSummaryQuery selects events with the number of their public markets,
+// how many of them are open and the credits traded across all of them
+const eventSummaryQuery = `
+	SELECT e.id, e.ticker, e.title, e.description, e.category, e.closes_at, e.created_at, e.updated_at,
+	       COUNT(m.id),
+	       COUNT(m.id) FILTER (WHERE m.status = 'OPEN'),
+	       COALESCE((
+	           SELECT SUM(t.quantity * t.price_credits)
+	           FROM trades t
+	           JOIN contracts c ON c.id = t.contract_id
+	           JOIN markets tm ON tm.id = c.market_id
+	           WHERE tm.event_id = e.id
+	       ), 0)
+	FROM events e
+	LEFT JOIN markets m ON m.event_id = e.id AND m.status != 'DRAFT'
+`
+
+// EventRepository handles event database operations
+type EventRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewEventRepository creates a new event repository
+func NewEventRepository(pool *pgxpool.Pool) *EventRepository {
+	return &EventRepository{pool: pool}
+}
+
+func scanEventSummary(row pgx.Row) (*models.EventSummary, error) {
+	summary := &models.EventSummary{Event: &models.Event{}}
+	err := row.Scan(
+		&summary.ID,
+		&summary.Ticker,
+		&summary.Title,
+		&summary.Description,
+		&summary.Category,
+		&summary.ClosesAt,
+		&summary.CreatedAt,
+		&summary.UpdatedAt,
+		&summary.MarketCount,
+		&summary.OpenMarkets,
+		&summary.Volume,
+	)
+	return summary, err
+}
+
+// Create creates a new event
+func (r *EventRepository) Create(ctx context.Context, event *models.Event) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO events (id, ticker, title, description, category, closes_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`,
+		event.ID,
+		event.Ticker,
+		event.Title,
+		event.Description,
+		event.Category,
+		event.ClosesAt,
+	).Scan(&event.CreatedAt, &event.UpdatedAt)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTickerTaken
+		}
+		if fkErr := foreignKeyError(err); fkErr != nil {
+			return fkErr
+		}
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves events that have public markets, soonest closing first
+func (r *EventRepository) List(ctx context.Context, category string, page, pageSize int) ([]*models.EventSummary, int, error) {
+	var totalCount int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM events e
+		WHERE ($1 = '' OR e.category = $1)
+		  AND EXISTS (SELECT 1 FROM markets m WHERE m.event_id = e.id AND m.status != 'DRAFT')
+	`, category).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, eventSummaryQuery+`
+		WHERE ($1 = '' OR e.category = $1)
+		GROUP BY e.id
+		HAVING COUNT(m.id) > 0
+		ORDER BY e.closes_at, e.ticker
+		LIMIT $2 OFFSET $3
+	`, category, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.EventSummary{}
+	for rows.Next() {
+		summary, err := scanEventSummary(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating event rows: %w", err)
+	}
+
+	return events, totalCount, nil
+}
+
+// GetSummary retrieves an event with its aggregate figures
+func (r *EventRepository) GetSummary(ctx context.Context, eventID uuid.UUID) (*models.EventSummary, error) {
+	summary, err := scanEventSummary(r.pool.QueryRow(ctx, eventSummaryQuery+`
+		WHERE e.id = $1
+		GROUP BY e.id
+	`, eventID))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	return summary, nil
+}
+
+// GetMarkets retrieves the markets of an event, soonest expiring first
+func (r *EventRepository) GetMarkets(ctx context.Context, eventID uuid.UUID, includeDrafts bool) ([]*models.Market, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+marketColumns+`
+		FROM markets
+		WHERE event_id = $1 AND ($2 OR status != $3)
+		ORDER BY expires_at, ticker
+	`, eventID, includeDrafts, models.MarketStatusDraft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event markets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarkets(rows)
+}
+
+// SetMarkets attaches markets to an event, or detaches them when eventID is nil
+func (r *EventRepository) SetMarkets(ctx context.Context, eventID *uuid.UUID, marketIDs []uuid.UUID) ([]*models.Market, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE markets SET event_id = $1, updated_at = NOW()
+		WHERE id = ANY($2)
+		RETURNING `+marketColumns,
+		eventID, marketIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event markets: %w", err)
+	}
+	defer rows.Close()
+
+	markets, err := scanMarkets(rows)
+	if err != nil {
+		if fkErr := foreignKeyError(err); fkErr != nil {
+			return nil, fkErr
+		}
+		return nil, err
+	}
+
+	if len(markets) != len(marketIDs) {
+		return nil, ErrMarketNotFound
+	}
+
+	return markets, nil
+}
+
+// CheckResolutions checks that resolutions could settle an event as
+// ResolveAll would, so that trading is not stopped for resolutions that
+// would then be refused
+func (r *EventRepository) CheckResolutions(ctx context.Context, eventID uuid.UUID, resolutions []*models.MarketResolveRequest) error {
+	markets, err := r.GetMarkets(ctx, eventID, true)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]*models.MarketResolveRequest, len(resolutions))
+	for _, resolution := range resolutions {
+		byID[resolution.MarketID] = resolution
+	}
+
+	for _, market := range markets {
+		resolution, ok := byID[market.ID]
+		delete(byID, market.ID)
+
+		if !isUnsettled(market) {
+			if ok {
+				return ErrMarketNotResolvable
+			}
+			continue
+		}
+		if !ok {
+			return ErrResolutionMissing
+		}
+
+		if err := checkResolution(ctx, r.pool, market, resolution); err != nil {
+			return err
+		}
+	}
+
+	if len(byID) > 0 {
+		return ErrMarketNotInEvent
+	}
+
+	return nil
+}
+
+// ResolveAll resolves every unsettled market of an event in a single
+// transaction. Each must have exactly one resolution; draft markets and
+// markets already resolved or cancelled are left alone.
+func (r *EventRepository) ResolveAll(ctx context.Context, eventID uuid.UUID, resolutions []*models.MarketResolveRequest) ([]*models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	markets, err := lockEventMarkets(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*models.MarketResolveRequest, len(resolutions))
+	for _, resolution := range resolutions {
+		byID[resolution.MarketID] = resolution
+	}
+
+	settlements := []*models.MarketSettlement{}
+	for _, market := range markets {
+		resolution, ok := byID[market.ID]
+		delete(byID, market.ID)
+
+		if !isUnsettled(market) {
+			if ok {
+				return nil, ErrMarketNotResolvable
+			}
+			continue
+		}
+		if !ok {
+			return nil, ErrResolutionMissing
+		}
+
+		var settlement *models.MarketSettlement
+		if market.Type == models.MarketTypeScalar {
+			if resolution.Value == nil {
+				return nil, ErrWrongMarketType
+			}
+			settlement, err = resolveScalarValue(ctx, tx, market.ID, *resolution.Value)
+		} else {
+			settlement, err = resolveOutcome(ctx, tx, market.ID, resolution.OutcomeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := withdrawPendingProposals(ctx, tx, market.ID); err != nil {
+			return nil, err
+		}
+
+		settlements = append(settlements, settlement)
+	}
+
+	if len(byID) > 0 {
+		return nil, ErrMarketNotInEvent
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlements, nil
+}
+
+// CancelAll cancels and refunds every unsettled market of an event in a
+// single transaction
+func (r *EventRepository) CancelAll(ctx context.Context, eventID uuid.UUID) ([]*models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	markets, err := lockEventMarkets(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements := []*models.MarketSettlement{}
+	for _, market := range markets {
+		if !isUnsettled(market) {
+			continue
+		}
+
+		settlement, err := cancelMarket(ctx, tx, market.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := withdrawPendingProposals(ctx, tx, market.ID); err != nil {
+			return nil, err
+		}
+
+		settlements = append(settlements, settlement)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlements, nil
+}
+
+// lockEventMarkets locks an event and all of its markets for the rest of the transaction
+func lockEventMarkets(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) ([]*models.Market, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+marketColumns+` FROM markets WHERE event_id = $1 ORDER BY id FOR UPDATE
+	`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock event markets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarkets(rows)
+}
+
+// isUnsettled reports whether a published market still awaits resolution
+func isUnsettled(market *models.Market) bool {
+	switch market.Status {
+	case models.MarketStatusDraft, models.MarketStatusResolved, models.MarketStatusCancelled:
+		return false
+	}
+	return true
+}
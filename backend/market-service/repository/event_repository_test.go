@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// createEvent creates an event of markets, deleting it when the test ends
+func createEvent(t *testing.T, events *EventRepository, pool *pgxpool.Pool, markets ...*models.Market) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	event := &models.Event{
+		ID:       uuid.New(),
+		Ticker:   "TEST_" + strings.ToUpper(uuid.NewString()[:8]),
+		Title:    "Event tests",
+		ClosesAt: time.Now().Add(time.Hour),
+	}
+	if err := events.Create(ctx, event); err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM events WHERE id = $1`, event.ID) })
+
+	ids := make([]uuid.UUID, len(markets))
+	for i, market := range markets {
+		ids[i] = market.ID
+	}
+	if _, err := events.SetMarkets(ctx, &event.ID, ids); err != nil {
+		t.Fatalf("failed to attach markets: %v", err)
+	}
+	return event.ID
+}
+
+// createTrade records quantity contracts traded at price between two orders
+// of userID
+func createTrade(t *testing.T, pool *pgxpool.Pool, userID, contractID uuid.UUID, quantity int, price float64) {
+	t.Helper()
+	maker := createOrder(t, pool, userID, contractID, models.OrderStatusFilled, 2)
+	taker := createOrder(t, pool, userID, contractID, models.OrderStatusFilled, 2)
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO trades (contract_id, maker_order_id, taker_order_id, quantity, price_credits)
+		VALUES ($1, $2, $3, $4, $5)
+	`, contractID, maker, taker, quantity, price)
+	if err != nil {
+		t.Fatalf("failed to create trade: %v", err)
+	}
+}
+
+// marketStatus returns the stored status of a market
+func marketStatus(t *testing.T, pool *pgxpool.Pool, marketID uuid.UUID) models.MarketStatus {
+	t.Helper()
+	var status models.MarketStatus
+	if err := pool.QueryRow(context.Background(), `SELECT status FROM markets WHERE id = $1`, marketID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestEventSummaryVolume(t *testing.T) {
+	repo, pool := testRepository(t)
+	events := NewEventRepository(pool)
+	expiresAt := time.Now().Add(time.Hour)
+
+	first, firstContracts := createMarket(t, repo, pool, models.MarketStatusOpen, nil, expiresAt)
+	second, secondContracts := createMarket(t, repo, pool, models.MarketStatusClosed, nil, expiresAt)
+	_, otherContracts := createMarket(t, repo, pool, models.MarketStatusOpen, nil, expiresAt)
+	eventID := createEvent(t, events, pool, first, second)
+
+	userID := createUser(t, pool)
+	createTrade(t, pool, userID, firstContracts[0].ID, 10, 0.4)
+	createTrade(t, pool, userID, firstContracts[1].ID, 5, 0.6)
+	createTrade(t, pool, userID, secondContracts[0].ID, 2, 0.5)
+	createTrade(t, pool, userID, otherContracts[0].ID, 100, 0.5)
+
+	summary, err := events.GetSummary(context.Background(), eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(summary.Volume-8) > 1e-9 {
+		t.Errorf("volume = %v credits, want 8 across the event's markets", summary.Volume)
+	}
+	if summary.MarketCount != 2 || summary.OpenMarkets != 1 {
+		t.Errorf("%d markets, %d open, want 2, 1 open", summary.MarketCount, summary.OpenMarkets)
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	repo, pool := testRepository(t)
+	events := NewEventRepository(pool)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	first, firstContracts := createMarket(t, repo, pool, models.MarketStatusOpen, nil, expiresAt)
+	second, secondContracts := createMarket(t, repo, pool, models.MarketStatusClosed, nil, expiresAt)
+	settled, settledContracts := createMarket(t, repo, pool, models.MarketStatusCancelled, nil, expiresAt)
+	eventID := createEvent(t, events, pool, first, second, settled)
+
+	resolveFirst := &models.MarketResolveRequest{MarketID: first.ID, OutcomeID: firstContracts[0].OutcomeID}
+	resolveSecond := &models.MarketResolveRequest{MarketID: second.ID, OutcomeID: secondContracts[1].OutcomeID}
+	resolveSettled := &models.MarketResolveRequest{MarketID: settled.ID, OutcomeID: settledContracts[0].OutcomeID}
+
+	// Nothing is settled until trading on every market has stopped
+	if _, err := events.ResolveAll(ctx, eventID, []*models.MarketResolveRequest{resolveFirst, resolveSecond}); !errors.Is(err, ErrTradingNotHalted) {
+		t.Fatalf("ResolveAll() before halting = %v, want %v", err, ErrTradingNotHalted)
+	}
+	for _, market := range []*models.Market{first, second} {
+		if _, err := repo.CancelRestingOrders(ctx, market.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refused := []struct {
+		name        string
+		resolutions []*models.MarketResolveRequest
+		wantErr     error
+	}{
+		{"market without a resolution", []*models.MarketResolveRequest{resolveFirst}, ErrResolutionMissing},
+		{"settled market", []*models.MarketResolveRequest{resolveFirst, resolveSecond, resolveSettled}, ErrMarketNotResolvable},
+		{"market of another event", []*models.MarketResolveRequest{resolveFirst, resolveSecond, {MarketID: uuid.New(), OutcomeID: uuid.New()}}, ErrMarketNotInEvent},
+	}
+	for _, tt := range refused {
+		if err := events.CheckResolutions(ctx, eventID, tt.resolutions); !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckResolutions() with a %s = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if _, err := events.ResolveAll(ctx, eventID, tt.resolutions); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ResolveAll() with a %s = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if status := marketStatus(t, pool, first.ID); status != models.MarketStatusOpen {
+			t.Fatalf("refused resolution left the first market %s", status)
+		}
+	}
+
+	resolutions := []*models.MarketResolveRequest{resolveFirst, resolveSecond}
+	if err := events.CheckResolutions(ctx, eventID, resolutions); err != nil {
+		t.Fatal(err)
+	}
+	settlements, err := events.ResolveAll(ctx, eventID, resolutions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settlements) != 2 {
+		t.Errorf("settled %d markets, want 2", len(settlements))
+	}
+	for _, market := range []*models.Market{first, second} {
+		if status := marketStatus(t, pool, market.ID); status != models.MarketStatusResolved {
+			t.Errorf("market %s left %s, want %s", market.Ticker, status, models.MarketStatusResolved)
+		}
+	}
+	if status := marketStatus(t, pool, settled.ID); status != models.MarketStatusCancelled {
+		t.Errorf("settled market changed to %s", status)
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	repo, pool := testRepository(t)
+	events := NewEventRepository(pool)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	open, _ := createMarket(t, repo, pool, models.MarketStatusOpen, nil, expiresAt)
+	closed, _ := createMarket(t, repo, pool, models.MarketStatusClosed, nil, expiresAt)
+	draft, _ := createMarket(t, repo, pool, models.MarketStatusDraft, nil, expiresAt)
+	eventID := createEvent(t, events, pool, open, closed, draft)
+
+	if _, err := repo.CancelRestingOrders(ctx, open.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// One market still trading refuses the whole event
+	if _, err := events.CancelAll(ctx, eventID); !errors.Is(err, ErrTradingNotHalted) {
+		t.Fatalf("CancelAll() before halting = %v, want %v", err, ErrTradingNotHalted)
+	}
+	if status := marketStatus(t, pool, open.ID); status != models.MarketStatusOpen {
+		t.Fatalf("refused cancellation left the open market %s", status)
+	}
+
+	if _, err := repo.CancelRestingOrders(ctx, closed.ID); err != nil {
+		t.Fatal(err)
+	}
+	settlements, err := events.CancelAll(ctx, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settlements) != 2 {
+		t.Errorf("cancelled %d markets, want 2", len(settlements))
+	}
+	for _, market := range []*models.Market{open, closed} {
+		if status := marketStatus(t, pool, market.ID); status != models.MarketStatusCancelled {
+			t.Errorf("market %s left %s, want %s", market.Ticker, status, models.MarketStatusCancelled)
+		}
+	}
+	if status := marketStatus(t, pool, draft.ID); status != models.MarketStatusDraft {
+		t.Errorf("draft market changed to %s", status)
+	}
+	if _, err := events.CancelAll(ctx, uuid.New()); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("CancelAll() of an unknown event = %v, want %v", err, ErrEventNotFound)
+	}
+}
+
+func TestIsUnsettled(t *testing.T) {
+	tests := []struct {
+		status models.MarketStatus
+		want   bool
+	}{
+		{models.MarketStatusDraft, false},
+		{models.MarketStatusUpcoming, true},
+		{models.MarketStatusOpen, true},
+		{models.MarketStatusClosed, true},
+		{models.MarketStatusResolved, false},
+		{models.MarketStatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		if got := isUnsettled(&models.Market{Status: tt.status}); got != tt.want {
+			t.Errorf("isUnsettled(%s) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
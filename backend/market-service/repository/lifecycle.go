@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"lfg/shared/models"
+)
+
+// OpenDueMarkets opens every UPCOMING market whose open time has passed and
+// returns the markets it opened
+func (r *MarketRepository) OpenDueMarkets(ctx context.Context, now time.Time) ([]*models.Market, error) {
+	query := `
+		UPDATE markets
+		SET status = $2, updated_at = NOW()
+		WHERE status = $3 AND opens_at IS NOT NULL AND opens_at <= $1 AND expires_at > $1
+		RETURNING ` + marketColumns
+
+	rows, err := r.pool.Query(ctx, query, now, models.MarketStatusOpen, models.MarketStatusUpcoming)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open markets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarkets(rows)
+}
+
+// CloseExpiredMarkets closes every UPCOMING or OPEN market that has passed its
+// expiry and returns the markets it closed
+func (r *MarketRepository) CloseExpiredMarkets(ctx context.Context, now time.Time) ([]*models.Market, error) {
+	query := `
+		UPDATE markets
+		SET status = $2, updated_at = NOW()
+		WHERE status IN ($3, $4) AND expires_at <= $1
+		RETURNING ` + marketColumns
+
+	rows, err := r.pool.Query(ctx, query, now, models.MarketStatusClosed, models.MarketStatusUpcoming, models.MarketStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close markets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarkets(rows)
+}
+
+// UnhaltedMarkets returns up to limit markets that stopped trading without
+// the matching engine confirming it halted them, oldest first
+func (r *MarketRepository) UnhaltedMarkets(ctx context.Context, limit int) ([]*models.Market, error) {
+	query := `
+		SELECT ` + marketColumns + `
+		FROM markets
+		WHERE trading_halted_at IS NULL AND status IN ($1, $2, $3)
+		ORDER BY updated_at
+		LIMIT $4`
+
+	rows, err := r.pool.Query(ctx, query,
+		models.MarketStatusClosed,
+		models.MarketStatusResolved,
+		models.MarketStatusCancelled,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unhalted markets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMarkets(rows)
+}
+
+// CancelRestingOrders cancels every working order on a market's contracts
+// once the matching engine has halted them, records the market as halted and
+// returns how many orders were cancelled
+func (r *MarketRepository) CancelRestingOrders(ctx context.Context, marketID uuid.UUID) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE orders o
+		SET status = $2, updated_at = NOW()
+		FROM contracts c
+		WHERE c.id = o.contract_id AND c.market_id = $1
+		  AND o.status IN ($3, $4, $5)
+	`
+
+	result, err := tx.Exec(ctx, query, marketID,
+		models.OrderStatusCancelled,
+		models.OrderStatusPending,
+		models.OrderStatusActive,
+		models.OrderStatusPartiallyFilled,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel orders: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE markets SET trading_halted_at = NOW() WHERE id = $1 AND trading_halted_at IS NULL
+	`, marketID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record trading halt: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanMarkets(rows pgx.Rows) ([]*models.Market, error) {
+	markets := []*models.Market{}
+	for rows.Next() {
+		var market models.Market
+		if err := scanMarket(rows, &market); err != nil {
+			return nil, fmt.Errorf("failed to scan market: %w", err)
+		}
+		markets = append(markets, &market)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating market rows: %w", err)
+	}
+
+	return markets, nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// testRepository connects to the database at TEST_DATABASE_URL, which must
+// be migrated to the latest schema, skipping the test without one. The
+// lifecycle queries update every market due, so it must be a test database.
+func testRepository(t *testing.T) (*MarketRepository, *pgxpool.Pool) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return NewMarketRepository(pool), pool
+}
+
+// createMarket creates a binary market in status, deleting it with its
+// contracts and orders when the test ends
+func createMarket(t *testing.T, repo *MarketRepository, pool *pgxpool.Pool, status models.MarketStatus, opensAt *time.Time, expiresAt time.Time) (*models.Market, []*models.Contract) {
+	t.Helper()
+	ctx := context.Background()
+	market := &models.Market{
+		ID:               uuid.New(),
+		Ticker:           "TEST-" + strings.ToUpper(uuid.NewString()[:8]),
+		Question:         "Will the lifecycle test pass?",
+		Rules:            "Resolves YES if it does",
+		ResolutionSource: "go test",
+		Status:           status,
+		Type:             models.MarketTypeBinary,
+		OpensAt:          opensAt,
+		ExpiresAt:        expiresAt,
+	}
+	_, contracts, err := repo.CreateWithOutcomes(ctx, market, []string{"YES", "NO"})
+	if err != nil {
+		t.Fatalf("failed to create market: %v", err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM markets WHERE id = $1`, market.ID) })
+	return market, contracts
+}
+
+// createUser creates a user to place orders, deleting it when the test ends
+func createUser(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	userID := uuid.New()
+	_, err := pool.Exec(ctx, `INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3)`,
+		userID, userID.String()+"@example.com", strings.Repeat("x", 60))
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	return userID
+}
+
+// createOrder creates a limit order to buy 2 contracts at 0.5 credits
+func createOrder(t *testing.T, pool *pgxpool.Pool, userID, contractID uuid.UUID, status models.OrderStatus, filled int) uuid.UUID {
+	t.Helper()
+	orderID := uuid.New()
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO orders (id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits)
+		VALUES ($1, $2, $3, 'LIMIT', 'BUY', $4, 2, $5, 0.5)
+	`, orderID, userID, contractID, status, filled)
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	return orderID
+}
+
+// marketIDs maps the IDs of markets to their statuses
+func marketIDs(markets []*models.Market) map[uuid.UUID]models.MarketStatus {
+	ids := make(map[uuid.UUID]models.MarketStatus, len(markets))
+	for _, market := range markets {
+		ids[market.ID] = market.Status
+	}
+	return ids
+}
+
+func TestOpenDueMarkets(t *testing.T) {
+	repo, pool := testRepository(t)
+	now := time.Now()
+	hour := time.Hour
+	at := func(d time.Duration) *time.Time { opensAt := now.Add(d); return &opensAt }
+
+	due, _ := createMarket(t, repo, pool, models.MarketStatusUpcoming, at(hour), now.Add(3*hour))
+	notDue, _ := createMarket(t, repo, pool, models.MarketStatusUpcoming, at(3*hour), now.Add(4*hour))
+	unscheduled, _ := createMarket(t, repo, pool, models.MarketStatusUpcoming, nil, now.Add(3*hour))
+	expired, _ := createMarket(t, repo, pool, models.MarketStatusUpcoming, at(hour), now.Add(90*time.Minute))
+	draft, _ := createMarket(t, repo, pool, models.MarketStatusDraft, at(hour), now.Add(3*hour))
+
+	opened, err := repo.OpenDueMarkets(context.Background(), now.Add(2*hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := marketIDs(opened)
+	if ids[due.ID] != models.MarketStatusOpen {
+		t.Errorf("market due to open returned as %q, want %q", ids[due.ID], models.MarketStatusOpen)
+	}
+	for name, market := range map[string]*models.Market{"not yet due": notDue, "unscheduled": unscheduled, "expired": expired, "draft": draft} {
+		if _, ok := ids[market.ID]; ok {
+			t.Errorf("opened the %s market", name)
+		}
+	}
+}
+
+func TestCloseExpiredMarkets(t *testing.T) {
+	repo, pool := testRepository(t)
+	now := time.Now()
+	hour := time.Hour
+
+	open, _ := createMarket(t, repo, pool, models.MarketStatusOpen, nil, now.Add(hour))
+	upcoming, _ := createMarket(t, repo, pool, models.MarketStatusUpcoming, nil, now.Add(hour))
+	unexpired, _ := createMarket(t, repo, pool, models.MarketStatusOpen, nil, now.Add(3*hour))
+	draft, _ := createMarket(t, repo, pool, models.MarketStatusDraft, nil, now.Add(hour))
+
+	closed, err := repo.CloseExpiredMarkets(context.Background(), now.Add(2*hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := marketIDs(closed)
+	for name, market := range map[string]*models.Market{"open": open, "upcoming": upcoming} {
+		if ids[market.ID] != models.MarketStatusClosed {
+			t.Errorf("expired %s market returned as %q, want %q", name, ids[market.ID], models.MarketStatusClosed)
+		}
+	}
+	for name, market := range map[string]*models.Market{"unexpired": unexpired, "draft": draft} {
+		if _, ok := ids[market.ID]; ok {
+			t.Errorf("closed the %s market", name)
+		}
+	}
+}
+
+func TestCancelRestingOrders(t *testing.T) {
+	repo, pool := testRepository(t)
+	ctx := context.Background()
+
+	market, contracts := createMarket(t, repo, pool, models.MarketStatusOpen, nil, time.Now().Add(time.Hour))
+	other, otherContracts := createMarket(t, repo, pool, models.MarketStatusOpen, nil, time.Now().Add(time.Hour))
+
+	userID := createUser(t, pool)
+
+	statuses := []models.OrderStatus{
+		models.OrderStatusPending,
+		models.OrderStatusActive,
+		models.OrderStatusPartiallyFilled,
+		models.OrderStatusFilled,
+		models.OrderStatusCancelled,
+		models.OrderStatusRejected,
+	}
+	orders := make(map[uuid.UUID]models.OrderStatus)
+	for _, contract := range append(contracts, otherContracts...) {
+		for _, status := range statuses {
+			filled := 0
+			if status == models.OrderStatusPartiallyFilled {
+				filled = 1
+			} else if status == models.OrderStatusFilled {
+				filled = 2
+			}
+			orderID := createOrder(t, pool, userID, contract.ID, status, filled)
+
+			want := status
+			if contract.MarketID == market.ID && (status == models.OrderStatusPending || status == models.OrderStatusActive || status == models.OrderStatusPartiallyFilled) {
+				want = models.OrderStatusCancelled
+			}
+			orders[orderID] = want
+		}
+	}
+
+	cancelled, err := repo.CancelRestingOrders(ctx, market.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(3 * len(contracts)); cancelled != want {
+		t.Errorf("cancelled %d orders, want %d", cancelled, want)
+	}
+
+	for orderID, want := range orders {
+		var status models.OrderStatus
+		if err := pool.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != want {
+			t.Errorf("order %s is %s, want %s", orderID, status, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		market     *models.Market
+		wantHalted bool
+	}{
+		{market, true},
+		{other, false},
+	} {
+		var halted bool
+		if err := pool.QueryRow(ctx, `SELECT trading_halted_at IS NOT NULL FROM markets WHERE id = $1`, tt.market.ID).Scan(&halted); err != nil {
+			t.Fatal(err)
+		}
+		if halted != tt.wantHalted {
+			t.Errorf("market %s halted = %v, want %v", tt.market.Ticker, halted, tt.wantHalted)
+		}
+	}
+}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"lfg/shared/models"
 )
 
 var (
-	ErrMarketNotFound = errors.New("market not found")
+	ErrMarketNotFound  = errors.New("market not found")
+	ErrOutcomeNotFound = errors.New("outcome not found")
+	ErrTickerTaken     = errors.New("ticker already exists")
+	ErrUnknownCategory = errors.New("unknown category")
 )
 
+var tickerInvalidPattern = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// marketColumns lists the markets columns in the order scanMarket expects
+const marketColumns = `id, ticker, question, rules, resolution_source, category, tags, event_id, template_id, status, market_type, scalar_lower_bound, scalar_upper_bound, opens_at, expires_at, resolved_at, winning_outcome_id, resolved_value, created_at, updated_at`
+
 // MarketRepository handles market database operations
 type MarketRepository struct {
 	pool *pgxpool.Pool
@@ -27,46 +37,58 @@ func NewMarketRepository(pool *pgxpool.Pool) *MarketRepository {
 	return &MarketRepository{pool: pool}
 }
 
-// List retrieves markets with filtering and pagination
-func (r *MarketRepository) List(ctx context.Context, status string, search string, page, pageSize int) ([]*models.Market, int, error) {
-	// Build query
-	query := `
-		SELECT id, ticker, question, rules, resolution_source, status, expires_at, resolved_at, outcome, created_at, updated_at
-		FROM markets
-		WHERE 1=1
-	`
-	countQuery := `SELECT COUNT(*) FROM markets WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
-
-	// Add status filter
-	if status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argCount)
-		countQuery += fmt.Sprintf(" AND status = $%d", argCount)
-		args = append(args, status)
-		argCount++
-	}
+// scanMarket scans a row selected with marketColumns into a market
+func scanMarket(row pgx.Row, market *models.Market) error {
+	return row.Scan(
+		&market.ID,
+		&market.Ticker,
+		&market.Question,
+		&market.Rules,
+		&market.ResolutionSource,
+		&market.Category,
+		&market.Tags,
+		&market.EventID,
+		&market.TemplateID,
+		&market.Status,
+		&market.Type,
+		&market.LowerBound,
+		&market.UpperBound,
+		&market.OpensAt,
+		&market.ExpiresAt,
+		&market.ResolvedAt,
+		&market.WinningOutcomeID,
+		&market.ResolvedValue,
+		&market.CreatedAt,
+		&market.UpdatedAt,
+	)
+}
 
-	// Add search filter
-	if search != "" {
-		searchPattern := "%" + strings.ToLower(search) + "%"
-		query += fmt.Sprintf(" AND (LOWER(ticker) LIKE $%d OR LOWER(question) LIKE $%d)", argCount, argCount)
-		countQuery += fmt.Sprintf(" AND (LOWER(ticker) LIKE $%d OR LOWER(question) LIKE $%d)", argCount, argCount)
-		args = append(args, searchPattern)
-		argCount++
-	}
+// List retrieves markets matching filter with pagination. Full-text searches
+// are ordered by rank, other listings by recency.
+func (r *MarketRepository) List(ctx context.Context, filter *models.MarketListFilter) ([]*models.Market, int, error) {
+	where := newMarketFilter(filter, "")
 
 	// Get total count
 	var totalCount int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM markets WHERE `+where.clause(), where.args...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count markets: %w", err)
 	}
 
+	orderBy := "created_at DESC"
+	if where.searchArg > 0 {
+		orderBy = fmt.Sprintf("ts_rank(search_vector, websearch_to_tsquery('english', $%d)) DESC, created_at DESC", where.searchArg)
+	}
+
 	// Add pagination
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	offset := (page - 1) * pageSize
-	args = append(args, pageSize, offset)
+	args := append(where.args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM markets
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, marketColumns, where.clause(), orderBy, len(args)-1, len(args))
 
 	// Execute query
 	rows, err := r.pool.Query(ctx, query, args...)
@@ -79,20 +101,7 @@ func (r *MarketRepository) List(ctx context.Context, status string, search strin
 	markets := []*models.Market{}
 	for rows.Next() {
 		var market models.Market
-		err := rows.Scan(
-			&market.ID,
-			&market.Ticker,
-			&market.Question,
-			&market.Rules,
-			&market.ResolutionSource,
-			&market.Status,
-			&market.ExpiresAt,
-			&market.ResolvedAt,
-			&market.Outcome,
-			&market.CreatedAt,
-			&market.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanMarket(rows, &market); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan market: %w", err)
 		}
 		markets = append(markets, &market)
@@ -104,25 +113,13 @@ func (r *MarketRepository) List(ctx context.Context, status string, search strin
 // GetByID retrieves a market by ID
 func (r *MarketRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Market, error) {
 	query := `
-		SELECT id, ticker, question, rules, resolution_source, status, expires_at, resolved_at, outcome, created_at, updated_at
+		SELECT ` + marketColumns + `
 		FROM markets
 		WHERE id = $1
 	`
 
 	var market models.Market
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&market.ID,
-		&market.Ticker,
-		&market.Question,
-		&market.Rules,
-		&market.ResolutionSource,
-		&market.Status,
-		&market.ExpiresAt,
-		&market.ResolvedAt,
-		&market.Outcome,
-		&market.CreatedAt,
-		&market.UpdatedAt,
-	)
+	err := scanMarket(r.pool.QueryRow(ctx, query, id), &market)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -137,25 +134,13 @@ func (r *MarketRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.M
 // GetByTicker retrieves a market by ticker
 func (r *MarketRepository) GetByTicker(ctx context.Context, ticker string) (*models.Market, error) {
 	query := `
-		SELECT id, ticker, question, rules, resolution_source, status, expires_at, resolved_at, outcome, created_at, updated_at
+		SELECT ` + marketColumns + `
 		FROM markets
 		WHERE ticker = $1
 	`
 
 	var market models.Market
-	err := r.pool.QueryRow(ctx, query, ticker).Scan(
-		&market.ID,
-		&market.Ticker,
-		&market.Question,
-		&market.Rules,
-		&market.ResolutionSource,
-		&market.Status,
-		&market.ExpiresAt,
-		&market.ResolvedAt,
-		&market.Outcome,
-		&market.CreatedAt,
-		&market.UpdatedAt,
-	)
+	err := scanMarket(r.pool.QueryRow(ctx, query, ticker), &market)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -167,13 +152,48 @@ func (r *MarketRepository) GetByTicker(ctx context.Context, ticker string) (*mod
 	return &market, nil
 }
 
-// GetContractsByMarketID retrieves contracts for a market
-func (r *MarketRepository) GetContractsByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Contract, error) {
+// GetOutcomesByMarketID retrieves the outcomes of a market in display order
+func (r *MarketRepository) GetOutcomesByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Outcome, error) {
 	query := `
-		SELECT id, market_id, side, ticker, created_at
-		FROM contracts
+		SELECT id, market_id, name, display_order, created_at
+		FROM market_outcomes
 		WHERE market_id = $1
-		ORDER BY side
+		ORDER BY display_order, name
+	`
+
+	rows, err := r.pool.Query(ctx, query, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	outcomes := []*models.Outcome{}
+	for rows.Next() {
+		var outcome models.Outcome
+		err := rows.Scan(
+			&outcome.ID,
+			&outcome.MarketID,
+			&outcome.Name,
+			&outcome.DisplayOrder,
+			&outcome.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outcome: %w", err)
+		}
+		outcomes = append(outcomes, &outcome)
+	}
+
+	return outcomes, nil
+}
+
+// GetContractsByMarketID retrieves contracts for a market in outcome display order
+func (r *MarketRepository) GetContractsByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Contract, error) {
+	query := `
+		SELECT c.id, c.market_id, c.outcome_id, c.ticker, c.created_at
+		FROM contracts c
+		JOIN market_outcomes o ON o.id = c.outcome_id
+		WHERE c.market_id = $1
+		ORDER BY o.display_order, o.name
 	`
 
 	rows, err := r.pool.Query(ctx, query, marketID)
@@ -188,7 +208,7 @@ func (r *MarketRepository) GetContractsByMarketID(ctx context.Context, marketID
 		err := rows.Scan(
 			&contract.ID,
 			&contract.MarketID,
-			&contract.Side,
+			&contract.OutcomeID,
 			&contract.Ticker,
 			&contract.CreatedAt,
 		)
@@ -203,45 +223,204 @@ func (r *MarketRepository) GetContractsByMarketID(ctx context.Context, marketID
 
 // Create creates a new market
 func (r *MarketRepository) Create(ctx context.Context, market *models.Market) error {
+	return insertMarket(ctx, r.pool, market)
+}
+
+// CreateOutcome creates a new market outcome
+func (r *MarketRepository) CreateOutcome(ctx context.Context, outcome *models.Outcome) error {
+	return insertOutcome(ctx, r.pool, outcome)
+}
+
+// CreateContract creates a new contract
+func (r *MarketRepository) CreateContract(ctx context.Context, contract *models.Contract) error {
+	return insertContract(ctx, r.pool, contract)
+}
+
+// CreateWithContracts creates a market together with its outcomes and
+// contracts in a single transaction
+func (r *MarketRepository) CreateWithContracts(ctx context.Context, market *models.Market, outcomes []*models.Outcome, contracts []*models.Contract) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertMarket(ctx, tx, market); err != nil {
+		return err
+	}
+
+	for _, outcome := range outcomes {
+		if err := insertOutcome(ctx, tx, outcome); err != nil {
+			return err
+		}
+	}
+
+	for _, contract := range contracts {
+		if err := insertContract(ctx, tx, contract); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWithOutcomes creates a market with one outcome per name, in display
+// order, and one contract per outcome, deriving contract tickers from the
+// market ticker and outcome names
+func (r *MarketRepository) CreateWithOutcomes(ctx context.Context, market *models.Market, outcomeNames []string) ([]*models.Outcome, []*models.Contract, error) {
+	outcomes := make([]*models.Outcome, len(outcomeNames))
+	contracts := make([]*models.Contract, len(outcomeNames))
+	usedTickers := make(map[string]bool, len(outcomeNames))
+	for i, name := range outcomeNames {
+		outcomes[i] = &models.Outcome{
+			ID:           uuid.New(),
+			MarketID:     market.ID,
+			Name:         name,
+			DisplayOrder: i,
+		}
+
+		ticker := contractTicker(market.Ticker, name, i)
+		if usedTickers[ticker] {
+			ticker = fmt.Sprintf("%s-%d", market.Ticker, i+1)
+		}
+		usedTickers[ticker] = true
+
+		contracts[i] = &models.Contract{
+			ID:        uuid.New(),
+			MarketID:  market.ID,
+			OutcomeID: outcomes[i].ID,
+			Ticker:    ticker,
+		}
+	}
+
+	if err := r.CreateWithContracts(ctx, market, outcomes, contracts); err != nil {
+		return nil, nil, err
+	}
+
+	return outcomes, contracts, nil
+}
+
+// contractTicker derives a contract ticker from its market ticker and outcome name
+func contractTicker(marketTicker, outcomeName string, index int) string {
+	suffix := strings.Trim(tickerInvalidPattern.ReplaceAllString(strings.ToUpper(outcomeName), "_"), "_")
+	if len(suffix) > 9 {
+		suffix = suffix[:9]
+	}
+	if suffix == "" {
+		suffix = fmt.Sprintf("%d", index+1)
+	}
+	return marketTicker + "-" + suffix
+}
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func insertMarket(ctx context.Context, db execer, market *models.Market) error {
 	query := `
-		INSERT INTO markets (id, ticker, question, rules, resolution_source, status, expires_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		INSERT INTO markets (id, ticker, question, rules, resolution_source, category, tags, event_id, template_id, status, market_type, scalar_lower_bound, scalar_upper_bound, opens_at, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	if market.Tags == nil {
+		market.Tags = []string{}
+	}
+
+	_, err := db.Exec(ctx, query,
 		market.ID,
 		market.Ticker,
 		market.Question,
 		market.Rules,
 		market.ResolutionSource,
+		market.Category,
+		market.Tags,
+		market.EventID,
+		market.TemplateID,
 		market.Status,
+		market.Type,
+		market.LowerBound,
+		market.UpperBound,
+		market.OpensAt,
 		market.ExpiresAt,
 	)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTickerTaken
+		}
+		if err := foreignKeyError(err); err != nil {
+			return err
+		}
 		return fmt.Errorf("failed to create market: %w", err)
 	}
 
 	return nil
 }
 
-// CreateContract creates a new contract
-func (r *MarketRepository) CreateContract(ctx context.Context, contract *models.Contract) error {
+func insertOutcome(ctx context.Context, db execer, outcome *models.Outcome) error {
 	query := `
-		INSERT INTO contracts (id, market_id, side, ticker, created_at)
+		INSERT INTO market_outcomes (id, market_id, name, display_order, created_at)
 		VALUES ($1, $2, $3, $4, NOW())
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	_, err := db.Exec(ctx, query,
+		outcome.ID,
+		outcome.MarketID,
+		outcome.Name,
+		outcome.DisplayOrder,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create outcome: %w", err)
+	}
+
+	return nil
+}
+
+func insertContract(ctx context.Context, db execer, contract *models.Contract) error {
+	query := `
+		INSERT INTO contracts (id, market_id, outcome_id, ticker, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`
+
+	_, err := db.Exec(ctx, query,
 		contract.ID,
 		contract.MarketID,
-		contract.Side,
+		contract.OutcomeID,
 		contract.Ticker,
 	)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTickerTaken
+		}
 		return fmt.Errorf("failed to create contract: %w", err)
 	}
 
 	return nil
 }
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// foreignKeyError maps a Postgres foreign key violation on a market's
+// references to the matching not-found error, and returns nil otherwise
+func foreignKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23503" {
+		return nil
+	}
+
+	if pgErr.ConstraintName == "markets_event_id_fkey" {
+		return ErrEventNotFound
+	}
+	return ErrUnknownCategory
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrMarketNotOpen        = errors.New("market is not open")
+	ErrWalletNotFound       = errors.New("wallet not found")
+	ErrInsufficientBalance  = errors.New("insufficient balance")
+	ErrInsufficientPosition = errors.New("insufficient position")
+)
+
+// GetPositionsByUserID retrieves a user's non-empty positions, optionally
+// limited to a single market
+func (r *MarketRepository) GetPositionsByUserID(ctx context.Context, userID uuid.UUID, marketID *uuid.UUID) ([]*models.Position, error) {
+	query := `
+		SELECT p.id, p.user_id, p.contract_id, p.quantity, p.created_at, p.updated_at
+		FROM positions p
+		JOIN contracts c ON c.id = p.contract_id
+		WHERE p.user_id = $1 AND p.quantity > 0 AND ($2::uuid IS NULL OR c.market_id = $2)
+		ORDER BY c.market_id, c.ticker
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query positions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPositions(rows)
+}
+
+// MintCompleteSet debits one credit per set from the user's wallet and adds
+// one contract of every outcome of the market to their positions
+func (r *MarketRepository) MintCompleteSet(ctx context.Context, userID, marketID uuid.UUID, quantity int) ([]*models.Position, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.MarketStatus
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM markets WHERE id = $1 FOR SHARE
+	`, marketID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMarketNotFound
+		}
+		return nil, fmt.Errorf("failed to get market: %w", err)
+	}
+
+	if status != models.MarketStatusOpen {
+		return nil, ErrMarketNotOpen
+	}
+
+	contracts, err := contractOutcomes(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := float64(quantity) * models.CompleteSetValueCredits
+	if err := adjustWallet(ctx, tx, userID, -cost); err != nil {
+		return nil, err
+	}
+
+	if err := adjustCostBasis(ctx, tx, userID, marketID, cost); err != nil {
+		return nil, err
+	}
+
+	positions := make([]*models.Position, 0, len(contracts))
+	for contractID := range contracts {
+		position, err := adjustPosition(ctx, tx, userID, contractID, quantity)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return positions, nil
+}
+
+// RedeemCompleteSet removes one contract of every outcome of the market from
+// the user's positions and credits one credit per set to their wallet
+func (r *MarketRepository) RedeemCompleteSet(ctx context.Context, userID, marketID uuid.UUID, quantity int) ([]*models.Position, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A resolved or cancelled market has already settled its positions
+	if _, err := lockResolvableMarket(ctx, tx, marketID); err != nil {
+		if errors.Is(err, ErrMarketNotResolvable) {
+			return nil, ErrMarketNotOpen
+		}
+		return nil, err
+	}
+
+	contracts, err := contractOutcomes(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]*models.Position, 0, len(contracts))
+	for contractID := range contracts {
+		position, err := adjustPosition(ctx, tx, userID, contractID, -quantity)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	payout := float64(quantity) * models.CompleteSetValueCredits
+	if err := adjustWallet(ctx, tx, userID, payout); err != nil {
+		return nil, err
+	}
+
+	if err := adjustCostBasis(ctx, tx, userID, marketID, -payout); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return positions, nil
+}
+
+// adjustWallet changes a user's wallet balance by amount, refusing to take
+// what the user's open orders hold
+func adjustWallet(ctx context.Context, tx pgx.Tx, userID uuid.UUID, amount float64) error {
+	var walletID uuid.UUID
+	var balance float64
+	err := tx.QueryRow(ctx, `
+		SELECT id, balance_credits FROM wallets WHERE user_id = $1 FOR UPDATE
+	`, userID).Scan(&walletID, &balance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWalletNotFound
+		}
+		return fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	held := 0.0
+	if amount < 0 {
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(credits), 0) FROM order_holds WHERE user_id = $1
+		`, userID).Scan(&held)
+		if err != nil {
+			return fmt.Errorf("failed to get held balance: %w", err)
+		}
+	}
+
+	if balance+amount < held {
+		return ErrInsufficientBalance
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE wallets SET balance_credits = balance_credits + $1, updated_at = NOW() WHERE id = $2
+	`, amount, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	return nil
+}
+
+// adjustCostBasis changes what a user has paid into a market by amount, which
+// is refunded if the market is cancelled
+func adjustCostBasis(ctx context.Context, tx pgx.Tx, userID, marketID uuid.UUID, amount float64) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO market_cost_basis (user_id, market_id, credits, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, market_id)
+		DO UPDATE SET credits = market_cost_basis.credits + EXCLUDED.credits, updated_at = NOW()
+	`, userID, marketID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to update cost basis: %w", err)
+	}
+
+	return nil
+}
+
+// adjustPosition changes a user's position in a contract by delta, refusing to
+// take the contracts the user's open sell orders hold
+func adjustPosition(ctx context.Context, tx pgx.Tx, userID, contractID uuid.UUID, delta int) (*models.Position, error) {
+	var row pgx.Row
+	if delta < 0 {
+		var quantity, held int
+		err := tx.QueryRow(ctx, `
+			SELECT quantity FROM positions WHERE user_id = $1 AND contract_id = $2 FOR UPDATE
+		`, userID, contractID).Scan(&quantity)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrInsufficientPosition
+			}
+			return nil, fmt.Errorf("failed to lock position: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM order_holds WHERE user_id = $1 AND contract_id = $2
+		`, userID, contractID).Scan(&held)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get held position: %w", err)
+		}
+
+		if quantity+delta < held {
+			return nil, ErrInsufficientPosition
+		}
+
+		row = tx.QueryRow(ctx, `
+			UPDATE positions
+			SET quantity = quantity + $3, updated_at = NOW()
+			WHERE user_id = $1 AND contract_id = $2
+			RETURNING id, user_id, contract_id, quantity, created_at, updated_at
+		`, userID, contractID, delta)
+	} else {
+		row = tx.QueryRow(ctx, `
+			INSERT INTO positions (id, user_id, contract_id, quantity, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			ON CONFLICT (user_id, contract_id)
+			DO UPDATE SET quantity = positions.quantity + EXCLUDED.quantity, updated_at = NOW()
+			RETURNING id, user_id, contract_id, quantity, created_at, updated_at
+		`, uuid.New(), userID, contractID, delta)
+	}
+
+	var position models.Position
+	err := row.Scan(
+		&position.ID,
+		&position.UserID,
+		&position.ContractID,
+		&position.Quantity,
+		&position.CreatedAt,
+		&position.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInsufficientPosition
+		}
+		return nil, fmt.Errorf("failed to update position: %w", err)
+	}
+
+	return &position, nil
+}
+
+func scanPositions(rows pgx.Rows) ([]*models.Position, error) {
+	positions := []*models.Position{}
+	for rows.Next() {
+		var position models.Position
+		err := rows.Scan(
+			&position.ID,
+			&position.UserID,
+			&position.ContractID,
+			&position.Quantity,
+			&position.CreatedAt,
+			&position.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan position: %w", err)
+		}
+		positions = append(positions, &position)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating position rows: %w", err)
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,594 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrProposalNotFound      = errors.New("resolution proposal not found")
+	ErrProposalPending       = errors.New("market already has a pending resolution proposal")
+	ErrProposalNotDisputable = errors.New("proposal cannot be disputed")
+	ErrProposalNotDisputed   = errors.New("proposal is not disputed")
+	ErrChallengeWindowClosed = errors.New("challenge window has closed")
+	ErrAlreadyDisputed       = errors.New("user has already disputed this proposal")
+	ErrMarketNotClosed       = errors.New("market must be closed before resolution is proposed")
+)
+
+const proposalColumns = `id, market_id, proposer_user_id, source, outcome_id, value, evidence, status, challenge_ends_at, finalized_at, created_at, updated_at`
+
+// scanProposal scans a row selected with proposalColumns into a proposal
+func scanProposal(row pgx.Row, proposal *models.ResolutionProposal) error {
+	return row.Scan(
+		&proposal.ID,
+		&proposal.MarketID,
+		&proposal.ProposerUserID,
+		&proposal.Source,
+		&proposal.OutcomeID,
+		&proposal.Value,
+		&proposal.Evidence,
+		&proposal.Status,
+		&proposal.ChallengeEndsAt,
+		&proposal.FinalizedAt,
+		&proposal.CreatedAt,
+		&proposal.UpdatedAt,
+	)
+}
+
+// SetResolver configures the automated resolver of a market
+func (r *MarketRepository) SetResolver(ctx context.Context, resolver *models.MarketResolver) error {
+	config := resolver.Config
+	if len(config) == 0 {
+		config = json.RawMessage(`{}`)
+	}
+
+	query := `
+		INSERT INTO market_resolvers (market_id, resolver_type, resolver_config, configured_at, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW(), NOW())
+		ON CONFLICT (market_id)
+		DO UPDATE SET resolver_type = EXCLUDED.resolver_type, resolver_config = EXCLUDED.resolver_config,
+		              configured_at = NOW(), last_checked_at = NULL, last_error = NULL, updated_at = NOW()
+	`
+
+	_, err := r.pool.Exec(ctx, query, resolver.MarketID, resolver.Type, config)
+	if err != nil {
+		return fmt.Errorf("failed to set resolver: %w", err)
+	}
+
+	return nil
+}
+
+// GetResolver retrieves the resolver configured for a market, if any
+func (r *MarketRepository) GetResolver(ctx context.Context, marketID uuid.UUID) (*models.MarketResolver, error) {
+	query := `
+		SELECT market_id, resolver_type, resolver_config, last_checked_at, last_error, created_at, updated_at
+		FROM market_resolvers
+		WHERE market_id = $1
+	`
+
+	var resolver models.MarketResolver
+	err := r.pool.QueryRow(ctx, query, marketID).Scan(
+		&resolver.MarketID,
+		&resolver.Type,
+		&resolver.Config,
+		&resolver.LastCheckedAt,
+		&resolver.LastError,
+		&resolver.CreatedAt,
+		&resolver.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get resolver: %w", err)
+	}
+
+	return &resolver, nil
+}
+
+// ResolversDue retrieves resolvers of closed markets that have no pending or
+// finalized proposal and were not checked since checkedBefore. A resolver
+// whose proposal was rejected after a dispute is skipped until an admin
+// configures it again, as it would only propose the same result.
+func (r *MarketRepository) ResolversDue(ctx context.Context, checkedBefore time.Time) ([]*models.MarketResolver, error) {
+	query := `
+		SELECT mr.market_id, mr.resolver_type, mr.resolver_config, mr.last_checked_at, mr.last_error, mr.created_at, mr.updated_at
+		FROM market_resolvers mr
+		JOIN markets m ON m.id = mr.market_id
+		WHERE m.status = $1
+		  AND (mr.last_checked_at IS NULL OR mr.last_checked_at < $2)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM resolution_proposals p
+		      WHERE p.market_id = mr.market_id AND p.status IN ($3, $4, $5)
+		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM resolution_proposals p
+		      WHERE p.market_id = mr.market_id AND p.status = $6 AND p.source = $7
+		        AND p.created_at >= mr.configured_at
+		  )
+	`
+
+	rows, err := r.pool.Query(ctx, query,
+		models.MarketStatusClosed,
+		checkedBefore,
+		models.ProposalStatusProposed,
+		models.ProposalStatusDisputed,
+		models.ProposalStatusFinalized,
+		models.ProposalStatusRejected,
+		models.ProposalSourceResolver,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resolvers: %w", err)
+	}
+	defer rows.Close()
+
+	resolvers := []*models.MarketResolver{}
+	for rows.Next() {
+		var resolver models.MarketResolver
+		err := rows.Scan(
+			&resolver.MarketID,
+			&resolver.Type,
+			&resolver.Config,
+			&resolver.LastCheckedAt,
+			&resolver.LastError,
+			&resolver.CreatedAt,
+			&resolver.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan resolver: %w", err)
+		}
+		resolvers = append(resolvers, &resolver)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating resolver rows: %w", err)
+	}
+
+	return resolvers, nil
+}
+
+// MarkResolverChecked records when a resolver last ran and its error, if any
+func (r *MarketRepository) MarkResolverChecked(ctx context.Context, marketID uuid.UUID, checkErr error) error {
+	var lastError *string
+	if checkErr != nil {
+		msg := checkErr.Error()
+		lastError = &msg
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		UPDATE market_resolvers SET last_checked_at = NOW(), last_error = $2 WHERE market_id = $1
+	`, marketID, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to update resolver: %w", err)
+	}
+
+	return nil
+}
+
+// CreateProposal records a proposed resolution for a closed market and opens
+// its challenge window
+func (r *MarketRepository) CreateProposal(ctx context.Context, proposal *models.ResolutionProposal) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	market, err := lockMarket(ctx, tx, proposal.MarketID)
+	if err != nil {
+		return err
+	}
+
+	if market.Status != models.MarketStatusClosed {
+		return ErrMarketNotClosed
+	}
+
+	if market.Type == models.MarketTypeScalar {
+		if proposal.Value == nil || proposal.OutcomeID != nil {
+			return ErrWrongMarketType
+		}
+	} else {
+		if proposal.OutcomeID == nil || proposal.Value != nil {
+			return ErrWrongMarketType
+		}
+
+		var exists bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM market_outcomes WHERE id = $1 AND market_id = $2)
+		`, *proposal.OutcomeID, proposal.MarketID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check outcome: %w", err)
+		}
+		if !exists {
+			return ErrOutcomeNotFound
+		}
+	}
+
+	err = scanProposal(tx.QueryRow(ctx, `
+		INSERT INTO resolution_proposals (id, market_id, proposer_user_id, source, outcome_id, value, evidence, status, challenge_ends_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING `+proposalColumns,
+		proposal.ID,
+		proposal.MarketID,
+		proposal.ProposerUserID,
+		proposal.Source,
+		proposal.OutcomeID,
+		proposal.Value,
+		proposal.Evidence,
+		models.ProposalStatusProposed,
+		proposal.ChallengeEndsAt,
+	), proposal)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrProposalPending
+		}
+		return fmt.Errorf("failed to create proposal: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetProposal retrieves a resolution proposal by ID
+func (r *MarketRepository) GetProposal(ctx context.Context, proposalID uuid.UUID) (*models.ResolutionProposal, error) {
+	var proposal models.ResolutionProposal
+	err := scanProposal(r.pool.QueryRow(ctx, `
+		SELECT `+proposalColumns+` FROM resolution_proposals WHERE id = $1
+	`, proposalID), &proposal)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProposalNotFound
+		}
+		return nil, fmt.Errorf("failed to get proposal: %w", err)
+	}
+
+	return &proposal, nil
+}
+
+// GetResolutionHistory retrieves every proposal and dispute made on a market
+func (r *MarketRepository) GetResolutionHistory(ctx context.Context, marketID uuid.UUID) (*models.ResolutionHistory, error) {
+	history := &models.ResolutionHistory{
+		MarketID:  marketID,
+		Proposals: []*models.ResolutionProposal{},
+		Disputes:  []*models.ResolutionDispute{},
+	}
+
+	resolver, err := r.GetResolver(ctx, marketID)
+	if err != nil {
+		return nil, err
+	}
+	history.Resolver = resolver
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+proposalColumns+`
+		FROM resolution_proposals
+		WHERE market_id = $1
+		ORDER BY created_at DESC
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query proposals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var proposal models.ResolutionProposal
+		if err := scanProposal(rows, &proposal); err != nil {
+			return nil, fmt.Errorf("failed to scan proposal: %w", err)
+		}
+		history.Proposals = append(history.Proposals, &proposal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating proposal rows: %w", err)
+	}
+
+	disputeRows, err := r.pool.Query(ctx, `
+		SELECT d.id, d.proposal_id, d.user_id, d.bond_credits, d.reason, d.status, d.created_at, d.settled_at
+		FROM resolution_disputes d
+		JOIN resolution_proposals p ON p.id = d.proposal_id
+		WHERE p.market_id = $1
+		ORDER BY d.created_at DESC
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query disputes: %w", err)
+	}
+	defer disputeRows.Close()
+
+	for disputeRows.Next() {
+		var dispute models.ResolutionDispute
+		err := disputeRows.Scan(
+			&dispute.ID,
+			&dispute.ProposalID,
+			&dispute.UserID,
+			&dispute.BondCredits,
+			&dispute.Reason,
+			&dispute.Status,
+			&dispute.CreatedAt,
+			&dispute.SettledAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		history.Disputes = append(history.Disputes, &dispute)
+	}
+
+	if err := disputeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dispute rows: %w", err)
+	}
+
+	return history, nil
+}
+
+// Dispute challenges a proposal within its challenge window, taking the bond
+// from the disputing user's wallet
+func (r *MarketRepository) Dispute(ctx context.Context, dispute *models.ResolutionDispute) (*models.ResolutionProposal, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	proposal, err := lockProposal(ctx, tx, dispute.ProposalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal.Status != models.ProposalStatusProposed && proposal.Status != models.ProposalStatusDisputed {
+		return nil, ErrProposalNotDisputable
+	}
+
+	if !time.Now().Before(proposal.ChallengeEndsAt) {
+		return nil, ErrChallengeWindowClosed
+	}
+
+	if err := adjustWallet(ctx, tx, dispute.UserID, -dispute.BondCredits); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO resolution_disputes (id, proposal_id, user_id, bond_credits, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING status, created_at
+	`, dispute.ID, dispute.ProposalID, dispute.UserID, dispute.BondCredits, dispute.Reason, models.DisputeStatusOpen,
+	).Scan(&dispute.Status, &dispute.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrAlreadyDisputed
+		}
+		return nil, fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	if proposal.Status != models.ProposalStatusDisputed {
+		err = scanProposal(tx.QueryRow(ctx, `
+			UPDATE resolution_proposals SET status = $2, updated_at = NOW() WHERE id = $1
+			RETURNING `+proposalColumns,
+			proposal.ID, models.ProposalStatusDisputed,
+		), proposal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark proposal disputed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return proposal, nil
+}
+
+// DueProposals returns the IDs of undisputed proposals whose challenge window has ended
+func (r *MarketRepository) DueProposals(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id FROM resolution_proposals WHERE status = $1 AND challenge_ends_at <= $2
+	`, models.ProposalStatusProposed, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due proposals: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan proposal ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating proposal rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// FinalizeProposal resolves the market to an undisputed proposal once its
+// challenge window has ended and settles all positions
+func (r *MarketRepository) FinalizeProposal(ctx context.Context, proposalID uuid.UUID) (*models.ResolutionProposal, *models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	proposal, err := lockProposal(ctx, tx, proposalID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if proposal.Status != models.ProposalStatusProposed {
+		return nil, nil, ErrInvalidTransition
+	}
+
+	if time.Now().Before(proposal.ChallengeEndsAt) {
+		return nil, nil, ErrInvalidTransition
+	}
+
+	settlement, err := finalizeProposal(ctx, tx, proposal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return proposal, settlement, nil
+}
+
+// Adjudicate rules on a disputed proposal. Upholding the disputes rejects the
+// proposal and refunds every bond; rejecting them forfeits the bonds and
+// finalizes the proposal.
+func (r *MarketRepository) Adjudicate(ctx context.Context, proposalID uuid.UUID, uphold bool) (*models.ResolutionProposal, *models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	proposal, err := lockProposal(ctx, tx, proposalID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if proposal.Status != models.ProposalStatusDisputed {
+		return nil, nil, ErrProposalNotDisputed
+	}
+
+	var settlement *models.MarketSettlement
+	if uphold {
+		_, err = tx.Exec(ctx, `
+			UPDATE wallets w
+			SET balance_credits = w.balance_credits + d.bond_credits, updated_at = NOW()
+			FROM resolution_disputes d
+			WHERE d.proposal_id = $1 AND d.status = $2 AND d.user_id = w.user_id
+		`, proposalID, models.DisputeStatusOpen)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to refund bonds: %w", err)
+		}
+
+		err = scanProposal(tx.QueryRow(ctx, `
+			UPDATE resolution_proposals SET status = $2, updated_at = NOW() WHERE id = $1
+			RETURNING `+proposalColumns,
+			proposalID, models.ProposalStatusRejected,
+		), proposal)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to reject proposal: %w", err)
+		}
+	} else {
+		settlement, err = finalizeProposal(ctx, tx, proposal)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	disputeStatus := models.DisputeStatusRejected
+	if uphold {
+		disputeStatus = models.DisputeStatusUpheld
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE resolution_disputes SET status = $3, settled_at = NOW()
+		WHERE proposal_id = $1 AND status = $2
+	`, proposalID, models.DisputeStatusOpen, disputeStatus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to settle disputes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return proposal, settlement, nil
+}
+
+// finalizeProposal resolves the proposal's market within tx and marks the
+// proposal finalized
+func finalizeProposal(ctx context.Context, tx pgx.Tx, proposal *models.ResolutionProposal) (*models.MarketSettlement, error) {
+	var settlement *models.MarketSettlement
+	var err error
+	if proposal.Value != nil {
+		settlement, err = resolveScalarValue(ctx, tx, proposal.MarketID, *proposal.Value)
+	} else {
+		settlement, err = resolveOutcome(ctx, tx, proposal.MarketID, *proposal.OutcomeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanProposal(tx.QueryRow(ctx, `
+		UPDATE resolution_proposals SET status = $2, finalized_at = $3, updated_at = NOW() WHERE id = $1
+		RETURNING `+proposalColumns,
+		proposal.ID, models.ProposalStatusFinalized, settlement.SettledAt,
+	), proposal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize proposal: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// withdrawPendingProposals rejects any pending proposal of a market that an
+// admin resolved or cancelled directly and refunds the bonds of its disputes
+func withdrawPendingProposals(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE wallets w
+		SET balance_credits = w.balance_credits + d.bond_credits, updated_at = NOW()
+		FROM resolution_disputes d
+		JOIN resolution_proposals p ON p.id = d.proposal_id
+		WHERE p.market_id = $1 AND d.status = $2 AND d.user_id = w.user_id
+	`, marketID, models.DisputeStatusOpen)
+	if err != nil {
+		return fmt.Errorf("failed to refund bonds: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE resolution_disputes d
+		SET status = $3, settled_at = NOW()
+		FROM resolution_proposals p
+		WHERE p.id = d.proposal_id AND p.market_id = $1 AND d.status = $2
+	`, marketID, models.DisputeStatusOpen, models.DisputeStatusUpheld)
+	if err != nil {
+		return fmt.Errorf("failed to settle disputes: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE resolution_proposals SET status = $4, updated_at = NOW()
+		WHERE market_id = $1 AND status IN ($2, $3)
+	`, marketID, models.ProposalStatusProposed, models.ProposalStatusDisputed, models.ProposalStatusRejected)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw proposals: %w", err)
+	}
+
+	return nil
+}
+
+// lockProposal locks a proposal row for the rest of the transaction
+func lockProposal(ctx context.Context, tx pgx.Tx, proposalID uuid.UUID) (*models.ResolutionProposal, error) {
+	var proposal models.ResolutionProposal
+	err := scanProposal(tx.QueryRow(ctx, `
+		SELECT `+proposalColumns+` FROM resolution_proposals WHERE id = $1 FOR UPDATE
+	`, proposalID), &proposal)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProposalNotFound
+		}
+		return nil, fmt.Errorf("failed to lock proposal: %w", err)
+	}
+
+	return &proposal, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"lfg/shared/models"
+)
+
+// marketFilter builds the WHERE clause shared by market listings and facet counts
+type marketFilter struct {
+	conditions []string
+	args       []interface{}
+	searchArg  int
+}
+
+// newMarketFilter translates filter into SQL conditions, leaving out the
+// condition on skip ("category" or "status") so that facet counts of that
+// dimension are not narrowed by its own selection
+func newMarketFilter(filter *models.MarketListFilter, skip string) *marketFilter {
+	f := &marketFilter{conditions: []string{"TRUE"}}
+
+	if filter.Status != "" && skip != "status" {
+		f.add("status = $%d", filter.Status)
+	}
+
+	// Hide drafts from public listings
+	if !filter.IncludeDrafts {
+		f.add("status != $%d", models.MarketStatusDraft)
+	}
+
+	if filter.Category != "" && skip != "category" {
+		f.add("category = $%d", filter.Category)
+	}
+
+	if filter.EventID != nil {
+		f.add("event_id = $%d", *filter.EventID)
+	}
+
+	if filter.TemplateID != nil {
+		f.add("template_id = $%d", *filter.TemplateID)
+	}
+
+	if len(filter.Tags) > 0 {
+		f.add("tags @> $%d", filter.Tags)
+	}
+
+	if filter.Search != "" {
+		// Wildcards in the search match themselves in tickers
+		f.args = append(f.args, filter.Search, "%"+escapeLike(strings.ToLower(filter.Search))+"%")
+		f.searchArg = len(f.args) - 1
+		f.conditions = append(f.conditions, fmt.Sprintf(
+			"(search_vector @@ websearch_to_tsquery('english', $%d) OR LOWER(ticker) LIKE $%d ESCAPE '\\')",
+			f.searchArg, len(f.args),
+		))
+	}
+
+	return f
+}
+
+// add appends a condition whose placeholders refer to arg
+func (f *marketFilter) add(condition string, arg interface{}) {
+	f.args = append(f.args, arg)
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, len(f.args)))
+}
+
+func (f *marketFilter) clause() string {
+	return strings.Join(f.conditions, " AND ")
+}
+
+// Facets counts the markets matching filter per category and per status
+func (r *MarketRepository) Facets(ctx context.Context, filter *models.MarketListFilter) (*models.MarketFacets, error) {
+	categories, err := r.facetCounts(ctx, "category", newMarketFilter(filter, "category"))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := r.facetCounts(ctx, "status", newMarketFilter(filter, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MarketFacets{
+		Categories: categories,
+		Statuses:   statuses,
+	}, nil
+}
+
+func (r *MarketRepository) facetCounts(ctx context.Context, column string, where *marketFilter) ([]*models.FacetCount, error) {
+	query := fmt.Sprintf(`
+		SELECT %[1]s::text, COUNT(*)
+		FROM markets
+		WHERE %[2]s AND %[1]s IS NOT NULL
+		GROUP BY %[1]s
+		ORDER BY COUNT(*) DESC, %[1]s
+	`, column, where.clause())
+
+	rows, err := r.pool.Query(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s facets: %w", column, err)
+	}
+	defer rows.Close()
+
+	counts := []*models.FacetCount{}
+	for rows.Next() {
+		var count models.FacetCount
+		if err := rows.Scan(&count.Value, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet count: %w", err)
+		}
+		counts = append(counts, &count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating facet rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Autocomplete suggests public markets whose ticker or question starts with
+// prefix, or whose question has words starting with the words of prefix.
+// Ticker matches come first, then open markets, then the most recent.
+func (r *MarketRepository) Autocomplete(ctx context.Context, prefix string, limit int) ([]*models.MarketSuggestion, error) {
+	likePattern := escapeLike(strings.ToLower(prefix)) + "%"
+
+	var wordQuery *string
+	if words := prefixTSQuery(prefix); words != "" {
+		wordQuery = &words
+	}
+
+	query := `
+		SELECT id, ticker, question, status
+		FROM markets
+		WHERE status != $1
+		  AND (LOWER(ticker) LIKE $2 ESCAPE '\' OR LOWER(question) LIKE $2 ESCAPE '\' OR search_vector @@ to_tsquery('english', $3))
+		ORDER BY (LOWER(ticker) LIKE $2 ESCAPE '\') DESC, (status = $4) DESC, created_at DESC
+		LIMIT $5
+	`
+
+	rows, err := r.pool.Query(ctx, query, models.MarketStatusDraft, likePattern, wordQuery, models.MarketStatusOpen, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []*models.MarketSuggestion{}
+	for rows.Next() {
+		var suggestion models.MarketSuggestion
+		err := rows.Scan(
+			&suggestion.ID,
+			&suggestion.Ticker,
+			&suggestion.Question,
+			&suggestion.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating suggestion rows: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// ListCategories retrieves all market categories in display order
+func (r *MarketRepository) ListCategories(ctx context.Context) ([]*models.Category, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT slug, name, display_order, created_at
+		FROM market_categories
+		ORDER BY display_order, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	for rows.Next() {
+		var category models.Category
+		err := rows.Scan(
+			&category.Slug,
+			&category.Name,
+			&category.DisplayOrder,
+			&category.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, &category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category rows: %w", err)
+	}
+
+	return categories, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// prefixTSQuery turns free text into a tsquery matching every word, the last
+// one as a prefix, e.g. "bitcoin re" becomes "bitcoin & re:*"
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"lfg/shared/models"
+)
+
+func TestMarketFilterSearchEscapesWildcards(t *testing.T) {
+	tests := []struct {
+		search  string
+		pattern string
+	}{
+		{"btc", "%btc%"},
+		{"BTC-100K", "%btc-100k%"},
+		{"%", `%\%%`},
+		{"a_b", `%a\_b%`},
+		{`50\%`, `%50\\\%%`},
+	}
+
+	for _, tt := range tests {
+		f := newMarketFilter(&models.MarketListFilter{Search: tt.search}, "")
+
+		if got := f.args[f.searchArg-1]; got != tt.search {
+			t.Errorf("search %q: text search argument = %v, want the raw search", tt.search, got)
+		}
+		if got := f.args[len(f.args)-1]; got != tt.pattern {
+			t.Errorf("search %q: ticker pattern = %v, want %q", tt.search, got, tt.pattern)
+		}
+		if !strings.Contains(f.clause(), `ESCAPE '\'`) {
+			t.Errorf("search %q: clause %q does not declare the escape character", tt.search, f.clause())
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrMarketNotResolvable = errors.New("market cannot be resolved in its current status")
+	ErrWrongMarketType     = errors.New("resolution does not match market type")
+	ErrInvalidScalarValue  = errors.New("scalar resolution value must be a finite number")
+	ErrTradingNotHalted    = errors.New("trading on the market has not been halted")
+)
+
+// Resolve resolves a market to exactly one winning outcome and settles all
+// positions: each share of the winning outcome's contract pays one credit and
+// every other contract pays nothing.
+func (r *MarketRepository) Resolve(ctx context.Context, marketID, outcomeID uuid.UUID) (*models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	settlement, err := resolveOutcome(ctx, tx, marketID, outcomeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withdrawPendingProposals(ctx, tx, marketID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// ResolveScalar resolves a scalar market to a numeric value and settles all
+// positions: LONG pays linearly in the value clamped to the market's range and
+// SHORT pays the remainder, so each share settles at a fraction of a credit.
+func (r *MarketRepository) ResolveScalar(ctx context.Context, marketID uuid.UUID, value float64) (*models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	settlement, err := resolveScalarValue(ctx, tx, marketID, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withdrawPendingProposals(ctx, tx, marketID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// CheckResolution checks that a resolution could settle a market, so that
+// trading is not stopped for a resolution that would then be refused
+func (r *MarketRepository) CheckResolution(ctx context.Context, market *models.Market, resolution *models.MarketResolveRequest) error {
+	return checkResolution(ctx, r.pool, market, resolution)
+}
+
+// checkResolution checks a resolution's outcome belongs to the market, or
+// that its value can price the contracts of a scalar market
+func checkResolution(ctx context.Context, pool *pgxpool.Pool, market *models.Market, resolution *models.MarketResolveRequest) error {
+	if market.Type == models.MarketTypeScalar {
+		if resolution.Value == nil {
+			return ErrWrongMarketType
+		}
+		if math.IsNaN(*resolution.Value) || math.IsInf(*resolution.Value, 0) {
+			return ErrInvalidScalarValue
+		}
+		if _, _, err := market.ScalarPayouts(*resolution.Value); err != nil {
+			return ErrInvalidBounds
+		}
+		return nil
+	}
+
+	var exists bool
+	err := pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM market_outcomes WHERE id = $1 AND market_id = $2)
+	`, resolution.OutcomeID, market.ID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check outcome: %w", err)
+	}
+	if !exists {
+		return ErrOutcomeNotFound
+	}
+
+	return nil
+}
+
+// resolveOutcome resolves a binary or categorical market within tx
+func resolveOutcome(ctx context.Context, tx pgx.Tx, marketID, outcomeID uuid.UUID) (*models.MarketSettlement, error) {
+	market, err := lockResolvableMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireTradingHalted(ctx, tx, market); err != nil {
+		return nil, err
+	}
+
+	if market.Type == models.MarketTypeScalar {
+		return nil, ErrWrongMarketType
+	}
+
+	contracts, err := contractOutcomes(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	payouts := make(map[uuid.UUID]float64, len(contracts))
+	found := false
+	for contractID, contractOutcomeID := range contracts {
+		if contractOutcomeID == outcomeID {
+			payouts[contractID] = models.CompleteSetValueCredits
+			found = true
+		} else {
+			payouts[contractID] = 0
+		}
+	}
+	if !found {
+		return nil, ErrOutcomeNotFound
+	}
+
+	settlement, err := settlePositions(ctx, tx, marketID, payouts)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE markets
+		SET status = $2, winning_outcome_id = $3, resolved_at = $4, updated_at = NOW()
+		WHERE id = $1
+	`, marketID, models.MarketStatusResolved, outcomeID, settlement.SettledAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve market: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// resolveScalarValue resolves a scalar market within tx
+func resolveScalarValue(ctx context.Context, tx pgx.Tx, marketID uuid.UUID, value float64) (*models.MarketSettlement, error) {
+	market, err := lockResolvableMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireTradingHalted(ctx, tx, market); err != nil {
+		return nil, err
+	}
+
+	if market.Type != models.MarketTypeScalar {
+		return nil, ErrWrongMarketType
+	}
+
+	// Settling with bounds that cannot price the contracts would pay nothing
+	// on both sides
+	long, short, err := market.ScalarPayouts(value)
+	if err != nil {
+		return nil, ErrInvalidBounds
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT c.id, o.name
+		FROM contracts c
+		JOIN market_outcomes o ON o.id = c.outcome_id
+		WHERE c.market_id = $1
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contracts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := make(map[uuid.UUID]float64, 2)
+	for rows.Next() {
+		var contractID uuid.UUID
+		var outcomeName string
+		if err := rows.Scan(&contractID, &outcomeName); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+
+		switch outcomeName {
+		case models.OutcomeNameLong:
+			payouts[contractID] = long
+		case models.OutcomeNameShort:
+			payouts[contractID] = short
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contract rows: %w", err)
+	}
+	rows.Close()
+
+	settlement, err := settlePositions(ctx, tx, marketID, payouts)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE markets
+		SET status = $2, resolved_value = $3, resolved_at = $4, updated_at = NOW()
+		WHERE id = $1
+	`, marketID, models.MarketStatusResolved, value, settlement.SettledAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve market: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// Cancel cancels a market and refunds every holder of its contracts what
+// they paid into the market, net of what they took out of it, so cancelling
+// does not move credits from one side of the market to the other. Holders who
+// took out more than they paid in keep the difference and are refunded
+// nothing.
+func (r *MarketRepository) Cancel(ctx context.Context, marketID uuid.UUID) (*models.MarketSettlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	settlement, err := cancelMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withdrawPendingProposals(ctx, tx, marketID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// cancelMarket cancels and refunds a market within tx
+func cancelMarket(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) (*models.MarketSettlement, error) {
+	market, err := lockMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if market.Status == models.MarketStatusResolved || market.Status == models.MarketStatusCancelled {
+		return nil, ErrInvalidTransition
+	}
+
+	if err := requireTradingHalted(ctx, tx, market); err != nil {
+		return nil, err
+	}
+
+	settlement, err := refundCostBasis(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE markets SET status = $2, updated_at = NOW() WHERE id = $1
+	`, marketID, models.MarketStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel market: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// refundCostBasis refunds the holders of a market's contracts their cost
+// basis in the market and closes their positions
+func refundCostBasis(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) (*models.MarketSettlement, error) {
+	settlement := &models.MarketSettlement{
+		MarketID:  marketID,
+		Payouts:   []*models.ContractPayout{},
+		SettledAt: time.Now(),
+	}
+
+	// Refunds are worked out before positions are closed, as only holders
+	// are refunded
+	rows, err := tx.Query(ctx, `
+		SELECT b.user_id, b.credits
+		FROM market_cost_basis b
+		WHERE b.market_id = $1 AND b.credits > 0
+		  AND EXISTS (
+			SELECT 1 FROM positions p
+			JOIN contracts c ON c.id = p.contract_id
+			WHERE c.market_id = b.market_id AND p.user_id = b.user_id AND p.quantity > 0
+		  )
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost basis: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var userID uuid.UUID
+		var credits float64
+		if err := rows.Scan(&userID, &credits); err != nil {
+			return nil, fmt.Errorf("failed to scan cost basis: %w", err)
+		}
+		refunds[userID] = credits
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cost basis rows: %w", err)
+	}
+	rows.Close()
+
+	for userID, credits := range refunds {
+		_, err := tx.Exec(ctx, `
+			UPDATE wallets SET balance_credits = balance_credits + $2, updated_at = NOW() WHERE user_id = $1
+		`, userID, credits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refund cost basis: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO notifications (user_id, type, message, payload)
+			SELECT $1, $4,
+				format('%s cancelled: %s credits refunded', m.ticker, round($3::numeric, 2)),
+				jsonb_build_object('market_id', m.id, 'credits', $3::numeric)
+			FROM markets m
+			WHERE m.id = $2
+		`, userID, marketID, credits, models.NotificationMarketRefund)
+		if err != nil {
+			return nil, fmt.Errorf("failed to notify refunded holder: %w", err)
+		}
+
+		settlement.TotalCredits += credits
+	}
+	settlement.RefundedUsers = len(refunds)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE market_cost_basis SET credits = 0, updated_at = NOW() WHERE market_id = $1 AND credits != 0
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear cost basis: %w", err)
+	}
+
+	contracts, err := contractOutcomes(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	for contractID := range contracts {
+		var sharesSettled int
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM positions WHERE contract_id = $1 AND quantity > 0
+		`, contractID).Scan(&sharesSettled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum positions: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE positions SET quantity = 0, updated_at = NOW() WHERE contract_id = $1 AND quantity > 0
+		`, contractID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to close positions: %w", err)
+		}
+
+		settlement.Payouts = append(settlement.Payouts, &models.ContractPayout{
+			ContractID:    contractID,
+			SharesSettled: sharesSettled,
+		})
+	}
+
+	return settlement, nil
+}
+
+// lockMarket locks a market row for the rest of the transaction
+func lockMarket(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) (*models.Market, error) {
+	var market models.Market
+	err := scanMarket(tx.QueryRow(ctx, `
+		SELECT `+marketColumns+` FROM markets WHERE id = $1 FOR UPDATE
+	`, marketID), &market)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMarketNotFound
+		}
+		return nil, fmt.Errorf("failed to lock market: %w", err)
+	}
+
+	return &market, nil
+}
+
+// lockResolvableMarket locks a market row and checks it has been published
+// and not already resolved or cancelled
+func lockResolvableMarket(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) (*models.Market, error) {
+	market, err := lockMarket(ctx, tx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch market.Status {
+	case models.MarketStatusDraft, models.MarketStatusResolved, models.MarketStatusCancelled:
+		return nil, ErrMarketNotResolvable
+	}
+
+	return market, nil
+}
+
+// requireTradingHalted checks the matching engine has halted a locked market
+// that was ever published, so that no fill lands after it is settled
+func requireTradingHalted(ctx context.Context, tx pgx.Tx, market *models.Market) error {
+	if market.Status == models.MarketStatusDraft {
+		return nil
+	}
+
+	var halted bool
+	err := tx.QueryRow(ctx, `
+		SELECT trading_halted_at IS NOT NULL FROM markets WHERE id = $1
+	`, market.ID).Scan(&halted)
+	if err != nil {
+		return fmt.Errorf("failed to check trading halt: %w", err)
+	}
+
+	if !halted {
+		return ErrTradingNotHalted
+	}
+
+	return nil
+}
+
+// contractOutcomes maps every contract of a market to its outcome ID
+func contractOutcomes(ctx context.Context, tx pgx.Tx, marketID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, outcome_id FROM contracts WHERE market_id = $1
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contracts := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var contractID, outcomeID uuid.UUID
+		if err := rows.Scan(&contractID, &outcomeID); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contracts[contractID] = outcomeID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contract rows: %w", err)
+	}
+
+	return contracts, nil
+}
+
+// settlePositions pays every holder of a market's contracts the per-share
+// payout of that contract and closes the positions. Payouts of all contracts
+// in a market add up to one credit, so a complete set always settles at par.
+func settlePositions(ctx context.Context, tx pgx.Tx, marketID uuid.UUID, payouts map[uuid.UUID]float64) (*models.MarketSettlement, error) {
+	settlement := &models.MarketSettlement{
+		MarketID:  marketID,
+		Payouts:   make([]*models.ContractPayout, 0, len(payouts)),
+		SettledAt: time.Now(),
+	}
+
+	for contractID, payoutPerShare := range payouts {
+		var sharesSettled int
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM positions WHERE contract_id = $1 AND quantity > 0
+		`, contractID).Scan(&sharesSettled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum positions: %w", err)
+		}
+
+		if payoutPerShare > 0 && sharesSettled > 0 {
+			_, err = tx.Exec(ctx, `
+				UPDATE wallets w
+				SET balance_credits = w.balance_credits + p.quantity * $2, updated_at = NOW()
+				FROM positions p
+				WHERE p.contract_id = $1 AND p.user_id = w.user_id AND p.quantity > 0
+			`, contractID, payoutPerShare)
+			if err != nil {
+				return nil, fmt.Errorf("failed to pay out positions: %w", err)
+			}
+		}
+
+		// Holders learn of the payout from their notification inbox, even if
+		// they are offline when the market settles
+		_, err = tx.Exec(ctx, `
+			INSERT INTO notifications (user_id, type, message, payload)
+			SELECT p.user_id, $3,
+				format('%s settled: %s %s shares paid %s credits', m.ticker, p.quantity, c.ticker, round(p.quantity * $2::numeric, 2)),
+				jsonb_build_object(
+					'market_id', m.id,
+					'contract_id', c.id,
+					'quantity', p.quantity,
+					'payout_per_share', $2::numeric,
+					'credits', p.quantity * $2::numeric
+				)
+			FROM positions p
+			JOIN contracts c ON c.id = p.contract_id
+			JOIN markets m ON m.id = c.market_id
+			WHERE p.contract_id = $1 AND p.quantity > 0
+		`, contractID, payoutPerShare, models.NotificationMarketSettled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to notify position holders: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE positions SET quantity = 0, updated_at = NOW() WHERE contract_id = $1 AND quantity > 0
+		`, contractID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to close positions: %w", err)
+		}
+
+		totalCredits := float64(sharesSettled) * payoutPerShare
+		settlement.Payouts = append(settlement.Payouts, &models.ContractPayout{
+			ContractID:     contractID,
+			PayoutPerShare: payoutPerShare,
+			SharesSettled:  sharesSettled,
+			TotalCredits:   totalCredits,
+		})
+		settlement.TotalCredits += totalCredits
+	}
+
+	return settlement, nil
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrTemplateNotFound  = errors.New("market template not found")
+	ErrTemplateNameTaken = errors.New("market template name already exists")
+)
+
+const templateColumns = `id, name, ticker_pattern, question_pattern, rules_pattern, resolution_source, market_type, outcomes, scalar_lower_bound, scalar_upper_bound, category, tags, event_id, params, interval_seconds, duration_seconds, auto_publish, active, next_run_at, last_run_at, last_error, created_at, updated_at`
+
+// TemplateRepository handles market template database operations
+type TemplateRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(pool *pgxpool.Pool) *TemplateRepository {
+	return &TemplateRepository{pool: pool}
+}
+
+// scanTemplate scans a row selected with templateColumns into a template
+func scanTemplate(row pgx.Row, template *models.MarketTemplate) error {
+	return row.Scan(
+		&template.ID,
+		&template.Name,
+		&template.TickerPattern,
+		&template.QuestionPattern,
+		&template.RulesPattern,
+		&template.ResolutionSource,
+		&template.Type,
+		&template.Outcomes,
+		&template.LowerBound,
+		&template.UpperBound,
+		&template.Category,
+		&template.Tags,
+		&template.EventID,
+		&template.Params,
+		&template.IntervalSeconds,
+		&template.DurationSeconds,
+		&template.AutoPublish,
+		&template.Active,
+		&template.NextRunAt,
+		&template.LastRunAt,
+		&template.LastError,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+}
+
+// Create creates a new market template
+func (r *TemplateRepository) Create(ctx context.Context, template *models.MarketTemplate) error {
+	if template.Outcomes == nil {
+		template.Outcomes = []string{}
+	}
+	if template.Tags == nil {
+		template.Tags = []string{}
+	}
+	if template.Params == nil {
+		template.Params = map[string]string{}
+	}
+
+	err := scanTemplate(r.pool.QueryRow(ctx, `
+		INSERT INTO market_templates (id, name, ticker_pattern, question_pattern, rules_pattern, resolution_source, market_type, outcomes,
+		                              scalar_lower_bound, scalar_upper_bound, category, tags, event_id, params, interval_seconds,
+		                              duration_seconds, auto_publish, active, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NOW(), NOW())
+		RETURNING `+templateColumns,
+		template.ID,
+		template.Name,
+		template.TickerPattern,
+		template.QuestionPattern,
+		template.RulesPattern,
+		template.ResolutionSource,
+		template.Type,
+		template.Outcomes,
+		template.LowerBound,
+		template.UpperBound,
+		template.Category,
+		template.Tags,
+		template.EventID,
+		template.Params,
+		template.IntervalSeconds,
+		template.DurationSeconds,
+		template.AutoPublish,
+		template.Active,
+		template.NextRunAt,
+	), template)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTemplateNameTaken
+		}
+		if fkErr := foreignKeyError(err); fkErr != nil {
+			return fkErr
+		}
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a market template by ID
+func (r *TemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.MarketTemplate, error) {
+	var template models.MarketTemplate
+	err := scanTemplate(r.pool.QueryRow(ctx, `
+		SELECT `+templateColumns+` FROM market_templates WHERE id = $1
+	`, id), &template)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// List retrieves all market templates by name
+func (r *TemplateRepository) List(ctx context.Context) ([]*models.MarketTemplate, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+templateColumns+` FROM market_templates ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTemplates(rows)
+}
+
+// Update applies the set fields of req to a template
+func (r *TemplateRepository) Update(ctx context.Context, req *models.MarketTemplateUpdateRequest) (*models.MarketTemplate, error) {
+	var template models.MarketTemplate
+	err := scanTemplate(r.pool.QueryRow(ctx, `
+		UPDATE market_templates
+		SET params = COALESCE($2, params),
+		    active = COALESCE($3, active),
+		    next_run_at = COALESCE($4, next_run_at),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+templateColumns,
+		req.TemplateID,
+		req.Params,
+		req.Active,
+		req.NextRunAt,
+	), &template)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// ClaimDue returns the active templates due to run by now and advances their
+// next run past now, so every run is claimed by exactly one scheduler even
+// with several market-service replicas. The returned templates hold the run
+// time that was claimed in NextRunAt: the latest run due by now.
+func (r *TemplateRepository) ClaimDue(ctx context.Context, now time.Time) ([]*models.MarketTemplate, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+templateColumns+`
+		FROM market_templates
+		WHERE active AND next_run_at <= $1
+		ORDER BY next_run_at
+		FOR UPDATE SKIP LOCKED
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due templates: %w", err)
+	}
+
+	templates, err := scanTemplates(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		// Runs missed while the service was down are skipped rather than
+		// creating a backlog of markets that have already expired
+		run, next := claimRun(template.NextRunAt, time.Duration(template.IntervalSeconds)*time.Second, now)
+		template.NextRunAt = run
+
+		_, err := tx.Exec(ctx, `
+			UPDATE market_templates SET next_run_at = $2, last_run_at = $3, updated_at = NOW() WHERE id = $1
+		`, template.ID, next, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to advance template: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return templates, nil
+}
+
+// claimRun returns the latest run of a schedule due by now, counting from
+// the due run at nextRunAt, and the run after it
+func claimRun(nextRunAt time.Time, interval time.Duration, now time.Time) (run, next time.Time) {
+	run = nextRunAt
+	if interval > 0 && now.After(run) {
+		run = run.Add(now.Sub(run) / interval * interval)
+	}
+	return run, run.Add(interval)
+}
+
+// RecordRunError records the error of a template's latest run, or clears it
+func (r *TemplateRepository) RecordRunError(ctx context.Context, templateID uuid.UUID, runErr error) error {
+	var lastError *string
+	if runErr != nil {
+		msg := runErr.Error()
+		lastError = &msg
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		UPDATE market_templates SET last_error = $2 WHERE id = $1
+	`, templateID, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to record template run: %w", err)
+	}
+
+	return nil
+}
+
+func scanTemplates(rows pgx.Rows) ([]*models.MarketTemplate, error) {
+	templates := []*models.MarketTemplate{}
+	for rows.Next() {
+		var template models.MarketTemplate
+		if err := scanTemplate(rows, &template); err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, &template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating template rows: %w", err)
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimRun(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		due      time.Time
+		interval time.Duration
+		now      time.Time
+		wantRun  time.Time
+		wantNext time.Time
+	}{
+		{"due right now", start, day, start, start, start.Add(day)},
+		{"due moments ago", start, day, start.Add(time.Minute), start, start.Add(day)},
+		{"one run missed", start, day, start.Add(day + time.Minute), start.Add(day), start.Add(2 * day)},
+		{"days of downtime", start, day, start.Add(5*day + 3*hour), start.Add(5 * day), start.Add(6 * day)},
+		{"exactly on a later run", start, hour, start.Add(3 * hour), start.Add(3 * hour), start.Add(4 * hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, next := claimRun(tt.due, tt.interval, tt.now)
+			if !run.Equal(tt.wantRun) {
+				t.Errorf("run = %v, want %v", run, tt.wantRun)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("next = %v, want %v", next, tt.wantNext)
+			}
+			if run.After(tt.now) || !next.After(tt.now) {
+				t.Errorf("run %v and next %v do not straddle now %v", run, next, tt.now)
+			}
+		})
+	}
+}
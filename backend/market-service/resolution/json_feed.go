@@ -0,0 +1,165 @@
+package resolution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"lfg/shared/models"
+)
+
+// JSONFeedType is the resolver type of JSONFeedResolver
+const JSONFeedType = "json_feed"
+
+func init() {
+	Register(JSONFeedType, NewJSONFeedResolver)
+}
+
+// JSONFeedConfig configures a JSON feed resolver. URL is the http or https
+// address of the feed; Field is a dotted path to the result within the
+// document.
+type JSONFeedConfig struct {
+	URL   string `json:"url"`
+	Field string `json:"field"`
+}
+
+// JSONFeedResolver reads a market result from an HTTP feed.
+// A number resolves a scalar market, a string names the winning outcome and a
+// boolean resolves a binary market to YES or NO. A missing or null field means
+// the source has not published a result yet.
+type JSONFeedResolver struct {
+	config JSONFeedConfig
+	client *http.Client
+}
+
+// NewJSONFeedResolver creates a JSON feed resolver from its configuration
+func NewJSONFeedResolver(config json.RawMessage, timeout time.Duration) (Resolver, error) {
+	var cfg JSONFeedConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid json_feed config: %w", err)
+	}
+
+	// Feeds are only fetched over HTTP, so a resolver cannot read the
+	// service's own files
+	feedURL, err := url.Parse(cfg.URL)
+	if err != nil || (feedURL.Scheme != "http" && feedURL.Scheme != "https") || feedURL.Host == "" {
+		return nil, errors.New("json_feed config needs an http or https url")
+	}
+
+	if cfg.Field == "" {
+		return nil, errors.New("json_feed config needs a field")
+	}
+
+	return &JSONFeedResolver{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Resolve fetches the feed and interprets the configured field
+func (j *JSONFeedResolver) Resolve(ctx context.Context, market *models.Market, outcomes []*models.Outcome) (*Result, error) {
+	body, err := j.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	value := document
+	for _, key := range strings.Split(j.config.Field, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, ErrNotResolved
+			}
+			value = node[index]
+		default:
+			return nil, ErrNotResolved
+		}
+	}
+
+	evidence := fmt.Sprintf("%s %s = %v", j.config.URL, j.config.Field, value)
+
+	switch v := value.(type) {
+	case nil:
+		return nil, ErrNotResolved
+
+	case float64:
+		if market.Type != models.MarketTypeScalar {
+			return nil, fmt.Errorf("feed returned a number for a %s market", market.Type)
+		}
+		return &Result{Value: &v, Evidence: evidence}, nil
+
+	case bool:
+		if market.Type != models.MarketTypeBinary {
+			return nil, fmt.Errorf("feed returned a boolean for a %s market", market.Type)
+		}
+		name := models.OutcomeNameNo
+		if v {
+			name = models.OutcomeNameYes
+		}
+		return matchOutcome(name, outcomes, evidence)
+
+	case string:
+		if market.Type == models.MarketTypeScalar {
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("feed returned a non-numeric value for a scalar market: %q", v)
+			}
+			return &Result{Value: &number, Evidence: evidence}, nil
+		}
+		return matchOutcome(v, outcomes, evidence)
+	}
+
+	return nil, fmt.Errorf("feed field %s has unsupported type %T", j.config.Field, value)
+}
+
+func (j *JSONFeedResolver) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	return body, nil
+}
+
+// matchOutcome finds the outcome with the given name, ignoring case
+func matchOutcome(name string, outcomes []*models.Outcome, evidence string) (*Result, error) {
+	for _, outcome := range outcomes {
+		if strings.EqualFold(strings.TrimSpace(name), outcome.Name) {
+			id := outcome.ID
+			return &Result{OutcomeID: &id, Evidence: evidence}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("feed result %q does not match any outcome", name)
+}
@@ -0,0 +1,99 @@
+package resolution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+)
+
+func TestNewJSONFeedResolverConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"https url", `{"url": "https://feeds.example.com/result.json", "field": "result"}`, false},
+		{"http url", `{"url": "http://feeds.example.com/result.json", "field": "result"}`, false},
+		{"local path", `{"path": "/etc/passwd", "field": "result"}`, true},
+		{"file url", `{"url": "file:///etc/passwd", "field": "result"}`, true},
+		{"url without host", `{"url": "http:///result.json", "field": "result"}`, true},
+		{"relative url", `{"url": "result.json", "field": "result"}`, true},
+		{"missing field", `{"url": "https://feeds.example.com/result.json"}`, true},
+		{"not json", `not json`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJSONFeedResolver(json.RawMessage(tt.config), time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONFeedResolverResolve(t *testing.T) {
+	yes := &models.Outcome{ID: uuid.New(), Name: models.OutcomeNameYes}
+	no := &models.Outcome{ID: uuid.New(), Name: models.OutcomeNameNo}
+	binary := &models.Market{Type: models.MarketTypeBinary}
+	scalar := &models.Market{Type: models.MarketTypeScalar}
+
+	tests := []struct {
+		name        string
+		document    string
+		field       string
+		market      *models.Market
+		wantOutcome *models.Outcome
+		wantValue   *float64
+		wantErr     error
+	}{
+		{"boolean true", `{"result": true}`, "result", binary, yes, nil, nil},
+		{"boolean false", `{"result": false}`, "result", binary, no, nil, nil},
+		{"outcome name", `{"data": {"winner": "yes"}}`, "data.winner", binary, yes, nil, nil},
+		{"array index", `{"rows": [{"v": 42.5}]}`, "rows.0.v", scalar, nil, ptr(42.5), nil},
+		{"numeric string", `{"v": " 7 "}`, "v", scalar, nil, ptr(7), nil},
+		{"null field", `{"result": null}`, "result", binary, nil, nil, ErrNotResolved},
+		{"missing field", `{}`, "result", binary, nil, nil, ErrNotResolved},
+		{"index out of range", `{"rows": []}`, "rows.0", scalar, nil, nil, ErrNotResolved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.document))
+			}))
+			defer server.Close()
+
+			config, _ := json.Marshal(JSONFeedConfig{URL: server.URL, Field: tt.field})
+			resolver, err := NewJSONFeedResolver(config, time.Second)
+			if err != nil {
+				t.Fatalf("NewJSONFeedResolver: %v", err)
+			}
+
+			result, err := resolver.Resolve(context.Background(), tt.market, []*models.Outcome{yes, no})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if tt.wantOutcome != nil && (result.OutcomeID == nil || *result.OutcomeID != tt.wantOutcome.ID) {
+				t.Errorf("outcome = %v, want %s", result.OutcomeID, tt.wantOutcome.Name)
+			}
+			if tt.wantValue != nil && (result.Value == nil || *result.Value != *tt.wantValue) {
+				t.Errorf("value = %v, want %v", result.Value, *tt.wantValue)
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
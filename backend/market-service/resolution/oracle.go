@@ -0,0 +1,147 @@
+package resolution
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+	"lfg/market-service/scheduler"
+)
+
+// Oracle periodically runs the resolvers of closed markets, proposing their
+// results, and finalizes proposals whose challenge window passed undisputed
+type Oracle struct {
+	repo            *repository.MarketRepository
+	lifecycle       *scheduler.LifecycleScheduler
+	challengeWindow time.Duration
+	resolverTimeout time.Duration
+	interval        time.Duration
+}
+
+// NewOracle creates a new resolution oracle
+func NewOracle(repo *repository.MarketRepository, lifecycle *scheduler.LifecycleScheduler, challengeWindow, resolverTimeout, interval time.Duration) *Oracle {
+	return &Oracle{
+		repo:            repo,
+		lifecycle:       lifecycle,
+		challengeWindow: challengeWindow,
+		resolverTimeout: resolverTimeout,
+		interval:        interval,
+	}
+}
+
+// ChallengeWindow returns how long proposals stay open to disputes
+func (o *Oracle) ChallengeWindow() time.Duration {
+	return o.challengeWindow
+}
+
+// Run checks resolvers and due proposals every interval until ctx is cancelled
+func (o *Oracle) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	o.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.tick(ctx)
+		}
+	}
+}
+
+func (o *Oracle) tick(ctx context.Context) {
+	now := time.Now()
+
+	resolvers, err := o.repo.ResolversDue(ctx, now.Add(-o.interval))
+	if err != nil {
+		log.Printf("Failed to get due resolvers: %v", err)
+	}
+	for _, resolver := range resolvers {
+		err := o.runResolver(ctx, resolver)
+		if errors.Is(err, ErrNotResolved) {
+			err = nil
+		}
+		if err != nil {
+			log.Printf("Resolver %s for market %s failed: %v", resolver.Type, resolver.MarketID, err)
+		}
+		if markErr := o.repo.MarkResolverChecked(ctx, resolver.MarketID, err); markErr != nil {
+			log.Printf("Failed to record resolver check for market %s: %v", resolver.MarketID, markErr)
+		}
+	}
+
+	due, err := o.repo.DueProposals(ctx, now)
+	if err != nil {
+		log.Printf("Failed to get due proposals: %v", err)
+	}
+	for _, proposalID := range due {
+		proposal, settlement, err := o.repo.FinalizeProposal(ctx, proposalID)
+		if err != nil {
+			log.Printf("Failed to finalize proposal %s: %v", proposalID, err)
+			continue
+		}
+		log.Printf("Finalized proposal %s, paid out %.2f credits", proposal.ID, settlement.TotalCredits)
+		o.PublishStatus(ctx, proposal.MarketID, models.MarketLifecycleResolved)
+	}
+}
+
+// runResolver asks a market's resolver for its result and proposes it
+func (o *Oracle) runResolver(ctx context.Context, config *models.MarketResolver) error {
+	resolver, err := NewResolver(config.Type, config.Config, o.resolverTimeout)
+	if err != nil {
+		return err
+	}
+
+	market, err := o.repo.GetByID(ctx, config.MarketID)
+	if err != nil {
+		return err
+	}
+
+	outcomes, err := o.repo.GetOutcomesByMarketID(ctx, market.ID)
+	if err != nil {
+		return err
+	}
+
+	resolveCtx, cancel := context.WithTimeout(ctx, o.resolverTimeout)
+	defer cancel()
+
+	result, err := resolver.Resolve(resolveCtx, market, outcomes)
+	if err != nil {
+		return err
+	}
+
+	proposal := &models.ResolutionProposal{
+		ID:              uuid.New(),
+		MarketID:        market.ID,
+		Source:          models.ProposalSourceResolver,
+		OutcomeID:       result.OutcomeID,
+		Value:           result.Value,
+		Evidence:        &result.Evidence,
+		ChallengeEndsAt: time.Now().Add(o.challengeWindow),
+	}
+
+	if err := o.repo.CreateProposal(ctx, proposal); err != nil {
+		return err
+	}
+
+	log.Printf("Resolver %s proposed a result for market %s", config.Type, market.Ticker)
+	o.PublishStatus(ctx, market.ID, models.MarketLifecycleResolutionProposed)
+
+	return nil
+}
+
+// PublishStatus emits a lifecycle event with the market's current state
+func (o *Oracle) PublishStatus(ctx context.Context, marketID uuid.UUID, eventType models.MarketLifecycleEventType) {
+	market, err := o.repo.GetByID(ctx, marketID)
+	if err != nil {
+		log.Printf("Failed to load market %s for lifecycle event: %v", marketID, err)
+		return
+	}
+
+	o.lifecycle.Publish(ctx, eventType, market)
+}
@@ -0,0 +1,60 @@
+package resolution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrNotResolved     = errors.New("source has not published a result yet")
+	ErrUnknownResolver = errors.New("unknown resolver type")
+)
+
+// Result is the outcome a resolver read from its source. Binary and
+// categorical markets resolve to an outcome, scalar markets to a value.
+type Result struct {
+	OutcomeID *uuid.UUID
+	Value     *float64
+	Evidence  string
+}
+
+// Resolver reads the result of a market from an external source
+type Resolver interface {
+	Resolve(ctx context.Context, market *models.Market, outcomes []*models.Outcome) (*Result, error)
+}
+
+// Factory builds a resolver from its JSON configuration
+type Factory func(config json.RawMessage, timeout time.Duration) (Resolver, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a resolver type available to markets
+func Register(resolverType string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[resolverType] = factory
+}
+
+// NewResolver builds the resolver of the given type
+func NewResolver(resolverType string, config json.RawMessage, timeout time.Duration) (Resolver, error) {
+	registryMu.RLock()
+	factory, ok := registry[resolverType]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownResolver, resolverType)
+	}
+
+	return factory(config, timeout)
+}
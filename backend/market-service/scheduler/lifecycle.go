@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	pb "lfg/matching-engine/proto"
+	"lfg/shared/metrics"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+)
+
+// haltBatch is how many markets the scheduler halts again at a time when
+// the matching engine did not confirm halting them
+const haltBatch = 100
+
+// MarketStore opens and closes markets and records which stopped markets the
+// matching engine has halted
+type MarketStore interface {
+	OpenDueMarkets(ctx context.Context, now time.Time) ([]*models.Market, error)
+	CloseExpiredMarkets(ctx context.Context, now time.Time) ([]*models.Market, error)
+	UnhaltedMarkets(ctx context.Context, limit int) ([]*models.Market, error)
+	GetContractsByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Contract, error)
+	CancelRestingOrders(ctx context.Context, marketID uuid.UUID) (int64, error)
+}
+
+// LifecycleScheduler periodically opens markets that have reached their open
+// time and closes markets that have expired. Stopped markets the matching
+// engine has not confirmed halting are halted again until it does.
+type LifecycleScheduler struct {
+	repo     MarketStore
+	engine   pb.MatchingEngineClient
+	natsConn *nats.Conn
+	interval time.Duration
+}
+
+// NewLifecycleScheduler creates a new lifecycle scheduler
+func NewLifecycleScheduler(repo MarketStore, engine pb.MatchingEngineClient, natsConn *nats.Conn, interval time.Duration) *LifecycleScheduler {
+	return &LifecycleScheduler{
+		repo:     repo,
+		engine:   engine,
+		natsConn: natsConn,
+		interval: interval,
+	}
+}
+
+// Run processes due markets every interval until ctx is cancelled
+func (s *LifecycleScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *LifecycleScheduler) tick(ctx context.Context) {
+	now := time.Now()
+
+	opened, err := s.repo.OpenDueMarkets(ctx, now)
+	if err != nil {
+		log.Printf("Failed to open due markets: %v", err)
+	}
+	for _, market := range opened {
+		log.Printf("Opened market %s", market.Ticker)
+		s.Publish(ctx, models.MarketLifecycleOpened, market)
+	}
+
+	// Markets closed here are left unhalted if halting fails and picked up
+	// again below on a later tick
+	closed, err := s.repo.CloseExpiredMarkets(ctx, now)
+	if err != nil {
+		log.Printf("Failed to close expired markets: %v", err)
+	}
+	for _, market := range closed {
+		log.Printf("Closed expired market %s", market.Ticker)
+		if err := s.HaltTrading(ctx, market); err != nil {
+			log.Printf("Failed to halt trading on market %s: %v", market.Ticker, err)
+		}
+		s.Publish(ctx, models.MarketLifecycleClosed, market)
+	}
+
+	unhalted, err := s.repo.UnhaltedMarkets(ctx, haltBatch)
+	if err != nil {
+		log.Printf("Failed to get unhalted markets: %v", err)
+	}
+	for _, market := range unhalted {
+		if err := s.HaltTrading(ctx, market); err != nil {
+			log.Printf("Failed to halt trading on market %s: %v", market.Ticker, err)
+		}
+	}
+}
+
+// HaltTrading stops the matching engine from accepting orders on every
+// contract of a market and then cancels all of the market's resting orders.
+// It fails without cancelling anything unless the engine halted every
+// contract, and the market is not settled until it succeeds.
+func (s *LifecycleScheduler) HaltTrading(ctx context.Context, market *models.Market) error {
+	contracts, err := s.repo.GetContractsByMarketID(ctx, market.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get contracts: %w", err)
+	}
+
+	for _, contract := range contracts {
+		haltCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err := s.engine.HaltContract(haltCtx, &pb.HaltContractRequest{ContractId: contract.ID.String()})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to halt contract %s: %w", contract.Ticker, err)
+		}
+	}
+
+	cancelled, err := s.repo.CancelRestingOrders(ctx, market.ID)
+	if err != nil {
+		return err
+	}
+
+	if cancelled > 0 {
+		log.Printf("Cancelled %d resting orders on market %s", cancelled, market.Ticker)
+	}
+
+	return nil
+}
+
+// Publish emits a lifecycle event for a market on NATS, carrying the trace
+// of ctx
+func (s *LifecycleScheduler) Publish(ctx context.Context, eventType models.MarketLifecycleEventType, market *models.Market) {
+	if s.natsConn == nil {
+		return
+	}
+
+	event := models.MarketLifecycleEvent{
+		Type:       eventType,
+		MarketID:   market.ID,
+		Ticker:     market.Ticker,
+		Status:     market.Status,
+		OccurredAt: time.Now(),
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal lifecycle event: %v", err)
+		return
+	}
+
+	msg := nats.NewMsg(models.MarketLifecycleSubject)
+	msg.Data = eventJSON
+	tracing.Inject(ctx, msg.Header)
+
+	if err := s.natsConn.PublishMsg(msg); err != nil {
+		metrics.PublishFailed(msg.Subject)
+		log.Printf("Failed to publish lifecycle event: %v", err)
+	}
+}
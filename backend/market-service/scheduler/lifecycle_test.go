@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/models"
+	pb "lfg/matching-engine/proto"
+)
+
+// fakeEngine halts contracts unless told to fail on one
+type fakeEngine struct {
+	pb.MatchingEngineClient
+	failOn string
+	halted []string
+}
+
+func (e *fakeEngine) HaltContract(ctx context.Context, req *pb.HaltContractRequest, opts ...grpc.CallOption) (*pb.HaltContractResponse, error) {
+	if req.ContractId == e.failOn {
+		return nil, status.Error(codes.Unavailable, "matching engine unavailable")
+	}
+	e.halted = append(e.halted, req.ContractId)
+	return &pb.HaltContractResponse{Success: true}, nil
+}
+
+// fakeStore keeps markets in memory and records which were halted
+type fakeStore struct {
+	contracts map[uuid.UUID][]*models.Contract
+	expired   []*models.Market
+	unhalted  []*models.Market
+	halted    []uuid.UUID
+}
+
+func (s *fakeStore) OpenDueMarkets(ctx context.Context, now time.Time) ([]*models.Market, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) CloseExpiredMarkets(ctx context.Context, now time.Time) ([]*models.Market, error) {
+	closed := s.expired
+	s.expired = nil
+	for _, market := range closed {
+		market.Status = models.MarketStatusClosed
+		s.unhalted = append(s.unhalted, market)
+	}
+	return closed, nil
+}
+
+func (s *fakeStore) UnhaltedMarkets(ctx context.Context, limit int) ([]*models.Market, error) {
+	return s.unhalted, nil
+}
+
+func (s *fakeStore) GetContractsByMarketID(ctx context.Context, marketID uuid.UUID) ([]*models.Contract, error) {
+	return s.contracts[marketID], nil
+}
+
+func (s *fakeStore) CancelRestingOrders(ctx context.Context, marketID uuid.UUID) (int64, error) {
+	s.halted = append(s.halted, marketID)
+	unhalted := []*models.Market{}
+	for _, market := range s.unhalted {
+		if market.ID != marketID {
+			unhalted = append(unhalted, market)
+		}
+	}
+	s.unhalted = unhalted
+	return 0, nil
+}
+
+// newMarket creates a market with a contract per ticker in store
+func newMarket(store *fakeStore, tickers ...string) *models.Market {
+	market := &models.Market{ID: uuid.New(), Ticker: "MARKET", Status: models.MarketStatusOpen}
+	for _, ticker := range tickers {
+		store.contracts[market.ID] = append(store.contracts[market.ID], &models.Contract{ID: uuid.New(), MarketID: market.ID, Ticker: ticker})
+	}
+	return market
+}
+
+func TestHaltTrading(t *testing.T) {
+	tests := []struct {
+		name       string
+		failOn     int
+		wantErr    bool
+		wantHalted int
+	}{
+		{
+			name:       "every contract halted",
+			failOn:     -1,
+			wantHalted: 2,
+		},
+		{
+			name:       "engine fails on a contract",
+			failOn:     1,
+			wantErr:    true,
+			wantHalted: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{contracts: map[uuid.UUID][]*models.Contract{}}
+			market := newMarket(store, "YES", "NO")
+			engine := &fakeEngine{}
+			if tt.failOn >= 0 {
+				engine.failOn = store.contracts[market.ID][tt.failOn].ID.String()
+			}
+
+			err := NewLifecycleScheduler(store, engine, nil, time.Minute).HaltTrading(context.Background(), market)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HaltTrading() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(engine.halted) != tt.wantHalted {
+				t.Errorf("engine halted %d contracts, want %d", len(engine.halted), tt.wantHalted)
+			}
+			if cancelled := len(store.halted) > 0; cancelled == tt.wantErr {
+				t.Errorf("resting orders cancelled = %v with err %v", cancelled, err)
+			}
+		})
+	}
+}
+
+func TestTickHaltsMarketsAgainUntilEngineConfirms(t *testing.T) {
+	store := &fakeStore{contracts: map[uuid.UUID][]*models.Contract{}}
+	market := newMarket(store, "YES")
+	store.expired = []*models.Market{market}
+	engine := &fakeEngine{failOn: store.contracts[market.ID][0].ID.String()}
+	s := NewLifecycleScheduler(store, engine, nil, time.Minute)
+
+	s.tick(context.Background())
+	if len(store.halted) != 0 || len(store.unhalted) != 1 {
+		t.Fatalf("market recorded as halted although the engine failed")
+	}
+
+	engine.failOn = ""
+	s.tick(context.Background())
+	if len(store.halted) != 1 || store.halted[0] != market.ID {
+		t.Errorf("halted markets = %v, want %s", store.halted, market.ID)
+	}
+	if len(store.unhalted) != 0 {
+		t.Errorf("%d markets left unhalted", len(store.unhalted))
+	}
+}
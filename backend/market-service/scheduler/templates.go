@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/market-service/repository"
+)
+
+// templateFuncs are available to template patterns
+var templateFuncs = template.FuncMap{
+	"date":  func(layout string, t time.Time) string { return t.UTC().Format(layout) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// TemplateScheduler periodically creates markets from the recurring market
+// templates that are due
+type TemplateScheduler struct {
+	repo         *repository.MarketRepository
+	templateRepo *repository.TemplateRepository
+	lifecycle    *LifecycleScheduler
+	interval     time.Duration
+}
+
+// NewTemplateScheduler creates a new template scheduler
+func NewTemplateScheduler(repo *repository.MarketRepository, templateRepo *repository.TemplateRepository, lifecycle *LifecycleScheduler, interval time.Duration) *TemplateScheduler {
+	return &TemplateScheduler{
+		repo:         repo,
+		templateRepo: templateRepo,
+		lifecycle:    lifecycle,
+		interval:     interval,
+	}
+}
+
+// Run creates markets for due templates every interval until ctx is cancelled
+func (s *TemplateScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *TemplateScheduler) tick(ctx context.Context) {
+	templates, err := s.templateRepo.ClaimDue(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to claim due market templates: %v", err)
+		return
+	}
+
+	for _, tmpl := range templates {
+		market, err := s.createMarket(ctx, tmpl, tmpl.NextRunAt)
+		if err != nil {
+			log.Printf("Failed to create market from template %s: %v", tmpl.Name, err)
+		} else {
+			log.Printf("Created market %s from template %s", market.Ticker, tmpl.Name)
+		}
+
+		if err := s.templateRepo.RecordRunError(ctx, tmpl.ID, err); err != nil {
+			log.Printf("Failed to record run of template %s: %v", tmpl.Name, err)
+		}
+	}
+}
+
+// createMarket creates the market of one template run together with its
+// outcomes and contracts
+func (s *TemplateScheduler) createMarket(ctx context.Context, tmpl *models.MarketTemplate, runAt time.Time) (*models.Market, error) {
+	market, err := RenderTemplate(tmpl, runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := s.repo.CreateWithOutcomes(ctx, market, TemplateOutcomes(tmpl)); err != nil {
+		return nil, err
+	}
+
+	if market.Status == models.MarketStatusOpen {
+		s.lifecycle.Publish(ctx, models.MarketLifecycleOpened, market)
+	}
+
+	return market, nil
+}
+
+// RenderTemplate renders the market of a template run starting at runAt. The
+// market opens at runAt and expires the template's duration later; it is
+// created open when the template auto-publishes and as a draft otherwise.
+func RenderTemplate(tmpl *models.MarketTemplate, runAt time.Time) (*models.Market, error) {
+	data := models.MarketTemplateData{
+		OpensAt:   runAt,
+		ExpiresAt: runAt.Add(time.Duration(tmpl.DurationSeconds) * time.Second),
+		Params:    tmpl.Params,
+	}
+
+	ticker, err := renderPattern("ticker", tmpl.TickerPattern, data)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := renderPattern("question", tmpl.QuestionPattern, data)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := renderPattern("rules", tmpl.RulesPattern, data)
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.MarketStatusDraft
+	if tmpl.AutoPublish {
+		status = models.MarketStatusOpen
+	}
+
+	templateID := tmpl.ID
+	return &models.Market{
+		ID:               uuid.New(),
+		Ticker:           strings.ToUpper(ticker),
+		Question:         question,
+		Rules:            rules,
+		ResolutionSource: tmpl.ResolutionSource,
+		Category:         tmpl.Category,
+		Tags:             tmpl.Tags,
+		EventID:          tmpl.EventID,
+		TemplateID:       &templateID,
+		Status:           status,
+		Type:             tmpl.Type,
+		LowerBound:       tmpl.LowerBound,
+		UpperBound:       tmpl.UpperBound,
+		OpensAt:          &data.OpensAt,
+		ExpiresAt:        data.ExpiresAt,
+	}, nil
+}
+
+// TemplateOutcomes returns the outcome names of the markets a template creates
+func TemplateOutcomes(tmpl *models.MarketTemplate) []string {
+	switch tmpl.Type {
+	case models.MarketTypeCategorical:
+		return tmpl.Outcomes
+	case models.MarketTypeScalar:
+		return []string{models.OutcomeNameLong, models.OutcomeNameShort}
+	}
+	return []string{models.OutcomeNameYes, models.OutcomeNameNo}
+}
+
+func renderPattern(name, pattern string, data models.MarketTemplateData) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s pattern: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s pattern: %w", name, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
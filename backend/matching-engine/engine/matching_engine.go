@@ -9,9 +9,26 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	pb "lfg/matching-engine/proto"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 )
 
+// BookDeltaSubjectPrefix prefixes the contract ID in the NATS subject order
+// book deltas are published on
+const BookDeltaSubjectPrefix = "orderbook."
+
+// TradeSubjectPrefix prefixes the contract ID in the NATS subject trades are
+// published on, so consumers can follow single contracts or all of them
+// with "trades.*"
+const TradeSubjectPrefix = "trades."
+
+var tracer = tracing.Tracer("lfg/matching-engine/engine")
+
 // MatchingEngine manages the order books for all contracts
 type MatchingEngine struct {
 	OrderBooks map[string]*OrderBook // Map of contract ID to OrderBook
@@ -42,8 +59,14 @@ func (me *MatchingEngine) GetOrCreateOrderBook(contractID string) *OrderBook {
 	return newOB
 }
 
-// PlaceOrder implements the gRPC PlaceOrder method
+// PlaceOrder implements the gRPC PlaceOrder method. An order placed again
+// with the same ID gets the response it got the first time, so callers can
+// resubmit orders they never got an answer for.
 func (me *MatchingEngine) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.PlaceOrderResponse, error) {
+	if err := validateOrder(req); err != nil {
+		return nil, err
+	}
+
 	// Get or create order book for contract
 	orderBook := me.GetOrCreateOrderBook(req.ContractId)
 
@@ -60,7 +83,26 @@ func (me *MatchingEngine) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequ
 	}
 
 	// Add order to book and match
-	trades, quantityFilled, status := orderBook.AddOrder(order)
+	_, span := tracer.Start(ctx, "orderbook.AddOrder", trace.WithAttributes(
+		attribute.String("contract.id", req.ContractId),
+		attribute.String("order.id", req.OrderId),
+	))
+	start := time.Now()
+	placement, delta := orderBook.AddOrder(order)
+	trades, quantityFilled, status := placement.Trades, placement.QuantityFilled, placement.Status
+	span.SetAttributes(
+		attribute.String("order.status", status),
+		attribute.Int("order.quantity_filled", quantityFilled),
+		attribute.Int("trades.count", len(trades)),
+		attribute.Bool("order.replayed", placement.Replayed),
+	)
+	span.End()
+	if !placement.Replayed {
+		matchDuration.Observe(time.Since(start).Seconds())
+		ordersTotal.WithLabelValues(req.Type.String(), req.Side.String(), status).Inc()
+		tradesTotal.Add(float64(len(trades)))
+	}
+	me.publishBookDelta(ctx, delta)
 
 	// Convert trades to protobuf format
 	pbTrades := make([]*pb.Trade, len(trades))
@@ -83,8 +125,8 @@ func (me *MatchingEngine) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequ
 		averagePrice = totalValue / float64(quantityFilled)
 	}
 
-	// Publish trade events to NATS
-	if me.natsConn != nil && len(trades) > 0 {
+	// Publish trade events to NATS, once
+	if me.natsConn != nil && len(trades) > 0 && !placement.Replayed {
 		for _, trade := range trades {
 			tradeEvent := map[string]interface{}{
 				"trade_id":       trade.ID,
@@ -104,8 +146,8 @@ func (me *MatchingEngine) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequ
 				continue
 			}
 
-			// Publish to trades topic
-			if err := me.natsConn.Publish("trades", eventJSON); err != nil {
+			// Publish to the contract's trades topic
+			if err := me.publish(ctx, TradeSubjectPrefix+trade.ContractID, eventJSON); err != nil {
 				log.Printf("Failed to publish trade event: %v", err)
 			} else {
 				log.Printf("Published trade event: %s", trade.ID)
@@ -122,6 +164,21 @@ func (me *MatchingEngine) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequ
 	}, nil
 }
 
+// validateOrder rejects orders the book cannot take with InvalidArgument
+func validateOrder(req *pb.PlaceOrderRequest) error {
+	switch {
+	case req.OrderId == "":
+		return status.Error(codes.InvalidArgument, "order ID required")
+	case req.ContractId == "":
+		return status.Error(codes.InvalidArgument, "contract ID required")
+	case req.Quantity <= 0:
+		return status.Error(codes.InvalidArgument, "quantity must be positive")
+	case req.Type == pb.OrderType_LIMIT && req.LimitPrice <= 0:
+		return status.Error(codes.InvalidArgument, "limit price required for limit orders")
+	}
+	return nil
+}
+
 // CancelOrder implements the gRPC CancelOrder method
 func (me *MatchingEngine) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
 	me.mu.RLock()
@@ -135,7 +192,14 @@ func (me *MatchingEngine) CancelOrder(ctx context.Context, req *pb.CancelOrderRe
 		}, nil
 	}
 
-	success := orderBook.CancelOrder(req.OrderId)
+	_, span := tracer.Start(ctx, "orderbook.CancelOrder", trace.WithAttributes(
+		attribute.String("contract.id", req.ContractId),
+		attribute.String("order.id", req.OrderId),
+	))
+	success, delta := orderBook.CancelOrder(req.OrderId)
+	span.SetAttributes(attribute.Bool("order.cancelled", success))
+	span.End()
+	me.publishBookDelta(ctx, delta)
 	message := "Order cancelled successfully"
 	if !success {
 		message = "Order not found or already filled"
@@ -165,7 +229,12 @@ func (me *MatchingEngine) GetOrderBook(ctx context.Context, req *pb.GetOrderBook
 		depth = 10 // Default depth
 	}
 
-	bids, asks := orderBook.GetAggregatedBook(depth)
+	_, span := tracer.Start(ctx, "orderbook.GetAggregatedBook", trace.WithAttributes(
+		attribute.String("contract.id", req.ContractId),
+		attribute.Int("orderbook.depth", depth),
+	))
+	bids, asks, sequence := orderBook.GetAggregatedBook(depth)
+	span.End()
 
 	// Convert to protobuf format
 	pbBids := make([]*pb.OrderBookLevel, len(bids))
@@ -187,11 +256,72 @@ func (me *MatchingEngine) GetOrderBook(ctx context.Context, req *pb.GetOrderBook
 	}
 
 	return &pb.GetOrderBookResponse{
-		Bids: pbBids,
-		Asks: pbAsks,
+		Bids:     pbBids,
+		Asks:     pbAsks,
+		Sequence: sequence,
+	}, nil
+}
+
+// HaltContract implements the gRPC HaltContract method
+func (me *MatchingEngine) HaltContract(ctx context.Context, req *pb.HaltContractRequest) (*pb.HaltContractResponse, error) {
+	// Create the book if needed so orders arriving later are rejected too
+	orderBook := me.GetOrCreateOrderBook(req.ContractId)
+
+	_, span := tracer.Start(ctx, "orderbook.Halt", trace.WithAttributes(
+		attribute.String("contract.id", req.ContractId),
+	))
+	cancelled, delta := orderBook.Halt()
+	span.SetAttributes(attribute.Int("orders.cancelled", len(cancelled)))
+	span.End()
+	me.publishBookDelta(ctx, delta)
+	log.Printf("Halted contract %s, cancelled %d resting orders", req.ContractId, len(cancelled))
+
+	return &pb.HaltContractResponse{
+		Success:           true,
+		CancelledOrderIds: cancelled,
 	}, nil
 }
 
+// Halt halts the books of contracts without publishing anything, for the
+// contracts of markets that stopped trading before the engine started
+func (me *MatchingEngine) Halt(contractIDs []string) {
+	for _, contractID := range contractIDs {
+		me.GetOrCreateOrderBook(contractID).Halt()
+	}
+}
+
+// publishBookDelta publishes the price levels changed by an operation on a
+// book to the book's NATS subject
+func (me *MatchingEngine) publishBookDelta(ctx context.Context, delta *BookDelta) {
+	if me.natsConn == nil || delta == nil {
+		return
+	}
+
+	deltaJSON, err := json.Marshal(delta)
+	if err != nil {
+		log.Printf("Failed to marshal order book delta: %v", err)
+		return
+	}
+
+	if err := me.publish(ctx, BookDeltaSubjectPrefix+delta.ContractID, deltaJSON); err != nil {
+		log.Printf("Failed to publish order book delta: %v", err)
+	}
+}
+
+// publish publishes data on a NATS subject with the trace context and
+// request ID of the call it results from
+func (me *MatchingEngine) publish(ctx context.Context, subject string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	tracing.Inject(ctx, msg.Header)
+
+	if err := me.natsConn.PublishMsg(msg); err != nil {
+		metrics.PublishFailed(subject)
+		return err
+	}
+	return nil
+}
+
 // Trade represents a matched trade
 type Trade struct {
 	ID            string
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ordersTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lfg_engine_orders_total",
+		Help: "Orders processed by the matching engine, by type, side and resulting status.",
+	}, []string{"type", "side", "status"})
+
+	tradesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lfg_engine_trades_total",
+		Help: "Trades executed by the matching engine.",
+	})
+
+	matchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "lfg_engine_match_duration_seconds",
+		Help:    "Time taken to add an order to its book and match it.",
+		Buckets: []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025},
+	})
+
+	bookDepth = prometheus.NewDesc("lfg_engine_book_depth",
+		"Price levels on each side of a contract's order book.",
+		[]string{"contract_id", "side"}, nil)
+
+	restingOrders = prometheus.NewDesc("lfg_engine_resting_orders",
+		"Orders resting on each side of a contract's order book.",
+		[]string{"contract_id", "side"}, nil)
+)
+
+// Collectors returns the metrics of the engine, for registration with the
+// metrics registry
+func (me *MatchingEngine) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{ordersTotal, tradesTotal, matchDuration, &bookCollector{engine: me}}
+}
+
+// bookCollector reads the depth and resting orders of every order book at
+// scrape time
+type bookCollector struct {
+	engine *MatchingEngine
+}
+
+// Describe implements prometheus.Collector
+func (c *bookCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bookDepth
+	ch <- restingOrders
+}
+
+// Collect implements prometheus.Collector
+func (c *bookCollector) Collect(ch chan<- prometheus.Metric) {
+	c.engine.mu.RLock()
+	books := make([]*OrderBook, 0, len(c.engine.OrderBooks))
+	for _, ob := range c.engine.OrderBooks {
+		books = append(books, ob)
+	}
+	c.engine.mu.RUnlock()
+
+	for _, ob := range books {
+		stats := ob.Stats()
+		ch <- prometheus.MustNewConstMetric(bookDepth, prometheus.GaugeValue, float64(stats.BidLevels), ob.ContractID, "bid")
+		ch <- prometheus.MustNewConstMetric(bookDepth, prometheus.GaugeValue, float64(stats.AskLevels), ob.ContractID, "ask")
+		ch <- prometheus.MustNewConstMetric(restingOrders, prometheus.GaugeValue, float64(stats.BidOrders), ob.ContractID, "bid")
+		ch <- prometheus.MustNewConstMetric(restingOrders, prometheus.GaugeValue, float64(stats.AskOrders), ob.ContractID, "ask")
+	}
+}
@@ -26,23 +26,75 @@ type OrderBook struct {
 	ContractID string
 	Bids       []*Order // Buy orders (sorted high to low)
 	Asks       []*Order // Sell orders (sorted low to high)
+	Halted     bool     // Halted books reject all new orders
+	Sequence   uint64   // Incremented with every change to the resting orders
+	placed     map[string]*Placement
+	placedIDs  []string // IDs of placed orders, oldest first
 	mu         sync.Mutex
 }
 
+// placedRetention is how long a book remembers the outcome of an order, so
+// that an order submitted again, because its caller never got the answer,
+// gets that outcome instead of being added twice
+const placedRetention = time.Hour
+
+// Placement is the outcome of adding an order to a book
+type Placement struct {
+	Trades         []*Trade
+	QuantityFilled int
+	Status         string
+	Replayed       bool // The order was added before and this is that outcome
+	placedAt       time.Time
+}
+
+// LevelChange is the total quantity resting at a price level after a change
+// to the book; a zero quantity means the level was removed
+type LevelChange struct {
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+}
+
+// BookDelta lists the price levels changed by one operation on a book. Its
+// sequence is one more than the previous delta's, so a gap means a missed delta.
+type BookDelta struct {
+	ContractID string        `json:"contract_id"`
+	Sequence   uint64        `json:"sequence"`
+	Bids       []LevelChange `json:"bids"`
+	Asks       []LevelChange `json:"asks"`
+	BestBid    *float64      `json:"best_bid"`
+	BestAsk    *float64      `json:"best_ask"`
+	Timestamp  int64         `json:"timestamp"`
+}
+
 // NewOrderBook creates a new OrderBook
 func NewOrderBook(contractID string) *OrderBook {
 	return &OrderBook{
 		ContractID: contractID,
 		Bids:       make([]*Order, 0),
 		Asks:       make([]*Order, 0),
+		placed:     make(map[string]*Placement),
 	}
 }
 
-// AddOrder adds a new order to the order book and attempts to match it
-func (ob *OrderBook) AddOrder(order *Order) ([]*Trade, int, string) {
+// AddOrder adds a new order to the order book and attempts to match it. An
+// order added before is not added again; its original outcome is returned,
+// marked as replayed, even once the book is halted. The returned delta is
+// nil when the order left the book unchanged.
+func (ob *OrderBook) AddOrder(order *Order) (*Placement, *BookDelta) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
+	ob.forgetPlaced(time.Now().Add(-placedRetention))
+	if placed, ok := ob.placed[order.ID]; ok {
+		replayed := *placed
+		replayed.Replayed = true
+		return &replayed, nil
+	}
+
+	if ob.Halted {
+		return &Placement{Trades: []*Trade{}, Status: "REJECTED"}, nil
+	}
+
 	trades := []*Trade{}
 	quantityFilled := 0
 
@@ -66,19 +118,52 @@ func (ob *OrderBook) AddOrder(order *Order) ([]*Trade, int, string) {
 		status = "PARTIALLY_FILLED"
 	}
 
+	// Trades consume liquidity on the side opposite the order
+	bidPrices := []float64{}
+	askPrices := []float64{}
+	for _, trade := range trades {
+		if order.Side == pb.OrderSide_BUY {
+			askPrices = append(askPrices, trade.Price)
+		} else {
+			bidPrices = append(bidPrices, trade.Price)
+		}
+	}
+
 	// If not fully filled and it's a limit order, add to book
 	if quantityFilled < order.Quantity && order.Type == pb.OrderType_LIMIT {
 		order.Quantity = order.Quantity - quantityFilled // Remaining quantity
+		order.Filled = 0
 		if order.Side == pb.OrderSide_BUY {
 			ob.Bids = append(ob.Bids, order)
 			ob.sortBids()
+			bidPrices = append(bidPrices, order.LimitPrice)
 		} else {
 			ob.Asks = append(ob.Asks, order)
 			ob.sortAsks()
+			askPrices = append(askPrices, order.LimitPrice)
 		}
 	}
 
-	return trades, quantityFilled, status
+	placement := &Placement{
+		Trades:         trades,
+		QuantityFilled: quantityFilled,
+		Status:         status,
+		placedAt:       time.Now(),
+	}
+	ob.placed[order.ID] = placement
+	ob.placedIDs = append(ob.placedIDs, order.ID)
+
+	return placement, ob.delta(bidPrices, askPrices)
+}
+
+// forgetPlaced forgets the outcomes of orders placed before cutoff
+func (ob *OrderBook) forgetPlaced(cutoff time.Time) {
+	n := 0
+	for n < len(ob.placedIDs) && ob.placed[ob.placedIDs[n]].placedAt.Before(cutoff) {
+		delete(ob.placed, ob.placedIDs[n])
+		n++
+	}
+	ob.placedIDs = ob.placedIDs[n:]
 }
 
 // matchBuyOrder matches a buy order against the ask side
@@ -180,7 +265,7 @@ func (ob *OrderBook) matchSellOrder(order *Order) ([]*Trade, int) {
 }
 
 // CancelOrder removes an order from the book
-func (ob *OrderBook) CancelOrder(orderID string) bool {
+func (ob *OrderBook) CancelOrder(orderID string) (bool, *BookDelta) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
@@ -189,7 +274,7 @@ func (ob *OrderBook) CancelOrder(orderID string) bool {
 		if order != nil && order.ID == orderID {
 			ob.Bids[i] = nil
 			ob.cleanupBids()
-			return true
+			return true, ob.delta([]float64{order.LimitPrice}, nil)
 		}
 	}
 
@@ -198,22 +283,133 @@ func (ob *OrderBook) CancelOrder(orderID string) bool {
 		if order != nil && order.ID == orderID {
 			ob.Asks[i] = nil
 			ob.cleanupAsks()
-			return true
+			return true, ob.delta(nil, []float64{order.LimitPrice})
 		}
 	}
 
-	return false
+	return false, nil
 }
 
-// GetAggregatedBook returns aggregated price levels
-func (ob *OrderBook) GetAggregatedBook(depth int) ([]PriceLevel, []PriceLevel) {
+// Halt stops the book from accepting orders and removes all resting orders,
+// returning the IDs of the orders it cancelled
+func (ob *OrderBook) Halt() ([]string, *BookDelta) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.Halted = true
+
+	cancelled := make([]string, 0, len(ob.Bids)+len(ob.Asks))
+	bidPrices := make([]float64, 0, len(ob.Bids))
+	askPrices := make([]float64, 0, len(ob.Asks))
+	for _, order := range ob.Bids {
+		if order != nil {
+			cancelled = append(cancelled, order.ID)
+			bidPrices = append(bidPrices, order.LimitPrice)
+		}
+	}
+	for _, order := range ob.Asks {
+		if order != nil {
+			cancelled = append(cancelled, order.ID)
+			askPrices = append(askPrices, order.LimitPrice)
+		}
+	}
+
+	ob.Bids = make([]*Order, 0)
+	ob.Asks = make([]*Order, 0)
+
+	return cancelled, ob.delta(bidPrices, askPrices)
+}
+
+// GetAggregatedBook returns aggregated price levels, best first, and the
+// sequence of the book they were taken at
+func (ob *OrderBook) GetAggregatedBook(depth int) ([]PriceLevel, []PriceLevel, uint64) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
 	bidLevels := ob.aggregateSide(ob.Bids, depth)
 	askLevels := ob.aggregateSide(ob.Asks, depth)
 
-	return bidLevels, askLevels
+	return bidLevels, askLevels, ob.Sequence
+}
+
+// BookStats counts the price levels and resting orders on each side of a book
+type BookStats struct {
+	BidLevels int
+	AskLevels int
+	BidOrders int
+	AskOrders int
+}
+
+// Stats returns the current price levels and resting orders of the book
+func (ob *OrderBook) Stats() BookStats {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	bidLevels := ob.aggregateSide(ob.Bids, len(ob.Bids))
+	askLevels := ob.aggregateSide(ob.Asks, len(ob.Asks))
+
+	stats := BookStats{BidLevels: len(bidLevels), AskLevels: len(askLevels)}
+	for _, level := range bidLevels {
+		stats.BidOrders += level.OrderCount
+	}
+	for _, level := range askLevels {
+		stats.AskOrders += level.OrderCount
+	}
+	return stats
+}
+
+// delta advances the book sequence and reports the current quantity at each
+// of the given bid and ask prices. It returns nil when no price was touched.
+// Must be called with the book locked.
+func (ob *OrderBook) delta(bidPrices, askPrices []float64) *BookDelta {
+	if len(bidPrices) == 0 && len(askPrices) == 0 {
+		return nil
+	}
+
+	ob.Sequence++
+
+	delta := &BookDelta{
+		ContractID: ob.ContractID,
+		Sequence:   ob.Sequence,
+		Bids:       levelChanges(ob.Bids, bidPrices),
+		Asks:       levelChanges(ob.Asks, askPrices),
+		Timestamp:  time.Now().Unix(),
+	}
+
+	// Both sides are kept sorted best price first
+	if len(ob.Bids) > 0 {
+		bestBid := ob.Bids[0].LimitPrice
+		delta.BestBid = &bestBid
+	}
+	if len(ob.Asks) > 0 {
+		bestAsk := ob.Asks[0].LimitPrice
+		delta.BestAsk = &bestAsk
+	}
+
+	return delta
+}
+
+// levelChanges returns the resting quantity at each distinct price
+func levelChanges(orders []*Order, prices []float64) []LevelChange {
+	changes := make([]LevelChange, 0, len(prices))
+	seen := make(map[float64]bool, len(prices))
+
+	for _, price := range prices {
+		if seen[price] {
+			continue
+		}
+		seen[price] = true
+
+		quantity := 0
+		for _, order := range orders {
+			if order != nil && order.LimitPrice == price {
+				quantity += order.Quantity - order.Filled
+			}
+		}
+		changes = append(changes, LevelChange{Price: price, Quantity: quantity})
+	}
+
+	return changes
 }
 
 // PriceLevel represents an aggregated price level
@@ -223,9 +419,11 @@ type PriceLevel struct {
 	OrderCount int
 }
 
-// aggregateSide aggregates orders by price level
+// aggregateSide aggregates orders by price level. The side is kept sorted
+// best price first, so the levels come out in the same order.
 func (ob *OrderBook) aggregateSide(orders []*Order, depth int) []PriceLevel {
-	priceMap := make(map[float64]*PriceLevel)
+	levels := make([]PriceLevel, 0)
+	index := make(map[float64]int)
 
 	for _, order := range orders {
 		if order == nil {
@@ -237,24 +435,19 @@ func (ob *OrderBook) aggregateSide(orders []*Order, depth int) []PriceLevel {
 			continue
 		}
 
-		if level, exists := priceMap[order.LimitPrice]; exists {
-			level.Quantity += remaining
-			level.OrderCount++
+		if i, exists := index[order.LimitPrice]; exists {
+			levels[i].Quantity += remaining
+			levels[i].OrderCount++
 		} else {
-			priceMap[order.LimitPrice] = &PriceLevel{
+			index[order.LimitPrice] = len(levels)
+			levels = append(levels, PriceLevel{
 				Price:      order.LimitPrice,
 				Quantity:   remaining,
 				OrderCount: 1,
-			}
+			})
 		}
 	}
 
-	// Convert map to slice
-	levels := make([]PriceLevel, 0, len(priceMap))
-	for _, level := range priceMap {
-		levels = append(levels, *level)
-	}
-
 	// Limit to depth
 	if len(levels) > depth {
 		levels = levels[:depth]
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	pb "lfg/matching-engine/proto"
+)
+
+func limitOrder(id string, side pb.OrderSide, quantity int, price float64) *Order {
+	return &Order{
+		ID:         id,
+		UserID:     "user-" + id,
+		ContractID: "contract",
+		Type:       pb.OrderType_LIMIT,
+		Side:       side,
+		Quantity:   quantity,
+		LimitPrice: price,
+		Timestamp:  time.Now(),
+	}
+}
+
+func TestAddOrderReplaysPlacedOrders(t *testing.T) {
+	tests := []struct {
+		name         string
+		halt         bool
+		wantStatus   string
+		wantFilled   int
+		wantTrades   int
+		wantReplayed bool
+	}{
+		{
+			name:         "placed again",
+			wantStatus:   "PARTIALLY_FILLED",
+			wantFilled:   4,
+			wantTrades:   1,
+			wantReplayed: true,
+		},
+		{
+			name:         "placed again once the book is halted",
+			halt:         true,
+			wantStatus:   "PARTIALLY_FILLED",
+			wantFilled:   4,
+			wantTrades:   1,
+			wantReplayed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderBook("contract")
+			ob.AddOrder(limitOrder("ask", pb.OrderSide_SELL, 4, 0.5))
+
+			first, _ := ob.AddOrder(limitOrder("bid", pb.OrderSide_BUY, 10, 0.6))
+			if first.Replayed {
+				t.Fatal("first placement reported as replayed")
+			}
+			if tt.halt {
+				ob.Halt()
+			}
+
+			again, delta := ob.AddOrder(limitOrder("bid", pb.OrderSide_BUY, 10, 0.6))
+			if again.Replayed != tt.wantReplayed || again.Status != tt.wantStatus || again.QuantityFilled != tt.wantFilled || len(again.Trades) != tt.wantTrades {
+				t.Errorf("AddOrder() again = %s, %d filled in %d trades, replayed %v; want %s, %d in %d, replayed %v",
+					again.Status, again.QuantityFilled, len(again.Trades), again.Replayed, tt.wantStatus, tt.wantFilled, tt.wantTrades, tt.wantReplayed)
+			}
+			if delta != nil {
+				t.Errorf("replayed placement changed the book: %+v", delta)
+			}
+			if stats := ob.Stats(); !tt.halt && stats.BidOrders != 1 {
+				t.Errorf("%d bids resting, want 1", stats.BidOrders)
+			}
+		})
+	}
+}
+
+func TestAddOrderForgetsOldPlacements(t *testing.T) {
+	ob := NewOrderBook("contract")
+	first, _ := ob.AddOrder(limitOrder("bid", pb.OrderSide_BUY, 10, 0.6))
+	first.placedAt = time.Now().Add(-placedRetention - time.Minute)
+	ob.AddOrder(limitOrder("other", pb.OrderSide_BUY, 1, 0.1))
+
+	if _, ok := ob.placed["bid"]; ok {
+		t.Errorf("placement kept past its retention")
+	}
+	if _, ok := ob.placed["other"]; !ok {
+		t.Errorf("recent placement forgotten")
+	}
+}
+
+func TestAddOrderRejectsOnHaltedBook(t *testing.T) {
+	ob := NewOrderBook("contract")
+	ob.Halt()
+
+	placement, _ := ob.AddOrder(limitOrder("bid", pb.OrderSide_BUY, 10, 0.6))
+	if placement.Status != "REJECTED" || placement.Replayed {
+		t.Errorf("AddOrder() = %s, replayed %v; want REJECTED", placement.Status, placement.Replayed)
+	}
+}
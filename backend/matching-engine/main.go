@@ -1,26 +1,53 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 
 	"lfg/matching-engine/engine"
 	pb "lfg/matching-engine/proto"
+	"lfg/matching-engine/repository"
+	"lfg/shared/auth"
+	"lfg/shared/config"
+	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 )
 
 func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceMatchingEngine, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Get port from environment or use default
 	port := os.Getenv("GRPC_PORT")
 	if port == "" {
 		port = "50051"
 	}
+	metricsPort := os.Getenv("METRICS_PORT")
+	if metricsPort == "" {
+		metricsPort = "9100"
+	}
 
 	// Connect to NATS
 	natsURL := os.Getenv("NATS_URL")
@@ -37,12 +64,68 @@ func main() {
 		defer natsConn.Close()
 	}
 
+	// Connect to the database the markets are kept in
+	ctx := context.Background()
+	pool, err := db.NewPool(ctx, db.Config{
+		Host:            cfg.DBHost,
+		Port:            cfg.DBPort,
+		User:            cfg.DBUser,
+		Password:        cfg.DBPassword,
+		Database:        cfg.DBName,
+		SSLMode:         cfg.DBSSLMode,
+		MaxConns:        cfg.DBMaxConns,
+		MinConns:        cfg.DBMinConns,
+		MaxConnLifetime: 1 * time.Hour,
+		MaxConnIdleTime: 30 * time.Minute,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close(pool)
+	metrics.RegisterPool(pool)
+
 	// Initialize matching engine
 	matchingEngine := engine.NewMatchingEngine(natsConn)
 	log.Println("Matching engine initialized")
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Books are kept in memory only, so the contracts of markets that
+	// stopped trading are halted again before any order is taken
+	stopped, err := repository.NewMarketRepository(pool).StoppedContracts(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load stopped markets: %v", err)
+	}
+	matchingEngine.Halt(stopped)
+	log.Printf("Halted %d contracts of stopped markets", len(stopped))
+	metrics.MustRegister(matchingEngine.Collectors()...)
+
+	// Only allow services to make the calls they need
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceMatchingEngine, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceOrder,
+		pb.MatchingEngine_PlaceOrder_FullMethodName,
+		pb.MatchingEngine_CancelOrder_FullMethodName,
+		pb.MatchingEngine_GetOrderBook_FullMethodName,
+	)
+	serviceGuard.Allow(auth.ServiceMarket,
+		pb.MatchingEngine_GetOrderBook_FullMethodName,
+		pb.MatchingEngine_HaltContract_FullMethodName,
+	)
+	serviceGuard.Allow(auth.ServiceNotification, pb.MatchingEngine_GetOrderBook_FullMethodName)
+
+	// Create gRPC server, served over TLS as calls carry service tokens
+	serverCreds, err := auth.ServerCredentials(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load gRPC credentials: %v", err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCreds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor(), serviceGuard.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor(), serviceGuard.StreamServerInterceptor()),
+	)
 	pb.RegisterMatchingEngineServer(grpcServer, matchingEngine)
 
 	// Start listening
@@ -59,6 +142,22 @@ func main() {
 		}
 	}()
 
+	// Serve metrics over HTTP, as the engine has no HTTP API
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
+	metricsServer := &http.Server{
+		Addr:              ":" + metricsPort,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Matching engine metrics listening on port %s...\n", metricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server failed: %v", err)
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -66,5 +165,6 @@ func main() {
 
 	log.Println("Shutting down matching engine...")
 	grpcServer.GracefulStop()
+	metricsServer.Close()
 	fmt.Println("Matching engine exited")
 }
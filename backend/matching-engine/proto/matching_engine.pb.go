@@ -534,6 +534,7 @@ type GetOrderBookResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Bids          []*OrderBookLevel      `protobuf:"bytes,1,rep,name=bids,proto3" json:"bids,omitempty"`
 	Asks          []*OrderBookLevel      `protobuf:"bytes,2,rep,name=asks,proto3" json:"asks,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"` // Book sequence the snapshot was taken at
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -582,6 +583,13 @@ func (x *GetOrderBookResponse) GetAsks() []*OrderBookLevel {
 	return nil
 }
 
+func (x *GetOrderBookResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
 // OrderBookLevel represents aggregated orders at a price level
 type OrderBookLevel struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -643,6 +651,104 @@ func (x *OrderBookLevel) GetOrderCount() int32 {
 	return 0
 }
 
+// HaltContractRequest identifies the contract to halt
+type HaltContractRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HaltContractRequest) Reset() {
+	*x = HaltContractRequest{}
+	mi := &file_proto_matching_engine_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HaltContractRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HaltContractRequest) ProtoMessage() {}
+
+func (x *HaltContractRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_matching_engine_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HaltContractRequest.ProtoReflect.Descriptor instead.
+func (*HaltContractRequest) Descriptor() ([]byte, []int) {
+	return file_proto_matching_engine_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HaltContractRequest) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+// HaltContractResponse lists the resting orders cancelled by the halt
+type HaltContractResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Success           bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	CancelledOrderIds []string               `protobuf:"bytes,2,rep,name=cancelled_order_ids,json=cancelledOrderIds,proto3" json:"cancelled_order_ids,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *HaltContractResponse) Reset() {
+	*x = HaltContractResponse{}
+	mi := &file_proto_matching_engine_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HaltContractResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HaltContractResponse) ProtoMessage() {}
+
+func (x *HaltContractResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_matching_engine_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HaltContractResponse.ProtoReflect.Descriptor instead.
+func (*HaltContractResponse) Descriptor() ([]byte, []int) {
+	return file_proto_matching_engine_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HaltContractResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *HaltContractResponse) GetCancelledOrderIds() []string {
+	if x != nil {
+		return x.CancelledOrderIds
+	}
+	return nil
+}
+
 var File_proto_matching_engine_proto protoreflect.FileDescriptor
 
 const file_proto_matching_engine_proto_rawDesc = "" +
@@ -682,27 +788,35 @@ const file_proto_matching_engine_proto_rawDesc = "" +
 	"\x13GetOrderBookRequest\x12\x1f\n" +
 	"\vcontract_id\x18\x01 \x01(\tR\n" +
 	"contractId\x12\x14\n" +
-	"\x05depth\x18\x02 \x01(\x05R\x05depth\"r\n" +
+	"\x05depth\x18\x02 \x01(\x05R\x05depth\"\x8e\x01\n" +
 	"\x14GetOrderBookResponse\x12,\n" +
 	"\x04bids\x18\x01 \x03(\v2\x18.matching.OrderBookLevelR\x04bids\x12,\n" +
-	"\x04asks\x18\x02 \x03(\v2\x18.matching.OrderBookLevelR\x04asks\"c\n" +
+	"\x04asks\x18\x02 \x03(\v2\x18.matching.OrderBookLevelR\x04asks\x12\x1a\n" +
+	"\bsequence\x18\x03 \x01(\x04R\bsequence\"c\n" +
 	"\x0eOrderBookLevel\x12\x14\n" +
 	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
 	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12\x1f\n" +
 	"\vorder_count\x18\x03 \x01(\x05R\n" +
-	"orderCount*\"\n" +
+	"orderCount\"6\n" +
+	"\x13HaltContractRequest\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\"`\n" +
+	"\x14HaltContractResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12.\n" +
+	"\x13cancelled_order_ids\x18\x02 \x03(\tR\x11cancelledOrderIds*\"\n" +
 	"\tOrderType\x12\n" +
 	"\n" +
 	"\x06MARKET\x10\x00\x12\t\n" +
 	"\x05LIMIT\x10\x01*\x1e\n" +
 	"\tOrderSide\x12\a\n" +
 	"\x03BUY\x10\x00\x12\b\n" +
-	"\x04SELL\x10\x012\xf4\x01\n" +
+	"\x04SELL\x10\x012\xc3\x02\n" +
 	"\x0eMatchingEngine\x12G\n" +
 	"\n" +
 	"PlaceOrder\x12\x1b.matching.PlaceOrderRequest\x1a\x1c.matching.PlaceOrderResponse\x12J\n" +
 	"\vCancelOrder\x12\x1c.matching.CancelOrderRequest\x1a\x1d.matching.CancelOrderResponse\x12M\n" +
-	"\fGetOrderBook\x12\x1d.matching.GetOrderBookRequest\x1a\x1e.matching.GetOrderBookResponseB\x1bZ\x19lfg/matching-engine/protob\x06proto3"
+	"\fGetOrderBook\x12\x1d.matching.GetOrderBookRequest\x1a\x1e.matching.GetOrderBookResponse\x12M\n" +
+	"\fHaltContract\x12\x1d.matching.HaltContractRequest\x1a\x1e.matching.HaltContractResponseB\x1bZ\x19lfg/matching-engine/protob\x06proto3"
 
 var (
 	file_proto_matching_engine_proto_rawDescOnce sync.Once
@@ -717,7 +831,7 @@ func file_proto_matching_engine_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_matching_engine_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_proto_matching_engine_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_matching_engine_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_proto_matching_engine_proto_goTypes = []any{
 	(OrderType)(0),               // 0: matching.OrderType
 	(OrderSide)(0),               // 1: matching.OrderSide
@@ -729,24 +843,28 @@ var file_proto_matching_engine_proto_goTypes = []any{
 	(*GetOrderBookRequest)(nil),  // 7: matching.GetOrderBookRequest
 	(*GetOrderBookResponse)(nil), // 8: matching.GetOrderBookResponse
 	(*OrderBookLevel)(nil),       // 9: matching.OrderBookLevel
+	(*HaltContractRequest)(nil),  // 10: matching.HaltContractRequest
+	(*HaltContractResponse)(nil), // 11: matching.HaltContractResponse
 }
 var file_proto_matching_engine_proto_depIdxs = []int32{
-	0, // 0: matching.PlaceOrderRequest.type:type_name -> matching.OrderType
-	1, // 1: matching.PlaceOrderRequest.side:type_name -> matching.OrderSide
-	4, // 2: matching.PlaceOrderResponse.trades:type_name -> matching.Trade
-	9, // 3: matching.GetOrderBookResponse.bids:type_name -> matching.OrderBookLevel
-	9, // 4: matching.GetOrderBookResponse.asks:type_name -> matching.OrderBookLevel
-	2, // 5: matching.MatchingEngine.PlaceOrder:input_type -> matching.PlaceOrderRequest
-	5, // 6: matching.MatchingEngine.CancelOrder:input_type -> matching.CancelOrderRequest
-	7, // 7: matching.MatchingEngine.GetOrderBook:input_type -> matching.GetOrderBookRequest
-	3, // 8: matching.MatchingEngine.PlaceOrder:output_type -> matching.PlaceOrderResponse
-	6, // 9: matching.MatchingEngine.CancelOrder:output_type -> matching.CancelOrderResponse
-	8, // 10: matching.MatchingEngine.GetOrderBook:output_type -> matching.GetOrderBookResponse
-	8, // [8:11] is the sub-list for method output_type
-	5, // [5:8] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	0,  // 0: matching.PlaceOrderRequest.type:type_name -> matching.OrderType
+	1,  // 1: matching.PlaceOrderRequest.side:type_name -> matching.OrderSide
+	4,  // 2: matching.PlaceOrderResponse.trades:type_name -> matching.Trade
+	9,  // 3: matching.GetOrderBookResponse.bids:type_name -> matching.OrderBookLevel
+	9,  // 4: matching.GetOrderBookResponse.asks:type_name -> matching.OrderBookLevel
+	2,  // 5: matching.MatchingEngine.PlaceOrder:input_type -> matching.PlaceOrderRequest
+	5,  // 6: matching.MatchingEngine.CancelOrder:input_type -> matching.CancelOrderRequest
+	7,  // 7: matching.MatchingEngine.GetOrderBook:input_type -> matching.GetOrderBookRequest
+	10, // 8: matching.MatchingEngine.HaltContract:input_type -> matching.HaltContractRequest
+	3,  // 9: matching.MatchingEngine.PlaceOrder:output_type -> matching.PlaceOrderResponse
+	6,  // 10: matching.MatchingEngine.CancelOrder:output_type -> matching.CancelOrderResponse
+	8,  // 11: matching.MatchingEngine.GetOrderBook:output_type -> matching.GetOrderBookResponse
+	11, // 12: matching.MatchingEngine.HaltContract:output_type -> matching.HaltContractResponse
+	9,  // [9:13] is the sub-list for method output_type
+	5,  // [5:9] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_matching_engine_proto_init() }
@@ -760,7 +878,7 @@ func file_proto_matching_engine_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_matching_engine_proto_rawDesc), len(file_proto_matching_engine_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,7 @@ const (
 	MatchingEngine_PlaceOrder_FullMethodName   = "/matching.MatchingEngine/PlaceOrder"
 	MatchingEngine_CancelOrder_FullMethodName  = "/matching.MatchingEngine/CancelOrder"
 	MatchingEngine_GetOrderBook_FullMethodName = "/matching.MatchingEngine/GetOrderBook"
+	MatchingEngine_HaltContract_FullMethodName = "/matching.MatchingEngine/HaltContract"
 )
 
 // MatchingEngineClient is the client API for MatchingEngine service.
@@ -30,12 +31,16 @@ const (
 //
 // MatchingEngine service handles order matching
 type MatchingEngineClient interface {
-	// PlaceOrder submits a new order to the matching engine
+	// PlaceOrder submits a new order to the matching engine. An order submitted
+	// again with the same ID gets its original response and is not added twice.
+	// Invalid orders fail with INVALID_ARGUMENT.
 	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error)
 	// CancelOrder cancels an active order
 	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
 	// GetOrderBook retrieves the current order book for a contract
 	GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*GetOrderBookResponse, error)
+	// HaltContract stops accepting orders for a contract and cancels resting ones
+	HaltContract(ctx context.Context, in *HaltContractRequest, opts ...grpc.CallOption) (*HaltContractResponse, error)
 }
 
 type matchingEngineClient struct {
@@ -76,18 +81,32 @@ func (c *matchingEngineClient) GetOrderBook(ctx context.Context, in *GetOrderBoo
 	return out, nil
 }
 
+func (c *matchingEngineClient) HaltContract(ctx context.Context, in *HaltContractRequest, opts ...grpc.CallOption) (*HaltContractResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HaltContractResponse)
+	err := c.cc.Invoke(ctx, MatchingEngine_HaltContract_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MatchingEngineServer is the server API for MatchingEngine service.
 // All implementations must embed UnimplementedMatchingEngineServer
 // for forward compatibility.
 //
 // MatchingEngine service handles order matching
 type MatchingEngineServer interface {
-	// PlaceOrder submits a new order to the matching engine
+	// PlaceOrder submits a new order to the matching engine. An order submitted
+	// again with the same ID gets its original response and is not added twice.
+	// Invalid orders fail with INVALID_ARGUMENT.
 	PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error)
 	// CancelOrder cancels an active order
 	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
 	// GetOrderBook retrieves the current order book for a contract
 	GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error)
+	// HaltContract stops accepting orders for a contract and cancels resting ones
+	HaltContract(context.Context, *HaltContractRequest) (*HaltContractResponse, error)
 	mustEmbedUnimplementedMatchingEngineServer()
 }
 
@@ -107,6 +126,9 @@ func (UnimplementedMatchingEngineServer) CancelOrder(context.Context, *CancelOrd
 func (UnimplementedMatchingEngineServer) GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
 }
+func (UnimplementedMatchingEngineServer) HaltContract(context.Context, *HaltContractRequest) (*HaltContractResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HaltContract not implemented")
+}
 func (UnimplementedMatchingEngineServer) mustEmbedUnimplementedMatchingEngineServer() {}
 func (UnimplementedMatchingEngineServer) testEmbeddedByValue()                        {}
 
@@ -182,6 +204,24 @@ func _MatchingEngine_GetOrderBook_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MatchingEngine_HaltContract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HaltContractRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingEngineServer).HaltContract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingEngine_HaltContract_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingEngineServer).HaltContract(ctx, req.(*HaltContractRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MatchingEngine_ServiceDesc is the grpc.ServiceDesc for MatchingEngine service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -201,6 +241,10 @@ var MatchingEngine_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOrderBook",
 			Handler:    _MatchingEngine_GetOrderBook_Handler,
 		},
+		{
+			MethodName: "HaltContract",
+			Handler:    _MatchingEngine_HaltContract_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/matching_engine.proto",
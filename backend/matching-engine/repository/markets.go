@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// MarketRepository reads the markets the matching engine trades
+type MarketRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewMarketRepository creates a new market repository
+func NewMarketRepository(pool *pgxpool.Pool) *MarketRepository {
+	return &MarketRepository{pool: pool}
+}
+
+// StoppedContracts returns the IDs of the contracts of every market that has
+// stopped trading, which the engine must not take orders for
+func (r *MarketRepository) StoppedContracts(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.id
+		FROM contracts c
+		JOIN markets m ON m.id = c.market_id
+		WHERE m.status IN ($1, $2, $3)
+	`, models.MarketStatusClosed, models.MarketStatusResolved, models.MarketStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stopped contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contractIDs := []string{}
+	for rows.Next() {
+		var contractID string
+		if err := rows.Scan(&contractID); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contractIDs = append(contractIDs, contractID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contract rows: %w", err)
+	}
+
+	return contractIDs, nil
+}
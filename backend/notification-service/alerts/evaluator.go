@@ -0,0 +1,232 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	pb "lfg/matching-engine/proto"
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+	"lfg/notification-service/streams"
+)
+
+// AlertStore finds the alert rules to evaluate and records the alerts they
+// raise
+type AlertStore interface {
+	ActiveContractRules(ctx context.Context, contractID uuid.UUID) ([]*models.AlertRule, error)
+	ActiveMarketRules(ctx context.Context, marketID uuid.UUID, ruleType models.AlertRuleType) ([]*models.AlertRule, error)
+	DueClosingRules(ctx context.Context, now time.Time) ([]*models.AlertRule, error)
+	GetMarket(ctx context.Context, marketID uuid.UUID) (*models.Market, error)
+	Trigger(ctx context.Context, rule *models.AlertRule, message string, payload interface{}) (*models.Alert, error)
+}
+
+// Evaluator checks alert rules against trades and market lifecycle events,
+// and periodically for markets about to close, and records the alerts raised.
+// Alerts reach users through their notification inbox.
+type Evaluator struct {
+	repo               AlertStore
+	matchingEngineAddr string
+	serviceTokens      *auth.ServiceTokens
+	interval           time.Duration
+}
+
+// NewEvaluator creates a new alert evaluator
+func NewEvaluator(repo AlertStore, matchingEngineAddr string, serviceTokens *auth.ServiceTokens, interval time.Duration) *Evaluator {
+	return &Evaluator{
+		repo:               repo,
+		matchingEngineAddr: matchingEngineAddr,
+		serviceTokens:      serviceTokens,
+		interval:           interval,
+	}
+}
+
+// Run checks CLOSING_SOON rules every interval until ctx is cancelled
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	e.checkClosing(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.checkClosing(ctx)
+		}
+	}
+}
+
+// HandleTrade evaluates the price and spread rules on the traded contract
+func (e *Evaluator) HandleTrade(ctx context.Context, trade *streams.TradeEvent) {
+	rules, err := e.repo.ActiveContractRules(ctx, trade.ContractID)
+	if err != nil {
+		log.Printf("Failed to get alert rules for contract %s: %v", trade.ContractID, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	// The book is only fetched when a spread rule needs it
+	spread := -1.0
+	for _, rule := range rules {
+		if rule.Type == models.AlertRuleSpreadBelow {
+			spread = e.spread(ctx, trade.ContractID)
+			break
+		}
+	}
+
+	for _, rule := range rules {
+		threshold := *rule.Threshold
+
+		switch rule.Type {
+		case models.AlertRulePriceAbove:
+			if trade.Price >= threshold {
+				e.trigger(ctx, rule, "%s traded at %.4f, at or above %.4f", trade.Price, threshold, map[string]interface{}{
+					"contract_id": trade.ContractID,
+					"trade_id":    trade.TradeID,
+					"price":       trade.Price,
+					"threshold":   threshold,
+				})
+			}
+		case models.AlertRulePriceBelow:
+			if trade.Price <= threshold {
+				e.trigger(ctx, rule, "%s traded at %.4f, at or below %.4f", trade.Price, threshold, map[string]interface{}{
+					"contract_id": trade.ContractID,
+					"trade_id":    trade.TradeID,
+					"price":       trade.Price,
+					"threshold":   threshold,
+				})
+			}
+		case models.AlertRuleSpreadBelow:
+			if spread >= 0 && spread <= threshold {
+				e.trigger(ctx, rule, "%s spread narrowed to %.4f, at or below %.4f", spread, threshold, map[string]interface{}{
+					"contract_id": trade.ContractID,
+					"spread":      spread,
+					"threshold":   threshold,
+				})
+			}
+		}
+	}
+}
+
+// HandleLifecycle evaluates the MARKET_RESOLVED rules of a market when it is
+// resolved or cancelled
+func (e *Evaluator) HandleLifecycle(ctx context.Context, event *models.MarketLifecycleEvent) {
+	var outcome string
+	switch event.Type {
+	case models.MarketLifecycleResolved:
+		outcome = "resolved"
+	case models.MarketLifecycleCancelled:
+		outcome = "cancelled"
+	default:
+		return
+	}
+
+	rules, err := e.repo.ActiveMarketRules(ctx, event.MarketID, models.AlertRuleMarketResolved)
+	if err != nil {
+		log.Printf("Failed to get alert rules for market %s: %v", event.Ticker, err)
+		return
+	}
+
+	for _, rule := range rules {
+		message := fmt.Sprintf("%s was %s", event.Ticker, outcome)
+		e.deliver(ctx, rule, message, map[string]interface{}{
+			"status":      event.Status,
+			"occurred_at": event.OccurredAt,
+		})
+	}
+}
+
+func (e *Evaluator) checkClosing(ctx context.Context) {
+	rules, err := e.repo.DueClosingRules(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to get closing alert rules: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		market, err := e.repo.GetMarket(ctx, rule.MarketID)
+		if err != nil {
+			log.Printf("Failed to get market %s: %v", rule.MarketID, err)
+			continue
+		}
+
+		remaining := time.Until(market.ExpiresAt).Round(time.Minute)
+		message := fmt.Sprintf("%s closes in %s", market.Ticker, remaining)
+		e.deliver(ctx, rule, message, map[string]interface{}{
+			"expires_at": market.ExpiresAt,
+		})
+	}
+}
+
+// trigger formats a trade alert message prefixed with the market ticker and
+// delivers it
+func (e *Evaluator) trigger(ctx context.Context, rule *models.AlertRule, format string, value, threshold float64, payload map[string]interface{}) {
+	market, err := e.repo.GetMarket(ctx, rule.MarketID)
+	if err != nil {
+		log.Printf("Failed to get market %s: %v", rule.MarketID, err)
+		return
+	}
+
+	e.deliver(ctx, rule, fmt.Sprintf(format, market.Ticker, value, threshold), payload)
+}
+
+// deliver records the alert of a rule
+func (e *Evaluator) deliver(ctx context.Context, rule *models.AlertRule, message string, payload map[string]interface{}) {
+	alert, err := e.repo.Trigger(ctx, rule, message, payload)
+	if err != nil {
+		if err != repository.ErrRuleNotActive {
+			log.Printf("Failed to trigger alert rule %s: %v", rule.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Raised %s alert for user: %s", alert.Type, alert.UserID)
+}
+
+// spread returns the best ask minus the best bid of a contract, or -1 when
+// either side of the book is empty or the engine cannot be reached
+func (e *Evaluator) spread(ctx context.Context, contractID uuid.UUID) float64 {
+	conn, err := grpc.NewClient(e.matchingEngineAddr,
+		grpc.WithTransportCredentials(e.serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(e.serviceTokens.Credentials(auth.ServiceMatchingEngine)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		log.Printf("Failed to connect to matching engine: %v", err)
+		return -1
+	}
+	defer conn.Close()
+
+	bookCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	book, err := pb.NewMatchingEngineClient(conn).GetOrderBook(bookCtx, &pb.GetOrderBookRequest{
+		ContractId: contractID.String(),
+		Depth:      1,
+	})
+	if err != nil {
+		log.Printf("Failed to get order book for contract %s: %v", contractID, err)
+		return -1
+	}
+
+	return bookSpread(book)
+}
+
+// bookSpread returns the best ask minus the best bid of a book, or -1 when
+// either side of it is empty
+func bookSpread(book *pb.GetOrderBookResponse) float64 {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return -1
+	}
+
+	return book.Asks[0].Price - book.Bids[0].Price
+}
@@ -0,0 +1,195 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "lfg/matching-engine/proto"
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+	"lfg/notification-service/streams"
+)
+
+// fakeStore serves the rules of a single market and records the alerts
+// raised, refusing to raise those of inactive rules
+type fakeStore struct {
+	market   *models.Market
+	rules    []*models.AlertRule
+	inactive bool
+	messages []string
+}
+
+func (s *fakeStore) ActiveContractRules(ctx context.Context, contractID uuid.UUID) ([]*models.AlertRule, error) {
+	var rules []*models.AlertRule
+	for _, rule := range s.rules {
+		if rule.ContractID != nil && *rule.ContractID == contractID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (s *fakeStore) ActiveMarketRules(ctx context.Context, marketID uuid.UUID, ruleType models.AlertRuleType) ([]*models.AlertRule, error) {
+	var rules []*models.AlertRule
+	for _, rule := range s.rules {
+		if rule.MarketID == marketID && rule.Type == ruleType {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (s *fakeStore) DueClosingRules(ctx context.Context, now time.Time) ([]*models.AlertRule, error) {
+	return s.ActiveMarketRules(ctx, s.market.ID, models.AlertRuleClosingSoon)
+}
+
+func (s *fakeStore) GetMarket(ctx context.Context, marketID uuid.UUID) (*models.Market, error) {
+	return s.market, nil
+}
+
+func (s *fakeStore) Trigger(ctx context.Context, rule *models.AlertRule, message string, payload interface{}) (*models.Alert, error) {
+	if s.inactive {
+		return nil, repository.ErrRuleNotActive
+	}
+	s.messages = append(s.messages, message)
+	return &models.Alert{ID: uuid.New(), RuleID: &rule.ID, UserID: rule.UserID, Type: rule.Type, Message: message}, nil
+}
+
+// newRule creates an active rule of market, on contractID when it is set
+func newRule(market *models.Market, contractID *uuid.UUID, ruleType models.AlertRuleType, threshold float64) *models.AlertRule {
+	return &models.AlertRule{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		MarketID:   market.ID,
+		ContractID: contractID,
+		Type:       ruleType,
+		Threshold:  &threshold,
+		Active:     true,
+	}
+}
+
+func TestHandleTrade(t *testing.T) {
+	tests := []struct {
+		name      string
+		ruleType  models.AlertRuleType
+		threshold float64
+		price     float64
+		inactive  bool
+		want      string
+	}{
+		{name: "price above the threshold", ruleType: models.AlertRulePriceAbove, threshold: 0.6, price: 0.65, want: "ELECTION traded at 0.6500, at or above 0.6000"},
+		{name: "price at the upper threshold", ruleType: models.AlertRulePriceAbove, threshold: 0.6, price: 0.6, want: "ELECTION traded at 0.6000, at or above 0.6000"},
+		{name: "price below the upper threshold", ruleType: models.AlertRulePriceAbove, threshold: 0.6, price: 0.55},
+		{name: "price below the threshold", ruleType: models.AlertRulePriceBelow, threshold: 0.4, price: 0.35, want: "ELECTION traded at 0.3500, at or below 0.4000"},
+		{name: "price above the lower threshold", ruleType: models.AlertRulePriceBelow, threshold: 0.4, price: 0.45},
+		{name: "rule already triggered", ruleType: models.AlertRulePriceAbove, threshold: 0.6, price: 0.65, inactive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market := &models.Market{ID: uuid.New(), Ticker: "ELECTION"}
+			contractID, otherID := uuid.New(), uuid.New()
+			store := &fakeStore{
+				market: market,
+				rules: []*models.AlertRule{
+					newRule(market, &contractID, tt.ruleType, tt.threshold),
+					newRule(market, &otherID, tt.ruleType, tt.threshold),
+				},
+				inactive: tt.inactive,
+			}
+			e := NewEvaluator(store, "", nil, time.Minute)
+
+			e.HandleTrade(context.Background(), &streams.TradeEvent{TradeID: "t1", ContractID: contractID, Quantity: 1, Price: tt.price})
+
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+			if len(store.messages) != len(want) || (len(want) > 0 && store.messages[0] != want[0]) {
+				t.Errorf("raised %q, want %q", store.messages, want)
+			}
+		})
+	}
+}
+
+func TestHandleLifecycle(t *testing.T) {
+	tests := []struct {
+		eventType models.MarketLifecycleEventType
+		want      string
+	}{
+		{models.MarketLifecycleResolved, "ELECTION was resolved"},
+		{models.MarketLifecycleCancelled, "ELECTION was cancelled"},
+		{models.MarketLifecycleOpened, ""},
+		{models.MarketLifecycleClosed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.eventType), func(t *testing.T) {
+			market := &models.Market{ID: uuid.New(), Ticker: "ELECTION"}
+			store := &fakeStore{
+				market: market,
+				rules: []*models.AlertRule{
+					newRule(market, nil, models.AlertRuleMarketResolved, 0),
+					newRule(market, nil, models.AlertRuleClosingSoon, 60),
+				},
+			}
+			e := NewEvaluator(store, "", nil, time.Minute)
+
+			e.HandleLifecycle(context.Background(), &models.MarketLifecycleEvent{Type: tt.eventType, MarketID: market.ID, Ticker: market.Ticker})
+
+			got := ""
+			if len(store.messages) > 0 {
+				got = store.messages[0]
+			}
+			if len(store.messages) > 1 || got != tt.want {
+				t.Errorf("raised %q, want %q", store.messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClosing(t *testing.T) {
+	market := &models.Market{ID: uuid.New(), Ticker: "ELECTION", ExpiresAt: time.Now().Add(30*time.Minute + 10*time.Second)}
+	store := &fakeStore{
+		market: market,
+		rules: []*models.AlertRule{
+			newRule(market, nil, models.AlertRuleClosingSoon, 60),
+			newRule(market, nil, models.AlertRuleMarketResolved, 0),
+		},
+	}
+	e := NewEvaluator(store, "", nil, time.Minute)
+
+	e.checkClosing(context.Background())
+
+	if len(store.messages) != 1 || store.messages[0] != "ELECTION closes in 30m0s" {
+		t.Errorf("raised %q, want [ELECTION closes in 30m0s]", store.messages)
+	}
+}
+
+func TestBookSpread(t *testing.T) {
+	level := func(price float64) []*pb.OrderBookLevel {
+		return []*pb.OrderBookLevel{{Price: price, Quantity: 10}, {Price: 0.5, Quantity: 10}}
+	}
+
+	tests := []struct {
+		name string
+		book *pb.GetOrderBookResponse
+		want float64
+	}{
+		{"both sides", &pb.GetOrderBookResponse{Bids: level(0.45), Asks: level(0.48)}, 0.03},
+		{"no bids", &pb.GetOrderBookResponse{Asks: level(0.48)}, -1},
+		{"no asks", &pb.GetOrderBookResponse{Bids: level(0.45)}, -1},
+		{"empty book", &pb.GetOrderBookResponse{}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bookSpread(tt.book); got < tt.want-1e-9 || got > tt.want+1e-9 {
+				t.Errorf("bookSpread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
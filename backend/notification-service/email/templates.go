@@ -0,0 +1,300 @@
+package email
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"lfg/shared/models"
+)
+
+// layout wraps the HTML body of every email
+const layout = `{{define "layout"}}<!DOCTYPE html>
+<html>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #1a1a1a; max-width: 600px; margin: 0 auto; padding: 24px;">
+<h2 style="margin-top: 0;">{{.Subject}}</h2>
+{{template "content" .}}
+<p style="color: #888; font-size: 12px; margin-top: 32px;">You receive this email because of your LFG notification settings. You can change which emails you receive, and whether they are batched into a digest, from your account settings.</p>
+</body>
+</html>{{end}}`
+
+// textFooter ends the text body of every email
+const textFooter = `
+
+--
+You receive this email because of your LFG notification settings. You can
+change which emails you receive, and whether they are batched into a digest,
+from your account settings.
+`
+
+// notificationTemplate holds the templates of one type of notification
+type notificationTemplate struct {
+	subject string
+	text    string
+	html    string
+}
+
+var notificationTemplates = map[models.NotificationType]notificationTemplate{
+	models.NotificationOrderFilled: {
+		subject: "Order filled: {{.Message}}",
+		text: `{{.Message}}
+
+Quantity: {{.Payload.quantity}}
+Price: {{.Payload.price}}
+Trade: {{.Payload.trade_id}}`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>Quantity</td><td>{{.Payload.quantity}}</td></tr>
+<tr><td>Price</td><td>{{.Payload.price}}</td></tr>
+<tr><td>Trade</td><td>{{.Payload.trade_id}}</td></tr>
+</table>`,
+	},
+	models.NotificationMarketSettled: {
+		subject: "Market settled: {{.Payload.credits}} credits paid out",
+		text: `{{.Message}}
+
+Shares settled: {{.Payload.quantity}}
+Payout per share: {{.Payload.payout_per_share}}
+Credits paid: {{.Payload.credits}}`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>Shares settled</td><td>{{.Payload.quantity}}</td></tr>
+<tr><td>Payout per share</td><td>{{.Payload.payout_per_share}}</td></tr>
+<tr><td>Credits paid</td><td>{{.Payload.credits}}</td></tr>
+</table>`,
+	},
+	models.NotificationMarketRefund: {
+		subject: "Market cancelled: {{.Payload.credits}} credits refunded",
+		text: `{{.Message}}
+
+Credits refunded: {{.Payload.credits}}`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>Credits refunded</td><td>{{.Payload.credits}}</td></tr>
+</table>`,
+	},
+	models.NotificationDeposit: {
+		subject: "Deposit completed",
+		text: `{{.Message}}
+
+Amount: {{.Payload.crypto_amount}} {{.Payload.crypto_type}}
+Credits: {{.Payload.credits}}
+Transaction: {{.Payload.transaction_id}}`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>Amount</td><td>{{.Payload.crypto_amount}} {{.Payload.crypto_type}}</td></tr>
+<tr><td>Credits</td><td>{{.Payload.credits}}</td></tr>
+<tr><td>Transaction</td><td>{{.Payload.transaction_id}}</td></tr>
+</table>`,
+	},
+	models.NotificationWithdrawal: {
+		subject: "Withdrawal completed",
+		text: `{{.Message}}
+
+Amount: {{.Payload.crypto_amount}} {{.Payload.crypto_type}}
+Credits: {{.Payload.credits}}
+Transaction: {{.Payload.transaction_id}}
+
+If you did not request this withdrawal, contact support immediately.`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>Amount</td><td>{{.Payload.crypto_amount}} {{.Payload.crypto_type}}</td></tr>
+<tr><td>Credits</td><td>{{.Payload.credits}}</td></tr>
+<tr><td>Transaction</td><td>{{.Payload.transaction_id}}</td></tr>
+</table>
+<p><strong>If you did not request this withdrawal, contact support immediately.</strong></p>`,
+	},
+	models.NotificationNewLogin: {
+		subject: "New sign-in to your account",
+		text: `{{.Message}}
+
+IP address: {{.Payload.ip_address}}
+Device: {{.Payload.user_agent}}
+Time: {{.CreatedAt.UTC.Format "2006-01-02 15:04 MST"}}
+
+If this was not you, change your password right away.`,
+		html: `<p>{{.Message}}</p>
+<table>
+<tr><td>IP address</td><td>{{.Payload.ip_address}}</td></tr>
+<tr><td>Device</td><td>{{.Payload.user_agent}}</td></tr>
+<tr><td>Time</td><td>{{.CreatedAt.UTC.Format "2006-01-02 15:04 MST"}}</td></tr>
+</table>
+<p><strong>If this was not you, change your password right away.</strong></p>`,
+	},
+	models.NotificationAlert: {
+		subject: "Alert: {{.Message}}",
+		text:    `{{.Message}}`,
+		html:    `<p>{{.Message}}</p>`,
+	},
+}
+
+const digestSubject = `Your LFG digest: {{len .Notifications}} update{{if ne (len .Notifications) 1}}s{{end}}`
+
+const digestText = `Here is what happened since your last digest:
+{{range .Notifications}}
+- {{.CreatedAt.UTC.Format "Jan 2 15:04"}}  {{.Message}}{{end}}`
+
+const digestHTML = `<p>Here is what happened since your last digest:</p>
+<table>
+{{range .Notifications}}<tr><td style="color: #888; padding-right: 12px;">{{.CreatedAt.UTC.Format "Jan 2 15:04"}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>`
+
+// compiledTemplate holds the parsed templates of one email
+type compiledTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Renderer renders notifications into emails
+type Renderer struct {
+	notifications map[models.NotificationType]*compiledTemplate
+	digest        *compiledTemplate
+}
+
+// notificationData is what a notification template sees; Payload is the
+// decoded notification payload
+type notificationData struct {
+	*models.Notification
+	Subject string
+	Payload map[string]interface{}
+}
+
+// digestData is what the digest template sees
+type digestData struct {
+	Subject       string
+	Notifications []*models.Notification
+}
+
+// NewRenderer parses the email templates
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{notifications: make(map[models.NotificationType]*compiledTemplate)}
+
+	for notificationType, tmpl := range notificationTemplates {
+		compiled, err := compile(string(notificationType), tmpl.subject, tmpl.text, tmpl.html)
+		if err != nil {
+			return nil, err
+		}
+		r.notifications[notificationType] = compiled
+	}
+
+	digest, err := compile("digest", digestSubject, digestText, digestHTML)
+	if err != nil {
+		return nil, err
+	}
+	r.digest = digest
+
+	return r, nil
+}
+
+func compile(name, subject, text, html string) (*compiledTemplate, error) {
+	subjectTmpl, err := texttemplate.New(name).Option("missingkey=zero").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s subject template: %w", name, err)
+	}
+
+	textTmpl, err := texttemplate.New(name).Option("missingkey=zero").Parse(text + textFooter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+	}
+
+	htmlTmpl, err := htmltemplate.New(name).Option("missingkey=zero").Parse(layout)
+	if err == nil {
+		_, err = htmlTmpl.New("content").Parse(html)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s HTML template: %w", name, err)
+	}
+
+	return &compiledTemplate{subject: subjectTmpl, text: textTmpl, html: htmlTmpl}, nil
+}
+
+// render executes the templates of one email and fills in msg's subject and
+// bodies. setSubject receives the rendered subject so the HTML layout can
+// show it as its heading.
+func (t *compiledTemplate) render(msg *models.EmailMessage, data interface{}, setSubject func(string)) error {
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render subject: %w", err)
+	}
+	msg.Subject = truncate(buf.String(), 255)
+	setSubject(msg.Subject)
+
+	buf.Reset()
+	if err := t.text.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render text body: %w", err)
+	}
+	msg.TextBody = buf.String()
+
+	buf.Reset()
+	if err := t.html.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return fmt.Errorf("failed to render HTML body: %w", err)
+	}
+	msg.HTMLBody = buf.String()
+
+	return nil
+}
+
+// Supports reports whether notifications of a type can be emailed
+func (r *Renderer) Supports(notificationType models.NotificationType) bool {
+	_, ok := r.notifications[notificationType]
+	return ok
+}
+
+// Notification renders the email of a single notification to address
+func (r *Renderer) Notification(notification *models.Notification, address string) (*models.EmailMessage, error) {
+	tmpl, ok := r.notifications[notification.Type]
+	if !ok {
+		return nil, fmt.Errorf("no email template for notification type %s", notification.Type)
+	}
+
+	data := &notificationData{Notification: notification, Payload: map[string]interface{}{}}
+	if len(notification.Payload) > 0 {
+		if err := json.Unmarshal(notification.Payload, &data.Payload); err != nil {
+			return nil, fmt.Errorf("failed to decode notification payload: %w", err)
+		}
+	}
+
+	notificationID := notification.ID
+	msg := &models.EmailMessage{
+		UserID:         notification.UserID,
+		NotificationID: &notificationID,
+		To:             address,
+	}
+	if err := tmpl.render(msg, data, func(subject string) { data.Subject = subject }); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// Digest renders one email listing several notifications to address
+func (r *Renderer) Digest(notifications []*models.Notification, address string) (*models.EmailMessage, error) {
+	if len(notifications) == 0 {
+		return nil, fmt.Errorf("digest has no notifications")
+	}
+
+	data := &digestData{Notifications: notifications}
+	msg := &models.EmailMessage{
+		UserID: notifications[0].UserID,
+		To:     address,
+	}
+	if err := r.digest.render(msg, data, func(subject string) { data.Subject = subject }); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,224 @@
+package email
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+const (
+	// routeBatchSize is the number of new notifications routed per poll
+	routeBatchSize = 100
+	// digestLimit is the most notifications listed in one digest; the rest
+	// follow in the next one
+	digestLimit = 50
+	// sendBatchSize is the number of emails sent per poll
+	sendBatchSize = 50
+	// sendLease is how long a claimed email is held before another worker
+	// may retry it
+	sendLease = 2 * time.Minute
+	// retryBase and retryMax bound the exponential backoff between attempts
+	retryBase = 30 * time.Second
+	retryMax  = 6 * time.Hour
+)
+
+// OutboxStore claims notifications to email and the emails due in the
+// outbox, and records what became of them
+type OutboxStore interface {
+	ClaimNew(ctx context.Context, limit int) ([]*repository.PendingEmail, error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.EmailPreferences, error)
+	SetEmailState(ctx context.Context, notificationID uuid.UUID, state string) error
+	Enqueue(ctx context.Context, msg *models.EmailMessage) error
+	DueDigests(ctx context.Context) ([]*repository.DigestRecipient, error)
+	ClaimDigest(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error)
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.EmailMessage, error)
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	MarkAttemptFailed(ctx context.Context, id uuid.UUID, sendErr error, retryAt *time.Time) error
+}
+
+// Worker emails users their notifications. Each poll it routes new
+// notifications by the user's preferences, either rendering them into the
+// outbox right away, holding them for a digest or skipping them, then
+// renders the digests that are due and sends what is in the outbox, retrying
+// transient failures with backoff. Every step claims its rows, so any number
+// of instances can run a worker.
+type Worker struct {
+	repo        OutboxStore
+	renderer    *Renderer
+	sender      Sender
+	interval    time.Duration
+	maxAttempts int
+}
+
+// NewWorker creates a new email worker
+func NewWorker(repo OutboxStore, renderer *Renderer, sender Sender, interval time.Duration, maxAttempts int) *Worker {
+	return &Worker{
+		repo:        repo,
+		renderer:    renderer,
+		sender:      sender,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Run polls every interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	w.route(ctx)
+	w.digest(ctx)
+	w.send(ctx)
+}
+
+// route decides how each new notification is emailed
+func (w *Worker) route(ctx context.Context) {
+	pending, err := w.repo.ClaimNew(ctx, routeBatchSize)
+	if err != nil {
+		log.Printf("Failed to claim notifications for email: %v", err)
+		return
+	}
+
+	preferences := make(map[uuid.UUID]*models.EmailPreferences)
+	for _, p := range pending {
+		notification := p.Notification
+
+		prefs, ok := preferences[notification.UserID]
+		if !ok {
+			prefs, err = w.repo.GetPreferences(ctx, notification.UserID)
+			if err != nil {
+				// Left PROCESSING, the notification is routed again once
+				// its claim times out
+				log.Printf("Failed to get email preferences of user %s: %v", notification.UserID, err)
+				continue
+			}
+			preferences[notification.UserID] = prefs
+		}
+
+		mode := prefs.ModeFor(notification.Type)
+		if !w.renderer.Supports(notification.Type) {
+			mode = models.EmailModeOff
+		}
+
+		switch mode {
+		case models.EmailModeImmediate:
+			msg, err := w.renderer.Notification(notification, p.Address)
+			if err != nil {
+				log.Printf("Failed to render email for notification %s: %v", notification.ID, err)
+				w.setState(ctx, notification.ID, repository.EmailStateSkipped)
+				continue
+			}
+			if err := w.repo.Enqueue(ctx, msg); err != nil {
+				log.Printf("Failed to queue email for notification %s: %v", notification.ID, err)
+			}
+		case models.EmailModeDigest:
+			w.setState(ctx, notification.ID, repository.EmailStateDigest)
+		default:
+			w.setState(ctx, notification.ID, repository.EmailStateSkipped)
+		}
+	}
+}
+
+// digest renders the digests that are due into the outbox
+func (w *Worker) digest(ctx context.Context) {
+	recipients, err := w.repo.DueDigests(ctx)
+	if err != nil {
+		log.Printf("Failed to get due email digests: %v", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		notifications, err := w.repo.ClaimDigest(ctx, recipient.UserID, digestLimit)
+		if err != nil {
+			log.Printf("Failed to claim digest of user %s: %v", recipient.UserID, err)
+			continue
+		}
+		if len(notifications) == 0 {
+			// Another worker took this digest
+			continue
+		}
+
+		msg, err := w.renderer.Digest(notifications, recipient.Address)
+		if err == nil {
+			err = w.repo.Enqueue(ctx, msg)
+		}
+		if err != nil {
+			log.Printf("Failed to queue digest of user %s: %v", recipient.UserID, err)
+			// Return the notifications so the next poll retries the digest
+			for _, notification := range notifications {
+				w.setState(ctx, notification.ID, repository.EmailStateDigest)
+			}
+			continue
+		}
+
+		log.Printf("Queued digest of %d notifications for user: %s", len(notifications), recipient.UserID)
+	}
+}
+
+// send delivers the emails due in the outbox
+func (w *Worker) send(ctx context.Context) {
+	messages, err := w.repo.ClaimOutbox(ctx, sendBatchSize, sendLease)
+	if err != nil {
+		log.Printf("Failed to claim emails: %v", err)
+		return
+	}
+
+	for _, msg := range messages {
+		sendErr := w.sender.Send(ctx, msg)
+		if sendErr == nil {
+			if err := w.repo.MarkSent(ctx, msg.ID); err != nil {
+				log.Printf("Failed to mark email %s sent: %v", msg.ID, err)
+			}
+			continue
+		}
+
+		var retryAt *time.Time
+		if !IsPermanent(sendErr) && msg.Attempts < w.maxAttempts {
+			next := time.Now().Add(backoff(msg.Attempts))
+			retryAt = &next
+			log.Printf("Failed to send email %s (attempt %d), retrying at %s: %v", msg.ID, msg.Attempts, next.Format(time.RFC3339), sendErr)
+		} else {
+			log.Printf("Giving up on email %s after %d attempts: %v", msg.ID, msg.Attempts, sendErr)
+		}
+
+		if err := w.repo.MarkAttemptFailed(ctx, msg.ID, sendErr, retryAt); err != nil {
+			log.Printf("Failed to record failure of email %s: %v", msg.ID, err)
+		}
+	}
+}
+
+func (w *Worker) setState(ctx context.Context, notificationID uuid.UUID, state string) {
+	if err := w.repo.SetEmailState(ctx, notificationID, state); err != nil {
+		log.Printf("Failed to update email state of notification %s: %v", notificationID, err)
+	}
+}
+
+// backoff returns the delay before the attempt following the given one,
+// doubling from retryBase up to retryMax
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
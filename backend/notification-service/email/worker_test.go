@@ -0,0 +1,300 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// fakeOutbox serves notifications and emails from memory and records what
+// the worker did with them
+type fakeOutbox struct {
+	OutboxStore
+
+	pending     []*repository.PendingEmail
+	preferences map[uuid.UUID]*models.EmailPreferences
+	digests     map[uuid.UUID][]*models.Notification
+	outbox      []*models.EmailMessage
+	enqueueErr  error
+
+	states   map[uuid.UUID]string
+	enqueued []*models.EmailMessage
+	sent     []uuid.UUID
+	failed   map[uuid.UUID]*time.Time
+}
+
+func newFakeOutbox() *fakeOutbox {
+	return &fakeOutbox{
+		preferences: make(map[uuid.UUID]*models.EmailPreferences),
+		digests:     make(map[uuid.UUID][]*models.Notification),
+		states:      make(map[uuid.UUID]string),
+		failed:      make(map[uuid.UUID]*time.Time),
+	}
+}
+
+func (s *fakeOutbox) ClaimNew(ctx context.Context, limit int) ([]*repository.PendingEmail, error) {
+	pending := s.pending
+	s.pending = nil
+	return pending, nil
+}
+
+func (s *fakeOutbox) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.EmailPreferences, error) {
+	if prefs, ok := s.preferences[userID]; ok {
+		return prefs, nil
+	}
+	return models.DefaultEmailPreferences(userID), nil
+}
+
+func (s *fakeOutbox) SetEmailState(ctx context.Context, notificationID uuid.UUID, state string) error {
+	s.states[notificationID] = state
+	return nil
+}
+
+func (s *fakeOutbox) Enqueue(ctx context.Context, msg *models.EmailMessage) error {
+	if s.enqueueErr != nil {
+		return s.enqueueErr
+	}
+	if msg.NotificationID != nil {
+		s.states[*msg.NotificationID] = repository.EmailStateQueued
+	}
+	s.enqueued = append(s.enqueued, msg)
+	return nil
+}
+
+func (s *fakeOutbox) DueDigests(ctx context.Context) ([]*repository.DigestRecipient, error) {
+	var recipients []*repository.DigestRecipient
+	for userID := range s.digests {
+		recipients = append(recipients, &repository.DigestRecipient{UserID: userID, Address: "user@example.com"})
+	}
+	return recipients, nil
+}
+
+func (s *fakeOutbox) ClaimDigest(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error) {
+	notifications := s.digests[userID]
+	delete(s.digests, userID)
+	for _, notification := range notifications {
+		s.states[notification.ID] = repository.EmailStateDigested
+	}
+	return notifications, nil
+}
+
+func (s *fakeOutbox) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.EmailMessage, error) {
+	outbox := s.outbox
+	s.outbox = nil
+	return outbox, nil
+}
+
+func (s *fakeOutbox) MarkSent(ctx context.Context, id uuid.UUID) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+
+func (s *fakeOutbox) MarkAttemptFailed(ctx context.Context, id uuid.UUID, sendErr error, retryAt *time.Time) error {
+	s.failed[id] = retryAt
+	return nil
+}
+
+// fakeSender fails to send with err
+type fakeSender struct {
+	err error
+}
+
+func (s fakeSender) Send(ctx context.Context, msg *models.EmailMessage) error {
+	return s.err
+}
+
+// newWorker creates a worker on store, sending with sender and trying each
+// email at most 5 times
+func newWorker(t *testing.T, store *fakeOutbox, sender Sender) *Worker {
+	t.Helper()
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewWorker(store, renderer, sender, time.Minute, 5)
+}
+
+// notification creates a notification of a type for userID
+func notification(userID uuid.UUID, notificationType models.NotificationType) *models.Notification {
+	return &models.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      notificationType,
+		Message:   "Bought 10 ELECTION-YES at 0.45",
+		Payload:   []byte(`{"quantity":10}`),
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestRoute(t *testing.T) {
+	off, immediate := models.EmailModeOff, models.EmailModeImmediate
+
+	tests := []struct {
+		name             string
+		notificationType models.NotificationType
+		update           models.EmailPreferencesUpdateRequest
+		want             string
+	}{
+		{"fill by default", models.NotificationOrderFilled, models.EmailPreferencesUpdateRequest{}, repository.EmailStateDigest},
+		{"fill emailed right away", models.NotificationOrderFilled, models.EmailPreferencesUpdateRequest{Fills: &immediate}, repository.EmailStateQueued},
+		{"settlement by default", models.NotificationMarketSettled, models.EmailPreferencesUpdateRequest{}, repository.EmailStateQueued},
+		{"settlements turned off", models.NotificationMarketSettled, models.EmailPreferencesUpdateRequest{Settlements: &off}, repository.EmailStateSkipped},
+		{"alert by default", models.NotificationAlert, models.EmailPreferencesUpdateRequest{}, repository.EmailStateSkipped},
+		{"unknown type", models.NotificationType("GOSSIP"), models.EmailPreferencesUpdateRequest{}, repository.EmailStateSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			store := newFakeOutbox()
+			prefs := models.DefaultEmailPreferences(userID)
+			prefs.Apply(&tt.update)
+			store.preferences[userID] = prefs
+
+			n := notification(userID, tt.notificationType)
+			store.pending = []*repository.PendingEmail{{Notification: n, Address: "user@example.com"}}
+			newWorker(t, store, fakeSender{}).route(context.Background())
+
+			if got := store.states[n.ID]; got != tt.want {
+				t.Errorf("notification left %s, want %s", got, tt.want)
+			}
+			if tt.want != repository.EmailStateQueued {
+				return
+			}
+			if len(store.enqueued) != 1 {
+				t.Fatalf("queued %d emails, want 1", len(store.enqueued))
+			}
+			if msg := store.enqueued[0]; msg.To != "user@example.com" || msg.UserID != userID || msg.Subject == "" || msg.TextBody == "" || msg.HTMLBody == "" {
+				t.Errorf("queued %+v, want a rendered email to the user", msg)
+			}
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	t.Run("due digest", func(t *testing.T) {
+		userID := uuid.New()
+		store := newFakeOutbox()
+		store.digests[userID] = []*models.Notification{
+			notification(userID, models.NotificationOrderFilled),
+			notification(userID, models.NotificationOrderFilled),
+		}
+		newWorker(t, store, fakeSender{}).digest(context.Background())
+
+		if len(store.enqueued) != 1 {
+			t.Fatalf("queued %d emails, want one digest", len(store.enqueued))
+		}
+		if msg := store.enqueued[0]; msg.Subject != "Your LFG digest: 2 updates" || msg.NotificationID != nil {
+			t.Errorf("queued %q for notification %v, want a digest of 2 updates", msg.Subject, msg.NotificationID)
+		}
+	})
+
+	t.Run("digest that cannot be queued", func(t *testing.T) {
+		userID := uuid.New()
+		notifications := []*models.Notification{notification(userID, models.NotificationOrderFilled)}
+		store := newFakeOutbox()
+		store.digests[userID] = notifications
+		store.enqueueErr = errors.New("database unavailable")
+		newWorker(t, store, fakeSender{}).digest(context.Background())
+
+		// The notifications wait for the next poll's digest
+		if got := store.states[notifications[0].ID]; got != repository.EmailStateDigest {
+			t.Errorf("notification left %s, want %s", got, repository.EmailStateDigest)
+		}
+	})
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		attempts    int
+		wantSent    bool
+		wantRetryIn time.Duration
+	}{
+		{name: "sent", attempts: 1, wantSent: true},
+		{name: "first transient failure", err: errors.New("connection refused"), attempts: 1, wantRetryIn: 30 * time.Second},
+		{name: "later transient failure", err: &textproto.Error{Code: 421, Msg: "try again later"}, attempts: 3, wantRetryIn: 2 * time.Minute},
+		{name: "permanent failure", err: &textproto.Error{Code: 550, Msg: "no such user"}, attempts: 1},
+		{name: "last attempt", err: errors.New("connection refused"), attempts: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.EmailMessage{ID: uuid.New(), To: "user@example.com", Attempts: tt.attempts}
+			store := newFakeOutbox()
+			store.outbox = []*models.EmailMessage{msg}
+			start := time.Now()
+			newWorker(t, store, fakeSender{err: tt.err}).send(context.Background())
+
+			if sent := len(store.sent) == 1; sent != tt.wantSent {
+				t.Fatalf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if tt.wantSent {
+				return
+			}
+
+			retryAt, failed := store.failed[msg.ID]
+			if !failed {
+				t.Fatal("failure not recorded")
+			}
+			switch {
+			case tt.wantRetryIn == 0 && retryAt != nil:
+				t.Errorf("retried at %s, want the email given up", retryAt)
+			case tt.wantRetryIn != 0 && retryAt == nil:
+				t.Errorf("given up, want a retry in %s", tt.wantRetryIn)
+			case tt.wantRetryIn != 0:
+				if retryIn := retryAt.Sub(start); retryIn < tt.wantRetryIn || retryIn > tt.wantRetryIn+time.Second {
+					t.Errorf("retried in %s, want %s", retryIn, tt.wantRetryIn)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 512 * 30 * time.Second},
+		{11, 6 * time.Hour},
+		{40, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"mailbox unavailable", &textproto.Error{Code: 550, Msg: "no such user"}, true},
+		{"wrapped rejection", errors.Join(errors.New("sending"), &textproto.Error{Code: 554, Msg: "rejected"}), true},
+		{"server busy", &textproto.Error{Code: 421, Msg: "try again later"}, false},
+		{"network error", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Errorf("IsPermanent(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
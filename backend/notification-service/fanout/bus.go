@@ -0,0 +1,229 @@
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"lfg/matching-engine/engine"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
+	"lfg/notification-service/handlers"
+	"lfg/notification-service/streams"
+)
+
+var tracer = tracing.Tracer("lfg/notification-service/fanout")
+
+// UserEventsSubject returns the NATS subject carrying a user's events
+func UserEventsSubject(userID string) string {
+	return "user." + userID + ".events"
+}
+
+// UserPresenceSubject returns the NATS subject the instances holding a
+// user's connections answer presence queries on
+func UserPresenceSubject(userID string) string {
+	return "user." + userID + ".presence"
+}
+
+// Presence is an instance's answer to a presence query
+type Presence struct {
+	InstanceID  string `json:"instance_id"`
+	Connections int    `json:"connections"`
+}
+
+// Handler handles the events the bus delivers to this instance
+type Handler interface {
+	HandleUserEvent(ctx context.Context, userID string, event *streams.UserEvent)
+	HandleTrade(ctx context.Context, trade *streams.TradeEvent)
+	HandleBookDelta(ctx context.Context, delta *engine.BookDelta)
+}
+
+// Bus implements streams.Bus over NATS. Each instance subscribes to the
+// subjects of the users connected to it and of the contracts its clients
+// follow, so events only reach the instances that need them. Without a
+// NATS connection the bus delivers user events to this instance only.
+type Bus struct {
+	ctx        context.Context
+	nc         *nats.Conn
+	hub        *handlers.Hub
+	handler    Handler
+	instanceID string
+
+	mu        sync.Mutex
+	users     map[string][]*nats.Subscription
+	contracts map[uuid.UUID][]*nats.Subscription
+}
+
+// NewBus creates a new bus delivering events to handler until ctx is
+// cancelled. nc may be nil.
+func NewBus(ctx context.Context, nc *nats.Conn, hub *handlers.Hub, handler Handler) *Bus {
+	return &Bus{
+		ctx:        ctx,
+		nc:         nc,
+		hub:        hub,
+		handler:    handler,
+		instanceID: uuid.New().String(),
+		users:      make(map[string][]*nats.Subscription),
+		contracts:  make(map[uuid.UUID][]*nats.Subscription),
+	}
+}
+
+// PublishToUser sends an event to the instances holding the user's
+// connections. Nobody receives it when the user is offline.
+func (b *Bus) PublishToUser(userID string, event *streams.UserEvent) {
+	if b.nc == nil {
+		b.handler.HandleUserEvent(b.ctx, userID, event)
+		return
+	}
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal user event: %v", err)
+		return
+	}
+
+	if err := b.nc.Publish(UserEventsSubject(userID), eventJSON); err != nil {
+		metrics.PublishFailed(UserEventsSubject(userID))
+		log.Printf("Failed to publish %s event for user %s: %v", event.Channel, userID, err)
+	}
+}
+
+// FollowUser subscribes to a user's events and presence queries
+func (b *Bus) FollowUser(userID string) {
+	if b.nc == nil {
+		return
+	}
+
+	events, err := b.nc.Subscribe(UserEventsSubject(userID), func(msg *nats.Msg) {
+		var event streams.UserEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			log.Printf("Failed to unmarshal user event: %v", err)
+			return
+		}
+
+		b.handler.HandleUserEvent(b.ctx, userID, &event)
+	})
+	if err != nil {
+		log.Printf("Failed to subscribe to events of user %s: %v", userID, err)
+		return
+	}
+
+	presence, err := b.nc.Subscribe(UserPresenceSubject(userID), func(msg *nats.Msg) {
+		reply, _ := json.Marshal(Presence{
+			InstanceID:  b.instanceID,
+			Connections: b.hub.Connections(userID),
+		})
+		msg.Respond(reply)
+	})
+	if err != nil {
+		log.Printf("Failed to subscribe to presence of user %s: %v", userID, err)
+		events.Unsubscribe()
+		return
+	}
+
+	b.mu.Lock()
+	b.users[userID] = []*nats.Subscription{events, presence}
+	b.mu.Unlock()
+}
+
+// UnfollowUser unsubscribes from a user's events and presence queries
+func (b *Bus) UnfollowUser(userID string) {
+	b.mu.Lock()
+	subs := b.users[userID]
+	delete(b.users, userID)
+	b.mu.Unlock()
+
+	unsubscribe(subs)
+}
+
+// FollowContract subscribes to a contract's trades and order book deltas
+func (b *Bus) FollowContract(contractID uuid.UUID) {
+	if b.nc == nil {
+		return
+	}
+
+	trades, err := b.nc.Subscribe(engine.TradeSubjectPrefix+contractID.String(), func(msg *nats.Msg) {
+		ctx, span := tracing.StartMessage(b.ctx, tracer, msg.Subject, msg.Header)
+		defer span.End()
+
+		var trade streams.TradeEvent
+		if err := json.Unmarshal(msg.Data, &trade); err != nil {
+			log.Printf("Failed to unmarshal trade event: %v", err)
+			return
+		}
+
+		b.handler.HandleTrade(ctx, &trade)
+	})
+	if err != nil {
+		log.Printf("Failed to subscribe to trades of contract %s: %v", contractID, err)
+		return
+	}
+
+	deltas, err := b.nc.Subscribe(engine.BookDeltaSubjectPrefix+contractID.String(), func(msg *nats.Msg) {
+		ctx, span := tracing.StartMessage(b.ctx, tracer, msg.Subject, msg.Header)
+		defer span.End()
+
+		var delta engine.BookDelta
+		if err := json.Unmarshal(msg.Data, &delta); err != nil {
+			log.Printf("Failed to unmarshal order book delta: %v", err)
+			return
+		}
+
+		b.handler.HandleBookDelta(ctx, &delta)
+	})
+	if err != nil {
+		log.Printf("Failed to subscribe to order book of contract %s: %v", contractID, err)
+		trades.Unsubscribe()
+		return
+	}
+
+	b.mu.Lock()
+	b.contracts[contractID] = []*nats.Subscription{trades, deltas}
+	b.mu.Unlock()
+}
+
+// UnfollowContract unsubscribes from a contract's trades and order book deltas
+func (b *Bus) UnfollowContract(contractID uuid.UUID) {
+	b.mu.Lock()
+	subs := b.contracts[contractID]
+	delete(b.contracts, contractID)
+	b.mu.Unlock()
+
+	unsubscribe(subs)
+}
+
+// Online reports whether a user has a connection on any instance
+func (b *Bus) Online(ctx context.Context, userID string) (bool, error) {
+	if b.nc == nil {
+		return b.hub.Connections(userID) > 0, nil
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Instances only answer for users connected to them, so a query nobody
+	// subscribed to fails fast with no responders
+	_, err := b.nc.RequestWithContext(queryCtx, UserPresenceSubject(userID), nil)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func unsubscribe(subs []*nats.Subscription) {
+	for _, sub := range subs {
+		if err := sub.Unsubscribe(); err != nil {
+			log.Printf("Failed to unsubscribe from %s: %v", sub.Subject, err)
+		}
+	}
+}
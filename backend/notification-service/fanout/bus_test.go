@@ -0,0 +1,365 @@
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+
+	"lfg/matching-engine/engine"
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/notification-service/handlers"
+	"lfg/notification-service/streams"
+)
+
+// activeUsers reports every user as active
+type activeUsers struct{}
+
+func (activeUsers) Statuses(_ context.Context, userIDs []uuid.UUID) (map[uuid.UUID]models.UserStatus, error) {
+	statuses := make(map[uuid.UUID]models.UserStatus, len(userIDs))
+	for _, userID := range userIDs {
+		statuses[userID] = models.UserStatusActive
+	}
+	return statuses, nil
+}
+
+// instance is one notification-service instance: a hub serving websockets
+// and its bus. It routes events as streams.Router does for contract scoped
+// channels, which need no database, and follows users and contracts as soon
+// as the hub gains them so a client's ack means its events are routed.
+type instance struct {
+	hub    *handlers.Hub
+	bus    *Bus
+	nc     *nats.Conn
+	server *httptest.Server
+}
+
+func newInstance(t *testing.T, ctx context.Context, natsURL string, jwtManager *auth.JWTManager) *instance {
+	t.Helper()
+
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	inst := &instance{hub: handlers.NewHub(10, 100, time.Minute), nc: nc}
+	inst.bus = NewBus(ctx, nc, inst.hub, inst)
+	wsAuth := handlers.NewWSAuth(jwtManager, activeUsers{}, nil, 5*time.Second, time.Hour)
+	wsAuth.SetAllowNoOrigin(true)
+	inst.hub.SetAuth(wsAuth)
+	inst.hub.SetInterest(inst)
+	go inst.hub.Run()
+
+	// Each trade's fills are sent by one instance of the queue group
+	_, err = nc.QueueSubscribe(engine.TradeSubjectPrefix+"*", "notification-service", func(msg *nats.Msg) {
+		var trade streams.TradeEvent
+		if err := json.Unmarshal(msg.Data, &trade); err != nil {
+			t.Errorf("Failed to unmarshal trade: %v", err)
+			return
+		}
+		for userID, orderID := range map[string]string{trade.MakerUserID: trade.MakerOrderID, trade.TakerUserID: trade.TakerOrderID} {
+			fill, _ := json.Marshal(streams.Fill{TradeID: trade.TradeID, OrderID: orderID, ContractID: trade.ContractID})
+			inst.bus.PublishToUser(userID, &streams.UserEvent{Channel: handlers.ChannelFills, Data: fill})
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	inst.server = httptest.NewServer(handlers.HandleWebSocket(inst.hub))
+	t.Cleanup(inst.server.Close)
+	return inst
+}
+
+func (i *instance) HandleUserEvent(_ context.Context, userID string, event *streams.UserEvent) {
+	i.hub.PublishToUser(userID, event.Channel, event.Data)
+}
+
+func (i *instance) HandleTrade(_ context.Context, trade *streams.TradeEvent) {
+	key := handlers.ChannelKey(handlers.ChannelTrades, handlers.ScopeContract, trade.ContractID)
+	if i.hub.Streaming(key) {
+		i.hub.Publish(key, handlers.ChannelTrades, trade)
+	}
+}
+
+func (i *instance) HandleBookDelta(context.Context, *engine.BookDelta) {}
+
+func (i *instance) StreamOpened(key string) {
+	if _, scope, contractID := handlers.ParseChannelKey(key); scope == handlers.ScopeContract {
+		i.bus.FollowContract(contractID)
+		i.nc.Flush()
+	}
+}
+
+func (i *instance) StreamClosed(key string) {
+	if _, scope, contractID := handlers.ParseChannelKey(key); scope == handlers.ScopeContract {
+		i.bus.UnfollowContract(contractID)
+	}
+}
+
+func (i *instance) UserConnected(userID string) {
+	i.bus.FollowUser(userID)
+	i.nc.Flush()
+}
+
+func (i *instance) UserDisconnected(userID string) {
+	i.bus.UnfollowUser(userID)
+}
+
+// client is a websocket connection counting the channel messages it gets
+type client struct {
+	userID string
+	conn   *websocket.Conn
+	acks   chan string
+
+	mu       sync.Mutex
+	messages map[string]int
+}
+
+func dial(t *testing.T, inst *instance, userID uuid.UUID, jwtManager *auth.JWTManager) *client {
+	t.Helper()
+
+	token, _, err := jwtManager.GenerateToken(userID, userID.String()+"@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "ws" + strings.TrimPrefix(inst.server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &client{userID: userID.String(), conn: conn, acks: make(chan string, 16), messages: make(map[string]int)}
+	go c.read()
+	return c
+}
+
+// read counts the messages of each channel. The hub batches queued messages
+// into one frame, one per line.
+func (c *client) read() {
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		for _, line := range bytes.Split(frame, []byte{'\n'}) {
+			var msg struct {
+				Type    string `json:"type"`
+				Channel string `json:"channel"`
+				Error   string `json:"error"`
+			}
+			if err := json.Unmarshal(line, &msg); err != nil {
+				continue
+			}
+
+			switch msg.Type {
+			case "connected":
+			case "subscribed", "error":
+				c.acks <- msg.Type + " " + msg.Channel + msg.Error
+			default:
+				c.mu.Lock()
+				c.messages[msg.Type]++
+				c.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (c *client) subscribe(t *testing.T, channel string, contractID uuid.UUID) {
+	t.Helper()
+
+	msg := handlers.ClientMessage{Op: handlers.OpSubscribe, Channel: channel}
+	if contractID != uuid.Nil {
+		msg.ContractID = contractID.String()
+	}
+	if err := c.conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ack := <-c.acks:
+		if !strings.HasPrefix(ack, "subscribed ") {
+			t.Fatalf("subscribing to %s: %s", channel, ack)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscribing to %s timed out", channel)
+	}
+}
+
+func (c *client) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int, len(c.messages))
+	for channel, n := range c.messages {
+		counts[channel] = n
+	}
+	return counts
+}
+
+// connection is a client of a user on one of the hubs
+type connection struct {
+	hub  int
+	user uuid.UUID
+}
+
+func TestBusFanOutReachesEveryHubOnce(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	server := natsserver.RunServer(&opts)
+	defer server.Shutdown()
+
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		hubs    int
+		clients []connection
+	}{
+		{
+			name:    "one user on two hubs",
+			hubs:    2,
+			clients: []connection{{0, alice}, {1, alice}, {1, bob}},
+		},
+		{
+			name:    "several connections per hub",
+			hubs:    3,
+			clients: []connection{{0, alice}, {0, alice}, {1, alice}, {1, bob}, {2, bob}, {2, bob}},
+		},
+		{
+			name:    "hub without the users",
+			hubs:    3,
+			clients: []connection{{0, alice}, {2, bob}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			jwtManager := auth.NewJWTManager("test-secret", time.Hour, time.Hour)
+			contractID := uuid.New()
+
+			instances := make([]*instance, tt.hubs)
+			for i := range instances {
+				instances[i] = newInstance(t, ctx, server.ClientURL(), jwtManager)
+			}
+
+			clients := make([]*client, len(tt.clients))
+			connections := make(map[*instance]map[string]int)
+			for i, c := range tt.clients {
+				inst := instances[c.hub]
+				clients[i] = dial(t, inst, c.user, jwtManager)
+				if connections[inst] == nil {
+					connections[inst] = make(map[string]int)
+				}
+				connections[inst][c.user.String()]++
+			}
+
+			// The hub follows a user once it has registered their connection
+			deadline := time.Now().Add(5 * time.Second)
+			for inst, users := range connections {
+				for userID, n := range users {
+					for inst.hub.Connections(userID) != n {
+						if time.Now().After(deadline) {
+							t.Fatalf("user %s did not connect", userID)
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+				}
+			}
+
+			for _, c := range clients {
+				for _, channel := range []string{handlers.ChannelOrders, handlers.ChannelBalance, handlers.ChannelFills} {
+					c.subscribe(t, channel, uuid.Nil)
+				}
+				c.subscribe(t, handlers.ChannelTrades, contractID)
+			}
+
+			// The engine publishes a trade between the users; a database
+			// change of each is forwarded by one instance, the listener
+			trade, _ := json.Marshal(streams.TradeEvent{
+				TradeID:      uuid.NewString(),
+				ContractID:   contractID,
+				MakerOrderID: uuid.NewString(),
+				TakerOrderID: uuid.NewString(),
+				MakerUserID:  alice.String(),
+				TakerUserID:  bob.String(),
+				Quantity:     10,
+				Price:        0.55,
+			})
+			publisher, err := nats.Connect(server.ClientURL())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer publisher.Close()
+			if err := publisher.Publish(engine.TradeSubjectPrefix+contractID.String(), trade); err != nil {
+				t.Fatal(err)
+			}
+
+			instances[0].bus.PublishToUser(alice.String(), &streams.UserEvent{Channel: handlers.ChannelOrders, Data: json.RawMessage(`{"status":"FILLED"}`)})
+			instances[len(instances)-1].bus.PublishToUser(bob.String(), &streams.UserEvent{Channel: handlers.ChannelBalance, Data: json.RawMessage(`{"balance":"100"}`)})
+
+			want := func(c *client) map[string]int {
+				counts := map[string]int{handlers.ChannelTrades: 1, handlers.ChannelFills: 1}
+				if c.userID == alice.String() {
+					counts[handlers.ChannelOrders] = 1
+				} else {
+					counts[handlers.ChannelBalance] = 1
+				}
+				return counts
+			}
+
+			delivered := func() bool {
+				for _, c := range clients {
+					got := c.counts()
+					for channel, n := range want(c) {
+						if got[channel] < n {
+							return false
+						}
+					}
+				}
+				return true
+			}
+			for deadline := time.Now().Add(5 * time.Second); !delivered() && time.Now().Before(deadline); {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// Anything delivered twice arrives soon after
+			time.Sleep(200 * time.Millisecond)
+
+			for i, c := range clients {
+				got, expected := c.counts(), want(c)
+				if len(got) != len(expected) {
+					t.Errorf("client %d on hub %d got %v, want %v", i, tt.clients[i].hub, got, expected)
+					continue
+				}
+				for channel, n := range expected {
+					if got[channel] != n {
+						t.Errorf("client %d on hub %d got %v, want %v", i, tt.clients[i].hub, got, expected)
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// AlertHandler handles HTTP requests for watchlists, alert rules and alert history
+type AlertHandler struct {
+	watchlistRepo *repository.WatchlistRepository
+	alertRepo     *repository.AlertRepository
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(watchlistRepo *repository.WatchlistRepository, alertRepo *repository.AlertRepository) *AlertHandler {
+	return &AlertHandler{
+		watchlistRepo: watchlistRepo,
+		alertRepo:     alertRepo,
+	}
+}
+
+// Watchlist handles retrieving the markets on the user's watchlist
+func (h *AlertHandler) Watchlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := h.watchlistRepo.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to get watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"markets": entries}, http.StatusOK)
+}
+
+// AddToWatchlist handles adding a market to the user's watchlist
+func (h *AlertHandler) AddToWatchlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchlistRepo.Add(r.Context(), userID, req.MarketID); err != nil {
+		respondAlertError(w, err)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"market_id": req.MarketID}, http.StatusCreated)
+}
+
+// RemoveFromWatchlist handles removing a market from the user's watchlist
+func (h *AlertHandler) RemoveFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchlistRepo.Remove(r.Context(), userID, req.MarketID); err != nil {
+		respondAlertError(w, err)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"market_id": req.MarketID}, http.StatusOK)
+}
+
+// Rules handles listing the user's alert rules
+func (h *AlertHandler) Rules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.alertRepo.ListRules(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to list alert rules", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"rules": rules}, http.StatusOK)
+}
+
+// CreateRule handles creating an alert rule
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.AlertRuleCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Type {
+	case models.AlertRulePriceAbove, models.AlertRulePriceBelow, models.AlertRuleSpreadBelow,
+		models.AlertRuleClosingSoon, models.AlertRuleMarketResolved:
+	default:
+		respondError(w, "Invalid alert rule type", http.StatusBadRequest)
+		return
+	}
+
+	rule := &models.AlertRule{
+		ID:         uuid.New(),
+		UserID:     userID,
+		MarketID:   req.MarketID,
+		ContractID: req.ContractID,
+		Type:       req.Type,
+		Threshold:  req.Threshold,
+	}
+
+	if err := h.alertRepo.CreateRule(r.Context(), rule); err != nil {
+		respondAlertError(w, err)
+		return
+	}
+
+	respondJSON(w, rule, http.StatusCreated)
+}
+
+// DeleteRule handles deleting one of the user's alert rules
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.AlertRuleDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertRepo.DeleteRule(r.Context(), userID, req.RuleID); err != nil {
+		respondAlertError(w, err)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"rule_id": req.RuleID}, http.StatusOK)
+}
+
+// History handles retrieving a page of the user's triggered alerts
+func (h *AlertHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page := parseInt(r.URL.Query().Get("page"), 1)
+	pageSize := parseInt(r.URL.Query().Get("page_size"), 20)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	alerts, totalCount, err := h.alertRepo.ListAlerts(r.Context(), userID, page, pageSize)
+	if err != nil {
+		respondError(w, "Failed to list alerts", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.AlertListResponse{
+		Alerts:     alerts,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
+func respondAlertError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrMarketNotFound:
+		respondError(w, "Market not found", http.StatusNotFound)
+	case repository.ErrNotOnWatchlist:
+		respondError(w, "Market is not on the watchlist", http.StatusNotFound)
+	case repository.ErrWatchlistFull:
+		respondError(w, "Watchlist is full", http.StatusConflict)
+	case repository.ErrRuleNotFound:
+		respondError(w, "Alert rule not found", http.StatusNotFound)
+	case repository.ErrTooManyRules:
+		respondError(w, "Too many active alert rules", http.StatusConflict)
+	case repository.ErrMarketSettled:
+		respondError(w, "Market is already settled", http.StatusConflict)
+	case repository.ErrContractRequired:
+		respondError(w, "Contract ID is required for price and spread alerts", http.StatusBadRequest)
+	case repository.ErrContractNotInMarket:
+		respondError(w, "Contract does not belong to this market", http.StatusBadRequest)
+	case repository.ErrInvalidThreshold:
+		respondError(w, "Threshold must be between 0 and 1 for price and spread alerts, and at least 1 second for closing alerts", http.StatusBadRequest)
+	default:
+		respondError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Helper functions
+func parseInt(s string, defaultValue int) int {
+	if s == "" {
+		return defaultValue
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+func respondJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, message string, statusCode int) {
+	respondJSON(w, map[string]string{"error": message}, statusCode)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// EmailHandler handles HTTP requests for the user's email preferences
+type EmailHandler struct {
+	repo *repository.EmailRepository
+}
+
+// NewEmailHandler creates a new email handler
+func NewEmailHandler(repo *repository.EmailRepository) *EmailHandler {
+	return &EmailHandler{repo: repo}
+}
+
+// Preferences handles retrieving which notifications the user is emailed and
+// how
+func (h *EmailHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := h.repo.GetPreferences(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to get email preferences", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, prefs, http.StatusOK)
+}
+
+// UpdatePreferences handles changing the user's email preferences; fields
+// left out of the request keep their current value
+func (h *EmailHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.EmailPreferencesUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !req.Valid() {
+		respondError(w, "Modes must be IMMEDIATE, DIGEST or OFF and digest_interval HOURLY or DAILY", http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.repo.GetPreferences(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to get email preferences", http.StatusInternalServerError)
+		return
+	}
+
+	prefs.Apply(&req)
+	if err := h.repo.SavePreferences(r.Context(), prefs); err != nil {
+		respondError(w, "Failed to save email preferences", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, prefs, http.StatusOK)
+}
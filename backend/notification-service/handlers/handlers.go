@@ -8,79 +8,175 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
-)
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// In production, validate origin properly
-		return true
-	},
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
+	"lfg/shared/auth"
+)
 
-// Client represents a WebSocket client
+// Client represents a WebSocket or Server-Sent Events client; Conn is nil
+// for the latter
 type Client struct {
 	ID     string
 	UserID string
 	Conn   *websocket.Conn
 	Send   chan []byte
+
+	// subscriptions holds the keys of the channels the client subscribed
+	// to; it is guarded by the hub's mutex
+	subscriptions map[string]bool
+
+	// expiresAt is when the client's access token expires; it is guarded by
+	// the hub's mutex
+	expiresAt time.Time
+
+	// closeCode and closeReason are sent in the close frame when the server
+	// disconnects the client; they are set before Send is closed
+	closeCode   int
+	closeReason string
+
+	// closed is set once Send is closed; it is guarded by the hub's mutex
+	closed bool
+}
+
+// Interest is told which channel streams and users a hub holds, so events
+// for them can be routed to this instance. It is called with the hub locked
+// and must not block or call back into the hub.
+type Interest interface {
+	StreamOpened(key string)
+	StreamClosed(key string)
+	UserConnected(userID string)
+	UserDisconnected(userID string)
 }
 
-// Hub maintains active client connections
+// Hub maintains active client connections, their channel subscriptions and
+// the sequenced streams of the channels
 type Hub struct {
 	clients    map[*Client]bool
+	users      map[string]int // User ID to open connections
+	channels   map[string]map[*Client]bool
+	streams    map[string]*stream
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	maxSubscriptions int
+	replaySize       int
+	replayWindow     time.Duration
+	snapshots        Snapshotter
+	inbox            Inbox
+	interest         Interest
+	auth             *WSAuth
 }
 
-// NewHub creates a new Hub
-func NewHub() *Hub {
+// NewHub creates a new Hub allowing each connection maxSubscriptions
+// channels. Each channel keeps its last replaySize messages, for as long as
+// replayWindow after its last subscriber leaves, for clients resuming it.
+func NewHub(maxSubscriptions, replaySize int, replayWindow time.Duration) *Hub {
 	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+		clients:          make(map[*Client]bool),
+		users:            make(map[string]int),
+		channels:         make(map[string]map[*Client]bool),
+		streams:          make(map[string]*stream),
+		broadcast:        make(chan []byte),
+		register:         make(chan *Client),
+		unregister:       make(chan *Client),
+		maxSubscriptions: maxSubscriptions,
+		replaySize:       replaySize,
+		replayWindow:     replayWindow,
 	}
 }
 
+// SetSnapshotter sets the source of the snapshots sent after subscribing
+func (h *Hub) SetSnapshotter(snapshots Snapshotter) {
+	h.snapshots = snapshots
+}
+
+// SetInbox sets the notification inbox delivered on connect and marked read
+// over the websocket
+func (h *Hub) SetInbox(inbox Inbox) {
+	h.inbox = inbox
+}
+
+// SetAuth sets the authenticator of the hub's connections. It must be set
+// before HandleWebSocket is called.
+func (h *Hub) SetAuth(auth *WSAuth) {
+	h.auth = auth
+}
+
+// SetInterest sets who is told of the streams and users the hub holds
+func (h *Hub) SetInterest(interest Interest) {
+	h.interest = interest
+}
+
 // Run starts the hub
 func (h *Hub) Run() {
+	prune := time.NewTicker(h.replayWindow)
+	defer prune.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-			log.Printf("Client %s connected (User: %s)", client.ID, client.UserID)
+			h.addClient(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
+				h.removeClient(client)
 				log.Printf("Client %s disconnected", client.ID)
 			}
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			for client := range h.clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
-					delete(h.clients, client)
+					h.removeClient(client)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+
+		case <-prune.C:
+			h.pruneStreams()
+		}
+	}
+}
+
+// addClient adds a client to the hub
+func (h *Hub) addClient(client *Client) {
+	h.mu.Lock()
+	h.clients[client] = true
+	h.users[client.UserID]++
+	if h.users[client.UserID] == 1 && h.interest != nil {
+		h.interest.UserConnected(client.UserID)
+	}
+	h.mu.Unlock()
+	log.Printf("Client %s connected (User: %s)", client.ID, client.UserID)
+}
+
+// removeClient drops a client and its subscriptions and closes its send
+// channel. Must be called with the hub locked.
+func (h *Hub) removeClient(client *Client) {
+	for key := range client.subscriptions {
+		h.removeSubscriber(key, client)
+	}
+	delete(h.clients, client)
+	close(client.Send)
+	client.closed = true
+
+	h.users[client.UserID]--
+	if h.users[client.UserID] <= 0 {
+		delete(h.users, client.UserID)
+		if h.interest != nil {
+			h.interest.UserDisconnected(client.UserID)
 		}
 	}
 }
 
-// BroadcastToUser sends a message to a specific user
+// BroadcastToUser sends a message to a specific user. A connection too far
+// behind to take it misses the message; anything that must reach the user is
+// kept in their notification inbox.
 func (h *Hub) BroadcastToUser(userID string, message []byte) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -90,20 +186,44 @@ func (h *Hub) BroadcastToUser(userID string, message []byte) {
 			select {
 			case client.Send <- message:
 			default:
-				// Channel full, skip
+				log.Printf("Dropped message for client %s (User: %s): send buffer full", client.ID, userID)
 			}
 		}
 	}
 }
 
-// HandleWebSocket handles WebSocket connections
+// Connections returns the number of open connections of a user
+func (h *Hub) Connections(userID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.users[userID]
+}
+
+// HandleWebSocket handles WebSocket connections. A token sent with the
+// handshake is checked before upgrading; otherwise the client has to send it
+// in an auth op as its first message.
 func HandleWebSocket(hub *Hub) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin:     hub.auth.CheckOrigin,
+		Subprotocols:    []string{BearerSubprotocol},
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract user ID from header (set by API gateway auth middleware)
-		userID := r.Header.Get("X-User-ID")
-		if userID == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		var claims *auth.Claims
+		if token := handshakeToken(r); token != "" {
+			var err error
+			claims, err = hub.auth.validate(r.Context(), token)
+			if err != nil {
+				status := http.StatusUnauthorized
+				if err == ErrAccountSuspended {
+					status = http.StatusForbidden
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
 		}
 
 		// Upgrade connection
@@ -113,25 +233,41 @@ func HandleWebSocket(hub *Hub) http.HandlerFunc {
 			return
 		}
 
+		if claims == nil {
+			claims, err = hub.auth.awaitToken(conn)
+			if err != nil {
+				code := CloseUnauthorized
+				if err == ErrAccountSuspended {
+					code = CloseAccountSuspended
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, err.Error()), time.Now().Add(time.Second))
+				conn.Close()
+				return
+			}
+		}
+		userID := claims.UserID.String()
+
 		// Create client
 		client := &Client{
-			ID:     generateClientID(),
-			UserID: userID,
-			Conn:   conn,
-			Send:   make(chan []byte, 256),
+			ID:            generateClientID(),
+			UserID:        userID,
+			Conn:          conn,
+			Send:          make(chan []byte, 256),
+			subscriptions: make(map[string]bool),
+			expiresAt:     claims.ExpiresAt.Time,
 		}
 
+		// Send welcome message. It is queued before registering, as the
+		// client may be disconnected, closing Send, as soon as it is.
+		client.Send <- welcomeMessage(client)
+
 		// Register client
 		hub.register <- client
 
-		// Send welcome message
-		welcomeMsg := map[string]string{
-			"type":    "connected",
-			"message": "Successfully connected to LFG Platform",
-			"user_id": userID,
+		// Deliver what the user missed while offline
+		if inboxJSON := hub.inboxMessage(userID); inboxJSON != nil {
+			hub.send(client, inboxJSON)
 		}
-		welcomeJSON, _ := json.Marshal(welcomeMsg)
-		client.Send <- welcomeJSON
 
 		// Start goroutines
 		go client.writePump()
@@ -139,6 +275,17 @@ func HandleWebSocket(hub *Hub) http.HandlerFunc {
 	}
 }
 
+// welcomeMessage builds the first message sent to a new client
+func welcomeMessage(client *Client) []byte {
+	message, _ := json.Marshal(map[string]interface{}{
+		"type":       "connected",
+		"message":    "Successfully connected to LFG Platform",
+		"user_id":    client.UserID,
+		"expires_at": client.expiresAt,
+	})
+	return message
+}
+
 // readPump reads messages from WebSocket connection
 func (c *Client) readPump(hub *Hub) {
 	defer func() {
@@ -161,8 +308,8 @@ func (c *Client) readPump(hub *Hub) {
 			break
 		}
 
-		// Handle incoming messages (e.g., subscription requests)
-		log.Printf("Received message from client %s: %s", c.ID, string(message))
+		// Handle subscription requests
+		hub.handleMessage(c, message)
 	}
 }
 
@@ -179,24 +326,16 @@ func (c *Client) writePump() {
 		case message, ok := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+				closeMessage := []byte{}
+				if c.closeCode != 0 {
+					closeMessage = websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
-			w.Write(message)
-
-			// Add queued messages
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
-			}
 
-			if err := w.Close(); err != nil {
+			// Each message is its own frame so clients can parse it as JSON
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialPump serves a websocket whose writes come from client's writePump and
+// returns the dialled end
+func dialPump(t *testing.T, client *Client) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade: %v", err)
+			return
+		}
+		client.Conn = conn
+		client.writePump()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestWritePumpSendsOneFramePerMessage(t *testing.T) {
+	messages := []string{`{"seq":1}`, `{"seq":2}`, `{"seq":3}`}
+	client := &Client{Send: make(chan []byte, len(messages))}
+	for _, message := range messages {
+		client.Send <- []byte(message)
+	}
+	close(client.Send)
+
+	conn := dialPump(t, client)
+	for _, want := range messages {
+		messageType, got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() err = %v, want %s", err, want)
+		}
+		if messageType != websocket.TextMessage || string(got) != want {
+			t.Errorf("ReadMessage() = %d %s, want a text frame %s", messageType, got, want)
+		}
+	}
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNoStatusReceived) {
+		t.Errorf("ReadMessage() after the messages err = %v, want a close", err)
+	}
+}
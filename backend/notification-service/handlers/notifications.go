@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// inboxDeliveryLimit is the number of unread notifications sent on connect;
+// older ones stay available through the REST listing
+const inboxDeliveryLimit = 100
+
+// Inbox stores users' notifications until they are read
+type Inbox interface {
+	Unread(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error)
+	UnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, all bool) (int64, error)
+}
+
+// NotificationMessage builds the message pushing a new notification to a
+// user's connections
+func NotificationMessage(notification *models.Notification) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":         "notification",
+		"notification": notification,
+	})
+}
+
+// inboxMessage builds the message carrying a user's unread notifications,
+// sent when they connect. It returns nil when there is nothing to send.
+func (h *Hub) inboxMessage(userID string) []byte {
+	if h.inbox == nil {
+		return nil
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unreadCount, err := h.inbox.UnreadCount(ctx, uid)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of user %s: %v", userID, err)
+		return nil
+	}
+	if unreadCount == 0 {
+		return nil
+	}
+
+	notifications, err := h.inbox.Unread(ctx, uid, inboxDeliveryLimit)
+	if err != nil {
+		log.Printf("Failed to get unread notifications of user %s: %v", userID, err)
+		return nil
+	}
+
+	message, err := json.Marshal(map[string]interface{}{
+		"type":          "notifications",
+		"notifications": notifications,
+		"unread_count":  unreadCount,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal notifications: %v", err)
+		return nil
+	}
+
+	return message
+}
+
+// markRead handles a client's request to mark notifications read and replies
+// with how many were marked and how many remain unread
+func (h *Hub) markRead(c *Client, msg *ClientMessage) {
+	if len(msg.IDs) == 0 && !msg.All {
+		h.reply(c, "error", msg.ID, "", ErrInvalidIDs)
+		return
+	}
+
+	uid, err := uuid.Parse(c.UserID)
+	if err != nil || h.inbox == nil {
+		h.reply(c, "error", msg.ID, "", ErrInboxUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	marked, err := h.inbox.MarkRead(ctx, uid, msg.IDs, msg.All)
+	if err != nil {
+		log.Printf("Failed to mark notifications read for user %s: %v", c.UserID, err)
+		h.reply(c, "error", msg.ID, "", ErrInboxUnavailable)
+		return
+	}
+
+	unreadCount, err := h.inbox.UnreadCount(ctx, uid)
+	if err != nil {
+		log.Printf("Failed to count unread notifications of user %s: %v", c.UserID, err)
+	}
+
+	reply := map[string]interface{}{
+		"type":         "marked_read",
+		"count":        marked,
+		"unread_count": unreadCount,
+	}
+	if msg.ID != "" {
+		reply["id"] = msg.ID
+	}
+
+	message, _ := json.Marshal(reply)
+	h.send(c, message)
+}
+
+// NotificationHandler handles HTTP requests for the user's notification inbox
+type NotificationHandler struct {
+	repo *repository.NotificationRepository
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(repo *repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{repo: repo}
+}
+
+// List handles retrieving a page of the user's notifications, optionally
+// only the unread ones
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page := parseInt(r.URL.Query().Get("page"), 1)
+	pageSize := parseInt(r.URL.Query().Get("page_size"), 20)
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	notifications, totalCount, unreadCount, err := h.repo.List(r.Context(), userID, unreadOnly, page, pageSize)
+	if err != nil {
+		respondError(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.NotificationListResponse{
+		Notifications: notifications,
+		TotalCount:    totalCount,
+		UnreadCount:   unreadCount,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
+// UnreadCount handles retrieving the number of the user's unread notifications
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.repo.UnreadCount(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"unread_count": count}, http.StatusOK)
+}
+
+// MarkRead handles marking the listed notifications of the user, or all of
+// them, as read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.NotificationReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.IDs) == 0 && !req.All {
+		respondError(w, "Either ids or all is required", http.StatusBadRequest)
+		return
+	}
+
+	marked, err := h.repo.MarkRead(r.Context(), userID, req.IDs, req.All)
+	if err != nil {
+		respondError(w, "Failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	count, err := h.repo.UnreadCount(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"count": marked, "unread_count": count}, http.StatusOK)
+}
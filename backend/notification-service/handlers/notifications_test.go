@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+)
+
+// fakeInbox keeps users' unread notifications in memory
+type fakeInbox struct {
+	mu     sync.Mutex
+	unread map[uuid.UUID][]*models.Notification
+}
+
+func (f *fakeInbox) Unread(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unread := f.unread[userID]
+	if len(unread) > limit {
+		unread = unread[:limit]
+	}
+	return unread, nil
+}
+
+func (f *fakeInbox) UnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.unread[userID]), nil
+}
+
+func (f *fakeInbox) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, all bool) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	read := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		read[id] = true
+	}
+
+	var unread []*models.Notification
+	for _, notification := range f.unread[userID] {
+		if !all && !read[notification.ID] {
+			unread = append(unread, notification)
+		}
+	}
+	marked := len(f.unread[userID]) - len(unread)
+	f.unread[userID] = unread
+	return int64(marked), nil
+}
+
+// notify leaves n unread notifications for userID in inbox
+func notify(inbox *fakeInbox, userID uuid.UUID, n int) []*models.Notification {
+	for i := 0; i < n; i++ {
+		inbox.unread[userID] = append(inbox.unread[userID], &models.Notification{
+			ID:      uuid.New(),
+			UserID:  userID,
+			Type:    models.NotificationOrderFilled,
+			Message: fmt.Sprintf("Order filled %d", i),
+		})
+	}
+	return inbox.unread[userID]
+}
+
+func TestInboxDeliveredOnConnect(t *testing.T) {
+	tests := []struct {
+		name        string
+		unread      int
+		wantSent    int
+		wantPending float64
+	}{
+		{"no unread notifications", 0, 0, 0},
+		{"unread notifications", 2, 2, 2},
+		{"more than are sent at once", inboxDeliveryLimit + 20, inboxDeliveryLimit, inboxDeliveryLimit + 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			inbox := &fakeInbox{unread: make(map[uuid.UUID][]*models.Notification)}
+			notify(inbox, userID, tt.unread)
+			hub := newTestHub(fakeStatuses{})
+			hub.SetInbox(inbox)
+
+			token, _, err := testJWT.GenerateToken(userID, "user@example.com", "user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, _, err := dialHub(t, hub, BearerSubprotocol, token)
+			if err != nil {
+				t.Fatalf("Dial() err = %v", err)
+			}
+			readType(t, conn, "connected")
+
+			if tt.wantSent == 0 {
+				// Nothing is queued ahead of the reply to the next request
+				conn.WriteJSON(ClientMessage{Op: OpMarkRead, All: true})
+				readType(t, conn, "marked_read")
+				return
+			}
+
+			msg := readType(t, conn, "notifications")
+			if sent := len(msg["notifications"].([]interface{})); sent != tt.wantSent {
+				t.Errorf("sent %d notifications, want %d", sent, tt.wantSent)
+			}
+			if msg["unread_count"] != tt.wantPending {
+				t.Errorf("unread_count = %v, want %v", msg["unread_count"], tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	userID := uuid.New()
+	inbox := &fakeInbox{unread: make(map[uuid.UUID][]*models.Notification)}
+	notifications := notify(inbox, userID, 3)
+	hub := newTestHub(fakeStatuses{})
+	hub.SetInbox(inbox)
+
+	token, _, err := testJWT.GenerateToken(userID, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialHub(t, hub, BearerSubprotocol, token)
+	if err != nil {
+		t.Fatalf("Dial() err = %v", err)
+	}
+	readType(t, conn, "connected")
+	readType(t, conn, "notifications")
+
+	conn.WriteJSON(ClientMessage{Op: OpMarkRead, ID: "1"})
+	if msg := readType(t, conn, "error"); msg["error"] != ErrInvalidIDs.Error() || msg["id"] != "1" {
+		t.Errorf("reply = %v, want %q for request 1", msg, ErrInvalidIDs)
+	}
+
+	conn.WriteJSON(ClientMessage{Op: OpMarkRead, ID: "2", IDs: []uuid.UUID{notifications[0].ID, uuid.New()}})
+	if msg := readType(t, conn, "marked_read"); msg["count"] != 1.0 || msg["unread_count"] != 2.0 || msg["id"] != "2" {
+		t.Errorf("reply = %v, want 1 marked and 2 unread for request 2", msg)
+	}
+
+	// Only what is still unread is delivered on the next connection
+	conn.Close()
+	conn, _, err = dialHub(t, hub, BearerSubprotocol, token)
+	if err != nil {
+		t.Fatalf("Dial() err = %v", err)
+	}
+	readType(t, conn, "connected")
+	if msg := readType(t, conn, "notifications"); msg["unread_count"] != 2.0 {
+		t.Errorf("unread_count on reconnecting = %v, want 2", msg["unread_count"])
+	}
+
+	conn.WriteJSON(ClientMessage{Op: OpMarkRead, All: true})
+	if msg := readType(t, conn, "marked_read"); msg["count"] != 2.0 || msg["unread_count"] != 0.0 {
+		t.Errorf("reply = %v, want 2 marked and none unread", msg)
+	}
+}
+
+func TestMarkReadWithoutInbox(t *testing.T) {
+	hub := newTestHub(fakeStatuses{})
+	token, _, err := testJWT.GenerateToken(uuid.New(), "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialHub(t, hub, BearerSubprotocol, token)
+	if err != nil {
+		t.Fatalf("Dial() err = %v", err)
+	}
+	readType(t, conn, "connected")
+
+	conn.WriteJSON(ClientMessage{Op: OpMarkRead, All: true})
+	if msg := readType(t, conn, "error"); msg["error"] != ErrInboxUnavailable.Error() {
+		t.Errorf("reply = %v, want %q", msg, ErrInboxUnavailable)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// channelMessage is a message published on a channel. Seq increases by one
+// with every message of the channel's stream, so a client can tell what it
+// missed and resume from the last seq it saw.
+type channelMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel"`
+	Epoch   string          `json:"epoch,omitempty"`
+	Seq     uint64          `json:"seq"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// replayEntry is a message kept for clients resuming a stream
+type replayEntry struct {
+	seq     uint64
+	message []byte
+}
+
+// stream is the sequence and replay buffer of one channel key. Streams are
+// guarded by the hub's mutex.
+//
+// A stream lives while it has subscribers and for the replay window after
+// the last one leaves, so a client that reconnects within the window can
+// resume it. The epoch identifies one lifetime of a stream; sequences from
+// another epoch, e.g. from before a restart, cannot be resumed.
+type stream struct {
+	epoch     string
+	seq       uint64
+	entries   []replayEntry // Ring buffer, oldest at head once full
+	head      int
+	idleSince time.Time // When the last subscriber left; zero while subscribed
+}
+
+func newStream() *stream {
+	return &stream{epoch: uuid.New().String()}
+}
+
+// record appends a message to the replay buffer, overwriting the oldest
+// message once size are held
+func (s *stream) record(seq uint64, message []byte, size int) {
+	if size <= 0 {
+		return
+	}
+
+	entry := replayEntry{seq: seq, message: message}
+	if len(s.entries) < size {
+		s.entries = append(s.entries, entry)
+		return
+	}
+
+	s.entries[s.head] = entry
+	s.head = (s.head + 1) % len(s.entries)
+}
+
+// since returns the messages after seq in order. ok is false when the gap
+// cannot be filled: the messages were already overwritten, or seq is ahead
+// of the stream.
+func (s *stream) since(seq uint64) (messages [][]byte, ok bool) {
+	if seq > s.seq {
+		return nil, false
+	}
+	if seq == s.seq {
+		return nil, true
+	}
+	if len(s.entries) == 0 || s.entries[s.head].seq > seq+1 {
+		return nil, false
+	}
+
+	for i := range s.entries {
+		entry := s.entries[(s.head+i)%len(s.entries)]
+		if entry.seq > seq {
+			messages = append(messages, entry.message)
+		}
+	}
+
+	return messages, true
+}
+
+// streamFor returns the stream of a channel key, creating it if needed. Must
+// be called with the hub locked.
+func (h *Hub) streamFor(key string) *stream {
+	s, ok := h.streams[key]
+	if !ok {
+		s = newStream()
+		h.streams[key] = s
+		if h.interest != nil {
+			h.interest.StreamOpened(key)
+		}
+	}
+	return s
+}
+
+// publish sequences a message on a channel's stream, records it for replay
+// and sends it to the channel's subscribers. Channels nobody subscribed to
+// within the replay window are skipped.
+func (h *Hub) publish(key, channel, label string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal %s message: %v", channel, err)
+		return
+	}
+
+	// Sequencing and sending happen under one lock so subscribers see a
+	// stream's messages in sequence order
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.streams[key]
+	if !ok {
+		return
+	}
+
+	s.seq++
+	message, err := json.Marshal(channelMessage{
+		Type:    channel,
+		Channel: label,
+		Seq:     s.seq,
+		Data:    raw,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal %s message: %v", channel, err)
+		return
+	}
+	s.record(s.seq, message, h.replaySize)
+
+	for client := range h.channels[key] {
+		select {
+		case client.Send <- message:
+		default:
+			// Channel full, skip; the client can resume from its last seq
+		}
+	}
+}
+
+// pruneStreams drops the streams that have had no subscribers for the
+// replay window
+func (h *Hub) pruneStreams() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.streams {
+		if !s.idleSince.IsZero() && time.Since(s.idleSince) > h.replayWindow {
+			delete(h.streams, key)
+			if h.interest != nil {
+				h.interest.StreamClosed(key)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// streamOf returns a stream that published seqs messages, keeping the last
+// size of them
+func streamOf(seqs, size int) *stream {
+	s := newStream()
+	for i := 0; i < seqs; i++ {
+		s.seq++
+		s.record(s.seq, []byte(fmt.Sprint(s.seq)), size)
+	}
+	return s
+}
+
+func TestStreamSince(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream *stream
+		seq    uint64
+		want   string
+		wantOK bool
+	}{
+		{"caught up", streamOf(3, 5), 3, "", true},
+		{"one missed", streamOf(3, 5), 2, "3", true},
+		{"all missed", streamOf(3, 5), 0, "1,2,3", true},
+		{"gap the buffer holds after wrapping", streamOf(8, 5), 4, "5,6,7,8", true},
+		{"gap the size of the buffer", streamOf(8, 5), 3, "4,5,6,7,8", true},
+		{"gap older than the buffer", streamOf(8, 5), 2, "", false},
+		{"seq ahead of the stream", streamOf(3, 5), 4, "", false},
+		{"stream without messages", streamOf(0, 5), 0, "", true},
+		{"stream keeping no messages", streamOf(3, 0), 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, ok := tt.stream.since(tt.seq)
+			var got []string
+			for _, message := range messages {
+				got = append(got, string(message))
+			}
+			if strings.Join(got, ",") != tt.want || ok != tt.wantOK {
+				t.Errorf("since(%d) = %v, %v, want [%s], %v", tt.seq, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment, so
+// proxies do not close it for inactivity
+const sseHeartbeatInterval = 15 * time.Second
+
+// streamPosition is the epoch and last seq a client saw of one channel
+type streamPosition struct {
+	epoch string
+	seq   uint64
+}
+
+// streamCursor tracks the position of an event stream in each of its
+// channels. It is sent as the SSE event ID so a reconnecting client resumes
+// every channel from where it left off: one "epoch:seq" per channel, in the
+// order the channels were requested, separated by commas.
+type streamCursor struct {
+	keys      []string
+	index     map[string]int
+	positions []streamPosition
+}
+
+func newStreamCursor(keys []string) *streamCursor {
+	cursor := &streamCursor{
+		keys:      keys,
+		index:     make(map[string]int, len(keys)),
+		positions: make([]streamPosition, len(keys)),
+	}
+	for i, key := range keys {
+		cursor.index[key] = i
+	}
+	return cursor
+}
+
+// parse restores the positions from a Last-Event-ID. An ID that does not
+// match the requested channels is ignored.
+func (c *streamCursor) parse(lastEventID string) {
+	parts := strings.Split(lastEventID, ",")
+	if lastEventID == "" || len(parts) != len(c.keys) {
+		return
+	}
+
+	for i, part := range parts {
+		epoch, rawSeq, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(rawSeq, 10, 64)
+		if err != nil {
+			continue
+		}
+		c.positions[i] = streamPosition{epoch: epoch, seq: seq}
+	}
+}
+
+// resume returns the epoch and last seq to resume a channel from, if known
+func (c *streamCursor) resume(key string) (string, *uint64) {
+	position := c.positions[c.index[key]]
+	if position.epoch == "" {
+		return "", nil
+	}
+	return position.epoch, &position.seq
+}
+
+// advance moves the cursor past a message sent to the client and reports
+// whether the message is a position in a channel's stream, which should
+// carry the cursor as its event ID
+func (c *streamCursor) advance(message []byte) bool {
+	var header struct {
+		Type    string `json:"type"`
+		Channel string `json:"channel"`
+		Epoch   string `json:"epoch"`
+		Seq     uint64 `json:"seq"`
+		Resumed bool   `json:"resumed"`
+	}
+	if err := json.Unmarshal(message, &header); err != nil {
+		return false
+	}
+
+	i, ok := c.index[header.Channel]
+	if !ok {
+		return false
+	}
+	position := &c.positions[i]
+
+	switch header.Type {
+	case "subscribed":
+		// A resumed channel continues from the restored seq as the replay
+		// follows; otherwise the snapshot that follows sets the position
+		if !header.Resumed {
+			position.seq = header.Seq
+		}
+		position.epoch = header.Epoch
+		return false
+	case "snapshot":
+		if header.Epoch != "" {
+			position.epoch = header.Epoch
+		}
+		position.seq = header.Seq
+		return position.epoch != ""
+	default:
+		if header.Seq == 0 || position.epoch == "" {
+			return false
+		}
+		position.seq = header.Seq
+		return true
+	}
+}
+
+// id encodes the cursor as an event ID
+func (c *streamCursor) id() string {
+	parts := make([]string, len(c.positions))
+	for i, position := range c.positions {
+		if position.epoch != "" {
+			parts[i] = position.epoch + ":" + strconv.FormatUint(position.seq, 10)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// HandleEventStream serves the websocket channels as Server-Sent Events for
+// clients behind proxies that break websockets. The channels are requested by
+// key, e.g. /events/stream?channel=trades.contract.<id>&channel=orders, and
+// each event's data is the message the websocket would send. On reconnect
+// the Last-Event-ID header, or the last_event_id parameter, resumes every
+// channel from the replay buffer where possible.
+//
+// The stream is authenticated by the API gateway, with an access token or an
+// API key, and ends when the account is suspended or the access token, if
+// any, expires.
+func HandleEventStream(hub *Hub) http.HandlerFunc {
+	return serveEventStream(hub, sseHeartbeatInterval)
+}
+
+// serveEventStream serves event streams, sending a heartbeat whenever one is
+// idle for heartbeatInterval
+func serveEventStream(hub *Hub, heartbeatInterval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondError(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		userID, expiresAt, err := hub.auth.authenticateStream(r)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if err == ErrAccountSuspended {
+				status = http.StatusForbidden
+			}
+			respondError(w, err.Error(), status)
+			return
+		}
+
+		keys, err := streamKeys(r.URL.Query()["channel"])
+		if err != nil {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(keys) > hub.maxSubscriptions {
+			respondError(w, ErrTooManySubscriptions.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cursor := newStreamCursor(keys)
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+		cursor.parse(lastEventID)
+
+		// The stream outlives the server's write timeout
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		client := &Client{
+			ID:            generateClientID(),
+			UserID:        userID,
+			Send:          make(chan []byte, 256),
+			subscriptions: make(map[string]bool),
+			expiresAt:     expiresAt,
+		}
+
+		// Registered directly rather than through the hub's loop, so the
+		// subscriptions below find the client registered
+		client.Send <- welcomeMessage(client)
+		hub.addClient(client)
+		defer func() {
+			hub.unregister <- client
+		}()
+
+		if inboxJSON := hub.inboxMessage(client.UserID); inboxJSON != nil {
+			hub.send(client, inboxJSON)
+		}
+
+		for _, key := range keys {
+			msg := &ClientMessage{Op: OpSubscribe}
+			msg.Epoch, msg.LastSeq = cursor.resume(key)
+
+			resumed, err := hub.subscribe(client, key, msg)
+			if err != nil {
+				hub.reply(client, "error", "", key, err)
+				continue
+			}
+			if !resumed {
+				hub.sendSnapshot(client, key)
+			}
+		}
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case message, ok := <-client.Send:
+				if !ok {
+					return
+				}
+				if err := writeEvent(w, cursor, message); err != nil {
+					return
+				}
+
+				// Add queued messages
+				n := len(client.Send)
+				for i := 0; i < n; i++ {
+					message, ok := <-client.Send
+					if !ok {
+						flusher.Flush()
+						return
+					}
+					if err := writeEvent(w, cursor, message); err != nil {
+						return
+					}
+				}
+				flusher.Flush()
+
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// authenticateStream returns the user of an event stream and when its
+// access token expires. The token the API gateway forwards is validated;
+// callers it authenticated otherwise, such as by API key, are identified by
+// the X-User-ID the service guard sets from the gateway's service token and
+// have no expiry.
+func (a *WSAuth) authenticateStream(r *http.Request) (string, time.Time, error) {
+	if token := handshakeToken(r); token != "" {
+		claims, err := a.validate(r.Context(), token)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return claims.UserID.String(), claims.ExpiresAt.Time, nil
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		return "", time.Time{}, ErrMissingToken
+	}
+	if err := a.checkActive(r.Context(), userID); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return userID.String(), time.Time{}, nil
+}
+
+// streamKeys validates the requested channel keys, dropping duplicates
+func streamKeys(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("at least one channel is required")
+	}
+
+	keys := []string{}
+	seen := make(map[string]bool)
+	for _, raw := range requested {
+		for _, requestedKey := range strings.Split(raw, ",") {
+			channel, scope, id := ParseChannelKey(strings.TrimSpace(requestedKey))
+
+			msg := &ClientMessage{Channel: channel}
+			if id != uuid.Nil {
+				switch scope {
+				case ScopeContract:
+					msg.ContractID = id.String()
+				case ScopeMarket:
+					msg.MarketID = id.String()
+				default:
+					return nil, ErrInvalidScope
+				}
+			}
+
+			key, err := channelKey(msg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", requestedKey, err)
+			}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// writeEvent writes a message as an event, with the cursor as its ID when it
+// is a position in one of the channels
+func writeEvent(w http.ResponseWriter, cursor *streamCursor, message []byte) error {
+	if cursor.advance(message) {
+		if _, err := fmt.Fprintf(w, "id: %s\n", cursor.id()); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "data: %s\n\n", message)
+	return err
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+)
+
+// fakeStatuses reports the status of users, active unless set otherwise
+type fakeStatuses map[uuid.UUID]models.UserStatus
+
+func (f fakeStatuses) Statuses(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]models.UserStatus, error) {
+	statuses := make(map[uuid.UUID]models.UserStatus, len(userIDs))
+	for _, userID := range userIDs {
+		statuses[userID] = models.UserStatusActive
+		if status, ok := f[userID]; ok {
+			statuses[userID] = status
+		}
+	}
+	return statuses, nil
+}
+
+var testJWT = auth.NewJWTManager("test-secret", time.Hour, 24*time.Hour)
+
+// newTestHub creates a running hub authenticating users with statuses
+func newTestHub(statuses fakeStatuses) *Hub {
+	hub := NewHub(10, 100, time.Minute)
+	hub.SetAuth(NewWSAuth(testJWT, statuses, []string{"https://app.example.com"}, 100*time.Millisecond, time.Minute))
+	go hub.Run()
+	return hub
+}
+
+// resumesFrom describes where a cursor resumes a channel from
+func resumesFrom(cursor *streamCursor, key string) string {
+	epoch, seq := cursor.resume(key)
+	if seq == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", epoch, *seq)
+}
+
+func TestStreamCursorParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventID string
+		wantOrders  string
+		wantFills   string
+	}{
+		{
+			name:        "every channel",
+			lastEventID: "e1:5,e2:7",
+			wantOrders:  "e1:5",
+			wantFills:   "e2:7",
+		},
+		{
+			name:        "channel without a position",
+			lastEventID: ",e2:7",
+			wantFills:   "e2:7",
+		},
+		{
+			name:        "malformed positions",
+			lastEventID: "e1:x,e2",
+		},
+		{
+			name:        "other channels",
+			lastEventID: "e1:5",
+		},
+		{
+			name: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := newStreamCursor([]string{"orders", "fills"})
+			cursor.parse(tt.lastEventID)
+
+			if got := resumesFrom(cursor, "orders"); got != tt.wantOrders {
+				t.Errorf("orders resumes from %q, want %q", got, tt.wantOrders)
+			}
+			if got := resumesFrom(cursor, "fills"); got != tt.wantFills {
+				t.Errorf("fills resumes from %q, want %q", got, tt.wantFills)
+			}
+		})
+	}
+}
+
+func TestStreamCursorAdvance(t *testing.T) {
+	cursor := newStreamCursor([]string{"orders", "fills"})
+
+	steps := []struct {
+		message  string
+		wantID   bool
+		wantNext string
+	}{
+		{`{"type":"subscribed","channel":"orders","epoch":"e1","seq":3}`, false, "e1:3,"},
+		{`{"type":"order","channel":"orders","seq":4}`, true, "e1:4,"},
+		{`{"type":"subscribed","channel":"fills","epoch":"e2","seq":0}`, false, "e1:4,e2:0"},
+		{`{"type":"snapshot","channel":"fills","seq":2}`, true, "e1:4,e2:2"},
+		{`{"type":"connected"}`, false, "e1:4,e2:2"},
+		{`{"type":"fill","channel":"fills","seq":3}`, true, "e1:4,e2:3"},
+	}
+
+	for _, step := range steps {
+		if got := cursor.advance([]byte(step.message)); got != step.wantID {
+			t.Errorf("advance(%s) = %v, want %v", step.message, got, step.wantID)
+		}
+		if got := cursor.id(); got != step.wantNext {
+			t.Errorf("id() after %s = %q, want %q", step.message, got, step.wantNext)
+		}
+	}
+
+	// A reconnect with the last ID resumes both channels from there
+	resumed := newStreamCursor([]string{"orders", "fills"})
+	resumed.parse(cursor.id())
+	if got := resumesFrom(resumed, "orders") + "," + resumesFrom(resumed, "fills"); got != cursor.id() {
+		t.Errorf("resumed cursor at %q, want %q", got, cursor.id())
+	}
+}
+
+func TestEventStreamAuthentication(t *testing.T) {
+	suspended := uuid.New()
+	hub := newTestHub(fakeStatuses{suspended: models.UserStatusSuspended})
+
+	token, _, err := testJWT.GenerateToken(uuid.New(), "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{
+			name:       "access token",
+			header:     "Authorization",
+			value:      "Bearer " + token,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "user the gateway authenticated by API key",
+			header:     "X-User-ID",
+			value:      uuid.New().String(),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "suspended user",
+			header:     "X-User-ID",
+			value:      suspended.String(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "invalid access token",
+			header:     "Authorization",
+			value:      "Bearer not-a-token",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no identity",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req := httptest.NewRequest(http.MethodGet, "/events/stream?channel=orders", nil).WithContext(ctx)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := newStreamRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				HandleEventStream(hub).ServeHTTP(rec, req)
+				close(done)
+			}()
+
+			if tt.wantStatus == http.StatusOK {
+				if line := rec.readLine(t); !strings.HasPrefix(line, "data: ") {
+					t.Errorf("first line of the stream = %q, want an event", line)
+				}
+			}
+			cancel()
+			<-done
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEventStreamHeartbeat(t *testing.T) {
+	hub := newTestHub(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/events/stream?channel=orders", nil).WithContext(ctx)
+	req.Header.Set("X-User-ID", uuid.New().String())
+	rec := newStreamRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		serveEventStream(hub, 10*time.Millisecond).ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	for {
+		line := rec.readLine(t)
+		if line == ": heartbeat" {
+			break
+		}
+	}
+	cancel()
+	<-done
+}
+
+// streamRecorder records a streamed response and lets tests read it line by
+// line as it is flushed
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	lines chan string
+}
+
+func newStreamRecorder() *streamRecorder {
+	return &streamRecorder{ResponseRecorder: httptest.NewRecorder(), lines: make(chan string, 100)}
+}
+
+func (r *streamRecorder) Flush() {
+	scanner := bufio.NewScanner(strings.NewReader(r.Body.String()))
+	r.Body.Reset()
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			r.lines <- line
+		}
+	}
+}
+
+// readLine returns the next non-empty line flushed, failing the test if none
+// is within a second
+func (r *streamRecorder) readLine(t *testing.T) string {
+	t.Helper()
+	select {
+	case line := <-r.lines:
+		return line
+	case <-time.After(time.Second):
+		t.Fatal("no line flushed within a second")
+		return ""
+	}
+}
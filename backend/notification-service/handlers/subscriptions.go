@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channels clients can subscribe to over the websocket
+const (
+	ChannelBook    = "book"
+	ChannelTrades  = "trades"
+	ChannelTicker  = "ticker"
+	ChannelOrders  = "orders"
+	ChannelFills   = "fills"
+	ChannelBalance = "balance"
+)
+
+// Scopes of the public channels
+const (
+	ScopeContract = "contract"
+	ScopeMarket   = "market"
+)
+
+// Operations clients can send over the websocket
+const (
+	OpSubscribe   = "subscribe"
+	OpUnsubscribe = "unsubscribe"
+	OpMarkRead    = "mark_read"
+)
+
+var (
+	ErrInvalidMessage       = errors.New("invalid message")
+	ErrUnknownOp            = errors.New("unknown op")
+	ErrUnknownChannel       = errors.New("unknown channel")
+	ErrInvalidScope         = errors.New("channel needs exactly one of contract_id or market_id")
+	ErrPrivateChannelScope  = errors.New("private channels take no contract_id or market_id")
+	ErrTooManySubscriptions = errors.New("too many subscriptions")
+	ErrNotSubscribed        = errors.New("not subscribed to channel")
+	ErrInvalidIDs           = errors.New("mark_read needs ids or all")
+	ErrInboxUnavailable     = errors.New("notification inbox unavailable")
+	ErrConnectionClosed     = errors.New("connection closed")
+)
+
+// ClientMessage is a request sent by a client over the websocket, e.g.
+// {"op":"subscribe","id":"1","channel":"trades","contract_id":"..."} or
+// {"op":"mark_read","ids":["..."]}. A reconnecting client resumes a channel
+// by subscribing with the epoch and last seq it saw. {"op":"auth","token":"..."}
+// authenticates a new connection or refreshes the token of an open one.
+type ClientMessage struct {
+	Op         string      `json:"op"`
+	ID         string      `json:"id,omitempty"`
+	Channel    string      `json:"channel"`
+	ContractID string      `json:"contract_id,omitempty"`
+	MarketID   string      `json:"market_id,omitempty"`
+	Epoch      string      `json:"epoch,omitempty"`
+	LastSeq    *uint64     `json:"last_seq,omitempty"`
+	IDs        []uuid.UUID `json:"ids,omitempty"`
+	All        bool        `json:"all,omitempty"`
+	Token      string      `json:"token,omitempty"`
+}
+
+// Snapshotter provides the current state of a channel, sent to a client right
+// after it subscribes. A nil snapshot sends nothing.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, userID, key string) (interface{}, error)
+}
+
+// privateChannels carry the connection user's own events
+var privateChannels = map[string]bool{
+	ChannelOrders:  true,
+	ChannelFills:   true,
+	ChannelBalance: true,
+}
+
+// ChannelKey returns the key of a public channel scoped to one contract or
+// market, e.g. "trades.contract.<id>"
+func ChannelKey(channel, scope string, id uuid.UUID) string {
+	return channel + "." + scope + "." + id.String()
+}
+
+// ParseChannelKey splits a channel key into its channel, scope and ID. The
+// keys of private channels are the bare channel name.
+func ParseChannelKey(key string) (channel, scope string, id uuid.UUID) {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) != 3 {
+		return key, "", uuid.Nil
+	}
+
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return key, "", uuid.Nil
+	}
+
+	return parts[0], parts[1], id
+}
+
+// channelKey validates the channel of a subscription request and returns its key
+func channelKey(msg *ClientMessage) (string, error) {
+	if privateChannels[msg.Channel] {
+		if msg.ContractID != "" || msg.MarketID != "" {
+			return "", ErrPrivateChannelScope
+		}
+		return msg.Channel, nil
+	}
+
+	switch msg.Channel {
+	case ChannelBook, ChannelTrades, ChannelTicker:
+	default:
+		return "", ErrUnknownChannel
+	}
+
+	// Order books are per contract
+	if (msg.ContractID == "") == (msg.MarketID == "") || (msg.Channel == ChannelBook && msg.ContractID == "") {
+		return "", ErrInvalidScope
+	}
+
+	scope, rawID := ScopeContract, msg.ContractID
+	if msg.MarketID != "" {
+		scope, rawID = ScopeMarket, msg.MarketID
+	}
+
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", ErrInvalidScope
+	}
+
+	return ChannelKey(msg.Channel, scope, id), nil
+}
+
+// handleMessage handles a request a client sent over the websocket and
+// replies with an ack or an error
+func (h *Hub) handleMessage(c *Client, raw []byte) {
+	var msg ClientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		h.reply(c, "error", "", "", ErrInvalidMessage)
+		return
+	}
+
+	switch msg.Op {
+	case OpSubscribe:
+		key, err := channelKey(&msg)
+		resumed := false
+		if err == nil {
+			resumed, err = h.subscribe(c, key, &msg)
+		}
+		if err != nil {
+			h.reply(c, "error", msg.ID, key, err)
+			return
+		}
+
+		// A resumed stream was brought up to date from the replay buffer
+		if !resumed {
+			h.sendSnapshot(c, key)
+		}
+
+	case OpUnsubscribe:
+		key, err := channelKey(&msg)
+		if err == nil {
+			err = h.unsubscribe(c, key)
+		}
+		if err != nil {
+			h.reply(c, "error", msg.ID, key, err)
+			return
+		}
+
+		h.reply(c, "unsubscribed", msg.ID, key, nil)
+
+	case OpMarkRead:
+		h.markRead(c, &msg)
+
+	case OpAuth:
+		h.refresh(c, &msg)
+
+	default:
+		h.reply(c, "error", msg.ID, "", ErrUnknownOp)
+	}
+}
+
+// subscriberKey returns the key a client's subscription to a channel is
+// indexed under. Private channels are indexed per user so their events only
+// need to visit the user's own connections.
+func subscriberKey(c *Client, key string) string {
+	if privateChannels[key] {
+		return key + "." + c.UserID
+	}
+	return key
+}
+
+// subscribe adds a client to a channel and acks it with the channel
+// stream's epoch and current seq. When the request carries the epoch and
+// last seq the client saw and the replay buffer still holds everything after
+// it, the missed messages follow the ack and resumed is true. Subscribing
+// twice only acks again.
+//
+// The ack and replay are queued under the hub lock so no message published
+// meanwhile can overtake them.
+func (h *Hub) subscribe(c *Client, key string, msg *ClientMessage) (resumed bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[c] {
+		return false, ErrConnectionClosed
+	}
+
+	label := key
+	key = subscriberKey(c, key)
+
+	if !c.subscriptions[key] {
+		if len(c.subscriptions) >= h.maxSubscriptions {
+			return false, ErrTooManySubscriptions
+		}
+
+		c.subscriptions[key] = true
+		if h.channels[key] == nil {
+			h.channels[key] = make(map[*Client]bool)
+		}
+		h.channels[key][c] = true
+	}
+
+	s := h.streamFor(key)
+	s.idleSince = time.Time{}
+
+	var replay [][]byte
+	if msg.LastSeq != nil && msg.Epoch == s.epoch {
+		replay, resumed = s.since(*msg.LastSeq)
+	}
+
+	ack := map[string]interface{}{
+		"type":    "subscribed",
+		"channel": label,
+		"epoch":   s.epoch,
+		"seq":     s.seq,
+		"resumed": resumed,
+	}
+	if msg.ID != "" {
+		ack["id"] = msg.ID
+	}
+	ackJSON, _ := json.Marshal(ack)
+
+	for _, message := range append([][]byte{ackJSON}, replay...) {
+		select {
+		case c.Send <- message:
+		default:
+			// Channel full, skip
+		}
+	}
+
+	return resumed, nil
+}
+
+// unsubscribe removes a client from a channel
+func (h *Hub) unsubscribe(c *Client, key string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key = subscriberKey(c, key)
+
+	if !c.subscriptions[key] {
+		return ErrNotSubscribed
+	}
+
+	delete(c.subscriptions, key)
+	h.removeSubscriber(key, c)
+
+	return nil
+}
+
+// removeSubscriber removes a client from a channel's subscribers. Once the
+// last one leaves, the channel's stream starts its replay window. Must be
+// called with the hub locked.
+func (h *Hub) removeSubscriber(key string, c *Client) {
+	delete(h.channels[key], c)
+	if len(h.channels[key]) == 0 {
+		delete(h.channels, key)
+		if s, ok := h.streams[key]; ok {
+			s.idleSince = time.Now()
+		}
+	}
+}
+
+// Streaming reports whether a public channel has subscribers, or had some
+// recently enough to be resumed, so its messages are wanted
+func (h *Hub) Streaming(key string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, ok := h.streams[key]
+	return ok
+}
+
+// Publish sends data on a public channel, e.g. "trades.contract.<id>", as a
+// message of the given channel type
+func (h *Hub) Publish(key, channel string, data interface{}) {
+	h.publish(key, channel, key, data)
+}
+
+// PublishToUser sends data to the connections of a user subscribed to one of
+// their private channels
+func (h *Hub) PublishToUser(userID, channel string, data interface{}) {
+	h.publish(channel+"."+userID, channel, channel, data)
+}
+
+// sendSnapshot sends a client the current state of a channel it subscribed
+// to. The snapshot carries the stream's seq when it was taken; messages after
+// it may already be reflected in the snapshot.
+func (h *Hub) sendSnapshot(c *Client, key string) {
+	if h.snapshots == nil {
+		return
+	}
+
+	h.mu.RLock()
+	var epoch string
+	var seq uint64
+	if s, ok := h.streams[subscriberKey(c, key)]; ok {
+		epoch, seq = s.epoch, s.seq
+	}
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snapshot, err := h.snapshots.Snapshot(ctx, c.UserID, key)
+	if err != nil {
+		log.Printf("Failed to get snapshot of %s: %v", key, err)
+		return
+	}
+	if snapshot == nil {
+		return
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("Failed to marshal snapshot of %s: %v", key, err)
+		return
+	}
+
+	message, err := json.Marshal(channelMessage{
+		Type:    "snapshot",
+		Channel: key,
+		Epoch:   epoch,
+		Seq:     seq,
+		Data:    data,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal snapshot of %s: %v", key, err)
+		return
+	}
+
+	h.send(c, message)
+}
+
+// reply sends a client an ack or error for one of its requests
+func (h *Hub) reply(c *Client, replyType, id, key string, err error) {
+	reply := map[string]string{"type": replyType}
+	if id != "" {
+		reply["id"] = id
+	}
+	if key != "" {
+		reply["channel"] = key
+	}
+	if err != nil {
+		reply["error"] = err.Error()
+	}
+
+	message, _ := json.Marshal(reply)
+	h.send(c, message)
+}
+
+// send queues a message for a client unless it has disconnected
+func (h *Hub) send(c *Client, message []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.Send <- message:
+	default:
+		// Channel full, skip
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestClient connects a client of userID to hub without a connection
+func newTestClient(hub *Hub, userID string) *Client {
+	client := &Client{
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		Send:          make(chan []byte, 100),
+		subscriptions: make(map[string]bool),
+	}
+	hub.addClient(client)
+	return client
+}
+
+// received drains the messages queued for a client
+func received(t *testing.T, c *Client) []channelMessage {
+	t.Helper()
+	var messages []channelMessage
+	for {
+		select {
+		case raw := <-c.Send:
+			var msg channelMessage
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				t.Fatalf("malformed message %s: %v", raw, err)
+			}
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+// seqs returns the seqs of the channel messages among messages
+func seqs(messages []channelMessage) []uint64 {
+	var result []uint64
+	for _, msg := range messages {
+		if msg.Type != "subscribed" {
+			result = append(result, msg.Seq)
+		}
+	}
+	return result
+}
+
+func TestSubscribeResume(t *testing.T) {
+	trades := ChannelKey(ChannelTrades, ScopeContract, uuid.New())
+	seq := func(n uint64) *uint64 { return &n }
+
+	tests := []struct {
+		name        string
+		epoch       func(*stream) string
+		lastSeq     *uint64
+		wantResumed bool
+		wantReplay  []uint64
+	}{
+		{
+			name:  "fresh subscription",
+			epoch: func(*stream) string { return "" },
+		},
+		{
+			name:        "caught up",
+			epoch:       func(s *stream) string { return s.epoch },
+			lastSeq:     seq(8),
+			wantResumed: true,
+		},
+		{
+			name:        "gap the replay buffer holds",
+			epoch:       func(s *stream) string { return s.epoch },
+			lastSeq:     seq(5),
+			wantResumed: true,
+			wantReplay:  []uint64{6, 7, 8},
+		},
+		{
+			name:    "cursor older than the replay buffer",
+			epoch:   func(s *stream) string { return s.epoch },
+			lastSeq: seq(2),
+		},
+		{
+			name:    "cursor of another epoch",
+			epoch:   func(*stream) string { return uuid.NewString() },
+			lastSeq: seq(5),
+		},
+		{
+			name:    "cursor ahead of the stream",
+			epoch:   func(s *stream) string { return s.epoch },
+			lastSeq: seq(9),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(10, 5, time.Minute)
+			first := newTestClient(hub, "user-1")
+			if _, err := hub.subscribe(first, trades, &ClientMessage{}); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 8; i++ {
+				hub.Publish(trades, ChannelTrades, map[string]int{"i": i})
+			}
+			if got := fmt.Sprint(seqs(received(t, first))); got != "[1 2 3 4 5 6 7 8]" {
+				t.Fatalf("subscriber received seqs %s, want 1 to 8", got)
+			}
+
+			// A client reconnecting with the cursor it saw
+			s := hub.streams[trades]
+			client := newTestClient(hub, "user-2")
+			resumed, err := hub.subscribe(client, trades, &ClientMessage{ID: "1", Epoch: tt.epoch(s), LastSeq: tt.lastSeq})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed != tt.wantResumed {
+				t.Errorf("resumed = %v, want %v", resumed, tt.wantResumed)
+			}
+
+			messages := received(t, client)
+			if len(messages) == 0 || messages[0].Type != "subscribed" {
+				t.Fatalf("messages = %v, want the ack first", messages)
+			}
+			if ack := messages[0]; ack.Epoch != s.epoch || ack.Seq != 8 {
+				t.Errorf("ack at %s:%d, want %s:8", ack.Epoch, ack.Seq, s.epoch)
+			}
+			if got, want := fmt.Sprint(seqs(messages)), fmt.Sprint(tt.wantReplay); got != want {
+				t.Errorf("replayed seqs %s, want %s", got, want)
+			}
+
+			// Later messages continue the sequence
+			hub.Publish(trades, ChannelTrades, map[string]int{"i": 8})
+			if got := fmt.Sprint(seqs(received(t, client))); got != "[9]" {
+				t.Errorf("next seqs %s, want [9]", got)
+			}
+		})
+	}
+}
+
+func TestStreamEpochs(t *testing.T) {
+	trades := ChannelKey(ChannelTrades, ScopeContract, uuid.New())
+	hub := NewHub(10, 5, time.Minute)
+	alice, bob := newTestClient(hub, "alice"), newTestClient(hub, "bob")
+
+	for _, c := range []*Client{alice, bob} {
+		for _, key := range []string{trades, ChannelOrders} {
+			if _, err := hub.subscribe(c, key, &ClientMessage{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	received(t, alice)
+	received(t, bob)
+
+	// Each user's private channel is a stream of its own
+	hub.PublishToUser("alice", ChannelOrders, map[string]string{"order": "1"})
+	hub.PublishToUser("alice", ChannelOrders, map[string]string{"order": "2"})
+	hub.PublishToUser("bob", ChannelOrders, map[string]string{"order": "3"})
+	hub.Publish(trades, ChannelTrades, map[string]string{"trade": "1"})
+
+	if got := fmt.Sprint(seqs(received(t, alice))); got != "[1 2 1]" {
+		t.Errorf("alice received seqs %s, want [1 2 1]", got)
+	}
+	if got := fmt.Sprint(seqs(received(t, bob))); got != "[1 1]" {
+		t.Errorf("bob received seqs %s, want [1 1]", got)
+	}
+
+	// The stream outlives its subscribers for the replay window, keeping its
+	// epoch, and starts a new one once pruned
+	epoch := hub.streams[trades].epoch
+	for _, c := range []*Client{alice, bob} {
+		if err := hub.unsubscribe(c, trades); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hub.pruneStreams()
+	if s, ok := hub.streams[trades]; !ok || s.epoch != epoch {
+		t.Fatal("stream dropped within the replay window")
+	}
+
+	hub.streams[trades].idleSince = time.Now().Add(-2 * time.Minute)
+	hub.pruneStreams()
+	if hub.Streaming(trades) {
+		t.Fatal("stream kept after the replay window")
+	}
+	if _, err := hub.subscribe(alice, trades, &ClientMessage{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := hub.streams[trades]; s.epoch == epoch || s.seq != 0 {
+		t.Errorf("stream resubscribed at %s:%d, want a new epoch from 0", s.epoch, s.seq)
+	}
+}
+
+func TestChannelKey(t *testing.T) {
+	contract, market := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		msg     ClientMessage
+		want    string
+		wantErr error
+	}{
+		{"private channel", ClientMessage{Channel: ChannelOrders}, ChannelOrders, nil},
+		{"scoped private channel", ClientMessage{Channel: ChannelFills, ContractID: contract.String()}, "", ErrPrivateChannelScope},
+		{"contract trades", ClientMessage{Channel: ChannelTrades, ContractID: contract.String()}, ChannelKey(ChannelTrades, ScopeContract, contract), nil},
+		{"market ticker", ClientMessage{Channel: ChannelTicker, MarketID: market.String()}, ChannelKey(ChannelTicker, ScopeMarket, market), nil},
+		{"market book", ClientMessage{Channel: ChannelBook, MarketID: market.String()}, "", ErrInvalidScope},
+		{"both scopes", ClientMessage{Channel: ChannelTrades, ContractID: contract.String(), MarketID: market.String()}, "", ErrInvalidScope},
+		{"no scope", ClientMessage{Channel: ChannelTrades}, "", ErrInvalidScope},
+		{"malformed ID", ClientMessage{Channel: ChannelTrades, ContractID: "not-a-uuid"}, "", ErrInvalidScope},
+		{"unknown channel", ClientMessage{Channel: "gossip"}, "", ErrUnknownChannel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := channelKey(&tt.msg)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("channelKey() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
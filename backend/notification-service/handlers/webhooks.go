@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// SecretGenerator creates webhook signing secrets
+type SecretGenerator func() (string, error)
+
+// WebhookHandler handles HTTP requests for the user's webhooks and their
+// delivery logs
+type WebhookHandler struct {
+	repo           *repository.WebhookRepository
+	generateSecret SecretGenerator
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(repo *repository.WebhookRepository, generateSecret SecretGenerator) *WebhookHandler {
+	return &WebhookHandler{
+		repo:           repo,
+		generateSecret: generateSecret,
+	}
+}
+
+// List handles retrieving the user's webhooks
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhooks, err := h.repo.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"webhooks": webhooks}, http.StatusOK)
+}
+
+// Create handles registering a webhook. The response carries the secret
+// deliveries are signed with; it is not shown again.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WebhookCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := url.Parse(req.URL)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" || len(req.URL) > 2048 {
+		respondError(w, "URL must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Events) == 0 {
+		respondError(w, "At least one event is required", http.StatusBadRequest)
+		return
+	}
+	events := []models.WebhookEvent{}
+	seen := make(map[models.WebhookEvent]bool)
+	for _, event := range req.Events {
+		if !event.Valid() {
+			respondError(w, "Events must be fills, orders or balance", http.StatusBadRequest)
+			return
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	secret, err := h.generateSecret()
+	if err != nil {
+		respondError(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	webhook := &models.Webhook{
+		ID:     uuid.New(),
+		UserID: userID,
+		URL:    req.URL,
+		Secret: secret,
+		Events: events,
+	}
+
+	if err := h.repo.Create(r.Context(), webhook); err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	respondJSON(w, webhook, http.StatusCreated)
+}
+
+// Delete handles removing one of the user's webhooks
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), userID, req.WebhookID); err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"webhook_id": req.WebhookID}, http.StatusOK)
+}
+
+// Enable handles re-enabling one of the user's webhooks after it was
+// disabled for failing
+func (h *WebhookHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.repo.Enable(r.Context(), userID, req.WebhookID)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	respondJSON(w, webhook, http.StatusOK)
+}
+
+// Deliveries handles retrieving a page of the delivery log of one of the
+// user's webhooks
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := uuid.Parse(r.URL.Query().Get("webhook_id"))
+	if err != nil {
+		respondError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	page := parseInt(r.URL.Query().Get("page"), 1)
+	pageSize := parseInt(r.URL.Query().Get("page_size"), 20)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	deliveries, totalCount, err := h.repo.ListDeliveries(r.Context(), userID, webhookID, page, pageSize)
+	if err != nil {
+		respondWebhookError(w, err)
+		return
+	}
+
+	response := models.WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	respondJSON(w, response, http.StatusOK)
+}
+
+func respondWebhookError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrWebhookNotFound:
+		respondError(w, "Webhook not found", http.StatusNotFound)
+	case repository.ErrTooManyWebhooks:
+		respondError(w, "Too many webhooks", http.StatusConflict)
+	default:
+		respondError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
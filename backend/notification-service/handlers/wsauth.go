@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+)
+
+// OpAuth carries an access token, either as the first message of a
+// connection that did not authenticate during the handshake or later to
+// refresh the token before it expires
+const OpAuth = "auth"
+
+// BearerSubprotocol is offered by browsers, which cannot set headers on a
+// websocket handshake, followed by the access token as a second subprotocol:
+// new WebSocket(url, ["bearer", token])
+const BearerSubprotocol = "bearer"
+
+// Close codes sent when the server ends a connection over its authorization
+const (
+	CloseUnauthorized     = 4001
+	CloseTokenExpired     = 4002
+	CloseAccountSuspended = 4003
+)
+
+var (
+	ErrMissingToken      = errors.New("missing access token")
+	ErrInvalidToken      = errors.New("invalid access token")
+	ErrTokenExpired      = errors.New("access token has expired")
+	ErrAccountSuspended  = errors.New("account is not active")
+	ErrTokenUserMismatch = errors.New("token belongs to another user")
+	ErrAuthUnavailable   = errors.New("authentication unavailable")
+)
+
+// UserStatuses looks up the account status of users
+type UserStatuses interface {
+	Statuses(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]models.UserStatus, error)
+}
+
+// WSAuth authenticates websocket connections and keeps them authorized: it
+// only accepts handshakes from allowed origins, takes the access token from
+// the Authorization header, the bearer subprotocol or the first message, and
+// disconnects clients whose token expires without being refreshed or whose
+// account is no longer active.
+type WSAuth struct {
+	jwtManager     *auth.JWTManager
+	users          UserStatuses
+	allowedOrigins []string
+	allowNoOrigin  bool
+	authTimeout    time.Duration
+	statusInterval time.Duration
+}
+
+// NewWSAuth creates a websocket authenticator. Clients that do not
+// authenticate during the handshake have authTimeout to send their token;
+// account statuses are rechecked every statusInterval.
+func NewWSAuth(jwtManager *auth.JWTManager, users UserStatuses, allowedOrigins []string, authTimeout, statusInterval time.Duration) *WSAuth {
+	return &WSAuth{
+		jwtManager:     jwtManager,
+		users:          users,
+		allowedOrigins: allowedOrigins,
+		authTimeout:    authTimeout,
+		statusInterval: statusInterval,
+	}
+}
+
+// SetAllowNoOrigin sets whether handshakes without an Origin header, which
+// only browsers send, are allowed. They are refused unless allowed.
+func (a *WSAuth) SetAllowNoOrigin(allow bool) {
+	a.allowNoOrigin = allow
+}
+
+// CheckOrigin allows handshakes from the allowed origins, and those without
+// an Origin header if SetAllowNoOrigin allowed them. "*" allows any origin
+// and "*.example.com" any subdomain of example.com.
+func (a *WSAuth) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return a.allowNoOrigin
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	for _, allowed := range a.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(strings.ToLower(parsed.Hostname()), strings.ToLower(allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// handshakeToken returns the access token sent with the handshake, if any
+func handshakeToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == BearerSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// validate checks an access token and the status of its user
+func (a *WSAuth) validate(ctx context.Context, token string) (*auth.Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	claims, err := a.jwtManager.ValidateToken(token)
+	if err != nil {
+		if err == auth.ErrExpiredToken {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+	if claims.Role == "refresh" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := a.checkActive(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkActive checks the account of a user is active
+func (a *WSAuth) checkActive(ctx context.Context, userID uuid.UUID) error {
+	statuses, err := a.users.Statuses(ctx, []uuid.UUID{userID})
+	if err != nil {
+		log.Printf("Failed to get status of user %s: %v", userID, err)
+		return ErrAuthUnavailable
+	}
+	if statuses[userID] != models.UserStatusActive {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
+// awaitToken reads the first message of a connection that did not
+// authenticate during the handshake, which must be an auth op
+func (a *WSAuth) awaitToken(conn *websocket.Conn) (*auth.Claims, error) {
+	conn.SetReadDeadline(time.Now().Add(a.authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		return nil, ErrMissingToken
+	}
+
+	var msg ClientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil || msg.Op != OpAuth {
+		return nil, ErrMissingToken
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return a.validate(ctx, msg.Token)
+}
+
+// refresh replaces the token of an authenticated connection, extending it
+// to the new token's expiry
+func (h *Hub) refresh(c *Client, msg *ClientMessage) {
+	if h.auth == nil {
+		h.reply(c, "error", msg.ID, "", ErrAuthUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claims, err := h.auth.validate(ctx, msg.Token)
+	if err == nil && claims.UserID.String() != c.UserID {
+		err = ErrTokenUserMismatch
+	}
+	if err != nil {
+		h.reply(c, "error", msg.ID, "", err)
+		if err == ErrAccountSuspended {
+			h.disconnect(c, CloseAccountSuspended, err)
+		}
+		return
+	}
+
+	h.mu.Lock()
+	c.expiresAt = claims.ExpiresAt.Time
+	h.mu.Unlock()
+
+	h.send(c, authenticatedMessage(msg.ID, c.UserID, claims.ExpiresAt.Time))
+}
+
+// authenticatedMessage builds the reply to a successful auth op
+func authenticatedMessage(id, userID string, expiresAt time.Time) []byte {
+	reply := map[string]interface{}{
+		"type":       "authenticated",
+		"user_id":    userID,
+		"expires_at": expiresAt,
+	}
+	if id != "" {
+		reply["id"] = id
+	}
+
+	message, _ := json.Marshal(reply)
+	return message
+}
+
+// disconnect tells a client why it is being disconnected and closes its
+// connection with code
+func (h *Hub) disconnect(c *Client, code int, reason error) {
+	message, _ := json.Marshal(map[string]string{
+		"type":   "disconnect",
+		"reason": reason.Error(),
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[c] {
+		return
+	}
+
+	select {
+	case c.Send <- message:
+	default:
+	}
+	c.closeCode = code
+	c.closeReason = reason.Error()
+	h.removeClient(c)
+	log.Printf("Disconnected client %s (User: %s): %v", c.ID, c.UserID, reason)
+}
+
+// Run disconnects clients whose token expired every second and those whose
+// account is no longer active every status interval, until ctx is cancelled
+func (a *WSAuth) Run(ctx context.Context, hub *Hub) {
+	expiry := time.NewTicker(time.Second)
+	defer expiry.Stop()
+	status := time.NewTicker(a.statusInterval)
+	defer status.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-expiry.C:
+			for _, client := range hub.expiredClients(now) {
+				hub.disconnect(client, CloseTokenExpired, ErrTokenExpired)
+			}
+		case <-status.C:
+			a.checkStatuses(ctx, hub)
+		}
+	}
+}
+
+// checkStatuses disconnects the clients of connected users whose account is
+// no longer active
+func (a *WSAuth) checkStatuses(ctx context.Context, hub *Hub) {
+	clients := hub.clientsByUser()
+	if len(clients) == 0 {
+		return
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(clients))
+	for userID := range clients {
+		if uid, err := uuid.Parse(userID); err == nil {
+			userIDs = append(userIDs, uid)
+		}
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	statuses, err := a.users.Statuses(statusCtx, userIDs)
+	if err != nil {
+		log.Printf("Failed to check status of connected users: %v", err)
+		return
+	}
+
+	for _, uid := range userIDs {
+		if statuses[uid] == models.UserStatusActive {
+			continue
+		}
+		for _, client := range clients[uid.String()] {
+			hub.disconnect(client, CloseAccountSuspended, ErrAccountSuspended)
+		}
+	}
+}
+
+// expiredClients returns the clients whose token expired before now
+func (h *Hub) expiredClients(now time.Time) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var expired []*Client
+	for client := range h.clients {
+		if !client.expiresAt.IsZero() && now.After(client.expiresAt) {
+			expired = append(expired, client)
+		}
+	}
+	return expired
+}
+
+// clientsByUser returns the connected clients grouped by user ID
+func (h *Hub) clientsByUser() map[string][]*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make(map[string][]*Client, len(h.users))
+	for client := range h.clients {
+		clients[client.UserID] = append(clients[client.UserID], client)
+	}
+	return clients
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowed       []string
+		allowNoOrigin bool
+		origin        string
+		want          bool
+	}{
+		{"allowed origin", []string{"https://app.example.com"}, false, "https://app.example.com", true},
+		{"allowed origin in another case", []string{"https://app.example.com"}, false, "https://APP.example.com", true},
+		{"other origin", []string{"https://app.example.com"}, false, "https://evil.com", false},
+		{"other scheme", []string{"https://app.example.com"}, false, "http://app.example.com", false},
+		{"subdomain", []string{"*.example.com"}, false, "https://app.example.com", true},
+		{"nested subdomain", []string{"*.example.com"}, false, "https://eu.app.example.com", true},
+		{"domain of the subdomains", []string{"*.example.com"}, false, "https://example.com", false},
+		{"domain ending like the subdomains", []string{"*.example.com"}, false, "https://evilexample.com", false},
+		{"any origin", []string{"*"}, false, "https://evil.com", true},
+		{"malformed origin", []string{"*"}, false, "not an origin", false},
+		{"no origin", []string{"*"}, false, "", false},
+		{"no origin when allowed", []string{"https://app.example.com"}, true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewWSAuth(testJWT, fakeStatuses{}, tt.allowed, time.Second, time.Minute)
+			a.SetAllowNoOrigin(tt.allowNoOrigin)
+
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := a.CheckOrigin(r); got != tt.want {
+				t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandshakeToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		protocols     string
+		want          string
+	}{
+		{"authorization header", "Bearer header-token", "", "header-token"},
+		{"bearer subprotocol", "", "bearer, protocol-token", "protocol-token"},
+		{"bearer subprotocol after others", "", "v1, bearer, protocol-token", "protocol-token"},
+		{"header before subprotocol", "Bearer header-token", "bearer, protocol-token", "header-token"},
+		{"bearer subprotocol without a token", "", "bearer", ""},
+		{"other authorization scheme", "Basic dXNlcg==", "", ""},
+		{"none", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.protocols != "" {
+				r.Header.Set("Sec-WebSocket-Protocol", tt.protocols)
+			}
+			if got := handshakeToken(r); got != tt.want {
+				t.Errorf("handshakeToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// dialHub serves hub's websocket handler and dials it from the allowed
+// origin with protocols
+func dialHub(t *testing.T, hub *Hub, protocols ...string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	server := httptest.NewServer(HandleWebSocket(hub))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: protocols}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), http.Header{"Origin": {"https://app.example.com"}})
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// readType reads the next message of a connection, failing the test unless
+// it is of type want
+func readType(t *testing.T, conn *websocket.Conn, want string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("reading a %s message: %v", want, err)
+	}
+	if msg["type"] != want {
+		t.Fatalf("message = %v, want type %s", msg, want)
+	}
+	return msg
+}
+
+// closeCode reads from a connection until it is closed and returns the code
+// it was closed with
+func closeCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok {
+				return closeErr.Code
+			}
+			t.Fatalf("connection ended without a close frame: %v", err)
+		}
+	}
+}
+
+func TestHandleWebSocketAuthentication(t *testing.T) {
+	user, suspended := uuid.New(), uuid.New()
+	hub := newTestHub(fakeStatuses{suspended: models.UserStatusSuspended})
+
+	token, _, err := testJWT.GenerateToken(user, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suspendedToken, _, err := testJWT.GenerateToken(suspended, "suspended@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("bearer subprotocol", func(t *testing.T) {
+		conn, _, err := dialHub(t, hub, BearerSubprotocol, token)
+		if err != nil {
+			t.Fatalf("Dial() err = %v", err)
+		}
+		if conn.Subprotocol() != BearerSubprotocol {
+			t.Errorf("subprotocol = %q, want %q", conn.Subprotocol(), BearerSubprotocol)
+		}
+		if msg := readType(t, conn, "connected"); msg["user_id"] != user.String() {
+			t.Errorf("connected as %v, want %s", msg["user_id"], user)
+		}
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		_, resp, err := dialHub(t, hub, BearerSubprotocol, suspendedToken)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("Dial() = %v, %v, want a 403", resp, err)
+		}
+	})
+
+	t.Run("token as the first message", func(t *testing.T) {
+		conn, _, err := dialHub(t, hub)
+		if err != nil {
+			t.Fatalf("Dial() err = %v", err)
+		}
+		conn.WriteJSON(ClientMessage{Op: OpAuth, Token: token})
+		if msg := readType(t, conn, "connected"); msg["user_id"] != user.String() {
+			t.Errorf("connected as %v, want %s", msg["user_id"], user)
+		}
+	})
+
+	t.Run("other first message", func(t *testing.T) {
+		conn, _, err := dialHub(t, hub)
+		if err != nil {
+			t.Fatalf("Dial() err = %v", err)
+		}
+		conn.WriteJSON(ClientMessage{Op: OpSubscribe, Channel: "orders"})
+		if code := closeCode(t, conn); code != CloseUnauthorized {
+			t.Errorf("close code = %d, want %d", code, CloseUnauthorized)
+		}
+	})
+
+	t.Run("no token before the timeout", func(t *testing.T) {
+		conn, _, err := dialHub(t, hub)
+		if err != nil {
+			t.Fatalf("Dial() err = %v", err)
+		}
+		start := time.Now()
+		if code := closeCode(t, conn); code != CloseUnauthorized {
+			t.Errorf("close code = %d, want %d", code, CloseUnauthorized)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("closed after %s, before the auth timeout", elapsed)
+		}
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	user := uuid.New()
+	hub := newTestHub(nil)
+
+	token, _, err := testJWT.GenerateToken(user, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialHub(t, hub, BearerSubprotocol, token)
+	if err != nil {
+		t.Fatalf("Dial() err = %v", err)
+	}
+	readType(t, conn, "connected")
+
+	later, _, err := auth.NewJWTManager("test-secret", 2*time.Hour, 24*time.Hour).GenerateToken(user, "user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := testJWT.GenerateToken(uuid.New(), "other@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.WriteJSON(ClientMessage{Op: OpAuth, ID: "1", Token: later})
+	msg := readType(t, conn, "authenticated")
+	expiresAt, _ := time.Parse(time.RFC3339, msg["expires_at"].(string))
+	if until := time.Until(expiresAt); until < 90*time.Minute {
+		t.Errorf("refreshed token expires in %s, want the new token's expiry", until)
+	}
+
+	conn.WriteJSON(ClientMessage{Op: OpAuth, ID: "2", Token: other})
+	if msg := readType(t, conn, "error"); msg["error"] != ErrTokenUserMismatch.Error() {
+		t.Errorf("error = %v, want %v", msg["error"], ErrTokenUserMismatch)
+	}
+
+	conn.WriteJSON(ClientMessage{Op: OpAuth, ID: "3", Token: "not-a-token"})
+	if msg := readType(t, conn, "error"); msg["error"] != ErrInvalidToken.Error() {
+		t.Errorf("error = %v, want %v", msg["error"], ErrInvalidToken)
+	}
+}
+
+func TestWSAuthRun(t *testing.T) {
+	active, suspended := uuid.New(), uuid.New()
+	statuses := fakeStatuses{}
+	hub := newTestHub(statuses)
+	a := NewWSAuth(testJWT, statuses, nil, time.Second, 10*time.Millisecond)
+
+	connect := func(userID uuid.UUID, expiresAt time.Time) *Client {
+		client := &Client{
+			ID:            uuid.NewString(),
+			UserID:        userID.String(),
+			Send:          make(chan []byte, 10),
+			subscriptions: make(map[string]bool),
+			expiresAt:     expiresAt,
+		}
+		hub.register <- client
+		return client
+	}
+	valid := connect(active, time.Now().Add(time.Hour))
+	expired := connect(active, time.Now().Add(-time.Second))
+	stream := connect(active, time.Time{})
+	suspendedClient := connect(suspended, time.Now().Add(time.Hour))
+
+	// Suspend the user once connected
+	statuses[suspended] = models.UserStatusSuspended
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx, hub)
+
+	for _, tt := range []struct {
+		name       string
+		client     *Client
+		wantCode   int
+		wantReason error
+	}{
+		{"expired token", expired, CloseTokenExpired, ErrTokenExpired},
+		{"suspended account", suspendedClient, CloseAccountSuspended, ErrAccountSuspended},
+	} {
+		select {
+		case message := <-tt.client.Send:
+			var msg map[string]string
+			json.Unmarshal(message, &msg)
+			if msg["type"] != "disconnect" || msg["reason"] != tt.wantReason.Error() {
+				t.Errorf("%s: message = %s, want a disconnect for %v", tt.name, message, tt.wantReason)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: client not disconnected", tt.name)
+		}
+
+		hub.mu.RLock()
+		closed, code := tt.client.closed, tt.client.closeCode
+		hub.mu.RUnlock()
+		if !closed || code != tt.wantCode {
+			t.Errorf("%s: closed = %v with code %d, want closed with %d", tt.name, closed, code, tt.wantCode)
+		}
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	for _, client := range []*Client{valid, stream} {
+		if client.closed {
+			t.Errorf("client expiring at %v was disconnected", client.expiresAt)
+		}
+	}
+}
@@ -12,19 +12,138 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go"
+
+	"lfg/matching-engine/engine"
+	"lfg/shared/auth"
+	"lfg/shared/config"
+	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+	"lfg/notification-service/alerts"
+	"lfg/notification-service/email"
+	"lfg/notification-service/fanout"
 	"lfg/notification-service/handlers"
+	"lfg/notification-service/repository"
+	"lfg/notification-service/streams"
+	"lfg/notification-service/webhooks"
 )
 
+// workQueue is the NATS queue group sharing out the events that must be
+// handled once across all instances
+const workQueue = "notification-service"
+
+var tracer = tracing.Tracer("lfg/notification-service")
+
 func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceNotification, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Create context
+	ctx := context.Background()
+
+	// Initialize database connection
+	dbCfg := db.Config{
+		Host:            cfg.DBHost,
+		Port:            cfg.DBPort,
+		User:            cfg.DBUser,
+		Password:        cfg.DBPassword,
+		Database:        cfg.DBName,
+		SSLMode:         cfg.DBSSLMode,
+		MaxConns:        cfg.DBMaxConns,
+		MinConns:        cfg.DBMinConns,
+		MaxConnLifetime: 1 * time.Hour,
+		MaxConnIdleTime: 30 * time.Minute,
+	}
+
+	pool, err := db.NewPool(ctx, dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close(pool)
+	metrics.RegisterPool(pool)
+
+	log.Println("Connected to database successfully")
+
+	// Initialize repositories
+	watchlistRepo := repository.NewWatchlistRepository(pool)
+	alertRepo := repository.NewAlertRepository(pool)
+	streamRepo := repository.NewStreamRepository(pool)
+	notificationRepo := repository.NewNotificationRepository(pool)
+	emailRepo := repository.NewEmailRepository(pool)
+	webhookRepo := repository.NewWebhookRepository(pool)
+	userRepo := repository.NewUserRepository(pool)
+
 	// Initialize WebSocket hub
-	hub := handlers.NewHub()
+	hub := handlers.NewHub(cfg.WSMaxSubscriptions, cfg.WSReplayBufferSize, cfg.WSReplayWindow)
+	hub.SetInbox(notificationRepo)
 	log.Println("WebSocket hub initialized")
 
+	// Connections authenticate with the same access tokens as the REST API
+	// and only from the allowed origins, or without an Origin header if
+	// WS_ALLOW_NO_ORIGIN is set
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.JWTAccessTTL, cfg.JWTRefreshTTL)
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceNotification, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	wsAuth := handlers.NewWSAuth(jwtManager, userRepo, cfg.CORSAllowedOrigins, cfg.WSAuthTimeout, cfg.WSStatusInterval)
+	wsAuth.SetAllowNoOrigin(cfg.WSAllowNoOrigin)
+	hub.SetAuth(wsAuth)
+
 	// Start hub in background
 	go hub.Run()
 	log.Println("WebSocket hub running")
 
-	// Connect to NATS and subscribe to trade events
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+
+	// Disconnect clients whose token expired or whose account was suspended
+	go wsAuth.Run(backgroundCtx, hub)
+
+	// Route trades, book deltas, order and wallet changes and new
+	// notifications to the hub's connections
+	router := streams.NewRouter(hub, streamRepo, notificationRepo, cfg.MatchingEngineGRPC, serviceTokens)
+	hub.SetSnapshotter(router)
+
+	// Deliver fills and order and wallet changes to users' webhooks
+	dispatcher := webhooks.NewDispatcher(webhookRepo, cfg.WebhookPollInterval, cfg.WebhookTimeout,
+		cfg.WebhookMaxAttempts, cfg.WebhookDisableAfter, cfg.WebhookAllowPrivate)
+	router.SetWebhooks(dispatcher)
+	go dispatcher.Run(backgroundCtx)
+	log.Printf("Webhook dispatcher delivering every %s", cfg.WebhookPollInterval)
+
+	// Evaluate alert rules and deliver alerts through the notification inbox
+	evaluator := alerts.NewEvaluator(alertRepo, cfg.MatchingEngineGRPC, serviceTokens, cfg.AlertCheckInterval)
+	go evaluator.Run(backgroundCtx)
+	log.Printf("Alert evaluator checking closing markets every %s", cfg.AlertCheckInterval)
+
+	// Email notifications to users by their preferences through SMTP
+	renderer, err := email.NewRenderer()
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+
+	sender, err := email.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom)
+	if err != nil {
+		log.Fatalf("Failed to configure email sender: %v", err)
+	}
+
+	emailWorker := email.NewWorker(emailRepo, renderer, sender, cfg.EmailPollInterval, cfg.EmailMaxAttempts)
+	go emailWorker.Run(backgroundCtx)
+	log.Printf("Email worker sending through %s:%d every %s", cfg.SMTPHost, cfg.SMTPPort, cfg.EmailPollInterval)
+
+	// Connect to NATS to share events with the other instances
 	natsURL := os.Getenv("NATS_URL")
 	if natsURL == "" {
 		natsURL = "nats://localhost:4222"
@@ -37,56 +156,107 @@ func main() {
 	} else {
 		log.Printf("Connected to NATS at %s", natsURL)
 		defer natsConn.Close()
+	}
 
-		// Subscribe to trades topic
-		_, err := natsConn.Subscribe("trades", func(msg *nats.Msg) {
-			var tradeEvent map[string]interface{}
-			if err := json.Unmarshal(msg.Data, &tradeEvent); err != nil {
+	// Instances only receive the events of their connected users and of the
+	// contracts their clients follow
+	bus := fanout.NewBus(backgroundCtx, natsConn, hub, router)
+	router.SetBus(bus)
+	hub.SetInterest(router)
+	go router.Run(backgroundCtx)
+	go router.ListenDatabase(backgroundCtx, pool)
+
+	if natsConn != nil {
+		// Each trade and lifecycle event is recorded by one instance of the
+		// queue group
+		_, err := natsConn.QueueSubscribe(engine.TradeSubjectPrefix+"*", workQueue, func(msg *nats.Msg) {
+			ctx, span := tracing.StartMessage(backgroundCtx, tracer, msg.Subject, msg.Header)
+			defer span.End()
+
+			var trade streams.TradeEvent
+			if err := json.Unmarshal(msg.Data, &trade); err != nil {
 				log.Printf("Failed to unmarshal trade event: %v", err)
 				return
 			}
 
-			log.Printf("Received trade event: %v", tradeEvent)
+			log.Printf("Received trade event: %s", trade.TradeID)
 
-			// Extract user IDs
-			makerUserID, _ := tradeEvent["maker_user_id"].(string)
-			takerUserID, _ := tradeEvent["taker_user_id"].(string)
+			// Fills reach both users through the fills channel and their
+			// notification inbox
+			router.RecordTrade(ctx, &trade)
+			evaluator.HandleTrade(ctx, &trade)
+		})
 
-			// Prepare notification message
-			notification := map[string]interface{}{
-				"type":  "trade",
-				"event": tradeEvent,
-			}
+		if err != nil {
+			log.Printf("Failed to subscribe to trades: %v", err)
+		} else {
+			log.Println("Subscribed to NATS trades topics")
+		}
 
-			notificationJSON, err := json.Marshal(notification)
-			if err != nil {
-				log.Printf("Failed to marshal notification: %v", err)
-				return
-			}
+		// Subscribe to market lifecycle events for resolution alerts
+		_, err = natsConn.QueueSubscribe(models.MarketLifecycleSubject, workQueue, func(msg *nats.Msg) {
+			ctx, span := tracing.StartMessage(backgroundCtx, tracer, msg.Subject, msg.Header)
+			defer span.End()
 
-			// Broadcast to both users involved in the trade
-			if makerUserID != "" {
-				hub.BroadcastToUser(makerUserID, notificationJSON)
-				log.Printf("Sent trade notification to maker user: %s", makerUserID)
+			var event models.MarketLifecycleEvent
+			if err := json.Unmarshal(msg.Data, &event); err != nil {
+				log.Printf("Failed to unmarshal lifecycle event: %v", err)
+				return
 			}
 
-			if takerUserID != "" {
-				hub.BroadcastToUser(takerUserID, notificationJSON)
-				log.Printf("Sent trade notification to taker user: %s", takerUserID)
-			}
+			evaluator.HandleLifecycle(ctx, &event)
 		})
 
 		if err != nil {
-			log.Printf("Failed to subscribe to trades: %v", err)
+			log.Printf("Failed to subscribe to market lifecycle events: %v", err)
 		} else {
-			log.Println("Subscribed to NATS trades topic")
+			log.Println("Subscribed to NATS market lifecycle topic")
 		}
 	}
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
 	mux.HandleFunc("/ws", handlers.HandleWebSocket(hub))
+	mux.HandleFunc("/events/stream", handlers.HandleEventStream(hub))
+
+	// Watchlist and alert routes
+	alertHandler := handlers.NewAlertHandler(watchlistRepo, alertRepo)
+	mux.HandleFunc("/watchlist", alertHandler.Watchlist)
+	mux.HandleFunc("/watchlist/add", alertHandler.AddToWatchlist)
+	mux.HandleFunc("/watchlist/remove", alertHandler.RemoveFromWatchlist)
+	mux.HandleFunc("/alerts", alertHandler.History)
+	mux.HandleFunc("/alerts/rules", alertHandler.Rules)
+	mux.HandleFunc("/alerts/rules/create", alertHandler.CreateRule)
+	mux.HandleFunc("/alerts/rules/delete", alertHandler.DeleteRule)
+
+	// Notification inbox routes
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	mux.HandleFunc("/notifications", notificationHandler.List)
+	mux.HandleFunc("/notifications/unread-count", notificationHandler.UnreadCount)
+	mux.HandleFunc("/notifications/read", notificationHandler.MarkRead)
+
+	// Email preference routes
+	emailHandler := handlers.NewEmailHandler(emailRepo)
+	mux.HandleFunc("/notifications/email-preferences", emailHandler.Preferences)
+	mux.HandleFunc("/notifications/email-preferences/update", emailHandler.UpdatePreferences)
+
+	// Webhook routes
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, webhooks.GenerateSecret)
+	mux.HandleFunc("/webhooks", webhookHandler.List)
+	mux.HandleFunc("/webhooks/create", webhookHandler.Create)
+	mux.HandleFunc("/webhooks/delete", webhookHandler.Delete)
+	mux.HandleFunc("/webhooks/enable", webhookHandler.Enable)
+	mux.HandleFunc("/webhooks/deliveries", webhookHandler.Deliveries)
+
+	// Only the API gateway may call the endpoints
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway,
+		"/ws", "/events/stream",
+		"/watchlist", "/watchlist/", "/alerts", "/alerts/",
+		"/notifications", "/notifications/", "/webhooks", "/webhooks/",
+	)
 
 	// Create HTTP server
 	port := os.Getenv("PORT")
@@ -96,7 +266,7 @@ func main() {
 
 	server := &http.Server{
 		Addr:         ":" + port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceNotification, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -116,6 +286,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopBackground()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
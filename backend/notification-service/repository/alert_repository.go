@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrRuleNotFound        = errors.New("alert rule not found")
+	ErrRuleNotActive       = errors.New("alert rule is no longer active")
+	ErrTooManyRules        = errors.New("too many active alert rules")
+	ErrMarketSettled       = errors.New("market is already settled")
+	ErrContractRequired    = errors.New("contract is required for price and spread alerts")
+	ErrContractNotInMarket = errors.New("contract does not belong to this market")
+	ErrInvalidThreshold    = errors.New("invalid alert threshold")
+)
+
+// maxActiveRules is the number of active alert rules a user can have
+const maxActiveRules = 100
+
+const alertRuleColumns = `id, user_id, market_id, contract_id, rule_type, threshold, active, triggered_at, created_at`
+
+const alertColumns = `id, rule_id, user_id, market_id, rule_type, message, payload, created_at`
+
+// AlertRepository handles alert rule and alert history database operations
+type AlertRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAlertRepository creates a new alert repository
+func NewAlertRepository(pool *pgxpool.Pool) *AlertRepository {
+	return &AlertRepository{pool: pool}
+}
+
+func scanAlertRule(row pgx.Row, rule *models.AlertRule) error {
+	return row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.MarketID,
+		&rule.ContractID,
+		&rule.Type,
+		&rule.Threshold,
+		&rule.Active,
+		&rule.TriggeredAt,
+		&rule.CreatedAt,
+	)
+}
+
+func scanAlert(row pgx.Row, alert *models.Alert) error {
+	return row.Scan(
+		&alert.ID,
+		&alert.RuleID,
+		&alert.UserID,
+		&alert.MarketID,
+		&alert.Type,
+		&alert.Message,
+		&alert.Payload,
+		&alert.CreatedAt,
+	)
+}
+
+// CreateRule validates and creates an alert rule
+func (r *AlertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	status, err := getMarketStatus(ctx, r.pool, rule.MarketID)
+	if err != nil {
+		return err
+	}
+	if status == models.MarketStatusResolved || status == models.MarketStatusCancelled {
+		return ErrMarketSettled
+	}
+
+	switch rule.Type {
+	case models.AlertRulePriceAbove, models.AlertRulePriceBelow, models.AlertRuleSpreadBelow:
+		if rule.ContractID == nil {
+			return ErrContractRequired
+		}
+		if rule.Threshold == nil || *rule.Threshold <= 0 || *rule.Threshold >= 1 {
+			return ErrInvalidThreshold
+		}
+
+		var marketID uuid.UUID
+		err := r.pool.QueryRow(ctx, `SELECT market_id FROM contracts WHERE id = $1`, *rule.ContractID).Scan(&marketID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get contract: %w", err)
+		}
+		if err != nil || marketID != rule.MarketID {
+			return ErrContractNotInMarket
+		}
+	case models.AlertRuleClosingSoon:
+		if rule.Threshold == nil || *rule.Threshold < 1 {
+			return ErrInvalidThreshold
+		}
+		rule.ContractID = nil
+	default:
+		rule.ContractID = nil
+		rule.Threshold = nil
+	}
+
+	var count int
+	err = r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM alert_rules WHERE user_id = $1 AND active
+	`, rule.UserID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count alert rules: %w", err)
+	}
+	if count >= maxActiveRules {
+		return ErrTooManyRules
+	}
+
+	err = scanAlertRule(r.pool.QueryRow(ctx, `
+		INSERT INTO alert_rules (id, user_id, market_id, contract_id, rule_type, threshold, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE, NOW())
+		RETURNING `+alertRuleColumns,
+		rule.ID,
+		rule.UserID,
+		rule.MarketID,
+		rule.ContractID,
+		rule.Type,
+		rule.Threshold,
+	), rule)
+	if err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+
+	return nil
+}
+
+// ListRules retrieves a user's alert rules, newest first
+func (r *AlertRepository) ListRules(ctx context.Context, userID uuid.UUID) ([]*models.AlertRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+alertRuleColumns+` FROM alert_rules WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlertRules(rows)
+}
+
+// DeleteRule deletes one of a user's alert rules. Alerts it already triggered
+// stay in the history.
+func (r *AlertRepository) DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM alert_rules WHERE id = $1 AND user_id = $2
+	`, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrRuleNotFound
+	}
+
+	return nil
+}
+
+// ActiveContractRules retrieves the active price and spread rules on a contract
+func (r *AlertRepository) ActiveContractRules(ctx context.Context, contractID uuid.UUID) ([]*models.AlertRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+alertRuleColumns+` FROM alert_rules WHERE contract_id = $1 AND active
+	`, contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contract alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlertRules(rows)
+}
+
+// ActiveMarketRules retrieves the active rules of one type on a market
+func (r *AlertRepository) ActiveMarketRules(ctx context.Context, marketID uuid.UUID, ruleType models.AlertRuleType) ([]*models.AlertRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+alertRuleColumns+` FROM alert_rules WHERE market_id = $1 AND rule_type = $2 AND active
+	`, marketID, ruleType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlertRules(rows)
+}
+
+// DueClosingRules retrieves the active CLOSING_SOON rules whose open market
+// expires within the rule's threshold of now
+func (r *AlertRepository) DueClosingRules(ctx context.Context, now time.Time) ([]*models.AlertRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT r.id, r.user_id, r.market_id, r.contract_id, r.rule_type, r.threshold, r.active, r.triggered_at, r.created_at
+		FROM alert_rules r
+		JOIN markets m ON m.id = r.market_id
+		WHERE r.rule_type = $1 AND r.active
+		  AND m.status = $2
+		  AND m.expires_at <= $3 + make_interval(secs => r.threshold)
+	`, models.AlertRuleClosingSoon, models.MarketStatusOpen, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query closing alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlertRules(rows)
+}
+
+// GetMarket retrieves the ticker, question, status and expiry of a market
+func (r *AlertRepository) GetMarket(ctx context.Context, marketID uuid.UUID) (*models.Market, error) {
+	var market models.Market
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, ticker, question, status, expires_at FROM markets WHERE id = $1
+	`, marketID).Scan(&market.ID, &market.Ticker, &market.Question, &market.Status, &market.ExpiresAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMarketNotFound
+		}
+		return nil, fmt.Errorf("failed to get market: %w", err)
+	}
+
+	return &market, nil
+}
+
+// Trigger deactivates a rule and records the alert it raised, both in the
+// alert history and in the user's notification inbox. Only the first
+// caller for a rule succeeds; later ones get ErrRuleNotActive, so a rule never
+// fires twice.
+func (r *AlertRepository) Trigger(ctx context.Context, rule *models.AlertRule, message string, payload interface{}) (*models.Alert, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal alert payload: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE alert_rules SET active = FALSE, triggered_at = NOW() WHERE id = $1 AND active
+	`, rule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deactivate alert rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrRuleNotActive
+	}
+
+	var alert models.Alert
+	err = scanAlert(tx.QueryRow(ctx, `
+		INSERT INTO alerts (id, rule_id, user_id, market_id, rule_type, message, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING `+alertColumns,
+		uuid.New(),
+		rule.ID,
+		rule.UserID,
+		rule.MarketID,
+		rule.Type,
+		message,
+		payloadJSON,
+	), &alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record alert: %w", err)
+	}
+
+	err = insertNotification(ctx, tx, alert.UserID, models.NotificationAlert, message, map[string]interface{}{
+		"alert_id":  alert.ID,
+		"rule_id":   rule.ID,
+		"rule_type": rule.Type,
+		"market_id": rule.MarketID,
+		"details":   json.RawMessage(payloadJSON),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// ListAlerts retrieves a page of a user's alert history, newest first
+func (r *AlertRepository) ListAlerts(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]*models.Alert, int, error) {
+	var totalCount int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM alerts WHERE user_id = $1
+	`, userID).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count alerts: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+alertColumns+`
+		FROM alerts
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []*models.Alert{}
+	for rows.Next() {
+		var alert models.Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating alert rows: %w", err)
+	}
+
+	return alerts, totalCount, nil
+}
+
+func scanAlertRules(rows pgx.Rows) ([]*models.AlertRule, error) {
+	rules := []*models.AlertRule{}
+	for rows.Next() {
+		var rule models.AlertRule
+		if err := scanAlertRule(rows, &rule); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alert rule rows: %w", err)
+	}
+
+	return rules, nil
+}
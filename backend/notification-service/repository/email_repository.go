@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// Email pipeline states of a notification
+const (
+	EmailStateProcessing = "PROCESSING"
+	EmailStateQueued     = "QUEUED"
+	EmailStateDigest     = "DIGEST"
+	EmailStateDigested   = "DIGESTED"
+	EmailStateSkipped    = "SKIPPED"
+)
+
+// emailClaimTimeout is how long a claimed notification may stay PROCESSING
+// before another worker takes it over
+const emailClaimTimeout = 5 * time.Minute
+
+const emailPreferencesColumns = `user_id, fills, settlements, transfers, security, alerts, digest_interval`
+
+const emailMessageColumns = `id, user_id, notification_id, to_address, subject, text_body, html_body, status, attempts, next_attempt_at, last_error, sent_at, created_at`
+
+// PendingEmail is a notification awaiting an email decision and the address
+// of its user
+type PendingEmail struct {
+	Notification *models.Notification
+	Address      string
+}
+
+// DigestRecipient is a user with a digest due
+type DigestRecipient struct {
+	UserID  uuid.UUID
+	Address string
+}
+
+// EmailRepository handles email preference, pipeline and outbox database operations
+type EmailRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewEmailRepository creates a new email repository
+func NewEmailRepository(pool *pgxpool.Pool) *EmailRepository {
+	return &EmailRepository{pool: pool}
+}
+
+func scanEmailPreferences(row pgx.Row, prefs *models.EmailPreferences) error {
+	return row.Scan(
+		&prefs.UserID,
+		&prefs.Fills,
+		&prefs.Settlements,
+		&prefs.Transfers,
+		&prefs.Security,
+		&prefs.Alerts,
+		&prefs.DigestInterval,
+	)
+}
+
+func scanEmailMessage(row pgx.Row, msg *models.EmailMessage) error {
+	return row.Scan(
+		&msg.ID,
+		&msg.UserID,
+		&msg.NotificationID,
+		&msg.To,
+		&msg.Subject,
+		&msg.TextBody,
+		&msg.HTMLBody,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.NextAttemptAt,
+		&msg.LastError,
+		&msg.SentAt,
+		&msg.CreatedAt,
+	)
+}
+
+// GetPreferences retrieves a user's email preferences, or the defaults if
+// they never set any
+func (r *EmailRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.EmailPreferences, error) {
+	var prefs models.EmailPreferences
+	err := scanEmailPreferences(r.pool.QueryRow(ctx, `
+		SELECT `+emailPreferencesColumns+` FROM email_preferences WHERE user_id = $1
+	`, userID), &prefs)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DefaultEmailPreferences(userID), nil
+		}
+		return nil, fmt.Errorf("failed to get email preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// SavePreferences creates or replaces a user's email preferences
+func (r *EmailRepository) SavePreferences(ctx context.Context, prefs *models.EmailPreferences) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO email_preferences (`+emailPreferencesColumns+`, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			fills = EXCLUDED.fills,
+			settlements = EXCLUDED.settlements,
+			transfers = EXCLUDED.transfers,
+			security = EXCLUDED.security,
+			alerts = EXCLUDED.alerts,
+			digest_interval = EXCLUDED.digest_interval
+	`,
+		prefs.UserID,
+		prefs.Fills,
+		prefs.Settlements,
+		prefs.Transfers,
+		prefs.Security,
+		prefs.Alerts,
+		prefs.DigestInterval,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save email preferences: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimNew claims up to limit notifications no worker has decided how to
+// email yet, oldest first. Claims left PROCESSING by a worker that died are
+// taken over after a timeout.
+func (r *EmailRepository) ClaimNew(ctx context.Context, limit int) ([]*PendingEmail, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH claimed AS (
+			UPDATE notifications
+			SET email_status = $1, email_claimed_at = NOW()
+			WHERE id IN (
+				SELECT id FROM notifications
+				WHERE email_status IS NULL OR (email_status = $1 AND email_claimed_at < $2)
+				ORDER BY created_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+notificationColumns+`
+		)
+		SELECT c.id, c.user_id, c.type, c.message, c.payload, c.read_at, c.created_at, u.email
+		FROM claimed c
+		JOIN users u ON u.id = c.user_id
+		ORDER BY c.created_at
+	`, EmailStateProcessing, time.Now().Add(-emailClaimTimeout), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	pending := []*PendingEmail{}
+	for rows.Next() {
+		var notification models.Notification
+		var address string
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.Type,
+			&notification.Message,
+			&notification.Payload,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+			&address,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		pending = append(pending, &PendingEmail{Notification: &notification, Address: address})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification rows: %w", err)
+	}
+
+	return pending, nil
+}
+
+// SetEmailState moves a notification along the email pipeline
+func (r *EmailRepository) SetEmailState(ctx context.Context, notificationID uuid.UUID, state string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE notifications SET email_status = $2 WHERE id = $1
+	`, notificationID, state)
+	if err != nil {
+		return fmt.Errorf("failed to update notification email state: %w", err)
+	}
+
+	return nil
+}
+
+// Enqueue adds an email to the outbox and, for the email of a single
+// notification, marks the notification QUEUED
+func (r *EmailRepository) Enqueue(ctx context.Context, msg *models.EmailMessage) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = scanEmailMessage(tx.QueryRow(ctx, `
+		INSERT INTO email_outbox (id, user_id, notification_id, to_address, subject, text_body, html_body, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0, NOW(), NOW())
+		RETURNING `+emailMessageColumns,
+		uuid.New(),
+		msg.UserID,
+		msg.NotificationID,
+		msg.To,
+		msg.Subject,
+		msg.TextBody,
+		msg.HTMLBody,
+		models.EmailStatusPending,
+	), msg)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue email: %w", err)
+	}
+
+	if msg.NotificationID != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE notifications SET email_status = $2 WHERE id = $1
+		`, *msg.NotificationID, EmailStateQueued)
+		if err != nil {
+			return fmt.Errorf("failed to update notification email state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DueDigests retrieves the users whose oldest notification waiting for a
+// digest is older than their digest interval
+func (r *EmailRepository) DueDigests(ctx context.Context) ([]*DigestRecipient, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT n.user_id, u.email
+		FROM notifications n
+		JOIN users u ON u.id = n.user_id
+		LEFT JOIN email_preferences p ON p.user_id = n.user_id
+		WHERE n.email_status = $1
+		GROUP BY n.user_id, u.email, p.digest_interval
+		HAVING MIN(n.created_at) <= NOW() - CASE COALESCE(p.digest_interval, $2)
+			WHEN $3 THEN INTERVAL '1 hour'
+			ELSE INTERVAL '1 day'
+		END
+	`, EmailStateDigest, models.EmailDigestDaily, models.EmailDigestHourly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due digests: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []*DigestRecipient{}
+	for rows.Next() {
+		var recipient DigestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Address); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, &recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating digest rows: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// ClaimDigest claims up to limit of a user's notifications waiting for a
+// digest, oldest first, and marks them DIGESTED
+func (r *EmailRepository) ClaimDigest(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE notifications
+		SET email_status = $3
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE user_id = $1 AND email_status = $2
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+notificationColumns,
+		userID, EmailStateDigest, EmailStateDigested, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digest notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := collectNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+
+	return notifications, nil
+}
+
+// ClaimOutbox claims up to limit pending emails due for an attempt and counts
+// the attempt. Each claim holds the email for lease, after which another
+// worker may retry it if this one never reports back.
+func (r *EmailRepository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*models.EmailMessage, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+emailMessageColumns,
+		models.EmailStatusPending, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim emails: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.EmailMessage{}
+	for rows.Next() {
+		var msg models.EmailMessage
+		if err := scanEmailMessage(rows, &msg); err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+// MarkSent records that an email was sent
+func (r *EmailRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE email_outbox SET status = $2, sent_at = NOW(), last_error = NULL WHERE id = $1
+	`, id, models.EmailStatusSent)
+	if err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt to send an email. The email is
+// retried at retryAt, or given up on when retryAt is nil.
+func (r *EmailRepository) MarkAttemptFailed(ctx context.Context, id uuid.UUID, sendErr error, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = r.pool.Exec(ctx, `
+			UPDATE email_outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1
+		`, id, sendErr.Error(), *retryAt)
+	} else {
+		_, err = r.pool.Exec(ctx, `
+			UPDATE email_outbox SET status = $2, last_error = $3 WHERE id = $1
+		`, id, models.EmailStatusFailed, sendErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record email failure: %w", err)
+	}
+
+	return nil
+}
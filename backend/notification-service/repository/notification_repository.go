@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrOrderNotFound        = errors.New("order not found")
+)
+
+const notificationColumns = `id, user_id, type, message, payload, read_at, created_at`
+
+// NotificationRepository handles the database operations of users' notification inboxes
+type NotificationRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(pool *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{pool: pool}
+}
+
+func scanNotification(row pgx.Row, notification *models.Notification) error {
+	return row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Type,
+		&notification.Message,
+		&notification.Payload,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+}
+
+// insertNotification adds a notification to a user's inbox as part of tx
+func insertNotification(ctx context.Context, tx pgx.Tx, userID uuid.UUID, notificationType models.NotificationType, message string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notifications (id, user_id, type, message, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, uuid.New(), userID, notificationType, message, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFill adds an ORDER_FILLED notification for one side of a trade to the
+// inbox of the order's owner
+func (r *NotificationRepository) CreateFill(ctx context.Context, orderID uuid.UUID, tradeID string, quantity int, price float64) (*models.Notification, error) {
+	var notification models.Notification
+	err := scanNotification(r.pool.QueryRow(ctx, `
+		INSERT INTO notifications (id, user_id, type, message, payload, created_at)
+		SELECT $1, o.user_id, $2,
+			format('%s %s %s @ %s', CASE o.side WHEN 'BUY' THEN 'Bought' ELSE 'Sold' END, $4::int, c.ticker, round($5::numeric, 4)),
+			jsonb_build_object(
+				'order_id', o.id,
+				'trade_id', $3::text,
+				'contract_id', c.id,
+				'market_id', c.market_id,
+				'side', o.side,
+				'quantity', $4::int,
+				'price', $5::numeric
+			),
+			NOW()
+		FROM orders o
+		JOIN contracts c ON c.id = o.contract_id
+		WHERE o.id = $6
+		RETURNING `+notificationColumns,
+		uuid.New(),
+		models.NotificationOrderFilled,
+		tradeID,
+		quantity,
+		price,
+		orderID,
+	), &notification)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to create fill notification: %w", err)
+	}
+
+	return &notification, nil
+}
+
+// GetByID retrieves a notification by ID
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	var notification models.Notification
+	err := scanNotification(r.pool.QueryRow(ctx, `
+		SELECT `+notificationColumns+` FROM notifications WHERE id = $1
+	`, id), &notification)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	return &notification, nil
+}
+
+// List retrieves a page of a user's notifications, newest first, along with
+// the total count of the listing and the user's unread count
+func (r *NotificationRepository) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]*models.Notification, int, int, error) {
+	var totalCount, unreadCount int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL) FROM notifications WHERE user_id = $1
+	`, userID).Scan(&totalCount, &unreadCount)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	if unreadOnly {
+		totalCount = unreadCount
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`, userID, unreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := collectNotifications(rows)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return notifications, totalCount, unreadCount, nil
+}
+
+// Unread retrieves up to limit of a user's unread notifications, oldest first
+// so they read in the order they happened
+func (r *NotificationRepository) Unread(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Notification, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+notificationColumns+`
+		FROM (
+			SELECT `+notificationColumns+`
+			FROM notifications
+			WHERE user_id = $1 AND read_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT $2
+		) latest
+		ORDER BY created_at
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return collectNotifications(rows)
+}
+
+// UnreadCount counts a user's unread notifications
+func (r *NotificationRepository) UnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// MarkRead marks the listed notifications of a user, or all of them, as read
+// and returns how many were unread
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, all bool) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND ($2 OR id = ANY($3))
+	`, userID, all, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func collectNotifications(rows pgx.Rows) ([]*models.Notification, error) {
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := scanNotification(rows, &notification); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification rows: %w", err)
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrContractNotFound = errors.New("contract not found")
+	ErrWalletNotFound   = errors.New("wallet not found")
+)
+
+// StreamRepository handles the lookups behind the websocket channels
+type StreamRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewStreamRepository creates a new stream repository
+func NewStreamRepository(pool *pgxpool.Pool) *StreamRepository {
+	return &StreamRepository{pool: pool}
+}
+
+// GetContractMarketID retrieves the market a contract belongs to
+func (r *StreamRepository) GetContractMarketID(ctx context.Context, contractID uuid.UUID) (uuid.UUID, error) {
+	var marketID uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+		SELECT market_id FROM contracts WHERE id = $1
+	`, contractID).Scan(&marketID)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrContractNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+
+	return marketID, nil
+}
+
+// GetMarketContractIDs retrieves the IDs of a market's contracts
+func (r *StreamRepository) GetMarketContractIDs(ctx context.Context, marketID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id FROM contracts WHERE market_id = $1 ORDER BY ticker
+	`, marketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market contracts: %w", err)
+	}
+	defer rows.Close()
+
+	contractIDs := []uuid.UUID{}
+	for rows.Next() {
+		var contractID uuid.UUID
+		if err := rows.Scan(&contractID); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contractIDs = append(contractIDs, contractID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contract rows: %w", err)
+	}
+
+	return contractIDs, nil
+}
+
+// GetLastTrade retrieves the latest trade of a contract, or nil if it has
+// never traded
+func (r *StreamRepository) GetLastTrade(ctx context.Context, contractID uuid.UUID) (*models.Trade, error) {
+	var trade models.Trade
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, contract_id, maker_order_id, taker_order_id, quantity, price_credits, executed_at
+		FROM trades
+		WHERE contract_id = $1
+		ORDER BY executed_at DESC
+		LIMIT 1
+	`, contractID).Scan(
+		&trade.ID,
+		&trade.ContractID,
+		&trade.MakerOrderID,
+		&trade.TakerOrderID,
+		&trade.Quantity,
+		&trade.PriceCredits,
+		&trade.ExecutedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last trade: %w", err)
+	}
+
+	return &trade, nil
+}
+
+// GetOpenOrders retrieves a user's resting orders, newest first
+func (r *StreamRepository) GetOpenOrders(ctx context.Context, userID uuid.UUID) ([]*models.Order, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
+		FROM orders
+		WHERE user_id = $1 AND status IN ($2, $3, $4)
+		ORDER BY created_at DESC
+	`, userID, models.OrderStatusPending, models.OrderStatusActive, models.OrderStatusPartiallyFilled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*models.Order{}
+	for rows.Next() {
+		var order models.Order
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.ContractID,
+			&order.Type,
+			&order.Side,
+			&order.Status,
+			&order.Quantity,
+			&order.QuantityFilled,
+			&order.LimitPriceCredits,
+			&order.StopPriceCredits,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, &order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order rows: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetWallet retrieves a user's wallet
+func (r *StreamRepository) GetWallet(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, user_id, balance_credits, created_at, updated_at FROM wallets WHERE user_id = $1
+	`, userID).Scan(&wallet.ID, &wallet.UserID, &wallet.BalanceCredits, &wallet.CreatedAt, &wallet.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return &wallet, nil
+}
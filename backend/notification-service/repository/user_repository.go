@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// UserRepository handles the user lookups of websocket authentication
+type UserRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
+	return &UserRepository{pool: pool}
+}
+
+// Statuses retrieves the account status of each of the users; users that do
+// not exist are left out
+func (r *UserRepository) Statuses(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]models.UserStatus, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, status FROM users WHERE id = ANY($1)
+	`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user statuses: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[uuid.UUID]models.UserStatus, len(userIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var status models.UserStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan user status: %w", err)
+		}
+		statuses[id] = status
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrMarketNotFound = errors.New("market not found")
+	ErrNotOnWatchlist = errors.New("market is not on the watchlist")
+	ErrWatchlistFull  = errors.New("watchlist is full")
+)
+
+// maxWatchlistSize is the number of markets a user can watch
+const maxWatchlistSize = 200
+
+// WatchlistRepository handles watchlist database operations
+type WatchlistRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWatchlistRepository creates a new watchlist repository
+func NewWatchlistRepository(pool *pgxpool.Pool) *WatchlistRepository {
+	return &WatchlistRepository{pool: pool}
+}
+
+// Add adds a published market to a user's watchlist. Adding a market that is
+// already watched is a no-op.
+func (r *WatchlistRepository) Add(ctx context.Context, userID, marketID uuid.UUID) error {
+	if _, err := getMarketStatus(ctx, r.pool, marketID); err != nil {
+		return err
+	}
+
+	var count int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM watchlists WHERE user_id = $1
+	`, userID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count watchlist: %w", err)
+	}
+	if count >= maxWatchlistSize {
+		return ErrWatchlistFull
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO watchlists (user_id, market_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, market_id) DO NOTHING
+	`, userID, marketID)
+	if err != nil {
+		return fmt.Errorf("failed to add to watchlist: %w", err)
+	}
+
+	return nil
+}
+
+// Remove removes a market from a user's watchlist
+func (r *WatchlistRepository) Remove(ctx context.Context, userID, marketID uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM watchlists WHERE user_id = $1 AND market_id = $2
+	`, userID, marketID)
+	if err != nil {
+		return fmt.Errorf("failed to remove from watchlist: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotOnWatchlist
+	}
+
+	return nil
+}
+
+// List retrieves the markets on a user's watchlist, soonest expiring first
+func (r *WatchlistRepository) List(ctx context.Context, userID uuid.UUID) ([]*models.WatchlistEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT m.id, m.ticker, m.question, m.status, m.expires_at, w.created_at
+		FROM watchlists w
+		JOIN markets m ON m.id = w.market_id
+		WHERE w.user_id = $1 AND m.status != $2
+		ORDER BY m.expires_at, m.ticker
+	`, userID, models.MarketStatusDraft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.WatchlistEntry{}
+	for rows.Next() {
+		var entry models.WatchlistEntry
+		err := rows.Scan(
+			&entry.MarketID,
+			&entry.Ticker,
+			&entry.Question,
+			&entry.Status,
+			&entry.ExpiresAt,
+			&entry.AddedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating watchlist rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// getMarketStatus returns the status of a published market
+func getMarketStatus(ctx context.Context, pool *pgxpool.Pool, marketID uuid.UUID) (models.MarketStatus, error) {
+	var status models.MarketStatus
+	err := pool.QueryRow(ctx, `SELECT status FROM markets WHERE id = $1`, marketID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrMarketNotFound
+		}
+		return "", fmt.Errorf("failed to get market: %w", err)
+	}
+
+	if status == models.MarketStatusDraft {
+		return "", ErrMarketNotFound
+	}
+
+	return status, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+// testPool connects to the database at TEST_DATABASE_URL, which must be
+// migrated to the latest schema, skipping the test without one
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// createUser creates a user, deleting it with its watchlist, rules and
+// alerts when the test ends
+func createUser(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	userID := uuid.New()
+	_, err := pool.Exec(ctx, `INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3)`,
+		userID, userID.String()+"@example.com", strings.Repeat("x", 60))
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	return userID
+}
+
+// createMarket creates a market in status expiring after expiresIn,
+// deleting it when the test ends
+func createMarket(t *testing.T, pool *pgxpool.Pool, status models.MarketStatus, expiresIn time.Duration) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	marketID := uuid.New()
+	_, err := pool.Exec(ctx, `
+		INSERT INTO markets (id, ticker, question, rules, resolution_source, status, expires_at)
+		VALUES ($1, $2, 'Will the watchlist test pass?', 'Resolves YES if it does', 'go test', $3, $4)
+	`, marketID, "TEST-"+strings.ToUpper(uuid.NewString()[:8]), status, time.Now().Add(expiresIn))
+	if err != nil {
+		t.Fatalf("failed to create market: %v", err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM markets WHERE id = $1`, marketID) })
+	return marketID
+}
+
+func TestWatchlist(t *testing.T) {
+	pool := testPool(t)
+	repo := NewWatchlistRepository(pool)
+	ctx := context.Background()
+
+	userID := createUser(t, pool)
+	later := createMarket(t, pool, models.MarketStatusOpen, 2*time.Hour)
+	sooner := createMarket(t, pool, models.MarketStatusClosed, time.Hour)
+	draft := createMarket(t, pool, models.MarketStatusDraft, time.Hour)
+
+	for _, marketID := range []uuid.UUID{draft, uuid.New()} {
+		if err := repo.Add(ctx, userID, marketID); !errors.Is(err, ErrMarketNotFound) {
+			t.Errorf("Add() of an unpublished market = %v, want %v", err, ErrMarketNotFound)
+		}
+	}
+
+	// Watching a market twice keeps one entry
+	for _, marketID := range []uuid.UUID{later, sooner, later} {
+		if err := repo.Add(ctx, userID, marketID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := repo.List(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].MarketID != sooner || entries[1].MarketID != later {
+		t.Fatalf("watchlist = %v, want the closed then the open market", entries)
+	}
+	if entries[0].Status != models.MarketStatusClosed {
+		t.Errorf("entry status = %s, want %s", entries[0].Status, models.MarketStatusClosed)
+	}
+
+	if err := repo.Remove(ctx, userID, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Remove(ctx, userID, later); !errors.Is(err, ErrNotOnWatchlist) {
+		t.Errorf("Remove() of an unwatched market = %v, want %v", err, ErrNotOnWatchlist)
+	}
+	if entries, err := repo.List(ctx, userID); err != nil || len(entries) != 1 {
+		t.Errorf("watchlist after removal = %v, %v, want one entry", entries, err)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	pool := testPool(t)
+	repo := NewAlertRepository(pool)
+	ctx := context.Background()
+
+	userID := createUser(t, pool)
+	marketID := createMarket(t, pool, models.MarketStatusOpen, time.Hour)
+	settled := createMarket(t, pool, models.MarketStatusCancelled, time.Hour)
+
+	if err := repo.CreateRule(ctx, &models.AlertRule{ID: uuid.New(), UserID: userID, MarketID: settled, Type: models.AlertRuleMarketResolved}); !errors.Is(err, ErrMarketSettled) {
+		t.Errorf("CreateRule() on a settled market = %v, want %v", err, ErrMarketSettled)
+	}
+
+	rule := &models.AlertRule{ID: uuid.New(), UserID: userID, MarketID: marketID, Type: models.AlertRuleMarketResolved}
+	if err := repo.CreateRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	alert, err := repo.Trigger(ctx, rule, "TEST was resolved", map[string]string{"status": "RESOLVED"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.UserID != userID || alert.Message != "TEST was resolved" {
+		t.Errorf("alert = %+v, want the rule's user and message", alert)
+	}
+
+	// A rule fires once
+	if _, err := repo.Trigger(ctx, rule, "TEST was resolved", nil); !errors.Is(err, ErrRuleNotActive) {
+		t.Errorf("second Trigger() = %v, want %v", err, ErrRuleNotActive)
+	}
+
+	var alerts, notifications int
+	err = pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM alerts WHERE rule_id = $1),
+		       (SELECT COUNT(*) FROM notifications WHERE user_id = $2)
+	`, rule.ID, userID).Scan(&alerts, &notifications)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts != 1 || notifications != 1 {
+		t.Errorf("recorded %d alerts and %d notifications, want 1 of each", alerts, notifications)
+	}
+}
@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrTooManyWebhooks = errors.New("too many webhooks")
+)
+
+// maxWebhooks is the number of webhooks a user can register
+const maxWebhooks = 10
+
+const webhookColumns = `id, user_id, url, secret, events, active, consecutive_failures, disabled_at, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, user_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at`
+
+// DueDelivery is a delivery claimed for an attempt with the endpoint it goes to
+type DueDelivery struct {
+	Delivery *models.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookRepository handles webhook endpoint and delivery database operations
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+func scanWebhook(row pgx.Row, webhook *models.Webhook) error {
+	var events []string
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&events,
+		&webhook.Active,
+		&webhook.ConsecutiveFailures,
+		&webhook.DisabledAt,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	webhook.Events = make([]models.WebhookEvent, len(events))
+	for i, event := range events {
+		webhook.Events[i] = models.WebhookEvent(event)
+	}
+	return nil
+}
+
+func scanWebhookDelivery(row pgx.Row, delivery *models.WebhookDelivery) error {
+	return row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.UserID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+}
+
+// Create registers a webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	var count int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM webhooks WHERE user_id = $1
+	`, webhook.UserID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooks {
+		return ErrTooManyWebhooks
+	}
+
+	events := make([]string, len(webhook.Events))
+	for i, event := range webhook.Events {
+		events[i] = string(event)
+	}
+
+	err = scanWebhook(r.pool.QueryRow(ctx, `
+		INSERT INTO webhooks (id, user_id, url, secret, events, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, NOW(), NOW())
+		RETURNING `+webhookColumns,
+		webhook.ID,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		events,
+	), webhook)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves a user's webhooks, newest first, without their secrets
+func (r *WebhookRepository) List(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhook.Secret = ""
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook rows: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// Delete removes one of a user's webhooks along with its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, userID, webhookID uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM webhooks WHERE id = $1 AND user_id = $2
+	`, webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Enable re-enables one of a user's webhooks after it was disabled for
+// failing, clearing its failure count
+func (r *WebhookRepository) Enable(ctx context.Context, userID, webhookID uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := scanWebhook(r.pool.QueryRow(ctx, `
+		UPDATE webhooks
+		SET active = TRUE, consecutive_failures = 0, disabled_at = NULL
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+webhookColumns,
+		webhookID, userID), &webhook)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to enable webhook: %w", err)
+	}
+
+	webhook.Secret = ""
+	return &webhook, nil
+}
+
+// Enqueue queues an event for every active webhook of a user subscribed to
+// its type
+func (r *WebhookRepository) Enqueue(ctx context.Context, userID uuid.UUID, event models.WebhookEvent, data interface{}) (int64, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, user_id, event, payload, status, next_attempt_at, created_at)
+		SELECT id, user_id, $2, $3, $4, NOW(), NOW()
+		FROM webhooks
+		WHERE user_id = $1 AND active AND $2 = ANY(events)
+	`, userID, string(event), payload, models.WebhookDeliveryPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDue claims up to limit pending deliveries due for an attempt and
+// counts the attempt. Each claim holds the delivery for lease, after which
+// another worker may retry it if this one never reports back.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*DueDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = $2
+			WHERE id IN (
+				SELECT d.id FROM webhook_deliveries d
+				JOIN webhooks w ON w.id = d.webhook_id
+				WHERE d.status = $1 AND d.next_attempt_at <= NOW() AND w.active
+				ORDER BY d.next_attempt_at
+				LIMIT $3
+				FOR UPDATE OF d SKIP LOCKED
+			)
+			RETURNING `+webhookDeliveryColumns+`
+		)
+		SELECT c.id, c.webhook_id, c.user_id, c.event, c.payload, c.status, c.attempts, c.next_attempt_at,
+			c.last_status_code, c.last_error, c.delivered_at, c.created_at, w.url, w.secret
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		ORDER BY c.created_at
+	`, models.WebhookDeliveryPending, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	due := []*DueDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var url, secret string
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.UserID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+			&url,
+			&secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		due = append(due, &DueDelivery{Delivery: &delivery, URL: url, Secret: secret})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+
+	return due, nil
+}
+
+// MarkDelivered records a delivery the endpoint accepted and clears the
+// webhook's failure count
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, delivery *models.WebhookDelivery, statusCode int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, last_status_code = $3, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1
+	`, delivery.ID, models.WebhookDeliveryDelivered, statusCode)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0
+	`, delivery.WebhookID)
+	if err != nil {
+		return fmt.Errorf("failed to reset webhook failures: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt at a delivery. The delivery is
+// retried at retryAt, or given up on when retryAt is nil. The webhook is
+// disabled once it has failed disableAfter attempts in a row, and its pending
+// deliveries given up on; it reports whether that happened.
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, delivery *models.WebhookDelivery, statusCode *int, attemptErr error, retryAt *time.Time, disableAfter int) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if retryAt != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET last_status_code = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1
+		`, delivery.ID, statusCode, attemptErr.Error(), *retryAt)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET status = $2, last_status_code = $3, last_error = $4 WHERE id = $1
+		`, delivery.ID, models.WebhookDeliveryFailed, statusCode, attemptErr.Error())
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook delivery failure: %w", err)
+	}
+
+	// The update locks the webhook's row, so concurrent failures are counted
+	// one after the other and only one of them disables it
+	var failures int
+	var active bool
+	err = tx.QueryRow(ctx, `
+		UPDATE webhooks SET consecutive_failures = consecutive_failures + 1
+		WHERE id = $1
+		RETURNING consecutive_failures, active
+	`, delivery.WebhookID).Scan(&failures, &active)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("failed to count webhook failure: %w", err)
+	}
+
+	disabled := disables(active, failures, disableAfter)
+	if disabled {
+		_, err = tx.Exec(ctx, `
+			UPDATE webhooks SET active = FALSE, disabled_at = NOW() WHERE id = $1
+		`, delivery.WebhookID)
+		if err != nil {
+			return false, fmt.Errorf("failed to disable webhook: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, last_error = COALESCE(last_error, 'webhook disabled')
+			WHERE webhook_id = $1 AND status = $3
+		`, delivery.WebhookID, models.WebhookDeliveryFailed, models.WebhookDeliveryPending)
+		if err != nil {
+			return false, fmt.Errorf("failed to give up webhook deliveries: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return disabled, nil
+}
+
+// disables reports whether a failure disables a webhook: it is still active
+// and its consecutive failures reached the number it is disabled after. A
+// webhook already disabled is not disabled again by failures of the
+// deliveries it had in flight.
+func disables(active bool, failures, disableAfter int) bool {
+	return active && failures >= disableAfter
+}
+
+// ListDeliveries retrieves a page of the delivery log of one of a user's
+// webhooks, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, userID, webhookID uuid.UUID, page, pageSize int) ([]*models.WebhookDelivery, int, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)
+	`, webhookID, userID).Scan(&exists)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !exists {
+		return nil, 0, ErrWebhookNotFound
+	}
+
+	var totalCount int
+	err = r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1
+	`, webhookID).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, webhookID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating webhook delivery rows: %w", err)
+	}
+
+	return deliveries, totalCount, nil
+}
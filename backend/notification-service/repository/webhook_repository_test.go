@@ -0,0 +1,29 @@
+package repository
+
+import "testing"
+
+func TestDisables(t *testing.T) {
+	tests := []struct {
+		name         string
+		active       bool
+		failures     int
+		disableAfter int
+		want         bool
+	}{
+		{"first failure", true, 1, 25, false},
+		{"failure before the threshold", true, 24, 25, false},
+		{"failure reaching the threshold", true, 25, 25, true},
+		{"failure beyond the threshold", true, 26, 25, true},
+		{"failure of an already disabled webhook", false, 26, 25, false},
+		{"failure of a webhook disabled by its owner", false, 1, 25, false},
+		{"threshold of one failure", true, 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := disables(tt.active, tt.failures, tt.disableAfter); got != tt.want {
+				t.Errorf("disables(%v, %d, %d) = %v, want %v", tt.active, tt.failures, tt.disableAfter, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package streams
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/notification-service/handlers"
+)
+
+// Bus carries events between notification-service instances: user events to
+// wherever the user is connected, and contract events to the instances
+// following the contract
+type Bus interface {
+	PublishToUser(userID string, event *UserEvent)
+	FollowUser(userID string)
+	UnfollowUser(userID string)
+	FollowContract(contractID uuid.UUID)
+	UnfollowContract(contractID uuid.UUID)
+}
+
+// interestChange is a stream or user the hub gained or lost
+type interestChange struct {
+	follow bool
+	key    string // Stream key, empty for user changes
+	userID string
+}
+
+// StreamOpened implements handlers.Interest
+func (r *Router) StreamOpened(key string) {
+	r.changes <- interestChange{follow: true, key: key}
+}
+
+// StreamClosed implements handlers.Interest
+func (r *Router) StreamClosed(key string) {
+	r.changes <- interestChange{follow: false, key: key}
+}
+
+// UserConnected implements handlers.Interest
+func (r *Router) UserConnected(userID string) {
+	r.changes <- interestChange{follow: true, userID: userID}
+}
+
+// UserDisconnected implements handlers.Interest
+func (r *Router) UserDisconnected(userID string) {
+	r.changes <- interestChange{follow: false, userID: userID}
+}
+
+// Run follows and unfollows users and contracts on the bus as the hub gains
+// and loses them, until ctx is cancelled. The hub reports changes while
+// locked, so they are applied here, in order, rather than in the hub.
+func (r *Router) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-r.changes:
+			r.apply(ctx, change)
+		}
+	}
+}
+
+func (r *Router) apply(ctx context.Context, change interestChange) {
+	if change.userID != "" {
+		if change.follow {
+			r.bus.FollowUser(change.userID)
+		} else {
+			r.bus.UnfollowUser(change.userID)
+		}
+		return
+	}
+
+	if !change.follow {
+		r.unfollowKey(change.key)
+		return
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	contractIDs, err := r.keyContractIDs(lookupCtx, change.key)
+	if err != nil {
+		log.Printf("Failed to get contracts of %s: %v", change.key, err)
+		return
+	}
+	if len(contractIDs) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	r.keyContracts[change.key] = contractIDs
+	var follow []uuid.UUID
+	for _, contractID := range contractIDs {
+		r.followed[contractID]++
+		if r.followed[contractID] == 1 {
+			follow = append(follow, contractID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, contractID := range follow {
+		r.bus.FollowContract(contractID)
+	}
+}
+
+// unfollowKey stops following the contracts of a stream key, dropping the
+// tickers of contracts no longer followed as they go stale from here on
+func (r *Router) unfollowKey(key string) {
+	r.mu.Lock()
+	contractIDs := r.keyContracts[key]
+	delete(r.keyContracts, key)
+	var unfollow []uuid.UUID
+	for _, contractID := range contractIDs {
+		r.followed[contractID]--
+		if r.followed[contractID] <= 0 {
+			delete(r.followed, contractID)
+			delete(r.tickers, contractID)
+			unfollow = append(unfollow, contractID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, contractID := range unfollow {
+		r.bus.UnfollowContract(contractID)
+	}
+}
+
+// keyContractIDs returns the contracts whose events feed a public stream.
+// Private streams are fed by their user's events and need none.
+func (r *Router) keyContractIDs(ctx context.Context, key string) ([]uuid.UUID, error) {
+	channel, scope, id := handlers.ParseChannelKey(key)
+
+	switch channel {
+	case handlers.ChannelBook, handlers.ChannelTrades, handlers.ChannelTicker:
+	default:
+		return nil, nil
+	}
+
+	if scope == handlers.ScopeContract {
+		return []uuid.UUID{id}, nil
+	}
+
+	return r.repo.GetMarketContractIDs(ctx, id)
+}
@@ -0,0 +1,602 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"lfg/matching-engine/engine"
+	pb "lfg/matching-engine/proto"
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/notification-service/handlers"
+	"lfg/notification-service/repository"
+)
+
+// Postgres channels the order, wallet and notification triggers notify on
+const (
+	OrderUpdatesChannel  = "order_updates"
+	WalletUpdatesChannel = "wallet_updates"
+	NotificationsChannel = "notifications"
+)
+
+// EventNotification is the channel of user events announcing a new inbox
+// notification
+const EventNotification = "notification"
+
+// bookSnapshotDepth is the number of price levels sent per side when a
+// client subscribes to an order book
+const bookSnapshotDepth = 50
+
+// listenerLockID is the Postgres advisory lock held by the one instance
+// forwarding database notifications
+const listenerLockID = 8508501
+
+// errNotListener is returned when another instance holds the listener lock
+var errNotListener = errors.New("another instance is listening")
+
+// TradeEvent is the trade published by the matching engine on the trades subject
+type TradeEvent struct {
+	TradeID      string    `json:"trade_id"`
+	ContractID   uuid.UUID `json:"contract_id"`
+	MakerOrderID string    `json:"maker_order_id"`
+	TakerOrderID string    `json:"taker_order_id"`
+	MakerUserID  string    `json:"maker_user_id"`
+	TakerUserID  string    `json:"taker_user_id"`
+	Quantity     int       `json:"quantity"`
+	Price        float64   `json:"price"`
+	ExecutedAt   int64     `json:"executed_at"`
+}
+
+// UserEvent is an event for one user's connections, carried between
+// instances on the user's subject
+type UserEvent struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// PublicTrade is a trade as published on the public trades channels,
+// without the counterparties
+type PublicTrade struct {
+	TradeID    string    `json:"trade_id"`
+	ContractID uuid.UUID `json:"contract_id"`
+	MarketID   uuid.UUID `json:"market_id"`
+	Quantity   int       `json:"quantity"`
+	Price      float64   `json:"price"`
+	ExecutedAt int64     `json:"executed_at"`
+}
+
+// Fill is one side of a trade as published on its owner's fills channel
+type Fill struct {
+	TradeID    string    `json:"trade_id"`
+	OrderID    string    `json:"order_id"`
+	ContractID uuid.UUID `json:"contract_id"`
+	MarketID   uuid.UUID `json:"market_id"`
+	Liquidity  string    `json:"liquidity"` // MAKER or TAKER
+	Quantity   int       `json:"quantity"`
+	Price      float64   `json:"price"`
+	ExecutedAt int64     `json:"executed_at"`
+}
+
+// Ticker is the latest trade and top of book of a contract
+type Ticker struct {
+	ContractID   uuid.UUID `json:"contract_id"`
+	MarketID     uuid.UUID `json:"market_id"`
+	LastPrice    *float64  `json:"last_price"`
+	LastQuantity int       `json:"last_quantity"`
+	LastTradeAt  int64     `json:"last_trade_at,omitempty"`
+	BestBid      *float64  `json:"best_bid"`
+	BestAsk      *float64  `json:"best_ask"`
+}
+
+// BookSnapshot is the order book sent when a client subscribes to it. Deltas
+// with a sequence at or below the snapshot's are already reflected in it.
+type BookSnapshot struct {
+	ContractID uuid.UUID            `json:"contract_id"`
+	Sequence   uint64               `json:"sequence"`
+	Bids       []engine.LevelChange `json:"bids"`
+	Asks       []engine.LevelChange `json:"asks"`
+}
+
+// Webhooks queues users' events for their webhook endpoints
+type Webhooks interface {
+	Dispatch(ctx context.Context, userID string, event models.WebhookEvent, data interface{})
+}
+
+// Router turns engine trades and order book deltas and database change
+// notifications into messages on the hub's public and private channels, and
+// provides the snapshots sent after subscribing.
+//
+// Public channel events arrive from the bus only for the contracts this
+// instance's clients follow. Events each counterparty or owner must get once,
+// fills and database changes, are handled by a single instance and sent to
+// the users' connections wherever they are through the bus.
+type Router struct {
+	hub                *handlers.Hub
+	repo               *repository.StreamRepository
+	notifications      *repository.NotificationRepository
+	bus                Bus
+	webhooks           Webhooks
+	matchingEngineAddr string
+	serviceTokens      *auth.ServiceTokens
+
+	mu        sync.Mutex
+	tickers   map[uuid.UUID]*Ticker
+	marketIDs map[uuid.UUID]uuid.UUID // Contract ID to market ID
+	followed  map[uuid.UUID]int       // Contract ID to streams following it
+
+	keyContracts map[string][]uuid.UUID // Stream key to contracts followed for it
+	changes      chan interestChange
+}
+
+// NewRouter creates a new stream router
+func NewRouter(hub *handlers.Hub, repo *repository.StreamRepository, notifications *repository.NotificationRepository, matchingEngineAddr string, serviceTokens *auth.ServiceTokens) *Router {
+	return &Router{
+		hub:                hub,
+		repo:               repo,
+		notifications:      notifications,
+		matchingEngineAddr: matchingEngineAddr,
+		serviceTokens:      serviceTokens,
+		tickers:            make(map[uuid.UUID]*Ticker),
+		marketIDs:          make(map[uuid.UUID]uuid.UUID),
+		followed:           make(map[uuid.UUID]int),
+		keyContracts:       make(map[string][]uuid.UUID),
+		changes:            make(chan interestChange, 1024),
+	}
+}
+
+// SetBus sets the bus carrying events between instances
+func (r *Router) SetBus(bus Bus) {
+	r.bus = bus
+}
+
+// SetWebhooks sets where fills and order and wallet changes are queued for
+// users' webhooks
+func (r *Router) SetWebhooks(webhooks Webhooks) {
+	r.webhooks = webhooks
+}
+
+// HandleTrade publishes a trade on the public trades and ticker channels of
+// its contract and market
+func (r *Router) HandleTrade(ctx context.Context, trade *TradeEvent) {
+	marketID, err := r.marketID(ctx, trade.ContractID)
+	if err != nil {
+		log.Printf("Failed to get market of contract %s: %v", trade.ContractID, err)
+		return
+	}
+
+	public := PublicTrade{
+		TradeID:    trade.TradeID,
+		ContractID: trade.ContractID,
+		MarketID:   marketID,
+		Quantity:   trade.Quantity,
+		Price:      trade.Price,
+		ExecutedAt: trade.ExecutedAt,
+	}
+	r.publish(handlers.ChannelTrades, trade.ContractID, marketID, public)
+
+	r.mu.Lock()
+	ticker := r.ticker(trade.ContractID, marketID)
+	price := trade.Price
+	ticker.LastPrice = &price
+	ticker.LastQuantity = trade.Quantity
+	ticker.LastTradeAt = trade.ExecutedAt
+	snapshot := *ticker
+	r.mu.Unlock()
+	r.publish(handlers.ChannelTicker, trade.ContractID, marketID, snapshot)
+}
+
+// RecordTrade sends a trade to the fills channels and webhooks of both
+// counterparties and adds an ORDER_FILLED notification to each one's inbox.
+// Only one instance records each trade.
+func (r *Router) RecordTrade(ctx context.Context, trade *TradeEvent) {
+	marketID, err := r.marketID(ctx, trade.ContractID)
+	if err != nil {
+		log.Printf("Failed to get market of contract %s: %v", trade.ContractID, err)
+		return
+	}
+
+	for _, fill := range []Fill{
+		{OrderID: trade.MakerOrderID, Liquidity: "MAKER"},
+		{OrderID: trade.TakerOrderID, Liquidity: "TAKER"},
+	} {
+		userID := trade.MakerUserID
+		if fill.Liquidity == "TAKER" {
+			userID = trade.TakerUserID
+		}
+		if userID == "" {
+			continue
+		}
+
+		fill.TradeID = trade.TradeID
+		fill.ContractID = trade.ContractID
+		fill.MarketID = marketID
+		fill.Quantity = trade.Quantity
+		fill.Price = trade.Price
+		fill.ExecutedAt = trade.ExecutedAt
+		r.sendToUser(userID, handlers.ChannelFills, fill)
+		r.dispatch(ctx, userID, models.WebhookEventFill, fill)
+		r.notifyFill(ctx, trade, fill.OrderID)
+	}
+}
+
+// notifyFill records one side of a trade in its order owner's inbox. The
+// inbox trigger then pushes it to the owner if they are connected.
+func (r *Router) notifyFill(ctx context.Context, trade *TradeEvent, rawOrderID string) {
+	orderID, err := uuid.Parse(rawOrderID)
+	if err != nil {
+		return
+	}
+
+	_, err = r.notifications.CreateFill(ctx, orderID, trade.TradeID, trade.Quantity, trade.Price)
+	if err != nil {
+		log.Printf("Failed to record fill of order %s: %v", orderID, err)
+	}
+}
+
+// HandleBookDelta publishes an order book delta on its contract's book
+// channel and the new top of book on the ticker channels
+func (r *Router) HandleBookDelta(ctx context.Context, delta *engine.BookDelta) {
+	contractID, err := uuid.Parse(delta.ContractID)
+	if err != nil {
+		return
+	}
+
+	r.publishKey(handlers.ChannelBook, handlers.ChannelKey(handlers.ChannelBook, handlers.ScopeContract, contractID), delta)
+
+	marketID, err := r.marketID(ctx, contractID)
+	if err != nil {
+		log.Printf("Failed to get market of contract %s: %v", contractID, err)
+		return
+	}
+
+	r.mu.Lock()
+	ticker := r.ticker(contractID, marketID)
+	ticker.BestBid = delta.BestBid
+	ticker.BestAsk = delta.BestAsk
+	snapshot := *ticker
+	r.mu.Unlock()
+	r.publish(handlers.ChannelTicker, contractID, marketID, snapshot)
+}
+
+// ListenDatabase forwards order and wallet change notifications to the
+// owners' orders and balance channels and webhooks, and new inbox notifications to their
+// users' connections, until ctx is cancelled. One instance listens at a time;
+// the others stand by and take over when its connection drops.
+func (r *Router) ListenDatabase(ctx context.Context, pool *pgxpool.Pool) {
+	for {
+		err := r.listen(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != errNotListener {
+			log.Printf("Database notification listener stopped, retrying: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (r *Router) listen(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// The connection may still be listening or hold the listener lock, so it
+	// is not returned to the pool; closing it releases both
+	defer conn.Hijack().Close(context.Background())
+
+	var listener bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", listenerLockID).Scan(&listener); err != nil {
+		return fmt.Errorf("failed to take listener lock: %w", err)
+	}
+	if !listener {
+		return errNotListener
+	}
+	log.Println("Forwarding database notifications")
+
+	for _, channel := range []string{OrderUpdatesChannel, WalletUpdatesChannel, NotificationsChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		r.handleNotification(ctx, notification)
+	}
+}
+
+func (r *Router) handleNotification(ctx context.Context, notification *pgconn.Notification) {
+	var owner struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal([]byte(notification.Payload), &owner); err != nil || owner.UserID == "" {
+		log.Printf("Invalid %s notification: %s", notification.Channel, notification.Payload)
+		return
+	}
+
+	channel := handlers.ChannelOrders
+	switch notification.Channel {
+	case OrderUpdatesChannel:
+		r.dispatch(ctx, owner.UserID, models.WebhookEventOrder, json.RawMessage(notification.Payload))
+	case WalletUpdatesChannel:
+		channel = handlers.ChannelBalance
+		r.dispatch(ctx, owner.UserID, models.WebhookEventBalance, json.RawMessage(notification.Payload))
+	case NotificationsChannel:
+		channel = EventNotification
+	}
+
+	r.bus.PublishToUser(owner.UserID, &UserEvent{Channel: channel, Data: json.RawMessage(notification.Payload)})
+}
+
+// dispatch queues an event for a user's webhooks
+func (r *Router) dispatch(ctx context.Context, userID string, event models.WebhookEvent, data interface{}) {
+	if r.webhooks != nil {
+		r.webhooks.Dispatch(ctx, userID, event, data)
+	}
+}
+
+// HandleUserEvent delivers an event to the connections of its user on this
+// instance
+func (r *Router) HandleUserEvent(ctx context.Context, userID string, event *UserEvent) {
+	if event.Channel == EventNotification {
+		r.pushNotification(ctx, userID, event.Data)
+		return
+	}
+
+	r.hub.PublishToUser(userID, event.Channel, event.Data)
+}
+
+// pushNotification sends a new inbox notification to its user's connections.
+// Offline users get it from their inbox when they next connect.
+func (r *Router) pushNotification(ctx context.Context, userID string, payload json.RawMessage) {
+	var created struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(payload, &created); err != nil {
+		log.Printf("Invalid %s event: %s", EventNotification, payload)
+		return
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	notification, err := r.notifications.GetByID(fetchCtx, created.ID)
+	if err != nil {
+		log.Printf("Failed to get notification %s: %v", created.ID, err)
+		return
+	}
+
+	message, err := handlers.NotificationMessage(notification)
+	if err != nil {
+		log.Printf("Failed to marshal notification: %v", err)
+		return
+	}
+
+	r.hub.BroadcastToUser(userID, message)
+}
+
+// Snapshot implements handlers.Snapshotter
+func (r *Router) Snapshot(ctx context.Context, userID, key string) (interface{}, error) {
+	channel, scope, id := handlers.ParseChannelKey(key)
+
+	switch channel {
+	case handlers.ChannelBook:
+		return r.bookSnapshot(ctx, id)
+
+	case handlers.ChannelTicker:
+		if scope == handlers.ScopeContract {
+			return r.tickerSnapshot(ctx, id)
+		}
+
+		contractIDs, err := r.repo.GetMarketContractIDs(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		tickers := make([]*Ticker, 0, len(contractIDs))
+		for _, contractID := range contractIDs {
+			ticker, err := r.tickerSnapshot(ctx, contractID)
+			if err != nil {
+				return nil, err
+			}
+			tickers = append(tickers, ticker)
+		}
+		return tickers, nil
+
+	case handlers.ChannelOrders:
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, err
+		}
+		return r.repo.GetOpenOrders(ctx, uid)
+
+	case handlers.ChannelBalance:
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, err
+		}
+		return r.repo.GetWallet(ctx, uid)
+	}
+
+	// Trades and fills are pure event streams
+	return nil, nil
+}
+
+// bookSnapshot fetches the current order book of a contract from the engine
+func (r *Router) bookSnapshot(ctx context.Context, contractID uuid.UUID) (*BookSnapshot, error) {
+	book, err := r.fetchBook(ctx, contractID, bookSnapshotDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &BookSnapshot{
+		ContractID: contractID,
+		Sequence:   book.Sequence,
+		Bids:       make([]engine.LevelChange, len(book.Bids)),
+		Asks:       make([]engine.LevelChange, len(book.Asks)),
+	}
+	for i, level := range book.Bids {
+		snapshot.Bids[i] = engine.LevelChange{Price: level.Price, Quantity: int(level.Quantity)}
+	}
+	for i, level := range book.Asks {
+		snapshot.Asks[i] = engine.LevelChange{Price: level.Price, Quantity: int(level.Quantity)}
+	}
+
+	return snapshot, nil
+}
+
+// tickerSnapshot returns the ticker of a contract, loading its last trade and
+// top of book the first time it is needed
+func (r *Router) tickerSnapshot(ctx context.Context, contractID uuid.UUID) (*Ticker, error) {
+	r.mu.Lock()
+	ticker, ok := r.tickers[contractID]
+	if ok {
+		snapshot := *ticker
+		r.mu.Unlock()
+		return &snapshot, nil
+	}
+	r.mu.Unlock()
+
+	marketID, err := r.marketID(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &Ticker{ContractID: contractID, MarketID: marketID}
+
+	trade, err := r.repo.GetLastTrade(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	if trade != nil {
+		price := trade.PriceCredits
+		loaded.LastPrice = &price
+		loaded.LastQuantity = trade.Quantity
+		loaded.LastTradeAt = trade.ExecutedAt.Unix()
+	}
+
+	book, err := r.fetchBook(ctx, contractID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(book.Bids) > 0 {
+		loaded.BestBid = &book.Bids[0].Price
+	}
+	if len(book.Asks) > 0 {
+		loaded.BestAsk = &book.Asks[0].Price
+	}
+
+	// A trade or delta may have arrived while loading; it is newer. Tickers
+	// are only kept up to date, and so cached, while their contract's events
+	// are followed.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ticker, ok := r.tickers[contractID]; ok {
+		snapshot := *ticker
+		return &snapshot, nil
+	}
+	if r.followed[contractID] > 0 {
+		r.tickers[contractID] = loaded
+	}
+	snapshot := *loaded
+
+	return &snapshot, nil
+}
+
+func (r *Router) fetchBook(ctx context.Context, contractID uuid.UUID, depth int32) (*pb.GetOrderBookResponse, error) {
+	conn, err := grpc.NewClient(r.matchingEngineAddr,
+		grpc.WithTransportCredentials(r.serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(r.serviceTokens.Credentials(auth.ServiceMatchingEngine)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to matching engine: %w", err)
+	}
+	defer conn.Close()
+
+	return pb.NewMatchingEngineClient(conn).GetOrderBook(ctx, &pb.GetOrderBookRequest{
+		ContractId: contractID.String(),
+		Depth:      depth,
+	})
+}
+
+// ticker returns the ticker of a contract, creating it if needed; only the
+// tickers of followed contracts are kept. Must be called with the router
+// locked.
+func (r *Router) ticker(contractID, marketID uuid.UUID) *Ticker {
+	ticker, ok := r.tickers[contractID]
+	if !ok {
+		ticker = &Ticker{ContractID: contractID, MarketID: marketID}
+		if r.followed[contractID] > 0 {
+			r.tickers[contractID] = ticker
+		}
+	}
+	return ticker
+}
+
+// marketID returns the market of a contract; contracts never move between
+// markets, so the lookup is cached
+func (r *Router) marketID(ctx context.Context, contractID uuid.UUID) (uuid.UUID, error) {
+	r.mu.Lock()
+	marketID, ok := r.marketIDs[contractID]
+	r.mu.Unlock()
+	if ok {
+		return marketID, nil
+	}
+
+	marketID, err := r.repo.GetContractMarketID(ctx, contractID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	r.mu.Lock()
+	r.marketIDs[contractID] = marketID
+	r.mu.Unlock()
+
+	return marketID, nil
+}
+
+// publish sends data on the contract and market scoped keys of a public channel
+func (r *Router) publish(channel string, contractID, marketID uuid.UUID, data interface{}) {
+	r.publishKey(channel, handlers.ChannelKey(channel, handlers.ScopeContract, contractID), data)
+	r.publishKey(channel, handlers.ChannelKey(channel, handlers.ScopeMarket, marketID), data)
+}
+
+func (r *Router) publishKey(channel, key string, data interface{}) {
+	if !r.hub.Streaming(key) {
+		return
+	}
+
+	r.hub.Publish(key, channel, data)
+}
+
+// sendToUser sends data on one of a user's private channels, wherever the
+// user is connected
+func (r *Router) sendToUser(userID, channel string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal %s message: %v", channel, err)
+		return
+	}
+
+	r.bus.PublishToUser(userID, &UserEvent{Channel: channel, Data: raw})
+}
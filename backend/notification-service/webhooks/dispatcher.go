@@ -0,0 +1,269 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// the timestamp, a dot and the request body, keyed with the webhook's secret.
+const (
+	HeaderEvent     = "X-LFG-Event"
+	HeaderDelivery  = "X-LFG-Delivery"
+	HeaderTimestamp = "X-LFG-Timestamp"
+	HeaderSignature = "X-LFG-Signature"
+)
+
+const (
+	// batchSize is the number of deliveries attempted per poll
+	batchSize = 50
+	// lease is how long a claimed delivery is held before another worker may
+	// retry it
+	lease = 2 * time.Minute
+	// retryBase and retryMax bound the exponential backoff between attempts
+	retryBase = 10 * time.Second
+	retryMax  = 1 * time.Hour
+	// maxErrorLength is the most of an endpoint's error response kept in the
+	// delivery log
+	maxErrorLength = 512
+)
+
+// errPrivateAddress is returned when a webhook URL resolves to an address on
+// a private network
+var errPrivateAddress = errors.New("webhook URL resolves to a private address")
+
+// DeliveryStore queues webhook deliveries, claims those due and records the
+// outcome of each attempt
+type DeliveryStore interface {
+	Enqueue(ctx context.Context, userID uuid.UUID, event models.WebhookEvent, data interface{}) (int64, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*repository.DueDelivery, error)
+	MarkDelivered(ctx context.Context, delivery *models.WebhookDelivery, statusCode int) error
+	MarkAttemptFailed(ctx context.Context, delivery *models.WebhookDelivery, statusCode *int, attemptErr error, retryAt *time.Time, disableAfter int) (bool, error)
+}
+
+// Envelope is the body of a delivery
+type Envelope struct {
+	ID        uuid.UUID           `json:"id"`
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      json.RawMessage     `json:"data"`
+}
+
+// Dispatcher queues users' events for their webhooks and delivers them,
+// retrying failed attempts with exponential backoff and disabling webhooks
+// that keep failing. Deliveries are claimed, so any number of instances can
+// run a dispatcher.
+type Dispatcher struct {
+	repo         DeliveryStore
+	client       *http.Client
+	interval     time.Duration
+	maxAttempts  int
+	disableAfter int
+}
+
+// NewDispatcher creates a new webhook dispatcher. Unless allowPrivate is set,
+// webhooks cannot reach loopback, link-local or private network addresses.
+func NewDispatcher(repo DeliveryStore, interval, timeout time.Duration, maxAttempts, disableAfter int, allowPrivate bool) *Dispatcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = rejectPrivate
+	}
+
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// Redirects are not followed, so an endpoint cannot send its
+			// deliveries somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		interval:     interval,
+		maxAttempts:  maxAttempts,
+		disableAfter: disableAfter,
+	}
+}
+
+// GenerateSecret creates a random webhook signing secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign returns the signature of a delivery body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch queues an event for the user's webhooks subscribed to it
+func (d *Dispatcher) Dispatch(ctx context.Context, userID string, event models.WebhookEvent, data interface{}) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+
+	if _, err := d.repo.Enqueue(ctx, uid, event, data); err != nil {
+		log.Printf("Failed to queue %s webhook event for user %s: %v", event, userID, err)
+	}
+}
+
+// Run delivers due events every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	due, err := d.repo.ClaimDue(ctx, batchSize, lease)
+	if err != nil {
+		log.Printf("Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range due {
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver makes one attempt at a delivery and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, due *repository.DueDelivery) {
+	delivery := due.Delivery
+
+	statusCode, attemptErr := d.post(ctx, due)
+	if attemptErr == nil {
+		if err := d.repo.MarkDelivered(ctx, delivery, statusCode); err != nil {
+			log.Printf("Failed to mark webhook delivery %s delivered: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < d.maxAttempts {
+		next := time.Now().Add(backoff(delivery.Attempts))
+		retryAt = &next
+	}
+
+	disabled, err := d.repo.MarkAttemptFailed(ctx, delivery, code, attemptErr, retryAt, d.disableAfter)
+	if err != nil {
+		log.Printf("Failed to record failure of webhook delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if disabled {
+		log.Printf("Disabled webhook %s of user %s after %d consecutive failures", delivery.WebhookID, delivery.UserID, d.disableAfter)
+	}
+}
+
+// post sends a delivery to its endpoint and returns the response status. Any
+// 2xx response is a success.
+func (d *Dispatcher) post(ctx context.Context, due *repository.DueDelivery) (int, error) {
+	delivery := due.Delivery
+
+	body, err := json.Marshal(&Envelope{
+		ID:        delivery.ID,
+		Event:     delivery.Event,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, due.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LFG-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(due.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return resp.StatusCode, nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	return resp.StatusCode, fmt.Errorf("endpoint responded %s: %s", resp.Status, bytes.TrimSpace(detail))
+}
+
+// rejectPrivate refuses connections to addresses that are not on the public
+// internet
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the attempt following the given one,
+// doubling from retryBase up to retryMax
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/notification-service/repository"
+)
+
+// fakeStore records the outcomes of delivery attempts
+type fakeStore struct {
+	DeliveryStore
+
+	delivered    int
+	failed       int
+	statusCode   *int
+	attemptErr   error
+	retryAt      *time.Time
+	disableAfter int
+}
+
+func (s *fakeStore) MarkDelivered(_ context.Context, _ *models.WebhookDelivery, statusCode int) error {
+	s.delivered++
+	s.statusCode = &statusCode
+	return nil
+}
+
+func (s *fakeStore) MarkAttemptFailed(_ context.Context, _ *models.WebhookDelivery, statusCode *int, attemptErr error, retryAt *time.Time, disableAfter int) (bool, error) {
+	s.failed++
+	s.statusCode, s.attemptErr, s.retryAt, s.disableAfter = statusCode, attemptErr, retryAt, disableAfter
+	return false, nil
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"delivery", "whsec_test", 1700000000, `{"id":1}`, "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"},
+		{"another timestamp", "whsec_test", 1700000001, `{"id":1}`, "sha256=5d1660afdffdc0e7e0b80abba2da86ffcbe766a26364d961d8c2c43416778b2a"},
+		{"another secret", "whsec_other", 1700000000, `{"id":1}`, "sha256=9c21515791baf591e45d7a07dd043081d85de11318bf326a8d6b19143d16c3e7"},
+		{"empty body", "whsec_test", 1700000000, "", "sha256=5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{6, 320 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int
+		allowPrivate bool
+		wantOutcome  string
+		wantStatus   int
+		wantRetryIn  time.Duration
+	}{
+		{name: "accepted", status: http.StatusOK, attempts: 1, allowPrivate: true, wantOutcome: "delivered", wantStatus: http.StatusOK},
+		{name: "accepted without content", status: http.StatusNoContent, attempts: 1, allowPrivate: true, wantOutcome: "delivered", wantStatus: http.StatusNoContent},
+		{name: "first failure", status: http.StatusInternalServerError, attempts: 1, allowPrivate: true, wantOutcome: "retried", wantStatus: http.StatusInternalServerError, wantRetryIn: 10 * time.Second},
+		{name: "later failure", status: http.StatusServiceUnavailable, attempts: 4, allowPrivate: true, wantOutcome: "retried", wantStatus: http.StatusServiceUnavailable, wantRetryIn: 80 * time.Second},
+		{name: "redirect", status: http.StatusFound, attempts: 1, allowPrivate: true, wantOutcome: "retried", wantStatus: http.StatusFound, wantRetryIn: 10 * time.Second},
+		{name: "last attempt", status: http.StatusInternalServerError, attempts: 5, allowPrivate: true, wantOutcome: "given up", wantStatus: http.StatusInternalServerError},
+		{name: "private address", status: http.StatusOK, attempts: 1, wantOutcome: "retried", wantRetryIn: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := "whsec_test"
+			delivery := &models.WebhookDelivery{
+				ID:        uuid.New(),
+				WebhookID: uuid.New(),
+				Event:     models.WebhookEventFill,
+				Payload:   []byte(`{"order_id":"o1"}`),
+				Attempts:  tt.attempts,
+				CreatedAt: time.Now(),
+			}
+
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				if tt.status == http.StatusFound {
+					http.Redirect(w, r, "/elsewhere", http.StatusFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			store := &fakeStore{}
+			d := NewDispatcher(store, time.Second, time.Second, 5, 25, tt.allowPrivate)
+			start := time.Now()
+			d.deliver(context.Background(), &repository.DueDelivery{Delivery: delivery, URL: server.URL, Secret: secret})
+
+			var outcome string
+			switch {
+			case store.delivered == 1 && store.failed == 0:
+				outcome = "delivered"
+			case store.failed == 1 && store.retryAt != nil:
+				outcome = "retried"
+			case store.failed == 1:
+				outcome = "given up"
+			}
+			if outcome != tt.wantOutcome {
+				t.Fatalf("outcome = %q (err %v), want %q", outcome, store.attemptErr, tt.wantOutcome)
+			}
+
+			if tt.wantStatus == 0 {
+				if store.statusCode != nil {
+					t.Errorf("status recorded as %d, want none", *store.statusCode)
+				}
+				if !errors.Is(store.attemptErr, errPrivateAddress) {
+					t.Errorf("attempt err = %v, want %v", store.attemptErr, errPrivateAddress)
+				}
+				if received != nil {
+					t.Error("delivered to a private address")
+				}
+			} else if store.statusCode == nil || *store.statusCode != tt.wantStatus {
+				t.Errorf("status recorded as %v, want %d", store.statusCode, tt.wantStatus)
+			}
+
+			if tt.wantRetryIn != 0 {
+				if retryIn := store.retryAt.Sub(start); retryIn < tt.wantRetryIn || retryIn > tt.wantRetryIn+time.Second {
+					t.Errorf("retried in %s, want %s", retryIn, tt.wantRetryIn)
+				}
+			}
+			if store.failed == 1 && store.disableAfter != 25 {
+				t.Errorf("failure counted toward disabling after %d, want 25", store.disableAfter)
+			}
+
+			if received == nil {
+				return
+			}
+			if got := received.Header.Get(HeaderEvent); got != string(delivery.Event) {
+				t.Errorf("%s = %q, want %q", HeaderEvent, got, delivery.Event)
+			}
+			if got := received.Header.Get(HeaderDelivery); got != delivery.ID.String() {
+				t.Errorf("%s = %q, want %q", HeaderDelivery, got, delivery.ID)
+			}
+			timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+			if err != nil {
+				t.Fatalf("%s = %q, want a Unix time", HeaderTimestamp, received.Header.Get(HeaderTimestamp))
+			}
+			if got := received.Header.Get(HeaderSignature); got != Sign(secret, timestamp, body) {
+				t.Errorf("%s = %q does not sign the timestamp and body", HeaderSignature, got)
+			}
+		})
+	}
+}
@@ -1,34 +1,30 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
+	"lfg/shared/idempotency"
 	"lfg/shared/models"
 	"lfg/order-service/repository"
-	pb "lfg/matching-engine/proto"
+	"lfg/order-service/trading"
 )
 
 // OrderHandler handles HTTP requests for order operations
 type OrderHandler struct {
-	repo               *repository.OrderRepository
-	matchingEngineAddr string
-	walletServiceURL   string
+	repo   *repository.OrderRepository
+	trader *trading.Trader
 }
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler(repo *repository.OrderRepository, matchingEngineAddr string, walletServiceURL string) *OrderHandler {
+func NewOrderHandler(repo *repository.OrderRepository, trader *trading.Trader) *OrderHandler {
 	return &OrderHandler{
-		repo:               repo,
-		matchingEngineAddr: matchingEngineAddr,
-		walletServiceURL:   walletServiceURL,
+		repo:   repo,
+		trader: trader,
 	}
 }
 
@@ -59,126 +55,32 @@ func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
-	if req.Quantity <= 0 {
-		respondError(w, "Quantity must be positive", http.StatusBadRequest)
-		return
-	}
-
-	if req.Type == models.OrderTypeLimit && (req.LimitPriceCredits == nil || *req.LimitPriceCredits <= 0) {
-		respondError(w, "Limit price required for limit orders", http.StatusBadRequest)
-		return
-	}
-
-	// Check wallet balance before placing order
-	if req.Type == models.OrderTypeLimit && req.LimitPriceCredits != nil {
-		requiredBalance := float64(req.Quantity) * (*req.LimitPriceCredits)
-		balance, err := h.checkWalletBalance(userIDStr)
-		if err != nil {
-			respondError(w, "Failed to check wallet balance", http.StatusInternalServerError)
-			return
-		}
-
-		if balance < requiredBalance {
-			errorMsg := fmt.Sprintf("Insufficient balance. Required: %.2f credits, Available: %.2f credits", requiredBalance, balance)
-			respondError(w, errorMsg, http.StatusBadRequest)
-			return
-		}
-	}
-
-	// Create order in database
-	order := &models.Order{
-		ID:                uuid.New(),
-		UserID:            userID,
-		ContractID:        req.ContractID,
-		Type:              req.Type,
-		Status:            models.OrderStatusPending,
-		Quantity:          req.Quantity,
-		QuantityFilled:    0,
-		LimitPriceCredits: req.LimitPriceCredits,
-	}
-
-	if err := h.repo.Create(r.Context(), order); err != nil {
-		respondError(w, "Failed to create order", http.StatusInternalServerError)
-		return
-	}
-
-	// Submit to matching engine via gRPC
-	conn, err := grpc.NewClient(h.matchingEngineAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	placement, err := h.trader.Place(r.Context(), userID, req)
 	if err != nil {
-		respondError(w, "Failed to connect to matching engine", http.StatusInternalServerError)
-		return
-	}
-	defer conn.Close()
-
-	client := pb.NewMatchingEngineClient(conn)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Determine order side from contract
-	var contractSide string
-	err = h.repo.GetPool().QueryRow(r.Context(), `
-		SELECT side FROM contracts WHERE id = $1
-	`, req.ContractID).Scan(&contractSide)
-
-	if err != nil {
-		respondError(w, "Failed to fetch contract details", http.StatusInternalServerError)
+		// Failures other than rejections placed nothing, so can be retried
+		var rejection *trading.Rejection
+		if !errors.As(err, &rejection) {
+			idempotency.Retryable(r.Context())
+		}
+		respondTradingError(w, err, "Failed to place order")
 		return
 	}
 
-	orderSide := pb.OrderSide_BUY
-	if contractSide == "NO" {
-		orderSide = pb.OrderSide_SELL
-	}
-
-	// Determine order type
-	orderType := pb.OrderType_MARKET
-	if req.Type == models.OrderTypeLimit {
-		orderType = pb.OrderType_LIMIT
-	}
-
-	limitPrice := 0.0
-	if req.LimitPriceCredits != nil {
-		limitPrice = *req.LimitPriceCredits
-	}
-
-	grpcReq := &pb.PlaceOrderRequest{
-		OrderId:    order.ID.String(),
-		UserId:     userID.String(),
-		ContractId: req.ContractID.String(),
-		Type:       orderType,
-		Side:       orderSide,
-		Quantity:   int32(req.Quantity),
-		LimitPrice: limitPrice,
+	// Return response
+	response := models.OrderPlaceResponse{
+		OrderID:        placement.Order.ID,
+		Status:         placement.Order.Status,
+		QuantityFilled: placement.Order.QuantityFilled,
+		AveragePrice:   placement.AveragePrice,
 	}
 
-	resp, err := client.PlaceOrder(ctx, grpcReq)
-	if err != nil {
-		// Update order status to rejected
-		h.repo.UpdateStatus(r.Context(), order.ID, models.OrderStatusRejected, 0)
-		respondError(w, "Failed to place order", http.StatusInternalServerError)
+	// A pending order is placed, but the matching engine has yet to answer
+	// for it or its fills are yet to be settled
+	if placement.Pending {
+		respondJSON(w, response, http.StatusAccepted)
 		return
 	}
 
-	// Update order based on matching engine response
-	status := models.OrderStatusActive
-	if resp.Status == "FILLED" {
-		status = models.OrderStatusFilled
-	} else if resp.Status == "PARTIALLY_FILLED" {
-		status = models.OrderStatusPartiallyFilled
-	}
-
-	h.repo.UpdateStatus(r.Context(), order.ID, status, int(resp.QuantityFilled))
-
-	// Return response
-	response := models.OrderPlaceResponse{
-		OrderID:        order.ID,
-		Status:         status,
-		QuantityFilled: int(resp.QuantityFilled),
-		AveragePrice:   resp.AveragePrice,
-	}
-
 	respondJSON(w, response, http.StatusCreated)
 }
 
@@ -209,56 +111,8 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get order
-	order, err := h.repo.GetByID(r.Context(), req.OrderID)
-	if err != nil {
-		if err == repository.ErrOrderNotFound {
-			respondError(w, "Order not found", http.StatusNotFound)
-			return
-		}
-		respondError(w, "Failed to get order", http.StatusInternalServerError)
-		return
-	}
-
-	// Verify ownership
-	if order.UserID != userID {
-		respondError(w, "Not authorized to cancel this order", http.StatusForbidden)
-		return
-	}
-
-	// Check if order can be cancelled
-	if order.Status != models.OrderStatusActive && order.Status != models.OrderStatusPartiallyFilled {
-		respondError(w, "Order cannot be cancelled", http.StatusBadRequest)
-		return
-	}
-
-	// Cancel in matching engine
-	conn, err := grpc.NewClient(h.matchingEngineAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		respondError(w, "Failed to connect to matching engine", http.StatusInternalServerError)
-		return
-	}
-	defer conn.Close()
-
-	client := pb.NewMatchingEngineClient(conn)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	grpcReq := &pb.CancelOrderRequest{
-		OrderId:    order.ID.String(),
-		ContractId: order.ContractID.String(),
-	}
-
-	_, err = client.CancelOrder(ctx, grpcReq)
-	if err != nil {
-		respondError(w, "Failed to cancel order", http.StatusInternalServerError)
-		return
-	}
-
-	// Update order status in database
-	if err := h.repo.Cancel(r.Context(), order.ID); err != nil {
-		respondError(w, "Failed to update order status", http.StatusInternalServerError)
+	if _, err := h.trader.Cancel(r.Context(), userID, req.OrderID); err != nil {
+		respondTradingError(w, err, "Failed to cancel order")
 		return
 	}
 
@@ -330,45 +184,6 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, map[string]string{"status": "healthy"}, http.StatusOK)
 }
 
-// checkWalletBalance checks the user's wallet balance via HTTP call to wallet service
-func (h *OrderHandler) checkWalletBalance(userID string) (float64, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	// Create request to wallet service
-	req, err := http.NewRequest(http.MethodGet, h.walletServiceURL+"/balance", nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set user ID header
-	req.Header.Set("X-User-ID", userID)
-
-	// Make request
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("failed to call wallet service: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("wallet service returned status %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var balanceResp struct {
-		Balance float64 `json:"balance"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&balanceResp); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return balanceResp.Balance, nil
-}
-
 // Helper functions
 func respondJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -379,3 +194,25 @@ func respondJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 func respondError(w http.ResponseWriter, message string, statusCode int) {
 	respondJSON(w, map[string]string{"error": message}, statusCode)
 }
+
+// respondTradingError responds with the reason an order or cancellation was
+// rejected, or with message when it failed
+func respondTradingError(w http.ResponseWriter, err error, message string) {
+	var rejection *trading.Rejection
+	if !errors.As(err, &rejection) {
+		log.Printf("%s: %v", message, err)
+		respondError(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch rejection.Reason {
+	case trading.ErrContractNotFound, trading.ErrOrderNotFound:
+		status = http.StatusNotFound
+	case trading.ErrMarketClosed, trading.ErrDuplicateOrder:
+		status = http.StatusConflict
+	case trading.ErrNotOrderOwner:
+		status = http.StatusForbidden
+	}
+	respondError(w, rejection.Message, status)
+}
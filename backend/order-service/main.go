@@ -4,16 +4,28 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"lfg/shared/auth"
 	"lfg/shared/config"
 	"lfg/shared/db"
+	"lfg/shared/idempotency"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 	"lfg/order-service/handlers"
+	tradingv1 "lfg/order-service/proto/trading/v1"
 	"lfg/order-service/repository"
+	"lfg/order-service/trading"
+	pb "lfg/matching-engine/proto"
 )
 
 func main() {
@@ -23,6 +35,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceOrder, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create context
 	ctx := context.Background()
 
@@ -45,26 +64,74 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close(pool)
+	metrics.RegisterPool(pool)
 
 	log.Println("Connected to database successfully")
 
 	// Initialize repository
 	orderRepo := repository.NewOrderRepository(pool)
 
+	// Initialize service authentication
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceOrder, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway, "/orders/", "/"+tradingv1.TradingService_ServiceDesc.ServiceName+"/")
+	serviceGuard.Allow(auth.ServiceFIXGateway, "/"+tradingv1.TradingService_ServiceDesc.ServiceName+"/")
+
+	// Order flow is continuous, so the matching engine connection is kept
+	// open rather than dialled per order
+	conn, err := grpc.NewClient(cfg.MatchingEngineGRPC,
+		grpc.WithTransportCredentials(serviceTokens.TransportCredentials()),
+		grpc.WithPerRPCCredentials(serviceTokens.Credentials(auth.ServiceMatchingEngine)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to matching engine: %v", err)
+	}
+	defer conn.Close()
+	engineClient := pb.NewMatchingEngineClient(conn)
+
+	// Fills and order book changes streamed by the trading API come from NATS
+	natsConn, err := nats.Connect(cfg.NATSURL)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to NATS: %v", err)
+		log.Println("Continuing without NATS (fill and order book streams will be unavailable)")
+	} else {
+		log.Printf("Connected to NATS at %s", cfg.NATSURL)
+		defer natsConn.Close()
+	}
+
 	// Initialize handlers
-	orderHandler := handlers.NewOrderHandler(orderRepo, cfg.MatchingEngineGRPC, cfg.WalletServiceURL)
+	// Fills that cannot be recorded when an order matches are retried until
+	// they are
+	settler := trading.NewSettler(orderRepo)
+	go settler.Run(ctx)
+
+	// Orders the matching engine did not answer for are submitted again
+	// until it does
+	trader := trading.NewTrader(orderRepo, engineClient, settler)
+	go trader.Run(ctx)
+	orderHandler := handlers.NewOrderHandler(orderRepo, trader)
+
+	// Retried placements carrying an Idempotency-Key get the first response,
+	// over HTTP and gRPC
+	idempotent := idempotency.NewMiddleware(idempotency.NewStore(pool), cfg.IdempotencyKeyTTL)
+	go idempotent.Run(ctx)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
-	mux.HandleFunc("/orders/place", orderHandler.PlaceOrder)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
+	mux.Handle("/orders/place", idempotent.Handler(http.HandlerFunc(orderHandler.PlaceOrder)))
 	mux.HandleFunc("/orders/cancel", orderHandler.CancelOrder)
 	mux.HandleFunc("/orders/status", orderHandler.GetOrderStatus)
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceOrder, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -78,6 +145,36 @@ func main() {
 		}
 	}()
 
+	// Serve the trading API the gateway exposes over gRPC and REST, over TLS
+	// as calls carry service tokens
+	serverCreds, err := auth.ServerCredentials(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load gRPC credentials: %v", err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCreds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			serviceGuard.UnaryServerInterceptor(),
+			idempotent.UnaryServerInterceptor(tradingv1.TradingService_PlaceOrder_FullMethodName),
+		),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor(), serviceGuard.StreamServerInterceptor()),
+	)
+	tradingv1.RegisterTradingServiceServer(grpcServer, trading.NewServer(trader, orderRepo, engineClient, natsConn))
+
+	listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	go func() {
+		log.Printf("Order service gRPC listening on port %s...\n", cfg.GRPCPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+
 	// Wait for interrupt
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -91,6 +188,7 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
+	grpcServer.Stop()
 
 	fmt.Println("Server exited")
 }
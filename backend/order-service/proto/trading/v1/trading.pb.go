@@ -0,0 +1,1647 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v6.32.1
+// source: proto/trading/v1/trading.proto
+
+package tradingv1
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Order types
+type OrderType int32
+
+const (
+	OrderType_ORDER_TYPE_UNSPECIFIED OrderType = 0
+	OrderType_ORDER_TYPE_MARKET      OrderType = 1
+	OrderType_ORDER_TYPE_LIMIT       OrderType = 2
+)
+
+// Enum value maps for OrderType.
+var (
+	OrderType_name = map[int32]string{
+		0: "ORDER_TYPE_UNSPECIFIED",
+		1: "ORDER_TYPE_MARKET",
+		2: "ORDER_TYPE_LIMIT",
+	}
+	OrderType_value = map[string]int32{
+		"ORDER_TYPE_UNSPECIFIED": 0,
+		"ORDER_TYPE_MARKET":      1,
+		"ORDER_TYPE_LIMIT":       2,
+	}
+)
+
+func (x OrderType) Enum() *OrderType {
+	p := new(OrderType)
+	*p = x
+	return p
+}
+
+func (x OrderType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_trading_v1_trading_proto_enumTypes[0].Descriptor()
+}
+
+func (OrderType) Type() protoreflect.EnumType {
+	return &file_proto_trading_v1_trading_proto_enumTypes[0]
+}
+
+func (x OrderType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderType.Descriptor instead.
+func (OrderType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{0}
+}
+
+// Order side
+type Side int32
+
+const (
+	Side_SIDE_UNSPECIFIED Side = 0
+	Side_SIDE_BUY         Side = 1
+	Side_SIDE_SELL        Side = 2
+)
+
+// Enum value maps for Side.
+var (
+	Side_name = map[int32]string{
+		0: "SIDE_UNSPECIFIED",
+		1: "SIDE_BUY",
+		2: "SIDE_SELL",
+	}
+	Side_value = map[string]int32{
+		"SIDE_UNSPECIFIED": 0,
+		"SIDE_BUY":         1,
+		"SIDE_SELL":        2,
+	}
+)
+
+func (x Side) Enum() *Side {
+	p := new(Side)
+	*p = x
+	return p
+}
+
+func (x Side) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Side) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_trading_v1_trading_proto_enumTypes[1].Descriptor()
+}
+
+func (Side) Type() protoreflect.EnumType {
+	return &file_proto_trading_v1_trading_proto_enumTypes[1]
+}
+
+func (x Side) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Side.Descriptor instead.
+func (Side) EnumDescriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{1}
+}
+
+// Order status
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_UNSPECIFIED      OrderStatus = 0
+	OrderStatus_ORDER_STATUS_PENDING          OrderStatus = 1
+	OrderStatus_ORDER_STATUS_ACTIVE           OrderStatus = 2
+	OrderStatus_ORDER_STATUS_PARTIALLY_FILLED OrderStatus = 3
+	OrderStatus_ORDER_STATUS_FILLED           OrderStatus = 4
+	OrderStatus_ORDER_STATUS_CANCELLED        OrderStatus = 5
+	OrderStatus_ORDER_STATUS_REJECTED         OrderStatus = 6
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "ORDER_STATUS_UNSPECIFIED",
+		1: "ORDER_STATUS_PENDING",
+		2: "ORDER_STATUS_ACTIVE",
+		3: "ORDER_STATUS_PARTIALLY_FILLED",
+		4: "ORDER_STATUS_FILLED",
+		5: "ORDER_STATUS_CANCELLED",
+		6: "ORDER_STATUS_REJECTED",
+	}
+	OrderStatus_value = map[string]int32{
+		"ORDER_STATUS_UNSPECIFIED":      0,
+		"ORDER_STATUS_PENDING":          1,
+		"ORDER_STATUS_ACTIVE":           2,
+		"ORDER_STATUS_PARTIALLY_FILLED": 3,
+		"ORDER_STATUS_FILLED":           4,
+		"ORDER_STATUS_CANCELLED":        5,
+		"ORDER_STATUS_REJECTED":         6,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_trading_v1_trading_proto_enumTypes[2].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_proto_trading_v1_trading_proto_enumTypes[2]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{2}
+}
+
+// Whether a fill's order rested on the book or took liquidity from it
+type Liquidity int32
+
+const (
+	Liquidity_LIQUIDITY_UNSPECIFIED Liquidity = 0
+	Liquidity_LIQUIDITY_MAKER       Liquidity = 1
+	Liquidity_LIQUIDITY_TAKER       Liquidity = 2
+)
+
+// Enum value maps for Liquidity.
+var (
+	Liquidity_name = map[int32]string{
+		0: "LIQUIDITY_UNSPECIFIED",
+		1: "LIQUIDITY_MAKER",
+		2: "LIQUIDITY_TAKER",
+	}
+	Liquidity_value = map[string]int32{
+		"LIQUIDITY_UNSPECIFIED": 0,
+		"LIQUIDITY_MAKER":       1,
+		"LIQUIDITY_TAKER":       2,
+	}
+)
+
+func (x Liquidity) Enum() *Liquidity {
+	p := new(Liquidity)
+	*p = x
+	return p
+}
+
+func (x Liquidity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Liquidity) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_trading_v1_trading_proto_enumTypes[3].Descriptor()
+}
+
+func (Liquidity) Type() protoreflect.EnumType {
+	return &file_proto_trading_v1_trading_proto_enumTypes[3]
+}
+
+func (x Liquidity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Liquidity.Descriptor instead.
+func (Liquidity) EnumDescriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{3}
+}
+
+// Order is an order of the user
+type Order struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ContractId     string                 `protobuf:"bytes,2,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Type           OrderType              `protobuf:"varint,3,opt,name=type,proto3,enum=lfg.trading.v1.OrderType" json:"type,omitempty"`
+	Side           Side                   `protobuf:"varint,4,opt,name=side,proto3,enum=lfg.trading.v1.Side" json:"side,omitempty"`
+	Status         OrderStatus            `protobuf:"varint,5,opt,name=status,proto3,enum=lfg.trading.v1.OrderStatus" json:"status,omitempty"`
+	Quantity       int32                  `protobuf:"varint,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	QuantityFilled int32                  `protobuf:"varint,7,opt,name=quantity_filled,json=quantityFilled,proto3" json:"quantity_filled,omitempty"`
+	LimitPrice     *float64               `protobuf:"fixed64,8,opt,name=limit_price,json=limitPrice,proto3,oneof" json:"limit_price,omitempty"` // Credits, set for LIMIT orders
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Order) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Order) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *Order) GetType() OrderType {
+	if x != nil {
+		return x.Type
+	}
+	return OrderType_ORDER_TYPE_UNSPECIFIED
+}
+
+func (x *Order) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *Order) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *Order) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Order) GetQuantityFilled() int32 {
+	if x != nil {
+		return x.QuantityFilled
+	}
+	return 0
+}
+
+func (x *Order) GetLimitPrice() float64 {
+	if x != nil && x.LimitPrice != nil {
+		return *x.LimitPrice
+	}
+	return 0
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// PlaceOrderRequest contains order details
+type PlaceOrderRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ContractId string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Type       OrderType              `protobuf:"varint,2,opt,name=type,proto3,enum=lfg.trading.v1.OrderType" json:"type,omitempty"`
+	Side       Side                   `protobuf:"varint,3,opt,name=side,proto3,enum=lfg.trading.v1.Side" json:"side,omitempty"`
+	Quantity   int32                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	LimitPrice *float64               `protobuf:"fixed64,5,opt,name=limit_price,json=limitPrice,proto3,oneof" json:"limit_price,omitempty"` // Required for LIMIT orders
+	// ID for the order, a UUID chosen by the caller to recognise the order's
+	// fills before the call returns. Generated when empty.
+	OrderId       string `protobuf:"bytes,6,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlaceOrderRequest) Reset() {
+	*x = PlaceOrderRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaceOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceOrderRequest) ProtoMessage() {}
+
+func (x *PlaceOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceOrderRequest.ProtoReflect.Descriptor instead.
+func (*PlaceOrderRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PlaceOrderRequest) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *PlaceOrderRequest) GetType() OrderType {
+	if x != nil {
+		return x.Type
+	}
+	return OrderType_ORDER_TYPE_UNSPECIFIED
+}
+
+func (x *PlaceOrderRequest) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *PlaceOrderRequest) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *PlaceOrderRequest) GetLimitPrice() float64 {
+	if x != nil && x.LimitPrice != nil {
+		return *x.LimitPrice
+	}
+	return 0
+}
+
+func (x *PlaceOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+// PlaceOrderResponse contains the placed order
+type PlaceOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	AveragePrice  float64                `protobuf:"fixed64,2,opt,name=average_price,json=averagePrice,proto3" json:"average_price,omitempty"` // Of the quantity filled right away
+	Fills         []*Fill                `protobuf:"bytes,3,rep,name=fills,proto3" json:"fills,omitempty"`                                     // Filled right away, taking liquidity
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlaceOrderResponse) Reset() {
+	*x = PlaceOrderResponse{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaceOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceOrderResponse) ProtoMessage() {}
+
+func (x *PlaceOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceOrderResponse.ProtoReflect.Descriptor instead.
+func (*PlaceOrderResponse) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PlaceOrderResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+func (x *PlaceOrderResponse) GetAveragePrice() float64 {
+	if x != nil {
+		return x.AveragePrice
+	}
+	return 0
+}
+
+func (x *PlaceOrderResponse) GetFills() []*Fill {
+	if x != nil {
+		return x.Fills
+	}
+	return nil
+}
+
+// CancelOrderRequest identifies the order to cancel
+type CancelOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderRequest) Reset() {
+	*x = CancelOrderRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderRequest) ProtoMessage() {}
+
+func (x *CancelOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderRequest.ProtoReflect.Descriptor instead.
+func (*CancelOrderRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CancelOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+// CancelOrderResponse contains the cancelled order
+type CancelOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderResponse) Reset() {
+	*x = CancelOrderResponse{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderResponse) ProtoMessage() {}
+
+func (x *CancelOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderResponse.ProtoReflect.Descriptor instead.
+func (*CancelOrderResponse) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CancelOrderResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+// AmendOrderRequest contains the new quantity, price or both of an order
+type AmendOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Quantity      *int32                 `protobuf:"varint,2,opt,name=quantity,proto3,oneof" json:"quantity,omitempty"` // New total quantity, including what has filled
+	LimitPrice    *float64               `protobuf:"fixed64,3,opt,name=limit_price,json=limitPrice,proto3,oneof" json:"limit_price,omitempty"`
+	ReplacementId string                 `protobuf:"bytes,4,opt,name=replacement_id,json=replacementId,proto3" json:"replacement_id,omitempty"` // ID for the replacement, as PlaceOrderRequest.order_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AmendOrderRequest) Reset() {
+	*x = AmendOrderRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AmendOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AmendOrderRequest) ProtoMessage() {}
+
+func (x *AmendOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AmendOrderRequest.ProtoReflect.Descriptor instead.
+func (*AmendOrderRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AmendOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *AmendOrderRequest) GetQuantity() int32 {
+	if x != nil && x.Quantity != nil {
+		return *x.Quantity
+	}
+	return 0
+}
+
+func (x *AmendOrderRequest) GetLimitPrice() float64 {
+	if x != nil && x.LimitPrice != nil {
+		return *x.LimitPrice
+	}
+	return 0
+}
+
+func (x *AmendOrderRequest) GetReplacementId() string {
+	if x != nil {
+		return x.ReplacementId
+	}
+	return ""
+}
+
+// AmendOrderResponse contains the cancelled order and its replacement
+type AmendOrderResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CancelledOrder *Order                 `protobuf:"bytes,1,opt,name=cancelled_order,json=cancelledOrder,proto3" json:"cancelled_order,omitempty"`
+	Order          *Order                 `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
+	AveragePrice   float64                `protobuf:"fixed64,3,opt,name=average_price,json=averagePrice,proto3" json:"average_price,omitempty"` // Of the quantity the replacement filled right away
+	Fills          []*Fill                `protobuf:"bytes,4,rep,name=fills,proto3" json:"fills,omitempty"`                                     // Filled right away by the replacement
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AmendOrderResponse) Reset() {
+	*x = AmendOrderResponse{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AmendOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AmendOrderResponse) ProtoMessage() {}
+
+func (x *AmendOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AmendOrderResponse.ProtoReflect.Descriptor instead.
+func (*AmendOrderResponse) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AmendOrderResponse) GetCancelledOrder() *Order {
+	if x != nil {
+		return x.CancelledOrder
+	}
+	return nil
+}
+
+func (x *AmendOrderResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+func (x *AmendOrderResponse) GetAveragePrice() float64 {
+	if x != nil {
+		return x.AveragePrice
+	}
+	return 0
+}
+
+func (x *AmendOrderResponse) GetFills() []*Fill {
+	if x != nil {
+		return x.Fills
+	}
+	return nil
+}
+
+// GetOrderRequest identifies the order to get
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+// GetOrderResponse contains the order
+type GetOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Order         *Order                 `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderResponse) Reset() {
+	*x = GetOrderResponse{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderResponse) ProtoMessage() {}
+
+func (x *GetOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderResponse.ProtoReflect.Descriptor instead.
+func (*GetOrderResponse) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetOrderResponse) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+// ListOrdersRequest filters the orders listed
+type ListOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        OrderStatus            `protobuf:"varint,1,opt,name=status,proto3,enum=lfg.trading.v1.OrderStatus" json:"status,omitempty"` // All statuses when unspecified
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                                   // At most 100, the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOrdersRequest) Reset() {
+	*x = ListOrdersRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersRequest) ProtoMessage() {}
+
+func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersRequest.ProtoReflect.Descriptor instead.
+func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListOrdersRequest) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *ListOrdersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// ListOrdersResponse contains the user's orders
+type ListOrdersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Orders        []*Order               `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOrdersResponse) Reset() {
+	*x = ListOrdersResponse{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersResponse) ProtoMessage() {}
+
+func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersResponse.ProtoReflect.Descriptor instead.
+func (*ListOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListOrdersResponse) GetOrders() []*Order {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+// StreamFillsRequest filters the fills streamed
+type StreamFillsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"` // All contracts when empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamFillsRequest) Reset() {
+	*x = StreamFillsRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamFillsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamFillsRequest) ProtoMessage() {}
+
+func (x *StreamFillsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamFillsRequest.ProtoReflect.Descriptor instead.
+func (*StreamFillsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamFillsRequest) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+// Fill is one side of a trade, for the user who owned the order
+type Fill struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TradeId       string                 `protobuf:"bytes,1,opt,name=trade_id,json=tradeId,proto3" json:"trade_id,omitempty"`
+	OrderId       string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	ContractId    string                 `protobuf:"bytes,3,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Liquidity     Liquidity              `protobuf:"varint,4,opt,name=liquidity,proto3,enum=lfg.trading.v1.Liquidity" json:"liquidity,omitempty"`
+	Quantity      int32                  `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Price         float64                `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	ExecutedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=executed_at,json=executedAt,proto3" json:"executed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Fill) Reset() {
+	*x = Fill{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Fill) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Fill) ProtoMessage() {}
+
+func (x *Fill) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Fill.ProtoReflect.Descriptor instead.
+func (*Fill) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Fill) GetTradeId() string {
+	if x != nil {
+		return x.TradeId
+	}
+	return ""
+}
+
+func (x *Fill) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *Fill) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *Fill) GetLiquidity() Liquidity {
+	if x != nil {
+		return x.Liquidity
+	}
+	return Liquidity_LIQUIDITY_UNSPECIFIED
+}
+
+func (x *Fill) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *Fill) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Fill) GetExecutedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExecutedAt
+	}
+	return nil
+}
+
+// StreamBookRequest identifies the order book to stream
+type StreamBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Depth         int32                  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"` // Price levels per side in snapshots, 50 by default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamBookRequest) Reset() {
+	*x = StreamBookRequest{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamBookRequest) ProtoMessage() {}
+
+func (x *StreamBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamBookRequest.ProtoReflect.Descriptor instead.
+func (*StreamBookRequest) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StreamBookRequest) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *StreamBookRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+// BookUpdate is a snapshot of an order book or a change to it. A new
+// snapshot replaces the book after deltas were missed.
+type BookUpdate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Update:
+	//
+	//	*BookUpdate_Snapshot
+	//	*BookUpdate_Delta
+	Update        isBookUpdate_Update `protobuf_oneof:"update"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookUpdate) Reset() {
+	*x = BookUpdate{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookUpdate) ProtoMessage() {}
+
+func (x *BookUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookUpdate.ProtoReflect.Descriptor instead.
+func (*BookUpdate) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BookUpdate) GetUpdate() isBookUpdate_Update {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *BookUpdate) GetSnapshot() *BookSnapshot {
+	if x != nil {
+		if x, ok := x.Update.(*BookUpdate_Snapshot); ok {
+			return x.Snapshot
+		}
+	}
+	return nil
+}
+
+func (x *BookUpdate) GetDelta() *BookDelta {
+	if x != nil {
+		if x, ok := x.Update.(*BookUpdate_Delta); ok {
+			return x.Delta
+		}
+	}
+	return nil
+}
+
+type isBookUpdate_Update interface {
+	isBookUpdate_Update()
+}
+
+type BookUpdate_Snapshot struct {
+	Snapshot *BookSnapshot `protobuf:"bytes,1,opt,name=snapshot,proto3,oneof"`
+}
+
+type BookUpdate_Delta struct {
+	Delta *BookDelta `protobuf:"bytes,2,opt,name=delta,proto3,oneof"`
+}
+
+func (*BookUpdate_Snapshot) isBookUpdate_Update() {}
+
+func (*BookUpdate_Delta) isBookUpdate_Update() {}
+
+// BookSnapshot is the order book at a sequence. Deltas with a sequence at or
+// below it are already reflected in it.
+type BookSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Bids          []*PriceLevel          `protobuf:"bytes,3,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks          []*PriceLevel          `protobuf:"bytes,4,rep,name=asks,proto3" json:"asks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookSnapshot) Reset() {
+	*x = BookSnapshot{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookSnapshot) ProtoMessage() {}
+
+func (x *BookSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookSnapshot.ProtoReflect.Descriptor instead.
+func (*BookSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *BookSnapshot) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *BookSnapshot) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *BookSnapshot) GetBids() []*PriceLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *BookSnapshot) GetAsks() []*PriceLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+// BookDelta lists the price levels changed by one operation on a book. Its
+// sequence is one more than the previous delta's.
+type BookDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContractId    string                 `protobuf:"bytes,1,opt,name=contract_id,json=contractId,proto3" json:"contract_id,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Bids          []*PriceLevel          `protobuf:"bytes,3,rep,name=bids,proto3" json:"bids,omitempty"` // A quantity of zero removes the level
+	Asks          []*PriceLevel          `protobuf:"bytes,4,rep,name=asks,proto3" json:"asks,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookDelta) Reset() {
+	*x = BookDelta{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookDelta) ProtoMessage() {}
+
+func (x *BookDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookDelta.ProtoReflect.Descriptor instead.
+func (*BookDelta) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *BookDelta) GetContractId() string {
+	if x != nil {
+		return x.ContractId
+	}
+	return ""
+}
+
+func (x *BookDelta) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *BookDelta) GetBids() []*PriceLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *BookDelta) GetAsks() []*PriceLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+func (x *BookDelta) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// PriceLevel is the quantity resting at a price
+type PriceLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceLevel) Reset() {
+	*x = PriceLevel{}
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceLevel) ProtoMessage() {}
+
+func (x *PriceLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_trading_v1_trading_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceLevel.ProtoReflect.Descriptor instead.
+func (*PriceLevel) Descriptor() ([]byte, []int) {
+	return file_proto_trading_v1_trading_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PriceLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+var File_proto_trading_v1_trading_proto protoreflect.FileDescriptor
+
+const file_proto_trading_v1_trading_proto_rawDesc = "" +
+	"\n" +
+	"\x1eproto/trading/v1/trading.proto\x12\x0elfg.trading.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb7\x03\n" +
+	"\x05Order\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vcontract_id\x18\x02 \x01(\tR\n" +
+	"contractId\x12-\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x19.lfg.trading.v1.OrderTypeR\x04type\x12(\n" +
+	"\x04side\x18\x04 \x01(\x0e2\x14.lfg.trading.v1.SideR\x04side\x123\n" +
+	"\x06status\x18\x05 \x01(\x0e2\x1b.lfg.trading.v1.OrderStatusR\x06status\x12\x1a\n" +
+	"\bquantity\x18\x06 \x01(\x05R\bquantity\x12'\n" +
+	"\x0fquantity_filled\x18\a \x01(\x05R\x0equantityFilled\x12$\n" +
+	"\vlimit_price\x18\b \x01(\x01H\x00R\n" +
+	"limitPrice\x88\x01\x01\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x0e\n" +
+	"\f_limit_price\"\xfa\x01\n" +
+	"\x11PlaceOrderRequest\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\x12-\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x19.lfg.trading.v1.OrderTypeR\x04type\x12(\n" +
+	"\x04side\x18\x03 \x01(\x0e2\x14.lfg.trading.v1.SideR\x04side\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x05R\bquantity\x12$\n" +
+	"\vlimit_price\x18\x05 \x01(\x01H\x00R\n" +
+	"limitPrice\x88\x01\x01\x12\x19\n" +
+	"\border_id\x18\x06 \x01(\tR\aorderIdB\x0e\n" +
+	"\f_limit_price\"\x92\x01\n" +
+	"\x12PlaceOrderResponse\x12+\n" +
+	"\x05order\x18\x01 \x01(\v2\x15.lfg.trading.v1.OrderR\x05order\x12#\n" +
+	"\raverage_price\x18\x02 \x01(\x01R\faveragePrice\x12*\n" +
+	"\x05fills\x18\x03 \x03(\v2\x14.lfg.trading.v1.FillR\x05fills\"/\n" +
+	"\x12CancelOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"B\n" +
+	"\x13CancelOrderResponse\x12+\n" +
+	"\x05order\x18\x01 \x01(\v2\x15.lfg.trading.v1.OrderR\x05order\"\xb9\x01\n" +
+	"\x11AmendOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x1f\n" +
+	"\bquantity\x18\x02 \x01(\x05H\x00R\bquantity\x88\x01\x01\x12$\n" +
+	"\vlimit_price\x18\x03 \x01(\x01H\x01R\n" +
+	"limitPrice\x88\x01\x01\x12%\n" +
+	"\x0ereplacement_id\x18\x04 \x01(\tR\rreplacementIdB\v\n" +
+	"\t_quantityB\x0e\n" +
+	"\f_limit_price\"\xd2\x01\n" +
+	"\x12AmendOrderResponse\x12>\n" +
+	"\x0fcancelled_order\x18\x01 \x01(\v2\x15.lfg.trading.v1.OrderR\x0ecancelledOrder\x12+\n" +
+	"\x05order\x18\x02 \x01(\v2\x15.lfg.trading.v1.OrderR\x05order\x12#\n" +
+	"\raverage_price\x18\x03 \x01(\x01R\faveragePrice\x12*\n" +
+	"\x05fills\x18\x04 \x03(\v2\x14.lfg.trading.v1.FillR\x05fills\",\n" +
+	"\x0fGetOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"?\n" +
+	"\x10GetOrderResponse\x12+\n" +
+	"\x05order\x18\x01 \x01(\v2\x15.lfg.trading.v1.OrderR\x05order\"^\n" +
+	"\x11ListOrdersRequest\x123\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x1b.lfg.trading.v1.OrderStatusR\x06status\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"C\n" +
+	"\x12ListOrdersResponse\x12-\n" +
+	"\x06orders\x18\x01 \x03(\v2\x15.lfg.trading.v1.OrderR\x06orders\"5\n" +
+	"\x12StreamFillsRequest\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\"\x85\x02\n" +
+	"\x04Fill\x12\x19\n" +
+	"\btrade_id\x18\x01 \x01(\tR\atradeId\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\tR\aorderId\x12\x1f\n" +
+	"\vcontract_id\x18\x03 \x01(\tR\n" +
+	"contractId\x127\n" +
+	"\tliquidity\x18\x04 \x01(\x0e2\x19.lfg.trading.v1.LiquidityR\tliquidity\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x05R\bquantity\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x01R\x05price\x12;\n" +
+	"\vexecuted_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"executedAt\"J\n" +
+	"\x11StreamBookRequest\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\x05R\x05depth\"\x85\x01\n" +
+	"\n" +
+	"BookUpdate\x12:\n" +
+	"\bsnapshot\x18\x01 \x01(\v2\x1c.lfg.trading.v1.BookSnapshotH\x00R\bsnapshot\x121\n" +
+	"\x05delta\x18\x02 \x01(\v2\x19.lfg.trading.v1.BookDeltaH\x00R\x05deltaB\b\n" +
+	"\x06update\"\xab\x01\n" +
+	"\fBookSnapshot\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\x12\x1a\n" +
+	"\bsequence\x18\x02 \x01(\x04R\bsequence\x12.\n" +
+	"\x04bids\x18\x03 \x03(\v2\x1a.lfg.trading.v1.PriceLevelR\x04bids\x12.\n" +
+	"\x04asks\x18\x04 \x03(\v2\x1a.lfg.trading.v1.PriceLevelR\x04asks\"\xe2\x01\n" +
+	"\tBookDelta\x12\x1f\n" +
+	"\vcontract_id\x18\x01 \x01(\tR\n" +
+	"contractId\x12\x1a\n" +
+	"\bsequence\x18\x02 \x01(\x04R\bsequence\x12.\n" +
+	"\x04bids\x18\x03 \x03(\v2\x1a.lfg.trading.v1.PriceLevelR\x04bids\x12.\n" +
+	"\x04asks\x18\x04 \x03(\v2\x1a.lfg.trading.v1.PriceLevelR\x04asks\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\">\n" +
+	"\n" +
+	"PriceLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity*T\n" +
+	"\tOrderType\x12\x1a\n" +
+	"\x16ORDER_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11ORDER_TYPE_MARKET\x10\x01\x12\x14\n" +
+	"\x10ORDER_TYPE_LIMIT\x10\x02*9\n" +
+	"\x04Side\x12\x14\n" +
+	"\x10SIDE_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bSIDE_BUY\x10\x01\x12\r\n" +
+	"\tSIDE_SELL\x10\x02*\xd1\x01\n" +
+	"\vOrderStatus\x12\x1c\n" +
+	"\x18ORDER_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14ORDER_STATUS_PENDING\x10\x01\x12\x17\n" +
+	"\x13ORDER_STATUS_ACTIVE\x10\x02\x12!\n" +
+	"\x1dORDER_STATUS_PARTIALLY_FILLED\x10\x03\x12\x17\n" +
+	"\x13ORDER_STATUS_FILLED\x10\x04\x12\x1a\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\x05\x12\x19\n" +
+	"\x15ORDER_STATUS_REJECTED\x10\x06*P\n" +
+	"\tLiquidity\x12\x19\n" +
+	"\x15LIQUIDITY_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fLIQUIDITY_MAKER\x10\x01\x12\x13\n" +
+	"\x0fLIQUIDITY_TAKER\x10\x022\x9d\x06\n" +
+	"\x0eTradingService\x12j\n" +
+	"\n" +
+	"PlaceOrder\x12!.lfg.trading.v1.PlaceOrderRequest\x1a\".lfg.trading.v1.PlaceOrderResponse\"\x15\x82\xd3\xe4\x93\x02\x0f:\x01*\"\n" +
+	"/v1/orders\x12u\n" +
+	"\vCancelOrder\x12\".lfg.trading.v1.CancelOrderRequest\x1a#.lfg.trading.v1.CancelOrderResponse\"\x1d\x82\xd3\xe4\x93\x02\x17*\x15/v1/orders/{order_id}\x12u\n" +
+	"\n" +
+	"AmendOrder\x12!.lfg.trading.v1.AmendOrderRequest\x1a\".lfg.trading.v1.AmendOrderResponse\" \x82\xd3\xe4\x93\x02\x1a:\x01*2\x15/v1/orders/{order_id}\x12l\n" +
+	"\bGetOrder\x12\x1f.lfg.trading.v1.GetOrderRequest\x1a .lfg.trading.v1.GetOrderResponse\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/orders/{order_id}\x12g\n" +
+	"\n" +
+	"ListOrders\x12!.lfg.trading.v1.ListOrdersRequest\x1a\".lfg.trading.v1.ListOrdersResponse\"\x12\x82\xd3\xe4\x93\x02\f\x12\n" +
+	"/v1/orders\x12c\n" +
+	"\vStreamFills\x12\".lfg.trading.v1.StreamFillsRequest\x1a\x14.lfg.trading.v1.Fill\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/v1/fills:stream0\x01\x12u\n" +
+	"\n" +
+	"StreamBook\x12!.lfg.trading.v1.StreamBookRequest\x1a\x1a.lfg.trading.v1.BookUpdate\"&\x82\xd3\xe4\x93\x02 \x12\x1e/v1/books/{contract_id}:stream0\x01B.Z,lfg/order-service/proto/trading/v1;tradingv1b\x06proto3"
+
+var (
+	file_proto_trading_v1_trading_proto_rawDescOnce sync.Once
+	file_proto_trading_v1_trading_proto_rawDescData []byte
+)
+
+func file_proto_trading_v1_trading_proto_rawDescGZIP() []byte {
+	file_proto_trading_v1_trading_proto_rawDescOnce.Do(func() {
+		file_proto_trading_v1_trading_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_trading_v1_trading_proto_rawDesc), len(file_proto_trading_v1_trading_proto_rawDesc)))
+	})
+	return file_proto_trading_v1_trading_proto_rawDescData
+}
+
+var file_proto_trading_v1_trading_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proto_trading_v1_trading_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_proto_trading_v1_trading_proto_goTypes = []any{
+	(OrderType)(0),                // 0: lfg.trading.v1.OrderType
+	(Side)(0),                     // 1: lfg.trading.v1.Side
+	(OrderStatus)(0),              // 2: lfg.trading.v1.OrderStatus
+	(Liquidity)(0),                // 3: lfg.trading.v1.Liquidity
+	(*Order)(nil),                 // 4: lfg.trading.v1.Order
+	(*PlaceOrderRequest)(nil),     // 5: lfg.trading.v1.PlaceOrderRequest
+	(*PlaceOrderResponse)(nil),    // 6: lfg.trading.v1.PlaceOrderResponse
+	(*CancelOrderRequest)(nil),    // 7: lfg.trading.v1.CancelOrderRequest
+	(*CancelOrderResponse)(nil),   // 8: lfg.trading.v1.CancelOrderResponse
+	(*AmendOrderRequest)(nil),     // 9: lfg.trading.v1.AmendOrderRequest
+	(*AmendOrderResponse)(nil),    // 10: lfg.trading.v1.AmendOrderResponse
+	(*GetOrderRequest)(nil),       // 11: lfg.trading.v1.GetOrderRequest
+	(*GetOrderResponse)(nil),      // 12: lfg.trading.v1.GetOrderResponse
+	(*ListOrdersRequest)(nil),     // 13: lfg.trading.v1.ListOrdersRequest
+	(*ListOrdersResponse)(nil),    // 14: lfg.trading.v1.ListOrdersResponse
+	(*StreamFillsRequest)(nil),    // 15: lfg.trading.v1.StreamFillsRequest
+	(*Fill)(nil),                  // 16: lfg.trading.v1.Fill
+	(*StreamBookRequest)(nil),     // 17: lfg.trading.v1.StreamBookRequest
+	(*BookUpdate)(nil),            // 18: lfg.trading.v1.BookUpdate
+	(*BookSnapshot)(nil),          // 19: lfg.trading.v1.BookSnapshot
+	(*BookDelta)(nil),             // 20: lfg.trading.v1.BookDelta
+	(*PriceLevel)(nil),            // 21: lfg.trading.v1.PriceLevel
+	(*timestamppb.Timestamp)(nil), // 22: google.protobuf.Timestamp
+}
+var file_proto_trading_v1_trading_proto_depIdxs = []int32{
+	0,  // 0: lfg.trading.v1.Order.type:type_name -> lfg.trading.v1.OrderType
+	1,  // 1: lfg.trading.v1.Order.side:type_name -> lfg.trading.v1.Side
+	2,  // 2: lfg.trading.v1.Order.status:type_name -> lfg.trading.v1.OrderStatus
+	22, // 3: lfg.trading.v1.Order.created_at:type_name -> google.protobuf.Timestamp
+	22, // 4: lfg.trading.v1.Order.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: lfg.trading.v1.PlaceOrderRequest.type:type_name -> lfg.trading.v1.OrderType
+	1,  // 6: lfg.trading.v1.PlaceOrderRequest.side:type_name -> lfg.trading.v1.Side
+	4,  // 7: lfg.trading.v1.PlaceOrderResponse.order:type_name -> lfg.trading.v1.Order
+	16, // 8: lfg.trading.v1.PlaceOrderResponse.fills:type_name -> lfg.trading.v1.Fill
+	4,  // 9: lfg.trading.v1.CancelOrderResponse.order:type_name -> lfg.trading.v1.Order
+	4,  // 10: lfg.trading.v1.AmendOrderResponse.cancelled_order:type_name -> lfg.trading.v1.Order
+	4,  // 11: lfg.trading.v1.AmendOrderResponse.order:type_name -> lfg.trading.v1.Order
+	16, // 12: lfg.trading.v1.AmendOrderResponse.fills:type_name -> lfg.trading.v1.Fill
+	4,  // 13: lfg.trading.v1.GetOrderResponse.order:type_name -> lfg.trading.v1.Order
+	2,  // 14: lfg.trading.v1.ListOrdersRequest.status:type_name -> lfg.trading.v1.OrderStatus
+	4,  // 15: lfg.trading.v1.ListOrdersResponse.orders:type_name -> lfg.trading.v1.Order
+	3,  // 16: lfg.trading.v1.Fill.liquidity:type_name -> lfg.trading.v1.Liquidity
+	22, // 17: lfg.trading.v1.Fill.executed_at:type_name -> google.protobuf.Timestamp
+	19, // 18: lfg.trading.v1.BookUpdate.snapshot:type_name -> lfg.trading.v1.BookSnapshot
+	20, // 19: lfg.trading.v1.BookUpdate.delta:type_name -> lfg.trading.v1.BookDelta
+	21, // 20: lfg.trading.v1.BookSnapshot.bids:type_name -> lfg.trading.v1.PriceLevel
+	21, // 21: lfg.trading.v1.BookSnapshot.asks:type_name -> lfg.trading.v1.PriceLevel
+	21, // 22: lfg.trading.v1.BookDelta.bids:type_name -> lfg.trading.v1.PriceLevel
+	21, // 23: lfg.trading.v1.BookDelta.asks:type_name -> lfg.trading.v1.PriceLevel
+	22, // 24: lfg.trading.v1.BookDelta.timestamp:type_name -> google.protobuf.Timestamp
+	5,  // 25: lfg.trading.v1.TradingService.PlaceOrder:input_type -> lfg.trading.v1.PlaceOrderRequest
+	7,  // 26: lfg.trading.v1.TradingService.CancelOrder:input_type -> lfg.trading.v1.CancelOrderRequest
+	9,  // 27: lfg.trading.v1.TradingService.AmendOrder:input_type -> lfg.trading.v1.AmendOrderRequest
+	11, // 28: lfg.trading.v1.TradingService.GetOrder:input_type -> lfg.trading.v1.GetOrderRequest
+	13, // 29: lfg.trading.v1.TradingService.ListOrders:input_type -> lfg.trading.v1.ListOrdersRequest
+	15, // 30: lfg.trading.v1.TradingService.StreamFills:input_type -> lfg.trading.v1.StreamFillsRequest
+	17, // 31: lfg.trading.v1.TradingService.StreamBook:input_type -> lfg.trading.v1.StreamBookRequest
+	6,  // 32: lfg.trading.v1.TradingService.PlaceOrder:output_type -> lfg.trading.v1.PlaceOrderResponse
+	8,  // 33: lfg.trading.v1.TradingService.CancelOrder:output_type -> lfg.trading.v1.CancelOrderResponse
+	10, // 34: lfg.trading.v1.TradingService.AmendOrder:output_type -> lfg.trading.v1.AmendOrderResponse
+	12, // 35: lfg.trading.v1.TradingService.GetOrder:output_type -> lfg.trading.v1.GetOrderResponse
+	14, // 36: lfg.trading.v1.TradingService.ListOrders:output_type -> lfg.trading.v1.ListOrdersResponse
+	16, // 37: lfg.trading.v1.TradingService.StreamFills:output_type -> lfg.trading.v1.Fill
+	18, // 38: lfg.trading.v1.TradingService.StreamBook:output_type -> lfg.trading.v1.BookUpdate
+	32, // [32:39] is the sub-list for method output_type
+	25, // [25:32] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
+}
+
+func init() { file_proto_trading_v1_trading_proto_init() }
+func file_proto_trading_v1_trading_proto_init() {
+	if File_proto_trading_v1_trading_proto != nil {
+		return
+	}
+	file_proto_trading_v1_trading_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_trading_v1_trading_proto_msgTypes[1].OneofWrappers = []any{}
+	file_proto_trading_v1_trading_proto_msgTypes[5].OneofWrappers = []any{}
+	file_proto_trading_v1_trading_proto_msgTypes[14].OneofWrappers = []any{
+		(*BookUpdate_Snapshot)(nil),
+		(*BookUpdate_Delta)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_trading_v1_trading_proto_rawDesc), len(file_proto_trading_v1_trading_proto_rawDesc)),
+			NumEnums:      4,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_trading_v1_trading_proto_goTypes,
+		DependencyIndexes: file_proto_trading_v1_trading_proto_depIdxs,
+		EnumInfos:         file_proto_trading_v1_trading_proto_enumTypes,
+		MessageInfos:      file_proto_trading_v1_trading_proto_msgTypes,
+	}.Build()
+	File_proto_trading_v1_trading_proto = out.File
+	file_proto_trading_v1_trading_proto_goTypes = nil
+	file_proto_trading_v1_trading_proto_depIdxs = nil
+}
@@ -0,0 +1,404 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.32.1
+// source: proto/trading/v1/trading.proto
+
+package tradingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TradingService_PlaceOrder_FullMethodName  = "/lfg.trading.v1.TradingService/PlaceOrder"
+	TradingService_CancelOrder_FullMethodName = "/lfg.trading.v1.TradingService/CancelOrder"
+	TradingService_AmendOrder_FullMethodName  = "/lfg.trading.v1.TradingService/AmendOrder"
+	TradingService_GetOrder_FullMethodName    = "/lfg.trading.v1.TradingService/GetOrder"
+	TradingService_ListOrders_FullMethodName  = "/lfg.trading.v1.TradingService/ListOrders"
+	TradingService_StreamFills_FullMethodName = "/lfg.trading.v1.TradingService/StreamFills"
+	TradingService_StreamBook_FullMethodName  = "/lfg.trading.v1.TradingService/StreamBook"
+)
+
+// TradingServiceClient is the client API for TradingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TradingService is the public trading API. The API gateway serves it over
+// gRPC, and over REST with the HTTP mappings below, so both share this
+// definition. Every call is made for the authenticated user. Calls refused
+// for a reason of the user's making carry a google.rpc.ErrorInfo detail in
+// the lfg.trading domain whose reason is one of INVALID_ORDER,
+// CONTRACT_NOT_FOUND, MARKET_CLOSED, INSUFFICIENT_FUNDS, DUPLICATE_ORDER,
+// ORDER_NOT_FOUND, NOT_ORDER_OWNER or NOT_CANCELLABLE.
+type TradingServiceClient interface {
+	// PlaceOrder places an order; what it fills right away is settled before
+	// it returns. Until it fills or is cancelled an order holds the credits it
+	// can cost, at the maximum price of 1 for a market buy, or the contracts
+	// it sells. An order matched but not yet settled is returned with what it
+	// filled and settled once it can be. An order the matching engine has not
+	// answered for is returned as ORDER_STATUS_PENDING and submitted again
+	// until it answers.
+	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error)
+	// CancelOrder cancels the unfilled remainder of an order
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// AmendOrder changes the quantity or limit price of a resting limit order.
+	// The order is cancelled and replaced by a new one for the rest of the
+	// amended quantity, which loses the original's time priority.
+	AmendOrder(ctx context.Context, in *AmendOrderRequest, opts ...grpc.CallOption) (*AmendOrderResponse, error)
+	// GetOrder gets one of the user's orders
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*GetOrderResponse, error)
+	// ListOrders lists the user's orders, newest first
+	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
+	// StreamFills streams the user's fills as trades happen
+	StreamFills(ctx context.Context, in *StreamFillsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Fill], error)
+	// StreamBook streams a snapshot of a contract's order book, then the
+	// changes to it
+	StreamBook(ctx context.Context, in *StreamBookRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookUpdate], error)
+}
+
+type tradingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTradingServiceClient(cc grpc.ClientConnInterface) TradingServiceClient {
+	return &tradingServiceClient{cc}
+}
+
+func (c *tradingServiceClient) PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlaceOrderResponse)
+	err := c.cc.Invoke(ctx, TradingService_PlaceOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradingServiceClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderResponse)
+	err := c.cc.Invoke(ctx, TradingService_CancelOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradingServiceClient) AmendOrder(ctx context.Context, in *AmendOrderRequest, opts ...grpc.CallOption) (*AmendOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AmendOrderResponse)
+	err := c.cc.Invoke(ctx, TradingService_AmendOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradingServiceClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*GetOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderResponse)
+	err := c.cc.Invoke(ctx, TradingService_GetOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradingServiceClient) ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOrdersResponse)
+	err := c.cc.Invoke(ctx, TradingService_ListOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradingServiceClient) StreamFills(ctx context.Context, in *StreamFillsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Fill], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TradingService_ServiceDesc.Streams[0], TradingService_StreamFills_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamFillsRequest, Fill]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TradingService_StreamFillsClient = grpc.ServerStreamingClient[Fill]
+
+func (c *tradingServiceClient) StreamBook(ctx context.Context, in *StreamBookRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TradingService_ServiceDesc.Streams[1], TradingService_StreamBook_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamBookRequest, BookUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TradingService_StreamBookClient = grpc.ServerStreamingClient[BookUpdate]
+
+// TradingServiceServer is the server API for TradingService service.
+// All implementations must embed UnimplementedTradingServiceServer
+// for forward compatibility.
+//
+// TradingService is the public trading API. The API gateway serves it over
+// gRPC, and over REST with the HTTP mappings below, so both share this
+// definition. Every call is made for the authenticated user. Calls refused
+// for a reason of the user's making carry a google.rpc.ErrorInfo detail in
+// the lfg.trading domain whose reason is one of INVALID_ORDER,
+// CONTRACT_NOT_FOUND, MARKET_CLOSED, INSUFFICIENT_FUNDS, DUPLICATE_ORDER,
+// ORDER_NOT_FOUND, NOT_ORDER_OWNER or NOT_CANCELLABLE.
+type TradingServiceServer interface {
+	// PlaceOrder places an order; what it fills right away is settled before
+	// it returns. Until it fills or is cancelled an order holds the credits it
+	// can cost, at the maximum price of 1 for a market buy, or the contracts
+	// it sells. An order matched but not yet settled is returned with what it
+	// filled and settled once it can be. An order the matching engine has not
+	// answered for is returned as ORDER_STATUS_PENDING and submitted again
+	// until it answers.
+	PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error)
+	// CancelOrder cancels the unfilled remainder of an order
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// AmendOrder changes the quantity or limit price of a resting limit order.
+	// The order is cancelled and replaced by a new one for the rest of the
+	// amended quantity, which loses the original's time priority.
+	AmendOrder(context.Context, *AmendOrderRequest) (*AmendOrderResponse, error)
+	// GetOrder gets one of the user's orders
+	GetOrder(context.Context, *GetOrderRequest) (*GetOrderResponse, error)
+	// ListOrders lists the user's orders, newest first
+	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
+	// StreamFills streams the user's fills as trades happen
+	StreamFills(*StreamFillsRequest, grpc.ServerStreamingServer[Fill]) error
+	// StreamBook streams a snapshot of a contract's order book, then the
+	// changes to it
+	StreamBook(*StreamBookRequest, grpc.ServerStreamingServer[BookUpdate]) error
+	mustEmbedUnimplementedTradingServiceServer()
+}
+
+// UnimplementedTradingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTradingServiceServer struct{}
+
+func (UnimplementedTradingServiceServer) PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedTradingServiceServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedTradingServiceServer) AmendOrder(context.Context, *AmendOrderRequest) (*AmendOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AmendOrder not implemented")
+}
+func (UnimplementedTradingServiceServer) GetOrder(context.Context, *GetOrderRequest) (*GetOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedTradingServiceServer) ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrders not implemented")
+}
+func (UnimplementedTradingServiceServer) StreamFills(*StreamFillsRequest, grpc.ServerStreamingServer[Fill]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFills not implemented")
+}
+func (UnimplementedTradingServiceServer) StreamBook(*StreamBookRequest, grpc.ServerStreamingServer[BookUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBook not implemented")
+}
+func (UnimplementedTradingServiceServer) mustEmbedUnimplementedTradingServiceServer() {}
+func (UnimplementedTradingServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeTradingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TradingServiceServer will
+// result in compilation errors.
+type UnsafeTradingServiceServer interface {
+	mustEmbedUnimplementedTradingServiceServer()
+}
+
+func RegisterTradingServiceServer(s grpc.ServiceRegistrar, srv TradingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTradingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TradingService_ServiceDesc, srv)
+}
+
+func _TradingService_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradingServiceServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TradingService_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradingServiceServer).PlaceOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradingService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradingServiceServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TradingService_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradingServiceServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradingService_AmendOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AmendOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradingServiceServer).AmendOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TradingService_AmendOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradingServiceServer).AmendOrder(ctx, req.(*AmendOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradingService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradingServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TradingService_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradingServiceServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradingService_ListOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradingServiceServer).ListOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TradingService_ListOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradingServiceServer).ListOrders(ctx, req.(*ListOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradingService_StreamFills_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamFillsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TradingServiceServer).StreamFills(m, &grpc.GenericServerStream[StreamFillsRequest, Fill]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TradingService_StreamFillsServer = grpc.ServerStreamingServer[Fill]
+
+func _TradingService_StreamBook_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamBookRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TradingServiceServer).StreamBook(m, &grpc.GenericServerStream[StreamBookRequest, BookUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TradingService_StreamBookServer = grpc.ServerStreamingServer[BookUpdate]
+
+// TradingService_ServiceDesc is the grpc.ServiceDesc for TradingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TradingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lfg.trading.v1.TradingService",
+	HandlerType: (*TradingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _TradingService_PlaceOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _TradingService_CancelOrder_Handler,
+		},
+		{
+			MethodName: "AmendOrder",
+			Handler:    _TradingService_AmendOrder_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _TradingService_GetOrder_Handler,
+		},
+		{
+			MethodName: "ListOrders",
+			Handler:    _TradingService_ListOrders_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFills",
+			Handler:       _TradingService_StreamFills_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamBook",
+			Handler:       _TradingService_StreamBook_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/trading/v1/trading.proto",
+}
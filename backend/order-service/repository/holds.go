@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// maxPrice is the most a contract can trade for, which market buys hold
+const maxPrice = 1.0
+
+// HeldCredits returns the credits an open order holds back from its user: the
+// unfilled part of a buy at its limit price, or at the maximum price for a
+// market buy. It matches the order_holds view.
+func HeldCredits(order *models.Order) float64 {
+	if order.Side != models.OrderSideBuy {
+		return 0
+	}
+
+	price := maxPrice
+	if order.LimitPriceCredits != nil {
+		price = *order.LimitPriceCredits
+	}
+	return float64(order.Quantity-order.QuantityFilled) * price
+}
+
+// HeldQuantity returns the contracts an open order holds back from its user:
+// the unfilled part of a sell
+func HeldQuantity(order *models.Order) int {
+	if order.Side != models.OrderSideSell {
+		return 0
+	}
+	return order.Quantity - order.QuantityFilled
+}
+
+// AvailableCredits returns the credits in a user's wallet not held by their
+// open orders
+func (r *OrderRepository) AvailableCredits(ctx context.Context, userID uuid.UUID) (float64, error) {
+	var available float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT w.balance_credits - COALESCE((SELECT SUM(credits) FROM order_holds WHERE user_id = w.user_id), 0)
+		FROM wallets w
+		WHERE w.user_id = $1
+	`, userID).Scan(&available)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get available balance: %w", err)
+	}
+
+	return available, nil
+}
+
+// AvailablePosition returns the contracts a user holds that are not held by
+// their open sell orders
+func (r *OrderRepository) AvailablePosition(ctx context.Context, userID, contractID uuid.UUID) (int, error) {
+	var available int
+	err := r.pool.QueryRow(ctx, `
+		SELECT p.quantity - COALESCE((SELECT SUM(quantity) FROM order_holds WHERE user_id = p.user_id AND contract_id = p.contract_id), 0)
+		FROM positions p
+		WHERE p.user_id = $1 AND p.contract_id = $2
+	`, userID, contractID).Scan(&available)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get available position: %w", err)
+	}
+
+	return available, nil
+}
+
+// reserve checks that a user can cover a new order out of what their open
+// orders do not already hold, locking the wallet or position it draws on
+// until tx ends so concurrent orders cannot spend the same funds
+func reserve(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	if order.Side == models.OrderSideSell {
+		var quantity, held int
+		err := tx.QueryRow(ctx, `
+			SELECT quantity FROM positions WHERE user_id = $1 AND contract_id = $2 FOR UPDATE
+		`, order.UserID, order.ContractID).Scan(&quantity)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInsufficientPosition
+			}
+			return fmt.Errorf("failed to lock position: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(quantity), 0) FROM order_holds WHERE user_id = $1 AND contract_id = $2
+		`, order.UserID, order.ContractID).Scan(&held)
+		if err != nil {
+			return fmt.Errorf("failed to get held position: %w", err)
+		}
+
+		if quantity-held < HeldQuantity(order) {
+			return ErrInsufficientPosition
+		}
+		return nil
+	}
+
+	var balance, held float64
+	err := tx.QueryRow(ctx, `
+		SELECT balance_credits FROM wallets WHERE user_id = $1 FOR UPDATE
+	`, order.UserID).Scan(&balance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInsufficientBalance
+		}
+		return fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(credits), 0) FROM order_holds WHERE user_id = $1
+	`, order.UserID).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("failed to get held balance: %w", err)
+	}
+
+	if balance-held < HeldCredits(order) {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
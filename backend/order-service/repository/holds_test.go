@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"testing"
+
+	"lfg/shared/models"
+)
+
+func TestHeldCredits(t *testing.T) {
+	price := 0.4
+
+	tests := []struct {
+		name  string
+		order models.Order
+		want  float64
+	}{
+		{
+			name:  "limit buy",
+			order: models.Order{Side: models.OrderSideBuy, Quantity: 10, LimitPriceCredits: &price},
+			want:  4,
+		},
+		{
+			name:  "partly filled limit buy",
+			order: models.Order{Side: models.OrderSideBuy, Quantity: 10, QuantityFilled: 5, LimitPriceCredits: &price},
+			want:  2,
+		},
+		{
+			name:  "market buy at the maximum price",
+			order: models.Order{Side: models.OrderSideBuy, Quantity: 10},
+			want:  10,
+		},
+		{
+			name:  "sell",
+			order: models.Order{Side: models.OrderSideSell, Quantity: 10, LimitPriceCredits: &price},
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HeldCredits(&tt.order); got != tt.want {
+				t.Errorf("HeldCredits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeldQuantity(t *testing.T) {
+	tests := []struct {
+		name  string
+		order models.Order
+		want  int
+	}{
+		{
+			name:  "sell",
+			order: models.Order{Side: models.OrderSideSell, Quantity: 10},
+			want:  10,
+		},
+		{
+			name:  "partly filled sell",
+			order: models.Order{Side: models.OrderSideSell, Quantity: 10, QuantityFilled: 7},
+			want:  3,
+		},
+		{
+			name:  "buy",
+			order: models.Order{Side: models.OrderSideBuy, Quantity: 10},
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HeldQuantity(&tt.order); got != tt.want {
+				t.Errorf("HeldQuantity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"lfg/shared/models"
 )
 
 var (
-	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrContractNotFound = errors.New("contract not found")
+	ErrDuplicateOrder   = errors.New("order ID already in use")
 )
 
 // OrderRepository handles order database operations
@@ -26,18 +30,32 @@ func NewOrderRepository(pool *pgxpool.Pool) *OrderRepository {
 	return &OrderRepository{pool: pool}
 }
 
-// Create creates a new order
+// Create creates a new order once its user can cover it out of what their
+// open orders do not already hold: the credits a buy holds or the contracts a
+// sell does, failing with ErrInsufficientBalance or ErrInsufficientPosition.
+// The order then holds them until it fills or is cancelled.
 func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := reserve(ctx, tx, order); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO orders (id, user_id, contract_id, type, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		INSERT INTO orders (id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	_, err = tx.Exec(ctx, query,
 		order.ID,
 		order.UserID,
 		order.ContractID,
 		order.Type,
+		order.Side,
 		order.Status,
 		order.Quantity,
 		order.QuantityFilled,
@@ -46,16 +64,24 @@ func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error
 	)
 
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateOrder
+		}
 		return fmt.Errorf("failed to create order: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 // GetByID retrieves an order by ID
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
 	query := `
-		SELECT id, user_id, contract_id, type, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
+		SELECT id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
 		FROM orders
 		WHERE id = $1
 	`
@@ -66,6 +92,7 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Or
 		&order.UserID,
 		&order.ContractID,
 		&order.Type,
+		&order.Side,
 		&order.Status,
 		&order.Quantity,
 		&order.QuantityFilled,
@@ -88,7 +115,7 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Or
 // GetByUserID retrieves all orders for a user
 func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, status string, limit int) ([]*models.Order, error) {
 	query := `
-		SELECT id, user_id, contract_id, type, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
+		SELECT id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
 		FROM orders
 		WHERE user_id = $1
 	`
@@ -119,6 +146,7 @@ func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, sta
 			&order.UserID,
 			&order.ContractID,
 			&order.Type,
+			&order.Side,
 			&order.Status,
 			&order.Quantity,
 			&order.QuantityFilled,
@@ -156,9 +184,43 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID uuid.UUID, s
 	return nil
 }
 
-// Cancel cancels an order
+// Cancel cancels the unfilled remainder of an order, releasing what it holds
 func (r *OrderRepository) Cancel(ctx context.Context, orderID uuid.UUID) error {
-	return r.UpdateStatus(ctx, orderID, models.OrderStatusCancelled, 0)
+	result, err := r.pool.Exec(ctx, `
+		UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1
+	`, orderID, models.OrderStatusCancelled)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+
+	return nil
+}
+
+// GetContractMarket returns the status and expiry of the market a contract belongs to
+func (r *OrderRepository) GetContractMarket(ctx context.Context, contractID uuid.UUID) (models.MarketStatus, time.Time, error) {
+	query := `
+		SELECT m.status, m.expires_at
+		FROM contracts c
+		JOIN markets m ON m.id = c.market_id
+		WHERE c.id = $1
+	`
+
+	var status models.MarketStatus
+	var expiresAt time.Time
+	err := r.pool.QueryRow(ctx, query, contractID).Scan(&status, &expiresAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", time.Time{}, ErrContractNotFound
+		}
+		return "", time.Time{}, fmt.Errorf("failed to get contract market: %w", err)
+	}
+
+	return status, expiresAt, nil
 }
 
 // GetPool returns the underlying database connection pool
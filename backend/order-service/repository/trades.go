@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrInsufficientPosition = errors.New("insufficient position")
+)
+
+// Fill represents a single execution reported by the matching engine
+type Fill struct {
+	TradeID      uuid.UUID `json:"trade_id"`
+	MakerOrderID uuid.UUID `json:"maker_order_id"`
+	Quantity     int       `json:"quantity"`
+	Price        float64   `json:"price"`
+	ExecutedAt   time.Time `json:"executed_at"`
+}
+
+// RecordFills records what the matching engine reported for a taker order,
+// its status and filled quantity, with its trades, and settles each trade:
+// the buyer pays price × quantity credits to the seller and receives the
+// contracts, and the maker order's filled quantity is advanced. It is all or
+// nothing, so the taker holds its funds until its trades are settled, and
+// recording the same order again does nothing.
+func (r *OrderRepository) RecordFills(ctx context.Context, taker *models.Order, fills []Fill) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.OrderStatus
+	var quantity, filled int
+	var recorded bool
+	err = tx.QueryRow(ctx, `
+		SELECT status, quantity, quantity_filled, EXISTS (SELECT 1 FROM trades WHERE taker_order_id = o.id)
+		FROM orders o
+		WHERE o.id = $1
+		FOR UPDATE OF o
+	`, taker.ID).Scan(&status, &quantity, &filled, &recorded)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("failed to lock taker order: %w", err)
+	}
+
+	if recorded {
+		return r.settled(ctx, tx, taker.ID)
+	}
+
+	filled += taker.QuantityFilled
+	status = takerStatus(status, taker.Status, quantity, filled)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders SET status = $2, quantity_filled = $3, updated_at = NOW() WHERE id = $1
+	`, taker.ID, status, filled)
+	if err != nil {
+		return fmt.Errorf("failed to update taker order: %w", err)
+	}
+
+	for _, fill := range fills {
+		var makerUserID uuid.UUID
+		var makerQuantity, makerFilled int
+		err := tx.QueryRow(ctx, `
+			SELECT user_id, quantity, quantity_filled FROM orders WHERE id = $1 FOR UPDATE
+		`, fill.MakerOrderID).Scan(&makerUserID, &makerQuantity, &makerFilled)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to lock maker order: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO trades (id, contract_id, maker_order_id, taker_order_id, quantity, price_credits, executed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, fill.TradeID, taker.ContractID, fill.MakerOrderID, taker.ID, fill.Quantity, fill.Price, fill.ExecutedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record trade: %w", err)
+		}
+
+		buyerID, sellerID := taker.UserID, makerUserID
+		if taker.Side == models.OrderSideSell {
+			buyerID, sellerID = makerUserID, taker.UserID
+		}
+
+		if err := transferPosition(ctx, tx, taker.ContractID, sellerID, buyerID, fill.Quantity); err != nil {
+			return err
+		}
+
+		cost := float64(fill.Quantity) * fill.Price
+		if err := transferCredits(ctx, tx, buyerID, sellerID, cost); err != nil {
+			return err
+		}
+
+		if err := adjustCostBasis(ctx, tx, buyerID, taker.ContractID, cost); err != nil {
+			return err
+		}
+		if err := adjustCostBasis(ctx, tx, sellerID, taker.ContractID, -cost); err != nil {
+			return err
+		}
+
+		makerFilled += fill.Quantity
+		makerStatus := models.OrderStatusPartiallyFilled
+		if makerFilled >= makerQuantity {
+			makerFilled = makerQuantity
+			makerStatus = models.OrderStatusFilled
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE orders SET status = $2, quantity_filled = $3, updated_at = NOW() WHERE id = $1
+		`, fill.MakerOrderID, makerStatus, makerFilled)
+		if err != nil {
+			return fmt.Errorf("failed to update maker order: %w", err)
+		}
+	}
+
+	if err := r.settled(ctx, tx, taker.ID); err != nil {
+		return err
+	}
+
+	taker.Status, taker.QuantityFilled = status, filled
+	return nil
+}
+
+// takerStatus returns the status of a taker order once filled of its quantity
+// has filled, from its status before and the status the matching engine
+// reported. A resting order may already have filled as a maker, and an order
+// cancelled with its market while its fills were pending stays cancelled.
+func takerStatus(current, reported models.OrderStatus, quantity, filled int) models.OrderStatus {
+	switch {
+	case current == models.OrderStatusCancelled || current == models.OrderStatusRejected:
+		return current
+	case filled >= quantity:
+		return models.OrderStatusFilled
+	case filled > 0 && reported == models.OrderStatusActive:
+		return models.OrderStatusPartiallyFilled
+	default:
+		return reported
+	}
+}
+
+// settled drops an order from the unsettled orders and commits tx
+func (r *OrderRepository) settled(ctx context.Context, tx pgx.Tx, orderID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		DELETE FROM unsettled_orders WHERE order_id = $1
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete unsettled order: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// transferPosition moves contracts from seller to buyer
+func transferPosition(ctx context.Context, tx pgx.Tx, contractID, sellerID, buyerID uuid.UUID, quantity int) error {
+	result, err := tx.Exec(ctx, `
+		UPDATE positions
+		SET quantity = quantity - $3, updated_at = NOW()
+		WHERE user_id = $1 AND contract_id = $2 AND quantity >= $3
+	`, sellerID, contractID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to debit position: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrInsufficientPosition
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO positions (id, user_id, contract_id, quantity, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (user_id, contract_id)
+		DO UPDATE SET quantity = positions.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, uuid.New(), buyerID, contractID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to credit position: %w", err)
+	}
+
+	return nil
+}
+
+// transferCredits moves credits from buyer to seller
+func transferCredits(ctx context.Context, tx pgx.Tx, buyerID, sellerID uuid.UUID, amount float64) error {
+	result, err := tx.Exec(ctx, `
+		UPDATE wallets
+		SET balance_credits = balance_credits - $2, updated_at = NOW()
+		WHERE user_id = $1 AND balance_credits >= $2
+	`, buyerID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to debit buyer: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrInsufficientBalance
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE wallets SET balance_credits = balance_credits + $2, updated_at = NOW() WHERE user_id = $1
+	`, sellerID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to credit seller: %w", err)
+	}
+
+	return nil
+}
+
+// adjustCostBasis changes what a user has paid into the market of a contract
+// by amount, which is refunded if the market is cancelled
+func adjustCostBasis(ctx context.Context, tx pgx.Tx, userID, contractID uuid.UUID, amount float64) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO market_cost_basis (user_id, market_id, credits, updated_at)
+		SELECT $1, market_id, $3, NOW() FROM contracts WHERE id = $2
+		ON CONFLICT (user_id, market_id)
+		DO UPDATE SET credits = market_cost_basis.credits + EXCLUDED.credits, updated_at = NOW()
+	`, userID, contractID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to update cost basis: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"testing"
+
+	"lfg/shared/models"
+)
+
+func TestTakerStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  models.OrderStatus
+		reported models.OrderStatus
+		quantity int
+		filled   int
+		want     models.OrderStatus
+	}{
+		{
+			name:     "resting without fills",
+			current:  models.OrderStatusPending,
+			reported: models.OrderStatusActive,
+			quantity: 10,
+			want:     models.OrderStatusActive,
+		},
+		{
+			name:     "partly filled",
+			current:  models.OrderStatusPending,
+			reported: models.OrderStatusPartiallyFilled,
+			quantity: 10,
+			filled:   4,
+			want:     models.OrderStatusPartiallyFilled,
+		},
+		{
+			name:     "filled",
+			current:  models.OrderStatusPending,
+			reported: models.OrderStatusFilled,
+			quantity: 10,
+			filled:   10,
+			want:     models.OrderStatusFilled,
+		},
+		{
+			name:     "filled as a maker before its fills were recorded",
+			current:  models.OrderStatusPartiallyFilled,
+			reported: models.OrderStatusActive,
+			quantity: 10,
+			filled:   3,
+			want:     models.OrderStatusPartiallyFilled,
+		},
+		{
+			name:     "filled as maker and taker together",
+			current:  models.OrderStatusPartiallyFilled,
+			reported: models.OrderStatusPartiallyFilled,
+			quantity: 10,
+			filled:   10,
+			want:     models.OrderStatusFilled,
+		},
+		{
+			name:     "market remainder cancelled",
+			current:  models.OrderStatusPending,
+			reported: models.OrderStatusCancelled,
+			quantity: 10,
+			filled:   6,
+			want:     models.OrderStatusCancelled,
+		},
+		{
+			name:     "cancelled with its market",
+			current:  models.OrderStatusCancelled,
+			reported: models.OrderStatusFilled,
+			quantity: 10,
+			filled:   10,
+			want:     models.OrderStatusCancelled,
+		},
+		{
+			name:     "rejected by the engine",
+			current:  models.OrderStatusPending,
+			reported: models.OrderStatusRejected,
+			quantity: 10,
+			want:     models.OrderStatusRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := takerStatus(tt.current, tt.reported, tt.quantity, tt.filled); got != tt.want {
+				t.Errorf("takerStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lfg/shared/models"
+)
+
+// Unsettled is an order the matching engine matched whose fills could not be
+// recorded yet. Its order carries the status and filled quantity the engine
+// reported.
+type Unsettled struct {
+	Order     *models.Order
+	Fills     []Fill
+	Attempts  int
+	LastError string
+}
+
+// SaveUnsettled keeps what the matching engine reported for an order, with
+// the error recording it failed with, until RecordFills succeeds for it.
+// Saving an order again counts another failed attempt.
+func (r *OrderRepository) SaveUnsettled(ctx context.Context, order *models.Order, fills []Fill, cause error) error {
+	encoded, err := json.Marshal(fills)
+	if err != nil {
+		return fmt.Errorf("failed to encode fills: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO unsettled_orders (order_id, status, quantity_filled, fills, attempts, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $5, NOW(), NOW())
+		ON CONFLICT (order_id) DO UPDATE SET
+			attempts = unsettled_orders.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			updated_at = NOW()
+	`, order.ID, order.Status, order.QuantityFilled, encoded, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to save unsettled order: %w", err)
+	}
+
+	return nil
+}
+
+// ListUnsettled returns up to limit unsettled orders, those retried least
+// recently first
+func (r *OrderRepository) ListUnsettled(ctx context.Context, limit int) ([]*Unsettled, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT o.id, o.user_id, o.contract_id, o.type, o.side, u.status, o.quantity, u.quantity_filled,
+		       o.limit_price_credits, o.stop_price_credits, o.created_at, o.updated_at,
+		       u.fills, u.attempts, u.last_error
+		FROM unsettled_orders u
+		JOIN orders o ON o.id = u.order_id
+		ORDER BY u.updated_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unsettled orders: %w", err)
+	}
+	defer rows.Close()
+
+	unsettled := []*Unsettled{}
+	for rows.Next() {
+		var order models.Order
+		var encoded []byte
+		u := &Unsettled{Order: &order}
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.ContractID,
+			&order.Type,
+			&order.Side,
+			&order.Status,
+			&order.Quantity,
+			&order.QuantityFilled,
+			&order.LimitPriceCredits,
+			&order.StopPriceCredits,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&encoded,
+			&u.Attempts,
+			&u.LastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan unsettled order: %w", err)
+		}
+
+		if err := json.Unmarshal(encoded, &u.Fills); err != nil {
+			return nil, fmt.Errorf("failed to decode fills of order %s: %w", order.ID, err)
+		}
+		unsettled = append(unsettled, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unsettled order rows: %w", err)
+	}
+
+	return unsettled, nil
+}
+
+// ListPending returns up to limit orders still pending since before
+// createdBefore, oldest first: orders the matching engine may or may not
+// have taken. Orders whose fills are saved as unsettled are left out.
+func (r *OrderRepository) ListPending(ctx context.Context, createdBefore time.Time, limit int) ([]*models.Order, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, contract_id, type, side, status, quantity, quantity_filled, limit_price_credits, stop_price_credits, created_at, updated_at
+		FROM orders o
+		WHERE status = $1 AND created_at < $2
+		  AND NOT EXISTS (SELECT 1 FROM unsettled_orders u WHERE u.order_id = o.id)
+		ORDER BY created_at
+		LIMIT $3
+	`, models.OrderStatusPending, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*models.Order{}
+	for rows.Next() {
+		var order models.Order
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.ContractID,
+			&order.Type,
+			&order.Side,
+			&order.Status,
+			&order.Quantity,
+			&order.QuantityFilled,
+			&order.LimitPriceCredits,
+			&order.StopPriceCredits,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending order: %w", err)
+		}
+		orders = append(orders, &order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending order rows: %w", err)
+	}
+
+	return orders, nil
+}
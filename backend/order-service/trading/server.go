@@ -0,0 +1,557 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"lfg/shared/auth"
+	"lfg/shared/idempotency"
+	"lfg/shared/models"
+	"lfg/order-service/repository"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+	"lfg/matching-engine/engine"
+	pb "lfg/matching-engine/proto"
+)
+
+const (
+	// maxListedOrders is the most orders ListOrders returns, and the default
+	maxListedOrders = 100
+
+	// bookSnapshotDepth is the default number of price levels per side in
+	// the snapshots of StreamBook
+	bookSnapshotDepth = 50
+
+	// streamBuffer is the number of NATS messages a stream holds while its
+	// client catches up; beyond it messages are dropped
+	streamBuffer = 256
+
+	// errorDomain is the domain of the ErrorInfo detailing rejections
+	errorDomain = "lfg.trading"
+)
+
+// tradeEvent is the trade published by the matching engine on the trades subject
+type tradeEvent struct {
+	TradeID      string  `json:"trade_id"`
+	ContractID   string  `json:"contract_id"`
+	MakerOrderID string  `json:"maker_order_id"`
+	TakerOrderID string  `json:"taker_order_id"`
+	MakerUserID  string  `json:"maker_user_id"`
+	TakerUserID  string  `json:"taker_user_id"`
+	Quantity     int     `json:"quantity"`
+	Price        float64 `json:"price"`
+	ExecutedAt   int64   `json:"executed_at"`
+}
+
+// rejectionReasons are the ErrorInfo reasons of rejections, as documented
+// in the trading API
+var rejectionReasons = map[error]string{
+	ErrInvalidOrder:      "INVALID_ORDER",
+	ErrContractNotFound:  "CONTRACT_NOT_FOUND",
+	ErrMarketClosed:      "MARKET_CLOSED",
+	ErrInsufficientFunds: "INSUFFICIENT_FUNDS",
+	ErrDuplicateOrder:    "DUPLICATE_ORDER",
+	ErrOrderNotFound:     "ORDER_NOT_FOUND",
+	ErrNotOrderOwner:     "NOT_ORDER_OWNER",
+	ErrNotCancellable:    "NOT_CANCELLABLE",
+}
+
+var orderStatuses = map[models.OrderStatus]tradingv1.OrderStatus{
+	models.OrderStatusPending:         tradingv1.OrderStatus_ORDER_STATUS_PENDING,
+	models.OrderStatusActive:          tradingv1.OrderStatus_ORDER_STATUS_ACTIVE,
+	models.OrderStatusPartiallyFilled: tradingv1.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED,
+	models.OrderStatusFilled:          tradingv1.OrderStatus_ORDER_STATUS_FILLED,
+	models.OrderStatusCancelled:       tradingv1.OrderStatus_ORDER_STATUS_CANCELLED,
+	models.OrderStatusRejected:        tradingv1.OrderStatus_ORDER_STATUS_REJECTED,
+}
+
+// Server serves the public trading API to the API gateway, for the user
+// whose identity each call's service token carries. Fills and order book
+// changes are streamed from the matching engine's NATS subjects.
+type Server struct {
+	trader   *Trader
+	repo     *repository.OrderRepository
+	engine   pb.MatchingEngineClient
+	natsConn *nats.Conn
+	tradingv1.UnimplementedTradingServiceServer
+}
+
+// NewServer creates a new trading API server. Streams are unavailable
+// without a NATS connection.
+func NewServer(trader *Trader, repo *repository.OrderRepository, engine pb.MatchingEngineClient, natsConn *nats.Conn) *Server {
+	return &Server{
+		trader:   trader,
+		repo:     repo,
+		engine:   engine,
+		natsConn: natsConn,
+	}
+}
+
+// PlaceOrder implements the gRPC PlaceOrder method
+func (s *Server) PlaceOrder(ctx context.Context, req *tradingv1.PlaceOrderRequest) (*tradingv1.PlaceOrderResponse, error) {
+	userID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	contractID, err := uuid.Parse(req.ContractId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid contract ID")
+	}
+
+	var orderID uuid.UUID
+	if req.OrderId != "" {
+		if orderID, err = uuid.Parse(req.OrderId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid order ID")
+		}
+	}
+
+	placeReq := models.OrderPlaceRequest{
+		OrderID:           orderID,
+		ContractID:        contractID,
+		Quantity:          int(req.Quantity),
+		LimitPriceCredits: req.LimitPrice,
+	}
+
+	switch req.Type {
+	case tradingv1.OrderType_ORDER_TYPE_MARKET:
+		placeReq.Type = models.OrderTypeMarket
+	case tradingv1.OrderType_ORDER_TYPE_LIMIT:
+		placeReq.Type = models.OrderTypeLimit
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Type must be ORDER_TYPE_MARKET or ORDER_TYPE_LIMIT")
+	}
+
+	switch req.Side {
+	case tradingv1.Side_SIDE_BUY:
+		placeReq.Side = models.OrderSideBuy
+	case tradingv1.Side_SIDE_SELL:
+		placeReq.Side = models.OrderSideSell
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Side must be SIDE_BUY or SIDE_SELL")
+	}
+
+	placement, err := s.trader.Place(ctx, userID, placeReq)
+	if err != nil {
+		// Failures other than rejections placed nothing, so can be retried
+		var rejection *Rejection
+		if !errors.As(err, &rejection) {
+			idempotency.Retryable(ctx)
+		}
+		return nil, toStatus(err, "Failed to place order")
+	}
+
+	return &tradingv1.PlaceOrderResponse{
+		Order:        toOrder(placement.Order),
+		AveragePrice: placement.AveragePrice,
+		Fills:        takerFills(placement),
+	}, nil
+}
+
+// GetOrder implements the gRPC GetOrder method
+func (s *Server) GetOrder(ctx context.Context, req *tradingv1.GetOrderRequest) (*tradingv1.GetOrderResponse, error) {
+	userID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderID, err := uuid.Parse(req.OrderId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid order ID")
+	}
+
+	order, err := s.trader.Get(ctx, userID, orderID)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get order")
+	}
+
+	return &tradingv1.GetOrderResponse{Order: toOrder(order)}, nil
+}
+
+// CancelOrder implements the gRPC CancelOrder method
+func (s *Server) CancelOrder(ctx context.Context, req *tradingv1.CancelOrderRequest) (*tradingv1.CancelOrderResponse, error) {
+	userID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderID, err := uuid.Parse(req.OrderId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid order ID")
+	}
+
+	order, err := s.trader.Cancel(ctx, userID, orderID)
+	if err != nil {
+		return nil, toStatus(err, "Failed to cancel order")
+	}
+
+	return &tradingv1.CancelOrderResponse{Order: toOrder(order)}, nil
+}
+
+// AmendOrder implements the gRPC AmendOrder method
+func (s *Server) AmendOrder(ctx context.Context, req *tradingv1.AmendOrderRequest) (*tradingv1.AmendOrderResponse, error) {
+	userID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderID, err := uuid.Parse(req.OrderId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid order ID")
+	}
+
+	var quantity *int
+	if req.Quantity != nil {
+		q := int(*req.Quantity)
+		quantity = &q
+	}
+
+	var replacementID uuid.UUID
+	if req.ReplacementId != "" {
+		if replacementID, err = uuid.Parse(req.ReplacementId); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid replacement ID")
+		}
+	}
+
+	cancelled, placement, err := s.trader.Amend(ctx, userID, orderID, replacementID, quantity, req.LimitPrice)
+	if err != nil {
+		return nil, toStatus(err, "Failed to amend order")
+	}
+
+	return &tradingv1.AmendOrderResponse{
+		CancelledOrder: toOrder(cancelled),
+		Order:          toOrder(placement.Order),
+		AveragePrice:   placement.AveragePrice,
+		Fills:          takerFills(placement),
+	}, nil
+}
+
+// ListOrders implements the gRPC ListOrders method
+func (s *Server) ListOrders(ctx context.Context, req *tradingv1.ListOrdersRequest) (*tradingv1.ListOrdersResponse, error) {
+	userID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderStatus string
+	if req.Status != tradingv1.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		for modelStatus, protoStatus := range orderStatuses {
+			if protoStatus == req.Status {
+				orderStatus = string(modelStatus)
+			}
+		}
+		if orderStatus == "" {
+			return nil, status.Error(codes.InvalidArgument, "Unknown order status")
+		}
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 || limit > maxListedOrders {
+		limit = maxListedOrders
+	}
+
+	orders, err := s.repo.GetByUserID(ctx, userID, orderStatus, limit)
+	if err != nil {
+		log.Printf("Failed to get orders of user %s: %v", userID, err)
+		return nil, status.Error(codes.Internal, "Failed to get orders")
+	}
+
+	resp := &tradingv1.ListOrdersResponse{Orders: make([]*tradingv1.Order, 0, len(orders))}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, toOrder(order))
+	}
+	return resp, nil
+}
+
+// StreamFills implements the gRPC StreamFills method. Fills missed while
+// the client was not connected, or was too slow to keep up, are not sent
+// again; ListOrders has the quantity filled of each order.
+func (s *Server) StreamFills(req *tradingv1.StreamFillsRequest, stream tradingv1.TradingService_StreamFillsServer) error {
+	ctx := stream.Context()
+	userID, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	subject := engine.TradeSubjectPrefix + "*"
+	if req.ContractId != "" {
+		if _, err := uuid.Parse(req.ContractId); err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid contract ID")
+		}
+		subject = engine.TradeSubjectPrefix + req.ContractId
+	}
+
+	msgs, unsubscribe, err := s.subscribe(subject)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case msg := <-msgs:
+			var trade tradeEvent
+			if err := json.Unmarshal(msg.Data, &trade); err != nil {
+				log.Printf("Failed to unmarshal trade event: %v", err)
+				continue
+			}
+
+			for _, fill := range fills(&trade, userID.String()) {
+				if err := stream.Send(fill); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// StreamBook implements the gRPC StreamBook method. Deltas are followed
+// from before the snapshot is taken, so none are missed in between, and a
+// new snapshot is sent whenever deltas were missed.
+func (s *Server) StreamBook(req *tradingv1.StreamBookRequest, stream tradingv1.TradingService_StreamBookServer) error {
+	ctx := stream.Context()
+	contractID, err := uuid.Parse(req.ContractId)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid contract ID")
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = bookSnapshotDepth
+	}
+
+	msgs, unsubscribe, err := s.subscribe(engine.BookDeltaSubjectPrefix + contractID.String())
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	sequence, err := s.sendSnapshot(stream, contractID.String(), depth)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case msg := <-msgs:
+			var delta engine.BookDelta
+			if err := json.Unmarshal(msg.Data, &delta); err != nil {
+				log.Printf("Failed to unmarshal order book delta: %v", err)
+				continue
+			}
+
+			if delta.Sequence <= sequence {
+				continue
+			}
+			if delta.Sequence != sequence+1 {
+				if sequence, err = s.sendSnapshot(stream, contractID.String(), depth); err != nil {
+					return err
+				}
+				continue
+			}
+			sequence = delta.Sequence
+
+			update := &tradingv1.BookDelta{
+				ContractId: delta.ContractID,
+				Sequence:   delta.Sequence,
+				Bids:       make([]*tradingv1.PriceLevel, 0, len(delta.Bids)),
+				Asks:       make([]*tradingv1.PriceLevel, 0, len(delta.Asks)),
+				Timestamp:  timestamppb.New(time.Unix(delta.Timestamp, 0)),
+			}
+			for _, level := range delta.Bids {
+				update.Bids = append(update.Bids, &tradingv1.PriceLevel{Price: level.Price, Quantity: int32(level.Quantity)})
+			}
+			for _, level := range delta.Asks {
+				update.Asks = append(update.Asks, &tradingv1.PriceLevel{Price: level.Price, Quantity: int32(level.Quantity)})
+			}
+
+			if err := stream.Send(&tradingv1.BookUpdate{Update: &tradingv1.BookUpdate_Delta{Delta: update}}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendSnapshot sends the order book of a contract as the matching engine
+// has it, returning the book's sequence
+func (s *Server) sendSnapshot(stream tradingv1.TradingService_StreamBookServer, contractID string, depth int32) (uint64, error) {
+	ctx, cancel := context.WithTimeout(stream.Context(), engineTimeout)
+	defer cancel()
+
+	book, err := s.engine.GetOrderBook(ctx, &pb.GetOrderBookRequest{
+		ContractId: contractID,
+		Depth:      depth,
+	})
+	if err != nil {
+		log.Printf("Failed to get order book of contract %s: %v", contractID, err)
+		return 0, status.Error(codes.Unavailable, "Failed to get order book")
+	}
+
+	snapshot := &tradingv1.BookSnapshot{
+		ContractId: contractID,
+		Sequence:   book.Sequence,
+		Bids:       make([]*tradingv1.PriceLevel, 0, len(book.Bids)),
+		Asks:       make([]*tradingv1.PriceLevel, 0, len(book.Asks)),
+	}
+	for _, level := range book.Bids {
+		snapshot.Bids = append(snapshot.Bids, &tradingv1.PriceLevel{Price: level.Price, Quantity: level.Quantity})
+	}
+	for _, level := range book.Asks {
+		snapshot.Asks = append(snapshot.Asks, &tradingv1.PriceLevel{Price: level.Price, Quantity: level.Quantity})
+	}
+
+	if err := stream.Send(&tradingv1.BookUpdate{Update: &tradingv1.BookUpdate_Snapshot{Snapshot: snapshot}}); err != nil {
+		return 0, err
+	}
+	return book.Sequence, nil
+}
+
+// subscribe delivers the messages published on a NATS subject until the
+// returned function is called
+func (s *Server) subscribe(subject string) (<-chan *nats.Msg, func(), error) {
+	if s.natsConn == nil {
+		return nil, nil, status.Error(codes.Unavailable, "Streaming is unavailable")
+	}
+
+	msgs := make(chan *nats.Msg, streamBuffer)
+	sub, err := s.natsConn.ChanSubscribe(subject, msgs)
+	if err != nil {
+		log.Printf("Failed to subscribe to %s: %v", subject, err)
+		return nil, nil, status.Error(codes.Unavailable, "Failed to start stream")
+	}
+
+	return msgs, func() { sub.Unsubscribe() }, nil
+}
+
+// fills returns the sides of a trade whose orders belong to a user: both
+// sides when the user traded with themselves
+func fills(trade *tradeEvent, userID string) []*tradingv1.Fill {
+	var result []*tradingv1.Fill
+	for _, side := range []struct {
+		userID    string
+		orderID   string
+		liquidity tradingv1.Liquidity
+	}{
+		{trade.MakerUserID, trade.MakerOrderID, tradingv1.Liquidity_LIQUIDITY_MAKER},
+		{trade.TakerUserID, trade.TakerOrderID, tradingv1.Liquidity_LIQUIDITY_TAKER},
+	} {
+		if side.userID != userID {
+			continue
+		}
+		result = append(result, &tradingv1.Fill{
+			TradeId:    trade.TradeID,
+			OrderId:    side.orderID,
+			ContractId: trade.ContractID,
+			Liquidity:  side.liquidity,
+			Quantity:   int32(trade.Quantity),
+			Price:      trade.Price,
+			ExecutedAt: timestamppb.New(time.Unix(trade.ExecutedAt, 0)),
+		})
+	}
+	return result
+}
+
+// takerFills returns the fills of a placed order, which took liquidity
+func takerFills(placement *Placement) []*tradingv1.Fill {
+	result := make([]*tradingv1.Fill, 0, len(placement.Fills))
+	for _, fill := range placement.Fills {
+		result = append(result, &tradingv1.Fill{
+			TradeId:    fill.TradeID.String(),
+			OrderId:    placement.Order.ID.String(),
+			ContractId: placement.Order.ContractID.String(),
+			Liquidity:  tradingv1.Liquidity_LIQUIDITY_TAKER,
+			Quantity:   int32(fill.Quantity),
+			Price:      fill.Price,
+			ExecutedAt: timestamppb.New(fill.ExecutedAt),
+		})
+	}
+	return result
+}
+
+// caller returns the user a call is made for
+func caller(ctx context.Context) (uuid.UUID, error) {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "Call is not made for a user")
+	}
+
+	userID, err := uuid.Parse(identity.UserID)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "Invalid user ID")
+	}
+	return userID, nil
+}
+
+// toStatus converts the reason an order or cancellation was rejected to a
+// gRPC status detailing the reason, or reports message when it failed
+func toStatus(err error, message string) error {
+	var rejection *Rejection
+	if !errors.As(err, &rejection) {
+		log.Printf("%s: %v", message, err)
+		return status.Error(codes.Internal, message)
+	}
+
+	code := codes.FailedPrecondition
+	switch rejection.Reason {
+	case ErrInvalidOrder:
+		code = codes.InvalidArgument
+	case ErrContractNotFound, ErrOrderNotFound:
+		code = codes.NotFound
+	case ErrNotOrderOwner:
+		code = codes.PermissionDenied
+	case ErrDuplicateOrder:
+		code = codes.AlreadyExists
+	}
+
+	st := status.New(code, rejection.Message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: rejectionReasons[rejection.Reason],
+		Domain: errorDomain,
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// toOrder converts an order to its API representation
+func toOrder(order *models.Order) *tradingv1.Order {
+	orderType := tradingv1.OrderType_ORDER_TYPE_MARKET
+	if order.Type == models.OrderTypeLimit {
+		orderType = tradingv1.OrderType_ORDER_TYPE_LIMIT
+	}
+	side := tradingv1.Side_SIDE_BUY
+	if order.Side == models.OrderSideSell {
+		side = tradingv1.Side_SIDE_SELL
+	}
+
+	result := &tradingv1.Order{
+		Id:             order.ID.String(),
+		ContractId:     order.ContractID.String(),
+		Type:           orderType,
+		Side:           side,
+		Status:         orderStatuses[order.Status],
+		Quantity:       int32(order.Quantity),
+		QuantityFilled: int32(order.QuantityFilled),
+		LimitPrice:     order.LimitPriceCredits,
+	}
+	if !order.CreatedAt.IsZero() {
+		result.CreatedAt = timestamppb.New(order.CreatedAt)
+	}
+	if !order.UpdatedAt.IsZero() {
+		result.UpdatedAt = timestamppb.New(order.UpdatedAt)
+	}
+	return result
+}
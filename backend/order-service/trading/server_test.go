@@ -0,0 +1,130 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/auth"
+	tradingv1 "lfg/order-service/proto/trading/v1"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{"invalid order", reject(ErrInvalidOrder, "Quantity must be positive"), codes.InvalidArgument, "INVALID_ORDER"},
+		{"unknown contract", reject(ErrContractNotFound, "Contract not found"), codes.NotFound, "CONTRACT_NOT_FOUND"},
+		{"market not open", reject(ErrMarketClosed, "Market is not open for trading"), codes.FailedPrecondition, "MARKET_CLOSED"},
+		{"insufficient funds", reject(ErrInsufficientFunds, "Insufficient funds"), codes.FailedPrecondition, "INSUFFICIENT_FUNDS"},
+		{"order ID in use", reject(ErrDuplicateOrder, "Order ID already in use"), codes.AlreadyExists, "DUPLICATE_ORDER"},
+		{"unknown order", reject(ErrOrderNotFound, "Order not found"), codes.NotFound, "ORDER_NOT_FOUND"},
+		{"another user's order", reject(ErrNotOrderOwner, "Order belongs to another user"), codes.PermissionDenied, "NOT_ORDER_OWNER"},
+		{"order not cancellable", reject(ErrNotCancellable, "Order is filled"), codes.FailedPrecondition, "NOT_CANCELLABLE"},
+		{"wrapped rejection", errors.Join(errors.New("placing"), reject(ErrMarketClosed, "Market is not open for trading")), codes.FailedPrecondition, "MARKET_CLOSED"},
+		{"failure", errors.New("connection refused"), codes.Internal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err, "Failed to place order"))
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", st.Code(), tt.wantCode)
+			}
+
+			var reason string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					if info.Domain != errorDomain {
+						t.Errorf("ErrorInfo domain = %q, want %q", info.Domain, errorDomain)
+					}
+					reason = info.Reason
+				}
+			}
+			if reason != tt.wantReason {
+				t.Errorf("ErrorInfo reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderValidation(t *testing.T) {
+	user := auth.Identity{UserID: uuid.NewString()}
+	valid := func(edit func(*tradingv1.PlaceOrderRequest)) *tradingv1.PlaceOrderRequest {
+		req := &tradingv1.PlaceOrderRequest{
+			ContractId: uuid.NewString(),
+			Side:       tradingv1.Side_SIDE_BUY,
+			Type:       tradingv1.OrderType_ORDER_TYPE_MARKET,
+			Quantity:   1,
+		}
+		edit(req)
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		req      *tradingv1.PlaceOrderRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "call not made for a user",
+			req:      valid(func(*tradingv1.PlaceOrderRequest) {}),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "call made for an invalid user",
+			identity: &auth.Identity{UserID: "not-a-uuid"},
+			req:      valid(func(*tradingv1.PlaceOrderRequest) {}),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "unspecified side",
+			identity: &user,
+			req:      valid(func(req *tradingv1.PlaceOrderRequest) { req.Side = tradingv1.Side_SIDE_UNSPECIFIED }),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unspecified type",
+			identity: &user,
+			req:      valid(func(req *tradingv1.PlaceOrderRequest) { req.Type = tradingv1.OrderType_ORDER_TYPE_UNSPECIFIED }),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "invalid contract ID",
+			identity: &user,
+			req:      valid(func(req *tradingv1.PlaceOrderRequest) { req.ContractId = "not-a-uuid" }),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "invalid order ID",
+			identity: &user,
+			req:      valid(func(req *tradingv1.PlaceOrderRequest) { req.OrderId = "not-a-uuid" }),
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.identity != nil {
+				ctx = auth.WithIdentity(ctx, *tt.identity)
+			}
+
+			// The server has no trader, so only calls refused before placing
+			// anything can be made
+			_, err := NewServer(nil, nil, nil, nil).PlaceOrder(ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v: %v", status.Code(err), tt.wantCode, err)
+			}
+		})
+	}
+}
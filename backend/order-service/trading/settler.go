@@ -0,0 +1,138 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"lfg/shared/models"
+	"lfg/order-service/repository"
+)
+
+// ErrUnsettled is returned for an order the matching engine matched whose
+// fills could not be recorded yet. The order stays pending, holding its
+// funds, while they are retried.
+var ErrUnsettled = errors.New("order matched but its fills are not yet settled")
+
+const (
+	// settleAttempts and settleBackoff bound the retries of an order's fills
+	// before they are left to Run
+	settleAttempts = 3
+	settleBackoff  = 100 * time.Millisecond
+
+	// settleInterval is how often Run retries unsettled orders, and
+	// settleBatch how many it retries at a time
+	settleInterval = 10 * time.Second
+	settleBatch    = 100
+)
+
+// SettlementStore records the fills of matched orders and keeps those it
+// could not record
+type SettlementStore interface {
+	RecordFills(ctx context.Context, order *models.Order, fills []repository.Fill) error
+	SaveUnsettled(ctx context.Context, order *models.Order, fills []repository.Fill, cause error) error
+	ListUnsettled(ctx context.Context, limit int) ([]*repository.Unsettled, error)
+}
+
+// Settler records what the matching engine reports for orders. Fills it
+// cannot record are saved as unsettled, or kept in memory when they cannot be
+// saved either, and retried by Run until they are recorded.
+type Settler struct {
+	store   SettlementStore
+	backoff time.Duration
+
+	mu     sync.Mutex
+	queued []*repository.Unsettled
+}
+
+// NewSettler creates a new settler
+func NewSettler(store SettlementStore) *Settler {
+	return &Settler{store: store, backoff: settleBackoff}
+}
+
+// Settle records the status, filled quantity and fills the matching engine
+// reported for an order, retrying briefly. Fills still not recorded are
+// left to Run and ErrUnsettled is returned.
+func (s *Settler) Settle(ctx context.Context, order *models.Order, fills []repository.Fill) error {
+	var err error
+	for attempt := 0; attempt < settleAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.backoff << (attempt - 1))
+		}
+		if err = s.store.RecordFills(ctx, order, fills); err == nil {
+			return nil
+		}
+	}
+
+	log.Printf("Failed to record fills for order %s, retrying later: %v", order.ID, err)
+	if saveErr := s.store.SaveUnsettled(ctx, order, fills, err); saveErr != nil {
+		log.Printf("Failed to save unsettled order %s, keeping it in memory: %v", order.ID, saveErr)
+		s.queue(&repository.Unsettled{Order: order, Fills: fills, Attempts: settleAttempts, LastError: err.Error()})
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnsettled, err)
+}
+
+// Run retries unsettled orders periodically until ctx is cancelled
+func (s *Settler) Run(ctx context.Context) {
+	ticker := time.NewTicker(settleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.retry(ctx)
+		}
+	}
+}
+
+// retry retries the orders kept in memory, then the saved ones
+func (s *Settler) retry(ctx context.Context) {
+	s.mu.Lock()
+	queued := s.queued
+	s.queued = nil
+	s.mu.Unlock()
+
+	for _, u := range queued {
+		err := s.store.RecordFills(ctx, u.Order, u.Fills)
+		if err == nil {
+			log.Printf("Settled order %s after %d failed attempts", u.Order.ID, u.Attempts)
+			continue
+		}
+		if saveErr := s.store.SaveUnsettled(ctx, u.Order, u.Fills, err); saveErr != nil {
+			u.Attempts++
+			u.LastError = err.Error()
+			s.queue(u)
+		}
+	}
+
+	unsettled, err := s.store.ListUnsettled(ctx, settleBatch)
+	if err != nil {
+		log.Printf("Failed to list unsettled orders: %v", err)
+		return
+	}
+
+	for _, u := range unsettled {
+		err := s.store.RecordFills(ctx, u.Order, u.Fills)
+		if err == nil {
+			log.Printf("Settled order %s after %d failed attempts", u.Order.ID, u.Attempts)
+			continue
+		}
+
+		log.Printf("Failed to record fills for order %s (attempt %d): %v", u.Order.ID, u.Attempts+1, err)
+		if err := s.store.SaveUnsettled(ctx, u.Order, u.Fills, err); err != nil {
+			log.Printf("Failed to save unsettled order %s: %v", u.Order.ID, err)
+		}
+	}
+}
+
+func (s *Settler) queue(u *repository.Unsettled) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = append(s.queued, u)
+}
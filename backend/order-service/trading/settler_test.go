@@ -0,0 +1,195 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/models"
+	"lfg/order-service/repository"
+)
+
+var errDown = errors.New("database unavailable")
+
+// fakeSettlementStore fails recording fills with the errors in recordErrs,
+// one per call, then succeeds, and keeps saved unsettled orders in memory
+type fakeSettlementStore struct {
+	recordErrs []error
+	saveErr    error
+	records    int
+	recorded   []uuid.UUID
+	unsettled  map[uuid.UUID]*repository.Unsettled
+}
+
+func (s *fakeSettlementStore) RecordFills(ctx context.Context, order *models.Order, fills []repository.Fill) error {
+	s.records++
+	if len(s.recordErrs) > 0 {
+		err := s.recordErrs[0]
+		s.recordErrs = s.recordErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	s.recorded = append(s.recorded, order.ID)
+	delete(s.unsettled, order.ID)
+	return nil
+}
+
+func (s *fakeSettlementStore) SaveUnsettled(ctx context.Context, order *models.Order, fills []repository.Fill, cause error) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	if s.unsettled == nil {
+		s.unsettled = map[uuid.UUID]*repository.Unsettled{}
+	}
+	u, ok := s.unsettled[order.ID]
+	if !ok {
+		u = &repository.Unsettled{Order: order, Fills: fills}
+		s.unsettled[order.ID] = u
+	}
+	u.Attempts++
+	u.LastError = cause.Error()
+	return nil
+}
+
+func (s *fakeSettlementStore) ListUnsettled(ctx context.Context, limit int) ([]*repository.Unsettled, error) {
+	unsettled := []*repository.Unsettled{}
+	for _, u := range s.unsettled {
+		unsettled = append(unsettled, u)
+	}
+	return unsettled, nil
+}
+
+func failures(n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = errDown
+	}
+	return errs
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name          string
+		recordErrs    []error
+		saveErr       error
+		wantErr       error
+		wantRecords   int
+		wantUnsettled int
+		wantQueued    int
+	}{
+		{
+			name:        "recorded",
+			wantRecords: 1,
+		},
+		{
+			name:        "recorded on a retry",
+			recordErrs:  failures(settleAttempts - 1),
+			wantRecords: settleAttempts,
+		},
+		{
+			name:          "saved as unsettled",
+			recordErrs:    failures(settleAttempts),
+			wantErr:       ErrUnsettled,
+			wantRecords:   settleAttempts,
+			wantUnsettled: 1,
+		},
+		{
+			name:        "kept in memory when it cannot be saved",
+			recordErrs:  failures(settleAttempts),
+			saveErr:     errDown,
+			wantErr:     ErrUnsettled,
+			wantRecords: settleAttempts,
+			wantQueued:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeSettlementStore{recordErrs: tt.recordErrs, saveErr: tt.saveErr}
+			settler := NewSettler(store)
+			settler.backoff = 0
+
+			order := &models.Order{ID: uuid.New(), Status: models.OrderStatusFilled, Quantity: 1, QuantityFilled: 1}
+			err := settler.Settle(context.Background(), order, []repository.Fill{{TradeID: uuid.New(), Quantity: 1, Price: 0.5}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Settle() err = %v, want %v", err, tt.wantErr)
+			}
+			if store.records != tt.wantRecords {
+				t.Errorf("recorded %d times, want %d", store.records, tt.wantRecords)
+			}
+			if len(store.unsettled) != tt.wantUnsettled {
+				t.Errorf("%d unsettled orders saved, want %d", len(store.unsettled), tt.wantUnsettled)
+			}
+			if len(settler.queued) != tt.wantQueued {
+				t.Errorf("%d unsettled orders kept in memory, want %d", len(settler.queued), tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestSettlerRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		saveErr       error
+		retryErrs     []error
+		wantRecorded  bool
+		wantAttempts  int
+		wantUnsettled int
+		wantQueued    int
+	}{
+		{
+			name:         "saved order recorded",
+			wantRecorded: true,
+		},
+		{
+			name:          "saved order failing again",
+			retryErrs:     failures(1),
+			wantAttempts:  2,
+			wantUnsettled: 1,
+		},
+		{
+			name:         "order in memory recorded",
+			saveErr:      errDown,
+			wantRecorded: true,
+		},
+		{
+			name:       "order in memory failing again",
+			saveErr:    errDown,
+			retryErrs:  failures(1),
+			wantQueued: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeSettlementStore{recordErrs: failures(settleAttempts), saveErr: tt.saveErr}
+			settler := NewSettler(store)
+			settler.backoff = 0
+
+			order := &models.Order{ID: uuid.New(), Status: models.OrderStatusFilled, Quantity: 1, QuantityFilled: 1}
+			if err := settler.Settle(context.Background(), order, nil); !errors.Is(err, ErrUnsettled) {
+				t.Fatalf("Settle() err = %v, want %v", err, ErrUnsettled)
+			}
+
+			store.recordErrs = tt.retryErrs
+			settler.retry(context.Background())
+
+			recorded := len(store.recorded) == 1 && store.recorded[0] == order.ID
+			if recorded != tt.wantRecorded {
+				t.Errorf("recorded = %v, want %v", recorded, tt.wantRecorded)
+			}
+			if len(store.unsettled) != tt.wantUnsettled {
+				t.Fatalf("%d unsettled orders saved, want %d", len(store.unsettled), tt.wantUnsettled)
+			}
+			if u := store.unsettled[order.ID]; u != nil && u.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", u.Attempts, tt.wantAttempts)
+			}
+			if len(settler.queued) != tt.wantQueued {
+				t.Errorf("%d unsettled orders kept in memory, want %d", len(settler.queued), tt.wantQueued)
+			}
+		})
+	}
+}
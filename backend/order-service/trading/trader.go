@@ -0,0 +1,488 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/models"
+	"lfg/shared/tracing"
+	"lfg/order-service/repository"
+	pb "lfg/matching-engine/proto"
+)
+
+// Reasons orders and cancellations are rejected
+var (
+	ErrInvalidOrder      = errors.New("invalid order")
+	ErrContractNotFound  = errors.New("contract not found")
+	ErrMarketClosed      = errors.New("market is not open for trading")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrDuplicateOrder    = errors.New("order ID already in use")
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrNotOrderOwner     = errors.New("order belongs to another user")
+	ErrNotCancellable    = errors.New("order cannot be cancelled")
+)
+
+const (
+	// engineTimeout bounds each call to the matching engine
+	engineTimeout = 5 * time.Second
+
+	// pendingAfter is how long after it was created an order still pending
+	// is taken to have got no answer from the matching engine, and
+	// resubmitInterval how often Run submits such orders again
+	pendingAfter     = 2 * engineTimeout
+	resubmitInterval = 10 * time.Second
+)
+
+// engineRefusals are the reasons for the codes the matching engine refuses
+// an order with, having taken nothing
+var engineRefusals = map[codes.Code]error{
+	codes.InvalidArgument:    ErrInvalidOrder,
+	codes.FailedPrecondition: ErrMarketClosed,
+}
+
+// Rejection is an order or cancellation refused for one of the reasons
+// above, with a message for the user
+type Rejection struct {
+	Reason  error
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return r.Message
+}
+
+func (r *Rejection) Unwrap() error {
+	return r.Reason
+}
+
+func reject(reason error, format string, args ...interface{}) error {
+	return &Rejection{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// Placement is a placed order and what it filled right away, taking
+// liquidity. A pending placement is not recorded yet: the matching engine has
+// not answered for the order, which Run submits again until it does, or the
+// settler has yet to record its fills. The order holds its funds until then.
+type Placement struct {
+	Order        *models.Order
+	Fills        []repository.Fill
+	AveragePrice float64
+	Pending      bool
+}
+
+// Trader places, cancels and amends orders with the matching engine for the
+// REST handlers and the gRPC trading API
+type Trader struct {
+	repo    *repository.OrderRepository
+	engine  pb.MatchingEngineClient
+	settler *Settler
+}
+
+// NewTrader creates a new trader
+func NewTrader(repo *repository.OrderRepository, engine pb.MatchingEngineClient, settler *Settler) *Trader {
+	return &Trader{
+		repo:    repo,
+		engine:  engine,
+		settler: settler,
+	}
+}
+
+// Place places an order for a user and settles what it filled right away.
+// It fails without having placed the order, unless it rejects it.
+func (t *Trader) Place(ctx context.Context, userID uuid.UUID, req models.OrderPlaceRequest) (*Placement, error) {
+	if err := t.check(ctx, userID, &req, nil); err != nil {
+		return nil, err
+	}
+	return t.submit(ctx, userID, req)
+}
+
+// Cancel cancels the unfilled remainder of a user's order
+func (t *Trader) Cancel(ctx context.Context, userID, orderID uuid.UUID) (*models.Order, error) {
+	order, err := t.cancellable(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.cancel(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Amend replaces a user's resting limit order with one for a new total
+// quantity, limit price or both. The replacement is checked before the order
+// is cancelled, and is for the new quantity less what the order filled; it
+// gets replacementID unless that is nil. It returns the cancelled order and
+// the placement of the replacement.
+func (t *Trader) Amend(ctx context.Context, userID, orderID, replacementID uuid.UUID, quantity *int, limitPrice *float64) (*models.Order, *Placement, error) {
+	if quantity == nil && limitPrice == nil {
+		return nil, nil, reject(ErrInvalidOrder, "Quantity or limit price required")
+	}
+
+	order, err := t.cancellable(ctx, userID, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if order.Type != models.OrderTypeLimit || order.LimitPriceCredits == nil {
+		return nil, nil, reject(ErrInvalidOrder, "Only limit orders can be amended")
+	}
+
+	newQuantity := order.Quantity
+	if quantity != nil {
+		newQuantity = *quantity
+	}
+	price := *order.LimitPriceCredits
+	if limitPrice != nil {
+		price = *limitPrice
+	}
+
+	if newQuantity <= order.QuantityFilled {
+		return nil, nil, reject(ErrInvalidOrder, "Quantity must exceed the %d contracts already filled", order.QuantityFilled)
+	}
+
+	replacement := models.OrderPlaceRequest{
+		OrderID:           replacementID,
+		ContractID:        order.ContractID,
+		Type:              models.OrderTypeLimit,
+		Side:              order.Side,
+		Quantity:          newQuantity - order.QuantityFilled,
+		LimitPriceCredits: &price,
+	}
+	if err := t.check(ctx, userID, &replacement, order); err != nil {
+		return nil, nil, err
+	}
+
+	if err := t.cancel(ctx, order); err != nil {
+		return nil, nil, err
+	}
+
+	placed, err := t.submit(ctx, userID, replacement)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, placed, nil
+}
+
+// check validates an order and checks that its market is open and the user
+// can cover it out of what their open orders do not already hold, counting
+// what replacing holds when the order replaces it. Creating the order checks
+// this again; checking first keeps an amendment from cancelling an order it
+// cannot replace. The side defaults to BUY.
+func (t *Trader) check(ctx context.Context, userID uuid.UUID, req *models.OrderPlaceRequest, replacing *models.Order) error {
+	if req.Quantity <= 0 {
+		return reject(ErrInvalidOrder, "Quantity must be positive")
+	}
+
+	if req.Type == models.OrderTypeLimit && (req.LimitPriceCredits == nil || *req.LimitPriceCredits <= 0) {
+		return reject(ErrInvalidOrder, "Limit price required for limit orders")
+	}
+
+	// Only accept orders on open, unexpired markets
+	marketStatus, expiresAt, err := t.repo.GetContractMarket(ctx, req.ContractID)
+	if err != nil {
+		if err == repository.ErrContractNotFound {
+			return reject(ErrContractNotFound, "Contract not found")
+		}
+		return fmt.Errorf("failed to fetch contract details: %w", err)
+	}
+
+	if !tradable(marketStatus, expiresAt, time.Now()) {
+		return reject(ErrMarketClosed, "Market is not open for trading")
+	}
+
+	if req.Side == "" {
+		req.Side = models.OrderSideBuy
+	}
+
+	if req.Side != models.OrderSideBuy && req.Side != models.OrderSideSell {
+		return reject(ErrInvalidOrder, "Side must be BUY or SELL")
+	}
+
+	// Sellers must hold the contracts they sell
+	if req.Side == models.OrderSideSell {
+		available, err := t.repo.AvailablePosition(ctx, userID, req.ContractID)
+		if err != nil {
+			return fmt.Errorf("failed to check position: %w", err)
+		}
+		if replacing != nil {
+			available += repository.HeldQuantity(replacing)
+		}
+
+		if available < req.Quantity {
+			return reject(ErrInsufficientFunds, "Insufficient position. Required: %d contracts, Available: %d contracts", req.Quantity, available)
+		}
+		return nil
+	}
+
+	// Buyers must be able to pay the limit price, or the maximum price for
+	// a market order
+	required := repository.HeldCredits(&models.Order{Side: req.Side, Quantity: req.Quantity, LimitPriceCredits: req.LimitPriceCredits})
+	available, err := t.repo.AvailableCredits(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check wallet balance: %w", err)
+	}
+	if replacing != nil {
+		available += repository.HeldCredits(replacing)
+	}
+
+	if available < required {
+		return reject(ErrInsufficientFunds, "Insufficient balance. Required: %.2f credits, Available: %.2f credits", required, available)
+	}
+
+	return nil
+}
+
+// tradable reports whether a market in status expiring at expiresAt accepts
+// orders at now
+func tradable(status models.MarketStatus, expiresAt, now time.Time) bool {
+	return status == models.MarketStatusOpen && now.Before(expiresAt)
+}
+
+// submit creates a checked order, submits it to the matching engine and
+// settles its fills
+func (t *Trader) submit(ctx context.Context, userID uuid.UUID, req models.OrderPlaceRequest) (*Placement, error) {
+	orderID := req.OrderID
+	if orderID == uuid.Nil {
+		orderID = uuid.New()
+	}
+
+	order := &models.Order{
+		ID:                orderID,
+		UserID:            userID,
+		ContractID:        req.ContractID,
+		Type:              req.Type,
+		Side:              req.Side,
+		Status:            models.OrderStatusPending,
+		Quantity:          req.Quantity,
+		QuantityFilled:    0,
+		LimitPriceCredits: req.LimitPriceCredits,
+	}
+
+	if err := t.repo.Create(ctx, order); err != nil {
+		switch err {
+		case repository.ErrDuplicateOrder:
+			return nil, reject(ErrDuplicateOrder, "Order ID %s already in use", orderID)
+		case repository.ErrInsufficientBalance:
+			return nil, reject(ErrInsufficientFunds, "Insufficient balance. Required: %.2f credits", repository.HeldCredits(order))
+		case repository.ErrInsufficientPosition:
+			return nil, reject(ErrInsufficientFunds, "Insufficient position. Required: %d contracts", order.Quantity)
+		}
+		return nil, err
+	}
+
+	// Once submitted, the order is recorded whether or not the caller waits
+	return t.place(context.WithoutCancel(ctx), order)
+}
+
+// place submits a created order to the matching engine and settles its
+// fills. An order the engine did not answer for may or may not have been
+// taken, so it is left pending, holding its funds, for Run to submit again;
+// the engine answers an order it already took with its original outcome.
+func (t *Trader) place(ctx context.Context, order *models.Order) (*Placement, error) {
+	engineCtx, cancel := context.WithTimeout(ctx, engineTimeout)
+	defer cancel()
+
+	orderSide := pb.OrderSide_BUY
+	if order.Side == models.OrderSideSell {
+		orderSide = pb.OrderSide_SELL
+	}
+
+	// Determine order type
+	orderType := pb.OrderType_MARKET
+	if order.Type == models.OrderTypeLimit {
+		orderType = pb.OrderType_LIMIT
+	}
+
+	limitPrice := 0.0
+	if order.LimitPriceCredits != nil {
+		limitPrice = *order.LimitPriceCredits
+	}
+
+	resp, err := t.engine.PlaceOrder(engineCtx, &pb.PlaceOrderRequest{
+		OrderId:    order.ID.String(),
+		UserId:     order.UserID.String(),
+		ContractId: order.ContractID.String(),
+		Type:       orderType,
+		Side:       orderSide,
+		Quantity:   int32(order.Quantity),
+		LimitPrice: limitPrice,
+	})
+	if err != nil {
+		reason, refused := engineRefusal(err)
+		if !refused {
+			log.Printf("No answer from the matching engine for order %s (request %s), submitting it again later: %v", order.ID, tracing.RequestID(ctx), err)
+			return &Placement{Order: order, Pending: true}, nil
+		}
+
+		log.Printf("Matching engine rejected order %s (request %s): %v", order.ID, tracing.RequestID(ctx), err)
+
+		// Update order status to rejected, releasing what it holds
+		if err := t.repo.UpdateStatus(ctx, order.ID, models.OrderStatusRejected, 0); err != nil {
+			log.Printf("Failed to reject order %s: %v", order.ID, err)
+		}
+		return nil, reject(reason, "Order rejected by the matching engine: %s", status.Convert(err).Message())
+	}
+
+	order.QuantityFilled = int(resp.QuantityFilled)
+	order.Status = placedStatus(order, resp.Status)
+
+	// Settle executions: move contracts and credits between counterparties
+	fills := make([]repository.Fill, 0, len(resp.Trades))
+	for _, trade := range resp.Trades {
+		tradeID, err := uuid.Parse(trade.TradeId)
+		if err != nil {
+			continue
+		}
+		makerOrderID, err := uuid.Parse(trade.MakerOrderId)
+		if err != nil {
+			continue
+		}
+		fills = append(fills, repository.Fill{
+			TradeID:      tradeID,
+			MakerOrderID: makerOrderID,
+			Quantity:     int(trade.Quantity),
+			Price:        trade.Price,
+			ExecutedAt:   time.Unix(trade.ExecutedAt, 0),
+		})
+	}
+
+	// The order keeps holding its funds until its fills are recorded
+	placement := &Placement{Order: order, Fills: fills, AveragePrice: resp.AveragePrice}
+	if err := t.settler.Settle(ctx, order, fills); err != nil {
+		log.Printf("Order %s placed but not settled (request %s): %v", order.ID, tracing.RequestID(ctx), err)
+		placement.Pending = true
+	}
+
+	return placement, nil
+}
+
+// engineRefusal returns the reason the matching engine refused an order
+// with, if err is a refusal. Any other error, a timeout included, leaves it
+// unknown whether the engine took the order.
+func engineRefusal(err error) (error, bool) {
+	reason, refused := engineRefusals[status.Code(err)]
+	return reason, refused
+}
+
+// Run submits orders left pending again periodically, until ctx is
+// cancelled
+func (t *Trader) Run(ctx context.Context) {
+	ticker := time.NewTicker(resubmitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.resubmit(ctx)
+		}
+	}
+}
+
+// resubmit submits the orders pending for longer than a submission takes to
+// the matching engine again
+func (t *Trader) resubmit(ctx context.Context) {
+	orders, err := t.repo.ListPending(ctx, time.Now().Add(-pendingAfter), settleBatch)
+	if err != nil {
+		log.Printf("Failed to list pending orders: %v", err)
+		return
+	}
+
+	for _, order := range orders {
+		placement, err := t.place(ctx, order)
+		switch {
+		case err != nil:
+			log.Printf("Failed to resubmit pending order %s: %v", order.ID, err)
+		case !placement.Pending:
+			log.Printf("Resubmitted pending order %s: %s", order.ID, placement.Order.Status)
+		}
+	}
+}
+
+// placedStatus maps the matching engine's status for a newly placed order to
+// the order's status. Only limit orders rest on the book, so the unfilled
+// remainder of any other order is cancelled.
+func placedStatus(order *models.Order, engineStatus string) models.OrderStatus {
+	switch {
+	case engineStatus == "REJECTED":
+		return models.OrderStatusRejected
+	case engineStatus == "FILLED" || order.QuantityFilled >= order.Quantity:
+		return models.OrderStatusFilled
+	case order.Type != models.OrderTypeLimit:
+		return models.OrderStatusCancelled
+	case engineStatus == "PARTIALLY_FILLED" || order.QuantityFilled > 0:
+		return models.OrderStatusPartiallyFilled
+	default:
+		return models.OrderStatusActive
+	}
+}
+
+// Get returns a user's order. Another user's order is not found.
+func (t *Trader) Get(ctx context.Context, userID, orderID uuid.UUID) (*models.Order, error) {
+	order, err := t.repo.GetByID(ctx, orderID)
+	if err != nil {
+		if err == repository.ErrOrderNotFound {
+			return nil, reject(ErrOrderNotFound, "Order not found")
+		}
+		return nil, err
+	}
+
+	if order.UserID != userID {
+		return nil, reject(ErrOrderNotFound, "Order not found")
+	}
+	return order, nil
+}
+
+// cancellable returns a user's order if it can be cancelled
+func (t *Trader) cancellable(ctx context.Context, userID, orderID uuid.UUID) (*models.Order, error) {
+	order, err := t.repo.GetByID(ctx, orderID)
+	if err != nil {
+		if err == repository.ErrOrderNotFound {
+			return nil, reject(ErrOrderNotFound, "Order not found")
+		}
+		return nil, err
+	}
+
+	// Verify ownership
+	if order.UserID != userID {
+		return nil, reject(ErrNotOrderOwner, "Not authorized to cancel this order")
+	}
+
+	// Check if order can be cancelled
+	if order.Status != models.OrderStatusActive && order.Status != models.OrderStatusPartiallyFilled {
+		return nil, reject(ErrNotCancellable, "Order cannot be cancelled")
+	}
+
+	return order, nil
+}
+
+// cancel cancels an order in the matching engine and records it as cancelled
+func (t *Trader) cancel(ctx context.Context, order *models.Order) error {
+	ctx = context.WithoutCancel(ctx)
+	engineCtx, cancel := context.WithTimeout(ctx, engineTimeout)
+	defer cancel()
+
+	_, err := t.engine.CancelOrder(engineCtx, &pb.CancelOrderRequest{
+		OrderId:    order.ID.String(),
+		ContractId: order.ContractID.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+
+	// Update order status in database
+	if err := t.repo.Cancel(ctx, order.ID); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	order.Status = models.OrderStatusCancelled
+	return nil
+}
@@ -0,0 +1,157 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"lfg/shared/models"
+)
+
+func TestPlacedStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		orderType    models.OrderType
+		quantity     int
+		filled       int
+		engineStatus string
+		want         models.OrderStatus
+	}{
+		{
+			name:         "limit order resting",
+			orderType:    models.OrderTypeLimit,
+			quantity:     10,
+			engineStatus: "ACTIVE",
+			want:         models.OrderStatusActive,
+		},
+		{
+			name:         "limit order partly filled",
+			orderType:    models.OrderTypeLimit,
+			quantity:     10,
+			filled:       4,
+			engineStatus: "PARTIALLY_FILLED",
+			want:         models.OrderStatusPartiallyFilled,
+		},
+		{
+			name:         "limit order filled",
+			orderType:    models.OrderTypeLimit,
+			quantity:     10,
+			filled:       10,
+			engineStatus: "FILLED",
+			want:         models.OrderStatusFilled,
+		},
+		{
+			name:         "market order filled",
+			orderType:    models.OrderTypeMarket,
+			quantity:     10,
+			filled:       10,
+			engineStatus: "FILLED",
+			want:         models.OrderStatusFilled,
+		},
+		{
+			name:         "market order remainder cancelled",
+			orderType:    models.OrderTypeMarket,
+			quantity:     10,
+			filled:       4,
+			engineStatus: "PARTIALLY_FILLED",
+			want:         models.OrderStatusCancelled,
+		},
+		{
+			name:         "market order without liquidity",
+			orderType:    models.OrderTypeMarket,
+			quantity:     10,
+			engineStatus: "ACTIVE",
+			want:         models.OrderStatusCancelled,
+		},
+		{
+			name:         "halted book",
+			orderType:    models.OrderTypeLimit,
+			quantity:     10,
+			engineStatus: "REJECTED",
+			want:         models.OrderStatusRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{Type: tt.orderType, Quantity: tt.quantity, QuantityFilled: tt.filled}
+			if got := placedStatus(order, tt.engineStatus); got != tt.want {
+				t.Errorf("placedStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineRefusal(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantReason  error
+		wantRefused bool
+	}{
+		{
+			name:        "invalid order",
+			err:         status.Error(codes.InvalidArgument, "quantity must be positive"),
+			wantReason:  ErrInvalidOrder,
+			wantRefused: true,
+		},
+		{
+			name:        "book not taking orders",
+			err:         status.Error(codes.FailedPrecondition, "contract halted"),
+			wantReason:  ErrMarketClosed,
+			wantRefused: true,
+		},
+		{
+			name: "timed out",
+			err:  status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+		},
+		{
+			name: "unavailable",
+			err:  status.Error(codes.Unavailable, "connection refused"),
+		},
+		{
+			name: "context cancelled before the call",
+			err:  context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, refused := engineRefusal(tt.err)
+			if refused != tt.wantRefused || reason != tt.wantReason {
+				t.Errorf("engineRefusal() = %v, %v; want %v, %v", reason, refused, tt.wantReason, tt.wantRefused)
+			}
+		})
+	}
+}
+
+func TestTradable(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		status    models.MarketStatus
+		expiresAt time.Time
+		want      bool
+	}{
+		{"open", models.MarketStatusOpen, now.Add(time.Hour), true},
+		{"open at expiry", models.MarketStatusOpen, now, false},
+		{"open past expiry", models.MarketStatusOpen, now.Add(-time.Second), false},
+		{"draft", models.MarketStatusDraft, now.Add(time.Hour), false},
+		{"upcoming", models.MarketStatusUpcoming, now.Add(time.Hour), false},
+		{"closed", models.MarketStatusClosed, now.Add(time.Hour), false},
+		{"resolved", models.MarketStatusResolved, now.Add(time.Hour), false},
+		{"cancelled", models.MarketStatusCancelled, now.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tradable(tt.status, tt.expiresAt, now); got != tt.want {
+				t.Errorf("tradable(%s) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// Headers of a request signed with an API key
+const (
+	APIKeyHeader          = "X-API-Key"
+	APIKeyTimestampHeader = "X-API-Timestamp"
+	APIKeySignatureHeader = "X-API-Signature"
+)
+
+// GenerateAPIKey creates the public ID and signing secret of an API key
+func GenerateAPIKey() (string, string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key ID: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key secret: %w", err)
+	}
+
+	return "lfgk_" + hex.EncodeToString(id), "lfgs_" + hex.EncodeToString(secret), nil
+}
+
+// SignAPIRequest returns the signature of a request made with an API key at
+// timestamp, in Unix seconds. The signature is the hex HMAC-SHA256, keyed
+// with the secret, of the timestamp, method, request URI (path and query)
+// and hex SHA-256 of the body, each followed by a newline except the last.
+func SignAPIRequest(secret string, timestamp int64, method, requestURI string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(method))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(requestURI))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAPIRequest reports whether signature is the signature of a request
+func VerifyAPIRequest(secret string, timestamp int64, method, requestURI string, body []byte, signature string) bool {
+	expected := SignAPIRequest(secret, timestamp, method, requestURI, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
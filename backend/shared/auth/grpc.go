@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizeCall checks that a gRPC call carries a service token allowing
+// its caller to call method, and returns the call's context with the
+// identity of the user the call is made for, if any
+func (g *ServiceGuard) authorizeCall(ctx context.Context, method string) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+
+	claims, err := g.Authorize(token, method)
+	if err != nil {
+		if err == ErrServiceForbidden {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if claims.UserID != "" {
+		ctx = WithIdentity(ctx, Identity{UserID: claims.UserID, Email: claims.Email, Role: claims.Role})
+	}
+	return ctx, nil
+}
+
+// UnaryServerInterceptor rejects unary calls the guard does not allow. The
+// identity in an allowed call's token is available from
+// IdentityFromContext.
+func (g *ServiceGuard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := g.authorizeCall(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streaming calls the guard does not allow.
+// The identity in an allowed call's token is available from
+// IdentityFromContext on the stream's context.
+func (g *ServiceGuard) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := g.authorizeCall(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream is a server stream with a different context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the stream's context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
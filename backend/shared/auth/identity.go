@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+// Identity is the user a call is made for
+type Identity struct {
+	UserID string
+	Email  string
+	Role   string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the user a call is made for. gRPC
+// calls made with it and ServiceCredentials carry the identity in their
+// service token.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the user a call is made for, if any
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok && identity.UserID != ""
+}
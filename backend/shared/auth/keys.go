@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"lfg/shared/config"
+)
+
+// LoadServiceTokens creates the service token manager of service from cfg:
+// the key it signs with, the public keys of the services whose tokens it
+// accepts and the transport of its gRPC calls. Without keys configured,
+// every service uses its development key.
+func LoadServiceTokens(service string, cfg *config.Config) (*ServiceTokens, error) {
+	key := DevServiceKey(service)
+	if cfg.ServiceTokenKey != "" {
+		var err error
+		if key, err = ParseServiceKey(cfg.ServiceTokenKey); err != nil {
+			return nil, fmt.Errorf("invalid SERVICE_TOKEN_KEY: %w", err)
+		}
+	}
+
+	publicKeys := DevServicePublicKeys()
+	if len(cfg.ServiceTokenPublicKeys) > 0 {
+		var err error
+		if publicKeys, err = ParseServicePublicKeys(cfg.ServiceTokenPublicKeys); err != nil {
+			return nil, fmt.Errorf("invalid SERVICE_TOKEN_PUBLIC_KEYS: %w", err)
+		}
+	}
+
+	tokens := NewServiceTokens(service, key, publicKeys, cfg.ServiceTokenTTL)
+
+	switch {
+	case cfg.GRPCTLSCAFile != "":
+		pem, err := os.ReadFile(cfg.GRPCTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GRPC_TLS_CA_FILE: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in GRPC_TLS_CA_FILE")
+		}
+		tokens.UseTLS(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12})
+	case cfg.GRPCAllowPlaintext:
+		log.Println("Warning: gRPC calls to other services are made without TLS (GRPC_ALLOW_PLAINTEXT)")
+		tokens.AllowPlaintext()
+	}
+
+	return tokens, nil
+}
+
+// ServerCredentials returns the transport credentials of a gRPC server other
+// services call: TLS with the certificate in cfg, or plaintext if allowed
+func ServerCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	if cfg.GRPCTLSCertFile != "" || cfg.GRPCTLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.GRPCTLSCertFile, cfg.GRPCTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gRPC TLS certificate: %w", err)
+		}
+		return credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}), nil
+	}
+
+	if !cfg.GRPCAllowPlaintext {
+		return nil, fmt.Errorf("GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE must be set unless GRPC_ALLOW_PLAINTEXT is")
+	}
+
+	log.Println("Warning: serving gRPC without TLS (GRPC_ALLOW_PLAINTEXT)")
+	return insecure.NewCredentials(), nil
+}
+
+// ParseServiceKey parses a service's signing key, the base64 encoded seed of
+// an Ed25519 key
+func ParseServiceKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(seed), ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParseServicePublicKeys parses the public keys of services, each given as
+// its name, "=" and the base64 encoded Ed25519 public key
+func ParseServicePublicKeys(entries []string) (map[string]ed25519.PublicKey, error) {
+	publicKeys := make(map[string]ed25519.PublicKey, len(entries))
+	for _, entry := range entries {
+		service, encoded, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || service == "" {
+			return nil, fmt.Errorf("%q is not service=key", entry)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key of %s: %w", service, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key of %s is %d bytes, want %d", service, len(key), ed25519.PublicKeySize)
+		}
+		publicKeys[service] = ed25519.PublicKey(key)
+	}
+	return publicKeys, nil
+}
+
+// DevServiceKey returns the key service signs with in development, derived
+// from its name. Anyone can derive it, so it is refused in production.
+func DevServiceKey(service string) ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte("lfg-dev-service-key:" + service))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+// DevServicePublicKeys returns the public keys of every service's
+// development key
+func DevServicePublicKeys() map[string]ed25519.PublicKey {
+	publicKeys := make(map[string]ed25519.PublicKey, len(services))
+	for _, service := range services {
+		publicKeys[service] = DevServiceKey(service).Public().(ed25519.PublicKey)
+	}
+	return publicKeys
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"lfg/shared/config"
+)
+
+func TestParseServiceKey(t *testing.T) {
+	seed := strings.Repeat("k", ed25519.SeedSize)
+
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{
+			name:    "seed",
+			encoded: base64.StdEncoding.EncodeToString([]byte(seed)),
+		},
+		{
+			name:    "not base64",
+			encoded: "not base64!",
+			wantErr: true,
+		},
+		{
+			name:    "short seed",
+			encoded: base64.StdEncoding.EncodeToString([]byte(seed[:16])),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseServiceKey(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseServiceKey() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !key.Equal(ed25519.NewKeyFromSeed([]byte(seed))) {
+				t.Errorf("ParseServiceKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestParseServicePublicKeys(t *testing.T) {
+	publicKey := DevServiceKey(ServiceOrder).Public().(ed25519.PublicKey)
+	encoded := base64.StdEncoding.EncodeToString(publicKey)
+
+	tests := []struct {
+		name     string
+		entries  []string
+		wantErr  bool
+		wantKeys []string
+	}{
+		{
+			name:     "keys",
+			entries:  []string{ServiceOrder + "=" + encoded, " " + ServiceMarket + "=" + encoded},
+			wantKeys: []string{ServiceOrder, ServiceMarket},
+		},
+		{
+			name:    "missing service",
+			entries: []string{"=" + encoded},
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			entries: []string{ServiceOrder},
+			wantErr: true,
+		},
+		{
+			name:    "not base64",
+			entries: []string{ServiceOrder + "=not base64!"},
+			wantErr: true,
+		},
+		{
+			name:    "short key",
+			entries: []string{ServiceOrder + "=" + base64.StdEncoding.EncodeToString(publicKey[:16])},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKeys, err := ParseServicePublicKeys(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseServicePublicKeys() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(publicKeys) != len(tt.wantKeys) {
+				t.Fatalf("ParseServicePublicKeys() returned %d keys, want %d", len(publicKeys), len(tt.wantKeys))
+			}
+			for _, service := range tt.wantKeys {
+				if !publicKeys[service].Equal(publicKey) {
+					t.Errorf("key of %s = %x, want %x", service, publicKeys[service], publicKey)
+				}
+			}
+		})
+	}
+}
+
+func TestServerCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{
+			name:    "no certificate",
+			wantErr: true,
+		},
+		{
+			name:    "missing certificate files",
+			cfg:     config.Config{GRPCTLSCertFile: "missing.crt", GRPCTLSKeyFile: "missing.key"},
+			wantErr: true,
+		},
+		{
+			name: "plaintext allowed",
+			cfg:  config.Config{GRPCAllowPlaintext: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ServerCredentials(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ServerCredentials() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
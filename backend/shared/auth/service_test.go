@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func devTokens(service string) *ServiceTokens {
+	return NewServiceTokens(service, DevServiceKey(service), DevServicePublicKeys(), time.Minute)
+}
+
+// forge signs a token issued as issuer for audience with key
+func forge(t *testing.T, method jwt.SigningMethod, key interface{}, issuer, audience string, expiresAt time.Time) string {
+	t.Helper()
+	claims := ServiceClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+		},
+	}
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestServiceTokensValidate(t *testing.T) {
+	wallet := devTokens(ServiceWallet)
+	future := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name       string
+		token      func(t *testing.T) string
+		wantErr    error
+		wantCaller string
+	}{
+		{
+			name: "issued by a known service",
+			token: func(t *testing.T) string {
+				token, _, err := devTokens(ServiceOrder).Issue(ServiceWallet, "", "", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantCaller: ServiceOrder,
+		},
+		{
+			name: "issued as another service",
+			token: func(t *testing.T) string {
+				return forge(t, jwt.SigningMethodEdDSA, DevServiceKey(ServiceNotification), ServiceOrder, ServiceWallet, future)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "issued by an unknown service",
+			token: func(t *testing.T) string {
+				return forge(t, jwt.SigningMethodEdDSA, DevServiceKey("intruder"), "intruder", ServiceWallet, future)
+			},
+			wantErr: ErrUnknownService,
+		},
+		{
+			name: "issued for another service",
+			token: func(t *testing.T) string {
+				token, _, err := devTokens(ServiceOrder).Issue(ServiceUser, "", "", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				return forge(t, jwt.SigningMethodEdDSA, DevServiceKey(ServiceOrder), ServiceOrder, ServiceWallet, time.Now().Add(-time.Minute))
+			},
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name: "signed with a shared secret",
+			token: func(t *testing.T) string {
+				return forge(t, jwt.SigningMethodHS256, []byte("dev-service-secret-change-in-production"), ServiceOrder, ServiceWallet, future)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "missing",
+			token:   func(t *testing.T) string { return "" },
+			wantErr: ErrMissingServiceToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := wallet.Validate(tt.token(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Caller() != tt.wantCaller {
+				t.Errorf("Caller() = %s, want %s", claims.Caller(), tt.wantCaller)
+			}
+		})
+	}
+}
+
+func TestServiceCredentialsRequireTransportSecurity(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext bool
+		want      bool
+	}{
+		{
+			name: "TLS",
+			want: true,
+		},
+		{
+			name:      "plaintext allowed",
+			plaintext: true,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := devTokens(ServiceOrder)
+			if tt.plaintext {
+				tokens.AllowPlaintext()
+			}
+			if got := tokens.Credentials(ServiceMatchingEngine).RequireTransportSecurity(); got != tt.want {
+				t.Errorf("RequireTransportSecurity() = %v, want %v", got, tt.want)
+			}
+			if got := tokens.TransportCredentials().Info().SecurityProtocol; (got == "tls") != tt.want {
+				t.Errorf("transport security protocol = %q", got)
+			}
+		})
+	}
+}
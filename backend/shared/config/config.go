@@ -13,7 +13,8 @@ type Config struct {
 	LogLevel    string
 
 	// Server
-	Port string
+	Port     string
+	GRPCPort string
 
 	// Database
 	DBHost     string
@@ -30,6 +31,17 @@ type Config struct {
 	JWTAccessTTL  time.Duration
 	JWTRefreshTTL time.Duration
 
+	// Service-to-service authentication
+	ServiceTokenKey        string   // Base64 Ed25519 seed the service signs its tokens with
+	ServiceTokenPublicKeys []string // service=base64 Ed25519 public key of each service it accepts calls from
+	ServiceTokenTTL        time.Duration
+
+	// TLS of gRPC calls between services
+	GRPCTLSCertFile    string
+	GRPCTLSKeyFile     string
+	GRPCTLSCAFile      string
+	GRPCAllowPlaintext bool // Call and serve without TLS, for local development
+
 	// NATS
 	NATSURL string
 
@@ -41,10 +53,75 @@ type Config struct {
 	CreditExchangeURL      string
 	NotificationServiceURL string
 	MatchingEngineGRPC     string
+	OrderServiceGRPC       string
+
+	// Market lifecycle
+	MarketSchedulerInterval time.Duration
+
+	// Resolution oracle
+	ResolutionChallengeWindow time.Duration
+	ResolutionDisputeBond     float64
+	ResolverTimeout           time.Duration
+
+	// Alerts
+	AlertCheckInterval time.Duration
+
+	// Email
+	SMTPHost          string
+	SMTPPort          int
+	SMTPUsername      string
+	SMTPPassword      string
+	EmailFrom         string
+	EmailPollInterval time.Duration
+	EmailMaxAttempts  int
+
+	// Webhooks
+	WebhookPollInterval time.Duration
+	WebhookTimeout      time.Duration
+	WebhookMaxAttempts  int
+	WebhookDisableAfter int
+	WebhookAllowPrivate bool
+
+	// WebSocket
+	WSMaxSubscriptions int
+	WSReplayBufferSize int
+	WSReplayWindow     time.Duration
+	WSAuthTimeout      time.Duration
+	WSStatusInterval   time.Duration
+	WSAllowNoOrigin    bool
+
+	// Tracing
+	TracingExporter     string
+	TracingOTLPEndpoint string
+	TracingOTLPInsecure bool
+	TracingSampleRatio  float64
+
+	// API Keys
+	APIKeySignatureWindow time.Duration
+	APIKeyCacheTTL        time.Duration
+	APIKeyCacheSize       int
+
+	// Idempotency Keys
+	IdempotencyKeyTTL time.Duration
+
+	// FIX gateway
+	FIXPort             string
+	FIXCompID           string
+	FIXLogonTimeout     time.Duration
+	FIXMessageRetention time.Duration
+	FIXTLSCertFile      string
+	FIXTLSKeyFile       string
+	FIXAllowPlaintext   bool // Serve sessions without TLS, for local development
+
+	// Metrics
+	MetricsToken string
 
 	// Rate Limiting
-	RateLimitRequests int
-	RateLimitWindow   time.Duration
+	RateLimitRequests       int
+	RateLimitWindow         time.Duration
+	RateLimitUserRequests   int
+	RateLimitTrustedProxies []string
+	RateLimitRedisURL       string
 
 	// CORS
 	CORSAllowedOrigins []string
@@ -56,7 +133,8 @@ func Load() (*Config, error) {
 		Environment: getEnv("ENVIRONMENT", "development"),
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
 
-		Port: getEnv("PORT", "8080"),
+		Port:     getEnv("PORT", "8080"),
+		GRPCPort: getEnv("GRPC_PORT", "50052"),
 
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnvAsInt("DB_PORT", 5432),
@@ -71,6 +149,15 @@ func Load() (*Config, error) {
 		JWTAccessTTL:  getEnvAsDuration("JWT_ACCESS_TTL", 15*time.Minute),
 		JWTRefreshTTL: getEnvAsDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
 
+		ServiceTokenKey:        getEnv("SERVICE_TOKEN_KEY", ""),
+		ServiceTokenPublicKeys: getEnvAsSlice("SERVICE_TOKEN_PUBLIC_KEYS", nil),
+		ServiceTokenTTL:        getEnvAsDuration("SERVICE_TOKEN_TTL", 1*time.Minute),
+
+		GRPCTLSCertFile:    getEnv("GRPC_TLS_CERT_FILE", ""),
+		GRPCTLSKeyFile:     getEnv("GRPC_TLS_KEY_FILE", ""),
+		GRPCTLSCAFile:      getEnv("GRPC_TLS_CA_FILE", ""),
+		GRPCAllowPlaintext: getEnvAsBool("GRPC_ALLOW_PLAINTEXT", false),
+
 		NATSURL: getEnv("NATS_URL", "nats://localhost:4222"),
 
 		UserServiceURL:         getEnv("USER_SERVICE_URL", "http://localhost:8080"),
@@ -80,9 +167,63 @@ func Load() (*Config, error) {
 		CreditExchangeURL:      getEnv("CREDIT_EXCHANGE_URL", "http://localhost:8084"),
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8085"),
 		MatchingEngineGRPC:     getEnv("MATCHING_ENGINE_GRPC", "localhost:50051"),
+		OrderServiceGRPC:       getEnv("ORDER_SERVICE_GRPC", "localhost:50052"),
+
+		MarketSchedulerInterval: getEnvAsDuration("MARKET_SCHEDULER_INTERVAL", 15*time.Second),
+
+		ResolutionChallengeWindow: getEnvAsDuration("RESOLUTION_CHALLENGE_WINDOW", 24*time.Hour),
+		ResolutionDisputeBond:     getEnvAsFloat("RESOLUTION_DISPUTE_BOND", 10),
+		ResolverTimeout:           getEnvAsDuration("RESOLVER_TIMEOUT", 10*time.Second),
+
+		AlertCheckInterval: getEnvAsDuration("ALERT_CHECK_INTERVAL", 30*time.Second),
+
+		SMTPHost:          getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:          getEnvAsInt("SMTP_PORT", 1025),
+		SMTPUsername:      getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:      getEnv("SMTP_PASSWORD", ""),
+		EmailFrom:         getEnv("EMAIL_FROM", "LFG <no-reply@lfg.local>"),
+		EmailPollInterval: getEnvAsDuration("EMAIL_POLL_INTERVAL", 10*time.Second),
+		EmailMaxAttempts:  getEnvAsInt("EMAIL_MAX_ATTEMPTS", 8),
+
+		WebhookPollInterval: getEnvAsDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		WebhookTimeout:      getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:  getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookDisableAfter: getEnvAsInt("WEBHOOK_DISABLE_AFTER", 25),
+		WebhookAllowPrivate: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		WSMaxSubscriptions: getEnvAsInt("WS_MAX_SUBSCRIPTIONS", 50),
+		WSReplayBufferSize: getEnvAsInt("WS_REPLAY_BUFFER_SIZE", 500),
+		WSReplayWindow:     getEnvAsDuration("WS_REPLAY_WINDOW", 2*time.Minute),
+		WSAuthTimeout:      getEnvAsDuration("WS_AUTH_TIMEOUT", 10*time.Second),
+		WSStatusInterval:   getEnvAsDuration("WS_STATUS_INTERVAL", 30*time.Second),
+		WSAllowNoOrigin:    getEnvAsBool("WS_ALLOW_NO_ORIGIN", false),
+
+		TracingExporter:     getEnv("TRACING_EXPORTER", "none"),
+		TracingOTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", "localhost:4317"),
+		TracingOTLPInsecure: getEnvAsBool("TRACING_OTLP_INSECURE", true),
+		TracingSampleRatio:  getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+
+		APIKeySignatureWindow: getEnvAsDuration("API_KEY_SIGNATURE_WINDOW", 30*time.Second),
+		APIKeyCacheTTL:        getEnvAsDuration("API_KEY_CACHE_TTL", 30*time.Second),
+		APIKeyCacheSize:       getEnvAsInt("API_KEY_CACHE_SIZE", 10000),
+
+		IdempotencyKeyTTL: getEnvAsDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+
+		FIXPort:             getEnv("FIX_PORT", "9878"),
+		FIXCompID:           getEnv("FIX_COMP_ID", "LFG"),
+		FIXLogonTimeout:     getEnvAsDuration("FIX_LOGON_TIMEOUT", 10*time.Second),
+		FIXMessageRetention: getEnvAsDuration("FIX_MESSAGE_RETENTION", 7*24*time.Hour),
+		FIXTLSCertFile:      getEnv("FIX_TLS_CERT_FILE", ""),
+		FIXTLSKeyFile:       getEnv("FIX_TLS_KEY_FILE", ""),
+		FIXAllowPlaintext:   getEnvAsBool("FIX_ALLOW_PLAINTEXT", false),
 
-		RateLimitRequests: getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitWindow:   getEnvAsDuration("RATE_LIMIT_WINDOW", 1*time.Minute),
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		RateLimitRequests:       getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitWindow:         getEnvAsDuration("RATE_LIMIT_WINDOW", 1*time.Minute),
+		RateLimitUserRequests:   getEnvAsInt("RATE_LIMIT_USER_REQUESTS", 300),
+		RateLimitTrustedProxies: getEnvAsSlice("RATE_LIMIT_TRUSTED_PROXIES", nil),
+		RateLimitRedisURL:       getEnv("RATE_LIMIT_REDIS_URL", ""),
 
 		CORSAllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
 	}
@@ -91,6 +232,15 @@ func Load() (*Config, error) {
 	if cfg.JWTSecret == "dev-secret-key-change-in-production" && cfg.Environment == "production" {
 		return nil, fmt.Errorf("JWT_SECRET must be set in production")
 	}
+	if (cfg.ServiceTokenKey == "" || len(cfg.ServiceTokenPublicKeys) == 0) && cfg.Environment == "production" {
+		return nil, fmt.Errorf("SERVICE_TOKEN_KEY and SERVICE_TOKEN_PUBLIC_KEYS must be set in production")
+	}
+	if cfg.GRPCAllowPlaintext && cfg.Environment == "production" {
+		return nil, fmt.Errorf("GRPC_ALLOW_PLAINTEXT must not be set in production")
+	}
+	if cfg.MetricsToken == "" && cfg.Environment == "production" {
+		return nil, fmt.Errorf("METRICS_TOKEN must be set in production")
+	}
 
 	return cfg, nil
 }
@@ -114,6 +264,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
 	if valueStr == "" {
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/tracing"
 )
 
 // Config holds database configuration
@@ -61,6 +63,9 @@ func NewPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
 		config.MaxConnIdleTime = 30 * time.Minute // Default
 	}
 
+	// Trace queries made for traced requests
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
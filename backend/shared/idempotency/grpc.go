@@ -0,0 +1,179 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"lfg/shared/auth"
+)
+
+// MetadataKey carries the key a gRPC call is made idempotent with
+const MetadataKey = "idempotency-key"
+
+// ReplayedMetadataKey is set in the header of calls replayed from an earlier
+// call
+const ReplayedMetadataKey = "idempotent-replayed"
+
+// serverErrors are the codes of calls failing as server errors, which map to
+// HTTP 5xx statuses
+var serverErrors = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.Internal:         true,
+	codes.DataLoss:         true,
+	codes.Unimplemented:    true,
+	codes.Unavailable:      true,
+	codes.DeadlineExceeded: true,
+}
+
+// grpcContentType marks stored outcomes of gRPC calls: the response as an
+// Any, or the status of a call that failed, in the protobuf wire format
+const grpcContentType = "application/grpc+proto"
+
+// UnaryServerInterceptor makes the calls to methods carrying an
+// idempotency-key metadata idempotent, as Handler does HTTP requests. Keys
+// are scoped to the user from auth.IdentityFromContext, so the interceptor
+// must run after the one authenticating calls.
+func (m *Middleware) UnaryServerInterceptor(methods ...string) grpc.UnaryServerInterceptor {
+	idempotent := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		idempotent[method] = true
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := firstValue(ctx, MetadataKey)
+		identity, ok := auth.IdentityFromContext(ctx)
+		if !idempotent[info.FullMethod] || key == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		userID, err := uuid.Parse(identity.UserID)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		if len(key) > maxKeyLength {
+			return nil, status.Error(codes.InvalidArgument, "Idempotency-Key must be at most 255 characters")
+		}
+
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Request cannot be hashed")
+		}
+
+		requestHash := hashCall(info.FullMethod, body)
+		now := time.Now()
+		record, err := m.store.Claim(ctx, userID, key, requestHash, now.Add(lockTimeout), now.Add(m.ttl))
+		if err != nil {
+			log.Printf("Failed to claim idempotency key for user %s: %v", userID, err)
+			return nil, status.Error(codes.Internal, "Failed to process Idempotency-Key")
+		}
+
+		if record != nil {
+			switch {
+			case record.RequestHash != requestHash:
+				return nil, status.Error(codes.InvalidArgument, "Idempotency-Key was already used for a different request")
+			case !record.Completed:
+				return nil, status.Error(codes.Aborted, "A request with this Idempotency-Key is still being processed")
+			default:
+				grpc.SetHeader(ctx, metadata.Pairs(ReplayedMetadataKey, "true"))
+				return replayCall(record)
+			}
+		}
+
+		result := &outcome{}
+		resp, callErr := handler(context.WithValue(ctx, outcomeKey{}, result), req)
+
+		// Store the outcome even if the caller went away, so its retry does
+		// not run the call again
+		storeCtx := context.WithoutCancel(ctx)
+		st := status.Convert(callErr)
+		if serverErrors[st.Code()] && result.retryable {
+			if err := m.store.Release(storeCtx, userID, key); err != nil {
+				log.Printf("Failed to release idempotency key for user %s: %v", userID, err)
+			}
+			return resp, callErr
+		}
+
+		stored, err := storedOutcome(st, resp)
+		if err != nil {
+			log.Printf("Failed to encode idempotent response for user %s: %v", userID, err)
+		} else if err := m.store.Complete(storeCtx, userID, key, int(st.Code()), grpcContentType, stored); err != nil {
+			log.Printf("Failed to store idempotent response for user %s: %v", userID, err)
+		}
+
+		return resp, callErr
+	}
+}
+
+// storedOutcome encodes the response of a call, or its status if it failed
+func storedOutcome(st *status.Status, resp interface{}) ([]byte, error) {
+	if st.Code() != codes.OK {
+		return proto.Marshal(st.Proto())
+	}
+
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("response %T is not a protobuf message", resp)
+	}
+	response, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(response)
+}
+
+// replayCall decodes the stored outcome of a call
+func replayCall(record *Record) (interface{}, error) {
+	if codes.Code(record.StatusCode) != codes.OK {
+		var st spb.Status
+		if err := proto.Unmarshal(record.Body, &st); err != nil {
+			return nil, status.Error(codes.Internal, "Failed to replay idempotent response")
+		}
+		return nil, status.FromProto(&st).Err()
+	}
+
+	var response anypb.Any
+	if err := proto.Unmarshal(record.Body, &response); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to replay idempotent response")
+	}
+	resp, err := response.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to replay idempotent response")
+	}
+	return resp, nil
+}
+
+// hashCall identifies a call by its method and request message
+func hashCall(method string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// firstValue returns the first value of a call's metadata key, if any
+func firstValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
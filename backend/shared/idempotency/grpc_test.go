@@ -0,0 +1,157 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"lfg/shared/auth"
+)
+
+const placeMethod = "/lfg.trading.v1.TradingService/PlaceOrder"
+
+// transportStream records the header a call sets
+type transportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// callStep is a call made through the interceptor and the outcome expected
+type callStep struct {
+	user      uuid.UUID
+	key       string
+	method    string
+	request   string
+	code      codes.Code // Code the handler fails with
+	retryable bool       // Whether the handler reports it had no effect
+
+	wantCode     codes.Code
+	wantReplayed bool
+	wantRuns     int // Times the handler has run after the call
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name  string
+		steps []callStep
+	}{
+		{
+			name: "retry replays the response",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", wantRuns: 1},
+				{user: alice, key: "k1", request: "order", wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "errors are replayed",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", code: codes.FailedPrecondition, wantCode: codes.FailedPrecondition, wantRuns: 1},
+				{user: alice, key: "k1", request: "order", wantCode: codes.FailedPrecondition, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "retryable server errors release the key",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", code: codes.Internal, retryable: true, wantCode: codes.Internal, wantRuns: 1},
+				{user: alice, key: "k1", request: "order", wantRuns: 2},
+				{user: alice, key: "k1", request: "order", wantReplayed: true, wantRuns: 2},
+			},
+		},
+		{
+			name: "retryable client errors are replayed",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", code: codes.InvalidArgument, retryable: true, wantCode: codes.InvalidArgument, wantRuns: 1},
+				{user: alice, key: "k1", request: "order", wantCode: codes.InvalidArgument, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "key reused with another request",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", wantRuns: 1},
+				{user: alice, key: "k1", request: "other order", wantCode: codes.InvalidArgument, wantRuns: 1},
+			},
+		},
+		{
+			name: "keys are scoped to their user",
+			steps: []callStep{
+				{user: alice, key: "k1", request: "order", wantRuns: 1},
+				{user: bob, key: "k1", request: "order", wantRuns: 2},
+			},
+		},
+		{
+			name: "calls to other methods run every time",
+			steps: []callStep{
+				{user: alice, key: "k1", method: "/lfg.trading.v1.TradingService/CancelOrder", request: "order", wantRuns: 1},
+				{user: alice, key: "k1", method: "/lfg.trading.v1.TradingService/CancelOrder", request: "order", wantRuns: 2},
+			},
+		},
+		{
+			name: "calls without a user run every time",
+			steps: []callStep{
+				{key: "k1", request: "order", wantRuns: 1},
+				{key: "k1", request: "order", wantRuns: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			interceptor := NewMiddleware(newMemoryStore(), time.Hour).UnaryServerInterceptor(placeMethod)
+
+			for i, s := range tt.steps {
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					runs++
+					if s.retryable {
+						Retryable(ctx)
+					}
+					if s.code != codes.OK {
+						return nil, status.Error(s.code, "failed")
+					}
+					return wrapperspb.String(fmt.Sprintf("run %d", runs)), nil
+				}
+
+				method := s.method
+				if method == "" {
+					method = placeMethod
+				}
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, s.key))
+				if s.user != uuid.Nil {
+					ctx = auth.WithIdentity(ctx, auth.Identity{UserID: s.user.String()})
+				}
+				stream := &transportStream{}
+				ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+				resp, err := interceptor(ctx, wrapperspb.String(s.request), &grpc.UnaryServerInfo{FullMethod: method}, handler)
+				if status.Code(err) != s.wantCode {
+					t.Errorf("step %d: code = %v, want %v", i, status.Code(err), s.wantCode)
+				}
+				if err == nil && !proto.Equal(resp.(proto.Message), wrapperspb.String(fmt.Sprintf("run %d", runs))) {
+					t.Errorf("step %d: response = %v, want that of run %d", i, resp, runs)
+				}
+				if replayed := len(stream.header.Get(ReplayedMetadataKey)) > 0; replayed != s.wantReplayed {
+					t.Errorf("step %d: replayed = %v, want %v", i, replayed, s.wantReplayed)
+				}
+				if runs != s.wantRuns {
+					t.Errorf("step %d: handler ran %d times, want %d", i, runs, s.wantRuns)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the key a client makes a request idempotent with
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from an earlier request
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// maxKeyLength bounds the keys clients can choose
+	maxKeyLength = 255
+
+	// maxBodyBytes bounds the bodies of idempotent requests, which are read
+	// in full to compare retries with the first request
+	maxBodyBytes = 1 << 20
+
+	// lockTimeout is how long a request holds its key before a retry can
+	// take it over, should the request never complete
+	lockTimeout = time.Minute
+
+	// purgeInterval is how often expired keys are deleted
+	purgeInterval = 10 * time.Minute
+)
+
+// KeyStore keeps idempotency keys and the responses of their requests;
+// Store implements it
+type KeyStore interface {
+	Claim(ctx context.Context, userID uuid.UUID, key, requestHash string, lockedUntil, expiresAt time.Time) (*Record, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// Middleware makes the requests of users carrying an Idempotency-Key header
+// idempotent. The first request made with a key runs and its response is
+// stored; retries with the same method, URI and body get that response back
+// without running, and requests reusing the key for anything else are
+// rejected. Server errors are stored too, as the request may have had effects
+// before failing, unless the handler reports with Retryable that it had
+// none; the key is then released so the request can be retried. Keys are
+// scoped to the user in X-User-ID and expire after ttl.
+type Middleware struct {
+	store KeyStore
+	ttl   time.Duration
+}
+
+// NewMiddleware creates a new idempotency middleware keeping keys for ttl
+func NewMiddleware(store KeyStore, ttl time.Duration) *Middleware {
+	return &Middleware{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Run deletes expired keys periodically until ctx is cancelled
+func (m *Middleware) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.store.Purge(ctx); err != nil {
+				log.Printf("Failed to purge idempotency keys: %v", err)
+			}
+		}
+	}
+}
+
+// Handler applies idempotency keys to the requests to next. Requests without
+// a key, or without a user, are passed through.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxKeyLength {
+			respondError(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			respondError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := hashRequest(r, body)
+		now := time.Now()
+		record, err := m.store.Claim(r.Context(), userID, key, requestHash, now.Add(lockTimeout), now.Add(m.ttl))
+		if err != nil {
+			log.Printf("Failed to claim idempotency key for user %s: %v", userID, err)
+			respondError(w, "Failed to process Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+
+		if record != nil {
+			switch {
+			case record.RequestHash != requestHash:
+				respondError(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+			case !record.Completed:
+				respondError(w, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+			default:
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.Body)
+			}
+			return
+		}
+
+		result := &outcome{}
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), outcomeKey{}, result)))
+
+		// Store the outcome even if the client went away, so its retry
+		// does not run the request again
+		ctx := context.WithoutCancel(r.Context())
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if status >= http.StatusInternalServerError && result.retryable {
+			if err := m.store.Release(ctx, userID, key); err != nil {
+				log.Printf("Failed to release idempotency key for user %s: %v", userID, err)
+			}
+			return
+		}
+
+		if err := m.store.Complete(ctx, userID, key, status, w.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store idempotent response for user %s: %v", userID, err)
+		}
+	})
+}
+
+// outcomeKey is the context key of the outcome of an idempotent request
+type outcomeKey struct{}
+
+// outcome is what the handler of an idempotent request reported about it
+type outcome struct {
+	retryable bool
+}
+
+// Retryable reports from the handler of the request ctx belongs to that the
+// request is failing without having had any effect, so a server error
+// releases its Idempotency-Key for a retry rather than being replayed
+func Retryable(ctx context.Context) {
+	if result, ok := ctx.Value(outcomeKey{}).(*outcome); ok {
+		result.retryable = true
+	}
+}
+
+// hashRequest identifies a request by its method, URI and body
+func hashRequest(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte("\n"))
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func respondError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
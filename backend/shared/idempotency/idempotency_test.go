@@ -0,0 +1,283 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryStore keeps keys in memory with the claim rules of Store
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*memoryRecord
+}
+
+type memoryRecord struct {
+	Record
+	lockedUntil time.Time
+	expiresAt   time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{keys: make(map[string]*memoryRecord)}
+}
+
+func (s *memoryStore) Claim(_ context.Context, userID uuid.UUID, key, requestHash string, lockedUntil, expiresAt time.Time) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	id := userID.String() + "/" + key
+	if existing, ok := s.keys[id]; ok {
+		abandoned := !existing.Completed && existing.lockedUntil.Before(now) && existing.RequestHash == requestHash
+		if !existing.expiresAt.Before(now) && !abandoned {
+			record := existing.Record
+			return &record, nil
+		}
+	}
+
+	s.keys[id] = &memoryRecord{Record: Record{RequestHash: requestHash}, lockedUntil: lockedUntil, expiresAt: expiresAt}
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.keys[userID.String()+"/"+key]
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = body
+	record.Completed = true
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, userID uuid.UUID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := userID.String() + "/" + key
+	if record, ok := s.keys[id]; ok && !record.Completed {
+		delete(s.keys, id)
+	}
+	return nil
+}
+
+func (s *memoryStore) Purge(context.Context) (int64, error) {
+	return 0, nil
+}
+
+// step is a request made through the middleware and the response expected
+type step struct {
+	user      uuid.UUID
+	key       string
+	uri       string
+	body      string
+	status    int  // Status the handler responds with
+	retryable bool // Whether the handler reports it had no effect
+
+	wantStatus   int
+	wantReplayed bool
+	wantRuns     int // Times the handler has run after the request
+}
+
+func TestMiddleware(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "retry replays the response",
+			steps: []step{
+				{user: alice, key: "k1", body: `{"quantity":1}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{user: alice, key: "k1", body: `{"quantity":1}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantReplayed: true, wantRuns: 1},
+				{user: alice, key: "k1", body: `{"quantity":1}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "client errors are replayed",
+			steps: []step{
+				{user: alice, key: "k1", body: `{}`, status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantRuns: 1},
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusBadRequest, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "server errors are replayed",
+			steps: []step{
+				{user: alice, key: "k1", body: `{}`, status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError, wantRuns: 1},
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusInternalServerError, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "retryable server errors release the key",
+			steps: []step{
+				{user: alice, key: "k1", body: `{}`, status: http.StatusServiceUnavailable, retryable: true, wantStatus: http.StatusServiceUnavailable, wantRuns: 1},
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 2},
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantReplayed: true, wantRuns: 2},
+			},
+		},
+		{
+			name: "retryable client errors are replayed",
+			steps: []step{
+				{user: alice, key: "k1", body: `{}`, status: http.StatusBadRequest, retryable: true, wantStatus: http.StatusBadRequest, wantRuns: 1},
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusBadRequest, wantReplayed: true, wantRuns: 1},
+			},
+		},
+		{
+			name: "key reused with another body",
+			steps: []step{
+				{user: alice, key: "k1", body: `{"quantity":1}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{user: alice, key: "k1", body: `{"quantity":2}`, status: http.StatusCreated, wantStatus: http.StatusUnprocessableEntity, wantRuns: 1},
+			},
+		},
+		{
+			name: "key reused on another URI",
+			steps: []step{
+				{user: alice, key: "k1", uri: "/orders/place", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{user: alice, key: "k1", uri: "/orders/place?dry_run=true", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusUnprocessableEntity, wantRuns: 1},
+			},
+		},
+		{
+			name: "keys are scoped to their user",
+			steps: []step{
+				{user: alice, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{user: bob, key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 2},
+			},
+		},
+		{
+			name: "requests without a key run every time",
+			steps: []step{
+				{user: alice, body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{user: alice, body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 2},
+			},
+		},
+		{
+			name: "requests without a user run every time",
+			steps: []step{
+				{key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 1},
+				{key: "k1", body: `{}`, status: http.StatusCreated, wantStatus: http.StatusCreated, wantRuns: 2},
+			},
+		},
+		{
+			name: "key too long",
+			steps: []step{
+				{user: alice, key: strings.Repeat("k", maxKeyLength+1), body: `{}`, status: http.StatusCreated, wantStatus: http.StatusBadRequest},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			m := NewMiddleware(newMemoryStore(), time.Hour)
+
+			for i, s := range tt.steps {
+				handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					runs++
+					if s.retryable {
+						Retryable(r.Context())
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(s.status)
+					fmt.Fprintf(w, `{"run":%d}`, runs)
+				}))
+
+				w := serve(handler, s)
+				if w.Code != s.wantStatus {
+					t.Errorf("step %d: status = %d, want %d: %s", i, w.Code, s.wantStatus, w.Body)
+				}
+				if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != s.wantReplayed {
+					t.Errorf("step %d: replayed = %v, want %v", i, replayed, s.wantReplayed)
+				}
+				if s.wantReplayed && w.Body.String() != fmt.Sprintf(`{"run":%d}`, runs) {
+					t.Errorf("step %d: replayed body %s is not the last run's", i, w.Body)
+				}
+				if runs != s.wantRuns {
+					t.Errorf("step %d: handler ran %d times, want %d", i, runs, s.wantRuns)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareConflictsWhileInFlight(t *testing.T) {
+	alice := uuid.New()
+	request := step{user: alice, key: "k1", body: `{}`}
+
+	m := NewMiddleware(newMemoryStore(), time.Hour)
+
+	// The retry arrives while the first request is still running
+	var retry *httptest.ResponseRecorder
+	var inner http.Handler
+	inner = m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retry == nil {
+			retry = serve(inner, request)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	if w := serve(inner, request); w.Code != http.StatusCreated {
+		t.Fatalf("first request: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if retry.Code != http.StatusConflict {
+		t.Errorf("concurrent retry: status = %d, want %d", retry.Code, http.StatusConflict)
+	}
+	if w := serve(inner, request); w.Code != http.StatusCreated || w.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("later retry: status = %d, replayed = %q; want a replayed %d", w.Code, w.Header().Get(ReplayedHeader), http.StatusCreated)
+	}
+}
+
+func TestMiddlewareTakesOverAbandonedKeys(t *testing.T) {
+	alice := uuid.New()
+	store := newMemoryStore()
+	m := NewMiddleware(store, time.Hour)
+
+	// A request that claimed the key and never completed, whose lock passed
+	hash := hashRequest(httptest.NewRequest(http.MethodPost, "/orders/place", nil), []byte(`{}`))
+	past := time.Now().Add(-time.Second)
+	if _, err := store.Claim(context.Background(), alice, "k1", hash, past, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	other := step{user: alice, key: "k1", body: `{"quantity":2}`}
+	if w := serve(handler, other); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("other request: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if w := serve(handler, step{user: alice, key: "k1", body: `{}`}); w.Code != http.StatusCreated || runs != 1 {
+		t.Errorf("retry: status = %d after %d runs, want %d after 1", w.Code, runs, http.StatusCreated)
+	}
+}
+
+func serve(handler http.Handler, s step) *httptest.ResponseRecorder {
+	uri := s.uri
+	if uri == "" {
+		uri = "/orders/place"
+	}
+
+	r := httptest.NewRequest(http.MethodPost, uri, strings.NewReader(s.body))
+	if s.key != "" {
+		r.Header.Set(Header, s.key)
+	}
+	if s.user != uuid.Nil {
+		r.Header.Set("X-User-ID", s.user.String())
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,130 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Record is the state of a key claimed by an earlier request
+type Record struct {
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Completed   bool
+}
+
+// Store keeps idempotency keys and the responses of their requests in the
+// "idempotency_keys" table
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore creates a new idempotency key store
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Claim claims a user's key for a request with the given hash until
+// lockedUntil, keeping it until expiresAt. It returns nil if the key was
+// claimed, or the record of the request that claimed it before. A key can
+// be claimed again once it has expired, or when its request never completed
+// and its lock has passed.
+func (s *Store) Claim(ctx context.Context, userID uuid.UUID, key, requestHash string, lockedUntil, expiresAt time.Time) (*Record, error) {
+	// The earlier claim can expire between the two queries, so try twice
+	for attempt := 0; attempt < 2; attempt++ {
+		var claimed bool
+		err := s.pool.QueryRow(ctx, `
+			INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, locked_until, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+				request_hash = EXCLUDED.request_hash,
+				status_code = NULL,
+				content_type = NULL,
+				response_body = NULL,
+				locked_until = EXCLUDED.locked_until,
+				completed_at = NULL,
+				expires_at = EXCLUDED.expires_at,
+				created_at = NOW()
+			WHERE idempotency_keys.expires_at < NOW()
+			   OR (idempotency_keys.completed_at IS NULL
+			       AND idempotency_keys.locked_until < NOW()
+			       AND idempotency_keys.request_hash = EXCLUDED.request_hash)
+			RETURNING TRUE
+		`, userID, key, requestHash, lockedUntil, expiresAt).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		var record Record
+		var statusCode *int
+		var contentType *string
+		var completedAt *time.Time
+		err = s.pool.QueryRow(ctx, `
+			SELECT request_hash, status_code, content_type, response_body, completed_at
+			FROM idempotency_keys
+			WHERE user_id = $1 AND idempotency_key = $2
+		`, userID, key).Scan(&record.RequestHash, &statusCode, &contentType, &record.Body, &completedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+
+		if statusCode != nil {
+			record.StatusCode = *statusCode
+		}
+		if contentType != nil {
+			record.ContentType = *contentType
+		}
+		record.Completed = completedAt != nil
+		return &record, nil
+	}
+
+	return nil, fmt.Errorf("failed to claim idempotency key: claim kept changing")
+}
+
+// Complete stores the response of the request that claimed a key
+func (s *Store) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release gives up the claim on a key whose request failed, so a retry can
+// make it again
+func (s *Store) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	_, err := s.pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND completed_at IS NULL
+	`, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes expired keys, returning how many it deleted
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcServerHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lfg_grpc_server_handled_total",
+		Help: "RPCs completed by the server, by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcServerHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lfg_grpc_server_handling_seconds",
+		Help:    "Duration of RPCs handled by the server, by method.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method"})
+)
+
+func init() {
+	registry.MustRegister(grpcServerHandled, grpcServerHandlingSeconds)
+}
+
+// UnaryServerInterceptor records the outcome and duration of unary RPCs.
+// It should come first in the chain so refused calls are recorded too.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the outcome and duration of streaming
+// RPCs
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+// observeRPC records an RPC that finished with err
+func observeRPC(method string, start time.Time, err error) {
+	grpcServerHandled.WithLabelValues(method, status.Code(err).String()).Inc()
+	grpcServerHandlingSeconds.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path metrics are served on by every service
+const Path = "/metrics"
+
+// registry holds the metrics of this process. Each process runs one service,
+// which Prometheus identifies by its scrape job.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lfg_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	natsPublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lfg_nats_publish_failures_total",
+		Help: "NATS messages that could not be published, by subject.",
+	}, []string{"subject"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		natsPublishFailures,
+	)
+}
+
+// MustRegister registers the collectors of a service with the registry
+// served on /metrics. It panics if a collector is already registered.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// Endpoint serves the metrics of this process. If token is set, scrapes
+// must send it as a bearer token.
+func Endpoint(token string) http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Handler records the latency and status of the requests to next, labelled
+// with the pattern of routes the request matches so that path parameters do
+// not add series. Requests matching no pattern are labelled "unmatched".
+func Handler(routes *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := "unmatched"
+		if _, pattern := routes.Handler(r); pattern != "" {
+			route = pattern
+		}
+
+		m := httpsnoop.CaptureMetrics(next, w, r)
+		httpRequestDuration.
+			WithLabelValues(route, method(r.Method), strconv.Itoa(m.Code)).
+			Observe(m.Duration.Seconds())
+	})
+}
+
+// method bounds the values of the method label to the standard methods
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	default:
+		return "OTHER"
+	}
+}
+
+// PublishFailed counts a message that could not be published on subject.
+// IDs in the subject, such as the contract of "trades.<contract_id>", are
+// replaced with "*".
+func PublishFailed(subject string) {
+	natsPublishFailures.WithLabelValues(subjectPattern(subject)).Inc()
+}
+
+// subjectPattern replaces the UUID tokens of a NATS subject with wildcards
+func subjectPattern(subject string) string {
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		if _, err := uuid.Parse(token); err == nil {
+			tokens[i] = "*"
+		}
+	}
+	return strings.Join(tokens, ".")
+}
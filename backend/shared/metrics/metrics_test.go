@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scrape returns the metrics served on /metrics
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Endpoint("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d, want %d", w.Code, http.StatusOK)
+	}
+	return w.Body.String()
+}
+
+// wantSeries fails the test unless the scrape has a sample starting with
+// series
+func wantSeries(t *testing.T, metrics string, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(metrics, "\n"+s+" ") {
+			t.Errorf("no sample of %s", s)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /test/markets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := Handler(mux, mux)
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/test/markets/"+uuid.NewString(), nil),
+		httptest.NewRequest(http.MethodGet, "/test/markets/"+uuid.NewString(), nil),
+		httptest.NewRequest("PURGE", "/test/nowhere", nil),
+		httptest.NewRequest(http.MethodGet, Path, nil),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	metrics := scrape(t)
+	wantSeries(t, metrics, `lfg_http_request_duration_seconds_count{method="OTHER",route="unmatched",status="404"}`)
+	if !strings.Contains(metrics, "\n"+`lfg_http_request_duration_seconds_count{method="GET",route="GET /test/markets/{id}",status="418"} 2`+"\n") {
+		t.Errorf("requests to one route with different IDs were not counted in one series")
+	}
+	if strings.Contains(metrics, `method="GET",route="unmatched"`) {
+		t.Errorf("recorded the scrape of %s", Path)
+	}
+}
+
+func TestEndpointToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"token", "Bearer secret", http.StatusOK},
+		{"wrong token", "Bearer other", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, Path, nil)
+			r.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			Endpoint("secret").ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.v1.Test/Call"}
+
+	for _, err := range []error{nil, status.Error(codes.NotFound, "not found")} {
+		interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+
+	wantSeries(t, scrape(t),
+		`lfg_grpc_server_handled_total{code="OK",method="/test.v1.Test/Call"}`,
+		`lfg_grpc_server_handled_total{code="NotFound",method="/test.v1.Test/Call"}`,
+		`lfg_grpc_server_handling_seconds_count{method="/test.v1.Test/Call"}`,
+	)
+}
+
+func TestSubjectPattern(t *testing.T) {
+	id := uuid.NewString()
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"trades." + id, "trades.*"},
+		{"orders." + id + ".filled", "orders.*.filled"},
+		{"markets.resolved", "markets.resolved"},
+	}
+
+	for _, tt := range tests {
+		if got := subjectPattern(tt.subject); got != tt.want {
+			t.Errorf("subjectPattern(%q) = %q, want %q", tt.subject, got, tt.want)
+		}
+	}
+}
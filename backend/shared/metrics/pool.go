@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConns = prometheus.NewDesc("lfg_db_pool_acquired_connections",
+		"Connections currently acquired from the pool.", nil, nil)
+	poolIdleConns = prometheus.NewDesc("lfg_db_pool_idle_connections",
+		"Idle connections in the pool.", nil, nil)
+	poolConstructingConns = prometheus.NewDesc("lfg_db_pool_constructing_connections",
+		"Connections currently being established.", nil, nil)
+	poolTotalConns = prometheus.NewDesc("lfg_db_pool_total_connections",
+		"Connections in the pool.", nil, nil)
+	poolMaxConns = prometheus.NewDesc("lfg_db_pool_max_connections",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc("lfg_db_pool_acquires_total",
+		"Successful connection acquires.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc("lfg_db_pool_empty_acquires_total",
+		"Acquires that had to wait for a connection because none was idle.", nil, nil)
+	poolCanceledAcquires = prometheus.NewDesc("lfg_db_pool_canceled_acquires_total",
+		"Acquires cancelled by their context.", nil, nil)
+	poolAcquireSeconds = prometheus.NewDesc("lfg_db_pool_acquire_seconds_total",
+		"Total time spent acquiring connections.", nil, nil)
+)
+
+// poolCollector reads the statistics of a connection pool at scrape time
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// RegisterPool exports the statistics of a service's connection pool
+func RegisterPool(pool *pgxpool.Pool) {
+	MustRegister(&poolCollector{pool: pool})
+}
+
+// Describe implements prometheus.Collector
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolConstructingConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolCanceledAcquires
+	ch <- poolAcquireSeconds
+}
+
+// Collect implements prometheus.Collector
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolConstructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireSeconds, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AlertRuleType represents the condition an alert rule watches for
+type AlertRuleType string
+
+const (
+	AlertRulePriceAbove     AlertRuleType = "PRICE_ABOVE"
+	AlertRulePriceBelow     AlertRuleType = "PRICE_BELOW"
+	AlertRuleSpreadBelow    AlertRuleType = "SPREAD_BELOW"
+	AlertRuleClosingSoon    AlertRuleType = "CLOSING_SOON"
+	AlertRuleMarketResolved AlertRuleType = "MARKET_RESOLVED"
+)
+
+// WatchlistEntry represents a market on a user's watchlist
+type WatchlistEntry struct {
+	MarketID  uuid.UUID    `json:"market_id" db:"market_id"`
+	Ticker    string       `json:"ticker" db:"ticker"`
+	Question  string       `json:"question" db:"question"`
+	Status    MarketStatus `json:"status" db:"status"`
+	ExpiresAt time.Time    `json:"expires_at" db:"expires_at"`
+	AddedAt   time.Time    `json:"added_at" db:"created_at"`
+}
+
+// WatchlistRequest represents the request to add a market to or remove it
+// from the watchlist
+type WatchlistRequest struct {
+	MarketID uuid.UUID `json:"market_id" validate:"required"`
+}
+
+// AlertRule represents the alert rule model corresponding to the "alert_rules"
+// table. Threshold is a contract price for PRICE_ABOVE and PRICE_BELOW, a
+// bid/ask spread for SPREAD_BELOW and seconds before expiry for CLOSING_SOON.
+type AlertRule struct {
+	ID          uuid.UUID     `json:"id" db:"id"`
+	UserID      uuid.UUID     `json:"user_id" db:"user_id"`
+	MarketID    uuid.UUID     `json:"market_id" db:"market_id" validate:"required"`
+	ContractID  *uuid.UUID    `json:"contract_id,omitempty" db:"contract_id"`
+	Type        AlertRuleType `json:"type" db:"rule_type" validate:"required,oneof=PRICE_ABOVE PRICE_BELOW SPREAD_BELOW CLOSING_SOON MARKET_RESOLVED"`
+	Threshold   *float64      `json:"threshold,omitempty" db:"threshold"`
+	Active      bool          `json:"active" db:"active"`
+	TriggeredAt *time.Time    `json:"triggered_at,omitempty" db:"triggered_at"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+}
+
+// AlertRuleCreateRequest represents the request to create an alert rule
+type AlertRuleCreateRequest struct {
+	MarketID   uuid.UUID     `json:"market_id" validate:"required"`
+	ContractID *uuid.UUID    `json:"contract_id,omitempty"`
+	Type       AlertRuleType `json:"type" validate:"required,oneof=PRICE_ABOVE PRICE_BELOW SPREAD_BELOW CLOSING_SOON MARKET_RESOLVED"`
+	Threshold  *float64      `json:"threshold,omitempty"`
+}
+
+// AlertRuleDeleteRequest represents the request to delete an alert rule
+type AlertRuleDeleteRequest struct {
+	RuleID uuid.UUID `json:"rule_id" validate:"required"`
+}
+
+// Alert represents a triggered alert corresponding to the "alerts" table
+type Alert struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	RuleID    *uuid.UUID      `json:"rule_id,omitempty" db:"rule_id"`
+	UserID    uuid.UUID       `json:"user_id" db:"user_id"`
+	MarketID  uuid.UUID       `json:"market_id" db:"market_id"`
+	Type      AlertRuleType   `json:"type" db:"rule_type"`
+	Message   string          `json:"message" db:"message"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AlertListResponse represents a page of a user's alert history
+type AlertListResponse struct {
+	Alerts     []*Alert `json:"alerts"`
+	TotalCount int      `json:"total_count"`
+	Page       int      `json:"page"`
+	PageSize   int      `json:"page_size"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyScope represents a group of routes an API key can reach
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead     APIKeyScope = "read"
+	APIKeyScopeTrade    APIKeyScope = "trade"
+	APIKeyScopeWithdraw APIKeyScope = "withdraw"
+)
+
+// Valid reports whether s is a known API key scope
+func (s APIKeyScope) Valid() bool {
+	return s == APIKeyScopeRead || s == APIKeyScopeTrade || s == APIKeyScopeWithdraw
+}
+
+// APIKey represents a user's API key corresponding to the "api_keys" table.
+// KeyID identifies the key in signed requests. Secret signs them and is only
+// returned when the key is created. Requests are only accepted from
+// AllowedIPs, addresses or CIDR ranges, when it is not empty.
+type APIKey struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	UserID     uuid.UUID     `json:"user_id" db:"user_id"`
+	KeyID      string        `json:"key_id" db:"key_id"`
+	Secret     string        `json:"secret,omitempty" db:"secret"`
+	Name       string        `json:"name" db:"name"`
+	Scopes     []APIKeyScope `json:"scopes" db:"scopes"`
+	AllowedIPs []string      `json:"allowed_ips" db:"allowed_ips"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+}
+
+// HasScope reports whether the key was given scope
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Usable reports whether the key can still sign requests at now
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// AllowsIP reports whether ip is in the key's allow-list of addresses and
+// CIDR ranges. An empty list allows every address.
+func (k *APIKey) AllowsIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, entry := range k.AllowedIPs {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyCreateRequest represents the request to create an API key
+type APIKeyCreateRequest struct {
+	Name       string        `json:"name" validate:"required,max=100"`
+	Scopes     []APIKeyScope `json:"scopes" validate:"required,min=1,dive,oneof=read trade withdraw"`
+	AllowedIPs []string      `json:"allowed_ips,omitempty"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+}
+
+// APIKeyRequest represents a request naming one of the user's API keys
+type APIKeyRequest struct {
+	APIKeyID uuid.UUID `json:"api_key_id" validate:"required"`
+}
+
+// APIKeyCredentials is what the API gateway needs to verify the requests of
+// a key: the key with its secret and the identity of its owner
+type APIKeyCredentials struct {
+	Key   *APIKey  `json:"key"`
+	Email string   `json:"email"`
+	Role  UserRole `json:"role"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction represents an audited admin action
+type AuditAction string
+
+const (
+	AuditActionMarketCreate  AuditAction = "MARKET_CREATE"
+	AuditActionMarketUpdate  AuditAction = "MARKET_UPDATE"
+	AuditActionMarketPublish AuditAction = "MARKET_PUBLISH"
+	AuditActionMarketClose   AuditAction = "MARKET_CLOSE"
+	AuditActionMarketCancel  AuditAction = "MARKET_CANCEL"
+	AuditActionMarketResolve AuditAction = "MARKET_RESOLVE"
+
+	AuditActionResolverConfigure    AuditAction = "RESOLVER_CONFIGURE"
+	AuditActionResolutionPropose    AuditAction = "RESOLUTION_PROPOSE"
+	AuditActionResolutionAdjudicate AuditAction = "RESOLUTION_ADJUDICATE"
+
+	AuditActionEventCreate  AuditAction = "EVENT_CREATE"
+	AuditActionEventMarkets AuditAction = "EVENT_MARKETS"
+	AuditActionEventResolve AuditAction = "EVENT_RESOLVE"
+	AuditActionEventCancel  AuditAction = "EVENT_CANCEL"
+
+	AuditActionTemplateCreate AuditAction = "TEMPLATE_CREATE"
+	AuditActionTemplateUpdate AuditAction = "TEMPLATE_UPDATE"
+)
+
+// AuditLogEntry represents the audit log model corresponding to the "admin_audit_log" table
+type AuditLogEntry struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	ActorUserID uuid.UUID       `json:"actor_user_id" db:"actor_user_id" validate:"required"`
+	Action      AuditAction     `json:"action" db:"action" validate:"required"`
+	MarketID    *uuid.UUID      `json:"market_id,omitempty" db:"market_id"`
+	Details     json.RawMessage `json:"details" db:"details"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailMode represents how a category of notifications is emailed
+type EmailMode string
+
+const (
+	EmailModeImmediate EmailMode = "IMMEDIATE"
+	EmailModeDigest    EmailMode = "DIGEST"
+	EmailModeOff       EmailMode = "OFF"
+)
+
+// Valid reports whether m is a known email mode
+func (m EmailMode) Valid() bool {
+	return m == EmailModeImmediate || m == EmailModeDigest || m == EmailModeOff
+}
+
+// EmailDigestInterval represents how often digest emails are sent
+type EmailDigestInterval string
+
+const (
+	EmailDigestHourly EmailDigestInterval = "HOURLY"
+	EmailDigestDaily  EmailDigestInterval = "DAILY"
+)
+
+// Valid reports whether i is a known digest interval
+func (i EmailDigestInterval) Valid() bool {
+	return i == EmailDigestHourly || i == EmailDigestDaily
+}
+
+// Duration returns the time between two digests
+func (i EmailDigestInterval) Duration() time.Duration {
+	if i == EmailDigestHourly {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// EmailStatus represents the delivery status of an outgoing email
+type EmailStatus string
+
+const (
+	EmailStatusPending EmailStatus = "PENDING"
+	EmailStatusSent    EmailStatus = "SENT"
+	EmailStatusFailed  EmailStatus = "FAILED"
+)
+
+// EmailPreferences represents the email preferences model corresponding to
+// the "email_preferences" table
+type EmailPreferences struct {
+	UserID         uuid.UUID           `json:"user_id" db:"user_id"`
+	Fills          EmailMode           `json:"fills" db:"fills"`
+	Settlements    EmailMode           `json:"settlements" db:"settlements"`
+	Transfers      EmailMode           `json:"transfers" db:"transfers"`
+	Security       EmailMode           `json:"security" db:"security"`
+	Alerts         EmailMode           `json:"alerts" db:"alerts"`
+	DigestInterval EmailDigestInterval `json:"digest_interval" db:"digest_interval"`
+}
+
+// DefaultEmailPreferences returns the preferences of a user who never set
+// any, matching the table's column defaults
+func DefaultEmailPreferences(userID uuid.UUID) *EmailPreferences {
+	return &EmailPreferences{
+		UserID:         userID,
+		Fills:          EmailModeDigest,
+		Settlements:    EmailModeImmediate,
+		Transfers:      EmailModeImmediate,
+		Security:       EmailModeImmediate,
+		Alerts:         EmailModeOff,
+		DigestInterval: EmailDigestDaily,
+	}
+}
+
+// ModeFor returns how notifications of a type are emailed
+func (p *EmailPreferences) ModeFor(notificationType NotificationType) EmailMode {
+	switch notificationType {
+	case NotificationOrderFilled:
+		return p.Fills
+	case NotificationMarketSettled, NotificationMarketRefund:
+		return p.Settlements
+	case NotificationDeposit, NotificationWithdrawal:
+		return p.Transfers
+	case NotificationNewLogin:
+		return p.Security
+	case NotificationAlert:
+		return p.Alerts
+	default:
+		return EmailModeOff
+	}
+}
+
+// EmailPreferencesUpdateRequest represents the request to change email
+// preferences; omitted fields are left unchanged
+type EmailPreferencesUpdateRequest struct {
+	Fills          *EmailMode           `json:"fills,omitempty" validate:"omitempty,oneof=IMMEDIATE DIGEST OFF"`
+	Settlements    *EmailMode           `json:"settlements,omitempty" validate:"omitempty,oneof=IMMEDIATE DIGEST OFF"`
+	Transfers      *EmailMode           `json:"transfers,omitempty" validate:"omitempty,oneof=IMMEDIATE DIGEST OFF"`
+	Security       *EmailMode           `json:"security,omitempty" validate:"omitempty,oneof=IMMEDIATE DIGEST OFF"`
+	Alerts         *EmailMode           `json:"alerts,omitempty" validate:"omitempty,oneof=IMMEDIATE DIGEST OFF"`
+	DigestInterval *EmailDigestInterval `json:"digest_interval,omitempty" validate:"omitempty,oneof=HOURLY DAILY"`
+}
+
+// Valid reports whether every field set in the request holds a known value
+func (req *EmailPreferencesUpdateRequest) Valid() bool {
+	for _, mode := range []*EmailMode{req.Fills, req.Settlements, req.Transfers, req.Security, req.Alerts} {
+		if mode != nil && !mode.Valid() {
+			return false
+		}
+	}
+	return req.DigestInterval == nil || req.DigestInterval.Valid()
+}
+
+// Apply sets the preferences changed by req
+func (p *EmailPreferences) Apply(req *EmailPreferencesUpdateRequest) {
+	if req.Fills != nil {
+		p.Fills = *req.Fills
+	}
+	if req.Settlements != nil {
+		p.Settlements = *req.Settlements
+	}
+	if req.Transfers != nil {
+		p.Transfers = *req.Transfers
+	}
+	if req.Security != nil {
+		p.Security = *req.Security
+	}
+	if req.Alerts != nil {
+		p.Alerts = *req.Alerts
+	}
+	if req.DigestInterval != nil {
+		p.DigestInterval = *req.DigestInterval
+	}
+}
+
+// EmailMessage represents an outgoing email corresponding to the
+// "email_outbox" table
+type EmailMessage struct {
+	ID             uuid.UUID   `json:"id" db:"id"`
+	UserID         uuid.UUID   `json:"user_id" db:"user_id"`
+	NotificationID *uuid.UUID  `json:"notification_id,omitempty" db:"notification_id"`
+	To             string      `json:"to" db:"to_address"`
+	Subject        string      `json:"subject" db:"subject"`
+	TextBody       string      `json:"text_body" db:"text_body"`
+	HTMLBody       string      `json:"html_body" db:"html_body"`
+	Status         EmailStatus `json:"status" db:"status"`
+	Attempts       int         `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time   `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError      *string     `json:"last_error,omitempty" db:"last_error"`
+	SentAt         *time.Time  `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+}
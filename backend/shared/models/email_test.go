@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEmailPreferencesModeFor(t *testing.T) {
+	prefs := &EmailPreferences{
+		Fills:       EmailModeDigest,
+		Settlements: EmailModeImmediate,
+		Transfers:   EmailModeOff,
+		Security:    EmailModeImmediate,
+		Alerts:      EmailModeDigest,
+	}
+
+	tests := []struct {
+		notificationType NotificationType
+		want             EmailMode
+	}{
+		{NotificationOrderFilled, EmailModeDigest},
+		{NotificationMarketSettled, EmailModeImmediate},
+		{NotificationMarketRefund, EmailModeImmediate},
+		{NotificationDeposit, EmailModeOff},
+		{NotificationWithdrawal, EmailModeOff},
+		{NotificationNewLogin, EmailModeImmediate},
+		{NotificationAlert, EmailModeDigest},
+		{NotificationType("GOSSIP"), EmailModeOff},
+	}
+
+	for _, tt := range tests {
+		if got := prefs.ModeFor(tt.notificationType); got != tt.want {
+			t.Errorf("ModeFor(%s) = %s, want %s", tt.notificationType, got, tt.want)
+		}
+	}
+}
+
+func TestEmailPreferencesApply(t *testing.T) {
+	off, immediate, bogus := EmailModeOff, EmailModeImmediate, EmailMode("SOMETIMES")
+	hourly, weekly := EmailDigestHourly, EmailDigestInterval("WEEKLY")
+
+	tests := []struct {
+		name      string
+		req       EmailPreferencesUpdateRequest
+		wantValid bool
+		want      EmailPreferences
+	}{
+		{
+			name:      "nothing changed",
+			wantValid: true,
+			want:      *DefaultEmailPreferences(uuid.Nil),
+		},
+		{
+			name:      "some fields changed",
+			req:       EmailPreferencesUpdateRequest{Fills: &immediate, Security: &off, DigestInterval: &hourly},
+			wantValid: true,
+			want: EmailPreferences{
+				Fills:          EmailModeImmediate,
+				Settlements:    EmailModeImmediate,
+				Transfers:      EmailModeImmediate,
+				Security:       EmailModeOff,
+				Alerts:         EmailModeOff,
+				DigestInterval: EmailDigestHourly,
+			},
+		},
+		{name: "unknown mode", req: EmailPreferencesUpdateRequest{Alerts: &bogus}},
+		{name: "unknown interval", req: EmailPreferencesUpdateRequest{DigestInterval: &weekly}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := tt.req.Valid(); valid != tt.wantValid {
+				t.Fatalf("Valid() = %v, want %v", valid, tt.wantValid)
+			}
+			if !tt.wantValid {
+				return
+			}
+
+			prefs := DefaultEmailPreferences(uuid.Nil)
+			prefs.Apply(&tt.req)
+			if *prefs != tt.want {
+				t.Errorf("preferences = %+v, want %+v", *prefs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event represents the event model corresponding to the "events" table. An
+// event groups related markets, e.g. every market on one election.
+type Event struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Ticker      string    `json:"ticker" db:"ticker" validate:"required,uppercase,max=50"`
+	Title       string    `json:"title" db:"title" validate:"required,max=255"`
+	Description string    `json:"description" db:"description"`
+	Category    *string   `json:"category,omitempty" db:"category" validate:"omitempty,max=50"`
+	ClosesAt    time.Time `json:"closes_at" db:"closes_at" validate:"required"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EventSummary represents an event with aggregate figures over its markets
+type EventSummary struct {
+	*Event
+	MarketCount int     `json:"market_count"`
+	OpenMarkets int     `json:"open_markets"`
+	Volume      float64 `json:"volume_credits"`
+}
+
+// EventDetail represents an event page: the event, its markets and their
+// traded volume
+type EventDetail struct {
+	*EventSummary
+	Markets []*Market `json:"markets"`
+}
+
+// EventCreateRequest represents the request to create a new event
+type EventCreateRequest struct {
+	Ticker      string    `json:"ticker" validate:"required,uppercase,max=50"`
+	Title       string    `json:"title" validate:"required,max=255"`
+	Description string    `json:"description"`
+	Category    *string   `json:"category,omitempty" validate:"omitempty,max=50"`
+	ClosesAt    time.Time `json:"closes_at" validate:"required"`
+}
+
+// EventMarketsRequest represents the request to attach markets to an event.
+// A nil event ID detaches them.
+type EventMarketsRequest struct {
+	EventID   *uuid.UUID  `json:"event_id"`
+	MarketIDs []uuid.UUID `json:"market_ids" validate:"required,min=1"`
+}
+
+// EventResolveRequest represents the request to resolve every unsettled
+// market of an event at once
+type EventResolveRequest struct {
+	EventID     uuid.UUID               `json:"event_id" validate:"required"`
+	Resolutions []*MarketResolveRequest `json:"resolutions" validate:"required,min=1,dive"`
+}
+
+// EventActionRequest represents an admin action on every market of an event
+type EventActionRequest struct {
+	EventID uuid.UUID `json:"event_id" validate:"required"`
+	Reason  string    `json:"reason,omitempty" validate:"max=500"`
+}
+
+// EventListResponse represents the response for listing events
+type EventListResponse struct {
+	Events     []*EventSummary `json:"events"`
+	TotalCount int             `json:"total_count"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+}
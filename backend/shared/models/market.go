@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,7 @@ import (
 type MarketStatus string
 
 const (
+	MarketStatusDraft     MarketStatus = "DRAFT"
 	MarketStatusUpcoming  MarketStatus = "UPCOMING"
 	MarketStatusOpen      MarketStatus = "OPEN"
 	MarketStatusClosed    MarketStatus = "CLOSED"
@@ -17,65 +19,215 @@ const (
 	MarketStatusCancelled MarketStatus = "CANCELLED"
 )
 
-// MarketOutcome represents the outcome of a resolved market
-type MarketOutcome string
+// MarketType represents the payout structure of a market
+type MarketType string
 
 const (
-	MarketOutcomeYes       MarketOutcome = "YES"
-	MarketOutcomeNo        MarketOutcome = "NO"
-	MarketOutcomeCancelled MarketOutcome = "CANCELLED"
+	MarketTypeBinary      MarketType = "BINARY"
+	MarketTypeCategorical MarketType = "CATEGORICAL"
+	MarketTypeScalar      MarketType = "SCALAR"
+)
+
+// Binary markets are categorical markets with exactly these two outcomes
+const (
+	OutcomeNameYes = "YES"
+	OutcomeNameNo  = "NO"
+)
+
+// Scalar markets have exactly these two outcomes
+const (
+	OutcomeNameLong  = "LONG"
+	OutcomeNameShort = "SHORT"
 )
 
 // Market represents the market model corresponding to the "markets" table
 type Market struct {
-	ID               uuid.UUID      `json:"id" db:"id"`
-	Ticker           string         `json:"ticker" db:"ticker" validate:"required,uppercase,max=50"`
-	Question         string         `json:"question" db:"question" validate:"required,min=10,max=500"`
-	Rules            string         `json:"rules" db:"rules" validate:"required"`
-	ResolutionSource string         `json:"resolution_source" db:"resolution_source" validate:"required,max=255"`
-	Status           MarketStatus   `json:"status" db:"status" validate:"required"`
-	ExpiresAt        time.Time      `json:"expires_at" db:"expires_at" validate:"required"`
-	ResolvedAt       *time.Time     `json:"resolved_at,omitempty" db:"resolved_at"`
-	Outcome          *MarketOutcome `json:"outcome,omitempty" db:"outcome"`
-	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
+	ID               uuid.UUID    `json:"id" db:"id"`
+	Ticker           string       `json:"ticker" db:"ticker" validate:"required,uppercase,max=50"`
+	Question         string       `json:"question" db:"question" validate:"required,min=10,max=500"`
+	Rules            string       `json:"rules" db:"rules" validate:"required"`
+	ResolutionSource string       `json:"resolution_source" db:"resolution_source" validate:"required,max=255"`
+	Category         *string      `json:"category,omitempty" db:"category" validate:"omitempty,max=50"`
+	Tags             []string     `json:"tags" db:"tags" validate:"max=10,dive,max=50"`
+	EventID          *uuid.UUID   `json:"event_id,omitempty" db:"event_id"`
+	TemplateID       *uuid.UUID   `json:"template_id,omitempty" db:"template_id"`
+	Status           MarketStatus `json:"status" db:"status" validate:"required"`
+	Type             MarketType   `json:"type" db:"market_type" validate:"required,oneof=BINARY CATEGORICAL SCALAR"`
+	LowerBound       *float64     `json:"lower_bound,omitempty" db:"scalar_lower_bound" validate:"required_if=Type SCALAR"`
+	UpperBound       *float64     `json:"upper_bound,omitempty" db:"scalar_upper_bound" validate:"required_if=Type SCALAR,omitempty,gtfield=LowerBound"`
+	OpensAt          *time.Time   `json:"opens_at,omitempty" db:"opens_at"`
+	ExpiresAt        time.Time    `json:"expires_at" db:"expires_at" validate:"required"`
+	ResolvedAt       *time.Time   `json:"resolved_at,omitempty" db:"resolved_at"`
+	WinningOutcomeID *uuid.UUID   `json:"winning_outcome_id,omitempty" db:"winning_outcome_id"`
+	ResolvedValue    *float64     `json:"resolved_value,omitempty" db:"resolved_value"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
 }
 
-// ContractSide represents the side of a contract (YES or NO)
-type ContractSide string
-
-const (
-	ContractSideYes ContractSide = "YES"
-	ContractSideNo  ContractSide = "NO"
-)
+// Outcome represents one named, mutually exclusive result of a market
+type Outcome struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	MarketID     uuid.UUID `json:"market_id" db:"market_id" validate:"required"`
+	Name         string    `json:"name" db:"name" validate:"required,max=100"`
+	DisplayOrder int       `json:"display_order" db:"display_order"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
 
-// Contract represents a contract (e.g., a "Yes" or "No" share) for a given market
+// Contract represents a tradable share paying out if its outcome wins
 type Contract struct {
-	ID        uuid.UUID    `json:"id" db:"id"`
-	MarketID  uuid.UUID    `json:"market_id" db:"market_id" validate:"required"`
-	Side      ContractSide `json:"side" db:"side" validate:"required,oneof=YES NO"`
-	Ticker    string       `json:"ticker" db:"ticker" validate:"required,max=60"`
-	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	ID        uuid.UUID `json:"id" db:"id"`
+	MarketID  uuid.UUID `json:"market_id" db:"market_id" validate:"required"`
+	OutcomeID uuid.UUID `json:"outcome_id" db:"outcome_id" validate:"required"`
+	Ticker    string    `json:"ticker" db:"ticker" validate:"required,max=60"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 // MarketCreateRequest represents the request to create a new market
 type MarketCreateRequest struct {
-	Ticker           string    `json:"ticker" validate:"required,uppercase,max=50"`
-	Question         string    `json:"question" validate:"required,min=10,max=500"`
-	Rules            string    `json:"rules" validate:"required"`
-	ResolutionSource string    `json:"resolution_source" validate:"required,max=255"`
-	ExpiresAt        time.Time `json:"expires_at" validate:"required"`
+	Ticker           string     `json:"ticker" validate:"required,uppercase,max=50"`
+	Question         string     `json:"question" validate:"required,min=10,max=500"`
+	Rules            string     `json:"rules" validate:"required"`
+	ResolutionSource string     `json:"resolution_source" validate:"required,max=255"`
+	Category         *string    `json:"category,omitempty" validate:"omitempty,max=50"`
+	Tags             []string   `json:"tags,omitempty" validate:"max=10,dive,max=50"`
+	EventID          *uuid.UUID `json:"event_id,omitempty"`
+	Type             MarketType `json:"type" validate:"omitempty,oneof=BINARY CATEGORICAL SCALAR"`
+	Outcomes         []string   `json:"outcomes,omitempty" validate:"required_if=Type CATEGORICAL,omitempty,min=2,max=20,dive,required,max=100"`
+	LowerBound       *float64   `json:"lower_bound,omitempty" validate:"required_if=Type SCALAR"`
+	UpperBound       *float64   `json:"upper_bound,omitempty" validate:"required_if=Type SCALAR,omitempty,gtfield=LowerBound"`
+	OpensAt          *time.Time `json:"opens_at,omitempty" validate:"omitempty,ltfield=ExpiresAt"`
+	ExpiresAt        time.Time  `json:"expires_at" validate:"required"`
 }
 
-// MarketResolveRequest represents the request to resolve a market
+// MarketUpdateRequest represents the request to edit a draft market. Only
+// fields that are set are changed.
+type MarketUpdateRequest struct {
+	MarketID         uuid.UUID  `json:"market_id" validate:"required"`
+	Question         *string    `json:"question,omitempty" validate:"omitempty,min=10,max=500"`
+	Rules            *string    `json:"rules,omitempty"`
+	ResolutionSource *string    `json:"resolution_source,omitempty" validate:"omitempty,max=255"`
+	Category         *string    `json:"category,omitempty" validate:"omitempty,max=50"`
+	Tags             []string   `json:"tags,omitempty" validate:"omitempty,max=10,dive,max=50"`
+	LowerBound       *float64   `json:"lower_bound,omitempty"`
+	UpperBound       *float64   `json:"upper_bound,omitempty"`
+	OpensAt          *time.Time `json:"opens_at,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// MarketActionRequest represents an admin action on a single market
+type MarketActionRequest struct {
+	MarketID uuid.UUID `json:"market_id" validate:"required"`
+	Reason   string    `json:"reason,omitempty" validate:"max=500"`
+}
+
+// MarketResolveRequest represents the request to resolve a market. Binary and
+// categorical markets resolve to an outcome, scalar markets to a value.
 type MarketResolveRequest struct {
-	Outcome MarketOutcome `json:"outcome" validate:"required,oneof=YES NO CANCELLED"`
+	MarketID  uuid.UUID `json:"market_id" validate:"required"`
+	OutcomeID uuid.UUID `json:"outcome_id,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+}
+
+// ErrInvalidScalarBounds is returned for scalar payouts of a market without
+// a lower bound below its upper bound
+var ErrInvalidScalarBounds = errors.New("scalar market bounds are invalid")
+
+// ScalarPayouts returns the per-share payouts of the LONG and SHORT contracts
+// of a scalar market resolved to value. The value is clamped to the market's
+// range and the LONG payout grows linearly from 0 at the lower bound to 1 at
+// the upper bound; SHORT pays the remainder.
+func (m *Market) ScalarPayouts(value float64) (long, short float64, err error) {
+	if m.LowerBound == nil || m.UpperBound == nil || !(*m.LowerBound < *m.UpperBound) {
+		return 0, 0, ErrInvalidScalarBounds
+	}
+
+	lower, upper := *m.LowerBound, *m.UpperBound
+	if value < lower {
+		value = lower
+	}
+	if value > upper {
+		value = upper
+	}
+
+	long = CompleteSetValueCredits * (value - lower) / (upper - lower)
+	short = CompleteSetValueCredits - long
+	return long, short, nil
+}
+
+// MarketLifecycleSubject is the NATS subject market lifecycle events are published on
+const MarketLifecycleSubject = "markets.lifecycle"
+
+// MarketLifecycleEventType represents a market status transition
+type MarketLifecycleEventType string
+
+const (
+	MarketLifecycleOpened    MarketLifecycleEventType = "OPENED"
+	MarketLifecycleClosed    MarketLifecycleEventType = "CLOSED"
+	MarketLifecycleResolved  MarketLifecycleEventType = "RESOLVED"
+	MarketLifecycleCancelled MarketLifecycleEventType = "CANCELLED"
+
+	MarketLifecycleResolutionProposed MarketLifecycleEventType = "RESOLUTION_PROPOSED"
+	MarketLifecycleResolutionDisputed MarketLifecycleEventType = "RESOLUTION_DISPUTED"
+)
+
+// MarketLifecycleEvent is published whenever a market changes status
+type MarketLifecycleEvent struct {
+	Type       MarketLifecycleEventType `json:"type"`
+	MarketID   uuid.UUID                `json:"market_id"`
+	Ticker     string                   `json:"ticker"`
+	Status     MarketStatus             `json:"status"`
+	OccurredAt time.Time                `json:"occurred_at"`
+}
+
+// Category represents a market category corresponding to the "market_categories" table
+type Category struct {
+	Slug         string    `json:"slug" db:"slug" validate:"required,max=50"`
+	Name         string    `json:"name" db:"name" validate:"required,max=100"`
+	DisplayOrder int       `json:"display_order" db:"display_order"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// MarketListFilter represents the filters of a market listing. Search is a
+// full-text query over question and rules; results are ranked by relevance
+// when it is set and by recency otherwise.
+type MarketListFilter struct {
+	Status        string
+	Search        string
+	Category      string
+	Tags          []string
+	EventID       *uuid.UUID
+	TemplateID    *uuid.UUID
+	IncludeDrafts bool
+	Page          int
+	PageSize      int
+}
+
+// FacetCount represents the number of markets with a given facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// MarketFacets represents market counts per category and per status. Each
+// facet is counted with every other filter applied.
+type MarketFacets struct {
+	Categories []*FacetCount `json:"categories"`
+	Statuses   []*FacetCount `json:"statuses"`
+}
+
+// MarketSuggestion represents an autocomplete match on ticker or question
+type MarketSuggestion struct {
+	ID       uuid.UUID    `json:"id"`
+	Ticker   string       `json:"ticker"`
+	Question string       `json:"question"`
+	Status   MarketStatus `json:"status"`
 }
 
 // MarketListResponse represents the response for listing markets
 type MarketListResponse struct {
-	Markets    []*Market `json:"markets"`
-	TotalCount int       `json:"total_count"`
-	Page       int       `json:"page"`
-	PageSize   int       `json:"page_size"`
+	Markets    []*Market     `json:"markets"`
+	TotalCount int           `json:"total_count"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+	Facets     *MarketFacets `json:"facets,omitempty"`
 }
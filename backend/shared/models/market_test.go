@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestScalarPayouts(t *testing.T) {
+	bound := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		lower     *float64
+		upper     *float64
+		value     float64
+		wantLong  float64
+		wantShort float64
+		wantErr   error
+	}{
+		{"at lower bound", bound(0), bound(100), 0, 0, 1, nil},
+		{"at upper bound", bound(0), bound(100), 100, 1, 0, nil},
+		{"midpoint", bound(0), bound(100), 50, 0.5, 0.5, nil},
+		{"quarter of a negative range", bound(-200), bound(-100), -175, 0.25, 0.75, nil},
+		{"below range clamps", bound(10), bound(20), 5, 0, 1, nil},
+		{"above range clamps", bound(10), bound(20), 25, 1, 0, nil},
+		{"missing lower bound", nil, bound(100), 50, 0, 0, ErrInvalidScalarBounds},
+		{"missing upper bound", bound(0), nil, 50, 0, 0, ErrInvalidScalarBounds},
+		{"equal bounds", bound(5), bound(5), 5, 0, 0, ErrInvalidScalarBounds},
+		{"inverted bounds", bound(100), bound(0), 50, 0, 0, ErrInvalidScalarBounds},
+		{"NaN bound", bound(math.NaN()), bound(100), 50, 0, 0, ErrInvalidScalarBounds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market := &Market{Type: MarketTypeScalar, LowerBound: tt.lower, UpperBound: tt.upper}
+
+			long, short, err := market.ScalarPayouts(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if math.Abs(long-tt.wantLong) > 1e-9 || math.Abs(short-tt.wantShort) > 1e-9 {
+				t.Errorf("payouts = (%v, %v), want (%v, %v)", long, short, tt.wantLong, tt.wantShort)
+			}
+			if math.Abs(long+short-CompleteSetValueCredits) > 1e-9 {
+				t.Errorf("payouts add up to %v, want a complete set's value", long+short)
+			}
+		})
+	}
+}
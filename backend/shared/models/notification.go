@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType represents what a notification reports
+type NotificationType string
+
+const (
+	NotificationOrderFilled   NotificationType = "ORDER_FILLED"
+	NotificationMarketSettled NotificationType = "MARKET_SETTLED"
+	NotificationMarketRefund  NotificationType = "MARKET_REFUND"
+	NotificationAlert         NotificationType = "ALERT"
+	NotificationDeposit       NotificationType = "DEPOSIT"
+	NotificationWithdrawal    NotificationType = "WITHDRAWAL"
+	NotificationNewLogin      NotificationType = "NEW_LOGIN"
+)
+
+// Notification represents the notification model corresponding to the
+// "notifications" table, a user's durable inbox
+type Notification struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	UserID    uuid.UUID        `json:"user_id" db:"user_id"`
+	Type      NotificationType `json:"type" db:"type"`
+	Message   string           `json:"message" db:"message"`
+	Payload   json.RawMessage  `json:"payload" db:"payload"`
+	ReadAt    *time.Time       `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// NotificationListResponse represents a page of a user's notifications
+type NotificationListResponse struct {
+	Notifications []*Notification `json:"notifications"`
+	TotalCount    int             `json:"total_count"`
+	UnreadCount   int             `json:"unread_count"`
+	Page          int             `json:"page"`
+	PageSize      int             `json:"page_size"`
+}
+
+// NotificationReadRequest represents the request to mark notifications read,
+// either the listed ones or all of them
+type NotificationReadRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+	All bool        `json:"all"`
+}
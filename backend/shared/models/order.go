@@ -16,6 +16,14 @@ const (
 	OrderTypeStopLimit OrderType = "STOP_LIMIT"
 )
 
+// OrderSide represents whether an order buys or sells its contract
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "BUY"
+	OrderSideSell OrderSide = "SELL"
+)
+
 // OrderStatus represents the status of an order
 type OrderStatus string
 
@@ -34,6 +42,7 @@ type Order struct {
 	UserID            uuid.UUID   `json:"user_id" db:"user_id" validate:"required"`
 	ContractID        uuid.UUID   `json:"contract_id" db:"contract_id" validate:"required"`
 	Type              OrderType   `json:"type" db:"type" validate:"required"`
+	Side              OrderSide   `json:"side" db:"side" validate:"required,oneof=BUY SELL"`
 	Status            OrderStatus `json:"status" db:"status" validate:"required"`
 	Quantity          int         `json:"quantity" db:"quantity" validate:"required,min=1"`
 	QuantityFilled    int         `json:"quantity_filled" db:"quantity_filled" validate:"min=0"`
@@ -45,8 +54,12 @@ type Order struct {
 
 // OrderPlaceRequest represents the request to place a new order
 type OrderPlaceRequest struct {
+	// OrderID is chosen by callers that need to know the order before it
+	// trades; it is generated when nil
+	OrderID           uuid.UUID `json:"order_id,omitempty"`
 	ContractID        uuid.UUID `json:"contract_id" validate:"required"`
 	Type              OrderType `json:"type" validate:"required,oneof=MARKET LIMIT STOP STOP_LIMIT"`
+	Side              OrderSide `json:"side,omitempty" validate:"omitempty,oneof=BUY SELL"`
 	Quantity          int       `json:"quantity" validate:"required,min=1,max=10000"`
 	LimitPriceCredits *float64  `json:"limit_price_credits,omitempty" validate:"omitempty,gt=0,lte=1"`
 	StopPriceCredits  *float64  `json:"stop_price_credits,omitempty" validate:"omitempty,gt=0,lte=1"`
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Position represents a user's holding of a contract
+type Position struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id" validate:"required"`
+	ContractID uuid.UUID `json:"contract_id" db:"contract_id" validate:"required"`
+	Quantity   int       `json:"quantity" db:"quantity" validate:"min=0"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CompleteSetRequest represents a request to mint or redeem complete sets.
+// A complete set holds one contract of every outcome of a market and is
+// always worth exactly one credit.
+type CompleteSetRequest struct {
+	MarketID uuid.UUID `json:"market_id" validate:"required"`
+	Quantity int       `json:"quantity" validate:"required,min=1,max=10000"`
+}
+
+// CompleteSetResponse represents the result of a complete set operation
+type CompleteSetResponse struct {
+	MarketID  uuid.UUID   `json:"market_id"`
+	Quantity  int         `json:"quantity"`
+	Credits   float64     `json:"credits"`
+	Positions []*Position `json:"positions"`
+}
+
+// CompleteSetValueCredits is the value of one complete set in credits
+const CompleteSetValueCredits = 1.0
+
+// ContractPayout represents what a contract paid out when its market settled
+type ContractPayout struct {
+	ContractID     uuid.UUID `json:"contract_id"`
+	PayoutPerShare float64   `json:"payout_per_share"`
+	SharesSettled  int       `json:"shares_settled"`
+	TotalCredits   float64   `json:"total_credits"`
+}
+
+// MarketSettlement summarizes the settlement of a resolved or cancelled market
+type MarketSettlement struct {
+	MarketID      uuid.UUID         `json:"market_id"`
+	Payouts       []*ContractPayout `json:"payouts"`
+	RefundedUsers int               `json:"refunded_users,omitempty"` // Holders refunded their cost basis, when cancelled
+	TotalCredits  float64           `json:"total_credits"`
+	SettledAt     time.Time         `json:"settled_at"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProposalStatus represents the status of a resolution proposal
+type ProposalStatus string
+
+const (
+	ProposalStatusProposed  ProposalStatus = "PROPOSED"
+	ProposalStatusDisputed  ProposalStatus = "DISPUTED"
+	ProposalStatusFinalized ProposalStatus = "FINALIZED"
+	ProposalStatusRejected  ProposalStatus = "REJECTED"
+)
+
+// ProposalSource represents who proposed a resolution
+type ProposalSource string
+
+const (
+	ProposalSourceAdmin    ProposalSource = "ADMIN"
+	ProposalSourceResolver ProposalSource = "RESOLVER"
+)
+
+// DisputeStatus represents the status of a dispute
+type DisputeStatus string
+
+const (
+	DisputeStatusOpen     DisputeStatus = "OPEN"
+	DisputeStatusUpheld   DisputeStatus = "UPHELD"
+	DisputeStatusRejected DisputeStatus = "REJECTED"
+)
+
+// MarketResolver represents the automated resolver configured for a market
+type MarketResolver struct {
+	MarketID      uuid.UUID       `json:"market_id" db:"market_id" validate:"required"`
+	Type          string          `json:"type" db:"resolver_type" validate:"required,max=50"`
+	Config        json.RawMessage `json:"config" db:"resolver_config"`
+	LastCheckedAt *time.Time      `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	LastError     *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// ResolutionProposal represents the proposal model corresponding to the "resolution_proposals" table
+type ResolutionProposal struct {
+	ID              uuid.UUID      `json:"id" db:"id"`
+	MarketID        uuid.UUID      `json:"market_id" db:"market_id" validate:"required"`
+	ProposerUserID  *uuid.UUID     `json:"proposer_user_id,omitempty" db:"proposer_user_id"`
+	Source          ProposalSource `json:"source" db:"source" validate:"required,oneof=ADMIN RESOLVER"`
+	OutcomeID       *uuid.UUID     `json:"outcome_id,omitempty" db:"outcome_id"`
+	Value           *float64       `json:"value,omitempty" db:"value"`
+	Evidence        *string        `json:"evidence,omitempty" db:"evidence"`
+	Status          ProposalStatus `json:"status" db:"status"`
+	ChallengeEndsAt time.Time      `json:"challenge_ends_at" db:"challenge_ends_at"`
+	FinalizedAt     *time.Time     `json:"finalized_at,omitempty" db:"finalized_at"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ResolutionDispute represents the dispute model corresponding to the "resolution_disputes" table
+type ResolutionDispute struct {
+	ID          uuid.UUID     `json:"id" db:"id"`
+	ProposalID  uuid.UUID     `json:"proposal_id" db:"proposal_id" validate:"required"`
+	UserID      uuid.UUID     `json:"user_id" db:"user_id" validate:"required"`
+	BondCredits float64       `json:"bond_credits" db:"bond_credits" validate:"gt=0"`
+	Reason      string        `json:"reason" db:"reason" validate:"required,max=2000"`
+	Status      DisputeStatus `json:"status" db:"status"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	SettledAt   *time.Time    `json:"settled_at,omitempty" db:"settled_at"`
+}
+
+// ResolutionProposeRequest represents an admin proposal of a market's result.
+// Binary and categorical markets take an outcome, scalar markets a value.
+type ResolutionProposeRequest struct {
+	MarketID  uuid.UUID `json:"market_id" validate:"required"`
+	OutcomeID uuid.UUID `json:"outcome_id,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+	Evidence  string    `json:"evidence,omitempty" validate:"max=2000"`
+}
+
+// ResolutionDisputeRequest represents a user's challenge of a pending proposal
+type ResolutionDisputeRequest struct {
+	ProposalID uuid.UUID `json:"proposal_id" validate:"required"`
+	Reason     string    `json:"reason" validate:"required,max=2000"`
+}
+
+// ResolutionAdjudicateRequest represents an admin ruling on a disputed proposal.
+// Upholding the disputes rejects the proposal; otherwise it is finalized.
+type ResolutionAdjudicateRequest struct {
+	ProposalID uuid.UUID `json:"proposal_id" validate:"required"`
+	Uphold     bool      `json:"uphold"`
+}
+
+// MarketResolverRequest represents the request to configure a market's resolver
+type MarketResolverRequest struct {
+	MarketID uuid.UUID       `json:"market_id" validate:"required"`
+	Type     string          `json:"type" validate:"required,max=50"`
+	Config   json.RawMessage `json:"config"`
+}
+
+// ResolutionHistory represents every proposal and dispute made on a market
+type ResolutionHistory struct {
+	MarketID  uuid.UUID             `json:"market_id"`
+	Resolver  *MarketResolver       `json:"resolver,omitempty"`
+	Proposals []*ResolutionProposal `json:"proposals"`
+	Disputes  []*ResolutionDispute  `json:"disputes"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MarketTemplate represents the template model corresponding to the
+// "market_templates" table. TickerPattern, QuestionPattern and RulesPattern
+// are Go text/template patterns rendered with MarketTemplateData.
+type MarketTemplate struct {
+	ID               uuid.UUID         `json:"id" db:"id"`
+	Name             string            `json:"name" db:"name" validate:"required,max=100"`
+	TickerPattern    string            `json:"ticker_pattern" db:"ticker_pattern" validate:"required,max=100"`
+	QuestionPattern  string            `json:"question_pattern" db:"question_pattern" validate:"required"`
+	RulesPattern     string            `json:"rules_pattern" db:"rules_pattern" validate:"required"`
+	ResolutionSource string            `json:"resolution_source" db:"resolution_source" validate:"required,max=255"`
+	Type             MarketType        `json:"type" db:"market_type" validate:"required,oneof=BINARY CATEGORICAL SCALAR"`
+	Outcomes         []string          `json:"outcomes" db:"outcomes"`
+	LowerBound       *float64          `json:"lower_bound,omitempty" db:"scalar_lower_bound"`
+	UpperBound       *float64          `json:"upper_bound,omitempty" db:"scalar_upper_bound"`
+	Category         *string           `json:"category,omitempty" db:"category"`
+	Tags             []string          `json:"tags" db:"tags"`
+	EventID          *uuid.UUID        `json:"event_id,omitempty" db:"event_id"`
+	Params           map[string]string `json:"params" db:"params"`
+	IntervalSeconds  int               `json:"interval_seconds" db:"interval_seconds" validate:"required,gt=0"`
+	DurationSeconds  int               `json:"duration_seconds" db:"duration_seconds" validate:"required,gt=0"`
+	AutoPublish      bool              `json:"auto_publish" db:"auto_publish"`
+	Active           bool              `json:"active" db:"active"`
+	NextRunAt        time.Time         `json:"next_run_at" db:"next_run_at"`
+	LastRunAt        *time.Time        `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastError        *string           `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt        time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// MarketTemplateData is what template patterns are rendered with, e.g.
+// "BTC_{{date \"20060102\" .ExpiresAt}}" or "{{.Params.threshold}}"
+type MarketTemplateData struct {
+	OpensAt   time.Time
+	ExpiresAt time.Time
+	Params    map[string]string
+}
+
+// MarketTemplateCreateRequest represents the request to create a market template
+type MarketTemplateCreateRequest struct {
+	Name             string            `json:"name" validate:"required,max=100"`
+	TickerPattern    string            `json:"ticker_pattern" validate:"required,max=100"`
+	QuestionPattern  string            `json:"question_pattern" validate:"required"`
+	RulesPattern     string            `json:"rules_pattern" validate:"required"`
+	ResolutionSource string            `json:"resolution_source" validate:"required,max=255"`
+	Type             MarketType        `json:"type" validate:"omitempty,oneof=BINARY CATEGORICAL SCALAR"`
+	Outcomes         []string          `json:"outcomes,omitempty"`
+	LowerBound       *float64          `json:"lower_bound,omitempty"`
+	UpperBound       *float64          `json:"upper_bound,omitempty"`
+	Category         *string           `json:"category,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	EventID          *uuid.UUID        `json:"event_id,omitempty"`
+	Params           map[string]string `json:"params,omitempty"`
+	IntervalSeconds  int               `json:"interval_seconds" validate:"required,gt=0"`
+	DurationSeconds  int               `json:"duration_seconds" validate:"required,gt=0"`
+	AutoPublish      *bool             `json:"auto_publish,omitempty"`
+	FirstRunAt       *time.Time        `json:"first_run_at,omitempty"`
+}
+
+// MarketTemplateUpdateRequest represents the request to change a template's
+// parameters or pause and resume it. Only fields that are set are changed.
+type MarketTemplateUpdateRequest struct {
+	TemplateID uuid.UUID         `json:"template_id" validate:"required"`
+	Params     map[string]string `json:"params,omitempty"`
+	Active     *bool             `json:"active,omitempty"`
+	NextRunAt  *time.Time        `json:"next_run_at,omitempty"`
+}
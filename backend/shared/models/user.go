@@ -15,6 +15,14 @@ const (
 	UserStatusBanned    UserStatus = "BANNED"
 )
 
+// UserRole represents the role of a user, carried in the JWT role claim
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
 // User represents the user model corresponding to the "users" table
 type User struct {
 	ID            uuid.UUID  `json:"id" db:"id"`
@@ -22,6 +30,7 @@ type User struct {
 	PasswordHash  string     `json:"-" db:"password_hash" validate:"required,min=60"`
 	WalletAddress *string    `json:"wallet_address,omitempty" db:"wallet_address"`
 	Status        UserStatus `json:"status" db:"status" validate:"required,oneof=ACTIVE SUSPENDED BANNED"`
+	Role          UserRole   `json:"role" db:"role" validate:"required,oneof=user admin"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
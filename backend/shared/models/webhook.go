@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEvent represents an event type a webhook can subscribe to. The names
+// match the private websocket channels carrying the same events.
+type WebhookEvent string
+
+const (
+	WebhookEventFill    WebhookEvent = "fills"
+	WebhookEventOrder   WebhookEvent = "orders"
+	WebhookEventBalance WebhookEvent = "balance"
+)
+
+// Valid reports whether e is a known webhook event type
+func (e WebhookEvent) Valid() bool {
+	return e == WebhookEventFill || e == WebhookEventOrder || e == WebhookEventBalance
+}
+
+// WebhookDeliveryStatus represents the status of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// Webhook represents a user's webhook endpoint corresponding to the
+// "webhooks" table. Secret signs the deliveries and is only returned when the
+// webhook is created.
+type Webhook struct {
+	ID                  uuid.UUID      `json:"id" db:"id"`
+	UserID              uuid.UUID      `json:"user_id" db:"user_id"`
+	URL                 string         `json:"url" db:"url"`
+	Secret              string         `json:"secret,omitempty" db:"secret"`
+	Events              []WebhookEvent `json:"events" db:"events"`
+	Active              bool           `json:"active" db:"active"`
+	ConsecutiveFailures int            `json:"consecutive_failures" db:"consecutive_failures"`
+	DisabledAt          *time.Time     `json:"disabled_at,omitempty" db:"disabled_at"`
+	CreatedAt           time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookCreateRequest represents the request to register a webhook endpoint
+type WebhookCreateRequest struct {
+	URL    string         `json:"url" validate:"required,url"`
+	Events []WebhookEvent `json:"events" validate:"required,min=1,dive,oneof=fills orders balance"`
+}
+
+// WebhookRequest represents a request naming one of the user's webhooks
+type WebhookRequest struct {
+	WebhookID uuid.UUID `json:"webhook_id" validate:"required"`
+}
+
+// WebhookDelivery represents one event sent to a webhook corresponding to the
+// "webhook_deliveries" table
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	WebhookID      uuid.UUID             `json:"webhook_id" db:"webhook_id"`
+	UserID         uuid.UUID             `json:"user_id" db:"user_id"`
+	Event          WebhookEvent          `json:"event" db:"event"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int                  `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      *string               `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+}
+
+// WebhookDeliveryListResponse represents a page of a webhook's delivery log
+type WebhookDeliveryListResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	TotalCount int                `json:"total_count"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// RequestIDHeader carries the ID the API gateway gives each request, in
+// HTTP and NATS message headers and (lowercased) gRPC metadata
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDAttribute is the span attribute holding the request ID
+const RequestIDAttribute = "request.id"
+
+// maxRequestIDLength bounds request IDs read from other services
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// NewRequestID generates a request ID
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// WithRequestID returns ctx carrying a request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// requestIDPropagator carries the request ID alongside the trace context, so
+// every transport that propagates traces also propagates it
+type requestIDPropagator struct{}
+
+// Inject implements propagation.TextMapPropagator
+func (requestIDPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if requestID := RequestID(ctx); requestID != "" {
+		carrier.Set(RequestIDHeader, requestID)
+	}
+}
+
+// Extract implements propagation.TextMapPropagator
+func (requestIDPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	requestID := carrier.Get(RequestIDHeader)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return ctx
+	}
+	return WithRequestID(ctx, requestID)
+}
+
+// Fields implements propagation.TextMapPropagator
+func (requestIDPropagator) Fields() []string {
+	return []string{RequestIDHeader}
+}
@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"lfg/shared/config"
+)
+
+// Exporters spans can be sent to
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Init installs the tracer provider of service and the propagator carrying
+// trace context, baggage and request IDs between services. Spans are sent to
+// the configured exporter; with none, request IDs are still propagated. The
+// returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, service string, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		requestIDPropagator{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TracingExporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.TracingOTLPEndpoint)}
+		if cfg.TracingOTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", cfg.TracingExporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(service),
+			semconv.DeploymentEnvironment(cfg.Environment),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of an instrumented package
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Handler traces the requests to a service, naming spans after the request
+// path, and carries the request ID they were sent with through their
+// context; requests without one are given one. Health checks and metrics
+// scrapes are not traced.
+func Handler(service string, next http.Handler) http.Handler {
+	withRequestID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		requestID := RequestID(ctx)
+		if requestID == "" {
+			requestID = NewRequestID()
+			ctx = WithRequestID(ctx, requestID)
+			r = r.WithContext(ctx)
+		}
+
+		r.Header.Set(RequestIDHeader, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String(RequestIDAttribute, requestID))
+
+		next.ServeHTTP(w, r)
+	})
+
+	return otelhttp.NewHandler(withRequestID, service,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/health" && r.URL.Path != "/metrics"
+		}),
+	)
+}
+
+// Transport traces the requests sent through base, propagating their trace
+// context and request ID to the called service
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// Inject adds the trace context and request ID of ctx to message headers,
+// such as those of a NATS message. The headers must not be nil.
+func Inject(ctx context.Context, header map[string][]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Extract returns ctx with the trace context and request ID carried by
+// message headers
+func Extract(ctx context.Context, header map[string][]string) context.Context {
+	if header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartMessage starts the span of handling a message received on subject,
+// continuing the trace of the publisher from the message headers
+func StartMessage(ctx context.Context, tracer trace.Tracer, subject string, header map[string][]string) (context.Context, trace.Span) {
+	ctx = Extract(ctx, header)
+
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("nats"),
+		semconv.MessagingDestinationName(subject),
+	}
+	if requestID := RequestID(ctx); requestID != "" {
+		attrs = append(attrs, attribute.String(RequestIDAttribute, requestID))
+	}
+
+	return tracer.Start(ctx, "receive "+subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
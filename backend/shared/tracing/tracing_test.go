@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/config"
+)
+
+// initPropagation installs the propagator of Init without exporting spans
+func initPropagation(t *testing.T) {
+	t.Helper()
+	if _, err := Init(context.Background(), "test", &config.Config{TracingExporter: ExporterNone}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlerRequestID(t *testing.T) {
+	initPropagation(t)
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantKept  bool
+	}{
+		{"request ID of the caller", "req-1", true},
+		{"no request ID", "", false},
+		{"over-long request ID", strings.Repeat("x", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen, seenHeader string
+			handler := Handler("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+				seenHeader = r.Header.Get(RequestIDHeader)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/markets", nil)
+			if tt.requestID != "" {
+				r.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.wantKept && seen != tt.requestID {
+				t.Errorf("request ID = %q, want the caller's %q", seen, tt.requestID)
+			}
+			if !tt.wantKept {
+				if _, err := uuid.Parse(seen); err != nil {
+					t.Errorf("request ID = %q, want a new one", seen)
+				}
+			}
+			if seenHeader != seen || w.Header().Get(RequestIDHeader) != seen {
+				t.Errorf("request header %q and response header %q, want both %q", seenHeader, w.Header().Get(RequestIDHeader), seen)
+			}
+		})
+	}
+}
+
+func TestTransportRequestID(t *testing.T) {
+	initPropagation(t)
+
+	var seen string
+	server := httptest.NewServer(Handler("callee", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	})))
+	defer server.Close()
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/wallet", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if seen != "req-1" {
+		t.Errorf("called service saw request ID %q, want req-1", seen)
+	}
+}
+
+func TestMessageRequestID(t *testing.T) {
+	initPropagation(t)
+
+	header := make(map[string][]string)
+	Inject(WithRequestID(context.Background(), "req-1"), header)
+	if got := RequestID(Extract(context.Background(), header)); got != "req-1" {
+		t.Errorf("request ID from message headers = %q, want req-1", got)
+	}
+
+	ctx, span := StartMessage(context.Background(), Tracer("test"), "trades", header)
+	span.End()
+	if got := RequestID(ctx); got != "req-1" {
+		t.Errorf("request ID of the message handler = %q, want req-1", got)
+	}
+
+	empty := make(map[string][]string)
+	Inject(context.Background(), empty)
+	if len(empty[RequestIDHeader]) != 0 {
+		t.Errorf("injected %v without a request ID", empty[RequestIDHeader])
+	}
+	if got := RequestID(Extract(context.Background(), nil)); got != "" {
+		t.Errorf("request ID without headers = %q, want none", got)
+	}
+}
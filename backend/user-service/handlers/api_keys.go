@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"lfg/shared/auth"
+	"lfg/shared/models"
+	"lfg/user-service/repository"
+)
+
+// maxAllowedIPs is the number of addresses or ranges a key can be limited to
+const maxAllowedIPs = 20
+
+// APIKeyHandler handles HTTP requests for the user's API keys, and the API
+// gateway's lookups of the keys signing requests
+type APIKeyHandler struct {
+	repo *repository.APIKeyRepository
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(repo *repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{repo: repo}
+}
+
+// List handles retrieving the user's API keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.repo.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"api_keys": keys}, http.StatusOK)
+}
+
+// Create handles creating an API key. The response carries the secret
+// requests are signed with; it is not shown again.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.APIKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		respondError(w, "Name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		respondError(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	scopes := []models.APIKeyScope{}
+	seen := make(map[models.APIKeyScope]bool)
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			respondError(w, "Scopes must be read, trade or withdraw", http.StatusBadRequest)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(req.AllowedIPs) > maxAllowedIPs {
+		respondError(w, "At most 20 allowed IPs can be set", http.StatusBadRequest)
+		return
+	}
+	allowedIPs := make([]string, 0, len(req.AllowedIPs))
+	for _, entry := range req.AllowedIPs {
+		normalized, ok := normalizeAllowedIP(entry)
+		if !ok {
+			respondError(w, "Allowed IPs must be IP addresses or CIDR ranges", http.StatusBadRequest)
+			return
+		}
+		allowedIPs = append(allowedIPs, normalized)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	keyID, secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		respondError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	key := &models.APIKey{
+		ID:         uuid.New(),
+		UserID:     userID,
+		KeyID:      keyID,
+		Secret:     secret,
+		Name:       name,
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	}
+
+	if err := h.repo.Create(r.Context(), key); err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	respondJSON(w, key, http.StatusCreated)
+}
+
+// Revoke handles revoking one of the user's API keys. Gateways may accept
+// requests signed with the key until their cached copy of it expires.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.repo.Revoke(r.Context(), userID, req.APIKeyID)
+	if err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	respondJSON(w, key, http.StatusOK)
+}
+
+// Credentials handles the API and FIX gateways' lookups of the key named by
+// the key_id query parameter, returning its secret and owner
+func (h *APIKeyHandler) Credentials(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keyID := r.URL.Query().Get("key_id")
+	if keyID == "" {
+		respondError(w, "key_id is required", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := h.repo.GetCredentials(r.Context(), keyID)
+	if err != nil {
+		respondAPIKeyError(w, err)
+		return
+	}
+
+	respondJSON(w, creds, http.StatusOK)
+}
+
+// normalizeAllowedIP parses an IP address or CIDR range, returning it in
+// canonical form
+func normalizeAllowedIP(entry string) (string, bool) {
+	entry = strings.TrimSpace(entry)
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network.String(), true
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.String(), true
+	}
+	return "", false
+}
+
+func respondAPIKeyError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrAPIKeyNotFound:
+		respondError(w, "API key not found", http.StatusNotFound)
+	case repository.ErrTooManyAPIKeys:
+		respondError(w, "Too many API keys; revoke one first", http.StatusConflict)
+	default:
+		respondError(w, "Failed to process API key", http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -57,6 +60,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
 		Status:       models.UserStatusActive,
+		Role:         models.UserRoleUser,
 	}
 
 	if err := h.repo.Create(r.Context(), user); err != nil {
@@ -68,8 +72,10 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordLogin(r, user.ID)
+
 	// Generate JWT tokens
-	accessToken, expiresAt, err := h.jwtManager.GenerateToken(user.ID, user.Email, "user")
+	accessToken, expiresAt, err := h.jwtManager.GenerateToken(user.ID, user.Email, string(user.Role))
 	if err != nil {
 		respondError(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -128,8 +134,10 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordLogin(r, user.ID)
+
 	// Generate JWT tokens
-	accessToken, expiresAt, err := h.jwtManager.GenerateToken(user.ID, user.Email, "user")
+	accessToken, expiresAt, err := h.jwtManager.GenerateToken(user.ID, user.Email, string(user.Role))
 	if err != nil {
 		respondError(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -186,6 +194,29 @@ func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, user, http.StatusOK)
 }
 
+// recordLogin records the device of a sign-in; failing to do so does not
+// fail the sign-in
+func (h *UserHandler) recordLogin(r *http.Request, userID uuid.UUID) {
+	if err := h.repo.RecordLogin(r.Context(), userID, clientIP(r), r.UserAgent()); err != nil {
+		log.Printf("Failed to record login of user %s: %v", userID, err)
+	}
+}
+
+// clientIP returns the address of the client behind the API gateway, which
+// appends the address it saw to X-Forwarded-For
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addresses := strings.Split(forwarded, ",")
+		return strings.TrimSpace(addresses[len(addresses)-1])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // Health check handler
 func Health(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, map[string]string{"status": "healthy"}, http.StatusOK)
@@ -13,6 +13,8 @@ import (
 	"lfg/shared/auth"
 	"lfg/shared/config"
 	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 	"lfg/user-service/handlers"
 	"lfg/user-service/repository"
 )
@@ -24,6 +26,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceUser, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create context
 	ctx := context.Background()
 
@@ -46,6 +55,7 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close(pool)
+	metrics.RegisterPool(pool)
 
 	log.Println("Connected to database successfully")
 
@@ -54,21 +64,41 @@ func main() {
 
 	// Initialize repository
 	userRepo := repository.NewUserRepository(pool)
+	apiKeyRepo := repository.NewAPIKeyRepository(pool)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userRepo, jwtManager)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
 
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
 	mux.HandleFunc("/register", userHandler.Register)
 	mux.HandleFunc("/login", userHandler.Login)
 	mux.HandleFunc("/profile", userHandler.Profile)
+	mux.HandleFunc("/api-keys", apiKeyHandler.List)
+	mux.HandleFunc("/api-keys/create", apiKeyHandler.Create)
+	mux.HandleFunc("/api-keys/revoke", apiKeyHandler.Revoke)
+
+	// The API gateway looks up the keys signing requests to verify them, and
+	// the FIX gateway the keys sessions log on with
+	mux.HandleFunc("/internal/api-keys/credentials", apiKeyHandler.Credentials)
+
+	// Only the API gateway may call the endpoints
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceUser, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway, "/register", "/login", "/profile",
+		"/api-keys", "/api-keys/", "/internal/api-keys/credentials")
+	serviceGuard.Allow(auth.ServiceFIXGateway, "/internal/api-keys/credentials")
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceUser, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
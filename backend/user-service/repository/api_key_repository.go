@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"lfg/shared/models"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrTooManyAPIKeys = errors.New("too many API keys")
+)
+
+// maxAPIKeys is the number of unrevoked API keys a user can hold
+const maxAPIKeys = 25
+
+const apiKeyColumns = `id, user_id, key_id, secret, name, scopes, allowed_ips, expires_at, revoked_at, created_at`
+
+// APIKeyRepository handles API key database operations
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{pool: pool}
+}
+
+func scanAPIKey(row pgx.Row, key *models.APIKey, extra ...interface{}) error {
+	var scopes []string
+	dest := []interface{}{
+		&key.ID,
+		&key.UserID,
+		&key.KeyID,
+		&key.Secret,
+		&key.Name,
+		&scopes,
+		&key.AllowedIPs,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	key.Scopes = make([]models.APIKeyScope, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = models.APIKeyScope(scope)
+	}
+	return nil
+}
+
+// Create stores a new API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	var count int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL
+	`, key.UserID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAPIKeys {
+		return ErrTooManyAPIKeys
+	}
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	err = scanAPIKey(r.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (id, user_id, key_id, secret, name, scopes, allowed_ips, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING `+apiKeyColumns,
+		key.ID,
+		key.UserID,
+		key.KeyID,
+		key.Secret,
+		key.Name,
+		scopes,
+		key.AllowedIPs,
+		key.ExpiresAt,
+	), key)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves a user's API keys, newest first, without their secrets
+func (r *APIKeyRepository) List(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		key.Secret = ""
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API key rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke stops one of a user's API keys from signing requests. The key is
+// kept so it still shows in the user's list.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, apiKeyID uuid.UUID) (*models.APIKey, error) {
+	var key models.APIKey
+	err := scanAPIKey(r.pool.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+apiKeyColumns,
+		apiKeyID, userID,
+	), &key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	key.Secret = ""
+	return &key, nil
+}
+
+// GetCredentials retrieves an API key by its public ID, with its secret and
+// the identity of its owner. Keys of inactive accounts are not found.
+func (r *APIKeyRepository) GetCredentials(ctx context.Context, keyID string) (*models.APIKeyCredentials, error) {
+	var key models.APIKey
+	creds := &models.APIKeyCredentials{Key: &key}
+	err := scanAPIKey(r.pool.QueryRow(ctx, `
+		SELECT k.id, k.user_id, k.key_id, k.secret, k.name, k.scopes, k.allowed_ips, k.expires_at, k.revoked_at, k.created_at,
+		       u.email, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_id = $1 AND u.status = $2
+	`, keyID, models.UserStatusActive), &key, &creds.Email, &creds.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return creds, nil
+}
@@ -30,8 +30,8 @@ func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, wallet_address, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		INSERT INTO users (id, email, password_hash, wallet_address, status, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
 	`
 
 	_, err := r.pool.Exec(ctx, query,
@@ -40,6 +40,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.PasswordHash,
 		user.WalletAddress,
 		user.Status,
+		user.Role,
 	)
 
 	if err != nil {
@@ -56,7 +57,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, wallet_address, status, created_at, updated_at
+		SELECT id, email, password_hash, wallet_address, status, role, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -68,6 +69,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		&user.PasswordHash,
 		&user.WalletAddress,
 		&user.Status,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -85,7 +87,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, wallet_address, status, created_at, updated_at
+		SELECT id, email, password_hash, wallet_address, status, role, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -97,6 +99,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		&user.PasswordHash,
 		&user.WalletAddress,
 		&user.Status,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -151,3 +154,55 @@ func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+// RecordLogin records the device a user signed in from. A sign-in from a
+// device not seen before, other than the user's first, adds a NEW_LOGIN
+// notification to their inbox.
+func (r *UserRepository) RecordLogin(ctx context.Context, userID uuid.UUID, ipAddress, userAgent string) error {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var knownDevices int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_login_devices WHERE user_id = $1
+	`, userID).Scan(&knownDevices)
+	if err != nil {
+		return fmt.Errorf("failed to count login devices: %w", err)
+	}
+
+	var newDevice bool
+	err = tx.QueryRow(ctx, `
+		INSERT INTO user_login_devices (user_id, ip_address, user_agent, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (user_id, ip_address, user_agent) DO UPDATE SET last_seen_at = NOW()
+		RETURNING xmax = 0
+	`, userID, ipAddress, userAgent).Scan(&newDevice)
+	if err != nil {
+		return fmt.Errorf("failed to record login device: %w", err)
+	}
+
+	if newDevice && knownDevices > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO notifications (user_id, type, message, payload)
+			VALUES ($1, $2, $3, jsonb_build_object('ip_address', $4::text, 'user_agent', $5::text))
+		`, userID, models.NotificationNewLogin,
+			fmt.Sprintf("New sign-in to your account from %s", ipAddress),
+			ipAddress, userAgent)
+		if err != nil {
+			return fmt.Errorf("failed to create login notification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
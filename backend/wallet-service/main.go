@@ -10,8 +10,11 @@ import (
 	"syscall"
 	"time"
 
+	"lfg/shared/auth"
 	"lfg/shared/config"
 	"lfg/shared/db"
+	"lfg/shared/metrics"
+	"lfg/shared/tracing"
 	"lfg/wallet-service/handlers"
 	"lfg/wallet-service/repository"
 )
@@ -23,6 +26,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), auth.ServiceWallet, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create context
 	ctx := context.Background()
 
@@ -45,6 +55,7 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close(pool)
+	metrics.RegisterPool(pool)
 
 	log.Println("Connected to database successfully")
 
@@ -57,15 +68,25 @@ func main() {
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.Health)
+	mux.Handle(metrics.Path, metrics.Endpoint(cfg.MetricsToken))
 	mux.HandleFunc("/balance", walletHandler.Balance)
 	mux.HandleFunc("/transactions", walletHandler.Transactions)
-	mux.HandleFunc("/credit", walletHandler.Credit)   // Internal only
-	mux.HandleFunc("/debit", walletHandler.Debit)     // Internal only
+	mux.HandleFunc("/credit", walletHandler.Credit) // Internal only
+	mux.HandleFunc("/debit", walletHandler.Debit)   // Internal only
+
+	// Only allow the services that need them to call the endpoints
+	serviceTokens, err := auth.LoadServiceTokens(auth.ServiceWallet, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load service credentials: %v", err)
+	}
+	serviceGuard := auth.NewServiceGuard(serviceTokens)
+	serviceGuard.Allow(auth.ServiceAPIGateway, "/balance", "/transactions")
+	serviceGuard.Allow(auth.ServiceCreditExchange, "/balance", "/credit", "/debit")
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      tracing.Handler(auth.ServiceWallet, metrics.Handler(mux, serviceGuard.Handler(mux))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -123,10 +123,16 @@ func (r *WalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID
 		return fmt.Errorf("failed to lock wallet: %w", err)
 	}
 
-	// Check if balance would go negative
+	// Debits cannot take what open orders hold
 	newBalance := currentBalance + amount
-	if newBalance < 0 {
-		return ErrInsufficientBalance
+	if amount < 0 {
+		held, err := heldCredits(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+		if newBalance < held {
+			return ErrInsufficientBalance
+		}
 	}
 
 	// Update balance
@@ -195,8 +201,12 @@ func (r *WalletRepository) Transfer(ctx context.Context, fromUserID, toUserID uu
 		return fmt.Errorf("failed to lock to wallet: %w", err)
 	}
 
-	// Check sufficient balance
-	if fromBalance < amount {
+	// Check sufficient balance, less what open orders hold
+	held, err := heldCredits(ctx, tx, fromWalletID)
+	if err != nil {
+		return err
+	}
+	if fromBalance-held < amount {
 		return ErrInsufficientBalance
 	}
 
@@ -225,12 +235,12 @@ func (r *WalletRepository) Transfer(ctx context.Context, fromUserID, toUserID uu
 	return nil
 }
 
-// GetLockedBalance calculates the locked balance from active orders
+// GetLockedBalance returns the credits held by a user's open orders
 func (r *WalletRepository) GetLockedBalance(ctx context.Context, userID uuid.UUID) (float64, error) {
 	query := `
-		SELECT COALESCE(SUM(quantity * limit_price_credits), 0) as locked_balance
-		FROM orders
-		WHERE user_id = $1 AND status = 'ACTIVE'
+		SELECT COALESCE(SUM(credits), 0) as locked_balance
+		FROM order_holds
+		WHERE user_id = $1
 	`
 
 	var lockedBalance float64
@@ -242,6 +252,22 @@ func (r *WalletRepository) GetLockedBalance(ctx context.Context, userID uuid.UUI
 	return lockedBalance, nil
 }
 
+// heldCredits returns the credits held by the open orders of a wallet's user
+func heldCredits(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (float64, error) {
+	var held float64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(h.credits), 0)
+		FROM wallets w
+		JOIN order_holds h ON h.user_id = w.user_id
+		WHERE w.id = $1
+	`, walletID).Scan(&held)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get held balance: %w", err)
+	}
+
+	return held, nil
+}
+
 // GetTransactionHistory retrieves transaction history from credit_transactions table
 func (r *WalletRepository) GetTransactionHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.CreditTransaction, error) {
 	query := `
@@ -0,0 +1,9 @@
+#!/bin/sh
+# Applies every *.up.sql migration in order when the Postgres container is
+# first initialised. Down migrations are intentionally skipped.
+set -e
+
+for f in $(ls /migrations/*.up.sql | sort); do
+	echo "Applying $f"
+	psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" -f "$f"
+done
@@ -0,0 +1,55 @@
+-- Rollback migration 002_categorical_markets
+
+-- Drop positions
+DROP TRIGGER IF EXISTS update_positions_updated_at ON positions;
+DROP TABLE IF EXISTS positions CASCADE;
+
+-- Drop order side
+ALTER TABLE orders DROP COLUMN IF EXISTS side;
+
+-- Restore enum types
+CREATE TYPE contract_side AS ENUM ('YES', 'NO');
+CREATE TYPE market_outcome AS ENUM ('YES', 'NO', 'CANCELLED');
+
+-- Restore YES/NO market outcome (categorical outcomes cannot be represented)
+ALTER TABLE markets ADD COLUMN outcome market_outcome NULL;
+
+UPDATE markets m
+SET outcome = o.name::market_outcome
+FROM market_outcomes o
+WHERE o.id = m.winning_outcome_id AND o.name IN ('YES', 'NO');
+
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS resolution_logic;
+ALTER TABLE markets ADD CONSTRAINT resolution_logic CHECK (
+    (status = 'RESOLVED' AND resolved_at IS NOT NULL AND outcome IS NOT NULL) OR
+    (status != 'RESOLVED' AND resolved_at IS NULL)
+);
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS fk_markets_winning_outcome;
+ALTER TABLE markets DROP COLUMN IF EXISTS winning_outcome_id;
+
+-- Restore contract side; non YES/NO contracts are removed
+DELETE FROM contracts c
+USING market_outcomes o
+WHERE o.id = c.outcome_id AND o.name NOT IN ('YES', 'NO');
+
+ALTER TABLE contracts ADD COLUMN side contract_side NULL;
+
+UPDATE contracts c
+SET side = o.name::contract_side
+FROM market_outcomes o
+WHERE o.id = c.outcome_id;
+
+ALTER TABLE contracts ALTER COLUMN side SET NOT NULL;
+ALTER TABLE contracts DROP CONSTRAINT IF EXISTS unique_market_outcome;
+ALTER TABLE contracts ADD CONSTRAINT unique_market_side UNIQUE(market_id, side);
+DROP INDEX IF EXISTS idx_contracts_outcome_id;
+ALTER TABLE contracts DROP CONSTRAINT IF EXISTS fk_contracts_outcome;
+ALTER TABLE contracts DROP COLUMN IF EXISTS outcome_id;
+
+-- Drop outcomes
+DROP TABLE IF EXISTS market_outcomes CASCADE;
+
+ALTER TABLE markets DROP COLUMN IF EXISTS market_type;
+
+DROP TYPE IF EXISTS order_side;
+DROP TYPE IF EXISTS market_type;
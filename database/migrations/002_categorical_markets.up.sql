@@ -0,0 +1,89 @@
+-- Categorical multi-outcome markets
+-- Migration: 002_categorical_markets
+
+-- Market type distinguishes classic YES/NO markets from N-outcome markets
+CREATE TYPE market_type AS ENUM ('BINARY', 'CATEGORICAL');
+CREATE TYPE order_side AS ENUM ('BUY', 'SELL');
+
+ALTER TABLE markets ADD COLUMN market_type market_type NOT NULL DEFAULT 'BINARY';
+
+-- Named outcomes of a market; every contract trades exactly one outcome
+CREATE TABLE IF NOT EXISTS market_outcomes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    market_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    display_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (market_id) REFERENCES markets(id) ON DELETE CASCADE,
+    CONSTRAINT unique_market_outcome_name UNIQUE(market_id, name)
+);
+
+CREATE INDEX idx_market_outcomes_market_id ON market_outcomes(market_id);
+
+-- Backfill YES/NO outcomes for existing binary markets
+INSERT INTO market_outcomes (market_id, name, display_order)
+SELECT m.id, o.name, o.display_order
+FROM markets m
+CROSS JOIN (VALUES ('YES', 0), ('NO', 1)) AS o(name, display_order);
+
+-- Contracts reference their outcome instead of a hard-coded side
+ALTER TABLE contracts ADD COLUMN outcome_id UUID NULL;
+
+UPDATE contracts c
+SET outcome_id = o.id
+FROM market_outcomes o
+WHERE o.market_id = c.market_id AND o.name = c.side::text;
+
+ALTER TABLE contracts ALTER COLUMN outcome_id SET NOT NULL;
+ALTER TABLE contracts ADD CONSTRAINT fk_contracts_outcome
+    FOREIGN KEY (outcome_id) REFERENCES market_outcomes(id) ON DELETE CASCADE;
+ALTER TABLE contracts DROP CONSTRAINT unique_market_side;
+ALTER TABLE contracts ADD CONSTRAINT unique_market_outcome UNIQUE(market_id, outcome_id);
+ALTER TABLE contracts DROP COLUMN side;
+
+CREATE INDEX idx_contracts_outcome_id ON contracts(outcome_id);
+
+-- Markets resolve to a winning outcome ID instead of the YES/NO enum
+ALTER TABLE markets ADD COLUMN winning_outcome_id UUID NULL;
+
+UPDATE markets m
+SET winning_outcome_id = o.id
+FROM market_outcomes o
+WHERE o.market_id = m.id AND o.name = m.outcome::text;
+
+ALTER TABLE markets ADD CONSTRAINT fk_markets_winning_outcome
+    FOREIGN KEY (winning_outcome_id) REFERENCES market_outcomes(id);
+ALTER TABLE markets DROP CONSTRAINT resolution_logic;
+ALTER TABLE markets ADD CONSTRAINT resolution_logic CHECK (
+    (status = 'RESOLVED' AND resolved_at IS NOT NULL AND winning_outcome_id IS NOT NULL) OR
+    (status != 'RESOLVED' AND resolved_at IS NULL)
+);
+ALTER TABLE markets DROP COLUMN outcome;
+
+DROP TYPE IF EXISTS market_outcome;
+DROP TYPE IF EXISTS contract_side;
+
+-- Orders carry an explicit side now that contracts no longer imply one
+ALTER TABLE orders ADD COLUMN side order_side NOT NULL DEFAULT 'BUY';
+
+-- Positions track contract holdings per user for settlement and complete sets
+CREATE TABLE IF NOT EXISTS positions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    contract_id UUID NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT position_quantity_non_negative CHECK (quantity >= 0),
+    CONSTRAINT unique_user_contract UNIQUE(user_id, contract_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (contract_id) REFERENCES contracts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_positions_user_id ON positions(user_id);
+CREATE INDEX idx_positions_contract_id ON positions(contract_id);
+
+CREATE TRIGGER update_positions_updated_at BEFORE UPDATE ON positions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,23 @@
+-- Rollback migration 003_scalar_markets
+
+-- Scalar markets cannot be represented without their bounds
+DELETE FROM markets WHERE market_type = 'SCALAR';
+
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS resolution_logic;
+ALTER TABLE markets ADD CONSTRAINT resolution_logic CHECK (
+    (status = 'RESOLVED' AND resolved_at IS NOT NULL AND winning_outcome_id IS NOT NULL) OR
+    (status != 'RESOLVED' AND resolved_at IS NULL)
+);
+
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS scalar_bounds_valid;
+ALTER TABLE markets DROP COLUMN IF EXISTS resolved_value;
+ALTER TABLE markets DROP COLUMN IF EXISTS scalar_upper_bound;
+ALTER TABLE markets DROP COLUMN IF EXISTS scalar_lower_bound;
+
+-- Enum values cannot be dropped; recreate the type without SCALAR
+ALTER TABLE markets ALTER COLUMN market_type DROP DEFAULT;
+ALTER TYPE market_type RENAME TO market_type_old;
+CREATE TYPE market_type AS ENUM ('BINARY', 'CATEGORICAL');
+ALTER TABLE markets ALTER COLUMN market_type TYPE market_type USING market_type::text::market_type;
+ALTER TABLE markets ALTER COLUMN market_type SET DEFAULT 'BINARY';
+DROP TYPE market_type_old;
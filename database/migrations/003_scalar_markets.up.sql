@@ -0,0 +1,21 @@
+-- Scalar (range) markets
+-- Migration: 003_scalar_markets
+
+-- Scalar markets trade LONG/SHORT contracts on a numeric result within a range
+ALTER TYPE market_type ADD VALUE IF NOT EXISTS 'SCALAR';
+
+ALTER TABLE markets ADD COLUMN scalar_lower_bound DECIMAL(20, 8) NULL;
+ALTER TABLE markets ADD COLUMN scalar_upper_bound DECIMAL(20, 8) NULL;
+ALTER TABLE markets ADD COLUMN resolved_value DECIMAL(20, 8) NULL;
+
+ALTER TABLE markets ADD CONSTRAINT scalar_bounds_valid CHECK (
+    (market_type = 'SCALAR' AND scalar_lower_bound IS NOT NULL AND scalar_upper_bound IS NOT NULL AND scalar_lower_bound < scalar_upper_bound) OR
+    (market_type != 'SCALAR' AND scalar_lower_bound IS NULL AND scalar_upper_bound IS NULL AND resolved_value IS NULL)
+);
+
+-- Scalar markets resolve to a value rather than a single winning outcome
+ALTER TABLE markets DROP CONSTRAINT resolution_logic;
+ALTER TABLE markets ADD CONSTRAINT resolution_logic CHECK (
+    (status = 'RESOLVED' AND resolved_at IS NOT NULL AND (winning_outcome_id IS NOT NULL OR resolved_value IS NOT NULL)) OR
+    (status != 'RESOLVED' AND resolved_at IS NULL)
+);
@@ -0,0 +1,7 @@
+-- Rollback migration 004_market_lifecycle
+
+DROP INDEX IF EXISTS idx_markets_open_expires_at;
+DROP INDEX IF EXISTS idx_markets_upcoming_opens_at;
+
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS opens_before_expiry;
+ALTER TABLE markets DROP COLUMN IF EXISTS opens_at;
@@ -0,0 +1,11 @@
+-- Market lifecycle scheduling
+-- Migration: 004_market_lifecycle
+
+-- UPCOMING markets open automatically once opens_at has passed
+ALTER TABLE markets ADD COLUMN opens_at TIMESTAMPTZ NULL;
+
+ALTER TABLE markets ADD CONSTRAINT opens_before_expiry CHECK (opens_at IS NULL OR opens_at < expires_at);
+
+-- Partial indexes for the scheduler's due-market scans
+CREATE INDEX idx_markets_upcoming_opens_at ON markets(opens_at) WHERE status = 'UPCOMING';
+CREATE INDEX idx_markets_open_expires_at ON markets(expires_at) WHERE status IN ('UPCOMING', 'OPEN');
@@ -0,0 +1,29 @@
+-- Rollback migration 005_admin_markets
+
+DROP TABLE IF EXISTS admin_audit_log CASCADE;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS user_role_valid;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+
+-- Drafts were never published; remove them before dropping the status
+DELETE FROM markets WHERE status = 'DRAFT';
+
+-- Enum values cannot be dropped; recreate the type without DRAFT. Objects
+-- comparing status against enum literals must be recreated around the change.
+DROP INDEX IF EXISTS idx_markets_upcoming_opens_at;
+DROP INDEX IF EXISTS idx_markets_open_expires_at;
+ALTER TABLE markets DROP CONSTRAINT IF EXISTS resolution_logic;
+ALTER TABLE markets ALTER COLUMN status DROP DEFAULT;
+
+ALTER TYPE market_status RENAME TO market_status_old;
+CREATE TYPE market_status AS ENUM ('UPCOMING', 'OPEN', 'CLOSED', 'RESOLVED', 'CANCELLED');
+ALTER TABLE markets ALTER COLUMN status TYPE market_status USING status::text::market_status;
+DROP TYPE market_status_old;
+
+ALTER TABLE markets ALTER COLUMN status SET DEFAULT 'UPCOMING';
+ALTER TABLE markets ADD CONSTRAINT resolution_logic CHECK (
+    (status = 'RESOLVED' AND resolved_at IS NOT NULL AND (winning_outcome_id IS NOT NULL OR resolved_value IS NOT NULL)) OR
+    (status != 'RESOLVED' AND resolved_at IS NULL)
+);
+CREATE INDEX idx_markets_upcoming_opens_at ON markets(opens_at) WHERE status = 'UPCOMING';
+CREATE INDEX idx_markets_open_expires_at ON markets(expires_at) WHERE status IN ('UPCOMING', 'OPEN');
@@ -0,0 +1,26 @@
+-- Admin market management
+-- Migration: 005_admin_markets
+
+-- Draft markets are being prepared by an admin and are not visible publicly
+ALTER TYPE market_status ADD VALUE IF NOT EXISTS 'DRAFT' BEFORE 'UPCOMING';
+
+-- User roles carried in the JWT role claim
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT user_role_valid CHECK (role IN ('user', 'admin'));
+
+-- Audit log of every admin action
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_user_id UUID NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    market_id UUID NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (actor_user_id) REFERENCES users(id),
+    FOREIGN KEY (market_id) REFERENCES markets(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_admin_audit_log_actor ON admin_audit_log(actor_user_id);
+CREATE INDEX idx_admin_audit_log_market ON admin_audit_log(market_id);
+CREATE INDEX idx_admin_audit_log_created_at ON admin_audit_log(created_at DESC);
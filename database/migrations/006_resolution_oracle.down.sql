@@ -0,0 +1,11 @@
+-- Rollback migration 006_resolution_oracle
+
+DROP TRIGGER IF EXISTS update_resolution_proposals_updated_at ON resolution_proposals;
+DROP TRIGGER IF EXISTS update_market_resolvers_updated_at ON market_resolvers;
+
+DROP TABLE IF EXISTS resolution_disputes CASCADE;
+DROP TABLE IF EXISTS resolution_proposals CASCADE;
+DROP TABLE IF EXISTS market_resolvers CASCADE;
+
+DROP TYPE IF EXISTS dispute_status;
+DROP TYPE IF EXISTS proposal_status;
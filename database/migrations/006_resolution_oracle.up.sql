@@ -0,0 +1,71 @@
+-- Resolution oracle with proposals and disputes
+-- Migration: 006_resolution_oracle
+
+CREATE TYPE proposal_status AS ENUM ('PROPOSED', 'DISPUTED', 'FINALIZED', 'REJECTED');
+CREATE TYPE dispute_status AS ENUM ('OPEN', 'UPHELD', 'REJECTED');
+
+-- Optional automated resolver per market (e.g. a JSON feed); resolver_config
+-- is interpreted by the resolver plugin named in resolver_type
+CREATE TABLE IF NOT EXISTS market_resolvers (
+    market_id UUID PRIMARY KEY,
+    resolver_type VARCHAR(50) NOT NULL,
+    resolver_config JSONB NOT NULL DEFAULT '{}',
+    last_checked_at TIMESTAMPTZ NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (market_id) REFERENCES markets(id) ON DELETE CASCADE
+);
+
+-- Proposed resolutions; each is held in a challenge window before finalizing
+CREATE TABLE IF NOT EXISTS resolution_proposals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    market_id UUID NOT NULL,
+    proposer_user_id UUID NULL,
+    source VARCHAR(20) NOT NULL,
+    outcome_id UUID NULL,
+    value DECIMAL(20, 8) NULL,
+    evidence TEXT NULL,
+    status proposal_status NOT NULL DEFAULT 'PROPOSED',
+    challenge_ends_at TIMESTAMPTZ NOT NULL,
+    finalized_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT proposal_source_valid CHECK (source IN ('ADMIN', 'RESOLVER')),
+    CONSTRAINT proposal_result_present CHECK (outcome_id IS NOT NULL OR value IS NOT NULL),
+    FOREIGN KEY (market_id) REFERENCES markets(id) ON DELETE CASCADE,
+    FOREIGN KEY (proposer_user_id) REFERENCES users(id),
+    FOREIGN KEY (outcome_id) REFERENCES market_outcomes(id)
+);
+
+CREATE INDEX idx_resolution_proposals_market_id ON resolution_proposals(market_id, created_at DESC);
+CREATE INDEX idx_resolution_proposals_due ON resolution_proposals(challenge_ends_at) WHERE status = 'PROPOSED';
+-- At most one pending proposal per market
+CREATE UNIQUE INDEX idx_resolution_proposals_pending ON resolution_proposals(market_id) WHERE status IN ('PROPOSED', 'DISPUTED');
+
+-- Disputes against a proposal, each backed by a bond
+CREATE TABLE IF NOT EXISTS resolution_disputes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    proposal_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    bond_credits DECIMAL(20, 8) NOT NULL,
+    reason TEXT NOT NULL,
+    status dispute_status NOT NULL DEFAULT 'OPEN',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    settled_at TIMESTAMPTZ NULL,
+
+    CONSTRAINT dispute_bond_positive CHECK (bond_credits > 0),
+    CONSTRAINT unique_proposal_disputer UNIQUE(proposal_id, user_id),
+    FOREIGN KEY (proposal_id) REFERENCES resolution_proposals(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_resolution_disputes_proposal_id ON resolution_disputes(proposal_id);
+
+CREATE TRIGGER update_market_resolvers_updated_at BEFORE UPDATE ON market_resolvers
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_resolution_proposals_updated_at BEFORE UPDATE ON resolution_proposals
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,13 @@
+-- Rollback migration 007_market_search
+
+DROP INDEX IF EXISTS idx_markets_question_prefix;
+DROP INDEX IF EXISTS idx_markets_ticker_prefix;
+DROP INDEX IF EXISTS idx_markets_search_vector;
+DROP INDEX IF EXISTS idx_markets_tags;
+DROP INDEX IF EXISTS idx_markets_category;
+
+ALTER TABLE markets DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE markets DROP COLUMN IF EXISTS tags;
+ALTER TABLE markets DROP COLUMN IF EXISTS category;
+
+DROP TABLE IF EXISTS market_categories;
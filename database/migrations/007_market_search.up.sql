@@ -0,0 +1,36 @@
+-- Market categories, tags and full-text search
+-- Migration: 007_market_search
+
+CREATE TABLE IF NOT EXISTS market_categories (
+    slug VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    display_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT category_slug_format CHECK (slug ~ '^[a-z0-9-]+$')
+);
+
+INSERT INTO market_categories (slug, name, display_order) VALUES
+('crypto', 'Crypto', 0),
+('economics', 'Economics', 1),
+('politics', 'Politics', 2),
+('science', 'Science & Tech', 3),
+('sports', 'Sports', 4)
+ON CONFLICT (slug) DO NOTHING;
+
+ALTER TABLE markets ADD COLUMN category VARCHAR(50) NULL REFERENCES market_categories(slug) ON UPDATE CASCADE;
+ALTER TABLE markets ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Question matches rank above rules matches
+ALTER TABLE markets ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(question, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(rules, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_markets_category ON markets(category);
+CREATE INDEX idx_markets_tags ON markets USING GIN(tags);
+CREATE INDEX idx_markets_search_vector ON markets USING GIN(search_vector);
+
+-- Prefix autocomplete on ticker and question
+CREATE INDEX idx_markets_ticker_prefix ON markets(LOWER(ticker) text_pattern_ops);
+CREATE INDEX idx_markets_question_prefix ON markets(LOWER(question) text_pattern_ops);
@@ -0,0 +1,7 @@
+-- Rollback migration 008_market_events
+
+DROP INDEX IF EXISTS idx_markets_event_id;
+ALTER TABLE markets DROP COLUMN IF EXISTS event_id;
+
+DROP TRIGGER IF EXISTS update_events_updated_at ON events;
+DROP TABLE IF EXISTS events;
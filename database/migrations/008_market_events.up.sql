@@ -0,0 +1,23 @@
+-- Events grouping related markets
+-- Migration: 008_market_events
+
+CREATE TABLE IF NOT EXISTS events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ticker VARCHAR(50) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    category VARCHAR(50) NULL REFERENCES market_categories(slug) ON UPDATE CASCADE,
+    closes_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_closes_at ON events(closes_at);
+CREATE INDEX idx_events_category ON events(category);
+
+CREATE TRIGGER update_events_updated_at BEFORE UPDATE ON events
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE markets ADD COLUMN event_id UUID NULL CONSTRAINT markets_event_id_fkey REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_markets_event_id ON markets(event_id);
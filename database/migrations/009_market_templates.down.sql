@@ -0,0 +1,7 @@
+-- Rollback migration 009_market_templates
+
+DROP INDEX IF EXISTS idx_markets_template_id;
+ALTER TABLE markets DROP COLUMN IF EXISTS template_id;
+
+DROP TRIGGER IF EXISTS update_market_templates_updated_at ON market_templates;
+DROP TABLE IF EXISTS market_templates;
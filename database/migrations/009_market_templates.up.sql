@@ -0,0 +1,43 @@
+-- Recurring market templates
+-- Migration: 009_market_templates
+
+-- Ticker, question and rules are Go text/template patterns rendered for every
+-- run; a run creates a market opening at next_run_at and expiring
+-- duration_seconds later, then next_run_at advances by interval_seconds
+CREATE TABLE IF NOT EXISTS market_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) UNIQUE NOT NULL,
+    ticker_pattern VARCHAR(100) NOT NULL,
+    question_pattern TEXT NOT NULL,
+    rules_pattern TEXT NOT NULL,
+    resolution_source VARCHAR(255) NOT NULL,
+    market_type market_type NOT NULL DEFAULT 'BINARY',
+    outcomes TEXT[] NOT NULL DEFAULT '{}',
+    scalar_lower_bound DECIMAL(20, 8) NULL,
+    scalar_upper_bound DECIMAL(20, 8) NULL,
+    category VARCHAR(50) NULL REFERENCES market_categories(slug) ON UPDATE CASCADE,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    event_id UUID NULL REFERENCES events(id) ON DELETE SET NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    interval_seconds INT NOT NULL,
+    duration_seconds INT NOT NULL,
+    auto_publish BOOLEAN NOT NULL DEFAULT TRUE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT template_interval_positive CHECK (interval_seconds > 0),
+    CONSTRAINT template_duration_positive CHECK (duration_seconds > 0)
+);
+
+CREATE INDEX idx_market_templates_due ON market_templates(next_run_at) WHERE active;
+
+CREATE TRIGGER update_market_templates_updated_at BEFORE UPDATE ON market_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE markets ADD COLUMN template_id UUID NULL REFERENCES market_templates(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_markets_template_id ON markets(template_id);
@@ -0,0 +1,6 @@
+-- Rollback migration 010_watchlists_alerts
+
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS alert_rules;
+DROP TYPE IF EXISTS alert_rule_type;
+DROP TABLE IF EXISTS watchlists;
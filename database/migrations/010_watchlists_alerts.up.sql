@@ -0,0 +1,51 @@
+-- Watchlists and price alerts
+-- Migration: 010_watchlists_alerts
+
+CREATE TABLE IF NOT EXISTS watchlists (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    market_id UUID NOT NULL REFERENCES markets(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, market_id)
+);
+
+CREATE INDEX idx_watchlists_market_id ON watchlists(market_id);
+
+CREATE TYPE alert_rule_type AS ENUM ('PRICE_ABOVE', 'PRICE_BELOW', 'SPREAD_BELOW', 'CLOSING_SOON', 'MARKET_RESOLVED');
+
+-- Rules fire once and are then deactivated. The threshold is a contract price
+-- for PRICE_ABOVE and PRICE_BELOW, a bid/ask spread for SPREAD_BELOW and a
+-- number of seconds before expiry for CLOSING_SOON.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    market_id UUID NOT NULL REFERENCES markets(id) ON DELETE CASCADE,
+    contract_id UUID NULL REFERENCES contracts(id) ON DELETE CASCADE,
+    rule_type alert_rule_type NOT NULL,
+    threshold DECIMAL(20, 8) NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    triggered_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT alert_rule_contract CHECK (
+        rule_type NOT IN ('PRICE_ABOVE', 'PRICE_BELOW', 'SPREAD_BELOW') OR contract_id IS NOT NULL
+    ),
+    CONSTRAINT alert_rule_threshold CHECK (rule_type = 'MARKET_RESOLVED' OR threshold > 0)
+);
+
+CREATE INDEX idx_alert_rules_user_id ON alert_rules(user_id);
+CREATE INDEX idx_alert_rules_contract ON alert_rules(contract_id) WHERE active;
+CREATE INDEX idx_alert_rules_market ON alert_rules(market_id, rule_type) WHERE active;
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NULL REFERENCES alert_rules(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    market_id UUID NOT NULL REFERENCES markets(id) ON DELETE CASCADE,
+    rule_type alert_rule_type NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alerts_user_created ON alerts(user_id, created_at DESC);
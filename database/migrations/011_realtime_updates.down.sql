@@ -0,0 +1,8 @@
+-- Rollback migration 011_realtime_updates
+
+DROP TRIGGER IF EXISTS notify_wallets_update ON wallets;
+DROP FUNCTION IF EXISTS notify_wallet_update();
+
+DROP TRIGGER IF EXISTS notify_orders_update ON orders;
+DROP TRIGGER IF EXISTS notify_orders_insert ON orders;
+DROP FUNCTION IF EXISTS notify_order_update();
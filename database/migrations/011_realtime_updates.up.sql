@@ -0,0 +1,49 @@
+-- Realtime order and balance updates
+-- Migration: 011_realtime_updates
+
+-- Every change to an order's status or fills and to a wallet's balance is
+-- announced with NOTIFY, whichever service made it, so notification-service
+-- can push it to the owner's private websocket channels
+CREATE OR REPLACE FUNCTION notify_order_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('order_updates', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id,
+        'contract_id', NEW.contract_id,
+        'type', NEW.type,
+        'side', NEW.side,
+        'status', NEW.status,
+        'quantity', NEW.quantity,
+        'quantity_filled', NEW.quantity_filled,
+        'limit_price_credits', NEW.limit_price_credits,
+        'updated_at', NEW.updated_at
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_orders_insert AFTER INSERT ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_order_update();
+
+CREATE TRIGGER notify_orders_update AFTER UPDATE OF status, quantity_filled ON orders
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status OR OLD.quantity_filled IS DISTINCT FROM NEW.quantity_filled)
+    EXECUTE FUNCTION notify_order_update();
+
+CREATE OR REPLACE FUNCTION notify_wallet_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('wallet_updates', json_build_object(
+        'user_id', NEW.user_id,
+        'balance_credits', NEW.balance_credits,
+        'updated_at', NEW.updated_at
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_wallets_update AFTER UPDATE OF balance_credits ON wallets
+    FOR EACH ROW
+    WHEN (OLD.balance_credits IS DISTINCT FROM NEW.balance_credits)
+    EXECUTE FUNCTION notify_wallet_update();
@@ -0,0 +1,5 @@
+-- Rollback migration 012_notifications
+
+DROP TRIGGER IF EXISTS notify_notifications_insert ON notifications;
+DROP FUNCTION IF EXISTS notify_notification_insert();
+DROP TABLE IF EXISTS notifications;
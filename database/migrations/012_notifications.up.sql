@@ -0,0 +1,34 @@
+-- Durable notification inbox
+-- Migration: 012_notifications
+
+-- Notifications are written by whichever service produces them, often in the
+-- same transaction as the change they report, and kept until read so users
+-- who were offline still receive them
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id, created_at) WHERE read_at IS NULL;
+
+-- New notifications are announced so notification-service can push them to
+-- connected users right away
+CREATE OR REPLACE FUNCTION notify_notification_insert()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('notifications', json_build_object(
+        'id', NEW.id,
+        'user_id', NEW.user_id
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_notifications_insert AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE FUNCTION notify_notification_insert();
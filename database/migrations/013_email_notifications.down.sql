@@ -0,0 +1,15 @@
+-- Rollback migration 013_email_notifications
+
+DROP TRIGGER IF EXISTS notify_credit_transactions_update ON credit_transactions;
+DROP TRIGGER IF EXISTS notify_credit_transactions_insert ON credit_transactions;
+DROP FUNCTION IF EXISTS notify_credit_transaction_completed();
+DROP TABLE IF EXISTS user_login_devices;
+DROP TABLE IF EXISTS email_outbox;
+DROP INDEX IF EXISTS idx_notifications_email_digest;
+DROP INDEX IF EXISTS idx_notifications_email_new;
+ALTER TABLE notifications DROP COLUMN IF EXISTS email_claimed_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS email_status;
+DROP TABLE IF EXISTS email_preferences;
+DROP TYPE IF EXISTS email_status;
+DROP TYPE IF EXISTS email_digest_interval;
+DROP TYPE IF EXISTS email_mode;
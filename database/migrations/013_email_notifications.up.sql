@@ -0,0 +1,111 @@
+-- Email notifications
+-- Migration: 013_email_notifications
+
+CREATE TYPE email_mode AS ENUM ('IMMEDIATE', 'DIGEST', 'OFF');
+CREATE TYPE email_digest_interval AS ENUM ('HOURLY', 'DAILY');
+CREATE TYPE email_status AS ENUM ('PENDING', 'SENT', 'FAILED');
+
+-- Per-user email preferences; users without a row get the column defaults
+CREATE TABLE IF NOT EXISTS email_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    fills email_mode NOT NULL DEFAULT 'DIGEST',
+    settlements email_mode NOT NULL DEFAULT 'IMMEDIATE',
+    transfers email_mode NOT NULL DEFAULT 'IMMEDIATE',
+    security email_mode NOT NULL DEFAULT 'IMMEDIATE',
+    alerts email_mode NOT NULL DEFAULT 'OFF',
+    digest_interval email_digest_interval NOT NULL DEFAULT 'DAILY',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_email_preferences_updated_at BEFORE UPDATE ON email_preferences
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Where each notification stands in the email pipeline: NULL until the email
+-- worker picks it up, then PROCESSING, QUEUED, DIGEST, DIGESTED or SKIPPED
+ALTER TABLE notifications ADD COLUMN email_status VARCHAR(20) NULL;
+ALTER TABLE notifications ADD COLUMN email_claimed_at TIMESTAMPTZ NULL;
+
+-- Notifications from before email existed are not emailed
+UPDATE notifications SET email_status = 'SKIPPED';
+
+CREATE INDEX idx_notifications_email_new ON notifications(created_at) WHERE email_status IS NULL OR email_status = 'PROCESSING';
+CREATE INDEX idx_notifications_email_digest ON notifications(user_id, created_at) WHERE email_status = 'DIGEST';
+
+-- Rendered emails waiting to be sent, retried with backoff on transient
+-- failures
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    notification_id UUID NULL REFERENCES notifications(id) ON DELETE SET NULL,
+    to_address VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    status email_status NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT NULL,
+    sent_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_outbox_pending ON email_outbox(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX idx_email_outbox_user ON email_outbox(user_id, created_at DESC);
+
+-- Devices users have signed in from, so a sign-in from a new one can be
+-- reported
+CREATE TABLE IF NOT EXISTS user_login_devices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, ip_address, user_agent)
+);
+
+-- Completed credit purchases and sales land in the user's inbox as deposits
+-- and withdrawals
+CREATE OR REPLACE FUNCTION notify_credit_transaction_completed()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.type = 'PURCHASE' THEN
+        INSERT INTO notifications (user_id, type, message, payload)
+        VALUES (
+            NEW.user_id,
+            'DEPOSIT',
+            format('Deposit of %s %s completed: %s credits added', NEW.crypto_amount, NEW.crypto_type, round(NEW.credit_amount, 2)),
+            jsonb_build_object(
+                'transaction_id', NEW.id,
+                'crypto_type', NEW.crypto_type,
+                'crypto_amount', NEW.crypto_amount,
+                'credits', NEW.credit_amount
+            )
+        );
+    ELSE
+        INSERT INTO notifications (user_id, type, message, payload)
+        VALUES (
+            NEW.user_id,
+            'WITHDRAWAL',
+            format('Withdrawal of %s credits completed: %s %s sent', round(NEW.credit_amount, 2), NEW.crypto_amount, NEW.crypto_type),
+            jsonb_build_object(
+                'transaction_id', NEW.id,
+                'crypto_type', NEW.crypto_type,
+                'crypto_amount', NEW.crypto_amount,
+                'credits', NEW.credit_amount
+            )
+        );
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_credit_transactions_insert AFTER INSERT ON credit_transactions
+    FOR EACH ROW
+    WHEN (NEW.status = 'COMPLETED')
+    EXECUTE FUNCTION notify_credit_transaction_completed();
+
+CREATE TRIGGER notify_credit_transactions_update AFTER UPDATE OF status ON credit_transactions
+    FOR EACH ROW
+    WHEN (NEW.status = 'COMPLETED' AND OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION notify_credit_transaction_completed();
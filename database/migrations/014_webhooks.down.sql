@@ -0,0 +1,5 @@
+-- Rollback migration 014_webhooks
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TYPE IF EXISTS webhook_delivery_status;
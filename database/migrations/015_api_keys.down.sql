@@ -0,0 +1,3 @@
+-- Rollback migration 015_api_keys
+
+DROP TABLE IF EXISTS api_keys;
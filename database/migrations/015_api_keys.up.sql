@@ -0,0 +1,20 @@
+-- API keys for automated trading
+-- Migration: 015_api_keys
+
+-- Keys sign requests with their secret, which is kept so the API gateway can
+-- verify the signatures. A key can only reach the routes of its scopes, from
+-- its allowed addresses if any, until it expires or is revoked
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key_id VARCHAR(64) NOT NULL UNIQUE,
+    secret VARCHAR(128) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys(user_id, created_at DESC);
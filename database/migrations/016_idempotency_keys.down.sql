@@ -0,0 +1,3 @@
+-- Rollback migration 016_idempotency_keys
+
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,21 @@
+-- Idempotency keys for order placement and credit exchange
+-- Migration: 016_idempotency_keys
+
+-- The first request made with a key claims it; its response is stored once
+-- it completes and replayed to retries until the key expires. A claim whose
+-- request never completed can be taken over once locked_until has passed
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER NULL,
+    content_type VARCHAR(255) NULL,
+    response_body BYTEA NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys(expires_at);
@@ -0,0 +1,5 @@
+-- Rollback migration 017_fix_sessions
+
+DROP TABLE IF EXISTS fix_orders;
+DROP TABLE IF EXISTS fix_messages;
+DROP TABLE IF EXISTS fix_sessions;
@@ -0,0 +1,45 @@
+-- FIX order entry sessions
+-- Migration: 017_fix_sessions
+
+-- A session is identified by the comp IDs of its counterparty and of the
+-- gateway, and belongs to the user whose API key first logged it on. Its
+-- sequence numbers survive reconnects until a logon resets them
+CREATE TABLE IF NOT EXISTS fix_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender_comp_id VARCHAR(64) NOT NULL,
+    target_comp_id VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    next_sender_seq INTEGER NOT NULL DEFAULT 1,
+    next_target_seq INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (sender_comp_id, target_comp_id)
+);
+
+-- Messages sent to a session, kept to answer its resend requests. body holds
+-- the fields after the standard header
+CREATE TABLE IF NOT EXISTS fix_messages (
+    session_id UUID NOT NULL REFERENCES fix_sessions(id) ON DELETE CASCADE,
+    seq_num INTEGER NOT NULL,
+    msg_type VARCHAR(8) NOT NULL,
+    body BYTEA NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, seq_num)
+);
+
+CREATE INDEX idx_fix_messages_sent ON fix_messages(sent_at);
+
+-- Orders entered over FIX, by the ClOrdID the session gave them. A
+-- replacement order carries over the quantity and value its predecessors
+-- filled, so its execution reports cover the whole chain
+CREATE TABLE IF NOT EXISTS fix_orders (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL REFERENCES fix_sessions(id) ON DELETE CASCADE,
+    cl_ord_id VARCHAR(64) NOT NULL,
+    orig_cl_ord_id VARCHAR(64) NULL,
+    order_qty INTEGER NOT NULL,
+    cum_qty INTEGER NOT NULL DEFAULT 0,
+    cum_value DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (session_id, cl_ord_id)
+);
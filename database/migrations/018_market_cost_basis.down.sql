@@ -0,0 +1,3 @@
+-- Rollback migration 018_market_cost_basis
+
+DROP TABLE IF EXISTS market_cost_basis;
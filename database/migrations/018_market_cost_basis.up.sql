@@ -0,0 +1,28 @@
+-- Cost basis of market positions
+-- Migration: 018_market_cost_basis
+
+-- What each user has paid into a market, net of what they took out of it:
+-- complete sets minted less sets redeemed, and contracts bought less
+-- contracts sold. A cancelled market refunds it, so cancelling puts every
+-- user back where they started rather than moving credits between sides.
+CREATE TABLE IF NOT EXISTS market_cost_basis (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    market_id UUID NOT NULL REFERENCES markets(id) ON DELETE CASCADE,
+    credits DECIMAL(18, 8) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, market_id)
+);
+
+CREATE INDEX idx_market_cost_basis_market_id ON market_cost_basis(market_id);
+
+-- Start from the recorded trades; complete sets minted before now were not
+-- recorded and count as paid for by the trades that spread them
+INSERT INTO market_cost_basis (user_id, market_id, credits)
+SELECT o.user_id, c.market_id,
+       SUM(CASE WHEN o.side = 'BUY' THEN t.quantity * t.price_credits ELSE -t.quantity * t.price_credits END)
+FROM trades t
+JOIN orders o ON o.id IN (t.maker_order_id, t.taker_order_id)
+JOIN contracts c ON c.id = t.contract_id
+GROUP BY o.user_id, c.market_id
+ON CONFLICT (user_id, market_id) DO NOTHING;
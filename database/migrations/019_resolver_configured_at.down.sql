@@ -0,0 +1,3 @@
+-- Rollback migration 019_resolver_configured_at
+
+ALTER TABLE market_resolvers DROP COLUMN IF EXISTS configured_at;
@@ -0,0 +1,9 @@
+-- Automated resolvers stop after a rejected proposal
+-- Migration: 019_resolver_configured_at
+
+-- When an admin last configured a resolver. A resolver whose proposal was
+-- rejected since then would only propose the same result again, so it is
+-- not run until it is configured again.
+ALTER TABLE market_resolvers ADD COLUMN IF NOT EXISTS configured_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE market_resolvers SET configured_at = created_at;
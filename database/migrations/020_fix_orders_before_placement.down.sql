@@ -0,0 +1,6 @@
+-- Rollback migration 020_fix_orders_before_placement
+
+DELETE FROM fix_orders WHERE order_id NOT IN (SELECT id FROM orders);
+
+ALTER TABLE fix_orders
+    ADD CONSTRAINT fix_orders_order_id_fkey FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
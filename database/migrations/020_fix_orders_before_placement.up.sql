@@ -0,0 +1,7 @@
+-- FIX orders are recorded before they are placed
+-- Migration: 020_fix_orders_before_placement
+
+-- fix-gateway places orders through order-service with an order ID of its
+-- choosing, recording the FIX order first so none of its fills go
+-- unreported; the order is created by order-service, so it may not exist yet
+ALTER TABLE fix_orders DROP CONSTRAINT IF EXISTS fix_orders_order_id_fkey;
@@ -0,0 +1,5 @@
+-- Rollback migration 021_order_holds
+
+DROP TABLE IF EXISTS unsettled_orders;
+DROP INDEX IF EXISTS idx_orders_open_user;
+DROP VIEW IF EXISTS order_holds;
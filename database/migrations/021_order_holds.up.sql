@@ -0,0 +1,34 @@
+-- Funds and contracts held by open orders, and fills awaiting settlement
+-- Migration: 021_order_holds
+
+-- An open order holds back what it can still cost its user: the unfilled
+-- part of a buy at its limit price, or at the maximum price of 1 credit for
+-- a market buy, and the unfilled part of a sell in contracts. Orders are
+-- only accepted, and credits and contracts only taken, out of what is not
+-- held
+CREATE OR REPLACE VIEW order_holds AS
+SELECT
+    user_id,
+    contract_id,
+    SUM(CASE WHEN side = 'BUY' THEN (quantity - quantity_filled) * COALESCE(limit_price_credits, 1) ELSE 0 END) AS credits,
+    SUM(CASE WHEN side = 'SELL' THEN quantity - quantity_filled ELSE 0 END) AS quantity
+FROM orders
+WHERE status IN ('PENDING', 'ACTIVE', 'PARTIALLY_FILLED')
+GROUP BY user_id, contract_id;
+
+CREATE INDEX idx_orders_open_user ON orders(user_id, contract_id) WHERE status IN ('PENDING', 'ACTIVE', 'PARTIALLY_FILLED');
+
+-- Matches order-service could not record when the matching engine reported
+-- them. The order stays PENDING, holding its funds, until they are recorded
+CREATE TABLE IF NOT EXISTS unsettled_orders (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    status order_status NOT NULL,
+    quantity_filled INTEGER NOT NULL,
+    fills JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_unsettled_orders_updated ON unsettled_orders(updated_at);
@@ -0,0 +1,4 @@
+-- Rollback migration 022_market_trading_halts
+
+DROP INDEX IF EXISTS idx_markets_unhalted;
+ALTER TABLE markets DROP COLUMN IF EXISTS trading_halted_at;
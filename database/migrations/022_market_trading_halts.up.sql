@@ -0,0 +1,14 @@
+-- Track which stopped markets the matching engine has halted
+-- Migration: 022_market_trading_halts
+
+-- Set once the matching engine has halted every contract of a market that
+-- stopped trading and its resting orders are cancelled. Markets stopped
+-- without it are halted again until the engine confirms, and are not settled
+-- before then.
+ALTER TABLE markets ADD COLUMN trading_halted_at TIMESTAMPTZ;
+
+-- Markets settled before halts were tracked stopped trading long ago
+UPDATE markets SET trading_halted_at = updated_at WHERE status IN ('RESOLVED', 'CANCELLED');
+
+CREATE INDEX idx_markets_unhalted ON markets(updated_at)
+    WHERE trading_halted_at IS NULL AND status IN ('CLOSED', 'RESOLVED', 'CANCELLED');
@@ -11,6 +11,9 @@ INSERT INTO users (id, email, password_hash, wallet_address, status, created_at,
 ('550e8400-e29b-41d4-a716-446655440004', 'eve@example.com', '$2a$12$LQv3c1yqBWVHxkd0LHAkCOYz6TtxMQJqhN8/LewY5GyYzpLpVF3jO', NULL, 'SUSPENDED', NOW(), NOW()),
 ('550e8400-e29b-41d4-a716-446655440005', 'admin@lfg.com', '$2a$12$LQv3c1yqBWVHxkd0LHAkCOYz6TtxMQJqhN8/LewY5GyYzpLpVF3jO', NULL, 'ACTIVE', NOW(), NOW());
 
+-- Admin role for market management
+UPDATE users SET role = 'admin' WHERE id = '550e8400-e29b-41d4-a716-446655440005';
+
 -- Wallets for test users
 INSERT INTO wallets (id, user_id, balance_credits, created_at, updated_at) VALUES
 ('660e8400-e29b-41d4-a716-446655440000', '550e8400-e29b-41d4-a716-446655440000', 1000.00000000, NOW(), NOW()),
@@ -28,23 +31,90 @@ INSERT INTO markets (id, ticker, question, rules, resolution_source, status, exp
 ('770e8400-e29b-41d4-a716-446655440003', 'AI_AGI_2026', 'Will AGI be achieved by end of 2026?', 'Market resolves YES if a credible AI lab (OpenAI, DeepMind, Anthropic, Meta) publicly announces AGI achievement by Dec 31, 2026. Expert panel determines credibility.', 'AI Research Community', 'OPEN', '2026-12-31 23:59:59+00', NOW(), NOW()),
 ('770e8400-e29b-41d4-a716-446655440004', 'MARS2030', 'Will humans land on Mars by 2030?', 'Market resolves YES if humans successfully land on Mars and survive for at least 24 hours by Dec 31, 2030 23:59:59 UTC.', 'NASA Official', 'OPEN', '2030-12-31 23:59:59+00', NOW(), NOW());
 
--- Contracts for markets (YES/NO for each market)
-INSERT INTO contracts (id, market_id, side, ticker, created_at) VALUES
+-- Outcomes for markets (YES/NO for each binary market)
+INSERT INTO market_outcomes (id, market_id, name, display_order, created_at) VALUES
 -- BTC100K2025
-('880e8400-e29b-41d4-a716-446655440000', '770e8400-e29b-41d4-a716-446655440000', 'YES', 'BTC100K2025-YES', NOW()),
-('880e8400-e29b-41d4-a716-446655440001', '770e8400-e29b-41d4-a716-446655440000', 'NO', 'BTC100K2025-NO', NOW()),
+('8a0e8400-e29b-41d4-a716-446655440000', '770e8400-e29b-41d4-a716-446655440000', 'YES', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440001', '770e8400-e29b-41d4-a716-446655440000', 'NO', 1, NOW()),
 -- ETH5K2025
-('880e8400-e29b-41d4-a716-446655440002', '770e8400-e29b-41d4-a716-446655440001', 'YES', 'ETH5K2025-YES', NOW()),
-('880e8400-e29b-41d4-a716-446655440003', '770e8400-e29b-41d4-a716-446655440001', 'NO', 'ETH5K2025-NO', NOW()),
+('8a0e8400-e29b-41d4-a716-446655440002', '770e8400-e29b-41d4-a716-446655440001', 'YES', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440003', '770e8400-e29b-41d4-a716-446655440001', 'NO', 1, NOW()),
 -- SUPERBOWL2026
-('880e8400-e29b-41d4-a716-446655440004', '770e8400-e29b-41d4-a716-446655440002', 'YES', 'SUPERBOWL2026-YES', NOW()),
-('880e8400-e29b-41d4-a716-446655440005', '770e8400-e29b-41d4-a716-446655440002', 'NO', 'SUPERBOWL2026-NO', NOW()),
+('8a0e8400-e29b-41d4-a716-446655440004', '770e8400-e29b-41d4-a716-446655440002', 'YES', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440005', '770e8400-e29b-41d4-a716-446655440002', 'NO', 1, NOW()),
 -- AI_AGI_2026
-('880e8400-e29b-41d4-a716-446655440006', '770e8400-e29b-41d4-a716-446655440003', 'YES', 'AI_AGI_2026-YES', NOW()),
-('880e8400-e29b-41d4-a716-446655440007', '770e8400-e29b-41d4-a716-446655440003', 'NO', 'AI_AGI_2026-NO', NOW()),
+('8a0e8400-e29b-41d4-a716-446655440006', '770e8400-e29b-41d4-a716-446655440003', 'YES', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440007', '770e8400-e29b-41d4-a716-446655440003', 'NO', 1, NOW()),
 -- MARS2030
-('880e8400-e29b-41d4-a716-446655440008', '770e8400-e29b-41d4-a716-446655440004', 'YES', 'MARS2030-YES', NOW()),
-('880e8400-e29b-41d4-a716-446655440009', '770e8400-e29b-41d4-a716-446655440004', 'NO', 'MARS2030-NO', NOW());
+('8a0e8400-e29b-41d4-a716-446655440008', '770e8400-e29b-41d4-a716-446655440004', 'YES', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440009', '770e8400-e29b-41d4-a716-446655440004', 'NO', 1, NOW());
+
+-- Contracts for markets (one per outcome)
+INSERT INTO contracts (id, market_id, outcome_id, ticker, created_at) VALUES
+-- BTC100K2025
+('880e8400-e29b-41d4-a716-446655440000', '770e8400-e29b-41d4-a716-446655440000', '8a0e8400-e29b-41d4-a716-446655440000', 'BTC100K2025-YES', NOW()),
+('880e8400-e29b-41d4-a716-446655440001', '770e8400-e29b-41d4-a716-446655440000', '8a0e8400-e29b-41d4-a716-446655440001', 'BTC100K2025-NO', NOW()),
+-- ETH5K2025
+('880e8400-e29b-41d4-a716-446655440002', '770e8400-e29b-41d4-a716-446655440001', '8a0e8400-e29b-41d4-a716-446655440002', 'ETH5K2025-YES', NOW()),
+('880e8400-e29b-41d4-a716-446655440003', '770e8400-e29b-41d4-a716-446655440001', '8a0e8400-e29b-41d4-a716-446655440003', 'ETH5K2025-NO', NOW()),
+-- SUPERBOWL2026
+('880e8400-e29b-41d4-a716-446655440004', '770e8400-e29b-41d4-a716-446655440002', '8a0e8400-e29b-41d4-a716-446655440004', 'SUPERBOWL2026-YES', NOW()),
+('880e8400-e29b-41d4-a716-446655440005', '770e8400-e29b-41d4-a716-446655440002', '8a0e8400-e29b-41d4-a716-446655440005', 'SUPERBOWL2026-NO', NOW()),
+-- AI_AGI_2026
+('880e8400-e29b-41d4-a716-446655440006', '770e8400-e29b-41d4-a716-446655440003', '8a0e8400-e29b-41d4-a716-446655440006', 'AI_AGI_2026-YES', NOW()),
+('880e8400-e29b-41d4-a716-446655440007', '770e8400-e29b-41d4-a716-446655440003', '8a0e8400-e29b-41d4-a716-446655440007', 'AI_AGI_2026-NO', NOW()),
+-- MARS2030
+('880e8400-e29b-41d4-a716-446655440008', '770e8400-e29b-41d4-a716-446655440004', '8a0e8400-e29b-41d4-a716-446655440008', 'MARS2030-YES', NOW()),
+('880e8400-e29b-41d4-a716-446655440009', '770e8400-e29b-41d4-a716-446655440004', '8a0e8400-e29b-41d4-a716-446655440009', 'MARS2030-NO', NOW());
+
+-- Categorical market with one outcome and contract per team
+INSERT INTO markets (id, ticker, question, rules, resolution_source, status, market_type, expires_at, created_at, updated_at) VALUES
+('770e8400-e29b-41d4-a716-446655440005', 'WC2026WINNER', 'Which team will win the 2026 FIFA World Cup?', 'Market resolves to the team that wins the 2026 FIFA World Cup final. Resolves to Other if the winner is not listed.', 'FIFA Official', 'OPEN', 'CATEGORICAL', '2026-07-19 23:59:59+00', NOW(), NOW());
+
+INSERT INTO market_outcomes (id, market_id, name, display_order, created_at) VALUES
+('8a0e8400-e29b-41d4-a716-446655440010', '770e8400-e29b-41d4-a716-446655440005', 'Brazil', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440011', '770e8400-e29b-41d4-a716-446655440005', 'France', 1, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440012', '770e8400-e29b-41d4-a716-446655440005', 'Argentina', 2, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440013', '770e8400-e29b-41d4-a716-446655440005', 'England', 3, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440014', '770e8400-e29b-41d4-a716-446655440005', 'Other', 4, NOW());
+
+INSERT INTO contracts (id, market_id, outcome_id, ticker, created_at) VALUES
+('880e8400-e29b-41d4-a716-446655440010', '770e8400-e29b-41d4-a716-446655440005', '8a0e8400-e29b-41d4-a716-446655440010', 'WC2026WINNER-BRA', NOW()),
+('880e8400-e29b-41d4-a716-446655440011', '770e8400-e29b-41d4-a716-446655440005', '8a0e8400-e29b-41d4-a716-446655440011', 'WC2026WINNER-FRA', NOW()),
+('880e8400-e29b-41d4-a716-446655440012', '770e8400-e29b-41d4-a716-446655440005', '8a0e8400-e29b-41d4-a716-446655440012', 'WC2026WINNER-ARG', NOW()),
+('880e8400-e29b-41d4-a716-446655440013', '770e8400-e29b-41d4-a716-446655440005', '8a0e8400-e29b-41d4-a716-446655440013', 'WC2026WINNER-ENG', NOW()),
+('880e8400-e29b-41d4-a716-446655440014', '770e8400-e29b-41d4-a716-446655440005', '8a0e8400-e29b-41d4-a716-446655440014', 'WC2026WINNER-OTHER', NOW());
+
+-- Scalar market with LONG/SHORT contracts paying linearly across the range
+INSERT INTO markets (id, ticker, question, rules, resolution_source, status, market_type, scalar_lower_bound, scalar_upper_bound, expires_at, created_at, updated_at) VALUES
+('770e8400-e29b-41d4-a716-446655440006', 'USCPI2026', 'What will US year-over-year CPI inflation be for December 2026?', 'Market resolves to the headline CPI-U year-over-year percentage change for December 2026. LONG pays linearly from 0 at 0% to 1 credit at 6%, clamped to that range; SHORT pays the remainder.', 'US Bureau of Labor Statistics', 'OPEN', 'SCALAR', 0, 6, '2027-01-15 23:59:59+00', NOW(), NOW());
+
+INSERT INTO market_outcomes (id, market_id, name, display_order, created_at) VALUES
+('8a0e8400-e29b-41d4-a716-446655440020', '770e8400-e29b-41d4-a716-446655440006', 'LONG', 0, NOW()),
+('8a0e8400-e29b-41d4-a716-446655440021', '770e8400-e29b-41d4-a716-446655440006', 'SHORT', 1, NOW());
+
+INSERT INTO contracts (id, market_id, outcome_id, ticker, created_at) VALUES
+('880e8400-e29b-41d4-a716-446655440020', '770e8400-e29b-41d4-a716-446655440006', '8a0e8400-e29b-41d4-a716-446655440020', 'USCPI2026-LONG', NOW()),
+('880e8400-e29b-41d4-a716-446655440021', '770e8400-e29b-41d4-a716-446655440006', '8a0e8400-e29b-41d4-a716-446655440021', 'USCPI2026-SHORT', NOW());
+
+-- Market categories and tags
+UPDATE markets SET category = 'crypto', tags = ARRAY['bitcoin', 'price'] WHERE ticker = 'BTC100K2025';
+UPDATE markets SET category = 'crypto', tags = ARRAY['ethereum', 'price'] WHERE ticker = 'ETH5K2025';
+UPDATE markets SET category = 'sports', tags = ARRAY['nfl', 'super-bowl'] WHERE ticker = 'SUPERBOWL2026';
+UPDATE markets SET category = 'science', tags = ARRAY['ai'] WHERE ticker = 'AI_AGI_2026';
+UPDATE markets SET category = 'science', tags = ARRAY['space', 'nasa'] WHERE ticker = 'MARS2030';
+UPDATE markets SET category = 'sports', tags = ARRAY['soccer', 'world-cup'] WHERE ticker = 'WC2026WINNER';
+UPDATE markets SET category = 'economics', tags = ARRAY['inflation', 'cpi'] WHERE ticker = 'USCPI2026';
+
+-- Events grouping related markets
+INSERT INTO events (id, ticker, title, description, category, closes_at, created_at, updated_at) VALUES
+('cc0e8400-e29b-41d4-a716-446655440000', 'CRYPTO2025', 'Crypto prices at the end of 2025', 'Where will the largest cryptocurrencies trade by the end of 2025?', 'crypto', '2025-12-31 23:59:59+00', NOW(), NOW());
+
+UPDATE markets SET event_id = 'cc0e8400-e29b-41d4-a716-446655440000' WHERE ticker IN ('BTC100K2025', 'ETH5K2025');
+
+-- Recurring market templates (paused; activate through the admin API)
+INSERT INTO market_templates (id, name, ticker_pattern, question_pattern, rules_pattern, resolution_source, market_type, category, tags, params, interval_seconds, duration_seconds, auto_publish, active, next_run_at, created_at, updated_at) VALUES
+('dd0e8400-e29b-41d4-a716-446655440000', 'Daily BTC close', 'BTC{{.Params.threshold}}_{{date "20060102" .ExpiresAt}}', 'Will BTC close above ${{.Params.threshold}} on {{date "Jan 2, 2006" .ExpiresAt}}?', 'Market resolves YES if the BTC/USD daily close on CoinMarketCap for {{date "January 2, 2006" .ExpiresAt}} (UTC) is above ${{.Params.threshold}}. Otherwise resolves NO.', 'CoinMarketCap', 'BINARY', 'crypto', ARRAY['bitcoin', 'price', 'daily'], '{"threshold": "100000"}', 86400, 86400, TRUE, FALSE, date_trunc('day', NOW()) + INTERVAL '1 day', NOW(), NOW());
 
 -- Sample orders
 INSERT INTO orders (id, user_id, contract_id, type, status, quantity, quantity_filled, limit_price_credits, created_at, updated_at) VALUES
@@ -67,3 +137,4 @@ INSERT INTO credit_transactions (id, user_id, type, crypto_type, crypto_amount,
 ('bb0e8400-e29b-41d4-a716-446655440000', '550e8400-e29b-41d4-a716-446655440000', 'PURCHASE', 'USDC', 1000.00000000, 1000.00000000, 'COMPLETED', NOW() - INTERVAL '1 day', NOW()),
 ('bb0e8400-e29b-41d4-a716-446655440001', '550e8400-e29b-41d4-a716-446655440001', 'PURCHASE', 'ETH', 0.25000000, 500.00000000, 'COMPLETED', NOW() - INTERVAL '12 hours', NOW()),
 ('bb0e8400-e29b-41d4-a716-446655440002', '550e8400-e29b-41d4-a716-446655440002', 'PURCHASE', 'BTC', 0.01000000, 250.00000000, 'COMPLETED', NOW() - INTERVAL '6 hours', NOW());
+